@@ -1,11 +1,34 @@
 package allManager
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/imports"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/magiclink"
+	"github.com/yash3004/user_management_service/mailer"
+	"github.com/yash3004/user_management_service/onetime"
 	"github.com/yash3004/user_management_service/policies"
 	projectusers "github.com/yash3004/user_management_service/project_users"
 	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/reports"
 	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/tokenkeys"
 	"github.com/yash3004/user_management_service/users"
+	"github.com/yash3004/user_management_service/webauthn"
+	"github.com/yash3004/user_management_service/webhooks"
 	"gorm.io/gorm"
 )
 
@@ -16,17 +39,212 @@ type Managers struct {
 	RoleManager        roles.RoleManager
 	PolicyManager      policies.PolicyManager
 	ProjectUserManager projectusers.ProjectUserManager
+	ImportManager      imports.ImportManager
+	WebhookManager     webhooks.WebhookManager
+	AuditManager       audit.AuditManager
+	MagicLinkManager   magiclink.MagicLinkManager
+	WebAuthnManager    webauthn.WebAuthnManager
+	LoginManager       logins.LoginManager
+	APITokenManager    apitokens.APITokenManager
+	OAuthStates        onetime.Store
+	TokenKeyManager    tokenkeys.TokenKeyManager
+	ReportManager      reports.ReportManager
+	ArtifactManager    artifacts.ArtifactManager
+	ChangeFeed         changefeed.ChangeFeed
+	ConsentManager     consents.ConsentManager
+	AuthUsers          *users.AuthUsers
+	RoleNetworks       *roles.Networks
+	Passwords          *password.Hasher
+	OAuthProviders     *oauth.ProviderFactory
+	Regions            *regions.Resolver
 	DB                 *gorm.DB
 }
 
-// NewManagers creates a new instance of all managers
-func NewManagers(db *gorm.DB) *Managers {
+// defaultAuthCacheTTL is how long the users, projects and role networks
+// requests authenticate with are cached unless configured otherwise
+const defaultAuthCacheTTL = 5 * time.Second
+
+// NewManagers creates a new instance of all managers. Project users are kept
+// in the databases regions routes them to, or all in db if it is nil. New
+// passwords are hashed with passwords; stored OAuth tokens are encrypted with
+// tokenKeys, which is nil when token encryption is not configured. Import
+// files and exports are kept in store. It fails when the configuration names
+// an unknown backend.
+func NewManagers(db *gorm.DB, regions *regions.Resolver, cfg cmd.Config, passwords *password.Hasher, tokenKeys *tokenkeys.KeyRing, store blobstore.Store) (*Managers, error) {
+	authCacheTTL := cfg.AuthCache.TTL
+	if authCacheTTL <= 0 {
+		authCacheTTL = defaultAuthCacheTTL
+	}
+	if cfg.AuthCache.Disabled {
+		authCacheTTL = 0
+	}
+	authUsers := users.NewAuthUsers(db, authCacheTTL)
+	authUsers.DeactivateOrphaned = cfg.Users.DeactivateOrphaned
+	roleNetworks := roles.NewNetworks(db, authCacheTTL)
+	artifactManager := artifacts.NewManager(db, store, artifacts.Options{
+		Retention:       cfg.Storage.Retention,
+		CleanupInterval: cfg.Storage.CleanupInterval,
+		SignedURLTTL:    cfg.Storage.SignedURLTTL,
+	})
+	webhookManager := webhooks.NewManager(db, webhooks.Options{
+		ServiceURL: cfg.Webhooks.ServiceURL,
+		Timeout:    cfg.Webhooks.Timeout,
+	})
+	oauthProviders := oauth.NewProviderFactory(OAuthProviderConfigs(cfg.OAuth), oauth.ClientOptions{
+		Timeout:          cfg.OAuth.HTTP.Timeout,
+		Retries:          cfg.OAuth.HTTP.Retries,
+		FailureThreshold: cfg.OAuth.HTTP.FailureThreshold,
+		Cooldown:         cfg.OAuth.HTTP.Cooldown,
+		Log:              logging.Named("oauth"),
+	})
+	projectManager := projects.NewManager(db, projects.Options{
+		CustomOAuthScopes: map[string]bool{
+			"google":    cfg.OAuth.Google.AllowCustomScopes,
+			"facebook":  cfg.OAuth.Facebook.AllowCustomScopes,
+			"github":    cfg.OAuth.GitHub.AllowCustomScopes,
+			"microsoft": cfg.OAuth.Microsoft.AllowCustomScopes,
+		},
+		Template:          ProjectTemplate(cfg.Projects.Template),
+		MaxRoleExpiration: cfg.Roles.MaxExpiration,
+		OAuthProviders:    oauthProviders,
+		ResolveTTL:        authCacheTTL,
+
+		MaxProjects:           cfg.Projects.MaxProjects,
+		MaxProjectsPerCreator: cfg.Projects.MaxPerCreator,
+		Regions:               regions,
+	})
+	// Validated at startup
+	cursorKey, _ := changefeed.ParseCursorKey(cfg.Users.Changes.CursorKey)
+	changeFeed := changefeed.NewManager(db, regions, changefeed.Options{
+		CursorKey: cursorKey,
+		MaxWait:   cfg.Users.Changes.MaxWait,
+	})
+	projectUserManager := projectusers.NewManager(db, regions, projectManager, webhooks.Publishers{webhookManager, changeFeed}, passwords, tokenKeys, logging.Named("projectusers"))
+	mail := mailer.New(mailer.Options{
+		From:     cfg.Mail.From,
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.Username,
+		Password: cfg.Mail.Password,
+	})
+	loginManager := logins.NewManager(db, webhookManager, mail)
+	oauthStates, err := onetime.New(db, cfg.OAuth.State.Store)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth.state configuration: %w", err)
+	}
+
 	return &Managers{
-		UserManager:        users.NewManager(db),
-		ProjectManager:     projects.NewManager(db),
-		RoleManager:        roles.NewManager(db),
-		PolicyManager:      policies.NewManager(db),
-		ProjectUserManager: projectusers.NewManager(db),
-		DB:                 db,
+		UserManager:        users.NewManager(db, regions, passwords, cfg.Users.EmailScope, authUsers),
+		ProjectManager:     projectManager,
+		RoleManager:        roles.NewManager(db, regions, webhookManager, roleNetworks, roles.Options{MaxExpiration: cfg.Roles.MaxExpiration}),
+		PolicyManager:      policies.NewManager(db, regions),
+		ProjectUserManager: projectUserManager,
+		ImportManager: imports.NewManager(db, projectUserManager, artifactManager, imports.Options{
+			ChunkSize: cfg.Import.ChunkSize,
+			Workers:   cfg.Import.Workers,
+			Lease:     cfg.Import.Lease,
+		}),
+		WebhookManager: webhookManager,
+		AuditManager:   audit.NewManager(db),
+		ConsentManager: consents.NewManager(db, projectManager),
+		MagicLinkManager: magiclink.NewManager(db, regions, mail, loginManager, magiclink.Options{
+			BaseURL:    cfg.MagicLink.BaseURL,
+			TTL:        cfg.MagicLink.TTL,
+			RateLimit:  cfg.MagicLink.RateLimit,
+			RateWindow: cfg.MagicLink.RateWindow,
+
+			MaxFailures:      cfg.MagicLink.MaxFailures,
+			FailureWindow:    cfg.MagicLink.FailureWindow,
+			MaxTokenFailures: cfg.MagicLink.MaxTokenFailures,
+		}),
+		WebAuthnManager: webauthn.NewManager(db, regions, loginManager),
+		LoginManager:    loginManager,
+		APITokenManager: apitokens.NewManager(db),
+		OAuthStates:     oauthStates,
+		TokenKeyManager: tokenkeys.NewManager(db, regions, tokenKeys),
+		ReportManager: reports.NewManager(db, regions, projectManager, mail, reports.Options{
+			Interval: cfg.Reports.Interval,
+			CatchUp:  cfg.Reports.CatchUp,
+		}),
+		ArtifactManager: artifactManager,
+		ChangeFeed:      changeFeed,
+		AuthUsers:       authUsers,
+		RoleNetworks:    roleNetworks,
+		Passwords:       passwords,
+		OAuthProviders:  oauthProviders,
+		Regions:         regions,
+		DB:              db,
+	}, nil
+}
+
+// OAuthProviderConfigs converts the globally configured OAuth providers. They
+// are built at startup; providers of project overrides are built on first use.
+func OAuthProviderConfigs(cfg cmd.OAuthConfig) map[string]oauth.ProviderConfig {
+	return map[string]oauth.ProviderConfig{
+		"google": {
+			ClientID:     cfg.Google.ClientID,
+			ClientSecret: cfg.Google.ClientSecret,
+			RedirectURL:  cfg.Google.RedirectURL,
+			Scopes:       cfg.Google.Scopes,
+
+			AllowedDomains:    cfg.Google.AllowedDomains,
+			AllowCustomScopes: cfg.Google.AllowCustomScopes,
+		},
+		"facebook": {
+			ClientID:     cfg.Facebook.ClientID,
+			ClientSecret: cfg.Facebook.ClientSecret,
+			RedirectURL:  cfg.Facebook.RedirectURL,
+			Scopes:       cfg.Facebook.Scopes,
+
+			AllowedDomains:    cfg.Facebook.AllowedDomains,
+			AllowCustomScopes: cfg.Facebook.AllowCustomScopes,
+		},
+	}
+}
+
+// StorageOptions converts the configured blob storage. The local backend
+// falls back to the import storage directory, where import files were kept
+// before they moved to the blob store.
+func StorageOptions(cfg cmd.Config) blobstore.Options {
+	localPath := cfg.Storage.Local.Path
+	if localPath == "" {
+		localPath = cfg.Import.StoragePath
+	}
+	return blobstore.Options{
+		Backend: cfg.Storage.Backend,
+		Local:   blobstore.LocalOptions{Path: localPath},
+		S3: blobstore.S3Options{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+		},
+	}
+}
+
+// ProjectTemplate converts the configured RBAC template of new projects
+func ProjectTemplate(cfg cmd.ProjectTemplateConfig) projects.Template {
+	var template projects.Template
+	for _, role := range cfg.Roles {
+		roleTemplate := projects.RoleTemplate{
+			Name:        role.Name,
+			Description: role.Description,
+			Expiration:  role.Expiration,
+			Default:     role.Default,
+			Policies:    []projects.PolicyTemplate{},
+		}
+		for _, policy := range role.Policies {
+			roleTemplate.Policies = append(roleTemplate.Policies, projects.PolicyTemplate{
+				Name:        policy.Name,
+				Description: policy.Description,
+				Resource:    policy.Resource,
+				Action:      policy.Action,
+				Effect:      policy.Effect,
+			})
+		}
+		template.Roles = append(template.Roles, roleTemplate)
 	}
+	return template
 }
@@ -0,0 +1,227 @@
+// Package apitokens manages project API tokens, the credentials a tenant's
+// backend uses to call machine-facing endpoints for its project.
+package apitokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("apitokens")
+
+// TokenPrefix starts every API token so they are easy to spot in code and logs
+const TokenPrefix = "ums_"
+
+// lastUsedResolution limits how often LastUsedAt is written for a busy token
+const lastUsedResolution = time.Minute
+
+// ErrInvalidToken is returned for missing, unknown, revoked or other-project
+// tokens alike
+var ErrInvalidToken = apierrors.New(http.StatusUnauthorized, "INVALID_API_TOKEN", "invalid or missing project API token")
+
+// ErrServiceAccountNotFound is returned when issuing a token to a user that is
+// not a live service account of the project
+var ErrServiceAccountNotFound = apierrors.NotFound("service account not found")
+
+// ErrServiceAccountTokenForbidden is returned when a caller who may not manage
+// service accounts asks for a token issued to one
+var ErrServiceAccountTokenForbidden = apierrors.New(http.StatusForbidden, "SERVICE_ACCOUNT_FORBIDDEN", "only super admins may issue tokens to service accounts")
+
+// APITokenManager defines the interface for project API token management
+type APITokenManager interface {
+	// CreateToken issues a token, to a service account of the project when
+	// serviceAccountID is set; the plaintext is only available here
+	CreateToken(ctx context.Context, projectID uuid.UUID, name string, serviceAccountID *uuid.UUID) (*schemas.ProjectAPIToken, string, error)
+	ListTokens(ctx context.Context, projectID uuid.UUID) ([]schemas.ProjectAPIToken, error)
+	RevokeToken(ctx context.Context, projectID, tokenID uuid.UUID) error
+	// Authenticate checks that token is a live token of the project
+	Authenticate(ctx context.Context, projectID uuid.UUID, token string) error
+}
+
+// Manager implements the APITokenManager interface
+type Manager struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+// NewManager creates a new API token manager
+func NewManager(db *gorm.DB) APITokenManager {
+	return &Manager{
+		DB:    db,
+		Clock: clock.Real{},
+	}
+}
+
+// HashToken returns the at-rest form of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken generates a random token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateToken issues a new API token for a project, or for one of its
+// service accounts
+func (m *Manager) CreateToken(ctx context.Context, projectID uuid.UUID, name string, serviceAccountID *uuid.UUID) (*schemas.ProjectAPIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", apierrors.BadRequest("VALIDATION_FAILED", "name is required")
+	}
+
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", apierrors.NotFound("project not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, "", errors.New("internal server error")
+	}
+
+	if serviceAccountID != nil {
+		var account schemas.User
+		if err := m.DB.First(&account, "id = ? AND project_id = ? AND type = ?", *serviceAccountID, projectID, schemas.UserTypeService).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, "", ErrServiceAccountNotFound
+			}
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, "", errors.New("internal server error")
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.For(ctx).Errorf("Failed to generate API token: %v", err)
+		return nil, "", errors.New("internal server error")
+	}
+
+	record := schemas.ProjectAPIToken{
+		ID:               uuid.New(),
+		Name:             name,
+		Prefix:           token[:len(TokenPrefix)+8],
+		TokenHash:        HashToken(token),
+		CreatedAt:        m.Clock.Now(),
+		ProjectId:        projectID,
+		ServiceAccountId: serviceAccountID,
+	}
+	if err := m.DB.Create(&record).Error; err != nil {
+		log.For(ctx).Errorf("Failed to create API token: %v", err)
+		return nil, "", errors.New("failed to create API token")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceAPIToken,
+		ResourceID:   record.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return &record, token, nil
+}
+
+// ListTokens lists the live API tokens of a project
+func (m *Manager) ListTokens(ctx context.Context, projectID uuid.UUID) ([]schemas.ProjectAPIToken, error) {
+	var tokens []schemas.ProjectAPIToken
+	if err := m.DB.Where("project_id = ?", projectID).Order("created_at").Find(&tokens).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes an API token; it stops working immediately
+func (m *Manager) RevokeToken(ctx context.Context, projectID, tokenID uuid.UUID) error {
+	result := m.DB.Where("id = ? AND project_id = ?", tokenID, projectID).Delete(&schemas.ProjectAPIToken{})
+	if result.Error != nil {
+		log.For(ctx).Errorf("Failed to revoke API token: %v", result.Error)
+		return errors.New("failed to revoke API token")
+	}
+	if result.RowsAffected == 0 {
+		return apierrors.NotFound("API token not found")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceAPIToken,
+		ResourceID:   tokenID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return nil
+}
+
+// Authenticate checks that token belongs to the project and is not revoked
+func (m *Manager) Authenticate(ctx context.Context, projectID uuid.UUID, token string) error {
+	record, err := lookup(m.DB, token)
+	if err != nil {
+		return err
+	}
+	if record.ProjectId != projectID {
+		return ErrInvalidToken
+	}
+	touch(m.DB, record, m.Clock.Now())
+	return nil
+}
+
+// ServiceAccount returns the ID of the service account a live token was
+// issued to. Tokens of the project alone fail with ErrInvalidToken.
+func ServiceAccount(db *gorm.DB, now time.Time, token string) (uuid.UUID, error) {
+	record, err := lookup(db, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if record.ServiceAccountId == nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	touch(db, record, now)
+	return *record.ServiceAccountId, nil
+}
+
+// lookup finds a live token
+func lookup(db *gorm.DB, token string) (*schemas.ProjectAPIToken, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	var record schemas.ProjectAPIToken
+	if err := db.First(&record, "token_hash = ?", HashToken(token)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		log.Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return &record, nil
+}
+
+// touch records a use of the token, at most once per lastUsedResolution
+func touch(db *gorm.DB, record *schemas.ProjectAPIToken, now time.Time) {
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedResolution {
+		if err := db.Model(record).Update("last_used_at", now).Error; err != nil {
+			log.Errorf("Failed to update API token last use: %v", err)
+		}
+	}
+}
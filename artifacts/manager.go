@@ -0,0 +1,276 @@
+// Package artifacts records the files the service keeps in its blob store,
+// hands out links to them and deletes them once their retention period has
+// passed.
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var log = logging.Named("artifacts")
+
+// Defaults for the options of the artifact manager
+const (
+	DefaultRetention       = 7 * 24 * time.Hour
+	DefaultCleanupInterval = time.Hour
+	DefaultSignedURLTTL    = 15 * time.Minute
+)
+
+// cleanupBatch is how many expired artifacts one cleanup pass loads at a time
+const cleanupBatch = 500
+
+// ErrArtifactNotFound is returned for keys without a live artifact
+var ErrArtifactNotFound = apierrors.New(http.StatusNotFound, "ARTIFACT_NOT_FOUND", "artifact not found or expired")
+
+// ArtifactManager defines the interface for stored artifacts
+type ArtifactManager interface {
+	// Save stores content under key and records it. An artifact saved with
+	// expires is deleted once the retention period has passed; one saved
+	// without is kept until Expire is called for it. Saving a key again
+	// replaces its content and keeps its record.
+	Save(ctx context.Context, key, kind string, projectID *uuid.UUID, expires bool, content io.Reader) error
+	// Open opens a live artifact
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// SignedURL returns a time-limited URL fetching the artifact directly
+	// from storage, or blobstore.ErrSignedURLNotSupported
+	SignedURL(ctx context.Context, key string) (string, error)
+	// Expire starts the retention period of artifacts kept until now
+	Expire(ctx context.Context, keys ...string) error
+	// Delete removes an artifact at once
+	Delete(ctx context.Context, key string) error
+	// Cleanup deletes the expired artifacts and returns how many it deleted
+	Cleanup(ctx context.Context) (int, error)
+	// Start runs Cleanup every interval until ctx is done
+	Start(ctx context.Context)
+}
+
+// Options configures the artifact manager
+type Options struct {
+	Retention       time.Duration // How long artifacts are kept once they expire
+	CleanupInterval time.Duration // How often expired artifacts are deleted
+	SignedURLTTL    time.Duration // Lifetime of the signed URLs handed out
+}
+
+// Manager implements the ArtifactManager interface
+type Manager struct {
+	DB      *gorm.DB
+	Store   blobstore.Store
+	Clock   clock.Clock
+	Options Options
+}
+
+// NewManager creates a new artifact manager
+func NewManager(db *gorm.DB, store blobstore.Store, opts Options) ArtifactManager {
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = DefaultCleanupInterval
+	}
+	if opts.SignedURLTTL <= 0 {
+		opts.SignedURLTTL = DefaultSignedURLTTL
+	}
+	return &Manager{
+		DB:      db,
+		Store:   store,
+		Clock:   clock.Real{},
+		Options: opts,
+	}
+}
+
+// Save stores the blob, then records it. A blob whose record cannot be
+// written is deleted again unless it replaced a recorded one.
+func (m *Manager) Save(ctx context.Context, key, kind string, projectID *uuid.UUID, expires bool, content io.Reader) error {
+	if err := m.Store.Put(ctx, key, content); err != nil {
+		return err
+	}
+
+	now := m.Clock.Now()
+	artifact := schemas.Artifact{
+		ID:        uuid.New(),
+		Key:       key,
+		Kind:      kind,
+		ProjectId: projectID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if expires {
+		expiresAt := now.Add(m.Options.Retention)
+		artifact.ExpiresAt = &expiresAt
+	}
+
+	result := m.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&artifact)
+	if result.Error != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(result.Error))
+		if !m.recorded(key) {
+			m.Store.Delete(ctx, key)
+		}
+		return errors.New("internal server error")
+	}
+	return nil
+}
+
+// recorded reports whether key has a record, assuming it does if the
+// database cannot tell
+func (m *Manager) recorded(key string) bool {
+	var count int64
+	if err := m.DB.Model(&schemas.Artifact{}).Where("blob_key = ?", key).Count(&count).Error; err != nil {
+		return true
+	}
+	return count > 0
+}
+
+// live loads the record of an artifact that has not expired yet
+func (m *Manager) live(key string) (*schemas.Artifact, error) {
+	var artifact schemas.Artifact
+	err := m.DB.Where("blob_key = ? AND (expires_at IS NULL OR expires_at > ?)", key, m.Clock.Now()).
+		First(&artifact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrArtifactNotFound
+	}
+	if err != nil {
+		log.Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	return &artifact, nil
+}
+
+// Open opens a live artifact's blob
+func (m *Manager) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if _, err := m.live(key); err != nil {
+		return nil, err
+	}
+	content, err := m.Store.Get(ctx, key)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, ErrArtifactNotFound
+	}
+	if err != nil {
+		log.For(ctx).Errorf("Failed to open artifact %s: %v", key, err)
+		return nil, errors.New("internal server error")
+	}
+	return content, nil
+}
+
+// SignedURL signs a URL of a live artifact. The URL never outlives the
+// artifact's retention.
+func (m *Manager) SignedURL(ctx context.Context, key string) (string, error) {
+	artifact, err := m.live(key)
+	if err != nil {
+		return "", err
+	}
+	ttl := m.Options.SignedURLTTL
+	if artifact.ExpiresAt != nil {
+		if left := artifact.ExpiresAt.Sub(m.Clock.Now()); left < ttl {
+			ttl = left.Truncate(time.Second)
+		}
+		if ttl < time.Second {
+			return "", ErrArtifactNotFound
+		}
+	}
+
+	url, err := m.Store.SignedURL(ctx, key, ttl)
+	if err != nil && !errors.Is(err, blobstore.ErrSignedURLNotSupported) {
+		log.For(ctx).Errorf("Failed to sign artifact URL %s: %v", key, err)
+		return "", errors.New("internal server error")
+	}
+	return url, err
+}
+
+// Expire sets the expiry of the given artifacts that have none yet
+func (m *Manager) Expire(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	now := m.Clock.Now()
+	if err := m.DB.Model(&schemas.Artifact{}).
+		Where("blob_key IN ? AND expires_at IS NULL", keys).
+		Updates(map[string]interface{}{
+			"expires_at": now.Add(m.Options.Retention),
+			"updated_at": now,
+		}).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	return nil
+}
+
+// Delete deletes an artifact's blob, then its record
+func (m *Manager) Delete(ctx context.Context, key string) error {
+	if err := m.Store.Delete(ctx, key); err != nil {
+		log.For(ctx).Errorf("Failed to delete artifact %s: %v", key, err)
+		return errors.New("internal server error")
+	}
+	if err := m.DB.Where("blob_key = ?", key).Delete(&schemas.Artifact{}).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	return nil
+}
+
+// Start launches the loop deleting expired artifacts
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.Options.CleanupInterval)
+		defer ticker.Stop()
+		for {
+			if deleted, err := m.Cleanup(ctx); err != nil {
+				log.For(ctx).Errorf("Artifact cleanup failed after deleting %d artifacts: %v", deleted, err)
+			} else if deleted > 0 {
+				log.For(ctx).Infof("Deleted %d expired artifacts", deleted)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Cleanup deletes every artifact that has expired. Blobs are deleted before
+// their records, so a blob is never left without one; an artifact whose
+// blob cannot be deleted is retried on the next pass. Several instances may
+// clean up at once, as deleting twice is harmless.
+func (m *Manager) Cleanup(ctx context.Context) (int, error) {
+	deleted := 0
+	var lastID uuid.UUID
+	for {
+		var expired []schemas.Artifact
+		if err := m.DB.Where("expires_at <= ? AND id > ?", m.Clock.Now(), lastID).
+			Order("id").Limit(cleanupBatch).Find(&expired).Error; err != nil {
+			return deleted, fmt.Errorf("failed to load expired artifacts: %w", err)
+		}
+		for _, artifact := range expired {
+			if ctx.Err() != nil {
+				return deleted, ctx.Err()
+			}
+			if err := m.Store.Delete(ctx, artifact.Key); err != nil {
+				log.For(ctx).Warningf("Failed to delete expired artifact %s: %v", artifact.Key, err)
+				continue
+			}
+			if err := m.DB.Delete(&schemas.Artifact{}, "id = ?", artifact.ID).Error; err != nil {
+				return deleted, fmt.Errorf("failed to delete artifact record: %w", err)
+			}
+			deleted++
+		}
+		if len(expired) < cleanupBatch {
+			return deleted, nil
+		}
+		lastID = expired[len(expired)-1].ID
+	}
+}
@@ -0,0 +1,149 @@
+package artifacts_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"gorm.io/gorm"
+)
+
+const retention = 24 * time.Hour
+
+// newManager returns an artifact manager keeping artifacts for a day in
+// store, on a fake clock
+func newManager(t *testing.T, store blobstore.Store) (*artifacts.Manager, *gorm.DB, *testutil.FakeClock) {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	manager := artifacts.NewManager(db, store, artifacts.Options{Retention: retention, SignedURLTTL: time.Hour}).(*artifacts.Manager)
+	clock := testutil.NewFakeClock(time.Now())
+	manager.Clock = clock
+	return manager, db, clock
+}
+
+func save(t *testing.T, manager *artifacts.Manager, key string, expires bool) {
+	t.Helper()
+	if err := manager.Save(context.Background(), key, "export", nil, expires, strings.NewReader(key)); err != nil {
+		t.Fatalf("Save(%s): %v", key, err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	store, err := blobstore.NewLocal(blobstore.LocalOptions{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, db, clock := newManager(t, store)
+	ctx := context.Background()
+
+	save(t, manager, "exports/expiring.json", true)
+	save(t, manager, "imports/running.csv", false)
+	save(t, manager, "imports/finished.csv", false)
+
+	content, err := manager.Open(ctx, "exports/expiring.json")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "exports/expiring.json" {
+		t.Errorf("Open = %q, want the saved content", data)
+	}
+	if _, err := manager.SignedURL(ctx, "exports/expiring.json"); !errors.Is(err, blobstore.ErrSignedURLNotSupported) {
+		t.Errorf("SignedURL on the local store: err = %v, want %v", err, blobstore.ErrSignedURLNotSupported)
+	}
+
+	// The import finishes halfway through the retention of the export
+	clock.Advance(retention / 2)
+	if err := manager.Expire(ctx, "imports/finished.csv", "exports/expiring.json"); err != nil {
+		t.Fatalf("Expire: %v", err)
+	}
+
+	clock.Advance(retention / 2)
+	if _, err := manager.Open(ctx, "exports/expiring.json"); !errors.Is(err, artifacts.ErrArtifactNotFound) {
+		t.Errorf("Open after the retention: err = %v, want %v", err, artifacts.ErrArtifactNotFound)
+	}
+	if deleted, err := manager.Cleanup(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Cleanup = %d, %v, want the expired export deleted", deleted, err)
+	}
+	if _, err := store.Get(ctx, "exports/expiring.json"); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("the expired export's blob is still stored: %v", err)
+	}
+
+	// Expire did not extend the export, and started the finished import's
+	// retention without touching the running one
+	clock.Advance(retention / 2)
+	if deleted, err := manager.Cleanup(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Cleanup = %d, %v, want the finished import deleted", deleted, err)
+	}
+	clock.Advance(365 * 24 * time.Hour)
+	if deleted, err := manager.Cleanup(ctx); err != nil || deleted != 0 {
+		t.Fatalf("Cleanup = %d, %v, want the running import kept", deleted, err)
+	}
+	var keys []string
+	if err := db.Model(&schemas.Artifact{}).Pluck("blob_key", &keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "imports/running.csv" {
+		t.Errorf("artifacts left = %v, want the running import", keys)
+	}
+	if _, err := manager.Open(ctx, "imports/running.csv"); err != nil {
+		t.Errorf("Open of the running import: %v", err)
+	}
+}
+
+func TestSignedURLNeverOutlivesTheArtifact(t *testing.T) {
+	fake := testutil.NewFakeS3(t)
+	store, err := blobstore.NewS3(fake.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, _, clock := newManager(t, store)
+	store.Clock = clock
+	ctx := context.Background()
+
+	save(t, manager, "exports/a.json", true)
+	if _, ok := fake.Object("exports/a.json"); !ok {
+		t.Fatal("the artifact was not uploaded")
+	}
+	expiresIn := func() string {
+		t.Helper()
+		signed, err := manager.SignedURL(ctx, "exports/a.json")
+		if err != nil {
+			t.Fatalf("SignedURL: %v", err)
+		}
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query().Get("X-Amz-Expires")
+	}
+
+	if got := expiresIn(); got != "3600" {
+		t.Errorf("a fresh artifact's URL expires in %ss, want the configured hour", got)
+	}
+	clock.Advance(retention - 10*time.Minute)
+	if got := expiresIn(); got != "600" {
+		t.Errorf("an artifact expiring in 10 minutes has a URL expiring in %ss, want 600", got)
+	}
+	clock.Advance(10 * time.Minute)
+	if _, err := manager.SignedURL(ctx, "exports/a.json"); !errors.Is(err, artifacts.ErrArtifactNotFound) {
+		t.Errorf("SignedURL of an expired artifact: err = %v, want %v", err, artifacts.ErrArtifactNotFound)
+	}
+
+	if deleted, err := manager.Cleanup(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Cleanup = %d, %v, want the artifact deleted", deleted, err)
+	}
+	if _, ok := fake.Object("exports/a.json"); ok {
+		t.Error("the expired artifact's object is still in the bucket")
+	}
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("audit")
+
+// Pagination defaults for audit queries
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// MaxQueryLength bounds the free-text search of an audit query
+const MaxQueryLength = 200
+
+// Filter narrows an audit query. Zero values match everything.
+type Filter struct {
+	ProjectID    *uuid.UUID
+	From         *time.Time // Inclusive
+	To           *time.Time // Inclusive
+	Actor        string
+	ResourceType string
+	Action       string
+	Query        string // Text the details, resource ID or actor must contain, ignoring case
+	Page         int    // 1-based
+	PageSize     int
+}
+
+// Page is one page of audit events, newest first
+type Page struct {
+	Events   []schemas.AuditEvent
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// AuditManager defines the interface for querying audit events
+type AuditManager interface {
+	ListEvents(ctx context.Context, filter Filter) (*Page, error)
+}
+
+// Manager implements the AuditManager interface
+type Manager struct {
+	DB *gorm.DB
+}
+
+// NewManager creates a new audit manager
+func NewManager(db *gorm.DB) AuditManager {
+	return &Manager{
+		DB: db,
+	}
+}
+
+// ListEvents lists audit events matching the filter, newest first
+func (m *Manager) ListEvents(ctx context.Context, filter Filter) (*Page, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, apierrors.BadRequest("INVALID_RANGE", "from must not be after to")
+	}
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	if len(filter.Query) > MaxQueryLength {
+		return nil, apierrors.BadRequest("INVALID_QUERY", fmt.Sprintf("q must be at most %d characters", MaxQueryLength))
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = DefaultPageSize
+	}
+	if filter.PageSize > MaxPageSize {
+		filter.PageSize = MaxPageSize
+	}
+
+	query := m.DB.Model(&schemas.AuditEvent{})
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Query != "" {
+		// A substring scan: the audit log is searched rarely, during
+		// incident response, so it is not worth a fulltext index
+		pattern := "%" + escapeLike(strings.ToLower(filter.Query)) + "%"
+		query = query.Where("LOWER(details) LIKE ? ESCAPE '!' OR LOWER(resource_id) LIKE ? ESCAPE '!' OR LOWER(actor) LIKE ? ESCAPE '!'",
+			pattern, pattern, pattern)
+	}
+
+	page := &Page{Page: filter.Page, PageSize: filter.PageSize}
+	if err := query.Count(&page.Total).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	if err := query.Order("created_at DESC").Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&page.Events).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	return page, nil
+}
+
+// likeEscaper escapes the LIKE wildcards, with ! as the escape character
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// escapeLike makes s match only itself in a LIKE pattern using ESCAPE '!'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,192 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/version"
+	"gorm.io/gorm"
+)
+
+// day is the start of the day the test events are recorded on
+var day = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// record stores an event by actor at the given hour of day
+func record(t *testing.T, db *gorm.DB, actor string, hour int, entry audit.Entry) {
+	t.Helper()
+
+	entry.At = day.Add(time.Duration(hour) * time.Hour)
+	audit.Record(audit.WithActor(context.Background(), actor), db, entry)
+}
+
+// seed records one event per hour from 0 to 5, alternating actors and
+// resource types
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	for hour := 0; hour < 6; hour++ {
+		actor, resource := "alice", audit.ResourceRole
+		if hour%2 == 1 {
+			actor, resource = "bob", audit.ResourcePolicy
+		}
+		action := audit.ActionCreate
+		if hour >= 4 {
+			action = audit.ActionDelete
+		}
+		record(t, db, actor, hour, audit.Entry{Action: action, ResourceType: resource, ResourceID: uuid.NewString()})
+	}
+}
+
+// hours returns the hours of day of a page's events
+func hours(page *audit.Page) []int {
+	out := make([]int, 0, len(page.Events))
+	for _, event := range page.Events {
+		out = append(out, int(event.CreatedAt.Sub(day)/time.Hour))
+	}
+	return out
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func at(hour int) *time.Time {
+	t := day.Add(time.Duration(hour) * time.Hour)
+	return &t
+}
+
+func TestListEventsFilters(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	seed(t, db)
+	manager := audit.NewManager(db)
+
+	for _, tc := range []struct {
+		name   string
+		filter audit.Filter
+		want   []int
+	}{
+		{"everything newest first", audit.Filter{}, []int{5, 4, 3, 2, 1, 0}},
+		{"inclusive range", audit.Filter{From: at(1), To: at(3)}, []int{3, 2, 1}},
+		{"from only", audit.Filter{From: at(4)}, []int{5, 4}},
+		{"to only", audit.Filter{To: at(1)}, []int{1, 0}},
+		{"single instant", audit.Filter{From: at(2), To: at(2)}, []int{2}},
+		{"range before every event", audit.Filter{To: &day}, []int{0}},
+		{"actor", audit.Filter{Actor: "bob"}, []int{5, 3, 1}},
+		{"resource type", audit.Filter{ResourceType: audit.ResourceRole}, []int{4, 2, 0}},
+		{"action", audit.Filter{Action: audit.ActionDelete}, []int{5, 4}},
+		{"combined", audit.Filter{From: at(1), Actor: "alice", Action: audit.ActionCreate}, []int{2}},
+		{"second page", audit.Filter{Page: 2, PageSize: 4}, []int{1, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := manager.ListEvents(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("ListEvents: %v", err)
+			}
+			if got := hours(page); !equalInts(got, tc.want) {
+				t.Errorf("events at hours %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	page, err := manager.ListEvents(context.Background(), audit.Filter{Page: 2, PageSize: 4})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if page.Total != 6 || page.Page != 2 || page.PageSize != 4 {
+		t.Errorf("page = total %d, page %d of size %d, want total 6, page 2 of size 4", page.Total, page.Page, page.PageSize)
+	}
+}
+
+func TestListEventsRejectsAnInvertedRange(t *testing.T) {
+	manager := audit.NewManager(testutil.NewTestDB(t))
+
+	_, err := manager.ListEvents(context.Background(), audit.Filter{From: at(3), To: at(2)})
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_RANGE" || apiErr.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("ListEvents = %v, want a 400 INVALID_RANGE", err)
+	}
+}
+
+func TestRecordTagsTheServiceVersion(t *testing.T) {
+	saved := version.Version
+	t.Cleanup(func() { version.Version = saved })
+	version.Version = "v1.2.3"
+	db := testutil.NewTestDB(t)
+
+	record(t, db, "alice", 0, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.ResourceRole, ResourceID: uuid.NewString()})
+	var event schemas.AuditEvent
+	if err := db.First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	if event.ServiceVersion != "v1.2.3" {
+		t.Fatalf("service version = %q, want v1.2.3", event.ServiceVersion)
+	}
+}
+
+func TestListEventsSearchesByEmail(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := audit.NewManager(db)
+	record(t, db, "root", 0, audit.Entry{Action: audit.ActionCreate, ResourceType: "user", ResourceID: uuid.NewString(),
+		Details: `{"after":{"email":"Alice@Example.com"}}`})
+	record(t, db, "bob", 1, audit.Entry{Action: audit.ActionUpdate, ResourceType: "user", ResourceID: uuid.NewString(),
+		Details: `{"before":{"email":"alice@example.com"},"after":{"email":"alice@example.org"}}`})
+	record(t, db, "alice@example.com", 2, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.ResourceRole, ResourceID: uuid.NewString()})
+	record(t, db, "root", 3, audit.Entry{Action: audit.ActionCreate, ResourceType: "user", ResourceID: uuid.NewString(),
+		Details: `{"after":{"email":"malice@example.net","ip":"203.0.113.7"}}`})
+	record(t, db, "root", 4, audit.Entry{Action: audit.ActionCreate, ResourceType: "user", ResourceID: uuid.NewString(),
+		Details: `{"after":{"email":"alicexexample.com"}}`})
+
+	for _, tc := range []struct {
+		name   string
+		filter audit.Filter
+		want   []int
+	}{
+		{"an email in details or the actor, ignoring case", audit.Filter{Query: "alice@example.com"}, []int{2, 1, 0}},
+		{"surrounding spaces are ignored", audit.Filter{Query: "  ALICE@EXAMPLE.COM "}, []int{2, 1, 0}},
+		{"an IP address", audit.Filter{Query: "203.0.113.7"}, []int{3}},
+		{"wildcards match only themselves", audit.Filter{Query: "alice_example%"}, nil},
+		{"combined with another filter", audit.Filter{Query: "alice@example.com", Actor: "root"}, []int{0}},
+		{"paginated", audit.Filter{Query: "alice@example.com", Page: 2, PageSize: 2}, []int{0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := manager.ListEvents(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("ListEvents: %v", err)
+			}
+			if got := hours(page); !equalInts(got, tc.want) {
+				t.Errorf("events at hours %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	page, err := manager.ListEvents(context.Background(), audit.Filter{Query: "alice@example.com", PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if page.Total != 3 {
+		t.Errorf("total = %d, want the 3 matching events", page.Total)
+	}
+
+	_, err = manager.ListEvents(context.Background(), audit.Filter{Query: strings.Repeat("a", audit.MaxQueryLength+1)})
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_QUERY" {
+		t.Fatalf("ListEvents with an overlong query = %v, want INVALID_QUERY", err)
+	}
+}
@@ -0,0 +1,206 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"golang.org/x/oauth2"
+)
+
+// Defaults for the options of the provider HTTP clients
+const (
+	DefaultTimeout          = 10 * time.Second
+	DefaultRetries          = 1
+	DefaultRetryBackoff     = 200 * time.Millisecond
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// ErrProviderUnavailable is returned without calling a provider whose
+// circuit breaker is open after repeated failures
+var ErrProviderUnavailable = apierrors.New(http.StatusServiceUnavailable, "OAUTH_PROVIDER_UNAVAILABLE", "the oauth provider is failing; try again later")
+
+// ClientOptions configures the HTTP clients providers are called with
+type ClientOptions struct {
+	Timeout time.Duration // Limit on each call, including reading the response
+	// Retries is how many times a failed GET is repeated; negative for
+	// never. Code exchanges are never repeated, as a code is only good for
+	// one exchange.
+	Retries      int
+	RetryBackoff time.Duration
+	// FailureThreshold is how many calls in a row must fail to open the
+	// breaker; it stays open for Cooldown, then lets one call through to
+	// test the provider
+	FailureThreshold int
+	Cooldown         time.Duration
+	// Log is where the clients and the provider factory log; nil for the
+	// "oauth" subsystem. Each client logs as its provider under it.
+	Log logging.Logger
+}
+
+// NewClient returns an HTTP client for the named provider that times calls
+// out, retries failed GETs and fails fast while the provider is failing
+func NewClient(name string, opts ClientOptions) *http.Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	} else if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.Log == nil {
+		opts.Log = logging.Named("oauth")
+	}
+	breaker := NewBreaker(name, opts.FailureThreshold, opts.Cooldown)
+	breaker.Log = opts.Log.Named(name)
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &breakerTransport{
+			Base:    http.DefaultTransport,
+			Breaker: breaker,
+			Retries: opts.Retries,
+			Backoff: opts.RetryBackoff,
+		},
+	}
+}
+
+// defaultClient calls the providers built without a client, which would
+// otherwise use http.DefaultClient and wait on a provider forever
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// withClient makes the oauth2 package use client for the calls made with ctx
+func withClient(ctx context.Context, client *http.Client) context.Context {
+	if client == nil {
+		client = defaultClient
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// Breaker is a circuit breaker for calls to one provider
+type Breaker struct {
+	Name      string
+	Threshold int
+	Cooldown  time.Duration
+	Clock     clock.Clock
+	Log       logging.Logger
+
+	mu        sync.Mutex
+	failures  int       // Calls in a row that failed
+	openUntil time.Time // Zero while closed
+	probing   bool      // A call is testing the provider after the cooldown
+}
+
+// NewBreaker creates a closed breaker
+func NewBreaker(name string, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	return &Breaker{
+		Name:      name,
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		Clock:     clock.Real{},
+		Log:       logging.Named("oauth").Named(name),
+	}
+}
+
+// Allow reports whether a call may be made. Once the cooldown has passed
+// an open breaker lets a single call through.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.probing || b.Clock.Now().Before(b.openUntil) {
+		return ErrProviderUnavailable
+	}
+	b.probing = true
+	return nil
+}
+
+// Record counts the outcome of a call allowed by Allow
+func (b *Breaker) Record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		if !b.openUntil.IsZero() {
+			b.Log.Infof("OAuth provider %s has recovered; closing its circuit breaker", b.Name)
+		}
+		b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+		return
+	}
+
+	b.failures++
+	if b.probing || b.failures >= b.Threshold {
+		if b.openUntil.IsZero() {
+			b.Log.Warningf("OAuth provider %s failed %d calls in a row; failing its logins fast for %v", b.Name, b.failures, b.Cooldown)
+		}
+		b.openUntil = b.Clock.Now().Add(b.Cooldown)
+		b.probing = false
+	}
+}
+
+// abandon ends a call allowed by Allow without an outcome, so that another
+// may test the provider
+func (b *Breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// breakerTransport calls a provider through its breaker, retrying GETs that
+// fail
+type breakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *Breaker
+	Retries int
+	Backoff time.Duration
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if req.Method == http.MethodGet && (req.Body == nil || req.Body == http.NoBody) {
+		attempts += t.Retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.Breaker.Allow(); err != nil {
+			return nil, err
+		}
+		resp, err := t.Base.RoundTrip(req)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		if failed && errors.Is(req.Context().Err(), context.Canceled) {
+			// The caller gave up, which says nothing of the provider
+			t.Breaker.abandon()
+			return resp, err
+		}
+		t.Breaker.Record(!failed)
+		if !failed || attempt == attempts-1 {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t.Breaker.Log.For(req.Context()).V(2).Infof("Retrying a failed call to %s", req.URL.Host)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.Backoff << attempt):
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package oauth_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// provider is a fake provider answering each call with the status that
+// status returns for it, counting the calls
+type provider struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newProvider(t *testing.T, status func(call int32) int) *provider {
+	t.Helper()
+	p := &provider{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status(p.calls.Add(1)))
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// call sends a request, with a body for a POST, and returns the status
+func call(t *testing.T, ctx context.Context, client *http.Client, method, url string) (int, error) {
+	t.Helper()
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader("code=abc")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestClientTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := oauth.NewClient("github", oauth.ClientOptions{Timeout: 100 * time.Millisecond, Retries: -1})
+	start := time.Now()
+	_, err := call(t, context.Background(), client, http.MethodGet, slow.URL)
+	if err == nil {
+		t.Fatal("a call to a provider that never answers succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the call gave up after %s, want about the 100ms timeout", elapsed)
+	}
+	var timeout interface{ Timeout() bool }
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+}
+
+func TestClientRetriesOnlyGETs(t *testing.T) {
+	flaky := newProvider(t, func(call int32) int {
+		if call%2 == 1 {
+			return http.StatusBadGateway
+		}
+		return http.StatusOK
+	})
+	client := oauth.NewClient("github", oauth.ClientOptions{Retries: 1, RetryBackoff: time.Millisecond})
+
+	if status, err := call(t, context.Background(), client, http.MethodGet, flaky.URL); err != nil || status != http.StatusOK {
+		t.Errorf("GET = %d, %v, want the retry's 200", status, err)
+	}
+	if n := flaky.calls.Load(); n != 2 {
+		t.Errorf("the provider got %d calls, want the failed one and its retry", n)
+	}
+
+	// A code exchange is never repeated
+	if status, err := call(t, context.Background(), client, http.MethodPost, flaky.URL); err != nil || status != http.StatusBadGateway {
+		t.Errorf("POST = %d, %v, want the failure passed on", status, err)
+	}
+	if n := flaky.calls.Load(); n != 3 {
+		t.Errorf("the provider got %d calls, want one more for the POST", n)
+	}
+}
+
+func TestClientBreakerOpensOnRepeatedFailures(t *testing.T) {
+	failing := newProvider(t, func(int32) int { return http.StatusServiceUnavailable })
+	client := oauth.NewClient("google", oauth.ClientOptions{Retries: -1, FailureThreshold: 3, Cooldown: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if status, err := call(t, ctx, client, http.MethodGet, failing.URL); err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("call %d = %d, %v, want the provider's 503", i+1, status, err)
+		}
+	}
+	_, err := call(t, ctx, client, http.MethodGet, failing.URL)
+	if !errors.Is(err, oauth.ErrProviderUnavailable) {
+		t.Fatalf("the call after %d failures: err = %v, want %v", 3, err, oauth.ErrProviderUnavailable)
+	}
+	if n := failing.calls.Load(); n != 3 {
+		t.Errorf("the provider got %d calls, want none once the breaker opened", n)
+	}
+
+	// Callers giving up say nothing of the provider
+	healthy := newProvider(t, func(int32) int { return http.StatusOK })
+	other := oauth.NewClient("github", oauth.ClientOptions{Retries: -1, FailureThreshold: 1, Cooldown: time.Hour})
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := call(t, cancelled, other, http.MethodGet, healthy.URL); err == nil {
+		t.Fatal("a cancelled call succeeded")
+	}
+	if status, err := call(t, ctx, other, http.MethodGet, healthy.URL); err != nil || status != http.StatusOK {
+		t.Errorf("the call after a cancelled one = %d, %v, want it let through", status, err)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Now())
+	breaker := oauth.NewBreaker("github", 2, time.Minute)
+	breaker.Clock = clock
+
+	fail := func() {
+		t.Helper()
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		breaker.Record(false)
+	}
+
+	fail()
+	breaker.Record(true)
+	fail()
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("a success reset the failures, but the breaker opened: %v", err)
+	}
+	breaker.Record(false)
+	if err := breaker.Allow(); !errors.Is(err, oauth.ErrProviderUnavailable) {
+		t.Fatalf("after 2 failures in a row: err = %v, want the breaker open", err)
+	}
+
+	// After the cooldown one call tests the provider; it fails and the
+	// breaker opens for another cooldown
+	clock.Advance(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("the test call after the cooldown: %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, oauth.ErrProviderUnavailable) {
+		t.Fatalf("a second call during the test: err = %v, want it refused", err)
+	}
+	breaker.Record(false)
+	clock.Advance(time.Minute - time.Second)
+	if err := breaker.Allow(); !errors.Is(err, oauth.ErrProviderUnavailable) {
+		t.Fatalf("within the new cooldown: err = %v, want the breaker open", err)
+	}
+
+	// A test call that succeeds closes it
+	clock.Advance(time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("the test call after the second cooldown: %v", err)
+	}
+	breaker.Record(true)
+	for i := 0; i < 3; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("call %d after recovering: %v", i+1, err)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 // GithubProvider implements the Provider interface for GitHub OAuth
 type GithubProvider struct {
 	config *oauth2.Config
+	client *http.Client
 }
 
 // NewGithubProvider creates a new GitHub OAuth provider
@@ -27,6 +28,7 @@ func NewGithubProvider(config ProviderConfig) *GithubProvider {
 			Scopes:       config.Scopes,
 			Endpoint:     github.Endpoint,
 		},
+		client: config.HTTPClient,
 	}
 }
 
@@ -35,23 +37,23 @@ func (p *GithubProvider) GetAuthURL(state string) string {
 }
 
 func (p *GithubProvider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	return p.config.Exchange(ctx, code)
+	return p.config.Exchange(withClient(ctx, p.client), code)
 }
 
 func (p *GithubProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (*UserInfo, error) {
-	client := p.config.Client(ctx, token)
-	
+	client := p.config.Client(withClient(ctx, p.client), token)
+
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %v", err)
 	}
 	defer resp.Body.Close()
-	
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
-	
+
 	var githubUser struct {
 		ID        int    `json:"id"`
 		Login     string `json:"login"`
@@ -59,23 +61,23 @@ func (p *GithubProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (
 		Email     string `json:"email"`
 		AvatarURL string `json:"avatar_url"`
 	}
-	
+
 	if err := json.Unmarshal(body, &githubUser); err != nil {
 		return nil, fmt.Errorf("failed to parse user info: %v", err)
 	}
-	
-	var email string
-	if githubUser.Email == "" {
-		email, err = p.getEmail(client)
-		if err != nil {
+
+	// The profile's public email carries no verified flag, so it is looked
+	// up in the email list; without access to the list it counts as unverified
+	email, verified, err := p.getEmail(client, githubUser.Email)
+	if err != nil {
+		if githubUser.Email == "" {
 			return nil, err
 		}
-	} else {
-		email = githubUser.Email
+		email, verified = githubUser.Email, false
 	}
-	
+
 	firstName, lastName := splitName(githubUser.Name)
-	
+
 	return &UserInfo{
 		ID:        fmt.Sprintf("%d", githubUser.ID),
 		Email:     email,
@@ -83,44 +85,59 @@ func (p *GithubProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (
 		FirstName: firstName,
 		LastName:  lastName,
 		Picture:   githubUser.AvatarURL,
+		Provider:  p.GetName(),
+
+		EmailVerified: verified,
 	}, nil
 }
 
-func (p *GithubProvider) getEmail(client *http.Client) (string, error) {
+// getEmail returns the user's email and whether GitHub has verified it: the
+// public email when given, else the primary verified email, else any
+// verified one
+func (p *GithubProvider) getEmail(client *http.Client, public string) (string, bool, error) {
 	resp, err := client.Get("https://api.github.com/user/emails")
 	if err != nil {
-		return "", fmt.Errorf("failed to get user emails: %v", err)
+		return "", false, fmt.Errorf("failed to get user emails: %v", err)
 	}
 	defer resp.Body.Close()
-	
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read emails response body: %v", err)
+		return "", false, fmt.Errorf("failed to read emails response body: %v", err)
 	}
-	
+
 	var emails []struct {
 		Email    string `json:"email"`
 		Primary  bool   `json:"primary"`
 		Verified bool   `json:"verified"`
 	}
-	
+
 	if err := json.Unmarshal(body, &emails); err != nil {
-		return "", fmt.Errorf("failed to parse emails: %v", err)
+		return "", false, fmt.Errorf("failed to parse emails: %v", err)
 	}
-	
+
+	if public != "" {
+		for _, e := range emails {
+			if strings.EqualFold(e.Email, public) {
+				return public, e.Verified, nil
+			}
+		}
+		return public, false, nil
+	}
+
 	for _, e := range emails {
 		if e.Primary && e.Verified {
-			return e.Email, nil
+			return e.Email, true, nil
 		}
 	}
-	
+
 	for _, e := range emails {
 		if e.Verified {
-			return e.Email, nil
+			return e.Email, true, nil
 		}
 	}
-	
-	return "", fmt.Errorf("no verified email found")
+
+	return "", false, fmt.Errorf("no verified email found")
 }
 
 func (p *GithubProvider) GetName() string {
@@ -131,11 +148,11 @@ func splitName(fullName string) (string, string) {
 	if fullName == "" {
 		return "", ""
 	}
-	
+
 	parts := strings.Fields(fullName)
 	if len(parts) == 1 {
 		return parts[0], ""
 	}
-	
+
 	return parts[0], strings.Join(parts[1:], " ")
-}
\ No newline at end of file
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -13,6 +14,7 @@ import (
 // GoogleProvider implements the Provider interface for Google OAuth
 type GoogleProvider struct {
 	config *oauth2.Config
+	client *http.Client
 }
 
 // NewGoogleProvider creates a new Google OAuth provider
@@ -25,6 +27,7 @@ func NewGoogleProvider(config ProviderConfig) *GoogleProvider {
 			Scopes:       config.Scopes,
 			Endpoint:     google.Endpoint,
 		},
+		client: config.HTTPClient,
 	}
 }
 
@@ -35,36 +38,37 @@ func (p *GoogleProvider) GetAuthURL(state string) string {
 
 // Exchange exchanges the auth code for tokens
 func (p *GoogleProvider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	return p.config.Exchange(ctx, code)
+	return p.config.Exchange(withClient(ctx, p.client), code)
 }
 
 // GetUserInfo gets user info from Google using the token
 func (p *GoogleProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (*UserInfo, error) {
-	client := p.config.Client(ctx, token)
+	client := p.config.Client(withClient(ctx, p.client), token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v3/userinfo")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %v", err)
 	}
 	defer resp.Body.Close()
-	
-	body, err :=io.ReadAll(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
-	
+
 	var googleUser struct {
-		Sub      string `json:"sub"`
-		Email    string `json:"email"`
-		Name     string `json:"name"`
-		GivenName string `json:"given_name"`
-		FamilyName string `json:"family_name"`
-		Picture  string `json:"picture"`
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		Name          string `json:"name"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+		EmailVerified bool   `json:"email_verified"`
 	}
-	
+
 	if err := json.Unmarshal(body, &googleUser); err != nil {
 		return nil, fmt.Errorf("failed to parse user info: %v", err)
 	}
-	
+
 	return &UserInfo{
 		ID:        googleUser.Sub,
 		Email:     googleUser.Email,
@@ -72,10 +76,13 @@ func (p *GoogleProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (
 		FirstName: googleUser.GivenName,
 		LastName:  googleUser.FamilyName,
 		Picture:   googleUser.Picture,
+		Provider:  p.GetName(),
+
+		EmailVerified: googleUser.EmailVerified,
 	}, nil
 }
 
 // GetName returns the name of the provider
 func (p *GoogleProvider) GetName() string {
 	return "google"
-}
\ No newline at end of file
+}
@@ -0,0 +1,53 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// Sizes of the user table columns a provider's user is stored in, in
+// characters. The email column is sized per table, so its limit is passed to
+// Fit.
+const (
+	MaxIDLength       = 100  // OAuthID
+	MaxProviderLength = 50   // OAuthType
+	MaxNameLength     = 100  // FirstName and LastName
+	MaxTokenLength    = 4000 // AccessToken and RefreshToken, as stored
+)
+
+// ErrFieldTooLong is returned for a provider's user whose ID or email is too
+// long to store. Those identify the user, so unlike names they are never cut.
+var ErrFieldTooLong = apierrors.New(http.StatusBadGateway, "OAUTH_FIELD_TOO_LONG", "the provider returned a user ID or email too long to store")
+
+// Fit makes the user fit the columns it is stored in: names longer than
+// MaxNameLength are cut to it, and an ID, provider or email longer than its
+// column fails with an error wrapping ErrFieldTooLong that names the field.
+func (u *UserInfo) Fit(maxEmailLength int) error {
+	for _, field := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"id", u.ID, MaxIDLength},
+		{"provider", u.Provider, MaxProviderLength},
+		{"email", u.Email, maxEmailLength},
+	} {
+		if n := utf8.RuneCountInString(field.value); n > field.max {
+			return fmt.Errorf("%w: %s is %d characters long, more than the %d stored", ErrFieldTooLong, field.name, n, field.max)
+		}
+	}
+	u.FirstName = truncate(u.FirstName, MaxNameLength)
+	u.LastName = truncate(u.LastName, MaxNameLength)
+	return nil
+}
+
+// truncate cuts s to at most max characters
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,46 @@
+package oauth_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/auth/oauth"
+)
+
+func TestFit(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("é", n) }
+	for _, tc := range []struct {
+		name    string
+		info    oauth.UserInfo
+		tooLong bool
+	}{
+		{"fitting", oauth.UserInfo{ID: long(oauth.MaxIDLength), Provider: "google", Email: "a@example.com", FirstName: long(oauth.MaxNameLength)}, false},
+		{"long names", oauth.UserInfo{ID: "1", Provider: "google", FirstName: long(300), LastName: long(101)}, false},
+		{"long ID", oauth.UserInfo{ID: long(oauth.MaxIDLength + 1), Provider: "google"}, true},
+		{"long provider", oauth.UserInfo{ID: "1", Provider: long(oauth.MaxProviderLength + 1)}, true},
+		{"long email", oauth.UserInfo{ID: "1", Provider: "google", Email: long(20) + "@example.com"}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			info := tc.info
+			err := info.Fit(24)
+			if tc.tooLong {
+				if !errors.Is(err, oauth.ErrFieldTooLong) {
+					t.Fatalf("Fit = %v, want %v", err, oauth.ErrFieldTooLong)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fit: %v", err)
+			}
+			for name, value := range map[string]string{"first name": info.FirstName, "last name": info.LastName} {
+				if n := len([]rune(value)); n > oauth.MaxNameLength {
+					t.Errorf("%s is %d characters long, want at most %d", name, n, oauth.MaxNameLength)
+				}
+			}
+			if info.ID != tc.info.ID || info.Email != tc.info.Email {
+				t.Errorf("Fit changed the ID or email to %q, %q", info.ID, info.Email)
+			}
+		})
+	}
+}
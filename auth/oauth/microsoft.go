@@ -1 +1 @@
-package oauth
\ No newline at end of file
+package oauth
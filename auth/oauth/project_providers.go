@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/metrics"
+)
+
+var (
+	projectProvidersCached = metrics.NewGauge("ums_oauth_project_providers",
+		"OAuth providers built from project overrides and held in memory.")
+	projectProviderConstructions = metrics.NewCounter("ums_oauth_project_provider_constructions_total",
+		"OAuth providers built from project overrides, by provider.", "provider")
+)
+
+// projectProviderKey identifies a provider built from a project's override.
+// The hash covers the override, so a changed override never reuses the
+// provider of the previous one.
+type projectProviderKey struct {
+	projectID uuid.UUID
+	name      string
+	hash      string
+}
+
+// projectProvider is built once, by the first caller to ask for it; the
+// others wait for it
+type projectProvider struct {
+	once     sync.Once
+	provider Provider
+	err      error
+}
+
+// GetProjectProvider returns the named provider of a project, built from the
+// project's override on first use and reused while the override stays the
+// same. Without an override the globally configured provider is returned.
+// It is safe for concurrent use.
+func (f *ProviderFactory) GetProjectProvider(projectID uuid.UUID, name string, override *ProviderConfig) (Provider, error) {
+	if override == nil {
+		return f.GetProvider(name)
+	}
+
+	key := projectProviderKey{projectID: projectID, name: name, hash: overrideHash(name, override)}
+	f.projectsMu.Lock()
+	entry, ok := f.projects[key]
+	if !ok {
+		// The override changed, perhaps on another instance: the provider of
+		// the previous one is no longer used
+		for other := range f.projects {
+			if other.projectID == projectID && other.name == name {
+				delete(f.projects, other)
+			}
+		}
+		entry = &projectProvider{}
+		f.projects[key] = entry
+		projectProvidersCached.Set(float64(len(f.projects)))
+	}
+	f.projectsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.provider, entry.err = f.GetProviderWithOverride(name, override)
+		projectProviderConstructions.Inc(name)
+		f.log.Named(name).V(4).Infof("Built the %s oauth provider of project %s", name, projectID)
+	})
+	if entry.err != nil {
+		f.projectsMu.Lock()
+		if f.projects[key] == entry {
+			delete(f.projects, key)
+			projectProvidersCached.Set(float64(len(f.projects)))
+		}
+		f.projectsMu.Unlock()
+	}
+	return entry.provider, entry.err
+}
+
+// EvictProject drops the providers built from a project's overrides, so the
+// next login builds them from the stored overrides. The projects manager
+// calls it when the overrides change or the project is deleted.
+func (f *ProviderFactory) EvictProject(projectID uuid.UUID) {
+	f.projectsMu.Lock()
+	defer f.projectsMu.Unlock()
+	for key := range f.projects {
+		if key.projectID == projectID {
+			delete(f.projects, key)
+		}
+	}
+	projectProvidersCached.Set(float64(len(f.projects)))
+}
+
+// overrideHash hashes the parts of an override a provider is built from
+func overrideHash(name string, override *ProviderConfig) string {
+	h := sha256.New()
+	for _, part := range []string{name, override.ClientID, override.ClientSecret, override.RedirectURL, strings.Join(override.Scopes, " ")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
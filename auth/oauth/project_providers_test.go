@@ -0,0 +1,155 @@
+package oauth_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// constructions returns how many project providers of each supported kind
+// have been built so far
+func constructions(t *testing.T) map[string]float64 {
+	t.Helper()
+
+	counts := make(map[string]float64)
+	for _, name := range oauth.SupportedProviders {
+		counts[name] = testutil.MetricValue(t, fmt.Sprintf(`ums_oauth_project_provider_constructions_total{provider=%q}`, name))
+	}
+	return counts
+}
+
+func override(clientID string) *oauth.ProviderConfig {
+	return &oauth.ProviderConfig{ClientID: clientID, ClientSecret: "secret", RedirectURL: "https://app.example.com/callback"}
+}
+
+func TestProjectProvidersAreBuiltOncePerKey(t *testing.T) {
+	const (
+		projects = 20
+		callers  = 10
+	)
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"google": {ClientID: "global", ClientSecret: "secret"},
+	}, oauth.ClientOptions{})
+	ids := make([]uuid.UUID, projects)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	before := constructions(t)
+
+	// Every caller asks for both providers of every project at once
+	type key struct {
+		project uuid.UUID
+		name    string
+	}
+	var (
+		mu   sync.Mutex
+		seen = make(map[key]map[oauth.Provider]bool)
+		wg   sync.WaitGroup
+	)
+	start := make(chan struct{})
+	for c := 0; c < callers; c++ {
+		for _, id := range ids {
+			for _, name := range oauth.SupportedProviders {
+				wg.Add(1)
+				go func(id uuid.UUID, name string) {
+					defer wg.Done()
+					<-start
+					provider, err := factory.GetProjectProvider(id, name, override("client-"+id.String()))
+					if err != nil {
+						t.Errorf("GetProjectProvider(%s, %s): %v", id, name, err)
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					k := key{id, name}
+					if seen[k] == nil {
+						seen[k] = make(map[oauth.Provider]bool)
+					}
+					seen[k][provider] = true
+				}(id, name)
+			}
+		}
+	}
+	close(start)
+	wg.Wait()
+
+	after := constructions(t)
+	for _, name := range oauth.SupportedProviders {
+		if got := after[name] - before[name]; got != projects {
+			t.Errorf("%s was built %v times for %d projects, want once each", name, got, projects)
+		}
+	}
+	for k, providers := range seen {
+		if len(providers) != 1 {
+			t.Errorf("the callers for %s of project %s got %d providers, want one shared", k.name, k.project, len(providers))
+		}
+	}
+	if got := testutil.MetricValue(t, "ums_oauth_project_providers"); got < projects*float64(len(oauth.SupportedProviders)) {
+		t.Errorf("ums_oauth_project_providers = %v, want at least %d", got, projects*len(oauth.SupportedProviders))
+	}
+}
+
+func TestProjectProvidersAreRebuiltWhenTheOverrideChanges(t *testing.T) {
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"google": {ClientID: "global", ClientSecret: "secret"},
+	}, oauth.ClientOptions{})
+	project := uuid.New()
+	built := func() float64 {
+		return constructions(t)["google"]
+	}
+	get := func(config *oauth.ProviderConfig) oauth.Provider {
+		t.Helper()
+		provider, err := factory.GetProjectProvider(project, "google", config)
+		if err != nil {
+			t.Fatalf("GetProjectProvider: %v", err)
+		}
+		return provider
+	}
+
+	start := built()
+	first := get(override("one"))
+	if again := get(override("one")); again != first || built()-start != 1 {
+		t.Fatalf("the same override was built %v times, want once", built()-start)
+	}
+
+	// A changed override replaces the provider of the previous one
+	changed := get(override("two"))
+	if changed == first || built()-start != 2 {
+		t.Errorf("a changed override reused the provider or was built %v times in all, want 2", built()-start)
+	}
+
+	// An evicted project builds again on next use
+	factory.EvictProject(project)
+	if rebuilt := get(override("two")); rebuilt == changed || built()-start != 3 {
+		t.Errorf("an evicted provider was reused or built %v times in all, want 3", built()-start)
+	}
+
+	// Without an override the global provider is used and nothing is built
+	global, err := factory.GetProvider("google")
+	if err != nil {
+		t.Fatalf("GetProvider: %v", err)
+	}
+	if provider := get(nil); provider != global || built()-start != 3 {
+		t.Errorf("a project without an override got %v, want the global provider without a build", provider)
+	}
+}
+
+func TestFailedProjectProviderBuildsAreRetried(t *testing.T) {
+	factory := oauth.NewProviderFactory(nil, oauth.ClientOptions{})
+	project := uuid.New()
+	sample := `ums_oauth_project_provider_constructions_total{provider="facebook"}`
+	before := testutil.MetricValue(t, sample)
+
+	for i := 0; i < 2; i++ {
+		if _, err := factory.GetProjectProvider(project, "facebook", override("client")); err == nil {
+			t.Fatalf("attempt %d: GetProjectProvider of an unsupported provider succeeded", i+1)
+		}
+	}
+	if got := testutil.MetricValue(t, sample) - before; got != 2 {
+		t.Errorf("the failed provider was attempted %v times, want each call to try again", got)
+	}
+}
@@ -3,7 +3,12 @@ package oauth
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -25,7 +30,16 @@ type UserInfo struct {
 	FirstName string
 	LastName  string
 	Picture   string
-	Provider  string	
+	Provider  string
+
+	// EmailVerified reports whether the provider has verified that the user
+	// controls Email. Unverified emails are never used to link the login to
+	// an existing account.
+	EmailVerified bool
+
+	// Token is what the provider granted for the login, set by the login
+	// service once the profile has been read
+	Token *oauth2.Token
 }
 
 type ProviderConfig struct {
@@ -33,29 +47,179 @@ type ProviderConfig struct {
 	ClientSecret string
 	RedirectURL  string
 	Scopes       []string
+
+	// AllowedDomains restricts logins to emails in these domains; empty
+	// allows every domain
+	AllowedDomains []string
+
+	// AllowCustomScopes lets Scopes, and the scopes of project overrides,
+	// name scopes outside the provider's known ones
+	AllowCustomScopes bool
+
+	// HTTPClient makes the calls to the provider; nil uses a client with
+	// DefaultTimeout
+	HTTPClient *http.Client
+}
+
+// ErrEmailDomainNotAllowed is returned when a provider's user has an email
+// outside the provider's allowed domains
+var ErrEmailDomainNotAllowed = apierrors.New(http.StatusForbidden, "EMAIL_DOMAIN_NOT_ALLOWED", "email domain is not allowed for this provider")
+
+// ErrEmailNotVerified is returned when a login's email belongs to an existing
+// user but the provider has not verified that the user controls it
+var ErrEmailNotVerified = apierrors.New(http.StatusForbidden, "EMAIL_NOT_VERIFIED", "the provider has not verified this email address, so it cannot be used to sign in to the existing account")
+
+// AllowsEmail reports whether email belongs to one of the allowed domains.
+// Domains match exactly and case-insensitively, so subdomains must be listed
+// separately.
+func (c ProviderConfig) AllowsEmail(email string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range c.AllowedDomains {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportedProviders lists the provider names NewProvider can construct
+var SupportedProviders = []string{"google", "github"}
+
+// IsSupported reports whether name is one of SupportedProviders
+func IsSupported(name string) bool {
+	for _, supported := range SupportedProviders {
+		if supported == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewProvider constructs the named provider from config
+func NewProvider(name string, config ProviderConfig) (Provider, error) {
+	switch name {
+	case "google":
+		return NewGoogleProvider(config), nil
+	case "github":
+		return NewGithubProvider(config), nil
+	}
+	return nil, fmt.Errorf("provider %s not supported", name)
 }
 
 type ProviderFactory struct {
 	providers map[string]Provider
+	configs   map[string]ProviderConfig
+	clients   map[string]*http.Client
+	log       logging.Logger
+
+	// Providers built from project overrides, on first use
+	projectsMu sync.Mutex
+	projects   map[projectProviderKey]*projectProvider
 }
 
-func NewProviderFactory(configs map[string]ProviderConfig) *ProviderFactory {
+// NewProviderFactory builds the supported providers among configs. Scopes
+// outside a provider's known ones are dropped with a warning unless the
+// provider allows custom scopes, and the scopes GetUserInfo needs are added.
+// Each provider gets an HTTP client built from opts, shared with the
+// project overrides of the provider so that they trip the same breaker.
+func NewProviderFactory(configs map[string]ProviderConfig, opts ClientOptions) *ProviderFactory {
+	if opts.Log == nil {
+		opts.Log = logging.Named("oauth")
+	}
 	factory := &ProviderFactory{
 		providers: make(map[string]Provider),
+		configs:   make(map[string]ProviderConfig),
+		clients:   make(map[string]*http.Client),
+		log:       opts.Log,
+		projects:  make(map[projectProviderKey]*projectProvider),
+	}
+	for _, name := range SupportedProviders {
+		factory.clients[name] = NewClient(name, opts)
 	}
 
 	for name, config := range configs {
-		switch name {
-		case "google":
-			factory.providers[name] = NewGoogleProvider(config)
-		case "github":
-			factory.providers[name] = NewGithubProvider(config)
+		if !IsSupported(name) {
+			continue
 		}
+		config.Scopes = factory.checkScopes(name, config.Scopes, config.AllowCustomScopes)
+		config.HTTPClient = factory.clients[name]
+		provider, err := NewProvider(name, config)
+		if err != nil {
+			continue
+		}
+		factory.providers[name] = provider
+		factory.configs[name] = config
 	}
 
 	return factory
 }
 
+// GetProviderWithOverride returns the named provider built from a project's
+// own client credentials. The redirect URL and scopes fall back to the global
+// configuration when the override leaves them empty. Without an override the
+// globally configured provider is returned. The provider is built on every
+// call; GetProjectProvider reuses it.
+func (f *ProviderFactory) GetProviderWithOverride(name string, override *ProviderConfig) (Provider, error) {
+	if override == nil {
+		return f.GetProvider(name)
+	}
+
+	config := *override
+	config.Scopes = f.Scopes(name, override.Scopes)
+	config.HTTPClient = f.clients[name]
+	if global, ok := f.configs[name]; ok && config.RedirectURL == "" {
+		config.RedirectURL = global.RedirectURL
+	}
+	return NewProvider(name, config)
+}
+
+// Scopes returns the scopes a login with the named provider requests: those
+// of a project override, or the global ones when the override names none.
+// Override scopes were validated when stored; any the provider no longer
+// allows are dropped.
+func (f *ProviderFactory) Scopes(name string, override []string) []string {
+	global, ok := f.configs[name]
+	if ok && len(override) == 0 {
+		return global.Scopes
+	}
+	return f.checkScopes(name, override, global.AllowCustomScopes)
+}
+
+// checkScopes returns the effective scopes for the named provider, logging
+// the unknown ones: kept when allowCustom is set, dropped otherwise
+func (f *ProviderFactory) checkScopes(name string, scopes []string, allowCustom bool) []string {
+	unknown, err := ValidateScopes(name, scopes, allowCustom)
+	if err != nil {
+		f.log.Named(name).Warningf("Ignoring %v; set allow_custom_scopes to request them", err)
+		kept := make([]string, 0, len(scopes))
+		for _, scope := range scopes {
+			if !containsScope(unknown, strings.TrimSpace(scope)) {
+				kept = append(kept, scope)
+			}
+		}
+		scopes = kept
+	} else if len(unknown) > 0 {
+		f.log.Named(name).Warningf("The %s oauth provider requests custom scopes: %s", name, strings.Join(unknown, ", "))
+	}
+	return EffectiveScopes(name, scopes)
+}
+
+// CheckEmailDomain rejects emails outside the named provider's globally
+// configured allowed domains. The allowlist applies to project overrides too.
+func (f *ProviderFactory) CheckEmailDomain(name, email string) error {
+	if !f.configs[name].AllowsEmail(email) {
+		return ErrEmailDomainNotAllowed
+	}
+	return nil
+}
+
 // GetProvider returns a provider by name
 func (f *ProviderFactory) GetProvider(name string) (Provider, error) {
 	provider, ok := f.providers[name]
@@ -69,3 +233,10 @@ func (f *ProviderFactory) GetProvider(name string) (Provider, error) {
 func (f *ProviderFactory) GetAllProviders() map[string]Provider {
 	return f.providers
 }
+
+// Configured reports whether the named provider has a global client ID, and
+// so can be used by projects without credentials of their own
+func (f *ProviderFactory) Configured(name string) bool {
+	_, ok := f.providers[name]
+	return ok && f.configs[name].ClientID != ""
+}
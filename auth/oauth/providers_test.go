@@ -0,0 +1,44 @@
+package oauth_test
+
+import (
+	"testing"
+
+	"github.com/yash3004/user_management_service/auth/oauth"
+)
+
+func TestAllowsEmail(t *testing.T) {
+	config := oauth.ProviderConfig{AllowedDomains: []string{"example.com", "@Corp.example.org"}}
+	for email, allowed := range map[string]bool{
+		"ada@example.com":          true,
+		"ada@Example.COM":          true,
+		"ada@corp.example.org":     true,
+		"ada@mail.example.com":     false,
+		"ada@example.com.evil.net": false,
+		"ada@elsewhere.com":        false,
+		"not-an-email":             false,
+	} {
+		if got := config.AllowsEmail(email); got != allowed {
+			t.Errorf("AllowsEmail(%q) = %v, want %v", email, got, allowed)
+		}
+	}
+
+	if !(oauth.ProviderConfig{}).AllowsEmail("ada@anywhere.net") {
+		t.Error("a provider without allowed domains rejected an email")
+	}
+}
+
+func TestCheckEmailDomain(t *testing.T) {
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"google": {ClientID: "client", ClientSecret: "secret", AllowedDomains: []string{"example.com"}},
+	}, oauth.ClientOptions{})
+
+	if err := factory.CheckEmailDomain("google", "ada@example.com"); err != nil {
+		t.Errorf("CheckEmailDomain of an allowed domain = %v", err)
+	}
+	if err := factory.CheckEmailDomain("google", "ada@elsewhere.com"); err != oauth.ErrEmailDomainNotAllowed {
+		t.Errorf("CheckEmailDomain of another domain = %v, want %v", err, oauth.ErrEmailDomainNotAllowed)
+	}
+	if err := factory.CheckEmailDomain("github", "ada@elsewhere.com"); err != nil {
+		t.Errorf("CheckEmailDomain of a provider without an allowlist = %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package oauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// providerScopes describes the scopes each provider is asked for
+type providerScopes struct {
+	// known are the scopes a login may request without AllowCustomScopes
+	known []string
+	// required are the scopes GetUserInfo depends on. Each group is met by
+	// any one of its scopes; when none is requested the first is added.
+	required [][]string
+}
+
+// scopeRules holds the scope rules of each provider. Scopes compare without
+// case, as Microsoft's do.
+var scopeRules = map[string]providerScopes{
+	"google": {
+		known: []string{
+			"openid", "email", "profile",
+			"https://www.googleapis.com/auth/userinfo.email",
+			"https://www.googleapis.com/auth/userinfo.profile",
+		},
+		required: [][]string{
+			{"https://www.googleapis.com/auth/userinfo.email", "email"},
+			{"https://www.googleapis.com/auth/userinfo.profile", "profile"},
+		},
+	},
+	"github": {
+		known:    []string{"read:user", "user:email"},
+		required: [][]string{{"user:email"}, {"read:user"}},
+	},
+	"facebook": {
+		known:    []string{"email", "public_profile"},
+		required: [][]string{{"email"}, {"public_profile"}},
+	},
+	"microsoft": {
+		known:    []string{"openid", "email", "profile", "offline_access", "User.Read"},
+		required: [][]string{{"User.Read"}},
+	},
+}
+
+// UnknownScopesError reports scopes outside a provider's known scopes
+type UnknownScopesError struct {
+	Provider string
+	Scopes   []string
+}
+
+func (e *UnknownScopesError) Error() string {
+	return fmt.Sprintf("unknown %s oauth scopes: %s", e.Provider, strings.Join(e.Scopes, ", "))
+}
+
+// KnownScopes returns the scopes the named provider may request without
+// AllowCustomScopes, or nil for a provider without scope rules
+func KnownScopes(provider string) []string {
+	return scopeRules[provider].known
+}
+
+// ValidateScopes checks the scopes requested from the named provider against
+// its known scopes. It returns the unknown ones, which are an
+// *UnknownScopesError unless allowCustom is set. Providers without scope
+// rules accept any scope.
+func ValidateScopes(provider string, scopes []string, allowCustom bool) ([]string, error) {
+	rules, ok := scopeRules[provider]
+	if !ok {
+		return nil, nil
+	}
+
+	var unknown []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope != "" && !containsScope(rules.known, scope) {
+			unknown = append(unknown, scope)
+		}
+	}
+	if len(unknown) > 0 && !allowCustom {
+		return unknown, &UnknownScopesError{Provider: provider, Scopes: unknown}
+	}
+	return unknown, nil
+}
+
+// EffectiveScopes returns the scopes a login requests: the given ones without
+// blanks and duplicates, plus the first scope of every required group none of
+// them meets
+func EffectiveScopes(provider string, scopes []string) []string {
+	effective := make([]string, 0, len(scopes)+2)
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope != "" && !containsScope(effective, scope) {
+			effective = append(effective, scope)
+		}
+	}
+	for _, group := range scopeRules[provider].required {
+		met := false
+		for _, scope := range group {
+			if containsScope(effective, scope) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			effective = append(effective, group[0])
+		}
+	}
+	return effective
+}
+
+// containsScope reports whether scopes holds scope, ignoring case
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if strings.EqualFold(s, scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package oauth_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestValidateScopes(t *testing.T) {
+	for _, tc := range []struct {
+		provider string
+		scopes   []string
+		unknown  []string
+	}{
+		{"google", []string{"openid", "email", "profile"}, nil},
+		{"google", []string{"https://www.googleapis.com/auth/userinfo.email", " EMAIL "}, nil},
+		{"google", []string{"email", "emial"}, []string{"emial"}},
+		{"google", []string{"https://www.googleapis.com/auth/drive"}, []string{"https://www.googleapis.com/auth/drive"}},
+		{"github", []string{"read:user", "user:email"}, nil},
+		{"github", []string{"user:email", "repo", "admin:org"}, []string{"repo", "admin:org"}},
+		{"facebook", []string{"email", "public_profile"}, nil},
+		{"facebook", []string{"email", "user_friends"}, []string{"user_friends"}},
+		{"microsoft", []string{"openid", "user.read", "offline_access"}, nil},
+		{"microsoft", []string{"User.Read", "Mail.Read"}, []string{"Mail.Read"}},
+		{"gitlab", []string{"anything"}, nil},
+		{"google", []string{"", "  "}, nil},
+	} {
+		t.Run(tc.provider+" "+strings.Join(tc.scopes, ","), func(t *testing.T) {
+			unknown, err := oauth.ValidateScopes(tc.provider, tc.scopes, false)
+			if strings.Join(unknown, ",") != strings.Join(tc.unknown, ",") {
+				t.Errorf("unknown = %v, want %v", unknown, tc.unknown)
+			}
+			var scopesErr *oauth.UnknownScopesError
+			switch {
+			case len(tc.unknown) == 0 && err != nil:
+				t.Errorf("err = %v, want none", err)
+			case len(tc.unknown) > 0 && (!errors.As(err, &scopesErr) || scopesErr.Provider != tc.provider):
+				t.Errorf("err = %v, want an UnknownScopesError for %s", err, tc.provider)
+			}
+
+			// Custom scopes are reported but allowed
+			unknown, err = oauth.ValidateScopes(tc.provider, tc.scopes, true)
+			if err != nil || strings.Join(unknown, ",") != strings.Join(tc.unknown, ",") {
+				t.Errorf("with custom scopes allowed: unknown = %v, err = %v, want %v and no error", unknown, err, tc.unknown)
+			}
+		})
+	}
+}
+
+func TestEffectiveScopes(t *testing.T) {
+	for _, tc := range []struct {
+		provider string
+		scopes   []string
+		want     []string
+	}{
+		{"google", nil, []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"}},
+		{"google", []string{"openid", "email", "profile"}, []string{"openid", "email", "profile"}},
+		{"google", []string{"email"}, []string{"email", "https://www.googleapis.com/auth/userinfo.profile"}},
+		{"github", []string{"user:email", " user:email", ""}, []string{"user:email", "read:user"}},
+		{"github", []string{"repo"}, []string{"repo", "user:email", "read:user"}},
+		{"facebook", []string{"public_profile"}, []string{"public_profile", "email"}},
+		{"microsoft", []string{"openid", "user.read"}, []string{"openid", "user.read"}},
+		{"microsoft", nil, []string{"User.Read"}},
+		{"gitlab", []string{"read_user"}, []string{"read_user"}},
+	} {
+		t.Run(tc.provider+" "+strings.Join(tc.scopes, ","), func(t *testing.T) {
+			if got := oauth.EffectiveScopes(tc.provider, tc.scopes); strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("EffectiveScopes = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// requestedScopes returns the scopes the provider's authorization URL asks for
+func requestedScopes(t *testing.T, provider oauth.Provider) string {
+	t.Helper()
+	u, err := url.Parse(provider.GetAuthURL("state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("scope")
+}
+
+func TestProviderFactoryScopes(t *testing.T) {
+	logs := testutil.CaptureKlog(t)
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"github": {ClientID: "id", ClientSecret: "secret", Scopes: []string{"user:email", "repo"}},
+		"google": {ClientID: "id", ClientSecret: "secret", Scopes: []string{"openid", "https://www.googleapis.com/auth/drive"}, AllowCustomScopes: true},
+	}, oauth.ClientOptions{})
+
+	github, err := factory.GetProvider("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requestedScopes(t, github); got != "user:email read:user" {
+		t.Errorf("github requests %q, want the unknown scope dropped and read:user added", got)
+	}
+	google, err := factory.GetProvider("google")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "openid https://www.googleapis.com/auth/drive https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/userinfo.profile"
+	if got := requestedScopes(t, google); got != want {
+		t.Errorf("google requests %q, want the custom scope kept and the required ones added", got)
+	}
+	if out := logs.String(); !strings.Contains(out, "unknown github oauth scopes: repo") || !strings.Contains(out, "requests custom scopes: https://www.googleapis.com/auth/drive") {
+		t.Errorf("the startup warnings do not name the unknown scopes:\n%s", out)
+	}
+
+	// Overrides fall back to the global scopes and are held to the same rules
+	if got := factory.Scopes("github", nil); strings.Join(got, " ") != "user:email read:user" {
+		t.Errorf("Scopes without an override = %v, want the global ones", got)
+	}
+	if got := factory.Scopes("github", []string{"read:user", "gist"}); strings.Join(got, " ") != "read:user user:email" {
+		t.Errorf("Scopes with an override = %v, want the unknown scope dropped and user:email added", got)
+	}
+	override, err := factory.GetProviderWithOverride("github", &oauth.ProviderConfig{ClientID: "project", ClientSecret: "secret", Scopes: []string{"read:user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requestedScopes(t, override); got != "read:user user:email" {
+		t.Errorf("the project's github provider requests %q, want read:user user:email", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 	
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
@@ -24,9 +25,16 @@ const (
 	OAuthStateKey = "oauth_state"
 )
 
+// DefaultRememberFor is how long a "remember me" session lasts unless the
+// session manager is given another duration
+const DefaultRememberFor = 30 * 24 * time.Hour
+
 type SessionManager struct {
 	store     sessions.Store
 	userStore UserStore
+
+	// rememberFor is the max age of the cookie of a "remember me" login
+	rememberFor time.Duration
 }
 
 type UserStore interface {
@@ -37,10 +45,20 @@ type UserStore interface {
 	Update(ctx context.Context, user *schemas.User) error
 }
 
-func NewSessionManager(secret []byte, userStore UserStore) *SessionManager {
+// NewSessionManager creates a session manager whose "remember me" sessions
+// last rememberFor, or DefaultRememberFor when it is not positive. With
+// secureCookies the session cookie is only sent over HTTPS.
+func NewSessionManager(secret []byte, userStore UserStore, rememberFor time.Duration, secureCookies bool) *SessionManager {
+	if rememberFor <= 0 {
+		rememberFor = DefaultRememberFor
+	}
+	store := sessions.NewCookieStore(secret)
+	store.Options.HttpOnly = true
+	store.Options.Secure = secureCookies
 	return &SessionManager{
-		store:     sessions.NewCookieStore(secret),
-		userStore: userStore,
+		store:       store,
+		userStore:   userStore,
+		rememberFor: rememberFor,
 	}
 }
 
@@ -48,13 +66,21 @@ func (sm *SessionManager) GetSession(r *http.Request) (*sessions.Session, error)
 	return sm.store.Get(r, SessionName)
 }
 
-func (sm *SessionManager) Login(ctx context.Context, w http.ResponseWriter, r *http.Request, user *schemas.User) error {
+// Login logs a user in. With remember the session cookie outlives the
+// browser session for the configured duration; otherwise it is a session
+// cookie, dropped when the browser closes.
+func (sm *SessionManager) Login(ctx context.Context, w http.ResponseWriter, r *http.Request, user *schemas.User, remember bool) error {
 	session, err := sm.GetSession(r)
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 	
 	session.Values[UserIDKey] = user.ID.String()
+	if remember {
+		session.Options.MaxAge = int(sm.rememberFor / time.Second)
+	} else {
+		session.Options.MaxAge = 0
+	}
 	
 	return session.Save(r, w)
 }
@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// login logs user in with the session manager and returns the session cookie
+// it set
+func login(t *testing.T, sm *auth.SessionManager, user *schemas.User, remember bool) *http.Cookie {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	if err := sm.Login(context.Background(), recorder, httptest.NewRequest(http.MethodPost, "/login", nil), user, remember); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == auth.SessionName {
+			return cookie
+		}
+	}
+	t.Fatal("Login set no session cookie")
+	return nil
+}
+
+func TestLoginCookieMaxAge(t *testing.T) {
+	user := &schemas.User{ID: uuid.New()}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	t.Run("remembered for the default duration", func(t *testing.T) {
+		cookie := login(t, auth.NewSessionManager(secret, nil, 0, false), user, true)
+		if want := int(auth.DefaultRememberFor / time.Second); cookie.MaxAge != want {
+			t.Errorf("MaxAge = %d, want %d", cookie.MaxAge, want)
+		}
+		if !cookie.HttpOnly || cookie.Secure {
+			t.Errorf("cookie is HttpOnly %v and Secure %v, want HttpOnly only", cookie.HttpOnly, cookie.Secure)
+		}
+	})
+
+	t.Run("remembered for a configured duration", func(t *testing.T) {
+		cookie := login(t, auth.NewSessionManager(secret, nil, 7*24*time.Hour, true), user, true)
+		if cookie.MaxAge != 7*24*60*60 {
+			t.Errorf("MaxAge = %d, want a week", cookie.MaxAge)
+		}
+		if !cookie.Secure {
+			t.Error("the cookie of a manager with secure cookies is not Secure")
+		}
+	})
+
+	t.Run("a browser session", func(t *testing.T) {
+		sm := auth.NewSessionManager(secret, nil, 0, false)
+		// A remembered login must not lengthen the next one's cookie
+		login(t, sm, user, true)
+		cookie := login(t, sm, user, false)
+		if cookie.MaxAge != 0 || !cookie.Expires.IsZero() {
+			t.Errorf("MaxAge = %d and Expires = %v, want a session cookie with neither", cookie.MaxAge, cookie.Expires)
+		}
+	})
+}
+
+func TestLogoutExpiresTheCookie(t *testing.T) {
+	sm := auth.NewSessionManager([]byte("0123456789abcdef0123456789abcdef"), nil, 0, false)
+	cookie := login(t, sm, &schemas.User{ID: uuid.New()}, true)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookie)
+	recorder := httptest.NewRecorder()
+	if err := sm.Logout(recorder, req); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Fatalf("Logout set cookies %+v, want the session cookie expired", cookies)
+	}
+}
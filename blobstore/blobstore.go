@@ -0,0 +1,82 @@
+// Package blobstore keeps the files the service produces and receives:
+// uploaded import files, import error reports and export artifacts. Stores
+// are addressed by slash-separated keys such as "imports/<id>.csv"; a local
+// directory and S3-compatible object storage are supported.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// ErrSignedURLNotSupported is returned by stores whose blobs cannot be
+// fetched by clients directly; their blobs are streamed through the API
+var ErrSignedURLNotSupported = errors.New("signed URLs are not supported by this storage backend")
+
+// Store keeps blobs by key
+type Store interface {
+	// Put stores the content read from r under key, replacing any blob
+	// stored there. The blob only becomes visible once it is complete.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob stored under key, or returns ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that fetches the blob without credentials
+	// until ttl has passed, or ErrSignedURLNotSupported
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Options selects and configures the storage backend
+type Options struct {
+	Backend string // BackendLocal (the default) or BackendS3
+	Local   LocalOptions
+	S3      S3Options
+}
+
+// New creates the store the options select
+func New(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", BackendLocal:
+		return NewLocal(opts.Local)
+	case BackendS3:
+		return NewS3(opts.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, must be %s or %s", opts.Backend, BackendLocal, BackendS3)
+	}
+}
+
+// ValidateKey checks that a key is a relative slash-separated path without
+// empty, "." or ".." segments, so that no backend can resolve it outside of
+// its root
+func ValidateKey(key string) error {
+	if key == "" {
+		return errors.New("blob key must not be empty")
+	}
+	if len(key) > 255 {
+		return fmt.Errorf("blob key %q is longer than 255 characters", key)
+	}
+	if strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("blob key %q contains an invalid character", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("blob key %q is not a clean relative path", key)
+		}
+	}
+	return nil
+}
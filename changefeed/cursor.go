@@ -0,0 +1,82 @@
+package changefeed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the
+// project by this service
+var ErrInvalidCursor = apierrors.BadRequest("INVALID_CURSOR", "cursor is malformed or was not issued for this project")
+
+// position is where a client is in a project's change feed: just past the
+// change of user ID at UpdatedAt. The zero position is before every change.
+type position struct {
+	ProjectID uuid.UUID `json:"p"`
+	UpdatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// encodeCursor turns a position into an opaque cursor: its JSON and an
+// HMAC-SHA256 of it, both base64url encoded and joined by a dot
+func encodeCursor(key []byte, pos position) string {
+	payload, _ := json.Marshal(pos)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(key, payload))
+}
+
+// decodeCursor checks a cursor's signature and that it belongs to the
+// project. The empty cursor is the zero position.
+func decodeCursor(key []byte, projectID uuid.UUID, cursor string) (position, error) {
+	if cursor == "" {
+		return position{ProjectID: projectID}, nil
+	}
+
+	encodedPayload, encodedMAC, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return position{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return position{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, sign(key, payload)) {
+		return position{}, ErrInvalidCursor
+	}
+
+	var pos position
+	if err := json.Unmarshal(payload, &pos); err != nil || pos.ProjectID != projectID {
+		return position{}, ErrInvalidCursor
+	}
+	return pos, nil
+}
+
+func sign(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// ParseCursorKey decodes a base64 cursor key of at least 32 bytes. The empty
+// string yields a nil key, for which NewManager picks a random one.
+func ParseCursorKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("cursor key must be base64 encoded")
+	}
+	if len(key) < 32 {
+		return nil, errors.New("cursor key must be at least 32 bytes")
+	}
+	return key, nil
+}
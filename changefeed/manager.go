@@ -0,0 +1,277 @@
+// Package changefeed serves a project's user changes as a feed that clients,
+// such as tenant backends keeping a local copy of their users, read from a
+// cursor onwards. Reads long-poll: a read finding nothing new waits until a
+// change is published or its wait runs out.
+package changefeed
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("changefeed")
+
+// Defaults for the options of the change feed
+const (
+	DefaultMaxWait      = time.Minute
+	DefaultSettle       = time.Second
+	DefaultPollInterval = 2 * time.Second
+)
+
+// Page sizes of a read
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Change types
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted" // A tombstone: the user was soft-deleted
+)
+
+// Change is one user's latest change. A user changed several times since
+// the cursor appears once, with its latest change.
+type Change struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version orders the changes of one user: a change with a higher
+	// version supersedes one with a lower
+	Version int64 `json:"version"`
+}
+
+// Page is the result of a read: the changes after the cursor, oldest first,
+// and the cursor to read on from
+type Page struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"` // More changes can be read at once
+}
+
+// ChangeFeed defines the interface for reading user changes. It is a
+// webhooks.Publisher so that published user events wake waiting reads.
+type ChangeFeed interface {
+	// Changes returns up to limit changes of the project's users after
+	// since, the cursor of a previous page or "" to start from the first
+	// user. When there are none it waits up to wait for one.
+	Changes(ctx context.Context, projectID uuid.UUID, since string, wait time.Duration, limit int) (*Page, error)
+	Publish(ctx context.Context, projectID uuid.UUID, event string, data interface{})
+}
+
+// Options configures the change feed
+type Options struct {
+	CursorKey []byte        // Signs cursors; must be the same on every instance
+	MaxWait   time.Duration // Longest wait a read may ask for
+	// Settle is how old a change must be before it is read. Changes are
+	// stamped before their transaction commits, so a change stamped earlier
+	// can become visible after a later one; holding back the newest changes
+	// keeps a cursor from moving past one that has yet to commit.
+	Settle time.Duration
+	// PollInterval is how often a waiting read looks for changes that were
+	// not published to this instance, e.g. made by another one
+	PollInterval time.Duration
+}
+
+// Manager implements the ChangeFeed interface
+type Manager struct {
+	DB      *gorm.DB
+	Regions *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Clock   clock.Clock
+	Options Options
+
+	mu      sync.Mutex
+	waiting map[uuid.UUID]chan struct{} // Closed when the project's users change
+}
+
+// NewManager creates a new change feed
+func NewManager(db *gorm.DB, regions *regions.Resolver, opts Options) ChangeFeed {
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = DefaultMaxWait
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultSettle
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if len(opts.CursorKey) == 0 {
+		log.Warningf("No change feed cursor key is configured; cursors will not survive a restart or work across instances")
+		opts.CursorKey = make([]byte, 32)
+		rand.Read(opts.CursorKey)
+	}
+	return &Manager{
+		DB:      db,
+		Regions: regions,
+		Clock:   clock.Real{},
+		Options: opts,
+		waiting: make(map[uuid.UUID]chan struct{}),
+	}
+}
+
+// Changes reads the changes after since, long-polling while there are none
+func (m *Manager) Changes(ctx context.Context, projectID uuid.UUID, since string, wait time.Duration, limit int) (*Page, error) {
+	pos, err := decodeCursor(m.Options.CursorKey, projectID, since)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if wait > m.Options.MaxWait {
+		wait = m.Options.MaxWait
+	}
+
+	var project schemas.Project
+	if err := m.DB.Select("id", "region").First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, projects.ErrProjectNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		// Subscribe before reading, so a change published during the read
+		// is not missed
+		changed := m.subscribe(projectID)
+		page, pending, err := m.read(ctx, m.Regions.For(m.DB, project.Region), projectID, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		left := time.Until(deadline)
+		if len(page.Changes) > 0 || left <= 0 {
+			return page, nil
+		}
+
+		// Look again once the pending change settles, or after the poll
+		// interval for changes made elsewhere
+		next := m.Options.PollInterval
+		if pending {
+			next = m.Options.Settle
+		}
+		if next > left {
+			next = left
+		}
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-changed:
+			// Give the published change time to settle
+			timer.Stop()
+			timer = time.NewTimer(min(m.Options.Settle, left))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// read loads the settled changes after pos from users, the database holding
+// the project's users table. It also reports whether there are changes yet
+// to settle.
+func (m *Manager) read(ctx context.Context, users *gorm.DB, projectID uuid.UUID, pos position, limit int) (*Page, bool, error) {
+	settled := m.Clock.Now().Add(-m.Options.Settle)
+	after := func(q *gorm.DB) *gorm.DB {
+		if pos.UpdatedAt.IsZero() {
+			return q
+		}
+		return q.Where("updated_at > ? OR (updated_at = ? AND id > ?)", pos.UpdatedAt, pos.UpdatedAt, pos.ID)
+	}
+
+	var changed []schemas.ProjectUser
+	table := projecttable.Users(projectID)
+	if err := after(users.Table(table).Unscoped().Select("id", "created_at", "updated_at", "deleted_at")).
+		Where("updated_at <= ?", settled).
+		Order("updated_at, id").
+		Limit(limit + 1).
+		Find(&changed).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, false, errors.New("internal server error")
+	}
+
+	page := &Page{Changes: []Change{}}
+	if len(changed) > limit {
+		changed = changed[:limit]
+		page.HasMore = true
+	}
+	for _, user := range changed {
+		change := Change{
+			ID:        user.ID,
+			Type:      ChangeUpdated,
+			UpdatedAt: user.UpdatedAt,
+			Version:   user.UpdatedAt.UnixMicro(),
+		}
+		switch {
+		case user.DeletedAt.Valid:
+			change.Type = ChangeDeleted
+		case user.CreatedAt.Equal(user.UpdatedAt):
+			change.Type = ChangeCreated
+		}
+		page.Changes = append(page.Changes, change)
+		pos.UpdatedAt, pos.ID = user.UpdatedAt, user.ID
+	}
+	page.Cursor = encodeCursor(m.Options.CursorKey, pos)
+
+	pending := false
+	if len(changed) == 0 {
+		var count int64
+		if err := after(users.Table(table).Unscoped().Model(&schemas.ProjectUser{})).
+			Where("updated_at > ?", settled).
+			Limit(1).Count(&count).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+			return nil, false, errors.New("internal server error")
+		}
+		pending = count > 0
+	}
+	return page, pending, nil
+}
+
+// subscribe returns a channel that is closed on the next change of the
+// project's users
+func (m *Manager) subscribe(projectID uuid.UUID) <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch, ok := m.waiting[projectID]
+	if !ok {
+		ch = make(chan struct{})
+		m.waiting[projectID] = ch
+	}
+	return ch
+}
+
+// Publish wakes the reads waiting for changes of the project's users. It
+// implements webhooks.Publisher; every event the service publishes is about
+// a project user.
+func (m *Manager) Publish(_ context.Context, projectID uuid.UUID, _ string, _ interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok := m.waiting[projectID]; ok {
+		close(ch)
+		delete(m.waiting, projectID)
+	}
+}
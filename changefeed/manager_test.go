@@ -0,0 +1,207 @@
+package changefeed_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+// fixture is a change feed over a project with three users, created in the
+// order a, b, c
+type fixture struct {
+	db      *gorm.DB
+	feed    changefeed.ChangeFeed
+	project uuid.UUID
+	users   map[string]schemas.ProjectUser
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithUser("a@example.com").Build(t, db)
+	f := &fixture{db: db, project: built.Project.ID, users: map[string]schemas.ProjectUser{"a": built.Users["a@example.com"]}}
+	// The builder stamps every user alike; give each its own time
+	for i, name := range []string{"a", "b", "c"} {
+		at := time.Now().Add(time.Duration(i-10) * time.Second)
+		if name != "a" {
+			user := f.users["a"]
+			user.ID, user.Email = uuid.New(), name+"@example.com"
+			if err := db.Table(testutil.ProjectUserTable(f.project)).Create(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			f.users[name] = user
+		}
+		f.set(t, name, map[string]interface{}{"created_at": at, "updated_at": at})
+	}
+	f.feed = changefeed.NewManager(db, nil, changefeed.Options{
+		CursorKey:    []byte("test cursor key"),
+		Settle:       time.Nanosecond,
+		PollInterval: time.Minute,
+	})
+	return f
+}
+
+// set updates columns of the named user
+func (f *fixture) set(t *testing.T, name string, columns map[string]interface{}) {
+	t.Helper()
+	if err := f.db.Table(testutil.ProjectUserTable(f.project)).Where("id = ?", f.users[name].ID).Updates(columns).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// read returns the changes after since without waiting
+func (f *fixture) read(t *testing.T, since string, limit int) *changefeed.Page {
+	t.Helper()
+	page, err := f.feed.Changes(context.Background(), f.project, since, 0, limit)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	return page
+}
+
+// is checks that the page holds the named users' changes of the given types
+func (f *fixture) is(t *testing.T, page *changefeed.Page, want ...string) {
+	t.Helper()
+	if len(page.Changes) != len(want)/2 {
+		t.Fatalf("changes = %+v, want %v", page.Changes, want)
+	}
+	for i, change := range page.Changes {
+		if change.ID != f.users[want[2*i]].ID || change.Type != want[2*i+1] {
+			t.Errorf("change %d = %+v, want %s %s", i, change, want[2*i], want[2*i+1])
+		}
+		if i > 0 && change.Version <= page.Changes[i-1].Version {
+			t.Errorf("change %d has version %d, not after %d", i, change.Version, page.Changes[i-1].Version)
+		}
+	}
+}
+
+func TestChangesResumeWithoutGaps(t *testing.T) {
+	f := newFixture(t)
+
+	first := f.read(t, "", 2)
+	f.is(t, first, "a", changefeed.ChangeCreated, "b", changefeed.ChangeCreated)
+	if !first.HasMore {
+		t.Error("the first page does not report more changes")
+	}
+	second := f.read(t, first.Cursor, 2)
+	f.is(t, second, "c", changefeed.ChangeCreated)
+	if second.HasMore {
+		t.Error("the last page reports more changes")
+	}
+	empty := f.read(t, second.Cursor, 2)
+	f.is(t, empty)
+	if empty.Cursor != second.Cursor {
+		t.Error("reading nothing moved the cursor")
+	}
+
+	// a is updated and b deleted; c stays as it was
+	now := time.Now()
+	f.set(t, "a", map[string]interface{}{"first_name": "Ada", "updated_at": now.Add(-2 * time.Second)})
+	f.set(t, "b", map[string]interface{}{"deleted_at": now.Add(-time.Second), "updated_at": now.Add(-time.Second)})
+	third := f.read(t, empty.Cursor, 10)
+	f.is(t, third, "a", changefeed.ChangeUpdated, "b", changefeed.ChangeDeleted)
+	if third.Changes[0].Version <= first.Changes[0].Version {
+		t.Errorf("a's update has version %d, not after its creation's %d", third.Changes[0].Version, first.Changes[0].Version)
+	}
+
+	// Reading from the start again sees each user once, with its latest change
+	f.is(t, f.read(t, "", 10), "c", changefeed.ChangeCreated, "a", changefeed.ChangeUpdated, "b", changefeed.ChangeDeleted)
+}
+
+func TestChangesLongPoll(t *testing.T) {
+	f := newFixture(t)
+	cursor := f.read(t, "", 10).Cursor
+	ctx := context.Background()
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		page, err := f.feed.Changes(ctx, f.project, cursor, 200*time.Millisecond, 10)
+		if err != nil {
+			t.Fatalf("Changes: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 5*time.Second {
+			t.Errorf("an empty read returned after %s, want the 200ms wait", elapsed)
+		}
+		f.is(t, page)
+		if page.Cursor != cursor {
+			t.Error("the timed out read moved the cursor")
+		}
+	})
+
+	t.Run("wakeup", func(t *testing.T) {
+		done := make(chan *changefeed.Page)
+		go func() {
+			page, err := f.feed.Changes(ctx, f.project, cursor, 30*time.Second, 10)
+			if err != nil {
+				t.Errorf("Changes: %v", err)
+			}
+			done <- page
+		}()
+
+		// The poll interval is a minute, so only the publish can wake the read
+		time.Sleep(50 * time.Millisecond)
+		f.set(t, "c", map[string]interface{}{"first_name": "Cy", "updated_at": time.Now()})
+		f.feed.Publish(ctx, f.project, "user.updated", nil)
+		select {
+		case page := <-done:
+			if page != nil {
+				f.is(t, page, "c", changefeed.ChangeUpdated)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the published change did not wake the waiting read")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		errs := make(chan error)
+		go func() {
+			_, err := f.feed.Changes(ctx, f.project, f.read(t, "", 10).Cursor, 30*time.Second, 10)
+			errs <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the read kept waiting after its caller went away")
+		}
+	})
+}
+
+func TestChangesRejects(t *testing.T) {
+	f := newFixture(t)
+	other := newFixture(t)
+	cursor := f.read(t, "", 1).Cursor
+	ctx := context.Background()
+	foreign, err := changefeed.NewManager(f.db, nil, changefeed.Options{CursorKey: []byte("other key")}).Changes(ctx, f.project, "", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, since := range map[string]string{
+		"a malformed cursor":             "not-a-cursor",
+		"a tampered cursor":              "x" + cursor,
+		"another project's cursor":       other.read(t, "", 1).Cursor,
+		"a cursor signed by another key": foreign.Cursor,
+	} {
+		if _, err := f.feed.Changes(ctx, f.project, since, 0, 10); !errors.Is(err, changefeed.ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want %v", name, err, changefeed.ErrInvalidCursor)
+		}
+	}
+	if _, err := f.feed.Changes(ctx, uuid.New(), "", 0, 10); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Errorf("an unknown project: err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+}
@@ -0,0 +1,29 @@
+package changefeed_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestChangesReadsTheTableInTheProjectRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	built := testutil.AProject().InRegion("eu", databases["eu"]).WithUser("a@example.com").Build(t, db)
+
+	feed := changefeed.NewManager(db, resolver, changefeed.Options{
+		CursorKey: []byte("test cursor key"),
+		Settle:    time.Nanosecond,
+	})
+	time.Sleep(time.Millisecond)
+	page, err := feed.Changes(context.Background(), built.Project.ID, "", 0, 10)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(page.Changes) != 1 || page.Changes[0].ID != built.Users["a@example.com"].ID {
+		t.Fatalf("changes = %+v, want the regional user", page.Changes)
+	}
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Authorize asks whether the user req.UserID of the project req.ProjectID
+// may perform req.Action on req.Resource. The call authenticates with the
+// project API token req.APIToken, or the client's token when it is empty.
+func (c *Client) Authorize(ctx context.Context, req AuthorizeRequest) (*AuthorizeResponse, error) {
+	client := c
+	if req.APIToken != "" {
+		client = c.WithToken(req.APIToken)
+	}
+	var resp AuthorizeResponse
+	if err := client.do(ctx, http.MethodPost, apiPath(req.ProjectID, "authorize"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// IntrospectBatch tells whether each of req.Tokens is active, and whom the
+// active ones were issued to
+func (c *Client) IntrospectBatch(ctx context.Context, req IntrospectBatchRequest) (*IntrospectBatchResponse, error) {
+	var resp IntrospectBatchResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("auth", "introspect-batch"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
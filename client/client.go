@@ -0,0 +1,223 @@
+// Package client calls the user management service from Go. Client has a
+// method for each supported endpoint, taking and returning the service's own
+// request and response types, re-exported in types.go, so that the two
+// cannot drift apart.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the client options
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultRetries      = 3
+	DefaultRetryBackoff = 500 * time.Millisecond
+	// MaxRetryWait caps the wait before a retry, whatever Retry-After asks
+	MaxRetryWait = 30 * time.Second
+)
+
+// apiPrefix is the path the versioned API is served under
+const apiPrefix = "/api/v1"
+
+// Options configures a Client
+type Options struct {
+	// Token is sent as the bearer token of every request: a login token, or
+	// a project API token for the machine-facing endpoints. Empty sends none.
+	Token string
+	// HTTPClient makes the calls; nil for one timing calls out after
+	// DefaultTimeout
+	HTTPClient *http.Client
+	// Retries is how many times a request answered with 429 Too Many
+	// Requests or 503 Service Unavailable is repeated; negative for never.
+	// Both mean the service turned the request away unprocessed.
+	Retries int
+	// RetryBackoff is the wait before the first retry, doubled for every
+	// further one. A Retry-After header on the response takes precedence.
+	RetryBackoff time.Duration
+}
+
+// Client calls the service at BaseURL. It is safe for concurrent use.
+type Client struct {
+	BaseURL string
+	opts    Options
+}
+
+// New creates a client for the service at baseURL, e.g.
+// https://ums.example.com
+func New(baseURL string, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	} else if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), opts: opts}
+}
+
+// WithToken returns a copy of the client sending token as the bearer token,
+// e.g. the one a Login returned
+func (c *Client) WithToken(token string) *Client {
+	clone := *c
+	clone.opts.Token = token
+	return &clone
+}
+
+// Error is an error answered by the service, decoded from its standard
+// error body
+type Error struct {
+	StatusCode int
+	Code       string // Machine-readable code, e.g. NOT_FOUND; empty for errors the service did not classify
+	Message    string
+	Field      string // JSON path of the request field at fault, when there is one
+	RequestID  string // Only on INTERNAL errors, for quoting in reports
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// errorBody is the body of the service's error responses
+type errorBody struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Field     string `json:"field"`
+	RequestID string `json:"request_id"`
+}
+
+// decodeError reads the error a response carries. Responses the service
+// writes without the standard body, such as those of its authentication
+// middleware, keep their text as the message.
+func decodeError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	var body errorBody
+	if err := json.Unmarshal(raw, &body); err == nil && body.Error != "" {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Error
+		apiErr.Field = body.Field
+		apiErr.RequestID = body.RequestID
+		return apiErr
+	}
+	apiErr.Message = strings.TrimSpace(string(raw))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// do sends a request to the API at path, with query parameters and in as
+// its JSON body when set, and decodes the response body into out when it is
+// set. Requests turned away with 429 or 503 are retried.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encoding the request: %w", err)
+		}
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		} else {
+			req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+		}
+		req.Header.Set("Accept", "application/json")
+		if c.opts.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.opts.Token)
+		}
+
+		resp, err := c.opts.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < c.opts.Retries {
+			wait := retryAfter(resp.Header.Get("Retry-After"), backoff)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+			continue
+		}
+		return readResponse(resp, out)
+	}
+}
+
+// readResponse decodes a successful response into out, or returns the error
+// a failed one carries
+func readResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding the response: %w", err)
+	}
+	return nil
+}
+
+// retryAfter is how long to wait before retrying: what the Retry-After
+// header asks, in seconds or as a date, or fallback without one, at most
+// MaxRetryWait
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	wait := fallback
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = max(time.Until(at), 0)
+	}
+	return min(wait, MaxRetryWait)
+}
+
+// apiPath escapes the parts of a path, each a path segment, and joins
+// them under the API prefix
+func apiPath(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = url.PathEscape(part)
+	}
+	return apiPrefix + "/" + strings.Join(escaped, "/")
+}
+
+// IsNotFound reports whether err is a 404 answered by the service
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
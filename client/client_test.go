@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/client"
+)
+
+func TestRequestsTurnedAwayAreRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the client's token", r.Header.Get("Authorization"))
+		}
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"projects": [{"id": "p1"}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	api := client.New(server.URL, client.Options{Token: "secret", RetryBackoff: time.Millisecond})
+	listed, err := api.ListProjects(context.Background(), client.ListProjectsRequest{})
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(listed.Projects) != 1 || listed.Projects[0].ID != "p1" || calls.Load() != 3 {
+		t.Errorf("projects = %+v after %d calls, want p1 after 3", listed.Projects, calls.Load())
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "slow down", "code": "RATE_LIMITED"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	api := client.New(server.URL, client.Options{Retries: 2, RetryBackoff: time.Millisecond})
+	_, err := api.ListProjects(context.Background(), client.ListProjectsRequest{})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Code != "RATE_LIMITED" || apiErr.Message != "slow down" {
+		t.Errorf("err = %v, want the decoded 429", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("called %d times, want the request and 2 retries", calls.Load())
+	}
+
+	calls.Store(0)
+	api = client.New(server.URL, client.Options{Retries: -1})
+	if _, err := api.ListProjects(context.Background(), client.ListProjectsRequest{}); err == nil || calls.Load() != 1 {
+		t.Errorf("without retries: err = %v after %d calls, want the 429 after 1", err, calls.Load())
+	}
+
+	// A Retry-After longer than the context allows ends the wait
+	api = client.New(server.URL, client.Options{RetryBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := api.ListProjects(ctx, client.ListProjectsRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestErrorsWithoutTheStandardBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/projects/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	api := client.New(server.URL, client.Options{})
+
+	_, err := api.ListProjects(context.Background(), client.ListProjectsRequest{})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "" || apiErr.Message != "Unauthorized" {
+		t.Errorf("err = %#v, want a 401 keeping the body as its message", err)
+	}
+	_, err = api.GetProject(context.Background(), client.GetProjectRequest{ID: "missing"})
+	if !client.IsNotFound(err) || !errors.As(err, &apiErr) || apiErr.Message != "Not Found" {
+		t.Errorf("err = %v, want a 404 with the status text", err)
+	}
+	if _, err := api.DeleteProject(context.Background(), client.DeleteProjectRequest{ID: "p1", Export: "download"}); err == nil {
+		t.Error("DeleteProject exporting a download succeeded")
+	}
+}
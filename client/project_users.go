@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateProjectUser creates a user in the project req.ProjectID with the
+// role req.RoleID
+func (c *Client) CreateProjectUser(ctx context.Context, req CreateProjectUserRequest) (*CreateProjectUserResponse, error) {
+	var resp CreateProjectUserResponse
+	if err := c.do(ctx, http.MethodPost, apiPath(req.ProjectID, "users", req.RoleID), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetProjectUser gets the user req.UserID of the project req.ProjectID
+func (c *Client) GetProjectUser(ctx context.Context, req GetProjectUserRequest) (*GetProjectUserResponse, error) {
+	var resp GetProjectUserResponse
+	if err := c.do(ctx, http.MethodGet, apiPath(req.ProjectID, "users", req.UserID), includeDeleted(req.IncludeDeleted), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListProjectUsers lists the users of the project req.ProjectID
+func (c *Client) ListProjectUsers(ctx context.Context, req ListProjectUsersRequest) (*ListProjectUsersResponse, error) {
+	query := includeDeleted(req.IncludeDeleted)
+	if req.Status != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("status", req.Status)
+	}
+	var resp ListProjectUsersResponse
+	if err := c.do(ctx, http.MethodGet, apiPath(req.ProjectID, "users"), sortQuery(query, req.Sort), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateProjectUser updates the user req.UserID of the project req.ProjectID
+func (c *Client) UpdateProjectUser(ctx context.Context, req UpdateProjectUserRequest) (*UpdateProjectUserResponse, error) {
+	var resp UpdateProjectUserResponse
+	if err := c.do(ctx, http.MethodPut, apiPath(req.ProjectID, "users", req.UserID), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetProjectUserStatus moves the user req.UserID of the project
+// req.ProjectID to req.Status
+func (c *Client) SetProjectUserStatus(ctx context.Context, req SetProjectUserStatusRequest) (*SetProjectUserStatusResponse, error) {
+	var resp SetProjectUserStatusResponse
+	if err := c.do(ctx, http.MethodPut, apiPath(req.ProjectID, "users", req.UserID, "status"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteProjectUser deletes the user req.UserID of the project req.ProjectID
+func (c *Client) DeleteProjectUser(ctx context.Context, req DeleteProjectUserRequest) (*DeleteProjectUserResponse, error) {
+	var resp DeleteProjectUserResponse
+	if err := c.do(ctx, http.MethodDelete, apiPath(req.ProjectID, "users", req.UserID), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RestoreProjectUser restores the deleted user req.UserID of the project
+// req.ProjectID
+func (c *Client) RestoreProjectUser(ctx context.Context, req RestoreProjectUserRequest) (*RestoreProjectUserResponse, error) {
+	var resp RestoreProjectUserResponse
+	if err := c.do(ctx, http.MethodPost, apiPath(req.ProjectID, "users", req.UserID, "restore"), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
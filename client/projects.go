@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// errDownloadExport is returned for deletions exporting the users as the
+// response body, which the client does not read
+var errDownloadExport = errors.New("client: download exports are not supported; export to a backup instead")
+
+// Login logs in with an email and password. Calls made with the returned
+// token go through WithToken.
+func (c *Client) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
+	var resp LoginResponse
+	if err := c.do(ctx, http.MethodPost, "/auth/login", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateProject creates a project
+func (c *Client) CreateProject(ctx context.Context, req CreateProjectRequest) (*CreateProjectResponse, error) {
+	var resp CreateProjectResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("projects"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetProject gets the project req.ID
+func (c *Client) GetProject(ctx context.Context, req GetProjectRequest) (*GetProjectResponse, error) {
+	var resp GetProjectResponse
+	if err := c.do(ctx, http.MethodGet, apiPath("projects", req.ID), includeDeleted(req.IncludeDeleted), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListProjects lists the projects
+func (c *Client) ListProjects(ctx context.Context, req ListProjectsRequest) (*ListProjectsResponse, error) {
+	var resp ListProjectsResponse
+	if err := c.do(ctx, http.MethodGet, apiPath("projects"), sortQuery(nil, req.Sort), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateProject updates the name and description of the project req.ID
+func (c *Client) UpdateProject(ctx context.Context, req UpdateProjectRequest) (*UpdateProjectResponse, error) {
+	var resp UpdateProjectResponse
+	if err := c.do(ctx, http.MethodPut, apiPath("projects", req.ID), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteProject deletes the project req.ID, with its users exported to a
+// backup first when req.Export is DeleteExportBackup
+func (c *Client) DeleteProject(ctx context.Context, req DeleteProjectRequest) (*DeleteProjectResponse, error) {
+	if req.Export == endpoints.DeleteExportDownload {
+		return nil, errDownloadExport
+	}
+	var query url.Values
+	if req.Export != "" {
+		query = url.Values{"export": {req.Export}}
+	}
+	var resp DeleteProjectResponse
+	if err := c.do(ctx, http.MethodDelete, apiPath("projects", req.ID), query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// includeDeleted returns the include_deleted query parameter when set
+func includeDeleted(set bool) url.Values {
+	if !set {
+		return nil
+	}
+	return url.Values{"include_deleted": {strconv.FormatBool(set)}}
+}
+
+// sortQuery adds the sort query parameter of order to query, unless order is
+// the default
+func sortQuery(query url.Values, order SortOrder) url.Values {
+	if order.Field == "" {
+		return query
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	value := order.Field
+	if order.Desc {
+		value = "-" + value
+	}
+	query.Set("sort", value)
+	return query
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// Resources, as the service returns them
+type (
+	Project         = endpoints.Project
+	ProjectSettings = schemas.ProjectSettings
+	User            = models.DisplayUser
+	Policy          = endpoints.Policy
+)
+
+// SortOrder is the order a list is returned in; the zero SortOrder is the
+// list's default
+type SortOrder = sorting.Order
+
+// Requests and responses of the endpoints the client calls
+type (
+	LoginRequest  = endpoints.LoginRequest
+	LoginResponse = endpoints.LoginResponse
+
+	CreateProjectRequest  = endpoints.CreateProjectRequest
+	CreateProjectResponse = endpoints.CreateProjectResponse
+	GetProjectRequest     = endpoints.GetProjectRequest
+	GetProjectResponse    = endpoints.GetProjectResponse
+	ListProjectsRequest   = endpoints.ListProjectsRequest
+	ListProjectsResponse  = endpoints.ListProjectsResponse
+	UpdateProjectRequest  = endpoints.UpdateProjectRequest
+	UpdateProjectResponse = endpoints.UpdateProjectResponse
+	DeleteProjectRequest  = endpoints.DeleteProjectRequest
+	DeleteProjectResponse = endpoints.DeleteProjectResponse
+
+	CreateProjectUserRequest     = endpoints.CreateProjectUserRequest
+	CreateProjectUserResponse    = endpoints.CreateProjectUserResponse
+	GetProjectUserRequest        = endpoints.GetProjectUserRequest
+	GetProjectUserResponse       = endpoints.GetProjectUserResponse
+	ListProjectUsersRequest      = endpoints.ListProjectUsersRequest
+	ListProjectUsersResponse     = endpoints.ListProjectUsersResponse
+	UpdateProjectUserRequest     = endpoints.UpdateProjectUserRequest
+	UpdateProjectUserResponse    = endpoints.UpdateProjectUserResponse
+	SetProjectUserStatusRequest  = endpoints.SetProjectUserStatusRequest
+	SetProjectUserStatusResponse = endpoints.SetProjectUserStatusResponse
+	DeleteProjectUserRequest     = endpoints.DeleteProjectUserRequest
+	DeleteProjectUserResponse    = endpoints.DeleteProjectUserResponse
+	RestoreProjectUserRequest    = endpoints.RestoreProjectUserRequest
+	RestoreProjectUserResponse   = endpoints.RestoreProjectUserResponse
+
+	AuthorizeRequest        = endpoints.AuthorizeRequest
+	AuthorizeResponse       = endpoints.AuthorizeResponse
+	IntrospectBatchRequest  = endpoints.IntrospectBatchRequest
+	IntrospectBatchResponse = endpoints.IntrospectBatchResponse
+	TokenIntrospection      = endpoints.TokenIntrospection
+)
@@ -13,11 +13,297 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Bind       BindOptions             `yaml:"bind"`
-	DB         DBConfigurations        `yaml:"database"`
-	Instrument InstrumentConfiguration `yaml:"intrument"`
-	Auth       AuthConfig              `yaml:"auth"`
-	OAuth      OAuthConfig             `yaml:"oauth"`
+	// Environment is "development" (the default) or "production", which
+	// refuses to start with insecure defaults
+	Environment string                  `yaml:"environment"`
+	Bind        BindOptions             `yaml:"bind"`
+	DB          DBConfigurations        `yaml:"database"`
+	Instrument  InstrumentConfiguration `yaml:"intrument"`
+	Auth        AuthConfig              `yaml:"auth"`
+	OAuth       OAuthConfig             `yaml:"oauth"`
+	Import      ImportConfig            `yaml:"import"`
+	API         APIConfig               `yaml:"api"`
+	Webhooks    WebhooksConfig          `yaml:"webhooks"`
+	Mail        MailConfig              `yaml:"mail"`
+	MagicLink   MagicLinkConfig         `yaml:"magic_link"`
+	Limits      LimitsConfig            `yaml:"limits"`
+	Authorize   AuthorizeConfig         `yaml:"authorize"`
+	AdminUI     AdminUIConfig           `yaml:"admin_ui"`
+	Passwords   PasswordsConfig         `yaml:"passwords"`
+	Projects    ProjectsConfig          `yaml:"projects"`
+	Roles       RolesConfig             `yaml:"roles"`
+	Policies    PoliciesConfig          `yaml:"policies"`
+	Metrics     MetricsConfig           `yaml:"metrics"`
+	Logging     LoggingConfig           `yaml:"logging"`
+	Users       UsersConfig             `yaml:"users"`
+	Reports     ReportsConfig           `yaml:"reports"`
+	Storage     StorageConfig           `yaml:"storage"`
+	AuthCache   AuthCacheConfig         `yaml:"auth_cache"`
+	CORS        CORSConfig              `yaml:"cors"`
+	Proxies     ProxiesConfig           `yaml:"proxies"`
+	Panics      PanicsConfig            `yaml:"panics"`
+}
+
+// PanicsConfig configures the reporting of panics in request handlers. They
+// are always logged, counted and answered with 500 INTERNAL.
+type PanicsConfig struct {
+	// ReportURL receives a JSON report of every panic by POST, e.g. an error
+	// tracker's ingestion endpoint; empty sends no reports. It is masked like
+	// a secret, as such URLs usually carry a key.
+	ReportURL     string        `yaml:"report_url"`
+	ReportTimeout time.Duration `yaml:"report_timeout"` // Defaults to 5s
+}
+
+// CORSConfig lists the origins browsers may call the API from. Projects can
+// allow more origins for their own routes in their settings.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"` // Bare origins, e.g. https://admin.example.com
+}
+
+// ProxiesConfig lists the load balancers and reverse proxies in front of
+// the service. Requests reaching the service through them are taken to come
+// from the client their X-Forwarded-For header names.
+type ProxiesConfig struct {
+	// Trusted are CIDR blocks of the proxies, e.g. 10.0.0.0/8; empty
+	// trusts none, and the connection's address is the client's
+	Trusted []string `yaml:"trusted"`
+}
+
+// AuthCacheConfig configures the cache of the users and projects that
+// authenticating a request loads. Changes made on this instance take effect
+// at once; those made on another take effect within the TTL.
+type AuthCacheConfig struct {
+	// Disabled reads them from the database on every request, for
+	// deployments that need every change seen at once everywhere
+	Disabled bool          `yaml:"disabled"`
+	TTL      time.Duration `yaml:"ttl"` // Defaults to 5s
+}
+
+// StorageConfig configures where uploaded import files, import error
+// reports and export artifacts are kept
+type StorageConfig struct {
+	Backend         string             `yaml:"backend"`          // local (the default) or s3
+	Retention       time.Duration      `yaml:"retention"`        // How long finished artifacts are kept; defaults to 168h
+	CleanupInterval time.Duration      `yaml:"cleanup_interval"` // How often expired artifacts are deleted; defaults to 1h
+	SignedURLTTL    time.Duration      `yaml:"signed_url_ttl"`   // Lifetime of download URLs handed to clients; defaults to 15m
+	Local           LocalStorageConfig `yaml:"local"`
+	S3              S3StorageConfig    `yaml:"s3"`
+}
+
+// LocalStorageConfig configures the local filesystem backend, which streams
+// downloads through the API
+type LocalStorageConfig struct {
+	Path string `yaml:"path"` // Defaults to import.storage_path, then to a directory under the OS temp dir
+}
+
+// S3StorageConfig configures the S3-compatible backend, which hands clients
+// signed download URLs
+type S3StorageConfig struct {
+	Endpoint        string `yaml:"endpoint"` // e.g. http://minio:9000; defaults to AWS S3 in the region
+	Region          string `yaml:"region"`   // Defaults to us-east-1
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	PathStyle       bool   `yaml:"path_style"` // Address the bucket in the path, as MinIO expects
+}
+
+// ReportsConfig configures the digest emails projects schedule in their
+// settings
+type ReportsConfig struct {
+	Interval time.Duration `yaml:"interval"` // How often schedules are checked; defaults to 1m
+	CatchUp  time.Duration `yaml:"catch_up"` // How late a digest missed while the service was down is still sent; defaults to 24h
+}
+
+// UsersConfig configures global users
+type UsersConfig struct {
+	// EmailScope is where a user's email must be unique: global (the
+	// default) or project, letting each project have its own user with the
+	// same email. Changing it migrates the unique index at startup.
+	EmailScope string `yaml:"email_scope"`
+	// Changes configures the feed of user changes at
+	// /api/v1/{projectId}/users/changes
+	Changes UserChangesConfig `yaml:"changes"`
+	// DeactivateOrphaned deactivates users found holding a role that was
+	// deleted when they next make a request. They are denied everything
+	// either way.
+	DeactivateOrphaned bool `yaml:"deactivate_orphaned"`
+}
+
+// UserChangesConfig configures the user change feed
+type UserChangesConfig struct {
+	// CursorKey signs the feed's cursors: at least 32 bytes in base64
+	// (openssl rand -base64 32), the same on every instance. Without one a
+	// random key is used and cursors break on restart.
+	CursorKey string        `yaml:"cursor_key"`
+	MaxWait   time.Duration `yaml:"max_wait"` // Longest long-poll wait; defaults to 1m
+}
+
+// LoggingConfig controls what personal data reaches the logs
+type LoggingConfig struct {
+	// RedactEmails is how email addresses are logged: mask (the default)
+	// keeps their first character and domain, hash replaces the local part
+	// with a short digest and off logs them as they are
+	RedactEmails string `yaml:"redact_emails"`
+	// Levels raises the verbosity of subsystems by name, e.g. projectusers,
+	// oauth, oauth.github or auth, above klog's -v
+	Levels map[string]int `yaml:"levels"`
+}
+
+// MetricsConfig tunes the metrics served at /metrics
+type MetricsConfig struct {
+	// ProjectLabelLimit is how many projects get a label of their own in the
+	// login metrics; the rest are labeled "other". Defaults to 100.
+	ProjectLabelLimit int `yaml:"project_label_limit"`
+}
+
+// ProjectsConfig configures project management
+type ProjectsConfig struct {
+	// Template lists the roles, with their policies, every new project
+	// starts with
+	Template ProjectTemplateConfig `yaml:"template"`
+	// UniqueIDCheckRate is how many unique ID checks a client IP may make a
+	// minute; 0 for the default of 60, negative for no limit
+	UniqueIDCheckRate int `yaml:"unique_id_check_rate"`
+	// MaxProjects caps the live projects of the deployment, and
+	// MaxPerCreator those each user created; 0 for no cap. SuperAdmin users
+	// are not capped.
+	MaxProjects   int `yaml:"max_projects"`
+	MaxPerCreator int `yaml:"max_per_creator"`
+	// Regions are where projects may keep their users
+	Regions RegionsConfig `yaml:"regions"`
+}
+
+// RegionsConfig lists the regions a project may be created in. The users of
+// a project in a region with a database of its own are kept there; those of
+// the other regions, and of projects without one, in the main database.
+type RegionsConfig struct {
+	Allowed   []string                    `yaml:"allowed"`
+	Databases map[string]DBConfigurations `yaml:"databases"` // By region
+}
+
+// RolesConfig configures role management
+type RolesConfig struct {
+	// MaxExpiration is the longest token lifetime a role may be given,
+	// through the API or the project template; 0 for no maximum
+	MaxExpiration time.Duration `yaml:"max_expiration"`
+}
+
+// PoliciesConfig configures policy management
+type PoliciesConfig struct {
+	// RevertSystemDrift changes system policies that were edited back to
+	// their definition at startup, rather than only logging the drift
+	RevertSystemDrift bool `yaml:"revert_system_drift"`
+}
+
+// ProjectTemplateConfig is the RBAC setup of new projects
+type ProjectTemplateConfig struct {
+	Roles []RoleTemplateConfig `yaml:"roles"`
+}
+
+// RoleTemplateConfig is a role created in every new project
+type RoleTemplateConfig struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Expiration  time.Duration          `yaml:"expiration"` // Token lifetime of the role's users
+	Default     bool                   `yaml:"default"`    // Makes it the project's default role
+	Policies    []PolicyTemplateConfig `yaml:"policies"`
+}
+
+// PolicyTemplateConfig is a policy attached to a template role
+type PolicyTemplateConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Resource    string `yaml:"resource"`
+	Action      string `yaml:"action"`
+	Effect      string `yaml:"effect"` // allow or deny
+}
+
+// PasswordsConfig selects how new password hashes are made. Hashes made by
+// another algorithm keep working and are replaced on the user's next login.
+type PasswordsConfig struct {
+	Algorithm  string       `yaml:"algorithm"`   // bcrypt (default) or argon2id
+	BcryptCost int          `yaml:"bcrypt_cost"` // Defaults to 10
+	Argon2     Argon2Config `yaml:"argon2"`
+	MinLength  int          `yaml:"min_length"` // Shortest password accepted for new global users; 0 for none
+	// MaxAgeDays is how long a global user's password lasts before it must
+	// be changed; 0 for no expiry. Projects may override it.
+	MaxAgeDays int `yaml:"max_age_days"`
+	// HistorySize is how many of their latest passwords, the current one
+	// included, users may not reuse; defaults to 3, negative allows any
+	HistorySize int `yaml:"history_size"`
+}
+
+// Argon2Config tunes argon2id hashing; zero values use the defaults
+type Argon2Config struct {
+	Memory      uint32 `yaml:"memory"`      // KiB, defaults to 65536
+	Iterations  uint32 `yaml:"iterations"`  // Defaults to 3
+	Parallelism uint8  `yaml:"parallelism"` // Defaults to 4
+}
+
+// AdminUIConfig configures the embedded admin UI served under /admin. It is
+// off unless enabled.
+type AdminUIConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AuthorizeConfig configures the authorization check endpoint
+type AuthorizeConfig struct {
+	RateLimit  int           `yaml:"rate_limit"`  // Checks per project within rate_window; 0 disables the limit
+	RateWindow time.Duration `yaml:"rate_window"` // Defaults to a minute
+}
+
+// LimitsConfig protects the server from overload
+type LimitsConfig struct {
+	// MaxConcurrentRequests caps requests in flight across the whole server;
+	// 0 disables the cap
+	MaxConcurrentRequests int           `yaml:"max_concurrent_requests"`
+	RetryAfter            time.Duration `yaml:"retry_after"` // Advertised to rejected clients, rounded up to whole seconds
+}
+
+// MailConfig configures outgoing email. Without an SMTP host messages are
+// written to the log instead.
+type MailConfig struct {
+	From     string `yaml:"from"`
+	SMTPHost string `yaml:"smtp_host"`
+	SMTPPort int    `yaml:"smtp_port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// MagicLinkConfig configures password-less email login. Projects opt in
+// through their settings.
+type MagicLinkConfig struct {
+	BaseURL    string        `yaml:"base_url"`   // Public URL of the service, used in emailed links
+	TTL        time.Duration `yaml:"ttl"`        // Lifetime of a link
+	RateLimit  int           `yaml:"rate_limit"` // Links per project and email within rate_window
+	RateWindow time.Duration `yaml:"rate_window"`
+
+	MaxFailures      int           `yaml:"max_failures"` // Failed verifications per client IP within failure_window
+	FailureWindow    time.Duration `yaml:"failure_window"`
+	MaxTokenFailures int           `yaml:"max_token_failures"` // Failed verifications that lock a token
+}
+
+// WebhooksConfig configures webhook delivery
+type WebhooksConfig struct {
+	ServiceURL string        `yaml:"service_url"` // Public URL of the service, the CloudEvents source
+	Timeout    time.Duration `yaml:"timeout"`     // Per-delivery HTTP timeout
+}
+
+// APIConfig configures the versioned API prefixes
+type APIConfig struct {
+	// LegacySunset is the date (YYYY-MM-DD) after which the unversioned /api
+	// alias may be removed; it is advertised in the Sunset header
+	LegacySunset string `yaml:"legacy_sunset"`
+}
+
+// ImportConfig configures asynchronous CSV user imports
+type ImportConfig struct {
+	StoragePath string `yaml:"storage_path"` // Deprecated: the default of storage.local.path
+	ChunkSize   int    `yaml:"chunk_size"`   // Rows committed per progress update
+	Workers     int    `yaml:"workers"`      // Jobs processed concurrently
+	// Lease is how long an instance's claim on a job lasts without being
+	// renewed; jobs whose lease lapsed are taken over. 0 means 2 minutes.
+	Lease time.Duration `yaml:"lease"`
 }
 
 type InstrumentConfiguration struct {
@@ -29,6 +315,22 @@ type InstrumentConfiguration struct {
 type AuthConfig struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
+	// JWTSecret signs the bearer tokens; empty uses a built-in secret that
+	// is only fit for development
+	JWTSecret string `yaml:"jwt_secret"`
+	// RememberFor is how long the session cookie of a login asking to be
+	// remembered lasts; 0 for 30 days
+	RememberFor time.Duration `yaml:"remember_for"`
+	// SecureCookies marks session cookies Secure, so browsers only send
+	// them over HTTPS
+	SecureCookies bool                `yaml:"secure_cookies"`
+	Introspection IntrospectionConfig `yaml:"introspection"`
+}
+
+// IntrospectionConfig bounds the batches of POST /api/v1/auth/introspect-batch
+type IntrospectionConfig struct {
+	MaxBatch int `yaml:"max_batch"` // Most tokens a batch may hold; 0 for 100
+	Workers  int `yaml:"workers"`   // Tokens of a batch introspected at once; 0 for 8
 }
 
 type OAuthConfig struct {
@@ -36,13 +338,47 @@ type OAuthConfig struct {
 	Facebook  OAuthProviderConfig `yaml:"facebook"`
 	GitHub    OAuthProviderConfig `yaml:"github"`
 	Microsoft OAuthProviderConfig `yaml:"microsoft"`
+	TokenKeys TokenKeysConfig     `yaml:"token_keys"`
+	HTTP      OAuthHTTPConfig     `yaml:"http"`
+	State     OAuthStateConfig    `yaml:"state"`
+}
+
+// OAuthStateConfig controls the states OAuth logins are validated with on
+// their callback
+type OAuthStateConfig struct {
+	// Store keeps the states: memory (the default), for a single instance,
+	// or database, which any instance can redeem
+	Store string        `yaml:"store"`
+	TTL   time.Duration `yaml:"ttl"` // How long a login can take; 0 for 10 minutes
+}
+
+// OAuthHTTPConfig controls the calls made to the OAuth providers. After
+// FailureThreshold failed calls in a row a provider's logins fail fast for
+// Cooldown.
+type OAuthHTTPConfig struct {
+	Timeout          time.Duration `yaml:"timeout"`
+	Retries          int           `yaml:"retries"` // Repeats of a failed GET; defaults to 1, -1 for none
+	FailureThreshold int           `yaml:"failure_threshold"`
+	Cooldown         time.Duration `yaml:"cooldown"`
+}
+
+// TokenKeysConfig holds the keys stored OAuth tokens are encrypted with.
+// Tokens are encrypted under the primary key; the others are retired keys
+// kept until POST /api/admin/rotate-token-key has re-encrypted their tokens.
+type TokenKeysConfig struct {
+	Primary string            `yaml:"primary"` // ID of the key new tokens are encrypted under
+	Keys    map[string]string `yaml:"keys"`    // Base64 encoded 32-byte keys by ID
 }
 
 type OAuthProviderConfig struct {
-	ClientID     string   `yaml:"client_id"`
-	ClientSecret string   `yaml:"client_secret"`
-	RedirectURL  string   `yaml:"redirect_url"`
-	Scopes       []string `yaml:"scopes"`
+	ClientID       string   `yaml:"client_id"`
+	ClientSecret   string   `yaml:"client_secret"`
+	RedirectURL    string   `yaml:"redirect_url"`
+	Scopes         []string `yaml:"scopes"`
+	AllowedDomains []string `yaml:"allowed_domains"` // Email domains allowed to log in; empty allows all
+	// AllowCustomScopes permits scopes outside the provider's known ones,
+	// here and in project overrides
+	AllowCustomScopes bool `yaml:"allow_custom_scopes"`
 }
 
 type BindOptions struct {
@@ -56,6 +392,10 @@ type DBConfigurations struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Database string `yaml:"database"`
+
+	// Startup connection retry; the backoff doubles after every failed attempt
+	ConnectAttempts int           `yaml:"connect_attempts"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff"`
 }
 
 func (cfg DBConfigurations) CreateDSN() string {
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// minRevealedSecretLength is the shortest secret MaskSecret shows the end of;
+// the last characters of anything shorter give too much of it away
+const minRevealedSecretLength = 8
+
+// MaskSecret hides a secret, keeping only its length and, for secrets of
+// at least eight characters, its last two: "****3f (32 chars)". That is
+// enough to tell which value is configured without revealing it. An unset
+// secret stays empty.
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) < minRevealedSecretLength {
+		return fmt.Sprintf("**** (%d chars)", len(secret))
+	}
+	return fmt.Sprintf("****%s (%d chars)", secret[len(secret)-2:], len(secret))
+}
+
+// Redacted returns a copy of the configuration with every secret masked by
+// MaskSecret: passwords, including those of the regional databases, the JWT
+// secret, OAuth client secrets, token keys, the change feed cursor key, the
+// S3 credentials and the panic report URL. It is what may be logged or shown
+// to administrators.
+func (c Config) Redacted() Config {
+	c.Auth.Password = MaskSecret(c.Auth.Password)
+	c.Auth.JWTSecret = MaskSecret(c.Auth.JWTSecret)
+	c.DB.Password = MaskSecret(c.DB.Password)
+	if c.Projects.Regions.Databases != nil {
+		databases := make(map[string]DBConfigurations, len(c.Projects.Regions.Databases))
+		for region, db := range c.Projects.Regions.Databases {
+			db.Password = MaskSecret(db.Password)
+			databases[region] = db
+		}
+		c.Projects.Regions.Databases = databases
+	}
+	c.Mail.Password = MaskSecret(c.Mail.Password)
+	c.OAuth.Google.ClientSecret = MaskSecret(c.OAuth.Google.ClientSecret)
+	c.OAuth.Facebook.ClientSecret = MaskSecret(c.OAuth.Facebook.ClientSecret)
+	c.OAuth.GitHub.ClientSecret = MaskSecret(c.OAuth.GitHub.ClientSecret)
+	c.OAuth.Microsoft.ClientSecret = MaskSecret(c.OAuth.Microsoft.ClientSecret)
+	if c.OAuth.TokenKeys.Keys != nil {
+		keys := make(map[string]string, len(c.OAuth.TokenKeys.Keys))
+		for id, key := range c.OAuth.TokenKeys.Keys {
+			keys[id] = MaskSecret(key)
+		}
+		c.OAuth.TokenKeys.Keys = keys
+	}
+	c.Users.Changes.CursorKey = MaskSecret(c.Users.Changes.CursorKey)
+	c.Storage.S3.AccessKeyID = MaskSecret(c.Storage.S3.AccessKeyID)
+	c.Storage.S3.SecretAccessKey = MaskSecret(c.Storage.S3.SecretAccessKey)
+	c.Panics.ReportURL = MaskSecret(c.Panics.ReportURL)
+	return c
+}
+
+// Document returns the configuration as the nested map its YAML file
+// decodes to, keyed by the YAML names, for encoding as JSON or logging.
+// Redact the configuration first.
+func (c Config) Document() (map[string]interface{}, error) {
+	raw, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Summary lists what the configuration turns on, as key/value pairs for a
+// structured log line: the bind addresses, the OAuth providers with a
+// client, the rate limits, CORS, metrics and the storage backend
+func (c Config) Summary() []interface{} {
+	var providers []string
+	for name, provider := range map[string]OAuthProviderConfig{
+		"google":    c.OAuth.Google,
+		"facebook":  c.OAuth.Facebook,
+		"github":    c.OAuth.GitHub,
+		"microsoft": c.OAuth.Microsoft,
+	} {
+		if provider.ClientID != "" {
+			providers = append(providers, name)
+		}
+	}
+	sort.Strings(providers)
+
+	environment := c.Environment
+	if environment == "" {
+		environment = EnvDevelopment
+	}
+	storage := c.Storage.Backend
+	switch storage {
+	case "", "local":
+		path := c.Storage.Local.Path
+		if path == "" {
+			path = c.Import.StoragePath
+		}
+		if path == "" {
+			path = "a temporary directory"
+		}
+		storage = "local (" + path + ")"
+	case "s3":
+		storage = "s3 (" + c.Storage.S3.Bucket + ")"
+	}
+	authCache := "default ttl"
+	if c.AuthCache.Disabled {
+		authCache = "off"
+	} else if c.AuthCache.TTL > 0 {
+		authCache = c.AuthCache.TTL.String()
+	}
+	emailScope := c.Users.EmailScope
+	if emailScope == "" {
+		emailScope = "global"
+	}
+
+	return []interface{}{
+		"environment", environment,
+		"http", fmt.Sprintf(":%d", c.Bind.HTTP),
+		"database", fmt.Sprintf("%s:%d/%s", c.DB.Host, c.DB.Port, c.DB.Database),
+		// The schema has no versions: it is migrated to match the build
+		"migrations", "automatic at startup",
+		"oauthProviders", strings.Join(providers, ","),
+		"corsOrigins", len(c.CORS.AllowedOrigins),
+		"trustedProxies", len(c.Proxies.Trusted),
+		"maxConcurrentRequests", c.Limits.MaxConcurrentRequests,
+		"authorizeRateLimit", c.Authorize.RateLimit,
+		"magicLinkRateLimit", c.MagicLink.RateLimit,
+		"metrics", "/metrics",
+		"adminUI", c.AdminUI.Enabled,
+		"authCache", authCache,
+		"storage", storage,
+		"emailScope", emailScope,
+		"panicReports", c.Panics.ReportURL != "",
+	}
+}
@@ -0,0 +1,76 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+)
+
+// withSecrets returns a configuration whose every secret field holds a
+// value of its own
+func withSecrets() (cmd.Config, []string) {
+	var cfg cmd.Config
+	var secrets []string
+	secret := func(name string) string {
+		value := "secret-" + name + "-value"
+		secrets = append(secrets, value)
+		return value
+	}
+	cfg.Auth.Password = secret("auth-password")
+	cfg.Auth.JWTSecret = secret("jwt")
+	cfg.DB.Password = secret("db-password")
+	cfg.Projects.Regions.Databases = map[string]cmd.DBConfigurations{"eu": {Host: "eu-db", Password: secret("eu-db-password")}}
+	cfg.Mail.Password = secret("mail-password")
+	cfg.OAuth.Google.ClientSecret = secret("google")
+	cfg.OAuth.Facebook.ClientSecret = secret("facebook")
+	cfg.OAuth.GitHub.ClientSecret = secret("github")
+	cfg.OAuth.Microsoft.ClientSecret = secret("microsoft")
+	cfg.OAuth.TokenKeys.Keys = map[string]string{"k1": secret("token-key")}
+	cfg.Users.Changes.CursorKey = secret("cursor-key")
+	cfg.Storage.S3.AccessKeyID = secret("s3-access-key")
+	cfg.Storage.S3.SecretAccessKey = secret("s3-secret-key")
+	cfg.Panics.ReportURL = "https://errors.example.com/ingest?key=" + secret("report-key")
+	return cfg, secrets
+}
+
+func TestRedactedConfigContainsNoSecrets(t *testing.T) {
+	cfg, secrets := withSecrets()
+
+	document, err := cfg.Redacted().Document()
+	if err != nil {
+		t.Fatalf("Document: %v", err)
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("failed to encode the redacted configuration: %v", err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(encoded), secret) {
+			t.Errorf("the redacted configuration contains %q: %s", secret, encoded)
+		}
+	}
+	if !strings.Contains(string(encoded), `"host":"eu-db"`) {
+		t.Errorf("the redacted configuration lost the settings that are not secret: %s", encoded)
+	}
+
+	if cfg.OAuth.TokenKeys.Keys["k1"] != "secret-token-key-value" || cfg.Projects.Regions.Databases["eu"].Password != "secret-eu-db-password-value" {
+		t.Error("Redacted masked the secrets of the configuration it was called on")
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	for _, tc := range []struct {
+		secret string
+		want   string
+	}{
+		{"", ""},
+		{"short", "**** (5 chars)"},
+		{"a-longer-secret-3f", "****3f (18 chars)"},
+	} {
+		if got := cmd.MaskSecret(tc.secret); got != tc.want {
+			t.Errorf("MaskSecret(%q) = %q, want %q", tc.secret, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/auth"
+	"k8s.io/klog/v2"
+)
+
+// Environments the service can run in
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// defaultSuperUserPasswords are the super user passwords shipped in the
+// sample configuration and the documentation
+var defaultSuperUserPasswords = []string{"admin123", "superuser123"}
+
+// InsecureDefaults lists the settings left at values that are unsafe outside
+// development
+func (c Config) InsecureDefaults() []string {
+	var problems []string
+	if c.Auth.JWTSecret == "" || c.Auth.JWTSecret == auth.DefaultJWTSecret {
+		problems = append(problems, "auth.jwt_secret is not set, so tokens are signed with the built-in secret")
+	}
+	if c.Auth.Password == "" {
+		problems = append(problems, "auth.password is not set, so the super user has the default password")
+	}
+	for _, password := range defaultSuperUserPasswords {
+		if c.Auth.Password == password {
+			problems = append(problems, "auth.password is the default super user password")
+		}
+	}
+	if !c.Auth.SecureCookies {
+		problems = append(problems, "auth.secure_cookies is off, so session cookies are sent over plain HTTP")
+	}
+	return problems
+}
+
+// CheckSecureDefaults guards startup against insecure defaults. In
+// production any of them is an error; in development each is logged as a
+// warning and startup proceeds.
+func (c Config) CheckSecureDefaults() error {
+	problems := c.InsecureDefaults()
+	switch c.Environment {
+	case "", EnvDevelopment:
+		for _, problem := range problems {
+			klog.Warningf("Insecure configuration: %s; this is refused in production", problem)
+		}
+		return nil
+	case EnvProduction:
+		if len(problems) > 0 {
+			return fmt.Errorf("insecure defaults in production: %s", strings.Join(problems, "; "))
+		}
+		return nil
+	}
+	return fmt.Errorf("environment must be %q or %q, not %q", EnvDevelopment, EnvProduction, c.Environment)
+}
@@ -0,0 +1,64 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// secureConfig returns a configuration with none of the insecure defaults
+func secureConfig(environment string) cmd.Config {
+	var cfg cmd.Config
+	cfg.Environment = environment
+	cfg.Auth.JWTSecret = "a-secret-of-our-own"
+	cfg.Auth.Password = "a-password-of-our-own"
+	cfg.Auth.SecureCookies = true
+	return cfg
+}
+
+func TestCheckSecureDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		setting  string // The setting the problem names; empty for none
+		insecure func(*cmd.Config)
+	}{
+		{"no insecure default", "", func(*cmd.Config) {}},
+		{"no JWT secret", "auth.jwt_secret", func(cfg *cmd.Config) { cfg.Auth.JWTSecret = "" }},
+		{"the built-in JWT secret", "auth.jwt_secret", func(cfg *cmd.Config) { cfg.Auth.JWTSecret = auth.DefaultJWTSecret }},
+		{"no super user password", "auth.password", func(cfg *cmd.Config) { cfg.Auth.Password = "" }},
+		{"the sample super user password", "auth.password", func(cfg *cmd.Config) { cfg.Auth.Password = "admin123" }},
+		{"the documented super user password", "auth.password", func(cfg *cmd.Config) { cfg.Auth.Password = "superuser123" }},
+		{"cookies that are not Secure", "auth.secure_cookies", func(cfg *cmd.Config) { cfg.Auth.SecureCookies = false }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, environment := range []string{cmd.EnvProduction, cmd.EnvDevelopment, ""} {
+				logs := testutil.CaptureKlog(t)
+				cfg := secureConfig(environment)
+				tc.insecure(&cfg)
+
+				err := cfg.CheckSecureDefaults()
+				production := environment == cmd.EnvProduction
+				if fails := err != nil; fails != (production && tc.setting != "") {
+					t.Fatalf("in %q: CheckSecureDefaults = %v", environment, err)
+				}
+				if err != nil && !strings.Contains(err.Error(), tc.setting) {
+					t.Errorf("in %q: error %q does not name %s", environment, err, tc.setting)
+				}
+				warned := strings.Contains(logs.String(), "Insecure configuration")
+				if want := !production && tc.setting != ""; warned != want {
+					t.Errorf("in %q: warned %v, want %v; logs:\n%s", environment, warned, want, logs.String())
+				}
+				if warned && !strings.Contains(logs.String(), tc.setting) {
+					t.Errorf("in %q: the warning does not name %s; logs:\n%s", environment, tc.setting, logs.String())
+				}
+			}
+		})
+	}
+
+	if err := secureConfig("staging").CheckSecureDefaults(); err == nil {
+		t.Error("CheckSecureDefaults accepted an unknown environment")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestAdminConfigIsMaskedAndForSuperAdminsOnly(t *testing.T) {
+	var cfg cmd.Config
+	cfg.DB.Host = "db.internal"
+	cfg.DB.Password = "a-database-password"
+	cfg.OAuth.GitHub.ClientSecret = "a-github-client-secret"
+	cfg.Storage.S3.SecretAccessKey = "an-s3-secret-access-key"
+	server := newTestServer(t, cfg)
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+
+	status, body := server.call(t, http.MethodGet, "/api/v1/admin/config", rootToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/admin/config = %d %s", status, body)
+	}
+	for _, secret := range []string{cfg.DB.Password, cfg.OAuth.GitHub.ClientSecret, cfg.Storage.S3.SecretAccessKey} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("the configuration shows %q: %s", secret, body)
+		}
+	}
+	var response endpoints.GetConfigResponse
+	decode(t, body, &response)
+	database, _ := response.Config["database"].(map[string]interface{})
+	if database["host"] != "db.internal" || database["password"] != cmd.MaskSecret(cfg.DB.Password) {
+		t.Errorf("database = %v, want the host and the masked password", database)
+	}
+
+	if status, body := server.call(t, http.MethodGet, "/api/v1/admin/config", tokenFor(t, member), nil); status != http.StatusForbidden {
+		t.Errorf("GET /api/v1/admin/config as a member = %d %s, want 403", status, body)
+	}
+}
+
+func TestRotateTokenKeyIsForSuperAdminsOnly(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+
+	path := "/api/v1/admin/rotate-token-key"
+	if status, body := server.call(t, http.MethodPost, path, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("POST %s without a token = %d %s, want 401", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodPost, path, tokenFor(t, member), nil); status != http.StatusForbidden {
+		t.Errorf("POST %s as a member = %d %s, want 403", path, status, body)
+	}
+	// No keys are configured, so the rotation itself is refused
+	if status, body := server.call(t, http.MethodPost, path, rootToken, nil); status != http.StatusConflict {
+		t.Errorf("POST %s as a super admin = %d %s, want 409", path, status, body)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+)
+
+func TestAdminUIFlag(t *testing.T) {
+	enabled := newTestServer(t, cmd.Config{AdminUI: cmd.AdminUIConfig{Enabled: true}})
+	status, body := enabled.call(t, http.MethodGet, "/admin/projects", "", nil)
+	if status != http.StatusOK || !strings.Contains(string(body), "User Management Admin") {
+		t.Fatalf("GET /admin/projects with the UI enabled = %d, want the UI", status)
+	}
+
+	disabled := newTestServer(t, cmd.Config{})
+	for _, path := range []string{"/admin/", "/admin/app.js", "/admin/projects"} {
+		if status, body := disabled.call(t, http.MethodGet, path, "", nil); status != http.StatusNotFound || strings.Contains(string(body), "User Management Admin") {
+			t.Errorf("GET %s with the UI disabled = %d, want 404", path, status)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestAPITokensNeedProjectsWrite(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("admin").WithRole("member").Build(t, server.DB)
+	other := testutil.AProject().WithRole("admin").Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(built.Roles["admin"]).Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(other.Roles["admin"]).Build(t, server.DB)
+	adminToken := tokenFor(t, testutil.AUser(t, server.DB, "admin@example.com", built.Roles["admin"], built.Project))
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+	outsiderToken := tokenFor(t, testutil.AUser(t, server.DB, "outsider@example.com", other.Roles["admin"], other.Project))
+
+	path := "/api/v1/projects/" + built.Project.ID.String() + "/api-tokens"
+	issue := map[string]string{"name": "ci"}
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"without projects:write", memberToken, http.StatusForbidden},
+		{"as an admin of another project", outsiderToken, http.StatusForbidden},
+	} {
+		if status, body := server.call(t, http.MethodPost, path, tc.token, issue); status != tc.want {
+			t.Errorf("POST %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+		if status, body := server.call(t, http.MethodGet, path, tc.token, nil); status != tc.want {
+			t.Errorf("GET %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+	}
+
+	status, body := server.call(t, http.MethodPost, path, adminToken, issue)
+	if status != http.StatusOK {
+		t.Fatalf("POST %s with projects:write = %d %s, want 200", path, status, body)
+	}
+	var created endpoints.CreateAPITokenResponse
+	decode(t, body, &created)
+
+	token := path + "/" + created.Token.ID
+	if status, body := server.call(t, http.MethodDelete, token, memberToken, nil); status != http.StatusForbidden {
+		t.Errorf("DELETE %s without projects:write = %d %s, want 403", token, status, body)
+	}
+	if status, body := server.call(t, http.MethodDelete, token, adminToken, nil); status != http.StatusOK {
+		t.Errorf("DELETE %s with projects:write = %d %s, want 200", token, status, body)
+	}
+}
+
+func TestOnlySuperAdminsIssueServiceAccountTokens(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("admin").Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(built.Roles["admin"]).Build(t, server.DB)
+	adminToken := tokenFor(t, testutil.AUser(t, server.DB, "admin@example.com", built.Roles["admin"], built.Project))
+
+	accounts := "/api/v1/projects/" + built.Project.ID.String() + "/service-accounts"
+	status, body := server.call(t, http.MethodPost, accounts, rootToken, map[string]string{"name": "deployer", "role_id": root.RoleId.String()})
+	if status != http.StatusOK {
+		t.Fatalf("POST %s = %d %s", accounts, status, body)
+	}
+	var account endpoints.CreateServiceAccountResponse
+	decode(t, body, &account)
+
+	path := "/api/v1/projects/" + built.Project.ID.String() + "/api-tokens"
+	issue := map[string]string{"name": "deploys", "service_account_id": account.ServiceAccount.ID}
+	if status, body := server.call(t, http.MethodPost, path, "", issue); status != http.StatusUnauthorized {
+		t.Errorf("POST %s for a service account without a token = %d %s, want 401", path, status, body)
+	}
+	status, body = server.call(t, http.MethodPost, path, adminToken, issue)
+	if status != http.StatusForbidden {
+		t.Errorf("POST %s for a service account as a project admin = %d %s, want 403", path, status, body)
+	}
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if apiErr.Code != apitokens.ErrServiceAccountTokenForbidden.Code {
+		t.Errorf("code = %q, want %s", apiErr.Code, apitokens.ErrServiceAccountTokenForbidden.Code)
+	}
+	if status, body := server.call(t, http.MethodPost, path, rootToken, issue); status != http.StatusOK {
+		t.Errorf("POST %s for a service account as a super admin = %d %s, want 200", path, status, body)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestAuditRangeValidation(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+
+	for _, tc := range []struct {
+		query  string
+		status int
+		code   string
+	}{
+		{"?from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z", http.StatusOK, ""},
+		{"?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z", http.StatusBadRequest, "INVALID_RANGE"},
+		{"?from=yesterday", http.StatusBadRequest, "INVALID_TIMESTAMP"},
+	} {
+		status, body := server.call(t, http.MethodGet, "/api/v1/audit"+tc.query, rootToken, nil)
+		if status != tc.status {
+			t.Errorf("GET /api/v1/audit%s: got %d %s, want %d", tc.query, status, body, tc.status)
+			continue
+		}
+		if tc.code != "" {
+			var errResp http_transport.ErrorResponse
+			decode(t, body, &errResp)
+			if errResp.Code != tc.code {
+				t.Errorf("GET /api/v1/audit%s: code %q, want %s", tc.query, errResp.Code, tc.code)
+			}
+		}
+	}
+}
+
+func TestAuditIsForSuperAdminsOnly(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+
+	for _, path := range []string{"/api/v1/audit", "/api/v1/audit?q=member@example.com"} {
+		if status, body := server.call(t, http.MethodGet, path, "", nil); status != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token = %d %s, want 401", path, status, body)
+		}
+		if status, body := server.call(t, http.MethodGet, path, tokenFor(t, member), nil); status != http.StatusForbidden {
+			t.Errorf("GET %s as a member = %d %s, want 403", path, status, body)
+		}
+	}
+}
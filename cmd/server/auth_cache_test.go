@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestAuthCacheSkipsTheDatabaseOnRepeatedRequests(t *testing.T) {
+	queriesPerRequest := func(cfg cmd.Config) int64 {
+		t.Helper()
+		server := newTestServer(t, cfg)
+		_, rootToken := aSuperAdmin(t, server.DB)
+		built := testutil.AProject().WithRole("member").Build(t, server.DB)
+		path := "/api/v1/projects/" + built.Project.ID.String() + "/service-accounts"
+
+		if status, body := server.call(t, http.MethodGet, path, rootToken, nil); status != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", path, status, body)
+		}
+		queries := testutil.CountQueries(t, server.DB)
+		if status, body := server.call(t, http.MethodGet, path, rootToken, nil); status != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", path, status, body)
+		}
+		return queries.Count()
+	}
+
+	cached := queriesPerRequest(cmd.Config{})
+	var disabled cmd.Config
+	disabled.AuthCache.Disabled = true
+	uncached := queriesPerRequest(disabled)
+	// At least the caller and the project are served from memory
+	if uncached-cached < 2 {
+		t.Errorf("a repeated request ran %d queries with the cache and %d without, want at least 2 fewer with it", cached, uncached)
+	}
+}
+
+func TestDeactivatingAUserLocksThemOutAtOnce(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+	memberToken := tokenFor(t, member)
+
+	if status, body := server.call(t, http.MethodGet, "/api/v1/me/context", memberToken, nil); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/me/context = %d %s", status, body)
+	}
+	statusPath := "/api/v1/users/" + member.ID.String() + "/status"
+	if status, body := server.call(t, http.MethodPut, statusPath, rootToken, map[string]string{"status": schemas.UserStatusDeactivated}); status != http.StatusOK {
+		t.Fatalf("PUT %s = %d %s", statusPath, status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, "/api/v1/me/context", memberToken, nil); status != http.StatusUnauthorized && status != http.StatusForbidden {
+		t.Errorf("GET /api/v1/me/context right after deactivation = %d %s, want the user refused", status, body)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/onetime"
+)
+
+func TestAuthConfigContract(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	project := built.Project
+	configPath := "/api/v1/" + project.ID.String() + "/auth/config"
+
+	get := func() map[string]json.RawMessage {
+		t.Helper()
+		resp, err := http.Get(server.URL + configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %d", configPath, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != "private, max-age=60" {
+			t.Errorf("Cache-Control = %q, want private, max-age=60", got)
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	// Nothing enabled: every method is left out, not null
+	body := get()
+	for _, key := range []string{"project_id", "branding", "signup_enabled", "methods"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("the response has no %s: %v", key, body)
+		}
+	}
+	if methods := string(body["methods"]); methods != "{}" {
+		t.Errorf("methods = %s, want {} with nothing enabled", methods)
+	}
+	if signup := string(body["signup_enabled"]); signup != "false" {
+		t.Errorf("signup_enabled = %s without a default role", signup)
+	}
+	var branding schemas.ProjectBranding
+	if err := json.Unmarshal(body["branding"], &branding); err != nil || branding.DisplayName != project.Name {
+		t.Errorf("branding = %s, want the project name as display name", body["branding"])
+	}
+
+	// Through the manager, which drops the project from the lookup cache
+	ctx := context.Background()
+	role := built.Roles["member"].ID
+	settings := project.Settings
+	settings.DefaultRoleID = &role
+	settings.MagicLink.Enabled = true
+	settings.WebAuthn = schemas.WebAuthnSettings{RPID: "example.com", Origins: []string{"https://app.example.com"}}
+	if _, err := server.Managers.ProjectManager.UpdateProjectSettings(ctx, project.ID, settings); err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+	github := schemas.OAuthProviderSettings{ClientID: "client", ClientSecret: "secret", RedirectURL: "https://app.example.com/callback"}
+	if _, err := server.Managers.ProjectManager.SetOAuthProvider(ctx, project.ID, "github", github); err != nil {
+		t.Fatalf("SetOAuthProvider: %v", err)
+	}
+
+	body = get()
+	var methods map[string]json.RawMessage
+	if err := json.Unmarshal(body["methods"], &methods); err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) != 3 || methods["magic_link"] == nil || methods["passkey"] == nil || methods["oauth"] == nil {
+		t.Fatalf("methods = %s, want magic_link, passkey and oauth", body["methods"])
+	}
+	var oauth []struct {
+		Provider  string   `json:"provider"`
+		LoginURL  string   `json:"login_url"`
+		ExpiresIn int64    `json:"expires_in"`
+		Scopes    []string `json:"scopes"`
+	}
+	if err := json.Unmarshal(methods["oauth"], &oauth); err != nil {
+		t.Fatal(err)
+	}
+	if len(oauth) != 1 || oauth[0].Provider != "github" || oauth[0].ExpiresIn <= 0 {
+		t.Fatalf("oauth = %s, want only the project's github client", methods["oauth"])
+	}
+
+	// The login URL's state was registered in the one-time store, once
+	loginURL, err := url.Parse(oauth[0].LoginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := loginURL.Query().Get("state")
+	subject, err := server.Managers.OAuthStates.Consume(ctx, onetime.PurposeOAuthState, state)
+	if err != nil {
+		t.Fatalf("the login URL's state %q is not in the one-time store: %v", state, err)
+	}
+	var issued struct {
+		ProjectID string `json:"project_id"`
+		RoleID    string `json:"role_id"`
+		Provider  string `json:"provider"`
+	}
+	if err := json.Unmarshal([]byte(subject), &issued); err != nil {
+		t.Fatal(err)
+	}
+	if issued.ProjectID != project.ID.String() || issued.RoleID != role.String() || issued.Provider != "github" {
+		t.Errorf("state subject = %s, want the project, its default role and github", subject)
+	}
+	if _, err := server.Managers.OAuthStates.Consume(ctx, onetime.PurposeOAuthState, state); err == nil {
+		t.Error("the state was accepted twice")
+	}
+}
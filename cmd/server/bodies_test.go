@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestCreateWithoutABodyIsABadRequest(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, rootToken := aSuperAdmin(t, server.DB)
+	usersPath := "/api/v1/" + root.ProjectId.String() + "/users/" + root.RoleId.String()
+
+	for _, tc := range []struct {
+		name string
+		path string
+		body string
+		code string
+	}{
+		{"project user without a body", usersPath, "", "REQUEST_BODY_REQUIRED"},
+		{"project without a body", "/api/v1/projects", "", "REQUEST_BODY_REQUIRED"},
+		{"role without a body", "/api/v1/roles", "", "REQUEST_BODY_REQUIRED"},
+		{"policy without a body", "/api/v1/policies", "", "REQUEST_BODY_REQUIRED"},
+		{"project user with malformed JSON", usersPath, `{"email": "a@example.com", "password": "hunter22"`, "INVALID_REQUEST_BODY"},
+		{"project user with a wrong type", usersPath, `{"email": 42}`, "INVALID_REQUEST_BODY"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+rootToken)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST %s: %v", tc.path, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("POST %s: got %d, want 400", tc.path, resp.StatusCode)
+			}
+			var errResp http_transport.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode the error: %v", err)
+			}
+			if errResp.Code != tc.code {
+				t.Errorf("code = %q, want %s", errResp.Code, tc.code)
+			}
+			if strings.Contains(errResp.Error, "hunter22") {
+				t.Errorf("error %q quotes the request body", errResp.Error)
+			}
+		})
+	}
+}
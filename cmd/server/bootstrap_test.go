@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+// acmeBootstrap is a bootstrap document for a full tenant
+func acmeBootstrap() map[string]any {
+	return map[string]any{
+		"name":      "Acme",
+		"unique_id": "Acme",
+		"settings": map[string]any{
+			"custom_resources": map[string][]string{"billing:invoices": {"read"}},
+		},
+		"roles": []map[string]any{
+			{"name": "admin", "expiration": 24, "policies": []map[string]string{
+				{"name": "everything", "resource": "*", "action": "*", "effect": "allow"},
+			}},
+			{"name": "member", "default": true, "policies": []map[string]string{
+				{"name": "read invoices", "resource": "billing:invoices", "action": "read", "effect": "allow"},
+			}},
+		},
+		"oauth_providers": map[string]any{
+			"github": map[string]string{"client_id": "acme-client", "client_secret": "acme-secret"},
+		},
+		"webhooks": []map[string]any{
+			{"url": "https://hooks.acme.example.com/ums", "events": []string{"user.created"}},
+		},
+		"admin": map[string]string{"email": "it@acme.example.com", "first_name": "Ida", "role": "admin"},
+	}
+}
+
+func TestBootstrapProvisionsAWholeTenant(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+
+	status, body := server.call(t, http.MethodPost, "/api/v1/projects/bootstrap", token, acmeBootstrap())
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/projects/bootstrap = %d %s", status, body)
+	}
+	var created endpoints.BootstrapProjectResponse
+	decode(t, body, &created)
+	projectID := uuid.MustParse(created.Project.ID)
+	if created.Project.UniqueID != "acme" || len(created.RoleIDs) != 2 || len(created.PolicyIDs) != 2 ||
+		len(created.Webhooks) != 1 || created.Webhooks[0].Secret == "" || created.AdminUserID == "" {
+		t.Fatalf("bootstrap = %s, want the project, 2 roles, 2 policies, a webhook with its secret and the admin", body)
+	}
+
+	var project schemas.Project
+	if err := server.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		t.Fatalf("the project was not stored: %v", err)
+	}
+	if project.Settings.DefaultRoleID == nil || project.Settings.DefaultRoleID.String() != created.RoleIDs["member"] {
+		t.Errorf("default role = %v, want member %s", project.Settings.DefaultRoleID, created.RoleIDs["member"])
+	}
+	if github, ok := project.Settings.OAuthProviders["github"]; !ok || github.ClientSecret != "acme-secret" {
+		t.Errorf("oauth providers = %+v, want the github override", project.Settings.OAuthProviders)
+	}
+	if _, ok := project.Settings.CustomResources["billing:invoices"]; !ok {
+		t.Errorf("custom resources = %v, want billing:invoices", project.Settings.CustomResources)
+	}
+
+	for name, id := range created.RoleIDs {
+		var role schemas.Role
+		if err := server.DB.First(&role, "id = ? AND project_id = ?", id, projectID).Error; err != nil || role.Name != name {
+			t.Errorf("role %s = %+v, %v, want it scoped to the project", name, role, err)
+		}
+	}
+	for name, id := range created.PolicyIDs {
+		var policy schemas.Policy
+		if err := server.DB.First(&policy, "id = ?", id).Error; err != nil || policy.Name != name {
+			t.Errorf("policy %s = %+v, %v", name, policy, err)
+		}
+	}
+	var subscription schemas.WebhookSubscription
+	if err := server.DB.First(&subscription, "id = ? AND project_id = ?", created.Webhooks[0].ID, projectID).Error; err != nil {
+		t.Errorf("the webhook subscription was not stored: %v", err)
+	}
+	var admin schemas.ProjectUser
+	if err := server.DB.Table(testutil.ProjectUserTable(projectID)).First(&admin, "id = ?", created.AdminUserID).Error; err != nil {
+		t.Fatalf("the admin was not stored: %v", err)
+	}
+	if admin.Email != "it@acme.example.com" || admin.Status != schemas.UserStatusInvited || admin.Active || admin.RoleId.String() != created.RoleIDs["admin"] {
+		t.Errorf("admin = %+v, want it@acme.example.com invited as admin", admin)
+	}
+
+	// The same unique ID cannot be bootstrapped twice
+	status, body = server.call(t, http.MethodPost, "/api/v1/projects/bootstrap", token, acmeBootstrap())
+	var failure http_transport.ErrorResponse
+	decode(t, body, &failure)
+	if status != http.StatusConflict || failure.Field != "unique_id" {
+		t.Errorf("a second bootstrap = %d %s, want 409 on unique_id", status, body)
+	}
+}
+
+func TestBootstrapValidationErrorsNameTheField(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+
+	for _, tc := range []struct {
+		field string
+		spoil func(doc map[string]any)
+	}{
+		{"name", func(doc map[string]any) { doc["name"] = " " }},
+		{"roles[1].policies[0].effect", func(doc map[string]any) {
+			doc["roles"].([]map[string]any)[1]["policies"] = []map[string]string{{"name": "p", "resource": "users", "action": "read", "effect": "maybe"}}
+		}},
+		{"oauth_providers.github.client_secret", func(doc map[string]any) {
+			doc["oauth_providers"] = map[string]any{"github": map[string]string{"client_id": "acme-client"}}
+		}},
+		{"webhooks[0].url", func(doc map[string]any) { doc["webhooks"] = []map[string]any{{"url": "hooks.acme.example.com"}} }},
+		{"admin.role", func(doc map[string]any) {
+			doc["admin"] = map[string]string{"email": "it@acme.example.com", "role": "owner"}
+		}},
+	} {
+		t.Run(tc.field, func(t *testing.T) {
+			doc := acmeBootstrap()
+			tc.spoil(doc)
+			status, body := server.call(t, http.MethodPost, "/api/v1/projects/bootstrap", token, doc)
+			var failure http_transport.ErrorResponse
+			decode(t, body, &failure)
+			if status != http.StatusBadRequest || failure.Field != tc.field {
+				t.Errorf("POST /api/v1/projects/bootstrap = %d %s, want 400 on %s", status, body, tc.field)
+			}
+		})
+	}
+
+	var count int64
+	if err := server.DB.Model(&schemas.Project{}).Where("unique_id = ?", "acme").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("a refused bootstrap created the project")
+	}
+}
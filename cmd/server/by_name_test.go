@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestLookupByName(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, rootToken := aSuperAdmin(t, server.DB)
+	role := testutil.ARole("Support Team").Build(t, server.DB)
+	policy := testutil.APolicy("read users/eu", "users", "read").ForRole(role).Build(t, server.DB)
+
+	for _, tc := range []struct {
+		path   string
+		key    string
+		status int
+		wantID string
+	}{
+		{"/api/v1/roles/by-name/" + url.PathEscape("Support Team"), "role", http.StatusOK, role.ID.String()},
+		{"/api/v1/roles/by-name/" + url.PathEscape("support team"), "role", http.StatusNotFound, ""},
+		{"/api/v1/roles/by-name/Nobody", "role", http.StatusNotFound, ""},
+		{"/api/v1/policies/by-name/" + url.PathEscape("read users/eu"), "policy", http.StatusOK, policy.ID.String()},
+		{"/api/v1/policies/by-name/" + url.PathEscape("Read users/eu"), "policy", http.StatusNotFound, ""},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			status, body := server.call(t, http.MethodGet, tc.path, rootToken, nil)
+			if status != tc.status {
+				t.Fatalf("GET %s = %d %s, want %d", tc.path, status, body, tc.status)
+			}
+			if tc.status == http.StatusNotFound {
+				var errResp http_transport.ErrorResponse
+				decode(t, body, &errResp)
+				if errResp.Code != "NOT_FOUND" {
+					t.Fatalf("error = %+v, want the NOT_FOUND envelope", errResp)
+				}
+				return
+			}
+			var response map[string]struct {
+				ID string `json:"id"`
+			}
+			decode(t, body, &response)
+			if got := response[tc.key].ID; got != tc.wantID {
+				t.Fatalf("GET %s found %s, want %s", tc.path, got, tc.wantID)
+			}
+		})
+	}
+
+	// Creating a project user accepts the role's name in place of its ID
+	usersPath := "/api/v1/" + root.ProjectId.String() + "/users/"
+	user := map[string]string{"email": "b@example.com", "password": testutil.DefaultPassword, "first_name": "B", "last_name": "User"}
+	status, body := server.call(t, http.MethodPost, usersPath+url.PathEscape("Support Team"), rootToken, user)
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST with a role name = %d %s", status, body)
+	}
+	var created struct {
+		User struct {
+			RoleID string `json:"role_id"`
+		} `json:"user"`
+	}
+	decode(t, body, &created)
+	if created.User.RoleID != role.ID.String() {
+		t.Fatalf("the user was created with role %q, want %s", created.User.RoleID, role.ID)
+	}
+
+	user["email"] = "c@example.com"
+	status, body = server.call(t, http.MethodPost, usersPath+"Nobody", rootToken, user)
+	var errResp http_transport.ErrorResponse
+	decode(t, body, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "INVALID_ROLE" {
+		t.Fatalf("POST with an unknown role name = %d %+v, want 400 INVALID_ROLE", status, errResp)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/client"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// The client is exercised against the real router, so that a route or body
+// changing on either side fails here
+func TestClientAgainstTheRouter(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, _ := aSuperAdmin(t, server.DB)
+	ctx := context.Background()
+	anonymous := client.New(server.URL+"/", client.Options{Retries: -1})
+
+	login, err := anonymous.Login(ctx, client.LoginRequest{Email: root.Email, Password: testutil.DefaultPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login.Token == "" || login.UserID != root.ID.String() {
+		t.Fatalf("login = %+v, want a token for %s", login, root.ID)
+	}
+	api := anonymous.WithToken(login.Token)
+
+	created, err := api.CreateProject(ctx, client.CreateProjectRequest{Name: "Shop", UniqueID: "shop"})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	id := created.Project.ID
+	if _, err := api.UpdateProject(ctx, client.UpdateProjectRequest{ID: id, Name: "Store", Description: "renamed"}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	got, err := api.GetProject(ctx, client.GetProjectRequest{ID: id})
+	if err != nil {
+		t.Fatalf("GetProject: %v", err)
+	}
+	if got.Project.Name != "Store" || got.Project.Description != "renamed" || got.Project.UniqueID != "shop" {
+		t.Errorf("project = %+v, want the renamed shop", got.Project)
+	}
+	listed, err := api.ListProjects(ctx, client.ListProjectsRequest{Sort: client.SortOrder{Field: "name", Desc: true}})
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(listed.Projects) != 2 || listed.Projects[0].ID != id {
+		t.Errorf("projects = %+v, want the store first of 2 by name descending", listed.Projects)
+	}
+
+	built := testutil.AProject().WithRole("Member").Build(t, server.DB)
+	projectID, roleID := built.Project.ID.String(), built.Roles["Member"].ID.String()
+	user, err := api.CreateProjectUser(ctx, client.CreateProjectUserRequest{
+		ProjectID: projectID, RoleID: roleID, Email: "a@example.com", Password: "long enough", FirstName: "A",
+	})
+	if err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	userID := user.User.ID
+	if _, err := api.DeleteProjectUser(ctx, client.DeleteProjectUserRequest{ProjectID: projectID, UserID: userID}); err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+	users, err := api.ListProjectUsers(ctx, client.ListProjectUsersRequest{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("ListProjectUsers: %v", err)
+	}
+	if len(users.Users) != 0 {
+		t.Errorf("users = %+v, want none left after the deletion", users.Users)
+	}
+	if _, err := api.GetProjectUser(ctx, client.GetProjectUserRequest{ProjectID: projectID, UserID: userID}); !client.IsNotFound(err) {
+		t.Errorf("GetProjectUser of a deleted user: err = %v, want not found", err)
+	}
+	if _, err := api.RestoreProjectUser(ctx, client.RestoreProjectUserRequest{ProjectID: projectID, UserID: userID}); err != nil {
+		t.Fatalf("RestoreProjectUser: %v", err)
+	}
+	fetched, err := api.GetProjectUser(ctx, client.GetProjectUserRequest{ProjectID: projectID, UserID: userID})
+	if err != nil || fetched.User.Email != "a@example.com" {
+		t.Errorf("GetProjectUser after the restore = %+v, %v", fetched, err)
+	}
+
+	if _, err := api.DeleteProject(ctx, client.DeleteProjectRequest{ID: id}); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	_, err = api.GetProject(ctx, client.GetProjectRequest{ID: id})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code == "" {
+		t.Errorf("GetProject of a deleted project: err = %v, want a coded 404", err)
+	}
+	_, err = api.CreateProject(ctx, client.CreateProjectRequest{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "INVALID_UNIQUE_ID" {
+		t.Errorf("CreateProject without a unique ID: err = %v, want 400 INVALID_UNIQUE_ID", err)
+	}
+	// The authentication middleware answers without the standard body
+	_, err = anonymous.IntrospectBatch(ctx, client.IntrospectBatchRequest{Tokens: []string{login.Token}})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message == "" {
+		t.Errorf("IntrospectBatch without a token: err = %v, want 401 with a message", err)
+	}
+}
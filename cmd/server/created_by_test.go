@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestCreatorsOfRolesAndPolicies(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	admin, rootToken := aSuperAdmin(t, server.DB)
+	seeded := testutil.ARole("Seeded").Build(t, server.DB)
+	seededPolicy := testutil.APolicy("seeded", "users", "read").ForRole(seeded).Build(t, server.DB)
+
+	type entry struct {
+		ID        string `json:"id"`
+		CreatedBy string `json:"created_by"`
+	}
+	get := func(path, key string) entry {
+		t.Helper()
+		status, body := server.call(t, http.MethodGet, path, rootToken, nil)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", path, status, body)
+		}
+		var response map[string]entry
+		decode(t, body, &response)
+		return response[key]
+	}
+
+	status, body := server.call(t, http.MethodPost, "/api/v1/roles", rootToken, map[string]any{"name": "Editor", "expiration": 3600})
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/roles = %d %s", status, body)
+	}
+	var created map[string]entry
+	decode(t, body, &created)
+	role := created["role"]
+	if role.CreatedBy != admin.ID.String() {
+		t.Errorf("created role reports creator %q, want the admin %s", role.CreatedBy, admin.ID)
+	}
+	if got := get("/api/v1/roles/"+role.ID, "role").CreatedBy; got != admin.ID.String() {
+		t.Errorf("GET of the created role reports creator %q, want the admin", got)
+	}
+	if got := get("/api/v1/roles/"+seeded.ID.String(), "role").CreatedBy; got != audit.SystemActor {
+		t.Errorf("seeded role reports creator %q, want %s", got, audit.SystemActor)
+	}
+
+	status, body = server.call(t, http.MethodPost, "/api/v1/policies", rootToken, map[string]string{
+		"name": "read users", "resource": "users", "action": "read", "effect": "allow",
+	})
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/policies = %d %s", status, body)
+	}
+	decode(t, body, &created)
+	if got := get("/api/v1/policies/"+created["policy"].ID, "policy").CreatedBy; got != admin.ID.String() {
+		t.Errorf("created policy reports creator %q, want the admin", got)
+	}
+	if got := get("/api/v1/policies/"+seededPolicy.ID.String(), "policy").CreatedBy; got != audit.SystemActor {
+		t.Errorf("seeded policy reports creator %q, want %s", got, audit.SystemActor)
+	}
+}
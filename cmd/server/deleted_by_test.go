@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// deletion is the deletion metadata of an entry in a GET response
+type deletion struct {
+	ID        string     `json:"id"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	DeletedBy string     `json:"deleted_by"`
+}
+
+func TestDeletionsAreAttributedToTheCaller(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	admin, rootToken := aSuperAdmin(t, server.DB)
+	role := testutil.ARole("Retired").Build(t, server.DB)
+	policy := testutil.APolicy("retired", "users", "read").Build(t, server.DB)
+	project := testutil.AProject().WithRole("member").Build(t, server.DB)
+	user := testutil.AUser(t, server.DB, "leaver@example.com", project.Roles["member"], project.Project)
+
+	for _, tc := range []struct {
+		name string
+		path string
+		key  string
+	}{
+		{"role", "/api/v1/roles/" + role.ID.String(), "role"},
+		{"policy", "/api/v1/policies/" + policy.ID.String(), "policy"},
+		{"user", "/api/v1/users/" + user.ID.String(), "user"},
+		{"project", "/api/v1/projects/" + project.Project.ID.String(), "project"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := time.Now().Add(-time.Second)
+			if status, body := server.call(t, http.MethodDelete, tc.path, rootToken, nil); status != http.StatusOK && status != http.StatusNoContent {
+				t.Fatalf("DELETE %s = %d %s", tc.path, status, body)
+			}
+
+			if status, body := server.call(t, http.MethodGet, tc.path, rootToken, nil); status != http.StatusNotFound {
+				t.Errorf("GET of the deleted %s = %d %s, want 404", tc.name, status, body)
+			}
+			status, body := server.call(t, http.MethodGet, tc.path+"?include_deleted=true", rootToken, nil)
+			if status != http.StatusOK {
+				t.Fatalf("GET %s?include_deleted=true = %d %s", tc.path, status, body)
+			}
+			var response map[string]deletion
+			decode(t, body, &response)
+			got := response[tc.key]
+			if got.DeletedBy != admin.ID.String() {
+				t.Errorf("deleted_by = %q, want the admin %s", got.DeletedBy, admin.ID)
+			}
+			if got.DeletedAt == nil || got.DeletedAt.Before(before) {
+				t.Errorf("deleted_at = %v, want the time of the DELETE", got.DeletedAt)
+			}
+		})
+	}
+
+	// A live entry reports no deletion
+	live := testutil.ARole("Live").Build(t, server.DB)
+	status, body := server.call(t, http.MethodGet, "/api/v1/roles/"+live.ID.String()+"?include_deleted=true", rootToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET of a live role with include_deleted = %d %s", status, body)
+	}
+	var response map[string]deletion
+	decode(t, body, &response)
+	if got := response["role"]; got.DeletedAt != nil || got.DeletedBy != "" {
+		t.Errorf("live role = %+v, want no deletion", got)
+	}
+}
+
+func TestProjectUserDeletionIsAttributedAndClearedOnRestore(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	admin, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	path := "/api/v1/" + built.Project.ID.String() + "/users/" + built.Users["a@example.com"].ID.String()
+
+	get := func() deletion {
+		t.Helper()
+		status, body := server.call(t, http.MethodGet, path+"?include_deleted=true", rootToken, nil)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s?include_deleted=true = %d %s", path, status, body)
+		}
+		var response map[string]deletion
+		decode(t, body, &response)
+		return response["user"]
+	}
+
+	if status, body := server.call(t, http.MethodDelete, path, rootToken, nil); status != http.StatusOK && status != http.StatusNoContent {
+		t.Fatalf("DELETE %s = %d %s", path, status, body)
+	}
+	if got := get(); got.DeletedAt == nil || got.DeletedBy != admin.ID.String() {
+		t.Errorf("deleted user = %+v, want deleted by the admin %s", got, admin.ID)
+	}
+
+	if status, body := server.call(t, http.MethodPost, path+"/restore", rootToken, nil); status != http.StatusOK {
+		t.Fatalf("POST %s/restore = %d %s", path, status, body)
+	}
+	if got := get(); got.DeletedAt != nil || got.DeletedBy != "" {
+		t.Errorf("restored user = %+v, want deleted_at and deleted_by cleared", got)
+	}
+}
+
+func TestIncludeDeletedRequiresThePolicy(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	project := testutil.AProject().WithRole("Reader").WithRole("Nobody").Build(t, server.DB)
+	testutil.APolicy("read roles", "roles", "read").ForRole(project.Roles["Reader"]).Build(t, server.DB)
+	reader := tokenFor(t, testutil.AUser(t, server.DB, "reader@example.com", project.Roles["Reader"], project.Project))
+	nobody := tokenFor(t, testutil.AUser(t, server.DB, "nobody@example.com", project.Roles["Nobody"], project.Project))
+
+	role := testutil.ARole("Retired").Build(t, server.DB)
+	path := "/api/v1/roles/" + role.ID.String()
+	if status, body := server.call(t, http.MethodDelete, path, rootToken, nil); status != http.StatusOK && status != http.StatusNoContent {
+		t.Fatalf("DELETE %s = %d %s", path, status, body)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"without roles:read", nobody, http.StatusForbidden},
+		{"with roles:read", reader, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if status, body := server.call(t, http.MethodGet, path+"?include_deleted=true", tc.token, nil); status != tc.want {
+				t.Fatalf("GET %s?include_deleted=true = %d %s, want %d", path, status, body, tc.want)
+			}
+		})
+	}
+	if status, body := server.call(t, http.MethodGet, path+"?include_deleted=maybe", rootToken, nil); status != http.StatusBadRequest {
+		t.Errorf("a malformed include_deleted = %d %s, want 400", status, body)
+	}
+}
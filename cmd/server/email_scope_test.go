@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestLoginWithAnEmailInSeveralProjects(t *testing.T) {
+	cfg := cmd.Config{}
+	cfg.Users.EmailScope = schemas.EmailScopeProject
+	server := newTestServer(t, cfg)
+	if err := internal.MigrateEmailScope(server.DB, schemas.EmailScopeProject); err != nil {
+		t.Fatalf("MigrateEmailScope: %v", err)
+	}
+	_, rootToken := aSuperAdmin(t, server.DB)
+
+	var projects []*testutil.BuiltProject
+	var ids []string
+	for i := 0; i < 2; i++ {
+		built := testutil.AProject().WithRole("member").Build(t, server.DB)
+		user := map[string]string{
+			"project_id": built.Project.ID.String(),
+			"role_id":    built.Roles["member"].ID.String(),
+			"email":      "shared@example.com",
+			"password":   testutil.DefaultPassword,
+		}
+		status, body := server.call(t, http.MethodPost, "/api/v1/users", rootToken, user)
+		if status != http.StatusOK {
+			t.Fatalf("creating the user in project %d = %d %s, want 200", i+1, status, body)
+		}
+		var created endpoints.CreateUserResponse
+		decode(t, body, &created)
+		projects, ids = append(projects, built), append(ids, created.User.ID)
+	}
+
+	status, body := server.call(t, http.MethodPost, "/auth/login", "", map[string]string{"email": "shared@example.com", "password": testutil.DefaultPassword})
+	if status != http.StatusBadRequest {
+		t.Fatalf("login without a project = %d %s, want 400", status, body)
+	}
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if apiErr.Code != "PROJECT_REQUIRED" {
+		t.Errorf("code = %s, want PROJECT_REQUIRED", apiErr.Code)
+	}
+
+	for i, built := range projects {
+		status, body := server.call(t, http.MethodPost, "/auth/login", "", map[string]string{
+			"email": "shared@example.com", "password": testutil.DefaultPassword, "project_id": built.Project.ID.String(),
+		})
+		if status != http.StatusOK {
+			t.Fatalf("login to project %d = %d %s, want 200", i+1, status, body)
+		}
+		var resp endpoints.LoginResponse
+		decode(t, body, &resp)
+		if resp.UserID != ids[i] {
+			t.Errorf("login to project %d logged in user %s, want %s", i+1, resp.UserID, ids[i])
+		}
+	}
+}
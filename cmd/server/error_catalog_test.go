@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestErrorCatalogIsServed(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+
+	status, body := server.call(t, http.MethodGet, "/api/v1/error-catalog", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/error-catalog = %d %s", status, body)
+	}
+	var catalog endpoints.ErrorCatalogResponse
+	decode(t, body, &catalog)
+
+	codes := make(map[string]map[string]int)
+	for _, route := range catalog.Routes {
+		key := route.Method + " " + route.Path
+		codes[key] = make(map[string]int)
+		for _, info := range route.Errors {
+			codes[key][info.Code] = info.Status
+		}
+	}
+	for route, want := range map[string]map[string]int{
+		"GET /api/v1/users/{id}":                   {"NOT_FOUND": http.StatusNotFound, "IP_NOT_ALLOWED": http.StatusForbidden},
+		"POST /api/v1/roles/{id}/rename":           {"ROLE_NAME_TAKEN": http.StatusConflict},
+		"GET /api/v1/{projectId}/users":            {"NOT_FOUND": http.StatusNotFound},
+		"POST /api/v1/projects/unique-id/validate": {"RATE_LIMITED": http.StatusTooManyRequests},
+		"GET /api/v1/projects/check-unique-id":     {"RATE_LIMITED": http.StatusTooManyRequests},
+	} {
+		got, ok := codes[route]
+		if !ok {
+			t.Errorf("the catalog has no %s", route)
+			continue
+		}
+		for code, status := range want {
+			if got[code] != status {
+				t.Errorf("%s lists %v, want %s with %d", route, got, code, status)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestIntrospectBatchAnswersGatewaysInOrder(t *testing.T) {
+	cfg := cmd.Config{}
+	cfg.Auth.Introspection.MaxBatch = 3
+	server := newTestServer(t, cfg)
+	built := testutil.AProject().WithRole("Gateway").WithRole("Member").Build(t, server.DB)
+	testutil.APolicy("introspect tokens", "tokens", "introspect").ForRole(built.Roles["Gateway"]).Build(t, server.DB)
+	gateway := testutil.AUser(t, server.DB, "gateway@example.com", built.Roles["Gateway"], built.Project)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["Member"], built.Project)
+	expired, err := auth.GenerateToken(member.ID, member.Email, member.RoleId, member.ProjectId, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/api/v1/auth/introspect-batch"
+
+	if status, body := server.call(t, http.MethodPost, path, tokenFor(t, member), endpoints.IntrospectBatchRequest{Tokens: []string{"x"}}); status != http.StatusForbidden {
+		t.Errorf("POST %s without the permission = %d %s, want 403", path, status, body)
+	}
+
+	status, body := server.call(t, http.MethodPost, path, tokenFor(t, gateway), endpoints.IntrospectBatchRequest{
+		Tokens: []string{"garbage", tokenFor(t, member), expired},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("POST %s = %d %s", path, status, body)
+	}
+	var batch endpoints.IntrospectBatchResponse
+	decode(t, body, &batch)
+	if batch.Active != 1 || len(batch.Results) != 3 {
+		t.Fatalf("batch = %+v, want 3 results with 1 active", batch)
+	}
+	if result := batch.Results[1]; !result.Active || result.UserID != member.ID.String() {
+		t.Errorf("the member's token = %+v, want active for %s", result, member.ID)
+	}
+	for i, reason := range map[int]string{0: "malformed", 2: "expired"} {
+		if result := batch.Results[i]; result.Active || result.Reason != reason {
+			t.Errorf("result %d = %+v, want inactive as %s", i, result, reason)
+		}
+	}
+
+	tooMany := endpoints.IntrospectBatchRequest{Tokens: []string{"a", "b", "c", "d"}}
+	status, body = server.call(t, http.MethodPost, path, tokenFor(t, gateway), tooMany)
+	var failure struct {
+		Code string `json:"code"`
+	}
+	decode(t, body, &failure)
+	if status != http.StatusBadRequest || failure.Code != "TOO_MANY_TOKENS" {
+		t.Errorf("POST %s of 4 tokens = %d %s, want 400 TOO_MANY_TOKENS", path, status, body)
+	}
+}
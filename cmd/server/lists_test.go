@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestEmptyListsAreEmptyArrays(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	empty := testutil.AProject().Named("Empty").WithUniqueID("empty").Build(t, server.DB)
+
+	for path, want := range map[string]string{
+		"/api/v1/policies": `{"policies":[]}`,
+		"/api/v1/" + empty.Project.ID.String() + "/users": `{"users":[]}`,
+	} {
+		status, body := server.call(t, http.MethodGet, path, rootToken, nil)
+		if status != http.StatusOK {
+			t.Errorf("GET %s: got %d %s, want 200", path, status, body)
+			continue
+		}
+		if got := strings.TrimSpace(string(body)); got != want {
+			t.Errorf("GET %s: got %s, want %s", path, got, want)
+		}
+	}
+}
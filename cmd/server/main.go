@@ -1,34 +1,143 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	allManager "github.com/yash3004/user_management_service"
-	"github.com/yash3004/user_management_service/auth/oauth"
+	sessionauth "github.com/yash3004/user_management_service/auth"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/changefeed"
 	cmd "github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/consents"
 	"github.com/yash3004/user_management_service/internal"
+	"github.com/yash3004/user_management_service/internal/adminui"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/ratelimit"
+	"github.com/yash3004/user_management_service/internal/redact"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
 	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+	"github.com/yash3004/user_management_service/internal/version"
+	"github.com/yash3004/user_management_service/oauthlogin"
+	"github.com/yash3004/user_management_service/policies"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/tokenkeys"
+	"github.com/yash3004/user_management_service/users"
 	"k8s.io/klog/v2"
 )
 
 type endpointManagers struct {
+	AuthManager        *endpoints.AuthEndpoint
 	ProjectManager     *endpoints.ProjectsEndpoint
 	RoleManager        *endpoints.RolesEndpoint
 	PolicyManager      *endpoints.PoliciesEndpoint
 	UserManager        *endpoints.UsersEndpoint
 	ProjectUserManager *endpoints.ProjectUsersEndpoint
 	OAuthManager       *endpoints.OAuthEndpoint
+	ImportManager      *endpoints.ImportsEndpoint
+	WebhookManager     *endpoints.WebhooksEndpoint
+	AuditManager       *endpoints.AuditEndpoint
+	MagicLinkManager   *endpoints.MagicLinkEndpoint
+	ProjectPasswords   *endpoints.ProjectPasswordEndpoint
+	AuthConfigManager  *endpoints.AuthConfigEndpoint
+	WebAuthnManager    *endpoints.WebAuthnEndpoint
+	APITokenManager    *endpoints.APITokensEndpoint
+	ServiceAccounts    *endpoints.ServiceAccountsEndpoint
+	AuthorizeManager   *endpoints.AuthorizeEndpoint
+	LoginManager       *endpoints.LoginsEndpoint
+	VersionManager     *endpoints.VersionEndpoint
+	HealthManager      *endpoints.HealthEndpoint
+	RoutesManager      *endpoints.RoutesEndpoint
+	ErrorCatalog       *endpoints.ErrorCatalogEndpoint
+	AdminManager       *endpoints.AdminEndpoint
+	ReportManager      *endpoints.ReportsEndpoint
+	MeManager          *endpoints.MeEndpoint
+	ArtifactManager    *endpoints.ArtifactsEndpoint
+	ChangesManager     *endpoints.ChangesEndpoint
+	Introspection      *endpoints.IntrospectionEndpoint
+	Consents           *endpoints.ConsentsEndpoint
+
+	authorizeLimiter *ratelimit.Limiter
+	uniqueIDLimiter  *ratelimit.Limiter
+	authUsers        *users.AuthUsers
+	roleNetworks     *roles.Networks
+	consentManager   consents.ConsentManager
+	projectUsers     projectusers.ProjectUserManager
+	sessions         *sessionauth.SessionManager
+	trustedProxies   http_transport.TrustedProxies
 }
 
+// defaultUniqueIDCheckRate is how many unique ID checks a client IP may make
+// a minute when projects.unique_id_check_rate is not set
+const defaultUniqueIDCheckRate = 60
+
+// verifyRoutes makes the server check its route wiring before it starts
+var verifyRoutes = flag.Bool("verify-routes", false, "Check every API route's endpoint and decoder wiring at startup and refuse to start if any is broken")
+
+// buildInfo exposes the running build to Prometheus as a constant 1
+var buildInfo = metrics.NewGauge("ums_build_info", "Build information of the running service.", "version", "commit", "build_time", "go_version")
+
 func main() {
+	// "server version" prints the build and exits without reading any config
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(version.Get())
+		return
+	}
+
+	// "server verify-routes" checks the route wiring and exits. It needs no
+	// config or database: decoders are exercised without calling endpoints.
+	if len(os.Args) > 1 && os.Args[1] == "verify-routes" {
+		klog.LogToStderr(false)
+		klog.SetOutput(io.Discard)
+		router := httpHandler(createEndpointManagers(&allManager.Managers{}, cmd.Config{}), cmd.Config{})
+		if err := http_transport.VerifyRoutes(router); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("all routes are wired correctly")
+		return
+	}
+
+	info := version.Get()
+	buildInfo.Set(1, info.Version, info.Commit, info.BuildTime, info.GoVersion)
+	klog.Infof("User management service %s", info)
+
 	//getting the configurations
 	cfg := cmd.GetConfigurations()
-	fmt.Print(cfg)
+	klog.InfoS("Configuration", cfg.Summary()...)
+	if document, err := cfg.Redacted().Document(); err != nil {
+		klog.Errorf("cannot encode the configuration: %v", err)
+	} else {
+		klog.InfoS("Effective configuration", "config", document)
+	}
+
+	if err := cfg.CheckSecureDefaults(); err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "" {
+		auth.SetJWTSecret(cfg.Auth.JWTSecret)
+	}
+
+	redactMode, err := redact.ParseMode(cfg.Logging.RedactEmails)
+	if err != nil {
+		log.Fatalf("invalid logging configuration: %v", err)
+	}
+	redact.SetMode(redactMode)
+	logging.SetLevels(cfg.Logging.Levels)
 
 	//skipping the migration for now
 	sqlDB, err := internal.CreateMySqlConnection(cfg)
@@ -46,14 +155,98 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to get gorm DB: %v", err)
 	}
+	regions, err := internal.GetRegions(cfg)
+	if err != nil {
+		log.Fatalf("failed to connect to the regional databases: %v", err)
+	}
 
-	managers := allManager.NewManagers(gormDB)
+	systemPolicies, err := policies.ReconcileSystemPolicies(context.Background(), gormDB, time.Now(), cfg.Policies.RevertSystemDrift)
+	if err != nil {
+		log.Fatalf("failed to reconcile the system policies: %v", err)
+	}
+	for _, name := range systemPolicies.Created {
+		klog.Infof("Created system policy %s (manifest version %d)", name, systemPolicies.Version)
+	}
+	for _, drift := range systemPolicies.Drifted {
+		if drift.Reverted {
+			klog.Warningf("Reverted system policy %s, whose %s had drifted from the manifest", drift.Name, strings.Join(drift.Fields, ", "))
+		} else {
+			klog.Warningf("System policy %s has drifted from the manifest in its %s; set policies.revert_system_drift to revert it", drift.Name, strings.Join(drift.Fields, ", "))
+		}
+	}
+
+	passwords, err := password.New(password.Config{
+		Algorithm:  cfg.Passwords.Algorithm,
+		BcryptCost: cfg.Passwords.BcryptCost,
+		Argon2: password.Argon2id{
+			Memory:      cfg.Passwords.Argon2.Memory,
+			Iterations:  cfg.Passwords.Argon2.Iterations,
+			Parallelism: cfg.Passwords.Argon2.Parallelism,
+		},
+		MinLength:   cfg.Passwords.MinLength,
+		MaxAgeDays:  cfg.Passwords.MaxAgeDays,
+		HistorySize: cfg.Passwords.HistorySize,
+	})
+	if err != nil {
+		log.Fatalf("invalid password configuration: %v", err)
+	}
+
+	if cfg.Metrics.ProjectLabelLimit > 0 {
+		auth.ProjectLabels.SetLimit(cfg.Metrics.ProjectLabelLimit)
+	}
+
+	tokenKeys, err := tokenkeys.NewKeyRing(cfg.OAuth.TokenKeys.Primary, cfg.OAuth.TokenKeys.Keys)
+	if err != nil {
+		log.Fatalf("invalid oauth token key configuration: %v", err)
+	}
+
+	if _, err := changefeed.ParseCursorKey(cfg.Users.Changes.CursorKey); err != nil {
+		log.Fatalf("invalid users.changes configuration: %v", err)
+	}
+
+	if err := allManager.ProjectTemplate(cfg.Projects.Template).Validate(cfg.Roles.MaxExpiration); err != nil {
+		log.Fatalf("invalid projects.template configuration: %v", err)
+	}
+
+	if _, err := http_transport.ParseTrustedProxies(cfg.Proxies.Trusted); err != nil {
+		log.Fatalf("invalid proxies configuration: %v", err)
+	}
+
+	store, err := blobstore.New(allManager.StorageOptions(cfg))
+	if err != nil {
+		log.Fatalf("invalid storage configuration: %v", err)
+	}
+
+	managers, err := allManager.NewManagers(gormDB, regions, cfg, passwords, tokenKeys, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Start the import workers, resuming any jobs interrupted by a restart
+	managers.ImportManager.Start(context.Background())
+	// Send the project digests that fall due
+	managers.ReportManager.Start(context.Background())
+	// Delete import files and exports past their retention
+	managers.ArtifactManager.Start(context.Background())
 
 	// Create endpoint managers
 	endpointMgrs := createEndpointManagers(managers, cfg)
 
 	// Create HTTP handler without authentication
-	handler := httpHandler(endpointMgrs)
+	router := httpHandler(endpointMgrs, cfg)
+	if *verifyRoutes {
+		if err := http_transport.VerifyRoutes(router); err != nil {
+			log.Fatalf("route wiring check failed: %v", err)
+		}
+		klog.Info("Route wiring check passed")
+	}
+
+	var handler http.Handler = router
+	handler = http_transport.CORS(cfg.CORS.AllowedOrigins, router, endpointMgrs.ProjectManager.ProjectManager)(handler)
+	handler = http_transport.ConcurrencyLimit(cfg.Limits.MaxConcurrentRequests, cfg.Limits.RetryAfter)(handler)
+	handler = http_transport.ServerHeader("user-management-service")(handler)
+	handler = http_transport.Recover(panicReporter(cfg.Panics))(handler)
+	handler = http_transport.RequestID(handler)
 
 	// Start the server
 	port := cfg.Bind.HTTP
@@ -69,44 +262,189 @@ func main() {
 	log.Fatal(srv.ListenAndServe())
 }
 
+// panicReporter returns the reporter configured for panics, or nil when
+// they are only logged
+func panicReporter(cfg cmd.PanicsConfig) http_transport.PanicReporter {
+	if cfg.ReportURL == "" {
+		return nil
+	}
+	timeout := cfg.ReportTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return http_transport.NewWebhookPanicReporter(cfg.ReportURL, timeout)
+}
+
 func createEndpointManagers(managers *allManager.Managers, cfg cmd.Config) *endpointManagers {
-	OauthCfg := cfg.OAuth
-	// Initialize OAuth providers
-	providerConfigs := map[string]oauth.ProviderConfig{
-		"google": {
-			ClientID:     OauthCfg.Google.ClientID,
-			ClientSecret: OauthCfg.Google.ClientSecret,
-			RedirectURL:  OauthCfg.Google.RedirectURL,
-			Scopes:       OauthCfg.Google.Scopes,
-		},
-		"facebook": {
-			ClientID:     OauthCfg.Facebook.ClientID,
-			ClientSecret: OauthCfg.Facebook.ClientSecret,
-			RedirectURL:  OauthCfg.Facebook.RedirectURL,
-			Scopes:       OauthCfg.Facebook.Scopes,
-		},
+	providerFactory := managers.OAuthProviders
+	oauthLogins := oauthlogin.NewManager(oauthlogin.ProjectAccounts{Users: managers.ProjectUserManager}, managers.RoleManager, providerFactory, managers.LoginManager)
+
+	// One limiter is shared by both API prefixes so the alias cannot double
+	// a project's allowance
+	var authorizeLimiter *ratelimit.Limiter
+	if cfg.Authorize.RateLimit > 0 {
+		window := cfg.Authorize.RateWindow
+		if window <= 0 {
+			window = time.Minute
+		}
+		authorizeLimiter = ratelimit.New(cfg.Authorize.RateLimit, window)
+	}
+
+	// Unique ID checks are called as the user types, so the default allowance
+	// is generous; it is there to slow down enumerating the taken IDs
+	var uniqueIDLimiter *ratelimit.Limiter
+	if rate := cfg.Projects.UniqueIDCheckRate; rate >= 0 {
+		if rate == 0 {
+			rate = defaultUniqueIDCheckRate
+		}
+		uniqueIDLimiter = ratelimit.New(rate, time.Minute)
 	}
 
-	providerFactory := oauth.NewProviderFactory(providerConfigs)
+	adminEndpoint := endpoints.NewAdminEndpoint(managers.DB, managers.Regions, managers.TokenKeyManager, managers.ProjectManager)
+	adminEndpoint.Config = cfg.Redacted().Document
+
+	apiTokensEndpoint := endpoints.NewAPITokensEndpoint(managers.APITokenManager)
+	apiTokensEndpoint.SuperAdmin = func(ctx context.Context, userID uuid.UUID) (bool, error) {
+		superAdmin, err := auth.IsSuperAdmin(managers.DB, userID)
+		if err != nil {
+			auth.Log.For(ctx).Errorf("Error fetching role: %v", err)
+		}
+		return superAdmin, err
+	}
+
+	oauthEndpoint := endpoints.NewOAuthEndpoint(oauthLogins, managers.ProjectManager, managers.OAuthStates, providerFactory, managers.ConsentManager)
+	oauthEndpoint.StateTTL = cfg.OAuth.State.TTL
 
 	return &endpointManagers{
-		ProjectManager:     endpoints.NewProjectsEndpoint(managers.ProjectManager),
+		AuthManager:        &endpoints.AuthEndpoint{DB: managers.DB, Passwords: managers.Passwords, Consents: managers.ConsentManager},
+		ProjectManager:     endpoints.NewProjectsEndpoint(managers.ProjectManager, managers.ArtifactManager),
 		RoleManager:        endpoints.NewRolesEndpoint(managers.RoleManager),
 		PolicyManager:      endpoints.NewPoliciesEndpoint(managers.PolicyManager),
-		UserManager:        endpoints.NewUsersEndpoint(managers.UserManager),
-		ProjectUserManager: endpoints.NewProjectUsersEndpoint(managers.ProjectUserManager),
-		OAuthManager:       endpoints.NewOAuthEndpoint(managers.ProjectUserManager, providerFactory),
+		UserManager:        endpoints.NewUsersEndpoint(managers.UserManager, managers.RoleManager),
+		ProjectUserManager: endpoints.NewProjectUsersEndpoint(managers.ProjectUserManager, managers.RoleManager),
+		OAuthManager:       oauthEndpoint,
+		ImportManager:      endpoints.NewImportsEndpoint(managers.ImportManager),
+		WebhookManager:     endpoints.NewWebhooksEndpoint(managers.WebhookManager),
+		AuditManager:       endpoints.NewAuditEndpoint(managers.AuditManager),
+		MagicLinkManager:   endpoints.NewMagicLinkEndpoint(managers.MagicLinkManager, managers.ConsentManager),
+		ProjectPasswords:   endpoints.NewProjectPasswordEndpoint(managers.ProjectUserManager, managers.ConsentManager),
+		AuthConfigManager:  endpoints.NewAuthConfigEndpoint(managers.ProjectManager, oauthEndpoint),
+		WebAuthnManager:    endpoints.NewWebAuthnEndpoint(managers.WebAuthnManager, managers.ConsentManager),
+		APITokenManager:    apiTokensEndpoint,
+		ServiceAccounts:    endpoints.NewServiceAccountsEndpoint(managers.UserManager, managers.RoleManager),
+		AuthorizeManager:   endpoints.NewAuthorizeEndpoint(managers.PolicyManager, managers.APITokenManager),
+		LoginManager:       endpoints.NewLoginsEndpoint(managers.LoginManager),
+		VersionManager:     endpoints.NewVersionEndpoint(),
+		HealthManager:      endpoints.NewHealthEndpoint(managers.DB),
+		RoutesManager:      endpoints.NewRoutesEndpoint(),
+		ErrorCatalog:       endpoints.NewErrorCatalogEndpoint(),
+		AdminManager:       adminEndpoint,
+		ReportManager:      endpoints.NewReportsEndpoint(managers.ReportManager),
+		MeManager:          endpoints.NewMeEndpoint(managers.UserManager, managers.RoleManager, managers.PolicyManager),
+		ArtifactManager:    endpoints.NewArtifactsEndpoint(managers.ArtifactManager),
+		ChangesManager:     endpoints.NewChangesEndpoint(managers.ChangeFeed, cfg.Users.Changes.MaxWait),
+		Introspection: endpoints.NewIntrospectionEndpoint(managers.AuthUsers, managers.ProjectUserManager,
+			cfg.Auth.Introspection.MaxBatch, cfg.Auth.Introspection.Workers),
+		Consents: endpoints.NewConsentsEndpoint(managers.ConsentManager, managers.ProjectUserManager),
 		// Initialize other endpoint managers as needed
+
+		authorizeLimiter: authorizeLimiter,
+		uniqueIDLimiter:  uniqueIDLimiter,
+		authUsers:        managers.AuthUsers,
+		roleNetworks:     managers.RoleNetworks,
+		consentManager:   managers.ConsentManager,
+		projectUsers:     managers.ProjectUserManager,
+		sessions:         sessionManager(cfg),
+		trustedProxies:   trustedProxies(cfg),
+	}
+}
+
+// trustedProxies returns the proxies of proxies.trusted, which main has
+// checked already
+func trustedProxies(cfg cmd.Config) http_transport.TrustedProxies {
+	trusted, err := http_transport.ParseTrustedProxies(cfg.Proxies.Trusted)
+	if err != nil {
+		klog.Errorf("invalid proxies.trusted: %v", err)
 	}
+	return trusted
 }
 
-func httpHandler(ep *endpointManagers) http.Handler {
+// sessionManager builds the manager of the session cookie set on login,
+// signed with a key derived from the JWT secret
+func sessionManager(cfg cmd.Config) *sessionauth.SessionManager {
+	secret := cfg.Auth.JWTSecret
+	if secret == "" {
+		secret = auth.DefaultJWTSecret
+	}
+	key := sha256.Sum256([]byte("session cookies:" + secret))
+	return sessionauth.NewSessionManager(key[:], nil, cfg.Auth.RememberFor, cfg.Auth.SecureCookies)
+}
+
+func httpHandler(ep *endpointManagers, cfg cmd.Config) *mux.Router {
 	r := mux.NewRouter()
+	// The client IP is resolved first, as Authorization checks it against
+	// the networks of roles
+	r.Use(http_transport.ClientIP(ep.trustedProxies))
+	r.Use(http_transport.Authorization(ep.AuthManager.DB, ep.authUsers, ep.roleNetworks, ep.consentManager, ep.projectUsers))
+	r.Methods("GET").Path("/metrics").Handler(metrics.Handler())
+	http_transport.AddHealthRoutes(r, ep.HealthManager)
+
+	authRouter := r.PathPrefix("/auth").Subrouter()
+	authRouter.Use(http_transport.ClientInfo)
+	http_transport.AddAuthRoutes(authRouter, ep.AuthManager, ep.sessions)
 
-	apiRouter := r.PathPrefix("/api").Subrouter()
+	if cfg.AdminUI.Enabled {
+		r.PathPrefix(adminui.Prefix).Handler(adminui.Handler())
+	}
+
+	v1Router := r.PathPrefix("/api/v1").Subrouter()
+	v1Router.Use(http_transport.APIVersion("v1"), http_transport.AuditActor, http_transport.ClientInfo)
+	mountV1Routes(v1Router, ep)
+
+	// The unversioned prefix is a deprecated alias of v1. It is registered
+	// after the versioned prefixes so that /api/v1/... is never captured by
+	// /api/{projectId}/...
+	var sunset time.Time
+	if cfg.API.LegacySunset != "" {
+		parsed, err := time.Parse(time.DateOnly, cfg.API.LegacySunset)
+		if err != nil {
+			klog.Errorf("invalid api.legacy_sunset %q: %v", cfg.API.LegacySunset, err)
+		}
+		sunset = parsed
+	}
+	legacyRouter := r.PathPrefix("/api").Subrouter()
+	legacyRouter.Use(http_transport.APIVersion("v1"), http_transport.Deprecated(sunset, "/api/v1"), http_transport.AuditActor, http_transport.ClientInfo)
+	mountV1Routes(legacyRouter, ep)
+
+	ep.RoutesManager.List = func() ([]endpoints.RouteInfo, error) {
+		return http_transport.ListRoutes(r)
+	}
+	ep.ErrorCatalog.Catalog = func() (*endpoints.ErrorCatalogResponse, error) {
+		return http_transport.ErrorCatalog(r)
+	}
+	routes, err := http_transport.ListRoutes(r)
+	if err != nil {
+		klog.Errorf("cannot print routes: %v", err)
+	}
+	for _, route := range routes {
+		klog.Infof("\t%s %s (%s)\n", route.Method, route.Path, route.Auth)
+	}
+
+	return r
+}
+
+// mountV1Routes registers the v1 API on a router. A future version mounts
+// its own prefix with this function and then overrides only the routes whose
+// behavior changes, so unchanged endpoints stay shared between versions.
+func mountV1Routes(apiRouter *mux.Router, ep *endpointManagers) {
+	apiRouter.Use(http_transport.ProjectContext(ep.ProjectManager.ProjectManager))
 
 	projectRouter := apiRouter.PathPrefix("/projects").Subrouter()
-	http_transport.AddProjectRoutes(projectRouter, ep.ProjectManager)
+	http_transport.AddWebhookRoutes(projectRouter, ep.WebhookManager)
+	http_transport.AddAPITokenRoutes(projectRouter, ep.APITokenManager)
+	http_transport.AddServiceAccountRoutes(projectRouter, ep.ServiceAccounts)
+	http_transport.AddReportRoutes(projectRouter, ep.ReportManager)
+	http_transport.AddProjectRoutes(projectRouter, ep.ProjectManager, ep.uniqueIDLimiter)
 
 	rolesRouter := apiRouter.PathPrefix("/roles").Subrouter()
 	http_transport.AddRoleRoutes(rolesRouter, ep.RoleManager)
@@ -114,30 +452,51 @@ func httpHandler(ep *endpointManagers) http.Handler {
 	policiesRouter := apiRouter.PathPrefix("/policies").Subrouter()
 	http_transport.AddPolicyRoutes(policiesRouter, ep.PolicyManager)
 
-	projectUserRouter := apiRouter.PathPrefix("/{projectId}/users").Subrouter()
-	http_transport.AddProjectUserRoutes(projectUserRouter, ep.ProjectUserManager)
+	usersRouter := apiRouter.PathPrefix("/users").Subrouter()
+	http_transport.AddUserValidationRoutes(usersRouter, ep.UserManager)
+	http_transport.AddUserMembershipRoutes(usersRouter, ep.UserManager)
+	http_transport.AddUserRoutes(usersRouter, ep.UserManager)
+
+	meRouter := apiRouter.PathPrefix("/me").Subrouter()
+	http_transport.AddMyConsentRoutes(meRouter, ep.Consents)
+	http_transport.AddMeRoutes(meRouter, ep.MeManager)
+
+	auditRouter := apiRouter.PathPrefix("/audit").Subrouter()
+	http_transport.AddAuditRoutes(auditRouter, ep.AuditManager)
 
 	oauthRouter := apiRouter.PathPrefix("/oauth_users").Subrouter()
 	http_transport.AddOAuthRoutes(oauthRouter, ep.OAuthManager)
 
-	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
-		path, err := route.GetPathTemplate()
-		if err != nil {
-			return nil
-		}
+	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
+	http_transport.AddAdminRoutes(adminRouter, ep.AdminManager)
+	http_transport.AddArtifactRoutes(adminRouter.PathPrefix("/artifacts").Subrouter(), ep.ArtifactManager)
+	http_transport.AddUserLookupRoutes(adminRouter.PathPrefix("/users").Subrouter(), ep.UserManager)
 
-		methods, err := route.GetMethods()
-		if err != nil {
-			return nil
-		}
+	authRouter := apiRouter.PathPrefix("/auth").Subrouter()
+	http_transport.AddIntrospectionRoutes(authRouter, ep.Introspection)
 
-		klog.Infof("\t%v %s\n", methods, path)
+	// The project scoped prefixes match any first segment, so they come after
+	// every fixed one; /admin/users/lookup would otherwise be taken for a
+	// user of a project called admin
+	projectUserRouter := apiRouter.PathPrefix("/{projectId}/users").Subrouter()
+	http_transport.AddImportRoutes(projectUserRouter, ep.ImportManager)
+	http_transport.AddUserChangeRoutes(projectUserRouter, ep.ChangesManager)
+	http_transport.AddWebAuthnCredentialRoutes(projectUserRouter, ep.WebAuthnManager)
+	http_transport.AddLoginHistoryRoutes(projectUserRouter, ep.LoginManager)
+	http_transport.AddConsentRoutes(projectUserRouter, ep.Consents)
+	http_transport.AddProjectUserRoutes(projectUserRouter, ep.ProjectUserManager)
 
-		return nil
-	})
-	if err != nil {
-		klog.Errorf("cannot print routes: %v", err)
-	}
+	projectRolesRouter := apiRouter.PathPrefix("/{projectId}/roles").Subrouter()
+	http_transport.AddProjectRoleRoutes(projectRolesRouter, ep.RoleManager)
 
-	return r
+	projectAuthRouter := apiRouter.PathPrefix("/{projectId}/auth").Subrouter()
+	http_transport.AddMagicLinkRoutes(projectAuthRouter, ep.MagicLinkManager)
+	http_transport.AddProjectPasswordRoutes(projectAuthRouter, ep.ProjectPasswords)
+	http_transport.AddWebAuthnLoginRoutes(projectAuthRouter, ep.WebAuthnManager)
+	http_transport.AddAuthConfigRoutes(projectAuthRouter, ep.AuthConfigManager)
+
+	http_transport.AddAuthorizeRoutes(apiRouter, ep.AuthorizeManager, ep.authorizeLimiter)
+	http_transport.AddVersionRoutes(apiRouter, ep.VersionManager)
+	http_transport.AddRouteListRoutes(apiRouter, ep.RoutesManager)
+	http_transport.AddErrorCatalogRoutes(apiRouter, ep.ErrorCatalog)
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/metrics"
+)
+
+// TestMain fails the suite when any request it served was answered with an
+// error code its route does not declare, so the error catalog cannot drift
+// from what the handlers return
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if undeclared := undeclaredErrors(); code == 0 && len(undeclared) > 0 {
+		fmt.Fprintf(os.Stderr, "routes answered with errors missing from their Errors:\n  %s\n", strings.Join(undeclared, "\n  "))
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// undeclaredErrors returns the samples of ums_undeclared_errors_total
+func undeclaredErrors() []string {
+	var buf bytes.Buffer
+	metrics.WriteAll(&buf)
+
+	var samples []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "ums_undeclared_errors_total{") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
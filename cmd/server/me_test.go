@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestMyContext(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	primary := testutil.AProject().WithRole("Editor").Build(t, server.DB)
+	other := testutil.AProject().WithRole("Viewer").Build(t, server.DB)
+	editor, viewer := primary.Roles["Editor"], other.Roles["Viewer"]
+	user := testutil.AUser(t, server.DB, "me@example.com", editor, primary.Project)
+	if _, err := server.Managers.UserManager.AddUserToProject(context.Background(), user.ID, other.Project.ID, viewer.ID); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+
+	// scoped builds a policy for role that applies only in project
+	scoped := func(name string, role schemas.Role, project schemas.Project) {
+		t.Helper()
+		policy := testutil.APolicy(name, "users", "write").ForRole(role).Build(t, server.DB)
+		if err := server.DB.Model(&policy).Update("project_id", project.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.APolicy("read users", "users", "read").ForRole(editor).Build(t, server.DB)
+	scoped("edit primary users", editor, primary.Project)
+	scoped("edit other users", editor, other.Project)
+	scoped("view other users", viewer, other.Project)
+
+	status, body := server.call(t, http.MethodGet, "/api/v1/me/context", tokenFor(t, user), nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/me/context = %d %s", status, body)
+	}
+	var response struct {
+		User struct {
+			ID    string `json:"id"`
+			Email string `json:"email"`
+		} `json:"user"`
+		Projects []struct {
+			ProjectID string `json:"project_id"`
+			Primary   bool   `json:"primary"`
+			Role      *struct {
+				Name string `json:"name"`
+			} `json:"role"`
+			Policies []struct {
+				Name string `json:"name"`
+			} `json:"policies"`
+		} `json:"projects"`
+	}
+	decode(t, body, &response)
+
+	if response.User.ID != user.ID.String() || response.User.Email != "me@example.com" {
+		t.Errorf("user = %+v, want the caller", response.User)
+	}
+	want := []struct {
+		project  string
+		primary  bool
+		role     string
+		policies []string
+	}{
+		{primary.Project.ID.String(), true, "Editor", []string{"read users", "edit primary users"}},
+		{other.Project.ID.String(), false, "Viewer", []string{"view other users"}},
+	}
+	if len(response.Projects) != len(want) {
+		t.Fatalf("projects = %+v, want the primary project and the membership", response.Projects)
+	}
+	for i, w := range want {
+		got := response.Projects[i]
+		if got.ProjectID != w.project || got.Primary != w.primary || got.Role == nil || got.Role.Name != w.role {
+			t.Errorf("project %d = %+v, want %s with role %s", i, got, w.project, w.role)
+			continue
+		}
+		var names []string
+		for _, p := range got.Policies {
+			names = append(names, p.Name)
+		}
+		sort.Strings(names)
+		sort.Strings(w.policies)
+		if strings.Join(names, ", ") != strings.Join(w.policies, ", ") {
+			t.Errorf("policies in %s = %v, want %v", w.role, names, w.policies)
+		}
+	}
+
+	if status, _ := server.call(t, http.MethodGet, "/api/v1/me/context", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me/context without a token = %d, want 401", status)
+	}
+}
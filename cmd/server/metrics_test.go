@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestAuthenticationMetrics(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, _ := aSuperAdmin(t, server.DB)
+
+	const (
+		success = `ums_login_attempts_total{result="success",method="password",project="none"}`
+		failure = `ums_login_attempts_total{result="failure",method="password",project="none"}`
+		expired = `ums_token_validation_failures_total{reason="expired"}`
+	)
+	scrape := func() []byte {
+		t.Helper()
+		status, body := server.call(t, http.MethodGet, "/metrics", "", nil)
+		if status != http.StatusOK {
+			t.Fatalf("GET /metrics = %d", status)
+		}
+		return body
+	}
+	before := scrape()
+
+	if status, body := server.call(t, http.MethodPost, "/auth/login", "", map[string]string{"email": root.Email, "password": testutil.DefaultPassword}); status != http.StatusOK {
+		t.Fatalf("login = %d %s, want 200", status, body)
+	}
+	for i := 0; i < 2; i++ {
+		if status, body := server.call(t, http.MethodPost, "/auth/login", "", map[string]string{"email": root.Email, "password": "wrong password"}); status != http.StatusUnauthorized {
+			t.Fatalf("login with a wrong password = %d %s, want 401", status, body)
+		}
+	}
+	stale, err := auth.GenerateToken(root.ID, root.Email, root.RoleId, root.ProjectId, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body := server.call(t, http.MethodGet, "/api/v1/users/"+root.ID.String(), stale, nil); status != http.StatusUnauthorized {
+		t.Fatalf("a call with an expired token = %d %s, want 401", status, body)
+	}
+
+	after := scrape()
+	for _, tc := range []struct {
+		sample string
+		want   float64
+	}{
+		{success, 1},
+		{failure, 2},
+		{expired, 1},
+	} {
+		if got := testutil.SampleValue(t, after, tc.sample) - testutil.SampleValue(t, before, tc.sample); got != tc.want {
+			t.Errorf("%s grew by %v, want %v", tc.sample, got, tc.want)
+		}
+	}
+}
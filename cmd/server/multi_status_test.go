@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestBulkDeleteReportsEachPolicy(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+	first := testutil.APolicy("first", "users", "read").Build(t, server.DB)
+	second := testutil.APolicy("second", "users", "write").Build(t, server.DB)
+	system := testutil.APolicy("system", "users", "delete").Build(t, server.DB)
+	if err := server.DB.Model(&system).Update("is_system", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	unknown := uuid.NewString()
+
+	status, body := server.call(t, http.MethodPost, "/api/v1/policies/bulk-delete", token, map[string]any{
+		"ids": []string{first.ID.String(), "not-a-uuid", unknown, first.ID.String(), system.ID.String(), second.ID.String()},
+	})
+	if status != http.StatusMultiStatus {
+		t.Fatalf("POST /api/v1/policies/bulk-delete = %d %s, want 207", status, body)
+	}
+	var response endpoints.MultiStatusResponse
+	decode(t, body, &response)
+
+	want := []endpoints.ItemResult{
+		{ID: first.ID.String(), Status: http.StatusOK},
+		{ID: "not-a-uuid", Status: http.StatusBadRequest, Code: "INVALID_POLICY_ID"},
+		{ID: unknown, Status: http.StatusNotFound, Code: "NOT_FOUND"},
+		{ID: system.ID.String(), Status: http.StatusConflict, Code: "SYSTEM_POLICY"},
+		{ID: second.ID.String(), Status: http.StatusOK},
+	}
+	if len(response.Results) != len(want) {
+		t.Fatalf("results = %+v, want one per distinct ID", response.Results)
+	}
+	for i, w := range want {
+		got := response.Results[i]
+		if got.ID != w.ID || got.Status != w.Status || got.Code != w.Code || (w.Code != "") != (got.Error != "") {
+			t.Errorf("result %d = %+v, want %+v with an error only on failure", i, got, w)
+		}
+	}
+	if response.Succeeded != 2 || response.Failed != 3 {
+		t.Errorf("succeeded %d and failed %d, want 2 and 3", response.Succeeded, response.Failed)
+	}
+
+	// The failures did not stop the other deletions
+	var left []schemas.Policy
+	if err := server.DB.Find(&left, "id IN ?", []uuid.UUID{first.ID, second.ID, system.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != system.ID {
+		t.Errorf("policies left = %+v, want only the system one", left)
+	}
+
+	third := testutil.APolicy("third", "users", "read").Build(t, server.DB)
+	status, body = server.call(t, http.MethodPost, "/api/v1/policies/bulk-delete", token, map[string]any{"ids": []string{third.ID.String()}})
+	if status != http.StatusOK {
+		t.Errorf("a bulk delete without failures = %d %s, want 200", status, body)
+	}
+	if status, body := server.call(t, http.MethodPost, "/api/v1/policies/bulk-delete", token, map[string]any{"ids": []string{}}); status != http.StatusBadRequest {
+		t.Errorf("a bulk delete of nothing = %d %s, want 400", status, body)
+	}
+}
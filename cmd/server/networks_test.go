@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestRoleNetworksOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("Admin").Build(t, server.DB)
+	role := built.Roles["Admin"]
+	admin := testutil.AUser(t, server.DB, "admin@example.com", role, built.Project)
+	token := tokenFor(t, admin)
+	ctx := context.Background()
+	restrict := func(cidrs ...string) {
+		t.Helper()
+		if _, err := server.Managers.RoleManager.UpdateRole(ctx, role.ID, role.Name, "", 0, cidrs); err != nil {
+			t.Fatalf("UpdateRole: %v", err)
+		}
+	}
+	refused := func(status int, body []byte, what string) {
+		t.Helper()
+		var apiErr http_transport.ErrorResponse
+		decode(t, body, &apiErr)
+		if status != http.StatusForbidden || apiErr.Code != "IP_NOT_ALLOWED" {
+			t.Errorf("%s = %d %s, want 403 IP_NOT_ALLOWED", what, status, body)
+		}
+	}
+
+	// The test server is reached over IPv4 loopback
+	restrict("10.0.0.0/8", "2001:db8::/32")
+	status, body := server.call(t, http.MethodGet, "/api/v1/me/context", token, nil)
+	refused(status, body, "GET /api/v1/me/context from outside the role's networks")
+	status, body = server.call(t, http.MethodPost, "/auth/login", "", map[string]string{"email": admin.Email, "password": testutil.DefaultPassword})
+	refused(status, body, "a login from outside the role's networks")
+
+	// Proxy headers are not trusted from a peer that is not a trusted proxy
+	if status := forwardedStatus(t, server, token, "10.1.2.3"); status != http.StatusForbidden {
+		t.Errorf("a request claiming an allowed address in X-Forwarded-For = %d, want 403", status)
+	}
+
+	restrict("127.0.0.0/8")
+	if status, body := server.call(t, http.MethodGet, "/api/v1/me/context", token, nil); status != http.StatusOK {
+		t.Errorf("GET /api/v1/me/context right after allowing loopback = %d %s, want 200", status, body)
+	}
+	if status, body := server.call(t, http.MethodPost, "/auth/login", "", map[string]string{"email": admin.Email, "password": testutil.DefaultPassword}); status != http.StatusOK {
+		t.Errorf("a login right after allowing loopback = %d %s, want 200", status, body)
+	}
+}
+
+// forwardedStatus calls GET /api/v1/me/context through proxies, listed in
+// X-Forwarded-For, and returns the response status
+func forwardedStatus(t *testing.T, server *testServer, token string, forwardedFor ...string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/me/context", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Forwarded-For", strings.Join(forwardedFor, ", "))
+	req.Header.Set("X-Real-IP", forwardedFor[0])
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRoleNetworksBehindTrustedProxies(t *testing.T) {
+	// The test server is reached over IPv4 loopback, the load balancer here
+	server := newTestServer(t, cmd.Config{Proxies: cmd.ProxiesConfig{Trusted: []string{"127.0.0.0/8", "192.168.0.0/16"}}})
+	built := testutil.AProject().WithRole("Admin").Build(t, server.DB)
+	role := built.Roles["Admin"]
+	admin := testutil.AUser(t, server.DB, "admin@example.com", role, built.Project)
+	token := tokenFor(t, admin)
+	if _, err := server.Managers.RoleManager.UpdateRole(context.Background(), role.ID, role.Name, "", 0, []string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name         string
+		forwardedFor []string
+		want         int
+	}{
+		{"a client in the role's networks", []string{"10.1.2.3"}, http.StatusOK},
+		{"a client outside them", []string{"203.0.113.7"}, http.StatusForbidden},
+		{"a client behind a chain of trusted proxies", []string{"10.1.2.3", "192.168.1.1"}, http.StatusOK},
+		// The proxy appends the peer it got the request from; what that peer
+		// wrote before it is not to be believed
+		{"a client claiming an allowed address", []string{"10.1.2.3", "203.0.113.7"}, http.StatusForbidden},
+		{"a malformed entry", []string{"10.1.2.3", "not an address"}, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := forwardedStatus(t, server, token, tc.forwardedFor...); status != tc.want {
+				t.Errorf("X-Forwarded-For %v = %d, want %d", tc.forwardedFor, status, tc.want)
+			}
+		})
+	}
+}
+
+func TestRoleNetworksApplyToProjectUsersOnTheirOwnRoutes(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	role := built.Roles["member"]
+	alice := built.Users["a@example.com"]
+	token, err := auth.GenerateToken(alice.ID, alice.Email, alice.RoleId, built.Project.ID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to issue a token: %v", err)
+	}
+	restrict := func(cidrs ...string) {
+		t.Helper()
+		if _, err := server.Managers.RoleManager.UpdateRole(context.Background(), role.ID, role.Name, "", 0, cidrs); err != nil {
+			t.Fatalf("UpdateRole: %v", err)
+		}
+	}
+
+	usersPath := "/api/v1/" + built.Project.ID.String() + "/users/" + alice.ID.String()
+	paths := []string{usersPath, usersPath + "/webauthn/credentials"}
+	// The test server is reached over IPv4 loopback
+	restrict("10.0.0.0/8")
+	for _, path := range paths {
+		status, body := server.call(t, http.MethodGet, path, token, nil)
+		var apiErr http_transport.ErrorResponse
+		decode(t, body, &apiErr)
+		if status != http.StatusForbidden || apiErr.Code != "IP_NOT_ALLOWED" {
+			t.Errorf("GET %s as the user from outside the role's networks = %d %s, want 403 IP_NOT_ALLOWED", path, status, body)
+		}
+	}
+
+	restrict("127.0.0.0/8")
+	for _, path := range paths {
+		if status, body := server.call(t, http.MethodGet, path, token, nil); status != http.StatusOK {
+			t.Errorf("GET %s as the user after allowing loopback = %d %s, want 200", path, status, body)
+		}
+	}
+}
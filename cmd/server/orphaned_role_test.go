@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestUsersWhoseRoleWasDeletedAreDenied(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		deactivate bool
+		wantStatus string
+	}{
+		{"kept", false, schemas.UserStatusActive},
+		{"deactivated", true, schemas.UserStatusDeactivated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := cmd.Config{}
+			cfg.Users.DeactivateOrphaned = tc.deactivate
+			server := newTestServer(t, cfg)
+			testutil.CaptureKlog(t)
+			built := testutil.AProject().WithRole("Gateway").WithRole(auth.SuperAdminRole).Build(t, server.DB)
+			testutil.APolicy("introspect tokens", "tokens", "introspect").ForRole(built.Roles["Gateway"]).Build(t, server.DB)
+			gateway := testutil.AUser(t, server.DB, "gateway@example.com", built.Roles["Gateway"], built.Project)
+			admin := testutil.AUser(t, server.DB, "admin@example.com", built.Roles[auth.SuperAdminRole], built.Project)
+			for _, role := range built.Roles {
+				if err := server.DB.Unscoped().Delete(&role).Error; err != nil {
+					t.Fatalf("failed to delete role %s: %v", role.Name, err)
+				}
+			}
+
+			introspect := endpoints.IntrospectBatchRequest{Tokens: []string{"x"}}
+			for _, call := range []struct {
+				user         schemas.User
+				method, path string
+				body         any
+			}{
+				{gateway, http.MethodPost, "/api/v1/auth/introspect-batch", introspect},
+				{admin, http.MethodGet, "/api/v1/admin/stats", nil},
+			} {
+				if status, body := server.call(t, call.method, call.path, tokenFor(t, call.user), call.body); status != http.StatusForbidden {
+					t.Errorf("%s %s by %s = %d %s, want 403", call.method, call.path, call.user.Email, status, body)
+				}
+
+				var user schemas.User
+				if err := server.DB.First(&user, "id = ?", call.user.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if user.Status != tc.wantStatus {
+					t.Errorf("%s is %s, want %s", call.user.Email, user.Status, tc.wantStatus)
+				}
+				// A deactivated user is turned away before their role is looked up
+				if status, body := server.call(t, call.method, call.path, tokenFor(t, call.user), call.body); status != http.StatusForbidden {
+					t.Errorf("%s %s by %s again = %d %s, want 403", call.method, call.path, call.user.Email, status, body)
+				}
+			}
+
+			var audited int64
+			if err := server.DB.Model(&schemas.AuditEvent{}).Where("action = ?", audit.ActionStatusChange).Count(&audited).Error; err != nil {
+				t.Fatal(err)
+			}
+			if want := map[bool]int64{false: 0, true: 2}[tc.deactivate]; audited != want {
+				t.Errorf("audited %d status changes, want %d", audited, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestPatchPolicyOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	role := testutil.ARole("Editor").Build(t, server.DB)
+	policy := testutil.APolicy("edit users", "users", "write").ForRole(role).Build(t, server.DB)
+	path := "/api/v1/policies/" + policy.ID.String()
+
+	status, body := server.call(t, http.MethodPatch, path, rootToken, map[string]string{"effect": "deny"})
+	if status != http.StatusOK {
+		t.Fatalf("PATCH %s = %d %s", path, status, body)
+	}
+	var patched endpoints.PatchPolicyResponse
+	decode(t, body, &patched)
+	if p := patched.Policy; p.Effect != "deny" || p.Name != "edit users" || p.Resource != "users" || p.Action != "write" {
+		t.Errorf("policy = %+v, want only the effect changed", p)
+	}
+
+	status, body = server.call(t, http.MethodPatch, path, rootToken, map[string]string{"effect": "maybe"})
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if status != http.StatusBadRequest || apiErr.Code != "INVALID_EFFECT" {
+		t.Errorf("PATCH %s with effect maybe = %d %s, want 400 INVALID_EFFECT", path, status, body)
+	}
+}
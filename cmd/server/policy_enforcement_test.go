@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestWildcardResourceRoleOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("everything").WithRole("member").Build(t, server.DB)
+	testutil.APolicy("everything", "*", "*").ForRole(built.Roles["everything"]).Build(t, server.DB)
+	token := tokenFor(t, testutil.AUser(t, server.DB, "owner@example.com", built.Roles["everything"], built.Project))
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+
+	path := "/api/v1/projects/" + built.Project.ID.String() + "/oauth-providers/github"
+	provider := map[string]string{"client_id": "acme", "client_secret": "s3cret"}
+	if status, body := server.call(t, http.MethodPut, path, token, provider); status != http.StatusOK {
+		t.Errorf("PUT %s with */* = %d %s, want 200", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodPut, path, memberToken, provider); status != http.StatusForbidden {
+		t.Errorf("PUT %s without policies = %d %s, want 403", path, status, body)
+	}
+}
+
+func TestSavedDenyBlocksTheRouteOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("reader").Build(t, server.DB)
+	role := built.Roles["reader"]
+	testutil.APolicy("all users", "users", "*").ForRole(role).Build(t, server.DB)
+	extra := testutil.APolicy("list projects", "projects", "read").ForRole(role).Build(t, server.DB)
+	token := tokenFor(t, testutil.AUser(t, server.DB, "reader@example.com", role, built.Project))
+
+	path := "/api/v1/" + built.Project.ID.String() + "/users"
+	if status, body := server.call(t, http.MethodGet, path, token, nil); status != http.StatusOK {
+		t.Fatalf("GET %s with users:* = %d %s, want 200", path, status, body)
+	}
+
+	deny := map[string]string{"resource": "users", "action": "read", "effect": "deny"}
+	status, body := server.call(t, http.MethodPost, "/api/v1/policies/"+extra.ID.String()+"/impact", rootToken, deny)
+	if status != http.StatusOK {
+		t.Fatalf("impact = %d %s, want 200", status, body)
+	}
+	var impact endpoints.PolicyImpactResponse
+	decode(t, body, &impact)
+	lost := false
+	for _, change := range impact.Lost {
+		lost = lost || change.Resource == "users" && change.Action == "read"
+	}
+	if !lost {
+		t.Fatalf("impact lost = %+v, want users:read", impact.Lost)
+	}
+
+	if status, body := server.call(t, http.MethodPatch, "/api/v1/policies/"+extra.ID.String(), rootToken, deny); status != http.StatusOK {
+		t.Fatalf("PATCH the policy to deny = %d %s, want 200", status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, path, token, nil); status != http.StatusForbidden {
+		t.Errorf("GET %s after the deny was saved = %d %s, want 403 as the preview said", path, status, body)
+	}
+}
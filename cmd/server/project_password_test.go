@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestProjectUserPasswordLoginAndChange(t *testing.T) {
+	cfg := cmd.Config{}
+	cfg.Passwords.MaxAgeDays = 30
+	server := newTestServer(t, cfg)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	authPath := "/api/v1/" + built.Project.ID.String() + "/auth"
+	login := func(password string) (int, []byte) {
+		return server.call(t, http.MethodPost, authPath+"/login", "", map[string]string{"email": "a@example.com", "password": password})
+	}
+	errorCode := func(body []byte) string {
+		var apiErr http_transport.ErrorResponse
+		decode(t, body, &apiErr)
+		return apiErr.Code
+	}
+
+	if status, body := login("wrong password"); status != http.StatusUnauthorized || errorCode(body) != "INVALID_CREDENTIALS" {
+		t.Fatalf("a wrong password = %d %s, want 401 INVALID_CREDENTIALS", status, body)
+	}
+	status, body := login(testutil.DefaultPassword)
+	if status != http.StatusOK {
+		t.Fatalf("POST %s/login = %d %s", authPath, status, body)
+	}
+	var response endpoints.ProjectLoginResponse
+	decode(t, body, &response)
+	if response.Token == "" || response.PasswordExpired || response.PasswordExpiresAt == nil {
+		t.Fatalf("login with a fresh password = %s", body)
+	}
+
+	changedAt := time.Now().AddDate(0, 0, -31)
+	if err := server.DB.Table(testutil.ProjectUserTable(built.Project.ID)).Where("email = ?", "a@example.com").Update("password_changed_at", changedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	status, body = login(testutil.DefaultPassword)
+	response = endpoints.ProjectLoginResponse{}
+	decode(t, body, &response)
+	if status != http.StatusOK || !response.PasswordExpired {
+		t.Fatalf("login with an expired password = %d %s", status, body)
+	}
+	if claims, err := auth.ParseToken(response.Token); err != nil || claims.Scope != auth.ScopePasswordChange {
+		t.Fatalf("claims = %+v, %v, want a token scoped to changing the password", claims, err)
+	}
+
+	change := func(current, next string) (int, []byte) {
+		return server.call(t, http.MethodPost, authPath+"/password", "", map[string]string{
+			"email": "a@example.com", "current_password": current, "new_password": next,
+		})
+	}
+	if status, body := change(testutil.DefaultPassword, testutil.DefaultPassword); status != http.StatusBadRequest || errorCode(body) != "PASSWORD_REUSED" {
+		t.Fatalf("changing to the current password = %d %s, want 400 PASSWORD_REUSED", status, body)
+	}
+	if status, body := change("wrong password", "new password"); status != http.StatusUnauthorized || errorCode(body) != "INVALID_CREDENTIALS" {
+		t.Fatalf("changing with a wrong password = %d %s, want 401 INVALID_CREDENTIALS", status, body)
+	}
+	if status, body := change(testutil.DefaultPassword, "new password"); status != http.StatusOK {
+		t.Fatalf("POST %s/password = %d %s", authPath, status, body)
+	}
+
+	status, body = login("new password")
+	response = endpoints.ProjectLoginResponse{}
+	decode(t, body, &response)
+	if status != http.StatusOK || response.PasswordExpired {
+		t.Fatalf("login after the change = %d %s", status, body)
+	}
+	if claims, err := auth.ParseToken(response.Token); err != nil || claims.Scope != "" {
+		t.Fatalf("claims = %+v, %v, want an unrestricted token", claims, err)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// projectPaths are the paths of the project CRUD routes in one style
+type projectPaths struct {
+	create, list     string
+	get, update, del func(id string) string
+}
+
+func TestVerbAndRESTfulProjectPathsBehaveTheSame(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+	const base = "/api/v1/projects"
+	restful := projectPaths{
+		create: base,
+		list:   base,
+		get:    func(id string) string { return base + "/" + id },
+		update: func(id string) string { return base + "/" + id },
+		del:    func(id string) string { return base + "/" + id },
+	}
+	verbs := projectPaths{
+		create: base + "/create",
+		list:   base + "/list",
+		get:    func(id string) string { return base + "/get/" + id },
+		update: func(id string) string { return base + "/update/" + id },
+		del:    func(id string) string { return base + "/delete/" + id },
+	}
+
+	// send calls path and checks the deprecation headers of its response,
+	// which only verb-style paths carry, naming their RESTful successor
+	send := func(t *testing.T, method, path, successor string, body any) (int, []byte) {
+		t.Helper()
+		var reader io.Reader
+		if body != nil {
+			encoded, _ := json.Marshal(body)
+			reader = bytes.NewReader(encoded)
+		}
+		req, err := http.NewRequest(method, server.URL+path, reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		deprecation, link := resp.Header.Get("Deprecation"), resp.Header.Get("Link")
+		if successor == "" {
+			if deprecation != "" || link != "" {
+				t.Errorf("%s %s is marked deprecated", method, path)
+			}
+		} else if !strings.HasPrefix(deprecation, "@") || link != "<"+successor+`>; rel="successor-version"` {
+			t.Errorf("%s %s: Deprecation = %q, Link = %q, want a date and the successor %s", method, path, deprecation, link, successor)
+		}
+		return resp.StatusCode, data
+	}
+
+	scrape := func() []byte {
+		t.Helper()
+		_, body := server.call(t, http.MethodGet, "/metrics", "", nil)
+		return body
+	}
+	before := scrape()
+
+	for _, style := range []struct {
+		name       string
+		paths      projectPaths
+		deprecated bool
+	}{
+		{"restful", restful, false},
+		{"verbs", verbs, true},
+	} {
+		t.Run(style.name, func(t *testing.T) {
+			p := style.paths
+			successor := func(path string) string {
+				if style.deprecated {
+					return path
+				}
+				return ""
+			}
+
+			status, body := send(t, http.MethodPost, p.create, successor(restful.create), map[string]string{"name": "Shop " + style.name, "unique_id": "shop_" + style.name})
+			if status != http.StatusOK && status != http.StatusCreated {
+				t.Fatalf("create = %d %s", status, body)
+			}
+			var created endpoints.CreateProjectResponse
+			decode(t, body, &created)
+			id := created.Project.ID
+
+			status, body = send(t, http.MethodGet, p.get(id), successor(restful.get(id)), nil)
+			var got endpoints.GetProjectResponse
+			decode(t, body, &got)
+			if status != http.StatusOK || got.Project.UniqueID != "shop_"+style.name {
+				t.Errorf("get = %d %s", status, body)
+			}
+
+			status, body = send(t, http.MethodGet, p.list, successor(restful.list), nil)
+			var listed endpoints.ListProjectsResponse
+			decode(t, body, &listed)
+			found := false
+			for _, project := range listed.Projects {
+				found = found || project.ID == id
+			}
+			if status != http.StatusOK || !found {
+				t.Errorf("list = %d %s, want the project listed", status, body)
+			}
+
+			status, body = send(t, http.MethodPut, p.update(id), successor(restful.update(id)), map[string]string{"name": "Renamed " + style.name})
+			var updated endpoints.UpdateProjectResponse
+			decode(t, body, &updated)
+			if status != http.StatusOK || updated.Project.Name != "Renamed "+style.name {
+				t.Errorf("update = %d %s", status, body)
+			}
+
+			status, body = send(t, http.MethodDelete, p.del(id), successor(restful.del(id)), nil)
+			var deleted endpoints.DeleteProjectResponse
+			decode(t, body, &deleted)
+			if status != http.StatusOK || !deleted.Success {
+				t.Errorf("delete = %d %s", status, body)
+			}
+			if status, body := send(t, http.MethodGet, p.get(id), successor(restful.get(id)), nil); status != http.StatusNotFound {
+				t.Errorf("get after the delete = %d %s, want 404", status, body)
+			}
+		})
+	}
+
+	// Each verb-style route counted its own requests; get was called twice
+	after := scrape()
+	for path, want := range map[string]float64{
+		`method="POST",path="/api/v1/projects/create"`:        1,
+		`method="GET",path="/api/v1/projects/get/{id}"`:       2,
+		`method="GET",path="/api/v1/projects/list"`:           1,
+		`method="PUT",path="/api/v1/projects/update/{id}"`:    1,
+		`method="DELETE",path="/api/v1/projects/delete/{id}"`: 1,
+	} {
+		sample := "ums_deprecated_requests_total{" + path + "}"
+		if got := testutil.SampleValue(t, after, sample) - testutil.SampleValue(t, before, sample); got != want {
+			t.Errorf("%s grew by %v, want %v", sample, got, want)
+		}
+	}
+
+	// Both styles are listed, the verb-style ones marked with their successor
+	status, body := server.call(t, http.MethodGet, "/api/v1/routes", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/routes = %d %s", status, body)
+	}
+	var listing endpoints.ListRoutesResponse
+	decode(t, body, &listing)
+	listed := make(map[string]endpoints.RouteInfo)
+	for _, route := range listing.Routes {
+		listed[route.Method+" "+route.Path] = route
+	}
+	for route, successor := range map[string]string{
+		"POST /api/v1/projects":               "",
+		"GET /api/v1/projects/{id}":           "",
+		"POST /api/v1/projects/create":        "/api/v1/projects",
+		"GET /api/v1/projects/get/{id}":       "/api/v1/projects/{id}",
+		"GET /api/v1/projects/list":           "/api/v1/projects",
+		"PUT /api/v1/projects/update/{id}":    "/api/v1/projects/{id}",
+		"DELETE /api/v1/projects/delete/{id}": "/api/v1/projects/{id}",
+	} {
+		info, ok := listed[route]
+		if !ok {
+			t.Errorf("%s is not listed", route)
+			continue
+		}
+		if info.Deprecated != (successor != "") || info.Successor != successor {
+			t.Errorf("%s is listed deprecated = %v with successor %q, want %q", route, info.Deprecated, info.Successor, successor)
+		}
+	}
+}
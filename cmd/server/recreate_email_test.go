@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestRecreateTheEmailOfADeletedUser(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	user := map[string]string{
+		"project_id": built.Project.ID.String(),
+		"role_id":    built.Roles["member"].ID.String(),
+		"email":      "a@example.com",
+		"password":   testutil.DefaultPassword,
+	}
+	create := func() (int, []byte) {
+		return server.call(t, http.MethodPost, "/api/v1/users", rootToken, user)
+	}
+
+	status, body := create()
+	if status != http.StatusOK {
+		t.Fatalf("POST /api/v1/users = %d %s", status, body)
+	}
+	var created endpoints.CreateUserResponse
+	decode(t, body, &created)
+	if status, body := server.call(t, http.MethodDelete, "/api/v1/users/"+created.User.ID, rootToken, nil); status != http.StatusOK && status != http.StatusNoContent {
+		t.Fatalf("DELETE the user = %d %s", status, body)
+	}
+
+	status, body = create()
+	if status != http.StatusOK {
+		t.Fatalf("recreating the deleted user's email = %d %s, want 200", status, body)
+	}
+	var recreated endpoints.CreateUserResponse
+	decode(t, body, &recreated)
+	if recreated.User.ID == created.User.ID {
+		t.Errorf("the recreated user has the deleted user's ID %s", created.User.ID)
+	}
+
+	status, body = create()
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if status != http.StatusConflict || apiErr.Code != "CONFLICT" {
+		t.Errorf("a second live user with the email = %d %s, want 409 CONFLICT", status, body)
+	}
+}
+
+func TestRestoreIntoATakenEmailIsAConflict(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	path := "/api/v1/" + built.Project.ID.String() + "/users/" + built.Users["a@example.com"].ID.String()
+
+	if status, body := server.call(t, http.MethodDelete, path, rootToken, nil); status != http.StatusOK && status != http.StatusNoContent {
+		t.Fatalf("DELETE %s = %d %s", path, status, body)
+	}
+	// Creating the email through the API would bring the deleted user back,
+	// so the new owner is inserted directly
+	other := schemas.ProjectUser{ID: uuid.New(), Email: "a@example.com", RoleId: built.Roles["member"].ID, ProjectId: built.Project.ID}
+	if err := server.DB.Table(testutil.ProjectUserTable(built.Project.ID)).Create(&other).Error; err != nil {
+		t.Fatalf("failed to take the email: %v", err)
+	}
+
+	status, body := server.call(t, http.MethodPost, path+"/restore", rootToken, nil)
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if status != http.StatusConflict || apiErr.Code != "CONFLICT" || apiErr.Error == "" {
+		t.Errorf("POST %s/restore = %d %s, want 409 CONFLICT with a message", path, status, body)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestLogsCarryNoEmailsOrPasswords(t *testing.T) {
+	redact.SetMode(redact.ModeMask)
+	t.Cleanup(func() { redact.SetMode(redact.DefaultMode) })
+
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	built.Project.Settings.MagicLink.Enabled = true
+	if err := server.DB.Save(&built.Project).Error; err != nil {
+		t.Fatalf("failed to enable magic links: %v", err)
+	}
+	project, role := built.Project.ID.String(), built.Roles["member"].ID.String()
+
+	const (
+		email    = "Jane.Secret@example.com"
+		password = "correct-horse-battery-staple"
+	)
+	logs := testutil.CaptureKlog(t)
+
+	user := map[string]string{"project_id": project, "role_id": role, "email": email, "password": password}
+	for i, want := range []int{http.StatusOK, http.StatusConflict} {
+		if status, body := server.call(t, http.MethodPost, "/api/v1/users", rootToken, user); status != want {
+			t.Fatalf("global user creation %d = %d %s, want %d", i+1, status, body, want)
+		}
+	}
+	for i, want := range []int{http.StatusOK, http.StatusConflict} {
+		if status, body := server.call(t, http.MethodPost, "/api/v1/"+project+"/users/"+role, rootToken, user); status != want {
+			t.Fatalf("project user creation %d = %d %s, want %d", i+1, status, body, want)
+		}
+	}
+	for _, login := range []map[string]string{
+		{"email": email, "password": password},
+		{"email": email, "password": password + "!"},
+		{"email": "nobody@example.com", "password": password},
+	} {
+		server.call(t, http.MethodPost, "/auth/login", "", login)
+	}
+	if status, body := server.call(t, http.MethodPost, "/api/v1/"+project+"/auth/magic-link", "", map[string]string{"email": email}); status != http.StatusOK {
+		t.Fatalf("magic link request = %d %s, want 200", status, body)
+	}
+	// A body cut short after the password must not be quoted back
+	resp, err := http.Post(server.URL+"/auth/login", "application/json",
+		strings.NewReader(`{"email": "`+email+`", "password": "`+password+`"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("a truncated login body = %d, want 400", resp.StatusCode)
+	}
+
+	logged := strings.ToLower(logs.String())
+	for _, secret := range []string{email, "nobody@example.com", password} {
+		if strings.Contains(logged, strings.ToLower(secret)) {
+			t.Errorf("the logs contain %q:\n%s", secret, logs.String())
+		}
+	}
+	if !strings.Contains(logged, "j***@example.com") {
+		t.Errorf("the logs never name the masked address, so the flows logged nothing to redact:\n%s", logs.String())
+	}
+}
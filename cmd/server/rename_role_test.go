@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestRenameRoleOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+	editor := testutil.ARole("Editor").Build(t, server.DB)
+	testutil.ARole("Viewer").Build(t, server.DB)
+	path := "/api/v1/roles/" + editor.ID.String() + "/rename"
+
+	status, body := server.call(t, http.MethodPost, path, token, map[string]string{"name": "Viewer"})
+	var failure http_transport.ErrorResponse
+	decode(t, body, &failure)
+	if status != http.StatusConflict || failure.Code != "ROLE_NAME_TAKEN" {
+		t.Fatalf("renaming to a taken name = %d %s, want 409 ROLE_NAME_TAKEN", status, body)
+	}
+
+	status, body = server.call(t, http.MethodPost, path, token, map[string]string{"name": "Writer"})
+	if status != http.StatusOK {
+		t.Fatalf("POST %s = %d %s", path, status, body)
+	}
+	var renamed map[string]struct {
+		Name string `json:"name"`
+	}
+	decode(t, body, &renamed)
+	if renamed["role"].Name != "Writer" {
+		t.Errorf("response = %s, want the role named Writer", body)
+	}
+}
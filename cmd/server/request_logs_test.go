@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestManagerLinesCarryTheRequestID(t *testing.T) {
+	logging.SetLevels(map[string]int{"projectusers": 4})
+	t.Cleanup(func() { logging.SetLevels(nil) })
+	server := newTestServer(t, cmd.Config{})
+	_, token := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	user := built.Users["a@example.com"]
+	// Request IDs are assigned around the router, as main does
+	front := httptest.NewServer(http_transport.RequestID(server.Router))
+	t.Cleanup(front.Close)
+	logs := testutil.CaptureKlog(t)
+
+	req, err := http.NewRequest(http.MethodDelete, front.URL+"/api/v1/"+built.Project.ID.String()+"/users/"+user.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE the user = %d", resp.StatusCode)
+	}
+	id := resp.Header.Get(requestid.Header)
+	if id == "" {
+		t.Fatal("the response has no request ID")
+	}
+
+	var line string
+	for _, l := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(l, "Soft-deleted user "+user.ID.String()) {
+			line = l
+		}
+	}
+	if line == "" {
+		t.Fatalf("the manager logged nothing about the deletion:\n%s", logs.String())
+	}
+	for _, want := range []string{`request_id="` + id + `"`, `logger="projectusers"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("the line lacks %s: %s", want, line)
+		}
+	}
+}
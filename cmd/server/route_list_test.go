@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestRouteListEndpoint(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+
+	if status, body := server.call(t, http.MethodGet, "/api/v1/routes", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("without a token = %d %s, want 401", status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, "/api/v1/routes", memberToken, nil); status != http.StatusForbidden {
+		t.Errorf("as a member = %d %s, want 403", status, body)
+	}
+	status, body := server.call(t, http.MethodGet, "/api/v1/routes", rootToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("as a SuperAdmin = %d %s, want 200", status, body)
+	}
+	var resp endpoints.ListRoutesResponse
+	decode(t, body, &resp)
+
+	listed := make(map[string]endpoints.RouteInfo)
+	for _, route := range resp.Routes {
+		listed[route.Method+" "+route.Path] = route
+	}
+	for _, want := range []endpoints.RouteInfo{
+		{Method: "POST", Path: "/auth/login", Auth: endpoints.AuthPublic},
+		{Method: "GET", Path: "/api/v1/routes", Auth: endpoints.AuthBearer, Role: auth.SuperAdminRole},
+		{Method: "GET", Path: "/api/v1/users/{id}", Auth: endpoints.AuthBearer, Resource: "users", Action: "read", IncludeDeleted: "users:read"},
+		{Method: "DELETE", Path: "/api/v1/users/{id}", Auth: endpoints.AuthBearer, Resource: "users", Action: "delete"},
+		{Method: "GET", Path: "/api/v1/{projectId}/users/deleted", Auth: endpoints.AuthBearer, Resource: "users", Action: "read"},
+		{Method: "POST", Path: "/api/v1/me/password", Auth: endpoints.AuthBearer},
+	} {
+		got, ok := listed[want.Method+" "+want.Path]
+		if !ok {
+			t.Errorf("%s %s is not listed", want.Method, want.Path)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s %s is listed as %+v, want %+v", want.Method, want.Path, got, want)
+		}
+	}
+	if len(listed) != len(resp.Routes) {
+		t.Errorf("%d routes are listed, %d of them distinct", len(resp.Routes), len(listed))
+	}
+}
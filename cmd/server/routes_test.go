@@ -0,0 +1,260 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	allManager "github.com/yash3004/user_management_service"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestUserLookupIsNotShadowedByProjectRoutes(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+
+	for _, prefix := range []string{"/api/v1", "/api"} {
+		req := httptest.NewRequest(http.MethodGet, prefix+"/admin/users/lookup?email=root@example.com", nil)
+		var match mux.RouteMatch
+		if !server.Router.(*mux.Router).Match(req, &match) {
+			t.Fatalf("%s/admin/users/lookup matches no route", prefix)
+		}
+		if template, _ := match.Route.GetPathTemplate(); template != prefix+"/admin/users/lookup" {
+			t.Errorf("%s/admin/users/lookup is routed to %s", prefix, template)
+		}
+
+		status, body := server.call(t, http.MethodGet, prefix+"/admin/users/lookup?email=root@example.com", rootToken, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s/admin/users/lookup: got %d %s", prefix, status, body)
+		}
+		var resp endpoints.LookupUsersResponse
+		decode(t, body, &resp)
+		if len(resp.Matches) != 1 || resp.Matches[0].Kind != "user" {
+			t.Errorf("%s/admin/users/lookup: got matches %+v, want the global user", prefix, resp.Matches)
+		}
+	}
+}
+
+// pathVar matches the variables of a route template
+var pathVar = regexp.MustCompile(`{[^}]+}`)
+
+func TestEveryRouteIsReachable(t *testing.T) {
+	router := httpHandler(createEndpointManagers(&allManager.Managers{}, cmd.Config{}), cmd.Config{})
+	routes, err := http_transport.ListRoutes(router)
+	if err != nil {
+		t.Fatalf("cannot list the routes: %v", err)
+	}
+
+	for _, route := range routes {
+		// Variables are filled with an ID, which no fixed segment looks like
+		path := pathVar.ReplaceAllString(route.Path, "0b3c1c52-7a7d-4c1e-9d0e-1f2a3b4c5d6e")
+		req := httptest.NewRequest(route.Method, path, nil)
+		var match mux.RouteMatch
+		if !router.Match(req, &match) || match.Route == nil {
+			t.Errorf("%s %s matches no route", route.Method, route.Path)
+			continue
+		}
+		if template, _ := match.Route.GetPathTemplate(); template != route.Path {
+			t.Errorf("%s %s is shadowed by %s", route.Method, route.Path, template)
+		}
+	}
+}
+
+func TestRouteWiringIsVerified(t *testing.T) {
+	router := httpHandler(createEndpointManagers(&allManager.Managers{}, cmd.Config{}), cmd.Config{})
+	if err := http_transport.VerifyRoutes(router); err != nil {
+		t.Fatalf("VerifyRoutes: %v", err)
+	}
+}
+
+func TestGlobalUserRoutes(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	root, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("reader").WithRole("member").Build(t, server.DB)
+	testutil.APolicy("read users", "users", "read").ForRole(built.Roles["reader"]).Build(t, server.DB)
+	reader := testutil.AUser(t, server.DB, "reader@example.com", built.Roles["reader"], built.Project)
+	readerToken := tokenFor(t, reader)
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+
+	for _, prefix := range []string{"/api/v1", "/api"} {
+		rootPath := prefix + "/users/" + root.ID.String()
+		if status, body := server.call(t, http.MethodGet, rootPath, "", nil); status != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token = %d %s, want 401", rootPath, status, body)
+		}
+		if status, body := server.call(t, http.MethodGet, rootPath, memberToken, nil); status != http.StatusForbidden {
+			t.Errorf("GET %s without users:read = %d %s, want 403", rootPath, status, body)
+		}
+		status, body := server.call(t, http.MethodGet, rootPath, readerToken, nil)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s with users:read = %d %s, want 200", rootPath, status, body)
+		}
+		var got endpoints.GetUserResponse
+		decode(t, body, &got)
+		if got.User.Email != root.Email {
+			t.Errorf("GET %s = %+v, want %s", rootPath, got.User, root.Email)
+		}
+	}
+
+	create := map[string]string{
+		"project_id": built.Project.ID.String(),
+		"role_id":    built.Roles["member"].ID.String(),
+		"email":      "new@example.com",
+		"password":   testutil.DefaultPassword,
+	}
+	if status, body := server.call(t, http.MethodPost, "/api/v1/users", readerToken, create); status != http.StatusForbidden {
+		t.Errorf("POST /api/v1/users with users:read only = %d %s, want 403", status, body)
+	}
+	status, body := server.call(t, http.MethodPost, "/api/v1/users", rootToken, create)
+	if status != http.StatusOK {
+		t.Fatalf("POST /api/v1/users = %d %s, want 200", status, body)
+	}
+	var created endpoints.CreateUserResponse
+	decode(t, body, &created)
+	if created.User.Email != "new@example.com" {
+		t.Errorf("created %+v, want new@example.com", created.User)
+	}
+
+	userPath := "/api/v1/users/" + created.User.ID
+	if status, body := server.call(t, http.MethodDelete, userPath, readerToken, nil); status != http.StatusForbidden {
+		t.Errorf("DELETE %s with users:read only = %d %s, want 403", userPath, status, body)
+	}
+	if status, body := server.call(t, http.MethodDelete, userPath, rootToken, nil); status != http.StatusOK {
+		t.Fatalf("DELETE %s = %d %s, want 200", userPath, status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, userPath, rootToken, nil); status != http.StatusNotFound {
+		t.Errorf("GET %s after the delete = %d %s, want 404", userPath, status, body)
+	}
+}
+
+func TestOnlySuperAdminsManageProjectMemberships(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	other := testutil.AProject().WithRole("admin").Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+	memberToken := tokenFor(t, member)
+
+	path := "/api/v1/users/" + member.ID.String() + "/projects"
+	grant := map[string]string{
+		"project_id": other.Project.ID.String(),
+		"role_id":    other.Roles["admin"].ID.String(),
+	}
+	if status, body := server.call(t, http.MethodPost, path, "", grant); status != http.StatusUnauthorized {
+		t.Errorf("POST %s without a token = %d %s, want 401", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodPost, path, memberToken, grant); status != http.StatusForbidden {
+		t.Errorf("POST %s granting itself a role = %d %s, want 403", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, path, memberToken, nil); status != http.StatusForbidden {
+		t.Errorf("GET %s as a member = %d %s, want 403", path, status, body)
+	}
+
+	scoped := built.Roles["member"]
+	scoped.ProjectId = &built.Project.ID
+	if err := server.DB.Save(&scoped).Error; err != nil {
+		t.Fatalf("failed to scope the role to the project: %v", err)
+	}
+	foreign := map[string]string{
+		"project_id": other.Project.ID.String(),
+		"role_id":    scoped.ID.String(),
+	}
+	if status, body := server.call(t, http.MethodPost, path, rootToken, foreign); status != http.StatusBadRequest {
+		t.Errorf("POST %s with a role of another project = %d %s, want 400", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodPost, path, rootToken, grant); status != http.StatusOK {
+		t.Fatalf("POST %s = %d %s, want 200", path, status, body)
+	}
+
+	membership := path + "/" + other.Project.ID.String()
+	if status, body := server.call(t, http.MethodDelete, membership, memberToken, nil); status != http.StatusForbidden {
+		t.Errorf("DELETE %s as a member = %d %s, want 403", membership, status, body)
+	}
+	if status, body := server.call(t, http.MethodDelete, membership, rootToken, nil); status != http.StatusOK {
+		t.Errorf("DELETE %s = %d %s, want 200", membership, status, body)
+	}
+}
+
+// publicRoutes are the routes anyone may call, under /api/v1 and /api alike:
+// logins and what a login page needs before it has a token, and the probes
+// and metadata scraped without one
+var publicRoutes = map[string]bool{
+	"GET /metrics":     true,
+	"GET /healthz":     true,
+	"GET /readyz":      true,
+	"POST /auth/login": true,
+	"GET /oauth_users/{projectId}/{roleId}/login/{provider}": true,
+	"GET /oauth_users/callback/{provider}":                   true,
+	"GET /oauth_users/{projectId}/providers":                 true,
+	"POST /{projectId}/auth/magic-link":                      true,
+	"GET /{projectId}/auth/magic-link/verify":                true,
+	"POST /{projectId}/auth/login":                           true,
+	"POST /{projectId}/auth/password":                        true,
+	"POST /{projectId}/auth/webauthn/login/begin":            true,
+	"POST /{projectId}/auth/webauthn/login/finish":           true,
+	"GET /{projectId}/auth/config":                           true,
+	// Authenticated by the project API token in the request body
+	"POST /{projectId}/authorize": true,
+	"GET /version":                true,
+	"GET /error-catalog":          true,
+}
+
+func TestOnlyLoginRoutesArePublic(t *testing.T) {
+	router := httpHandler(createEndpointManagers(&allManager.Managers{}, cmd.Config{}), cmd.Config{})
+	routes, err := http_transport.ListRoutes(router)
+	if err != nil {
+		t.Fatalf("cannot list the routes: %v", err)
+	}
+
+	for _, route := range routes {
+		if route.Auth != "public" {
+			continue
+		}
+		path := strings.TrimPrefix(strings.TrimPrefix(route.Path, "/api/v1"), "/api")
+		if !publicRoutes[route.Method+" "+path] {
+			t.Errorf("%s %s is public; give it a Requires or list it in publicRoutes", route.Method, route.Path)
+		}
+	}
+}
+
+func TestProjectRoutesCheckTheRoleInTheProject(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("admin").WithRole("member").Build(t, server.DB)
+	other := testutil.AProject().WithRole("admin").Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(built.Roles["admin"]).Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(other.Roles["admin"]).Build(t, server.DB)
+	adminToken := tokenFor(t, testutil.AUser(t, server.DB, "admin@example.com", built.Roles["admin"], built.Project))
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+	outsiderToken := tokenFor(t, testutil.AUser(t, server.DB, "outsider@example.com", other.Roles["admin"], other.Project))
+
+	path := "/api/v1/projects/" + built.Project.ID.String() + "/oauth-providers/github"
+	provider := map[string]string{"client_id": "acme", "client_secret": "s3cret"}
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"without projects:write", memberToken, http.StatusForbidden},
+		{"as an admin of another project", outsiderToken, http.StatusForbidden},
+	} {
+		if status, body := server.call(t, http.MethodPut, path, tc.token, provider); status != tc.want {
+			t.Errorf("PUT %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+		if status, body := server.call(t, http.MethodDelete, path, tc.token, nil); status != tc.want {
+			t.Errorf("DELETE %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+	}
+
+	if status, body := server.call(t, http.MethodPut, path, adminToken, provider); status != http.StatusOK {
+		t.Fatalf("PUT %s with projects:write = %d %s, want 200", path, status, body)
+	}
+	if status, body := server.call(t, http.MethodDelete, path, adminToken, nil); status != http.StatusOK {
+		t.Errorf("DELETE %s with projects:write = %d %s, want 200", path, status, body)
+	}
+}
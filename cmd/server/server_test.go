@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	allManager "github.com/yash3004/user_management_service"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// testServer serves the real router over a fresh test database
+type testServer struct {
+	*httptest.Server
+	DB       *gorm.DB
+	Managers *allManager.Managers
+	Router   http.Handler
+}
+
+// newTestServer starts the service's router with cfg on a fresh database.
+// Passwords follow cfg's rules but hash with the cheapest bcrypt cost.
+func newTestServer(t *testing.T, cfg cmd.Config) *testServer {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	if cfg.Storage.Local.Path == "" {
+		cfg.Storage.Local.Path = t.TempDir()
+	}
+	store, err := blobstore.New(allManager.StorageOptions(cfg))
+	if err != nil {
+		t.Fatalf("failed to open the blob store: %v", err)
+	}
+	passwords, err := password.New(password.Config{
+		BcryptCost:  4,
+		MinLength:   cfg.Passwords.MinLength,
+		MaxAgeDays:  cfg.Passwords.MaxAgeDays,
+		HistorySize: cfg.Passwords.HistorySize,
+	})
+	if err != nil {
+		t.Fatalf("invalid password configuration: %v", err)
+	}
+	managers, err := allManager.NewManagers(db, nil, cfg, passwords, nil, store)
+	if err != nil {
+		t.Fatalf("failed to create the managers: %v", err)
+	}
+	router := httpHandler(createEndpointManagers(managers, cfg), cfg)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &testServer{Server: server, DB: db, Managers: managers, Router: router}
+}
+
+// call sends a request with an optional bearer token and JSON body, and
+// returns the response status and body
+func (s *testServer) call(t *testing.T, method, path, token string, body any) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode the request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("failed to build the request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read the response: %v", err)
+	}
+	return resp.StatusCode, data
+}
+
+// tokenFor issues a bearer token for a global user
+func tokenFor(t *testing.T, user schemas.User) string {
+	t.Helper()
+
+	signed, err := auth.GenerateToken(user.ID, user.Email, user.RoleId, user.ProjectId, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to issue a token: %v", err)
+	}
+	return signed
+}
+
+// aSuperAdmin creates a global SuperAdmin in a project of its own and
+// returns a token for it
+func aSuperAdmin(t *testing.T, db *gorm.DB) (schemas.User, string) {
+	t.Helper()
+
+	built := testutil.AProject().WithRole(auth.SuperAdminRole).Build(t, db)
+	user := testutil.AUser(t, db, "root@example.com", built.Roles[auth.SuperAdminRole], built.Project)
+	return user, tokenFor(t, user)
+}
+
+// decode unmarshals a JSON response body
+func decode(t *testing.T, data []byte, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("failed to decode %s: %v", data, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	sessionauth "github.com/yash3004/user_management_service/auth"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestLoginSetsARememberedOrBrowserSessionCookie(t *testing.T) {
+	cfg := cmd.Config{}
+	cfg.Auth.RememberFor = 7 * 24 * time.Hour
+	server := newTestServer(t, cfg)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+
+	sessionCookie := func(remember bool) *http.Cookie {
+		t.Helper()
+		body, err := json.Marshal(map[string]interface{}{"email": "member@example.com", "password": testutil.DefaultPassword, "remember": remember})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(server.URL+"/auth/login", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /auth/login: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST /auth/login = %d", resp.StatusCode)
+		}
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == sessionauth.SessionName {
+				return cookie
+			}
+		}
+		t.Fatal("the login set no session cookie")
+		return nil
+	}
+
+	if cookie := sessionCookie(true); cookie.MaxAge != 7*24*60*60 || !cookie.HttpOnly {
+		t.Errorf("a remembered login's cookie has MaxAge %d and HttpOnly %v, want a week and HttpOnly", cookie.MaxAge, cookie.HttpOnly)
+	}
+	if cookie := sessionCookie(false); cookie.MaxAge != 0 || !cookie.Expires.IsZero() {
+		t.Errorf("a browser session's cookie has MaxAge %d and Expires %v, want neither", cookie.MaxAge, cookie.Expires)
+	}
+
+	resp, err := http.Post(server.URL+"/auth/login", "application/json", bytes.NewReader([]byte(`{"email": "member@example.com", "password": "wrong", "remember": true}`)))
+	if err != nil {
+		t.Fatalf("POST /auth/login: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || len(resp.Cookies()) != 0 {
+		t.Errorf("a failed login = %d with cookies %v, want 401 and none", resp.StatusCode, resp.Cookies())
+	}
+}
+
+func TestSecureCookiesMarkTheSessionCookieSecure(t *testing.T) {
+	for _, secure := range []bool{false, true} {
+		cfg := cmd.Config{}
+		cfg.Auth.SecureCookies = secure
+		server := newTestServer(t, cfg)
+		built := testutil.AProject().WithRole("member").Build(t, server.DB)
+		testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+
+		body, err := json.Marshal(map[string]string{"email": "member@example.com", "password": testutil.DefaultPassword})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(server.URL+"/auth/login", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /auth/login: %v", err)
+		}
+		resp.Body.Close()
+		cookies := resp.Cookies()
+		if len(cookies) != 1 || cookies[0].Secure != secure {
+			t.Errorf("with auth.secure_cookies %v the login set cookies %+v, want one with Secure %v", secure, cookies, secure)
+		}
+	}
+}
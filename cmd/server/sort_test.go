@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestListsAreSortedByTheSortParameter(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().Named("b").WithRole("b").WithUser("b@example.com").WithUser("c@example.com").WithUser("a@example.com").Build(t, server.DB)
+	testutil.AProject().Named("c").Build(t, server.DB)
+	testutil.AProject().Named("a").Build(t, server.DB)
+	for _, name := range []string{"c", "a"} {
+		testutil.ARole(name).Build(t, server.DB)
+	}
+	for _, name := range []string{"b", "c", "a"} {
+		testutil.APolicy(name, "users", "read").ForRole(built.Roles["b"]).Build(t, server.DB)
+	}
+
+	names := []string{"a", "b", "c"}
+	for _, list := range []struct {
+		path, key, field string
+		want             []string // The seeded values in ascending order
+	}{
+		{"/api/v1/" + built.Project.ID.String() + "/users", "users", "email", []string{"a@example.com", "b@example.com", "c@example.com"}},
+		{"/api/v1/projects", "projects", "name", names},
+		{"/api/v1/roles", "roles", "name", names},
+		{"/api/v1/policies", "policies", "name", names},
+	} {
+		seeded := make(map[string]bool)
+		for _, value := range list.want {
+			seeded[value] = true
+		}
+		sorted := func(sort string) []string {
+			t.Helper()
+			status, body := server.call(t, http.MethodGet, list.path+"?sort="+sort, rootToken, nil)
+			if status != http.StatusOK {
+				t.Fatalf("GET %s?sort=%s = %d %s", list.path, sort, status, body)
+			}
+			var response map[string][]map[string]json.RawMessage
+			decode(t, body, &response)
+			var values []string
+			for _, item := range response[list.key] {
+				var value string
+				decode(t, item[list.field], &value)
+				if seeded[value] {
+					values = append(values, value)
+				}
+			}
+			return values
+		}
+
+		asc, desc := sorted(list.field), sorted("-"+list.field)
+		if !reflect.DeepEqual(asc, list.want) {
+			t.Errorf("GET %s?sort=%s listed %v, want %v", list.path, list.field, asc, list.want)
+		}
+		reversed := make([]string, len(list.want))
+		for i, value := range list.want {
+			reversed[len(reversed)-1-i] = value
+		}
+		if !reflect.DeepEqual(desc, reversed) {
+			t.Errorf("GET %s?sort=-%s listed %v, want %v", list.path, list.field, desc, reversed)
+		}
+
+		status, body := server.call(t, http.MethodGet, list.path+"?sort=password", rootToken, nil)
+		var apiErr http_transport.ErrorResponse
+		decode(t, body, &apiErr)
+		if status != http.StatusBadRequest || apiErr.Code != "INVALID_SORT" {
+			t.Errorf("GET %s?sort=password = %d %s, want 400 INVALID_SORT", list.path, status, body)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestStatsCountTheSeededRecords(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithRole("viewer").
+		WithUser("a@example.com").WithUser("b@example.com").Build(t, server.DB)
+	testutil.APolicy("read users", "users", "read").ForRole(built.Roles["member"]).Build(t, server.DB)
+	testutil.APolicy("write users", "users", "write").ForRole(built.Roles["member"]).Build(t, server.DB)
+	member := testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project)
+	deleted := testutil.AUser(t, server.DB, "deleted@example.com", built.Roles["member"], built.Project)
+	if err := server.DB.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := server.call(t, http.MethodGet, "/api/v1/admin/stats", rootToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/admin/stats = %d %s", status, body)
+	}
+	var stats endpoints.GetStatsResponse
+	decode(t, body, &stats)
+	// The SuperAdmin and its role and project are counted too
+	if stats.Users != 2 || stats.ProjectUsers != 2 || stats.Projects != 2 || stats.Roles != 3 || stats.Policies != 2 {
+		t.Errorf("stats = %s, want 2 users, 2 project users, 2 projects, 3 roles and 2 policies", body)
+	}
+	if stats.DBPool.OpenConnections == 0 || stats.DBPool.OpenConnections != stats.DBPool.InUse+stats.DBPool.Idle {
+		t.Errorf("db_pool = %+v, want the open connections of the pool", stats.DBPool)
+	}
+	if stats.StartedAt.IsZero() || stats.StartedAt.After(time.Now()) || stats.UptimeSeconds < 0 {
+		t.Errorf("started_at = %v with uptime %ds", stats.StartedAt, stats.UptimeSeconds)
+	}
+
+	if status, body := server.call(t, http.MethodGet, "/api/v1/admin/stats", tokenFor(t, member), nil); status != http.StatusForbidden {
+		t.Errorf("GET /api/v1/admin/stats as a member = %d %s, want 403", status, body)
+	}
+	if status, _ := server.call(t, http.MethodGet, "/api/v1/admin/stats", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/admin/stats without a token = %d, want 401", status)
+	}
+}
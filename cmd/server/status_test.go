@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestProjectUserStatusOverHTTP(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").WithUser("b@example.com").Build(t, server.DB)
+	users := "/api/v1/" + built.Project.ID.String() + "/users"
+	statusPath := users + "/" + built.Users["a@example.com"].ID.String() + "/status"
+
+	status, body := server.call(t, http.MethodPut, statusPath, rootToken, map[string]string{"status": schemas.UserStatusInvited})
+	if status != http.StatusConflict {
+		t.Fatalf("active to invited = %d %s, want 409", status, body)
+	}
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if apiErr.Code != "INVALID_STATUS_TRANSITION" {
+		t.Errorf("code = %q, want INVALID_STATUS_TRANSITION", apiErr.Code)
+	}
+
+	if status, body := server.call(t, http.MethodPut, statusPath, rootToken, map[string]string{"status": "archived"}); status != http.StatusBadRequest {
+		t.Fatalf("an unknown status = %d %s, want 400", status, body)
+	}
+
+	status, body = server.call(t, http.MethodPut, statusPath, rootToken, map[string]string{"status": schemas.UserStatusSuspended})
+	if status != http.StatusOK {
+		t.Fatalf("active to suspended = %d %s, want 200", status, body)
+	}
+	var changed struct {
+		User models.DisplayUser `json:"user"`
+	}
+	decode(t, body, &changed)
+	if suspended := changed.User; suspended.Status != schemas.UserStatusSuspended || suspended.Active || len(suspended.Transitions) == 0 {
+		t.Errorf("user = %+v, want a suspended user with its next statuses", changed.User)
+	}
+
+	status, body = server.call(t, http.MethodGet, users+"?status="+schemas.UserStatusSuspended, rootToken, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET %s?status=suspended = %d %s", users, status, body)
+	}
+	var listed struct {
+		Users []models.DisplayUser `json:"users"`
+	}
+	decode(t, body, &listed)
+	if len(listed.Users) != 1 || listed.Users[0].Email != "a@example.com" {
+		t.Errorf("suspended users = %+v, want a@example.com only", listed.Users)
+	}
+}
+
+func TestUpdatingAUserOfAMissingProjectIsNotFound(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, server.DB)
+	userID := built.Users["a@example.com"].ID.String()
+
+	path := "/api/v1/" + uuid.NewString() + "/users/" + userID
+	status, body := server.call(t, http.MethodPut, path, rootToken, map[string]any{"first_name": "A", "last_name": "User", "active": true})
+	var apiErr http_transport.ErrorResponse
+	decode(t, body, &apiErr)
+	if status != http.StatusNotFound || apiErr.Code != "NOT_FOUND" {
+		t.Fatalf("PUT %s = %d %s, want 404 NOT_FOUND", path, status, body)
+	}
+}
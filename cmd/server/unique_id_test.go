@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestValidateUniqueIDThenCreate(t *testing.T) {
+	var cfg cmd.Config
+	cfg.Projects.UniqueIDCheckRate = 2
+	server := newTestServer(t, cfg)
+	_, token := aSuperAdmin(t, server.DB)
+
+	status, body := server.call(t, http.MethodPost, "/api/v1/projects/unique-id/validate", token, map[string]string{"unique_id": "Acme_Prod"})
+	if status != http.StatusOK {
+		t.Fatalf("POST unique-id/validate = %d %s", status, body)
+	}
+	var check endpoints.ValidateUniqueIDResponse
+	decode(t, body, &check)
+	if !check.Available || check.UniqueID != "acme_prod" {
+		t.Fatalf("check = %+v, want acme_prod available", check)
+	}
+
+	status, body = server.call(t, http.MethodPost, "/api/v1/projects", token, map[string]string{"name": "Acme", "unique_id": "Acme_Prod"})
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/projects = %d %s", status, body)
+	}
+	var created struct {
+		Project struct {
+			UniqueID string `json:"unique_id"`
+		} `json:"project"`
+	}
+	decode(t, body, &created)
+	if created.Project.UniqueID != check.UniqueID {
+		t.Errorf("created unique ID %q, want the checked %q", created.Project.UniqueID, check.UniqueID)
+	}
+
+	// The limit of 2 checks a minute is spent
+	status, body = server.call(t, http.MethodPost, "/api/v1/projects/unique-id/validate", token, map[string]string{"unique_id": "acme_prod"})
+	if status != http.StatusOK {
+		t.Fatalf("the second check = %d %s", status, body)
+	}
+	decode(t, body, &check)
+	if check.Available || len(check.Suggestions) == 0 {
+		t.Errorf("check of the created ID = %+v, want it taken with suggestions", check)
+	}
+	if status, body := server.call(t, http.MethodPost, "/api/v1/projects/unique-id/validate", token, map[string]string{"unique_id": "other"}); status != http.StatusTooManyRequests {
+		t.Errorf("the third check in a minute = %d %s, want 429", status, body)
+	}
+}
+
+func TestCheckUniqueIDThenCreate(t *testing.T) {
+	var cfg cmd.Config
+	cfg.Projects.UniqueIDCheckRate = 2
+	server := newTestServer(t, cfg)
+	_, token := aSuperAdmin(t, server.DB)
+
+	status, body := server.call(t, http.MethodGet, "/api/v1/projects/check-unique-id?value=Acme_Prod", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET check-unique-id = %d %s", status, body)
+	}
+	var check endpoints.CheckUniqueIDResponse
+	decode(t, body, &check)
+	if !check.Available || check.Normalized != "acme_prod" {
+		t.Fatalf("check = %+v, want acme_prod available", check)
+	}
+
+	status, body = server.call(t, http.MethodPost, "/api/v1/projects", token, map[string]string{"name": "Acme", "unique_id": "Acme_Prod"})
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/projects = %d %s", status, body)
+	}
+	var created struct {
+		Project struct {
+			UniqueID string `json:"unique_id"`
+		} `json:"project"`
+	}
+	decode(t, body, &created)
+	if created.Project.UniqueID != check.Normalized {
+		t.Errorf("created unique ID %q, want the checked %q", created.Project.UniqueID, check.Normalized)
+	}
+
+	// The limit of 2 checks a minute is spent
+	status, body = server.call(t, http.MethodGet, "/api/v1/projects/check-unique-id?value=acme_prod", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("the second check = %d %s", status, body)
+	}
+	decode(t, body, &check)
+	if check.Available || len(check.Suggestions) == 0 {
+		t.Errorf("check of the created ID = %+v, want it taken with suggestions", check)
+	}
+	if status, body := server.call(t, http.MethodGet, "/api/v1/projects/check-unique-id?value=other", token, nil); status != http.StatusTooManyRequests {
+		t.Errorf("the third check in a minute = %d %s, want 429", status, body)
+	}
+}
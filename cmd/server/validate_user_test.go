@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestValidateUserCreatesNothing(t *testing.T) {
+	var cfg cmd.Config
+	cfg.Passwords.MinLength = 12
+	server := newTestServer(t, cfg)
+	_, token := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").Build(t, server.DB)
+	testutil.AUser(t, server.DB, "taken@example.com", built.Roles["member"], built.Project)
+
+	var before int64
+	server.DB.Model(&schemas.User{}).Count(&before)
+
+	status, body := server.call(t, http.MethodPost, "/api/v1/users/validate", token, map[string]string{
+		"project_id": built.Project.ID.String(),
+		"role_id":    built.Roles["member"].ID.String(),
+		"email":      "taken@example.com",
+		"password":   "short",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("POST /api/v1/users/validate = %d %s, want 200", status, body)
+	}
+	var resp endpoints.ValidateUserResponse
+	decode(t, body, &resp)
+	codes := make(map[string]string)
+	for _, f := range resp.Errors {
+		codes[f.Field] = f.Code
+	}
+	if resp.Valid || len(codes) != 2 || codes["email"] != "EMAIL_TAKEN" || codes["password"] != "PASSWORD_TOO_SHORT" {
+		t.Errorf("response = %+v, want the email taken and the password too short", resp)
+	}
+
+	// The same user with a free email and a strong password is valid
+	status, body = server.call(t, http.MethodPost, "/api/v1/users/validate", token, map[string]string{
+		"project_id": built.Project.ID.String(),
+		"role_id":    built.Roles["member"].ID.String(),
+		"email":      "new@example.com",
+		"password":   "long enough password",
+	})
+	decode(t, body, &resp)
+	if status != http.StatusOK || !resp.Valid || len(resp.Errors) != 0 {
+		t.Errorf("validating a good user = %d %s, want valid", status, body)
+	}
+
+	var after int64
+	server.DB.Model(&schemas.User{}).Count(&after)
+	if after != before {
+		t.Errorf("%d users exist after validating, want the %d before", after, before)
+	}
+}
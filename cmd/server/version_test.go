@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/version"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	saved := [3]string{version.Version, version.Commit, version.BuildTime}
+	t.Cleanup(func() {
+		version.Version, version.Commit, version.BuildTime = saved[0], saved[1], saved[2]
+	})
+	server := newTestServer(t, cmd.Config{})
+
+	for _, tc := range []struct {
+		name                   string
+		version, commit, built string
+		want                   version.Info
+	}{
+		{"injected", "v1.2.3", "abc1234", "2026-03-01T09:00:00Z", version.Info{Version: "v1.2.3", Commit: "abc1234", BuildTime: "2026-03-01T09:00:00Z"}},
+		{"unset", "", "", "", version.Info{Version: "dev", Commit: "unknown", BuildTime: "unknown"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			version.Version, version.Commit, version.BuildTime = tc.version, tc.commit, tc.built
+			tc.want.GoVersion = runtime.Version()
+
+			// The endpoint is public
+			status, body := server.call(t, http.MethodGet, "/api/version", "", nil)
+			if status != http.StatusOK {
+				t.Fatalf("GET /api/version = %d: %s", status, body)
+			}
+			var got version.Info
+			decode(t, body, &got)
+			if got != tc.want {
+				t.Fatalf("version = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProbesReportTheVersion(t *testing.T) {
+	saved := version.Version
+	t.Cleanup(func() { version.Version = saved })
+	version.Version = "v1.2.3"
+	server := newTestServer(t, cmd.Config{})
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		status, body := server.call(t, http.MethodGet, path, "", nil)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", path, status, body)
+		}
+		var probe struct {
+			Status  string       `json:"status"`
+			Version version.Info `json:"version"`
+		}
+		decode(t, body, &probe)
+		if probe.Status != "ok" || probe.Version.Version != "v1.2.3" {
+			t.Errorf("GET %s = %s, want ok with version v1.2.3", path, body)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	allManager "github.com/yash3004/user_management_service"
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+func TestEveryV1RouteHasALegacyAlias(t *testing.T) {
+	router := httpHandler(createEndpointManagers(&allManager.Managers{}, cmd.Config{}), cmd.Config{})
+	routes, err := http_transport.ListRoutes(router)
+	if err != nil {
+		t.Fatalf("cannot list the routes: %v", err)
+	}
+
+	v1 := 0
+	for _, route := range routes {
+		legacy, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok {
+			continue
+		}
+		v1++
+		legacy = "/api" + legacy
+
+		path := pathVar.ReplaceAllString(legacy, "0b3c1c52-7a7d-4c1e-9d0e-1f2a3b4c5d6e")
+		req := httptest.NewRequest(route.Method, path, nil)
+		var match mux.RouteMatch
+		if !router.Match(req, &match) || match.Route == nil {
+			t.Errorf("%s %s has no legacy alias", route.Method, route.Path)
+			continue
+		}
+		if template, _ := match.Route.GetPathTemplate(); template != legacy {
+			t.Errorf("%s %s is routed to %s, want %s", route.Method, path, template, legacy)
+		}
+	}
+	if v1 == 0 {
+		t.Fatal("no routes are mounted under /api/v1")
+	}
+}
+
+func TestLegacyPrefixIsDeprecated(t *testing.T) {
+	server := newTestServer(t, cmd.Config{API: cmd.APIConfig{LegacySunset: "2027-01-31"}})
+	_, rootToken := aSuperAdmin(t, server.DB)
+
+	for _, path := range []string{"/policies", "/roles", "/version"} {
+		for _, prefix := range []string{"/api/v1", "/api"} {
+			req, err := http.NewRequest(http.MethodGet, server.URL+prefix+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+rootToken)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s%s: %v", prefix, path, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET %s%s: got %d, want 200", prefix, path, resp.StatusCode)
+			}
+			if got := resp.Header.Get(http_transport.APIVersionHeader); got != "v1" {
+				t.Errorf("GET %s%s: %s = %q, want v1", prefix, path, http_transport.APIVersionHeader, got)
+			}
+
+			deprecation, sunset, link := resp.Header.Get("Deprecation"), resp.Header.Get("Sunset"), resp.Header.Get("Link")
+			if prefix == "/api/v1" {
+				if deprecation != "" || sunset != "" {
+					t.Errorf("GET %s%s is marked deprecated", prefix, path)
+				}
+				continue
+			}
+			if deprecation != "true" {
+				t.Errorf("GET %s%s: Deprecation = %q, want true", prefix, path, deprecation)
+			}
+			if sunset != "Sun, 31 Jan 2027 00:00:00 GMT" {
+				t.Errorf("GET %s%s: Sunset = %q", prefix, path, sunset)
+			}
+			if !strings.Contains(link, "</api/v1>") || !strings.Contains(link, "successor-version") {
+				t.Errorf("GET %s%s: Link = %q, want the v1 successor", prefix, path, link)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestPasskeysAreManagedByTheirUserOrAnAdmin(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	_, rootToken := aSuperAdmin(t, server.DB)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").WithUser("b@example.com").Build(t, server.DB)
+	other := testutil.AProject().Build(t, server.DB)
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+	projectUserToken := func(project schemas.Project, user schemas.ProjectUser) string {
+		signed, err := auth.GenerateToken(user.ID, user.Email, user.RoleId, project.ID, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("failed to issue a token: %v", err)
+		}
+		return signed
+	}
+	alice := built.Users["a@example.com"]
+	aliceToken := projectUserToken(built.Project, alice)
+
+	usersPath := "/api/v1/" + built.Project.ID.String() + "/users/" + alice.ID.String()
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"as another user of the project", projectUserToken(built.Project, built.Users["b@example.com"]), http.StatusUnauthorized},
+		{"with a token for another project", projectUserToken(other.Project, alice), http.StatusUnauthorized},
+		{"as a member without users:read", memberToken, http.StatusForbidden},
+		{"as the user", aliceToken, http.StatusOK},
+		{"as a super admin", rootToken, http.StatusOK},
+	} {
+		if status, body := server.call(t, http.MethodGet, usersPath+"/webauthn/credentials", tc.token, nil); status != tc.want {
+			t.Errorf("GET %s/webauthn/credentials %s = %d %s, want %d", usersPath, tc.name, status, body, tc.want)
+		}
+	}
+	for _, path := range []string{"/webauthn/register/begin", "/webauthn/register/finish"} {
+		if status, body := server.call(t, http.MethodPost, usersPath+path, "", map[string]string{}); status != http.StatusUnauthorized {
+			t.Errorf("POST %s%s without a token = %d %s, want 401", usersPath, path, status, body)
+		}
+	}
+
+	if err := server.DB.Table(testutil.ProjectUserTable(built.Project.ID)).Where("id = ?", alice.ID).
+		Updates(map[string]interface{}{"status": schemas.UserStatusSuspended, "active": false}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status, body := server.call(t, http.MethodGet, usersPath+"/webauthn/credentials", aliceToken, nil); status != http.StatusForbidden {
+		t.Errorf("GET %s/webauthn/credentials as the suspended user = %d %s, want 403", usersPath, status, body)
+	}
+}
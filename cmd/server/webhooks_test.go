@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestWebhookSubscriptionsNeedProjectsWrite(t *testing.T) {
+	server := newTestServer(t, cmd.Config{})
+	built := testutil.AProject().WithRole("admin").WithRole("member").Build(t, server.DB)
+	other := testutil.AProject().WithRole("admin").Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(built.Roles["admin"]).Build(t, server.DB)
+	testutil.APolicy("write projects", "projects", "write").ForRole(other.Roles["admin"]).Build(t, server.DB)
+	adminToken := tokenFor(t, testutil.AUser(t, server.DB, "admin@example.com", built.Roles["admin"], built.Project))
+	memberToken := tokenFor(t, testutil.AUser(t, server.DB, "member@example.com", built.Roles["member"], built.Project))
+	outsiderToken := tokenFor(t, testutil.AUser(t, server.DB, "outsider@example.com", other.Roles["admin"], other.Project))
+
+	path := "/api/v1/projects/" + built.Project.ID.String() + "/webhooks"
+	subscribe := map[string]interface{}{"url": "https://hooks.example.com/ums", "events": []string{"user.created"}}
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"without projects:write", memberToken, http.StatusForbidden},
+		{"as an admin of another project", outsiderToken, http.StatusForbidden},
+	} {
+		if status, body := server.call(t, http.MethodPost, path, tc.token, subscribe); status != tc.want {
+			t.Errorf("POST %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+		if status, body := server.call(t, http.MethodGet, path, tc.token, nil); status != tc.want {
+			t.Errorf("GET %s %s = %d %s, want %d", path, tc.name, status, body, tc.want)
+		}
+	}
+
+	status, body := server.call(t, http.MethodPost, path, adminToken, subscribe)
+	if status != http.StatusOK {
+		t.Fatalf("POST %s with projects:write = %d %s, want 200", path, status, body)
+	}
+	var created endpoints.CreateWebhookResponse
+	decode(t, body, &created)
+
+	subscription := path + "/" + created.Webhook.ID
+	if status, body := server.call(t, http.MethodDelete, subscription, memberToken, nil); status != http.StatusForbidden {
+		t.Errorf("DELETE %s without projects:write = %d %s, want 403", subscription, status, body)
+	}
+	if status, body := server.call(t, http.MethodDelete, subscription, adminToken, nil); status != http.StatusOK {
+		t.Errorf("DELETE %s with projects:write = %d %s, want 200", subscription, status, body)
+	}
+
+	if status, body := server.call(t, http.MethodGet, "/api/v1/projects/webhooks/events", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/projects/webhooks/events without a token = %d %s, want 401", status, body)
+	}
+	if status, body := server.call(t, http.MethodGet, "/api/v1/projects/webhooks/events", memberToken, nil); status != http.StatusOK {
+		t.Errorf("GET /api/v1/projects/webhooks/events = %d %s, want 200", status, body)
+	}
+}
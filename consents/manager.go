@@ -0,0 +1,190 @@
+// Package consents records the documents, such as terms of service, that
+// users accept, and tells which of the versions their project requires they
+// have yet to accept. A project declares its documents in its consent
+// settings; bumping a document's version there makes every user accept it
+// again.
+package consents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("consents")
+
+// ErrUnknownDocument is returned for accepting a document the project does
+// not require
+var ErrUnknownDocument = apierrors.BadRequest("UNKNOWN_DOCUMENT", "the project does not require this document").At("document")
+
+// ErrVersionMismatch is an example of the error returned for accepting a
+// version other than the one the project requires
+var ErrVersionMismatch = versionMismatch("terms", "2")
+
+// ErrConsentRequired is answered to users of projects enforcing consents
+// until they accept every required version
+var ErrConsentRequired = apierrors.New(http.StatusUnavailableForLegalReasons, "CONSENT_REQUIRED",
+	"accept the documents your project requires through POST /api/v1/me/consents to continue")
+
+func versionMismatch(document, required string) *apierrors.Error {
+	return apierrors.New(http.StatusConflict, "VERSION_MISMATCH",
+		fmt.Sprintf("%s is at version %s; accept that version", document, required)).At("version")
+}
+
+// Outstanding is a document a user has yet to accept the required version of
+type Outstanding struct {
+	Document string `json:"document"`
+	Version  string `json:"version"`
+}
+
+// ConsentManager records the documents users accept
+type ConsentManager interface {
+	// Accept records the user accepting the version of document the project
+	// requires, from the client in ctx. Accepting it again returns the
+	// acceptance already recorded.
+	Accept(ctx context.Context, projectID, userID uuid.UUID, document, version string) (*schemas.Consent, error)
+	// ListConsents returns every acceptance of the user, newest first
+	ListConsents(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.Consent, error)
+	// Outstanding returns the required documents the user has not accepted
+	// the current version of, by document
+	Outstanding(ctx context.Context, projectID, userID uuid.UUID) ([]Outstanding, error)
+	// Enforced reports whether the project blocks users with outstanding
+	// consents
+	Enforced(ctx context.Context, projectID uuid.UUID) (bool, error)
+}
+
+// Manager implements the ConsentManager interface
+type Manager struct {
+	DB       *gorm.DB
+	Clock    clock.Clock
+	Projects projects.ProjectManager
+}
+
+// NewManager creates a new consent manager. Projects are read through
+// projects, whose cached ResolveProject lets Enforced run on every request.
+func NewManager(db *gorm.DB, projects projects.ProjectManager) ConsentManager {
+	return &Manager{
+		DB:       db,
+		Clock:    clock.Real{},
+		Projects: projects,
+	}
+}
+
+// Accept implements ConsentManager
+func (m *Manager) Accept(ctx context.Context, projectID, userID uuid.UUID, document, version string) (*schemas.Consent, error) {
+	project, err := m.Projects.ResolveProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	required, ok := project.Settings.Consents.Required[document]
+	if !ok {
+		return nil, ErrUnknownDocument
+	}
+	if version != required {
+		return nil, versionMismatch(document, required)
+	}
+
+	var existing schemas.Consent
+	err = m.DB.Where("project_id = ? AND user_id = ? AND document = ? AND version = ?", projectID, userID, document, version).
+		First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	consent := schemas.Consent{
+		ID:         uuid.New(),
+		Document:   document,
+		Version:    version,
+		IPAddress:  logins.ClientFromContext(ctx).IPAddress,
+		AcceptedAt: m.Clock.Now(),
+		ProjectId:  projectID,
+		UserId:     userID,
+	}
+	if err := m.DB.Create(&consent).Error; err != nil {
+		log.For(ctx).Errorf("Failed to record consent: %v", redact.Error(err))
+		return nil, errors.New("failed to record consent")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionAccept,
+		ResourceType: audit.ResourceConsent,
+		ResourceID:   consent.ID.String(),
+		Details:      fmt.Sprintf("user %s accepted %s version %s", userID, document, version),
+		At:           consent.AcceptedAt,
+	})
+	return &consent, nil
+}
+
+// ListConsents implements ConsentManager
+func (m *Manager) ListConsents(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.Consent, error) {
+	consents := []schemas.Consent{}
+	if err := m.DB.
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Order("accepted_at DESC").
+		Find(&consents).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	return consents, nil
+}
+
+// Outstanding implements ConsentManager
+func (m *Manager) Outstanding(ctx context.Context, projectID, userID uuid.UUID) ([]Outstanding, error) {
+	project, err := m.Projects.ResolveProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	required := project.Settings.Consents.Required
+	outstanding := []Outstanding{}
+	if len(required) == 0 {
+		return outstanding, nil
+	}
+
+	var accepted []schemas.Consent
+	if err := m.DB.Select("document", "version").
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Find(&accepted).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	has := make(map[Outstanding]bool, len(accepted))
+	for _, consent := range accepted {
+		has[Outstanding{Document: consent.Document, Version: consent.Version}] = true
+	}
+
+	for document, version := range required {
+		if doc := (Outstanding{Document: document, Version: version}); !has[doc] {
+			outstanding = append(outstanding, doc)
+		}
+	}
+	sort.Slice(outstanding, func(i, j int) bool { return outstanding[i].Document < outstanding[j].Document })
+	return outstanding, nil
+}
+
+// Enforced implements ConsentManager
+func (m *Manager) Enforced(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	project, err := m.Projects.ResolveProject(ctx, projectID)
+	if err != nil {
+		return false, err
+	}
+	settings := project.Settings.Consents
+	return settings.Enforce && len(settings.Required) > 0, nil
+}
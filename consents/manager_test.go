@@ -0,0 +1,127 @@
+package consents_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestBumpingAVersionAsksForConsentAgain(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+	projectID, userID := built.Project.ID, built.Users["a@example.com"].ID
+	projectManager := projects.NewManager(db, projects.Options{})
+	manager := consents.NewManager(db, projectManager).(*consents.Manager)
+	clock := testutil.NewFakeClock(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	manager.Clock = clock
+	ctx := logins.WithClient(context.Background(), logins.Client{IPAddress: "10.0.0.1"})
+
+	require := func(required map[string]string, enforce bool) {
+		t.Helper()
+		settings := schemas.ProjectSettings{Consents: schemas.ConsentSettings{Required: required, Enforce: enforce}}
+		if _, err := projectManager.UpdateProjectSettings(ctx, projectID, settings); err != nil {
+			t.Fatalf("UpdateProjectSettings: %v", err)
+		}
+	}
+	outstanding := func(want ...consents.Outstanding) {
+		t.Helper()
+		got, err := manager.Outstanding(ctx, projectID, userID)
+		if err != nil {
+			t.Fatalf("Outstanding: %v", err)
+		}
+		if want == nil {
+			want = []consents.Outstanding{}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("outstanding = %+v, want %+v", got, want)
+		}
+	}
+
+	outstanding()
+	require(map[string]string{"terms": "1", "privacy": "1"}, true)
+	if enforced, err := manager.Enforced(ctx, projectID); err != nil || !enforced {
+		t.Errorf("Enforced = %v, %v, want true", enforced, err)
+	}
+	outstanding(consents.Outstanding{Document: "privacy", Version: "1"}, consents.Outstanding{Document: "terms", Version: "1"})
+
+	first, err := manager.Accept(ctx, projectID, userID, "terms", "1")
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if first.IPAddress != "10.0.0.1" || !first.AcceptedAt.Equal(clock.Now()) {
+		t.Errorf("consent = %+v, want it recorded from 10.0.0.1 at %v", first, clock.Now())
+	}
+	clock.Advance(time.Hour)
+	again, err := manager.Accept(ctx, projectID, userID, "terms", "1")
+	if err != nil || again.ID != first.ID {
+		t.Errorf("accepting terms 1 again = %+v, %v, want the first acceptance", again, err)
+	}
+	if _, err := manager.Accept(ctx, projectID, userID, "privacy", "1"); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	outstanding()
+
+	require(map[string]string{"terms": "2", "privacy": "1"}, true)
+	outstanding(consents.Outstanding{Document: "terms", Version: "2"})
+	for _, tc := range []struct {
+		document, version, code string
+	}{
+		{"terms", "1", "VERSION_MISMATCH"},
+		{"cookies", "1", "UNKNOWN_DOCUMENT"},
+	} {
+		_, err := manager.Accept(ctx, projectID, userID, tc.document, tc.version)
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != tc.code {
+			t.Errorf("accepting %s %s: err = %v, want %s", tc.document, tc.version, err, tc.code)
+		}
+	}
+	clock.Advance(time.Hour)
+	if _, err := manager.Accept(ctx, projectID, userID, "terms", "2"); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	outstanding()
+
+	history, err := manager.ListConsents(ctx, projectID, userID)
+	if err != nil {
+		t.Fatalf("ListConsents: %v", err)
+	}
+	var versions []string
+	for _, consent := range history {
+		versions = append(versions, consent.Document+" "+consent.Version)
+	}
+	if want := []string{"terms 2", "privacy 1", "terms 1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("history = %v, want %v", versions, want)
+	}
+
+	var events []schemas.AuditEvent
+	if err := db.Where("action = ? AND resource_type = ?", audit.ActionAccept, audit.ResourceConsent).Order("created_at").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(history) {
+		t.Fatalf("audited %d acceptances, want one for each of the %d recorded", len(events), len(history))
+	}
+	recorded := map[string]bool{}
+	for _, consent := range history {
+		recorded[consent.ID.String()] = true
+	}
+	for _, event := range events {
+		if event.ProjectId == nil || *event.ProjectId != projectID || !recorded[event.ResourceID] {
+			t.Errorf("audit event %+v is not of an acceptance in the project", event)
+		}
+	}
+
+	require(map[string]string{"terms": "2"}, false)
+	if enforced, err := manager.Enforced(ctx, projectID); err != nil || enforced {
+		t.Errorf("Enforced without enforcement = %v, %v, want false", enforced, err)
+	}
+}
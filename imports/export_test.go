@@ -0,0 +1,13 @@
+package imports
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RunJob exposes runJob to the external tests, which stand in for a worker
+// that picked the job from the queue
+func (m *Manager) RunJob(ctx context.Context, id uuid.UUID) {
+	m.runJob(ctx, id)
+}
@@ -0,0 +1,245 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("imports")
+
+// ImportManager defines the interface for asynchronous CSV user imports
+type ImportManager interface {
+	CreateImport(ctx context.Context, projectID string, file io.Reader) (*schemas.ImportJob, error)
+	GetImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error)
+	CancelImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error)
+	OpenErrorReport(ctx context.Context, projectID string, jobID uuid.UUID) (io.ReadCloser, error)
+	// ErrorReportURL signs a time-limited URL of the error report, or
+	// returns blobstore.ErrSignedURLNotSupported
+	ErrorReportURL(ctx context.Context, projectID string, jobID uuid.UUID) (string, error)
+	Start(ctx context.Context)
+}
+
+// DefaultLease is how long a claim on a job lasts unless its worker renews it
+const DefaultLease = 2 * time.Minute
+
+// Options configures the import manager
+type Options struct {
+	ChunkSize int // Number of rows committed per progress update
+	Workers   int // Number of jobs processed concurrently
+	// Lease is how long a worker's claim on a job lasts without a renewal.
+	// Workers renew it well before then, so a job is only claimed again once
+	// the instance running it stopped. 0 means DefaultLease.
+	Lease time.Duration
+	// Instance names this instance as the owner of the jobs it claims; empty
+	// for the host name and a random suffix
+	Instance string
+}
+
+// Manager implements the ImportManager interface
+type Manager struct {
+	DB           *gorm.DB
+	Clock        clock.Clock
+	ProjectUsers projectusers.ProjectUserManager
+	Artifacts    artifacts.ArtifactManager // Keeps uploaded files and error reports
+	Options      Options
+
+	queue  chan uuid.UUID
+	mu     sync.Mutex
+	active map[uuid.UUID]struct{}
+}
+
+// NewManager creates a new import manager
+func NewManager(db *gorm.DB, projectUsers projectusers.ProjectUserManager, artifactManager artifacts.ArtifactManager, opts Options) ImportManager {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 500
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultLease
+	}
+	if opts.Instance == "" {
+		opts.Instance = instanceName()
+	}
+
+	return &Manager{
+		DB:           db,
+		Clock:        clock.Real{},
+		ProjectUsers: projectUsers,
+		Artifacts:    artifactManager,
+		Options:      opts,
+		queue:        make(chan uuid.UUID, 100),
+		active:       make(map[uuid.UUID]struct{}),
+	}
+}
+
+// instanceName names this instance by its host name, with a random suffix
+// telling apart the instances sharing a host
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "ums"
+	}
+	return truncate(host, 90) + "-" + uuid.NewString()[:8]
+}
+
+// CreateImport persists the uploaded CSV and queues it for processing
+func (m *Manager) CreateImport(ctx context.Context, projectID string, file io.Reader) (*schemas.ImportJob, error) {
+	projectUUID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectUUID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("project not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	job := schemas.ImportJob{
+		ID:        uuid.New(),
+		ProjectId: projectUUID,
+		Status:    schemas.ImportStatusPending,
+		CreatedAt: m.Clock.Now(),
+		UpdatedAt: m.Clock.Now(),
+	}
+	job.FilePath = "imports/" + job.ID.String() + ".csv"
+	job.ErrorReportPath = "imports/" + job.ID.String() + "_errors.csv"
+
+	// The upload is kept until the job finishes, then for the retention period
+	if err := m.Artifacts.Save(ctx, job.FilePath, schemas.ArtifactKindImportUpload, &projectUUID, false, file); err != nil {
+		log.For(ctx).Errorf("Failed to store import file: %v", err)
+		return nil, errors.New("failed to store import file")
+	}
+
+	if err := m.DB.Create(&job).Error; err != nil {
+		m.Artifacts.Delete(ctx, job.FilePath)
+		log.For(ctx).Errorf("Failed to create import job: %v", err)
+		return nil, errors.New("failed to create import job")
+	}
+
+	m.enqueue(job.ID)
+
+	return &job, nil
+}
+
+// GetImport gets an import job of a project
+func (m *Manager) GetImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error) {
+	var job schemas.ImportJob
+	if err := m.DB.First(&job, "id = ? AND project_id = ?", jobID, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("import job not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return &job, nil
+}
+
+// CancelImport cancels an import job that has not finished yet
+func (m *Manager) CancelImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error) {
+	job, err := m.GetImport(ctx, projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isFinished(job.Status) {
+		return nil, errors.New("import job has already finished")
+	}
+
+	now := m.Clock.Now()
+	result := m.DB.Model(&schemas.ImportJob{}).
+		Where("id = ? AND status IN ?", jobID, []string{schemas.ImportStatusPending, schemas.ImportStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      schemas.ImportStatusCancelled,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		log.For(ctx).Errorf("Failed to cancel import job: %v", result.Error)
+		return nil, errors.New("failed to cancel import job")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("import job has already finished")
+	}
+	m.expireFiles(ctx, job)
+
+	return m.GetImport(ctx, projectID, jobID)
+}
+
+// OpenErrorReport opens the error report of a finished import job
+func (m *Manager) OpenErrorReport(ctx context.Context, projectID string, jobID uuid.UUID) (io.ReadCloser, error) {
+	job, err := m.finishedWithErrors(ctx, projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := m.Artifacts.Open(ctx, job.ErrorReportPath)
+	if err != nil {
+		log.For(ctx).Errorf("Failed to open error report: %v", err)
+		return nil, errors.New("error report not available")
+	}
+	return content, nil
+}
+
+// ErrorReportURL signs a URL of the error report of a finished import job
+func (m *Manager) ErrorReportURL(ctx context.Context, projectID string, jobID uuid.UUID) (string, error) {
+	job, err := m.finishedWithErrors(ctx, projectID, jobID)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := m.Artifacts.SignedURL(ctx, job.ErrorReportPath)
+	if err != nil && !errors.Is(err, blobstore.ErrSignedURLNotSupported) {
+		log.For(ctx).Errorf("Failed to sign error report URL: %v", err)
+		return "", errors.New("error report not available")
+	}
+	return url, err
+}
+
+// finishedWithErrors loads a job that has an error report to download
+func (m *Manager) finishedWithErrors(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error) {
+	job, err := m.GetImport(ctx, projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isFinished(job.Status) {
+		return nil, errors.New("import job has not finished yet")
+	}
+	if job.RowsFailed == 0 {
+		return nil, errors.New("import job has no failed rows")
+	}
+	return job, nil
+}
+
+// expireFiles starts the retention period of a finished job's upload and
+// error report
+func (m *Manager) expireFiles(ctx context.Context, job *schemas.ImportJob) {
+	if err := m.Artifacts.Expire(ctx, job.FilePath, job.ErrorReportPath); err != nil {
+		log.For(ctx).Errorf("Failed to expire the files of import job %s: %v", job.ID, err)
+	}
+}
+
+func isFinished(status string) bool {
+	return status == schemas.ImportStatusCompleted ||
+		status == schemas.ImportStatusFailed ||
+		status == schemas.ImportStatusCancelled
+}
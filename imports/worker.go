@@ -0,0 +1,437 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+)
+
+// requiredColumns are the CSV header columns every import file must contain
+var requiredColumns = []string{"email", "password", "first_name", "last_name", "role_id"}
+
+// Start launches the import workers and resumes the jobs no live worker
+// holds: those pending, and those left running by an instance that stopped,
+// whose lease has lapsed. They are looked for again every lease period, so a
+// job abandoned by another instance is taken over without a restart.
+func (m *Manager) Start(ctx context.Context) {
+	for i := 0; i < m.Options.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					m.runJob(ctx, id)
+				}
+			}
+		}()
+	}
+
+	m.resumeAbandoned(ctx, true)
+	go func() {
+		ticker := time.NewTicker(m.Options.Lease)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.resumeAbandoned(ctx, false)
+			}
+		}
+	}()
+}
+
+// resumeAbandoned queues the running jobs whose lease lapsed and the pending
+// ones. Pending jobs are queued by the instance they were created on, so
+// after startup only those pending for a whole lease period are taken over.
+func (m *Manager) resumeAbandoned(ctx context.Context, startup bool) {
+	now := m.Clock.Now()
+	pendingSince := now
+	if !startup {
+		pendingSince = now.Add(-m.Options.Lease)
+	}
+	var jobs []schemas.ImportJob
+	if err := m.DB.Where("(status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)) OR (status = ? AND updated_at <= ?)",
+		schemas.ImportStatusRunning, now, schemas.ImportStatusPending, pendingSince).
+		Order("created_at").Find(&jobs).Error; err != nil {
+		log.For(ctx).Errorf("Failed to load unfinished import jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Status == schemas.ImportStatusRunning {
+			log.For(ctx).Infof("Resuming import job %s abandoned by %q from offset %d", job.ID, job.Owner, job.Offset)
+		}
+		m.enqueue(job.ID)
+	}
+}
+
+// enqueue schedules a job for processing without blocking the caller
+func (m *Manager) enqueue(id uuid.UUID) {
+	go func() {
+		m.queue <- id
+	}()
+}
+
+// runJob processes a job, guarding against the same job being run twice concurrently
+func (m *Manager) runJob(ctx context.Context, id uuid.UUID) {
+	m.mu.Lock()
+	if _, running := m.active[id]; running {
+		m.mu.Unlock()
+		return
+	}
+	m.active[id] = struct{}{}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.active, id)
+		m.mu.Unlock()
+	}()
+
+	if err := m.processJob(ctx, id); err != nil {
+		log.For(ctx).Errorf("Import job %s failed: %v", id, redact.Error(err))
+		now := m.Clock.Now()
+		result := m.DB.Model(&schemas.ImportJob{}).
+			Where("id = ? AND status = ? AND owner = ?", id, schemas.ImportStatusRunning, m.Options.Instance).
+			Updates(map[string]interface{}{
+				"status":      schemas.ImportStatusFailed,
+				"error":       truncate(err.Error(), 1000),
+				"finished_at": now,
+				"updated_at":  now,
+			})
+		if result.Error == nil && result.RowsAffected > 0 {
+			var job schemas.ImportJob
+			if m.DB.First(&job, "id = ?", id).Error == nil {
+				m.expireFiles(ctx, &job)
+			}
+		}
+	}
+}
+
+// processJob imports the job's file chunk by chunk, committing progress after every chunk
+// so that an interrupted job resumes from the last committed offset
+func (m *Manager) processJob(ctx context.Context, id uuid.UUID) error {
+	var job schemas.ImportJob
+	if err := m.DB.First(&job, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+	if isFinished(job.Status) {
+		return nil
+	}
+
+	// Claim the job if it is pending or its lease lapsed; otherwise another
+	// worker holds it or it was cancelled in the meantime
+	now := m.Clock.Now()
+	claim := m.DB.Model(&schemas.ImportJob{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)))",
+			id, schemas.ImportStatusPending, schemas.ImportStatusRunning, now).
+		Updates(map[string]interface{}{
+			"status":           schemas.ImportStatusRunning,
+			"owner":            m.Options.Instance,
+			"lease_expires_at": now.Add(m.Options.Lease),
+			"updated_at":       now,
+		})
+	if claim.Error != nil {
+		return fmt.Errorf("failed to mark job as running: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+	// A job taken over from a worker that stopped mid-chunk replays that
+	// chunk, whose users may already have been created
+	replaying := job.Status == schemas.ImportStatusRunning
+	// leased ends when the lease is lost, and the job stops at the end of
+	// the chunk without committing it
+	leased, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go m.renewLease(leased, cancel, id)
+
+	file, err := m.download(ctx, job.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer removeTemp(file)
+
+	// Read the header from the start of the file, then continue from the committed offset
+	headerReader := csv.NewReader(file)
+	header, err := headerReader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns, err := columnIndexes(header)
+	if err != nil {
+		return err
+	}
+
+	start := job.Offset
+	if start < headerReader.InputOffset() {
+		start = headerReader.InputOffset()
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek import file: %w", err)
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	// The report is built in a temporary file and stored after every chunk
+	// that adds to it; rows written after the last committed chunk are
+	// discarded by copying only the committed part of the stored report
+	report, err := os.CreateTemp("", "import-*-errors.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create error report: %w", err)
+	}
+	defer removeTemp(report)
+	if job.ErrorReportSize > 0 {
+		stored, err := m.Artifacts.Open(ctx, job.ErrorReportPath)
+		if err != nil {
+			return fmt.Errorf("failed to open error report: %w", err)
+		}
+		_, err = io.CopyN(report, stored, job.ErrorReportSize)
+		stored.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load error report: %w", err)
+		}
+	}
+	reportWriter := csv.NewWriter(report)
+	if job.ErrorReportSize == 0 {
+		reportWriter.Write([]string{"row", "email", "error"})
+	}
+
+	projectID := job.ProjectId.String()
+	for {
+		// Stop between chunks if the job was cancelled
+		var status string
+		if err := m.DB.Model(&schemas.ImportJob{}).Where("id = ?", id).Pluck("status", &status).Error; err != nil {
+			return fmt.Errorf("failed to reload job status: %w", err)
+		}
+		if status == schemas.ImportStatusCancelled {
+			log.For(ctx).Infof("Import job %s was cancelled", id)
+			return nil
+		}
+		if ctx.Err() != nil {
+			// Shutting down; the job resumes from the last committed offset on restart
+			return nil
+		}
+
+		done := false
+		processed, succeeded, failed := 0, 0, 0
+		replayed := map[string]bool{}
+		for processed < m.Options.ChunkSize {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				done = true
+				break
+			}
+			row := job.RowsProcessed + processed + 1
+			processed++
+			if err != nil {
+				return fmt.Errorf("failed to parse CSV row %d: %w", row, err)
+			}
+
+			email := field(record, columns, "email")
+			if len(record) != len(header) {
+				failed++
+				reportWriter.Write([]string{strconv.Itoa(row), email, "wrong number of fields"})
+				continue
+			}
+
+			roleID, err := uuid.Parse(field(record, columns, "role_id"))
+			if err != nil {
+				failed++
+				reportWriter.Write([]string{strconv.Itoa(row), email, "invalid role ID format"})
+				continue
+			}
+
+			if _, err := m.ProjectUsers.CreateProjectUser(ctx, projectID, email,
+				field(record, columns, "password"),
+				field(record, columns, "first_name"),
+				field(record, columns, "last_name"),
+				roleID); err != nil {
+				if replaying && errors.Is(err, projectusers.ErrEmailTaken) && !replayed[email] &&
+					m.createdByJob(ctx, &job, email, roleID) {
+					replayed[email] = true
+					succeeded++
+					continue
+				}
+				failed++
+				reportWriter.Write([]string{strconv.Itoa(row), email, err.Error()})
+				continue
+			}
+			succeeded++
+		}
+
+		// A worker that lost its lease during the chunk leaves the report
+		// to the one that took the job over
+		if leased.Err() != nil && ctx.Err() == nil {
+			log.For(ctx).Warningf("Lost the lease on import job %s", id)
+			return nil
+		}
+		reportWriter.Flush()
+		if err := reportWriter.Error(); err != nil {
+			return fmt.Errorf("failed to write error report: %w", err)
+		}
+		reportSize, err := report.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to stat error report: %w", err)
+		}
+		if reportSize != job.ErrorReportSize {
+			if err := m.Artifacts.Save(ctx, job.ErrorReportPath, schemas.ArtifactKindImportErrors, &job.ProjectId, false,
+				io.NewSectionReader(report, 0, reportSize)); err != nil {
+				return fmt.Errorf("failed to store error report: %w", err)
+			}
+		}
+
+		job.Offset = start + reader.InputOffset()
+		job.ErrorReportSize = reportSize
+		job.RowsProcessed += processed
+		job.RowsSucceeded += succeeded
+		job.RowsFailed += failed
+
+		updates := map[string]interface{}{
+			"offset":            job.Offset,
+			"error_report_size": job.ErrorReportSize,
+			"rows_processed":    job.RowsProcessed,
+			"rows_succeeded":    job.RowsSucceeded,
+			"rows_failed":       job.RowsFailed,
+			"lease_expires_at":  m.Clock.Now().Add(m.Options.Lease),
+			"updated_at":        m.Clock.Now(),
+		}
+		if done {
+			updates["status"] = schemas.ImportStatusCompleted
+			updates["finished_at"] = m.Clock.Now()
+		}
+
+		// Only the owner commits, so a worker that lost its lease cannot
+		// overwrite the progress of the one that took the job over
+		result := m.DB.Model(&schemas.ImportJob{}).
+			Where("id = ? AND status = ? AND owner = ?", id, schemas.ImportStatusRunning, m.Options.Instance).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to commit import progress: %w", result.Error)
+		}
+		if done {
+			m.expireFiles(ctx, &job)
+			return nil
+		}
+		replaying = false
+		if result.RowsAffected == 0 {
+			// A job cancelled during the chunk may have stored its report
+			// after the cancellation expired its files. One taken over by
+			// another worker still needs them.
+			if err := m.DB.Model(&schemas.ImportJob{}).Where("id = ?", id).Pluck("status", &status).Error; err == nil && isFinished(status) {
+				m.expireFiles(ctx, &job)
+			}
+			return nil
+		}
+	}
+}
+
+// createdByJob tells whether the project user with email is one the job
+// created in a chunk it did not commit: it was created in the role of the row
+// since the job was
+func (m *Manager) createdByJob(ctx context.Context, job *schemas.ImportJob, email string, roleID uuid.UUID) bool {
+	user, err := m.ProjectUsers.GetProjectUserByEmail(ctx, job.ProjectId.String(), email, false)
+	if err != nil {
+		log.For(ctx).V(4).Infof("Replayed import row of %s not created by job %s: %v", redact.SafeEmail(email), job.ID, redact.Error(err))
+		return false
+	}
+	return user.RoleID == roleID.String() && !user.CreatedAt.Before(job.CreatedAt)
+}
+
+// renewLease extends the claim on a running job every third of the lease
+// until ctx ends, and calls lost when the claim is gone: the job was
+// cancelled, finished, or taken over after a renewal came too late
+func (m *Manager) renewLease(ctx context.Context, lost context.CancelFunc, id uuid.UUID) {
+	ticker := time.NewTicker(m.Options.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := m.DB.Model(&schemas.ImportJob{}).
+			Where("id = ? AND status = ? AND owner = ?", id, schemas.ImportStatusRunning, m.Options.Instance).
+			Update("lease_expires_at", m.Clock.Now().Add(m.Options.Lease))
+		if result.Error != nil {
+			log.For(ctx).Errorf("Failed to renew the lease on import job %s: %v", id, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			lost()
+			return
+		}
+	}
+}
+
+// download copies a stored file to a temporary file, which lets a resumed
+// job seek to its committed offset
+func (m *Manager) download(ctx context.Context, key string) (*os.File, error) {
+	content, err := m.Artifacts.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	file, err := os.CreateTemp("", "import-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		removeTemp(file)
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		removeTemp(file)
+		return nil, err
+	}
+	return file, nil
+}
+
+// removeTemp closes and deletes a temporary file
+func removeTemp(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// columnIndexes maps the required column names to their position in the header
+func columnIndexes(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+func field(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
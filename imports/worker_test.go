@@ -0,0 +1,317 @@
+package imports_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/imports"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"gorm.io/gorm"
+)
+
+// header is the header line of the import files
+const header = "email,password,first_name,last_name,role_id\n"
+
+// row is one CSV line of the import file
+func row(email string, roleID uuid.UUID) string {
+	return email + ",password123,Test,User," + roleID.String() + "\n"
+}
+
+// fixture is an import manager whose files live in memory and whose user
+// creations are recorded
+type fixture struct {
+	*imports.Manager
+
+	mu      sync.Mutex
+	files   map[string][]byte
+	created []string
+	users   map[string]*models.DisplayUser // By email
+}
+
+func newFixture(t *testing.T, db *gorm.DB) *fixture {
+	t.Helper()
+
+	f := &fixture{files: map[string][]byte{}, users: map[string]*models.DisplayUser{}}
+	artifactManager := &mocks.ArtifactManager{
+		SaveFunc: func(_ context.Context, key, _ string, _ *uuid.UUID, _ bool, content io.Reader) error {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return err
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.files[key] = data
+			return nil
+		},
+		OpenFunc: func(_ context.Context, key string) (io.ReadCloser, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			data, ok := f.files[key]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+		ExpireFunc: func(context.Context, ...string) error { return nil },
+	}
+	projectUsers := &mocks.ProjectUserManager{
+		CreateProjectUserFunc: func(_ context.Context, _ string, email, _, _, _ string, roleID uuid.UUID) (*models.DisplayUser, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if _, taken := f.users[email]; taken {
+				return nil, projectusers.ErrEmailTaken
+			}
+			f.created = append(f.created, email)
+			user := &models.DisplayUser{ID: uuid.NewString(), Email: email, RoleID: roleID.String(), CreatedAt: time.Now()}
+			f.users[email] = user
+			return user, nil
+		},
+		GetProjectUserByEmailFunc: func(_ context.Context, _ string, email string, _ bool) (*models.DisplayUser, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			user, ok := f.users[email]
+			if !ok {
+				return nil, projectusers.ErrUserNotFound
+			}
+			return user, nil
+		},
+	}
+	f.Manager = imports.NewManager(db, projectUsers, artifactManager, imports.Options{ChunkSize: 2}).(*imports.Manager)
+	return f
+}
+
+func (f *fixture) createdEmails() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.created...)
+}
+
+// aJob stores an import file and a job of it in the given state
+func (f *fixture) aJob(t *testing.T, db *gorm.DB, projectID uuid.UUID, content string, job schemas.ImportJob) schemas.ImportJob {
+	t.Helper()
+
+	job.ID = uuid.New()
+	job.ProjectId = projectID
+	job.FilePath = "imports/" + job.ID.String() + ".csv"
+	job.ErrorReportPath = "imports/" + job.ID.String() + "_errors.csv"
+	f.files[job.FilePath] = []byte(content)
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("failed to create import job: %v", err)
+	}
+	return job
+}
+
+// waitForStatus polls the job until it reaches status
+func waitForStatus(t *testing.T, db *gorm.DB, id uuid.UUID, status string) schemas.ImportJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job schemas.ImportJob
+		if err := db.First(&job, "id = ?", id).Error; err != nil {
+			t.Fatalf("failed to load import job: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("import job is %q, want %q", job.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartResumesHalfProcessedJob(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	roleID := built.Roles["member"].ID
+	f := newFixture(t, db)
+
+	// The previous run committed the first chunk and was stopped while
+	// the job was running
+	content := header + row("a@example.com", roleID) + row("b@example.com", roleID) +
+		row("c@example.com", roleID) + row("d@example.com", roleID) + row("e@example.com", roleID)
+	committed := int64(len(header + row("a@example.com", roleID) + row("b@example.com", roleID)))
+	job := f.aJob(t, db, built.Project.ID, content, schemas.ImportJob{
+		Status:        schemas.ImportStatusRunning,
+		Offset:        committed,
+		RowsProcessed: 2,
+		RowsSucceeded: 2,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	finished := waitForStatus(t, db, job.ID, schemas.ImportStatusCompleted)
+	if got, want := strings.Join(f.createdEmails(), ","), "c@example.com,d@example.com,e@example.com"; got != want {
+		t.Errorf("created %s, want %s", got, want)
+	}
+	if finished.RowsProcessed != 5 || finished.RowsSucceeded != 5 || finished.RowsFailed != 0 {
+		t.Errorf("rows processed/succeeded/failed = %d/%d/%d, want 5/5/0",
+			finished.RowsProcessed, finished.RowsSucceeded, finished.RowsFailed)
+	}
+	if finished.Offset != int64(len(content)) {
+		t.Errorf("offset = %d, want %d", finished.Offset, len(content))
+	}
+	if finished.FinishedAt == nil {
+		t.Error("finished_at is not set")
+	}
+}
+
+func TestResumedJobCountsReplayedRowsAsImported(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	roleID := built.Roles["member"].ID
+	f := newFixture(t, db)
+	f.users["d@example.com"] = &models.DisplayUser{Email: "d@example.com", RoleID: roleID.String(), CreatedAt: time.Now().Add(-time.Hour)}
+
+	// The previous run committed the first chunk, then created c before it
+	// stopped. d was there before the job, and e is in the file twice.
+	content := header + row("a@example.com", roleID) + row("b@example.com", roleID) +
+		row("c@example.com", roleID) + row("d@example.com", roleID) + row("e@example.com", roleID) + row("e@example.com", roleID)
+	committed := int64(len(header + row("a@example.com", roleID) + row("b@example.com", roleID)))
+	job := f.aJob(t, db, built.Project.ID, content, schemas.ImportJob{
+		Status:        schemas.ImportStatusRunning,
+		Offset:        committed,
+		RowsProcessed: 2,
+		RowsSucceeded: 2,
+	})
+	f.users["c@example.com"] = &models.DisplayUser{Email: "c@example.com", RoleID: roleID.String(), CreatedAt: time.Now()}
+
+	f.RunJob(context.Background(), job.ID)
+
+	finished := waitForStatus(t, db, job.ID, schemas.ImportStatusCompleted)
+	if finished.RowsProcessed != 6 || finished.RowsSucceeded != 4 || finished.RowsFailed != 2 {
+		t.Errorf("rows processed/succeeded/failed = %d/%d/%d, want 6/4/2",
+			finished.RowsProcessed, finished.RowsSucceeded, finished.RowsFailed)
+	}
+	report := string(f.files[finished.ErrorReportPath])
+	if strings.Contains(report, "c@example.com") || !strings.Contains(report, "4,d@example.com") || !strings.Contains(report, "6,e@example.com") {
+		t.Errorf("error report = %q, want rows 4 and 6 only", report)
+	}
+}
+
+func TestRunJobSkipsJobClaimedElsewhere(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	f := newFixture(t, db)
+
+	lease := time.Now().Add(time.Minute)
+	job := f.aJob(t, db, built.Project.ID, header+row("a@example.com", built.Roles["member"].ID), schemas.ImportJob{
+		Status:         schemas.ImportStatusRunning,
+		Owner:          "other",
+		LeaseExpiresAt: &lease,
+	})
+
+	f.RunJob(context.Background(), job.ID)
+
+	if created := f.createdEmails(); len(created) != 0 {
+		t.Errorf("created %v for a job another worker is running", created)
+	}
+	var reloaded schemas.ImportJob
+	if err := db.First(&reloaded, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("failed to load import job: %v", err)
+	}
+	if reloaded.Status != schemas.ImportStatusRunning || reloaded.RowsProcessed != 0 {
+		t.Errorf("job = %s with %d rows processed, want it left running and untouched", reloaded.Status, reloaded.RowsProcessed)
+	}
+}
+
+func TestRunJobProcessesPendingJobOnce(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	roleID := built.Roles["member"].ID
+	f := newFixture(t, db)
+
+	job := f.aJob(t, db, built.Project.ID, header+row("a@example.com", roleID)+row("b@example.com", roleID)+row("c@example.com", roleID),
+		schemas.ImportJob{Status: schemas.ImportStatusPending})
+
+	f.RunJob(context.Background(), job.ID)
+	f.RunJob(context.Background(), job.ID)
+
+	waitForStatus(t, db, job.ID, schemas.ImportStatusCompleted)
+	if got, want := strings.Join(f.createdEmails(), ","), "a@example.com,b@example.com,c@example.com"; got != want {
+		t.Errorf("created %s, want %s", got, want)
+	}
+}
+
+func TestStartTakesOverOnlyJobsWhoseLeaseLapsed(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	roleID := built.Roles["member"].ID
+	f := newFixture(t, db)
+
+	live, lapsed := time.Now().Add(time.Hour), time.Now().Add(-time.Second)
+	held := f.aJob(t, db, built.Project.ID, header+row("held@example.com", roleID), schemas.ImportJob{
+		Status:         schemas.ImportStatusRunning,
+		Owner:          "live-instance",
+		LeaseExpiresAt: &live,
+	})
+	abandoned := f.aJob(t, db, built.Project.ID, header+row("abandoned@example.com", roleID), schemas.ImportJob{
+		Status:         schemas.ImportStatusRunning,
+		Owner:          "stopped-instance",
+		LeaseExpiresAt: &lapsed,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	finished := waitForStatus(t, db, abandoned.ID, schemas.ImportStatusCompleted)
+	if finished.Owner != f.Options.Instance {
+		t.Errorf("the abandoned job is owned by %q, want %q", finished.Owner, f.Options.Instance)
+	}
+	if got := strings.Join(f.createdEmails(), ","); got != "abandoned@example.com" {
+		t.Errorf("created %s, want only the user of the abandoned job", got)
+	}
+	var reloaded schemas.ImportJob
+	if err := db.First(&reloaded, "id = ?", held.ID).Error; err != nil {
+		t.Fatalf("failed to load import job: %v", err)
+	}
+	if reloaded.Status != schemas.ImportStatusRunning || reloaded.Owner != "live-instance" || reloaded.RowsProcessed != 0 {
+		t.Errorf("job held by a live instance = %s by %q with %d rows processed, want it left alone",
+			reloaded.Status, reloaded.Owner, reloaded.RowsProcessed)
+	}
+}
+
+func TestRunJobCommitsOnlyWhileItHoldsTheLease(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	roleID := built.Roles["member"].ID
+	f := newFixture(t, db)
+
+	job := f.aJob(t, db, built.Project.ID, header+row("a@example.com", roleID)+row("b@example.com", roleID)+row("c@example.com", roleID),
+		schemas.ImportJob{Status: schemas.ImportStatusPending})
+	// Another instance takes the job over while the first chunk is imported
+	f.ProjectUsers.(*mocks.ProjectUserManager).CreateProjectUserFunc = func(_ context.Context, _ string, email, _, _, _ string, _ uuid.UUID) (*models.DisplayUser, error) {
+		lapsed := time.Now().Add(-time.Second)
+		if err := db.Model(&schemas.ImportJob{}).Where("id = ?", job.ID).
+			Updates(map[string]interface{}{"owner": "other", "lease_expires_at": lapsed}).Error; err != nil {
+			t.Error(err)
+		}
+		return &models.DisplayUser{ID: uuid.NewString(), Email: email}, nil
+	}
+
+	f.RunJob(context.Background(), job.ID)
+
+	var reloaded schemas.ImportJob
+	if err := db.First(&reloaded, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("failed to load import job: %v", err)
+	}
+	if reloaded.Status != schemas.ImportStatusRunning || reloaded.Owner != "other" || reloaded.RowsProcessed != 0 || reloaded.Offset != 0 {
+		t.Errorf("job = %s by %q at offset %d with %d rows processed, want the chunk left uncommitted for the new owner",
+			reloaded.Status, reloaded.Owner, reloaded.Offset, reloaded.RowsProcessed)
+	}
+}
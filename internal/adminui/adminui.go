@@ -0,0 +1,135 @@
+// Package adminui serves a small read-only admin UI for browsing projects and
+// their users. The UI is a static single-page app embedded in the binary; it
+// talks to the regular API with the token from /auth/login.
+package adminui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Prefix is the path the UI is served under
+const Prefix = "/admin"
+
+// assetVersionPlaceholder in index.html is replaced with a hash of the other
+// assets, so their URLs change whenever their content does
+const assetVersionPlaceholder = "{{ASSET_VERSION}}"
+
+//go:embed static
+var static embed.FS
+
+type asset struct {
+	body        []byte
+	etag        string
+	contentType string
+}
+
+var assets = mustLoadAssets()
+
+func mustLoadAssets() map[string]asset {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	contents := map[string][]byte{}
+	if err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		contents[name] = body
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		if name != "index.html" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	version := sha256.New()
+	for _, name := range names {
+		version.Write([]byte(name))
+		version.Write(contents[name])
+	}
+	contents["index.html"] = bytes.ReplaceAll(contents["index.html"], []byte(assetVersionPlaceholder),
+		[]byte(hex.EncodeToString(version.Sum(nil))[:12]))
+
+	loaded := make(map[string]asset, len(contents))
+	for name, body := range contents {
+		sum := sha256.Sum256(body)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(body)
+		}
+		loaded[name] = asset{
+			body:        body,
+			etag:        `"` + hex.EncodeToString(sum[:8]) + `"`,
+			contentType: contentType,
+		}
+	}
+	return loaded
+}
+
+// Handler serves the admin UI under Prefix. Paths that name an embedded file
+// serve that file; other paths without a file extension serve index.html so
+// the app's own routes survive a reload. index.html is revalidated on every
+// load while the other assets, whose URLs carry a content version, are cached
+// for a year.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		rest, ok := strings.CutPrefix(r.URL.Path, Prefix)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			http.NotFound(w, r)
+			return
+		}
+		if rest == "" {
+			http.Redirect(w, r, Prefix+"/", http.StatusFound)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(rest), "/")
+		a, found := assets[name]
+		switch {
+		case found && name != "index.html":
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		case found || path.Ext(name) == "":
+			a = assets["index.html"]
+			w.Header().Set("Cache-Control", "no-cache")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", a.contentType)
+		h.Set("ETag", a.etag)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.body))
+	})
+}
@@ -0,0 +1,82 @@
+package adminui_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/adminui"
+)
+
+func get(method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	recorder := httptest.NewRecorder()
+	adminui.Handler().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestHandlerRouting(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		method       string
+		path         string
+		status       int
+		contentType  string
+		cacheControl string
+		isIndex      bool
+	}{
+		{"index", http.MethodGet, "/admin/", http.StatusOK, "text/html", "no-cache", true},
+		{"index by name", http.MethodGet, "/admin/index.html", http.StatusOK, "text/html", "no-cache", true},
+		{"script", http.MethodGet, "/admin/app.js", http.StatusOK, "javascript", "immutable", false},
+		{"stylesheet", http.MethodGet, "/admin/app.css", http.StatusOK, "text/css", "immutable", false},
+		{"app route falls back to the index", http.MethodGet, "/admin/projects/123/users", http.StatusOK, "text/html", "no-cache", true},
+		{"missing asset", http.MethodGet, "/admin/missing.js", http.StatusNotFound, "", "", false},
+		{"escape attempt", http.MethodGet, "/admin/../../go.mod", http.StatusNotFound, "", "", false},
+		{"another prefix", http.MethodGet, "/administrator", http.StatusNotFound, "", "", false},
+		{"head", http.MethodHead, "/admin/", http.StatusOK, "text/html", "no-cache", false},
+		{"post", http.MethodPost, "/admin/", http.StatusMethodNotAllowed, "", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := get(tc.method, tc.path, nil)
+			if recorder.Code != tc.status {
+				t.Fatalf("%s %s = %d, want %d", tc.method, tc.path, recorder.Code, tc.status)
+			}
+			if got := recorder.Header().Get("Content-Type"); !strings.Contains(got, tc.contentType) {
+				t.Errorf("Content-Type = %q, want %s", got, tc.contentType)
+			}
+			if got := recorder.Header().Get("Cache-Control"); !strings.Contains(got, tc.cacheControl) {
+				t.Errorf("Cache-Control = %q, want %s", got, tc.cacheControl)
+			}
+			if tc.isIndex && !strings.Contains(recorder.Body.String(), "<title>User Management Admin</title>") {
+				t.Errorf("%s did not serve index.html", tc.path)
+			}
+		})
+	}
+}
+
+func TestHandlerRedirectsThePrefix(t *testing.T) {
+	recorder := get(http.MethodGet, "/admin", nil)
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/admin/" {
+		t.Fatalf("GET /admin = %d to %q, want a redirect to /admin/", recorder.Code, recorder.Header().Get("Location"))
+	}
+}
+
+func TestIndexVersionsTheAssets(t *testing.T) {
+	body := get(http.MethodGet, "/admin/", nil).Body.String()
+	if strings.Contains(body, "{{ASSET_VERSION}}") || !strings.Contains(body, "/admin/app.js?v=") {
+		t.Fatalf("index.html does not version its assets:\n%s", body)
+	}
+
+	first := get(http.MethodGet, "/admin/app.js", nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("app.js has no ETag")
+	}
+	if got := get(http.MethodGet, "/admin/app.js", http.Header{"If-None-Match": {etag}}).Code; got != http.StatusNotModified {
+		t.Errorf("a revalidation with the current ETag got %d, want 304", got)
+	}
+}
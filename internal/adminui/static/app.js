@@ -0,0 +1,274 @@
+// Read-only admin UI. Pages are rendered from the regular JSON API with the
+// token returned by /auth/login; nothing here changes data.
+"use strict";
+
+const BASE = "/admin";
+const API = "/api/v1";
+const PAGE_SIZE = 25;
+const TOKEN_KEY = "ums-admin-token";
+const EMAIL_KEY = "ums-admin-email";
+
+const app = document.getElementById("app");
+
+// h builds an element. Text is always set through text nodes so API data is
+// never interpreted as HTML.
+function h(tag, attrs, ...children) {
+  const el = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key.startsWith("on")) {
+      el.addEventListener(key.slice(2), value);
+    } else if (value === true) {
+      el.setAttribute(key, "");
+    } else if (value !== false && value != null) {
+      el.setAttribute(key, value);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child == null || child === false) continue;
+    el.append(child instanceof Node ? child : document.createTextNode(String(child)));
+  }
+  return el;
+}
+
+function render(...nodes) {
+  app.replaceChildren(...nodes);
+}
+
+function link(href, text) {
+  return h("a", { href: BASE + href, "data-link": true }, text);
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+const STATUS_CLASSES = { active: "ok", suspended: "bad", pending_deletion: "bad" };
+
+function statusBadge(user) {
+  if (user.deleted_at) return h("span", { class: "badge bad" }, "deleted");
+  const status = user.status || (user.active ? "active" : "deactivated");
+  return h("span", { class: "badge " + (STATUS_CLASSES[status] || "warn") }, status.replace(/_/g, " "));
+}
+
+class UnauthorizedError extends Error {}
+
+async function api(path, options = {}) {
+  const headers = { Accept: "application/json", ...(options.headers || {}) };
+  const token = sessionStorage.getItem(TOKEN_KEY);
+  if (token) headers.Authorization = "Bearer " + token;
+
+  const resp = await fetch(path, { ...options, headers });
+  if (resp.status === 401) throw new UnauthorizedError("session expired");
+  const body = resp.status === 204 ? null : await resp.json().catch(() => null);
+  if (!resp.ok) throw new Error((body && body.error) || resp.statusText);
+  return body;
+}
+
+function navigate(href) {
+  history.pushState(null, "", href);
+  route();
+}
+
+document.addEventListener("click", (event) => {
+  const a = event.target.closest("a[data-link]");
+  if (!a || event.metaKey || event.ctrlKey || event.shiftKey || event.button !== 0) return;
+  event.preventDefault();
+  navigate(a.getAttribute("href"));
+});
+
+window.addEventListener("popstate", route);
+
+document.getElementById("logout").addEventListener("click", () => {
+  sessionStorage.removeItem(TOKEN_KEY);
+  sessionStorage.removeItem(EMAIL_KEY);
+  route();
+});
+
+function updateHeader() {
+  const email = sessionStorage.getItem(EMAIL_KEY);
+  document.getElementById("whoami").textContent = email || "";
+  document.getElementById("logout").hidden = !email;
+}
+
+function loginPage() {
+  const error = h("p", { class: "error" });
+  const form = h("form", { class: "login card" },
+    h("h1", {}, "Sign in"),
+    h("input", { name: "email", type: "email", placeholder: "Email", autocomplete: "username", required: true }),
+    h("input", { name: "password", type: "password", placeholder: "Password", autocomplete: "current-password", required: true }),
+    h("button", { type: "submit" }, "Sign in"),
+    error,
+  );
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    error.textContent = "";
+    try {
+      const resp = await api("/auth/login", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email: form.email.value, password: form.password.value }),
+      });
+      sessionStorage.setItem(TOKEN_KEY, resp.token);
+      sessionStorage.setItem(EMAIL_KEY, resp.email);
+      route();
+    } catch (err) {
+      error.textContent = err.message;
+    }
+  });
+  render(form);
+}
+
+async function projectsPage() {
+  const { projects } = await api(API + "/projects");
+  projects.sort((a, b) => a.name.localeCompare(b.name));
+  render(
+    h("h1", {}, "Projects"),
+    projects.length === 0
+      ? h("p", { class: "muted" }, "No projects yet.")
+      : h("table", {},
+          h("thead", {}, h("tr", {}, h("th", {}, "Name"), h("th", {}, "Unique ID"), h("th", {}, "Created"))),
+          h("tbody", {}, projects.map((p) =>
+            h("tr", {},
+              h("td", {}, link("/projects/" + p.id, p.name)),
+              h("td", {}, p.unique_id),
+              h("td", {}, formatTime(p.created_at)),
+            ))),
+        ),
+  );
+}
+
+async function projectUsersPage(projectId, params) {
+  const includeDeleted = params.get("deleted") === "1";
+  const query = (params.get("q") || "").trim().toLowerCase();
+  const page = Math.max(1, parseInt(params.get("page") || "1", 10) || 1);
+
+  const [{ project }, { users }] = await Promise.all([
+    api(API + "/projects/" + projectId),
+    api(API + "/" + projectId + "/users" + (includeDeleted ? "?include_deleted=true" : "")),
+  ]);
+
+  const matching = users
+    .filter((u) => !query || [u.email, u.first_name, u.last_name].some((v) => (v || "").toLowerCase().includes(query)))
+    .sort((a, b) => a.email.localeCompare(b.email));
+  const pages = Math.max(1, Math.ceil(matching.length / PAGE_SIZE));
+  const current = Math.min(page, pages);
+  const shown = matching.slice((current - 1) * PAGE_SIZE, current * PAGE_SIZE);
+
+  const pageHref = (changes) => {
+    const next = new URLSearchParams(params);
+    for (const [key, value] of Object.entries(changes)) {
+      if (value) next.set(key, value); else next.delete(key);
+    }
+    const qs = next.toString();
+    return BASE + "/projects/" + projectId + (qs ? "?" + qs : "");
+  };
+
+  const search = h("input", { type: "search", placeholder: "Search by email or name", value: params.get("q") || "" });
+  const toolbar = h("form", { class: "toolbar" },
+    search,
+    h("label", {},
+      h("input", { type: "checkbox", checked: includeDeleted, onchange: (e) => navigate(pageHref({ deleted: e.target.checked ? "1" : "", page: "" })) }),
+      " Show deleted"),
+  );
+  toolbar.addEventListener("submit", (event) => {
+    event.preventDefault();
+    navigate(pageHref({ q: search.value.trim(), page: "" }));
+  });
+
+  render(
+    h("div", { class: "crumbs" }, link("/", "Projects"), " / ", project.name),
+    h("h1", {}, project.name),
+    project.description ? h("p", { class: "muted" }, project.description) : null,
+    toolbar,
+    shown.length === 0
+      ? h("p", { class: "muted" }, "No users match.")
+      : h("table", {},
+          h("thead", {}, h("tr", {}, h("th", {}, "Email"), h("th", {}, "Name"), h("th", {}, "Status"), h("th", {}, "Created"))),
+          h("tbody", {}, shown.map((u) =>
+            h("tr", {},
+              h("td", {}, link("/projects/" + projectId + "/users/" + u.id, u.email)),
+              h("td", {}, [u.first_name, u.last_name].filter(Boolean).join(" ")),
+              h("td", {}, statusBadge(u)),
+              h("td", {}, formatTime(u.created_at)),
+            ))),
+        ),
+    h("div", { class: "pager" },
+      current > 1 ? h("a", { href: pageHref({ page: String(current - 1) }), "data-link": true }, "Previous") : null,
+      h("span", { class: "muted" }, `Page ${current} of ${pages} (${matching.length} users)`),
+      current < pages ? h("a", { href: pageHref({ page: String(current + 1) }), "data-link": true }, "Next") : null,
+    ),
+  );
+}
+
+async function userPage(projectId, userId) {
+  const [{ project }, { user }, { logins }] = await Promise.all([
+    api(API + "/projects/" + projectId),
+    api(API + "/" + projectId + "/users/" + userId + "?include_deleted=true"),
+    api(API + "/" + projectId + "/users/" + userId + "/logins"),
+  ]);
+  const role = await api(API + "/roles/" + user.role_id).then((r) => r.role).catch(() => null);
+
+  render(
+    h("div", { class: "crumbs" },
+      link("/", "Projects"), " / ", link("/projects/" + projectId, project.name), " / ", user.email),
+    h("h1", {}, user.email),
+    h("div", { class: "card" },
+      h("dl", {},
+        h("dt", {}, "Name"), h("dd", {}, [user.first_name, user.last_name].filter(Boolean).join(" ") || "-"),
+        h("dt", {}, "Status"), h("dd", {}, statusBadge(user)),
+        h("dt", {}, "Email verified"), h("dd", {}, user.email_verified ? "yes" : "no"),
+        h("dt", {}, "Role"), h("dd", {}, role ? role.name : user.role_id),
+        h("dt", {}, "Created"), h("dd", {}, formatTime(user.created_at)),
+        h("dt", {}, "Updated"), h("dd", {}, formatTime(user.updated_at)),
+        user.deleted_at ? [h("dt", {}, "Deleted"), h("dd", {}, formatTime(user.deleted_at))] : null,
+        h("dt", {}, "User ID"), h("dd", {}, user.id),
+      ),
+    ),
+    h("h2", {}, "Login history"),
+    logins.length === 0
+      ? h("p", { class: "muted" }, "No recorded logins.")
+      : h("table", {},
+          h("thead", {}, h("tr", {}, h("th", {}, "Time"), h("th", {}, "Method"), h("th", {}, "IP address"), h("th", {}, ""))),
+          h("tbody", {}, logins.map((l) =>
+            h("tr", {},
+              h("td", {}, formatTime(l.created_at)),
+              h("td", {}, l.method),
+              h("td", {}, l.ip_address),
+              h("td", {}, l.suspicious ? h("span", { class: "badge warn" }, "new IP or device") : null),
+            ))),
+        ),
+  );
+}
+
+async function route() {
+  updateHeader();
+  if (!sessionStorage.getItem(TOKEN_KEY)) {
+    loginPage();
+    return;
+  }
+
+  const path = location.pathname.slice(BASE.length).replace(/\/+$/, "");
+  const params = new URLSearchParams(location.search);
+  let m;
+  try {
+    if (path === "") {
+      await projectsPage();
+    } else if ((m = path.match(/^\/projects\/([^/]+)$/))) {
+      await projectUsersPage(m[1], params);
+    } else if ((m = path.match(/^\/projects\/([^/]+)\/users\/([^/]+)$/))) {
+      await userPage(m[1], m[2]);
+    } else {
+      render(h("h1", {}, "Not found"), h("p", {}, link("/", "Back to projects")));
+    }
+  } catch (err) {
+    if (err instanceof UnauthorizedError) {
+      sessionStorage.removeItem(TOKEN_KEY);
+      sessionStorage.removeItem(EMAIL_KEY);
+      loginPage();
+      return;
+    }
+    render(h("p", { class: "error" }, err.message), h("p", {}, link("/", "Back to projects")));
+  }
+}
+
+route();
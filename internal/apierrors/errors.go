@@ -0,0 +1,57 @@
+// Package apierrors defines errors that carry an HTTP status and a stable,
+// machine readable code. Managers return them where the caller needs to
+// tell failures apart; any other error is still reported as a 500.
+package apierrors
+
+import "net/http"
+
+// Error is an error with an HTTP status and a stable code
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Field is the JSON path of the request field the error is about, e.g.
+	// "roles[0].policies[1].effect"; empty when it is about no one field
+	Field string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StatusCode implements kithttp.StatusCoder
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// At returns a copy of the error about the request field at path. A field
+// the error already names is taken as relative to path.
+func (e *Error) At(path string) *Error {
+	c := *e
+	if c.Field != "" {
+		path += "." + c.Field
+	}
+	c.Field = path
+	return &c
+}
+
+// New creates an error with the given status, code and message
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest creates a 400 error
+func BadRequest(code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+// NotFound creates a 404 error
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, "NOT_FOUND", message)
+}
+
+// Conflict creates a 409 error
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, "CONFLICT", message)
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+)
+
+// Login methods, the method label of ums_login_attempts_total
+const (
+	MethodPassword  = "password"
+	MethodOAuth     = "oauth"
+	MethodMagicLink = "magic_link"
+	MethodPasskey   = "passkey"
+)
+
+// Login results, the result label of ums_login_attempts_total
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure" // Wrong credentials, or an invalid or expired link, code or challenge
+	ResultLocked  = "locked"  // The account exists but may not log in in its status
+	ResultError   = "error"   // The service failed to complete the login
+)
+
+// NoProject is the project label of logins not made to a project
+const NoProject = "none"
+
+// DefaultProjectLabelLimit is how many projects get a label of their own in
+// the login metrics unless configured otherwise
+const DefaultProjectLabelLimit = 100
+
+var (
+	loginAttempts = metrics.NewCounter("ums_login_attempts_total",
+		"Login attempts, by result, method and project.", "result", "method", "project")
+	tokenValidationFailures = metrics.NewCounter("ums_token_validation_failures_total",
+		"Bearer tokens that failed validation, by reason.", "reason")
+
+	// ProjectLabels keeps the project label of the login metrics bounded:
+	// projects beyond the limit are counted together as "other"
+	ProjectLabels = metrics.NewLabelCap(DefaultProjectLabelLimit)
+)
+
+// RecordLogin counts a login attempt. project is the project's ID as given
+// in the request, or NoProject; err is what the login returned. A project
+// gets a label of its own with its first successful login: until then, and
+// for IDs that name no project at all, attempts are labeled "other".
+func RecordLogin(method, project string, err error) {
+	result := LoginResult(err)
+	label := project
+	switch {
+	case project == NoProject:
+	case result == ResultSuccess:
+		label = ProjectLabels.Value(project)
+	default:
+		label = ProjectLabels.Lookup(project)
+	}
+	loginAttempts.Inc(result, method, label)
+}
+
+// LoginResult classifies the error a login returned. Client errors are
+// failures, except a refusal because of the account's status, which counts
+// as locked; errors the service did not map to a client error are its own.
+func LoginResult(err error) string {
+	if err == nil {
+		return ResultSuccess
+	}
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode() >= http.StatusInternalServerError {
+		return ResultError
+	}
+	if apiErr.Code == userstatus.LoginErrorCode {
+		return ResultLocked
+	}
+	return ResultFailure
+}
+
+// TokenFailureReason names why a token failed validation, as the reason
+// label of ums_token_validation_failures_total and in token introspection
+func TokenFailureReason(err error) string {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+			return "expired"
+		case validationErr.Errors&jwt.ValidationErrorNotValidYet != 0:
+			return "not_yet_valid"
+		case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return "bad_signature"
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return "malformed"
+		case validationErr.Errors&jwt.ValidationErrorUnverifiable != 0:
+			return "unverifiable"
+		}
+	}
+	return "invalid"
+}
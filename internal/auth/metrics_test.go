@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+)
+
+func loginAttempts(t *testing.T, result, method, project string) float64 {
+	t.Helper()
+	return testutil.MetricValue(t, fmt.Sprintf(`ums_login_attempts_total{result=%q,method=%q,project=%q}`, result, method, project))
+}
+
+func TestRecordLoginBoundsTheProjectLabel(t *testing.T) {
+	saved := auth.ProjectLabels
+	auth.ProjectLabels = metrics.NewLabelCap(1)
+	t.Cleanup(func() { auth.ProjectLabels = saved })
+
+	first, second := uuid.NewString(), uuid.NewString()
+	wrongPassword := apierrors.New(http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid email or password")
+	for _, tc := range []struct {
+		name    string
+		project string
+		err     error
+		result  string
+		label   string
+	}{
+		{"a failure before any success", first, wrongPassword, auth.ResultFailure, metrics.OtherLabel},
+		{"the first success labels the project", first, nil, auth.ResultSuccess, first},
+		{"later failures keep the label", first, wrongPassword, auth.ResultFailure, first},
+		{"a refusal for the account's status", first, userstatus.LoginError("suspended"), auth.ResultLocked, first},
+		{"a service failure", first, errors.New("database is down"), auth.ResultError, first},
+		{"a success past the cap", second, nil, auth.ResultSuccess, metrics.OtherLabel},
+		{"a login to no project", auth.NoProject, nil, auth.ResultSuccess, auth.NoProject},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := loginAttempts(t, tc.result, auth.MethodMagicLink, tc.label)
+			auth.RecordLogin(auth.MethodMagicLink, tc.project, tc.err)
+			if got := loginAttempts(t, tc.result, auth.MethodMagicLink, tc.label) - before; got != 1 {
+				t.Errorf("login_attempts{result=%s,project=%s} grew by %v, want 1", tc.result, tc.label, got)
+			}
+		})
+	}
+}
+
+func TestTokenValidationFailuresAreCountedByReason(t *testing.T) {
+	valid, err := auth.GenerateToken(uuid.New(), "a@example.com", uuid.New(), uuid.New(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := auth.GenerateToken(uuid.New(), "a@example.com", uuid.New(), uuid.New(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := valid[:len(valid)-4] + "AAAA"
+	if tampered == valid {
+		tampered = valid[:len(valid)-4] + "BBBB"
+	}
+
+	for _, tc := range []struct {
+		token  string
+		reason string
+	}{
+		{expired, "expired"},
+		{tampered, "bad_signature"},
+		{"not-a-jwt", "malformed"},
+	} {
+		t.Run(tc.reason, func(t *testing.T) {
+			sample := fmt.Sprintf(`ums_token_validation_failures_total{reason=%q}`, tc.reason)
+			before := testutil.MetricValue(t, sample)
+			if _, err := auth.ParseToken(tc.token); err == nil {
+				t.Fatal("ParseToken accepted the token")
+			} else if reason := auth.TokenFailureReason(err); reason != tc.reason {
+				t.Errorf("TokenFailureReason = %s, want %s", reason, tc.reason)
+			}
+			if got := testutil.MetricValue(t, sample) - before; got != 1 {
+				t.Errorf("%s grew by %v, want 1", sample, got)
+			}
+		})
+	}
+
+	before := testutil.MetricValue(t, `ums_token_validation_failures_total{reason="expired"}`)
+	if _, err := auth.ParseToken(valid); err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if got := testutil.MetricValue(t, `ums_token_validation_failures_total{reason="expired"}`); got != before {
+		t.Error("a valid token was counted as a failure")
+	}
+}
+
+func TestPolicyMiddlewareCountsItsDecisions(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("Reader").WithRole("Guest").Build(t, db)
+	testutil.APolicy("read users", "users", "read").ForRole(built.Roles["Reader"]).Build(t, db)
+	reader := testutil.AUser(t, db, "reader@example.com", built.Roles["Reader"], built.Project)
+	guest := testutil.AUser(t, db, "guest@example.com", built.Roles["Guest"], built.Project)
+
+	for _, tc := range []struct {
+		name   string
+		user   schemas.User
+		status int
+		effect string
+	}{
+		{"a role with the policy", reader, http.StatusOK, "allow"},
+		{"a role without it", guest, http.StatusForbidden, "deny"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sample := fmt.Sprintf(`ums_policy_decisions_total{resource="users",action="read",effect=%q}`, tc.effect)
+			before := testutil.MetricValue(t, sample)
+			if status := policyStatus(t, db, tc.user, built.Project); status != tc.status {
+				t.Fatalf("status = %d, want %d", status, tc.status)
+			}
+			if got := testutil.MetricValue(t, sample) - before; got != 1 {
+				t.Errorf("policy_decisions{effect=%s} grew by %v, want 1", tc.effect, got)
+			}
+		})
+	}
+}
@@ -5,22 +5,56 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/internal/logging"
 	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/policies"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
 // ContextKey is a type for context keys
 type ContextKey string
 
 const (
-	// UserContextKey is the key for user in context
+	// UserContextKey is the key for user in context. Only the fields of
+	// users.AuthUser are set on it; handlers needing more load the user.
 	UserContextKey ContextKey = "user"
 )
 
-// AuthMiddleware authenticates the user and adds user info to the request context
-func AuthMiddleware(db *gorm.DB) func(http.Handler) http.Handler {
+// SuperAdminRole is the name of the global role allowed everything
+const SuperAdminRole = roles.SuperAdminRole
+
+// IsSuperAdminRole reports whether role is the global SuperAdmin role. A
+// project role is never one, whatever its name.
+func IsSuperAdminRole(role *schemas.Role) bool {
+	return role != nil && role.ProjectId == nil && role.Name == SuperAdminRole
+}
+
+// Log is where the middleware logs; it may be replaced before the
+// middleware is built
+var Log = logging.Named("auth")
+
+// AuthMiddleware authenticates the user, loaded through authUsers, and adds
+// user info to the request context. Humans present a JWT; service accounts a
+// project API token issued to them.
+func AuthMiddleware(authUsers *users.AuthUsers) func(http.Handler) http.Handler {
+	return authMiddleware(authUsers, false)
+}
+
+// PasswordChangeMiddleware is AuthMiddleware also accepting tokens restricted
+// to ScopePasswordChange, for the route changing the password
+func PasswordChangeMiddleware(authUsers *users.AuthUsers) func(http.Handler) http.Handler {
+	return authMiddleware(authUsers, true)
+}
+
+func authMiddleware(authUsers *users.AuthUsers, allowPasswordChange bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header
@@ -38,43 +72,79 @@ func AuthMiddleware(db *gorm.DB) func(http.Handler) http.Handler {
 
 			// Extract the token
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			
-			// Validate token and get user ID
-			userID, err := ValidateToken(tokenString)
-			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-				return
+
+			// Service accounts authenticate with the API tokens issued to
+			// them, everyone else with a JWT
+			var userID uuid.UUID
+			serviceAccount := strings.HasPrefix(tokenString, apitokens.TokenPrefix)
+			if serviceAccount {
+				var err error
+				userID, err = apitokens.ServiceAccount(authUsers.DB.WithContext(r.Context()), time.Now(), tokenString)
+				if errors.Is(err, apitokens.ErrInvalidToken) {
+					http.Error(w, "Invalid or revoked API token", http.StatusUnauthorized)
+					return
+				} else if err != nil {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+			} else {
+				// Validate token and get user ID
+				claims, err := ParseToken(tokenString)
+				if err != nil {
+					http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+					return
+				}
+				userID = claims.UserID
+
+				// A token issued for an expired password only lets the user change it
+				if claims.Scope == ScopePasswordChange && !allowPasswordChange {
+					http.Error(w, "Password expired; change it to continue", http.StatusForbidden)
+					return
+				}
 			}
 
-			// Get user from database
-			var user schemas.User
-			if err := db.First(&user, "id = ?", userID).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Get user from the cache or database
+			authUser, err := authUsers.Get(r.Context(), userID)
+			if err != nil {
+				if errors.Is(err, users.ErrAuthUserNotFound) {
 					http.Error(w, "User not found", http.StatusUnauthorized)
 				} else {
-					klog.Errorf("Database error: %v", err)
 					http.Error(w, "Internal server error", http.StatusInternalServerError)
 				}
 				return
 			}
+			user := authUser.User()
+			if user.IsServiceAccount() != serviceAccount {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
 
-			// Check if user is active
-			if !user.Active {
-				http.Error(w, "User account is inactive", http.StatusForbidden)
+			// Only active users may use their token
+			if !userstatus.CanLogin(user.Status) {
+				http.Error(w, "User account is "+strings.ReplaceAll(user.Status, "_", " "), http.StatusForbidden)
 				return
 			}
 
-			// Add user to context
+			// Add user to context, and to the lines logged for the request
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
-			
+			ctx = logging.WithValues(ctx, "user_id", user.ID.String())
+
 			// Call the next handler with the updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// PolicyMiddleware checks if the user has the required permissions
-func PolicyMiddleware(db *gorm.DB, resource string, action string) func(http.Handler) http.Handler {
+// PolicyMiddleware checks if the user has the required permissions. Users
+// whose role was deleted have none, and are handed to authUsers.RoleDeleted
+// when it is their global role.
+func PolicyMiddleware(db *gorm.DB, authUsers *users.AuthUsers, resource string, action string) func(http.Handler) http.Handler {
+	return ProjectPolicyMiddleware(db, authUsers, "projectId", resource, action)
+}
+
+// ProjectPolicyMiddleware is PolicyMiddleware checking the role the user
+// holds in the project named by the path variable projectVar
+func ProjectPolicyMiddleware(db *gorm.DB, authUsers *users.AuthUsers, projectVar string, resource string, action string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get user from context
@@ -84,38 +154,84 @@ func PolicyMiddleware(db *gorm.DB, resource string, action string) func(http.Han
 				return
 			}
 
-			// Check if user has SuperAdmin role (bypass policy check)
-			var role schemas.Role
-			if err := db.First(&role, "id = ?", user.RoleId).Error; err != nil {
-				klog.Errorf("Error fetching role: %v", err)
+			// A global SuperAdmin has access to everything, in every project,
+			// whether or not it is a member
+			globalRole, err := findRole(db, user.RoleId)
+			if err != nil {
+				Log.For(r.Context()).Errorf("Error fetching role: %v", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
+			if IsSuperAdminRole(globalRole) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Resolve the role the user holds in the requested project, which
+			// may come from a membership rather than the primary project
+			role := globalRole
+			projectID, err := uuid.Parse(mux.Vars(r)[projectVar])
+			projectRoute := err == nil
+			if projectRoute {
+				roleID, err := users.ProjectRole(r.Context(), db, &user, projectID)
+				if errors.Is(err, users.ErrNotProjectMember) {
+					http.Error(w, "Permission denied", http.StatusForbidden)
+					return
+				} else if err != nil {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				if roleID != user.RoleId {
+					if role, err = findRole(db, roleID); err != nil {
+						Log.For(r.Context()).Errorf("Error fetching role: %v", err)
+						http.Error(w, "Internal server error", http.StatusInternalServerError)
+						return
+					}
+					if role == nil {
+						http.Error(w, "Permission denied", http.StatusForbidden)
+						return
+					}
+				}
+			}
+			if role == nil {
+				authUsers.RoleDeleted(r.Context(), user)
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+
+			// A project role grants nothing outside its project, so routes
+			// of no project honour only global roles
+			if !projectRoute && role.ProjectId != nil {
+				Log.For(r.Context()).V(4).Infof("Role %s is scoped to a project; denying %s:%s", role.ID, resource, action)
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
 
 			// SuperAdmin role has access to everything
-			if role.Name == "SuperAdmin" {
+			if IsSuperAdminRole(role) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Check policies for the user's role
-			var policies []schemas.Policy
-			if err := db.Where("roles_id = ? AND resource = ?", user.RoleId, resource).Find(&policies).Error; err != nil {
-				klog.Errorf("Error fetching policies: %v", err)
+			// Decide with the role's policies that apply here: its global
+			// ones, and on a project route that project's too
+			query := db.Where("roles_id = ?", role.ID)
+			if projectRoute {
+				query = query.Where("project_id = ? OR project_id IS NULL", projectID)
+			} else {
+				query = query.Where("project_id IS NULL")
+			}
+			var rolePolicies []schemas.Policy
+			if err := query.Order("created_at").Find(&rolePolicies).Error; err != nil {
+				Log.For(r.Context()).Errorf("Error fetching policies: %v", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			// Check if any policy allows the action
-			allowed := false
-			for _, policy := range policies {
-				if (policy.Action == "*" || policy.Action == action) && policy.Effect == "allow" {
-					allowed = true
-					break
-				}
-			}
-
-			if !allowed {
+			decision := policies.Evaluate(rolePolicies, resource, action)
+			policies.RecordDecision(resource, action, decision)
+			if !decision.Allowed {
+				Log.For(r.Context()).V(4).Infof("Role %s does not allow %s:%s (%s)", role.ID, resource, action, decision.Reason)
 				http.Error(w, "Permission denied", http.StatusForbidden)
 				return
 			}
@@ -126,3 +242,64 @@ func PolicyMiddleware(db *gorm.DB, resource string, action string) func(http.Han
 	}
 }
 
+// findRole loads a role, or returns nil when it was deleted
+func findRole(db *gorm.DB, id uuid.UUID) (*schemas.Role, error) {
+	var role schemas.Role
+	if err := db.First(&role, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// IsSuperAdmin reports whether a user's primary role is the global
+// SuperAdmin role. Users that do not exist or whose role was deleted are not.
+func IsSuperAdmin(db *gorm.DB, userID uuid.UUID) (bool, error) {
+	var user schemas.User
+	if err := db.Select("role_id").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	role, err := findRole(db, user.RoleId)
+	if err != nil {
+		return false, err
+	}
+	return IsSuperAdminRole(role), nil
+}
+
+// RoleMiddleware lets through only users whose global role is the named one.
+// It runs after AuthMiddleware, which puts the user in the context. Users
+// whose role was deleted are handed to authUsers.RoleDeleted.
+func RoleMiddleware(db *gorm.DB, authUsers *users.AuthUsers, roleName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserContextKey).(schemas.User)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var role schemas.Role
+			if err := db.First(&role, "id = ?", user.RoleId).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					authUsers.RoleDeleted(r.Context(), user)
+					http.Error(w, "Permission denied", http.StatusForbidden)
+					return
+				}
+				Log.For(r.Context()).Errorf("Error fetching role: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if role.ProjectId != nil || role.Name != roleName {
+				http.Error(w, "Permission denied", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
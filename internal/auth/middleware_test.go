@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/users"
+	"gorm.io/gorm"
+)
+
+// policyStatus runs a request by user for a route of project through
+// PolicyMiddleware requiring users:read, and returns its status
+func policyStatus(t *testing.T, db *gorm.DB, user schemas.User, project schemas.Project) int {
+	t.Helper()
+
+	r := mux.NewRouter()
+	r.Handle("/{projectId}/users", auth.PolicyMiddleware(db, users.NewAuthUsers(db, 0), "users", "read")(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })))
+
+	req := httptest.NewRequest(http.MethodGet, "/"+project.ID.String()+"/users", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPolicyMiddlewareLetsSuperAdminIntoProjectsItIsNotMemberOf(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	home := testutil.AProject().WithRole(auth.SuperAdminRole).Build(t, db)
+	other := testutil.AProject().Build(t, db)
+	root := testutil.AUser(t, db, "root@example.com", home.Roles[auth.SuperAdminRole], home.Project)
+
+	if status := policyStatus(t, db, root, other.Project); status != http.StatusOK {
+		t.Errorf("SuperAdmin outside the project: got %d, want 200", status)
+	}
+}
+
+func TestPolicyMiddlewareRefusesNonMembers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	home := testutil.AProject().WithRole("Reader").Build(t, db)
+	testutil.APolicy("read users", "users", "read").ForRole(home.Roles["Reader"]).Build(t, db)
+	other := testutil.AProject().Build(t, db)
+	user := testutil.AUser(t, db, "reader@example.com", home.Roles["Reader"], home.Project)
+
+	if status := policyStatus(t, db, user, home.Project); status != http.StatusOK {
+		t.Errorf("member with the policy: got %d, want 200", status)
+	}
+	if status := policyStatus(t, db, user, other.Project); status != http.StatusForbidden {
+		t.Errorf("non-member: got %d, want 403", status)
+	}
+}
+
+func TestPolicyMiddlewareUsesTheMembershipRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	home := testutil.AProject().WithRole("Guest").Build(t, db)
+	other := testutil.AProject().WithRole("Reader").Build(t, db)
+	testutil.APolicy("read users", "users", "read").ForRole(other.Roles["Reader"]).Build(t, db)
+	user := testutil.AUser(t, db, "member@example.com", home.Roles["Guest"], home.Project)
+
+	if status := policyStatus(t, db, user, home.Project); status != http.StatusForbidden {
+		t.Errorf("primary project without the policy: got %d, want 403", status)
+	}
+
+	now := time.Now()
+	if err := db.Create(&schemas.UserProjectMembership{
+		UserId: user.ID, ProjectId: other.Project.ID, RoleId: other.Roles["Reader"].ID, CreatedAt: now, UpdatedAt: now,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if status := policyStatus(t, db, user, other.Project); status != http.StatusOK {
+		t.Errorf("member through a membership: got %d, want 200", status)
+	}
+}
+
+func TestPolicyMiddlewareIgnoresProjectRolesNamedSuperAdmin(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	home := testutil.AProject().Build(t, db)
+	other := testutil.AProject().Build(t, db)
+	// Created before the name was reserved for the global role
+	role := testutil.ARole(auth.SuperAdminRole).InProject(home.Project.ID).Build(t, db)
+	user := testutil.AUser(t, db, "tenant@example.com", role, home.Project)
+
+	if status := policyStatus(t, db, user, other.Project); status != http.StatusForbidden {
+		t.Errorf("project SuperAdmin in another project: got %d, want 403", status)
+	}
+	superAdmin, err := auth.IsSuperAdmin(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if superAdmin {
+		t.Error("IsSuperAdmin = true for a project role, want false")
+	}
+}
+
+func TestPolicyMiddlewareHonoursOnlyGlobalRolesOnGlobalRoutes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	project := testutil.AProject().Build(t, db).Project
+	projectAdmin := testutil.ARole("Admin").InProject(project.ID).Build(t, db)
+	globalAdmin := testutil.ARole("Admin").Build(t, db)
+	testutil.APolicy("project users", "users", "*").ForRole(projectAdmin).Build(t, db)
+	testutil.APolicy("all users", "users", "*").ForRole(globalAdmin).Build(t, db)
+
+	r := mux.NewRouter()
+	r.Handle("/users/{id}", auth.PolicyMiddleware(db, users.NewAuthUsers(db, 0), "users", "delete")(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })))
+	status := func(user schemas.User) int {
+		req := httptest.NewRequest(http.MethodDelete, "/users/"+uuid.NewString(), nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := status(testutil.AUser(t, db, "tenant@example.com", projectAdmin, project)); got != http.StatusForbidden {
+		t.Errorf("project role on a global route: got %d, want 403", got)
+	}
+	if got := status(testutil.AUser(t, db, "admin@example.com", globalAdmin, project)); got != http.StatusOK {
+		t.Errorf("global role on a global route: got %d, want 200", got)
+	}
+}
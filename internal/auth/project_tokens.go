@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// DefaultSessionLength is the token lifetime for roles without an expiration
+const DefaultSessionLength = 24 * time.Hour
+
+// SessionLength is the lifetime of the tokens issued to project users of a
+// role: its expiration, or DefaultSessionLength when it has none
+func SessionLength(role schemas.Role) time.Duration {
+	if role.Expiration <= 0 {
+		return DefaultSessionLength
+	}
+	return role.Expiration
+}
+
+// IssueProjectUserToken issues the JWT a project user receives on login. The
+// token lives as long as the user's role allows, and is refused with
+// roles.ErrIPNotAllowed when the role may not be used from the address of the
+// client in ctx.
+func IssueProjectUserToken(ctx context.Context, db *gorm.DB, user schemas.ProjectUser, now time.Time) (string, time.Time, error) {
+	return issueProjectUserToken(ctx, db, user, now, "")
+}
+
+// IssueProjectUserPasswordChangeToken issues the JWT a project user whose
+// password has expired receives on a password login: restricted to
+// ScopePasswordChange and lasting at most PasswordChangeTokenTTL
+func IssueProjectUserPasswordChangeToken(ctx context.Context, db *gorm.DB, user schemas.ProjectUser, now time.Time) (string, time.Time, error) {
+	return issueProjectUserToken(ctx, db, user, now, ScopePasswordChange)
+}
+
+func issueProjectUserToken(ctx context.Context, db *gorm.DB, user schemas.ProjectUser, now time.Time, scope string) (string, time.Time, error) {
+	var role schemas.Role
+	if err := db.First(&role, "id = ?", user.RoleId).Error; err != nil {
+		klog.Errorf("Error fetching role: %v", err)
+		return "", time.Time{}, errors.New("internal server error")
+	}
+	if err := roles.CheckIP(&role, logins.ClientFromContext(ctx).IPAddress); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := now.Add(SessionLength(role))
+	if limit := now.Add(PasswordChangeTokenTTL); scope == ScopePasswordChange && limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+
+	token, err := GenerateScopedToken(user.ID, user.Email, role.ID, user.ProjectId, expiresAt, scope)
+	if err != nil {
+		klog.Errorf("Error generating token: %v", err)
+		return "", time.Time{}, errors.New("failed to generate authentication token")
+	}
+	return token, expiresAt, nil
+}
@@ -8,22 +8,49 @@ import (
 	"github.com/google/uuid"
 )
 
-var jwtSecret = []byte("your-secret-key-change-this-in-production")
+// DefaultJWTSecret signs tokens until SetJWTSecret is called. It is public,
+// so production deployments must configure their own.
+const DefaultJWTSecret = "your-secret-key-change-this-in-production"
+
+var jwtSecret = []byte(DefaultJWTSecret)
+
+// SetJWTSecret sets the secret tokens are signed and validated with. It must
+// be called before the server starts handling requests.
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
+
+// ScopePasswordChange restricts a token to changing the holder's expired
+// password; AuthMiddleware refuses it everywhere else
+const ScopePasswordChange = "password_change"
+
+// PasswordChangeTokenTTL is how long a token restricted to
+// ScopePasswordChange lasts, at most
+const PasswordChangeTokenTTL = 15 * time.Minute
 
 type TokenClaims struct {
 	UserID    uuid.UUID `json:"user_id"`
 	Email     string    `json:"email"`
 	RoleId    uuid.UUID `json:"role_id"`
 	ProjectId uuid.UUID `json:"project_id"`
+	// Scope restricts what the token may be used for; empty for none
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(userID uuid.UUID, email string, roleId uuid.UUID, projectId uuid.UUID, expirationTime time.Time) (string, error) {
+	return GenerateScopedToken(userID, email, roleId, projectId, expirationTime, "")
+}
 
+// GenerateScopedToken issues a token restricted to scope, such as
+// ScopePasswordChange
+func GenerateScopedToken(userID uuid.UUID, email string, roleId uuid.UUID, projectId uuid.UUID, expirationTime time.Time, scope string) (string, error) {
 	claims := &TokenClaims{
-		UserID: userID,
-		Email:  email,
-		RoleId: roleId,
+		UserID:    userID,
+		Email:     email,
+		RoleId:    roleId,
+		ProjectId: projectId,
+		Scope:     scope,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -44,6 +71,15 @@ func GenerateToken(userID uuid.UUID, email string, roleId uuid.UUID, projectId u
 }
 
 func ValidateToken(tokenString string) (uuid.UUID, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+// ParseToken validates a token and returns its claims
+func ParseToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -52,17 +88,20 @@ func ValidateToken(tokenString string) (uuid.UUID, error) {
 	})
 
 	if err != nil {
-		return uuid.Nil, err
+		tokenValidationFailures.Inc(TokenFailureReason(err))
+		return nil, err
 	}
 
 	if !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		tokenValidationFailures.Inc("invalid")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(*TokenClaims)
 	if !ok {
-		return uuid.Nil, errors.New("invalid token claims")
+		tokenValidationFailures.Inc("invalid")
+		return nil, errors.New("invalid token claims")
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
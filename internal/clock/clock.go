@@ -0,0 +1,17 @@
+package clock
+
+import "time"
+
+// Clock tells the current time. Managers take a Clock instead of calling
+// time.Now directly so that time-dependent behaviour can be controlled.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by the system time
+type Real struct{}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
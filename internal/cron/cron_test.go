@@ -0,0 +1,79 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/cron"
+)
+
+func TestNext(t *testing.T) {
+	// A Sunday
+	from := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 9 * * 1", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 1, 9, 45, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 8-17/4 * * 1-5", time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 10 * * 7", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+		// Day of month and day of week both restricted: either matches
+		{"0 0 13 * 5", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{" @MONTHLY ", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := cron.Parse(tc.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tc.want) {
+				t.Errorf("Next(%s) = %s, want %s", from, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLast(t *testing.T) {
+	schedule, err := cron.Parse("0 9 * * 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	if got := schedule.Last(monday.Add(-30*24*time.Hour), monday.Add(time.Hour)); !got.Equal(monday) {
+		t.Errorf("Last over a month = %s, want the latest Monday %s", got, monday)
+	}
+	if got := schedule.Last(monday.Add(-time.Minute), monday); !got.Equal(monday) {
+		t.Errorf("Last = %s, want the firing at the end of the window", got)
+	}
+	if got := schedule.Last(monday, monday.Add(24*time.Hour)); !got.IsZero() {
+		t.Errorf("Last = %s, want none: the window starts after the firing", got)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 9 * *",
+		"0 9 * * 1 2026",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@fortnightly",
+	} {
+		if _, err := cron.Parse(spec); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid schedule", spec)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// liveEmailsAreUnique checks the unique email index of table: a second live
+// user with an email fails, a deleted one does not, and restoring the
+// deleted user then fails
+func liveEmailsAreUnique(t *testing.T, db *gorm.DB, table string, newUser func(email string) interface{}) {
+	t.Helper()
+
+	if err := db.Table(table).Create(newUser("a@example.com")).Error; err != nil {
+		t.Fatalf("creating the first user: %v", err)
+	}
+	if err := db.Table(table).Create(newUser("a@example.com")).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("a second live user with the email: err = %v, want %v", err, gorm.ErrDuplicatedKey)
+	}
+
+	if err := db.Table(table).Where("email = ?", "a@example.com").Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatalf("soft deleting the user: %v", err)
+	}
+	if err := db.Table(table).Create(newUser("a@example.com")).Error; err != nil {
+		t.Fatalf("recreating the email of a deleted user: %v", err)
+	}
+	if err := db.Table(table).Create(newUser("a@example.com")).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("a second live user beside the recreated one: err = %v, want %v", err, gorm.ErrDuplicatedKey)
+	}
+
+	restore := db.Table(table).Unscoped().Where("email = ? AND deleted_at IS NOT NULL", "a@example.com").Update("deleted_at", nil)
+	if !errors.Is(restore.Error, gorm.ErrDuplicatedKey) {
+		t.Fatalf("restoring the deleted user: err = %v, want %v", restore.Error, gorm.ErrDuplicatedKey)
+	}
+}
+
+func TestEmailsAreUniqueAmongLiveUsers(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		built := testutil.AProject().WithRole("Member").Build(t, db)
+		liveEmailsAreUnique(t, db, "users", func(email string) interface{} {
+			return &schemas.User{ID: uuid.New(), Email: email, RoleId: built.Roles["Member"].ID, ProjectId: built.Project.ID}
+		})
+	})
+
+	t.Run("project users", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		built := testutil.AProject().WithRole("Member").Build(t, db)
+		liveEmailsAreUnique(t, db, testutil.ProjectUserTable(built.Project.ID), func(email string) interface{} {
+			return &schemas.ProjectUser{ID: uuid.New(), Email: email, RoleId: built.Roles["Member"].ID, ProjectId: built.Project.ID}
+		})
+	})
+}
+
+func TestMigrateProjectUserTablesUpgradesTheEmailIndex(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	logs := testutil.CaptureKlog(t)
+	role := testutil.ARole("Member").Build(t, db)
+
+	// Two tables as older versions made them: one with the unique index on
+	// the email alone, one whose live users share an email
+	legacyTable := func() (*schemas.Project, string) {
+		t.Helper()
+		project := schemas.Project{ID: uuid.New(), Name: "Legacy", UniqueID: "legacy_" + uuid.NewString()[:8]}
+		if err := db.Create(&project).Error; err != nil {
+			t.Fatal(err)
+		}
+		table := testutil.ProjectUserTable(project.ID)
+		if err := db.Table(table).Migrator().CreateTable(&schemas.ProjectUser{}); err != nil {
+			t.Fatal(err)
+		}
+		return &project, table
+	}
+	indexed, indexedTable := legacyTable()
+	legacyIndex := "idx_" + indexedTable + "_email"
+	if err := db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %q ON %q (email)", legacyIndex, indexedTable)).Error; err != nil {
+		t.Fatal(err)
+	}
+	duplicated, duplicatedTable := legacyTable()
+	for i := 0; i < 2; i++ {
+		user := schemas.ProjectUser{ID: uuid.New(), Email: "same@example.com", RoleId: role.ID, ProjectId: duplicated.ID}
+		if err := db.Table(duplicatedTable).Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := internal.MigrateProjectUserTables(db, nil); err != nil {
+		t.Fatalf("MigrateProjectUserTables: %v", err)
+	}
+
+	migrator := db.Migrator()
+	if !migrator.HasIndex(indexedTable, schemas.ProjectUserEmailIndex(indexedTable)) || migrator.HasIndex(indexedTable, legacyIndex) {
+		t.Errorf("%s was not moved from the email index to the live email index", indexedTable)
+	}
+	liveEmailsAreUnique(t, db, indexedTable, func(email string) interface{} {
+		return &schemas.ProjectUser{ID: uuid.New(), Email: email, RoleId: role.ID, ProjectId: indexed.ID}
+	})
+
+	if migrator.HasIndex(duplicatedTable, schemas.ProjectUserEmailIndex(duplicatedTable)) {
+		t.Errorf("%s was indexed although its live users share an email", duplicatedTable)
+	}
+	if want := "Project user table " + duplicatedTable + " has 1 emails used by more than one live user"; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs do not warn about the duplicates:\n%s", logs.String())
+	}
+}
@@ -2,33 +2,62 @@ package internal
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/yash3004/user_management_service/cmd"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 	"k8s.io/klog/v2"
 )
 
+const (
+	defaultConnectAttempts = 5
+	defaultConnectBackoff  = time.Second
+	maxConnectBackoff      = 30 * time.Second
+)
+
 // Global variable to store the GORM DB instance
 var gormDBInstance *gorm.DB
 
+// regionsInstance routes project users to the regional databases opened
+// with gormDBInstance
+var regionsInstance *regions.Resolver
+
+// Dialer opens a GORM connection for the given DSN
+type Dialer func(dsn string) (*gorm.DB, error)
+
+// MySQLDialer opens a MySQL connection through GORM. Duplicate key errors are
+// translated to gorm.ErrDuplicatedKey, which the managers turn into conflicts.
+func MySQLDialer(dsn string) (*gorm.DB, error) {
+	return gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: queryLogger(), TranslateError: true})
+}
+
+// queryLogger is GORM's default logger, except that while email redaction is
+// on the slow queries and errors it logs leave out the query parameters,
+// which hold emails and password hashes
+func queryLogger() logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:        200 * time.Millisecond,
+		LogLevel:             logger.Warn,
+		Colorful:             true,
+		ParameterizedQueries: redact.CurrentMode() != redact.ModeOff,
+	})
+}
+
 func CreateMySqlConnection(cfg cmd.Config) (*sql.DB, error) {
-	dsn := cfg.DB.CreateDSN()
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := GetGormDB(cfg)
 	if err != nil {
-		klog.Fatalf("Failed to connect to the database: %v", err)
 		return nil, err
 	}
-
-	// Store the GORM DB instance for later use
-	gormDBInstance = db
-
-	// Auto migrate schemas
-	db.AutoMigrate(&schemas.Role{})
-	db.AutoMigrate(&schemas.Policy{})
-	db.AutoMigrate(&schemas.Project{})
-
 	return db.DB()
 }
 
@@ -38,17 +67,292 @@ func GetGormDB(cfg cmd.Config) (*gorm.DB, error) {
 		return gormDBInstance, nil
 	}
 
-	// If the instance doesn't exist, create a new connection
-	dsn := cfg.DB.CreateDSN()
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := OpenWithRetry(MySQLDialer, cfg.DB.CreateDSN(), cfg.DB.ConnectAttempts, cfg.DB.ConnectBackoff)
 	if err != nil {
 		klog.Errorf("Failed to connect to the database: %v", err)
 		return nil, err
 	}
-	db.AutoMigrate(&schemas.Role{})
-	db.AutoMigrate(&schemas.Policy{})
-	db.AutoMigrate(&schemas.Project{})
 
+	// A failed migration leaves the schema behind the code, so the service
+	// stops rather than serve from it. Nothing is rolled back: AutoMigrate
+	// only adds, and what it added is harmless to the previous version.
+	if err := AutoMigrate(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate the database: %w", err)
+	}
+	resolver, err := OpenRegions(MySQLDialer, db, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := MigrateProjectUserTables(db, resolver); err != nil {
+		return nil, fmt.Errorf("failed to migrate the project user tables: %w", err)
+	}
+	// Running with another scope than the database enforces would let
+	// duplicate checks and the index disagree, so a failed switch is fatal
+	if err := MigrateEmailScope(db, cfg.Users.EmailScope); err != nil {
+		return nil, fmt.Errorf("failed to migrate the email scope: %w", err)
+	}
+
+	// Store the GORM DB instance for later use
 	gormDBInstance = db
+	regionsInstance = resolver
 	return db, nil
 }
+
+// GetRegions returns the resolver routing project users to the databases of
+// their regions, connecting to the databases first if needed
+func GetRegions(cfg cmd.Config) (*regions.Resolver, error) {
+	if _, err := GetGormDB(cfg); err != nil {
+		return nil, err
+	}
+	return regionsInstance, nil
+}
+
+// OpenRegions connects to the databases of the configured regions and
+// returns the resolver routing project users to them, and to primary for
+// the regions without one. Only project user tables live in the regional
+// databases, so nothing else is migrated there.
+func OpenRegions(dial Dialer, primary *gorm.DB, cfg cmd.Config) (*regions.Resolver, error) {
+	databases := make(map[string]*gorm.DB, len(cfg.Projects.Regions.Databases))
+	for region, dbCfg := range cfg.Projects.Regions.Databases {
+		db, err := OpenWithRetry(dial, dbCfg.CreateDSN(), dbCfg.ConnectAttempts, dbCfg.ConnectBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the database of region %s: %w", region, err)
+		}
+		databases[region] = db
+	}
+	resolver, err := regions.NewResolver(primary, cfg.Projects.Regions.Allowed, databases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid projects.regions configuration: %w", err)
+	}
+	return resolver, nil
+}
+
+// AutoMigrate migrates the shared (non per-project) tables
+func AutoMigrate(db *gorm.DB) error {
+	addingStatus := db.Migrator().HasTable(&schemas.User{}) && !db.Migrator().HasColumn(&schemas.User{}, "Status")
+	if err := db.AutoMigrate(
+		&schemas.User{},
+		&schemas.UserProjectMembership{},
+		&schemas.Role{},
+		&schemas.Policy{},
+		&schemas.Project{},
+		&schemas.ImportJob{},
+		&schemas.WebhookSubscription{},
+		&schemas.AuditEvent{},
+		&schemas.MagicLinkToken{},
+		&schemas.WebAuthnCredential{},
+		&schemas.LoginEvent{},
+		&schemas.ProjectAPIToken{},
+		&schemas.OneTimeToken{},
+		&schemas.ReportRun{},
+		&schemas.Artifact{},
+		&schemas.PasswordHistory{},
+		&schemas.Consent{},
+	); err != nil {
+		return err
+	}
+	if addingStatus {
+		if err := backfillStatus(db, "users"); err != nil {
+			return err
+		}
+	}
+	// A new database, or one whose index came from the old uniqueIndex tag
+	// under another name, starts out with global email uniqueness. One still
+	// on the index counting soft-deleted users keeps the scope it had.
+	migrator := db.Migrator()
+	if !migrator.HasIndex(&schemas.User{}, schemas.UserEmailIndex) && !migrator.HasIndex(&schemas.User{}, schemas.UserProjectEmailIndex) {
+		scope := schemas.EmailScopeGlobal
+		if migrator.HasIndex(&schemas.User{}, schemas.LegacyUserProjectEmailIndex) {
+			scope = schemas.EmailScopeProject
+		}
+		return MigrateEmailScope(db, scope)
+	}
+	return nil
+}
+
+// MigrateEmailScope makes the unique index on users match the email scope:
+// on the email alone for schemas.EmailScopeGlobal (also used for ""), on the
+// project and email for schemas.EmailScopeProject. The new index is created
+// before the old ones are dropped, so a switch that fails, e.g. to global
+// while two projects have users with the same email, leaves the old scope in
+// force. Only live users count: the index also covers
+// schemas.NotDeletedColumn, which is NULL for soft-deleted users, so their
+// emails can be used again.
+func MigrateEmailScope(db *gorm.DB, scope string) error {
+	if scope == "" {
+		scope = schemas.EmailScopeGlobal
+	}
+	want, drop := schemas.UserEmailIndex, schemas.UserProjectEmailIndex
+	columns := []string{"email"}
+	switch scope {
+	case schemas.EmailScopeGlobal:
+	case schemas.EmailScopeProject:
+		want, drop = drop, want
+		columns = []string{"project_id", "email"}
+	default:
+		return fmt.Errorf("unknown email scope %q, expected %s or %s", scope, schemas.EmailScopeGlobal, schemas.EmailScopeProject)
+	}
+
+	if err := schemas.AddNotDeletedColumn(db, "users"); err != nil {
+		return fmt.Errorf("adding %s to users: %w", schemas.NotDeletedColumn, err)
+	}
+
+	migrator := db.Migrator()
+	if !migrator.HasIndex(&schemas.User{}, want) {
+		var duplicates int64
+		if err := db.Table("(?) AS d", db.Table("users").Select(strings.Join(columns, ", ")).Where("deleted_at IS NULL").Group(strings.Join(columns, ", ")).Having("COUNT(*) > 1")).
+			Count(&duplicates).Error; err != nil {
+			return err
+		}
+		if duplicates > 0 {
+			return fmt.Errorf("cannot switch to the %s email scope: %d emails are used by more than one user within it", scope, duplicates)
+		}
+
+		quoted := make([]string, len(columns), len(columns)+1)
+		for i, column := range columns {
+			quoted[i] = db.Statement.Quote(column)
+		}
+		quoted = append(quoted, db.Statement.Quote(schemas.NotDeletedColumn))
+		if err := db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)",
+			db.Statement.Quote(want), db.Statement.Quote("users"), strings.Join(quoted, ", "))).Error; err != nil {
+			return fmt.Errorf("creating %s: %w", want, err)
+		}
+		klog.Infof("User emails are now unique within the %s scope", scope)
+	}
+	for _, name := range []string{drop, schemas.LegacyUserEmailIndex, schemas.LegacyUserProjectEmailIndex} {
+		if migrator.HasIndex(&schemas.User{}, name) {
+			if err := migrator.DropIndex(&schemas.User{}, name); err != nil {
+				return fmt.Errorf("dropping %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// backfillStatus derives the status of users that predate the status column
+// from their active flag. The column is added with every row active.
+func backfillStatus(db *gorm.DB, tableName string) error {
+	return db.Table(tableName).Where("active = ?", false).Update("status", schemas.UserStatusDeactivated).Error
+}
+
+// MigrateProjectUserTables brings every per-project user table up to date
+// with schemas.ProjectUser, so that columns added since a project was
+// created exist in its table. The projects are read from db and their tables
+// migrated in the database resolver routes their region to; a nil resolver
+// keeps every table in db.
+func MigrateProjectUserTables(db *gorm.DB, resolver *regions.Resolver) error {
+	// The IDs are read as stored, which legacyUserTable needs
+	var projects []struct {
+		ID     string
+		Region string
+	}
+	if err := db.Model(&schemas.Project{}).Select("id", "region").Find(&projects).Error; err != nil {
+		return err
+	}
+	for _, project := range projects {
+		projectID := project.ID
+		tableName, err := projecttable.UsersFor(projectID)
+		if err != nil {
+			klog.Warningf("Skipping the user table of project %q: %v", projectID, err)
+			continue
+		}
+		db := db
+		if regional := resolver.DB(project.Region); regional != nil {
+			db = regional
+		}
+		if legacy := legacyUserTable(db, projectID, tableName); legacy != "" {
+			klog.Warningf("Project user table %s is not in the normalized form and will not be used; rename it with RENAME TABLE %s TO %s",
+				legacy, projecttable.Quote(db, legacy), projecttable.Quote(db, tableName))
+			continue
+		}
+		addingStatus := !db.Table(tableName).Migrator().HasColumn(&schemas.ProjectUser{}, "Status")
+		if err := db.Table(tableName).AutoMigrate(&schemas.ProjectUser{}); err != nil {
+			return fmt.Errorf("migrating %s: %w", tableName, err)
+		}
+		if addingStatus {
+			if err := backfillStatus(db, tableName); err != nil {
+				return fmt.Errorf("backfilling %s status: %w", tableName, err)
+			}
+		}
+		migrateProjectUserEmailIndex(db, tableName)
+	}
+	return nil
+}
+
+// migrateProjectUserEmailIndex gives a per-project user table the unique
+// index on its live users' emails. Tables whose live users already share an
+// email are left without it, with a warning, rather than stopping the
+// service: the managers check for duplicates themselves, and the index is
+// created on the first start after the duplicates are merged.
+func migrateProjectUserEmailIndex(db *gorm.DB, tableName string) {
+	if db.Migrator().HasIndex(tableName, schemas.ProjectUserEmailIndex(tableName)) {
+		return
+	}
+	var duplicates int64
+	if err := db.Table("(?) AS d", db.Table(tableName).Select("email").Where("deleted_at IS NULL").Group("email").Having("COUNT(*) > 1")).
+		Count(&duplicates).Error; err != nil {
+		klog.Warningf("Cannot check %s for duplicate emails: %v", tableName, err)
+		return
+	}
+	if duplicates > 0 {
+		klog.Warningf("Project user table %s has %d emails used by more than one live user; not making emails unique until they are merged", tableName, duplicates)
+		return
+	}
+	if err := schemas.CreateProjectUserEmailIndex(db, tableName); err != nil {
+		klog.Warningf("Cannot make emails unique in %s: %v", tableName, err)
+	}
+}
+
+// legacyUserTable returns the name of a users table that was created under
+// the project ID as stored, e.g. in upper case, rather than the normalized
+// name, or "" if there is none. Such tables are not renamed automatically,
+// since whether the two names clash depends on the database's case
+// sensitivity; see the README for the statement to run.
+func legacyUserTable(db *gorm.DB, projectID, tableName string) string {
+	legacy := projecttable.Legacy(projectID)
+	if legacy == tableName || projecttable.Valid(legacy) {
+		return ""
+	}
+	migrator := db.Migrator()
+	if migrator.HasTable(tableName) || !migrator.HasTable(legacy) {
+		return ""
+	}
+	return legacy
+}
+
+// OpenWithRetry dials the database, retrying with exponential backoff until it
+// succeeds or the attempts are exhausted. This lets the service start before
+// the database is ready, as is common under container orchestration.
+func OpenWithRetry(dial Dialer, dsn string, attempts int, backoff time.Duration) (*gorm.DB, error) {
+	if attempts <= 0 {
+		attempts = defaultConnectAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		db, err := dial(dsn)
+		if err == nil {
+			if attempt > 1 {
+				klog.Infof("Connected to the database on attempt %d/%d", attempt, attempts)
+			}
+			return db, nil
+		}
+		lastErr = err
+
+		if attempt == attempts {
+			break
+		}
+		klog.Warningf("Database connection attempt %d/%d failed: %v; retrying in %s", attempt, attempts, err, backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("failed to connect to the database after %d attempts: %w", attempts, lastErr)
+}
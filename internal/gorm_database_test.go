@@ -0,0 +1,125 @@
+package internal_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// flakyDialer fails the first failures dials, then hands out db
+func flakyDialer(failures int, db *gorm.DB, dials *int) internal.Dialer {
+	return func(dsn string) (*gorm.DB, error) {
+		*dials++
+		if *dials <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return db, nil
+	}
+}
+
+func TestOpenWithRetryConnectsAfterFailures(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	logs := testutil.CaptureKlog(t)
+
+	dials := 0
+	opened, err := internal.OpenWithRetry(flakyDialer(2, db, &dials), "dsn", 5, time.Millisecond)
+	if err != nil {
+		t.Fatalf("OpenWithRetry() error = %v", err)
+	}
+	if opened != db {
+		t.Error("OpenWithRetry() did not return the dialed connection")
+	}
+	if dials != 3 {
+		t.Errorf("dialed %d times, want 3", dials)
+	}
+
+	output := logs.String()
+	for _, want := range []string{"attempt 1/5 failed", "attempt 2/5 failed", "Connected to the database on attempt 3/5"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("logs do not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestOpenWithRetryGivesUp(t *testing.T) {
+	dials := 0
+	_, err := internal.OpenWithRetry(flakyDialer(10, nil, &dials), "dsn", 3, time.Millisecond)
+	if err == nil {
+		t.Fatal("OpenWithRetry() succeeded, want an error")
+	}
+	if dials != 3 {
+		t.Errorf("dialed %d times, want 3", dials)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("error = %v, want the attempts and the last dial error", err)
+	}
+}
+
+// failDDL makes the statements of db starting with prefix fail, as a
+// migration the database refuses would
+func failDDL(t *testing.T, db *gorm.DB, prefix string) {
+	t.Helper()
+	err := db.Callback().Raw().Before("gorm:raw").Register("test:fail_ddl", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), prefix) {
+			tx.AddError(errors.New("migration refused"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailedMigrationIsReturnedWithoutRollingBack(t *testing.T) {
+	t.Run("shared tables", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+		// The consents table is new in the release being rolled out
+		if err := db.Migrator().DropTable(&schemas.Consent{}); err != nil {
+			t.Fatal(err)
+		}
+		failDDL(t, db, "CREATE TABLE `consents`")
+
+		err := internal.AutoMigrate(db)
+		if err == nil || !strings.Contains(err.Error(), "migration refused") {
+			t.Fatalf("AutoMigrate() error = %v, want the refused migration", err)
+		}
+		var projects int64
+		if err := db.Model(&schemas.Project{}).Where("id = ?", built.Project.ID).Count(&projects).Error; err != nil || projects != 1 {
+			t.Errorf("the project is gone after the failed migration (%d, %v)", projects, err)
+		}
+		for _, table := range []interface{}{&schemas.User{}, &schemas.Role{}, &schemas.Policy{}, &schemas.PasswordHistory{}} {
+			if !db.Migrator().HasTable(table) {
+				t.Errorf("%T's table was dropped by the failed migration", table)
+			}
+		}
+	})
+
+	t.Run("project user tables", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+		table := testutil.ProjectUserTable(built.Project.ID)
+		// The deleted_by column is new in the release being rolled out
+		if err := db.Table(table).Migrator().DropColumn(&schemas.ProjectUser{}, "DeletedBy"); err != nil {
+			t.Fatal(err)
+		}
+		failDDL(t, db, "ALTER TABLE `"+table+"` ADD")
+
+		err := internal.MigrateProjectUserTables(db, nil)
+		if err == nil || !strings.Contains(err.Error(), table) || !strings.Contains(err.Error(), "migration refused") {
+			t.Fatalf("MigrateProjectUserTables() error = %v, want the failure naming %s", err, table)
+		}
+		var user schemas.ProjectUser
+		if err := db.Table(table).Omit("DeletedBy").First(&user, "email = ?", "a@example.com").Error; err != nil {
+			t.Errorf("the user is gone after the failed migration: %v", err)
+		}
+		if db.Table(table).Migrator().HasColumn(&schemas.ProjectUser{}, "DeletedBy") {
+			t.Error("deleted_by was added although its migration failed")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// Package logging gives each subsystem of the service a named logger whose
+// verbosity can be raised on its own, so that e.g. "projectusers" can log at
+// level 4 without flooding the log with every other subsystem. Loggers are
+// backed by klog's contextual logging: lines written for a request carry the
+// values the request context holds, such as the request ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Logger writes the log lines of one subsystem
+type Logger interface {
+	// Named returns the logger of a part of the subsystem, e.g. "github"
+	// under "oauth". The part is filtered by its own level if one is set,
+	// and by the subsystem's otherwise.
+	Named(name string) Logger
+	// For returns the logger with the values ctx carries, such as the ID of
+	// the request being served
+	For(ctx context.Context) Logger
+	// V returns a logger whose Infof writes only when the subsystem's
+	// verbosity is at least level
+	V(level int) Logger
+	// Enabled reports whether Infof would write
+	Enabled() bool
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// levels is the verbosity of each subsystem configured with SetLevels
+var levels = struct {
+	sync.RWMutex
+	bySubsystem map[string]int
+}{}
+
+// SetLevels sets the verbosity of subsystems by name, e.g.
+// {"projectusers": 4, "oauth.github": 2}. Subsystems not named log at
+// klog's -v verbosity.
+func SetLevels(bySubsystem map[string]int) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.bySubsystem = make(map[string]int, len(bySubsystem))
+	for name, level := range bySubsystem {
+		levels.bySubsystem[name] = level
+	}
+}
+
+// level returns the verbosity set for the named subsystem or the closest
+// enclosing one, and whether any was set
+func level(name string) (int, bool) {
+	levels.RLock()
+	defer levels.RUnlock()
+	for {
+		if v, ok := levels.bySubsystem[name]; ok {
+			return v, true
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// Named returns the logger of a subsystem, which packages usually keep in a
+// package-level log variable. Its lines are prefixed with the name, their
+// verbosity can be set per subsystem, and with For(ctx) they carry the
+// request ID and user of the request being served.
+func Named(name string) Logger {
+	return &klogLogger{name: name}
+}
+
+// WithValues returns a context whose loggers add the key/value pairs to
+// every line written with For(ctx)
+func WithValues(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return klog.NewContext(ctx, klog.FromContext(ctx).WithValues(keysAndValues...))
+}
+
+// klogLogger is a Logger writing through a klog contextual logger
+type klogLogger struct {
+	name  string      // Dot-separated subsystem name
+	base  klog.Logger // Logger from the request context; klog's own when unset
+	set   bool        // base is set
+	level int         // Verbosity Infof writes at
+}
+
+func (l *klogLogger) Named(name string) Logger {
+	named := *l
+	named.name = l.name + "." + name
+	return &named
+}
+
+func (l *klogLogger) For(ctx context.Context) Logger {
+	withCtx := *l
+	withCtx.base, withCtx.set = klog.FromContext(ctx), true
+	return &withCtx
+}
+
+func (l *klogLogger) V(level int) Logger {
+	leveled := *l
+	leveled.level = level
+	return &leveled
+}
+
+func (l *klogLogger) Enabled() bool {
+	if v, ok := level(l.name); ok && l.level <= v {
+		return true
+	}
+	return klog.V(klog.Level(l.level)).Enabled()
+}
+
+func (l *klogLogger) Infof(format string, args ...interface{}) {
+	if l.Enabled() {
+		l.sink().Info(fmt.Sprintf(format, args...))
+	}
+}
+
+// Warningf writes at info severity, as contextual loggers have no warning
+// severity, with severity=warning among the values
+func (l *klogLogger) Warningf(format string, args ...interface{}) {
+	l.sink().Info(fmt.Sprintf(format, args...), "severity", "warning")
+}
+
+func (l *klogLogger) Errorf(format string, args ...interface{}) {
+	l.sink().Error(nil, fmt.Sprintf(format, args...))
+}
+
+// sink returns the klog logger to write a line through, named after the
+// subsystem and reporting the caller of Infof, Warningf or Errorf
+func (l *klogLogger) sink() klog.Logger {
+	base := l.base
+	if !l.set {
+		base = klog.Background()
+	}
+	return base.WithName(l.name).WithCallDepth(1)
+}
@@ -0,0 +1,85 @@
+package logging_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+// withLevels sets the subsystem levels for the test
+func withLevels(t *testing.T, levels map[string]int) {
+	t.Helper()
+
+	logging.SetLevels(levels)
+	t.Cleanup(func() { logging.SetLevels(nil) })
+}
+
+func TestLevelsArePerSubsystem(t *testing.T) {
+	withLevels(t, map[string]int{"projectusers": 4, "oauth.github": 2})
+
+	for _, tc := range []struct {
+		name   string
+		logger logging.Logger
+		level  int
+		writes bool
+	}{
+		{"projectusers", logging.Named("projectusers"), 4, true},
+		{"projectusers", logging.Named("projectusers"), 5, false},
+		{"projectusers.merge", logging.Named("projectusers").Named("merge"), 4, true},
+		{"oauth.github", logging.Named("oauth").Named("github"), 2, true},
+		{"oauth.github", logging.Named("oauth").Named("github"), 3, false},
+		{"oauth.google", logging.Named("oauth").Named("google"), 1, false},
+		{"oauth", logging.Named("oauth"), 1, false},
+		{"auth", logging.Named("auth"), 1, false},
+		{"auth", logging.Named("auth"), 0, true},
+	} {
+		logs := testutil.CaptureKlog(t)
+		tc.logger.V(tc.level).Infof("probe at %d", tc.level)
+		if written := strings.Contains(logs.String(), "probe at"); written != tc.writes {
+			t.Errorf("%s at level %d wrote %v, want %v", tc.name, tc.level, written, tc.writes)
+		}
+		if tc.logger.V(tc.level).Enabled() != tc.writes {
+			t.Errorf("%s at level %d: Enabled disagrees with what was written", tc.name, tc.level)
+		}
+	}
+}
+
+func TestWarningsAndErrorsIgnoreTheLevel(t *testing.T) {
+	withLevels(t, nil)
+	logs := testutil.CaptureKlog(t)
+
+	logger := logging.Named("projectusers").V(9)
+	logger.Warningf("a warning")
+	logger.Errorf("an error")
+	for _, line := range []string{"a warning", "an error"} {
+		if !strings.Contains(logs.String(), line) {
+			t.Errorf("%q was not written:\n%s", line, logs.String())
+		}
+	}
+}
+
+func TestLinesCarryTheSubsystemAndContext(t *testing.T) {
+	withLevels(t, nil)
+	logs := testutil.CaptureKlog(t)
+
+	ctx := logging.WithValues(context.Background(), "request_id", "req-123")
+	ctx = logging.WithValues(ctx, "user_id", "user-456")
+	logging.Named("oauth").Named("github").For(ctx).Infof("exchanged the code")
+	logging.Named("oauth").Infof("without a request")
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2:\n%s", len(lines), logs.String())
+	}
+	for _, want := range []string{"exchanged the code", `logger="oauth.github"`, `request_id="req-123"`, `user_id="user-456"`} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("the line for the request lacks %s: %s", want, lines[0])
+		}
+	}
+	if strings.Contains(lines[1], "request_id") || !strings.Contains(lines[1], `logger="oauth"`) {
+		t.Errorf("the line without a request = %s, want only the subsystem", lines[1])
+	}
+}
@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+var _ apitokens.APITokenManager = (*APITokenManager)(nil)
+
+// APITokenManager is an apitokens.APITokenManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type APITokenManager struct {
+	CreateTokenFunc  func(ctx context.Context, projectID uuid.UUID, name string, serviceAccountID *uuid.UUID) (*schemas.ProjectAPIToken, string, error)
+	ListTokensFunc   func(ctx context.Context, projectID uuid.UUID) ([]schemas.ProjectAPIToken, error)
+	RevokeTokenFunc  func(ctx context.Context, projectID, tokenID uuid.UUID) error
+	AuthenticateFunc func(ctx context.Context, projectID uuid.UUID, token string) error
+}
+
+// CreateToken calls CreateTokenFunc
+func (m *APITokenManager) CreateToken(ctx context.Context, projectID uuid.UUID, name string, serviceAccountID *uuid.UUID) (*schemas.ProjectAPIToken, string, error) {
+	if m.CreateTokenFunc == nil {
+		panic("mocks: APITokenManager.CreateToken called but CreateTokenFunc is not set")
+	}
+	return m.CreateTokenFunc(ctx, projectID, name, serviceAccountID)
+}
+
+// ListTokens calls ListTokensFunc
+func (m *APITokenManager) ListTokens(ctx context.Context, projectID uuid.UUID) ([]schemas.ProjectAPIToken, error) {
+	if m.ListTokensFunc == nil {
+		panic("mocks: APITokenManager.ListTokens called but ListTokensFunc is not set")
+	}
+	return m.ListTokensFunc(ctx, projectID)
+}
+
+// RevokeToken calls RevokeTokenFunc
+func (m *APITokenManager) RevokeToken(ctx context.Context, projectID, tokenID uuid.UUID) error {
+	if m.RevokeTokenFunc == nil {
+		panic("mocks: APITokenManager.RevokeToken called but RevokeTokenFunc is not set")
+	}
+	return m.RevokeTokenFunc(ctx, projectID, tokenID)
+}
+
+// Authenticate calls AuthenticateFunc
+func (m *APITokenManager) Authenticate(ctx context.Context, projectID uuid.UUID, token string) error {
+	if m.AuthenticateFunc == nil {
+		panic("mocks: APITokenManager.Authenticate called but AuthenticateFunc is not set")
+	}
+	return m.AuthenticateFunc(ctx, projectID, token)
+}
@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/artifacts"
+)
+
+var _ artifacts.ArtifactManager = (*ArtifactManager)(nil)
+
+// ArtifactManager is an artifacts.ArtifactManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ArtifactManager struct {
+	SaveFunc      func(ctx context.Context, key, kind string, projectID *uuid.UUID, expires bool, content io.Reader) error
+	OpenFunc      func(ctx context.Context, key string) (io.ReadCloser, error)
+	SignedURLFunc func(ctx context.Context, key string) (string, error)
+	ExpireFunc    func(ctx context.Context, keys ...string) error
+	DeleteFunc    func(ctx context.Context, key string) error
+	CleanupFunc   func(ctx context.Context) (int, error)
+	StartFunc     func(ctx context.Context)
+}
+
+// Save calls SaveFunc
+func (m *ArtifactManager) Save(ctx context.Context, key, kind string, projectID *uuid.UUID, expires bool, content io.Reader) error {
+	if m.SaveFunc == nil {
+		panic("mocks: ArtifactManager.Save called but SaveFunc is not set")
+	}
+	return m.SaveFunc(ctx, key, kind, projectID, expires, content)
+}
+
+// Open calls OpenFunc
+func (m *ArtifactManager) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if m.OpenFunc == nil {
+		panic("mocks: ArtifactManager.Open called but OpenFunc is not set")
+	}
+	return m.OpenFunc(ctx, key)
+}
+
+// SignedURL calls SignedURLFunc
+func (m *ArtifactManager) SignedURL(ctx context.Context, key string) (string, error) {
+	if m.SignedURLFunc == nil {
+		panic("mocks: ArtifactManager.SignedURL called but SignedURLFunc is not set")
+	}
+	return m.SignedURLFunc(ctx, key)
+}
+
+// Expire calls ExpireFunc
+func (m *ArtifactManager) Expire(ctx context.Context, keys ...string) error {
+	if m.ExpireFunc == nil {
+		panic("mocks: ArtifactManager.Expire called but ExpireFunc is not set")
+	}
+	return m.ExpireFunc(ctx, keys...)
+}
+
+// Delete calls DeleteFunc
+func (m *ArtifactManager) Delete(ctx context.Context, key string) error {
+	if m.DeleteFunc == nil {
+		panic("mocks: ArtifactManager.Delete called but DeleteFunc is not set")
+	}
+	return m.DeleteFunc(ctx, key)
+}
+
+// Cleanup calls CleanupFunc
+func (m *ArtifactManager) Cleanup(ctx context.Context) (int, error) {
+	if m.CleanupFunc == nil {
+		panic("mocks: ArtifactManager.Cleanup called but CleanupFunc is not set")
+	}
+	return m.CleanupFunc(ctx)
+}
+
+// Start calls StartFunc
+func (m *ArtifactManager) Start(ctx context.Context) {
+	if m.StartFunc == nil {
+		panic("mocks: ArtifactManager.Start called but StartFunc is not set")
+	}
+	m.StartFunc(ctx)
+}
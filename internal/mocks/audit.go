@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/yash3004/user_management_service/audit"
+)
+
+var _ audit.AuditManager = (*AuditManager)(nil)
+
+// AuditManager is an audit.AuditManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type AuditManager struct {
+	ListEventsFunc func(ctx context.Context, filter audit.Filter) (*audit.Page, error)
+}
+
+// ListEvents calls ListEventsFunc
+func (m *AuditManager) ListEvents(ctx context.Context, filter audit.Filter) (*audit.Page, error) {
+	if m.ListEventsFunc == nil {
+		panic("mocks: AuditManager.ListEvents called but ListEventsFunc is not set")
+	}
+	return m.ListEventsFunc(ctx, filter)
+}
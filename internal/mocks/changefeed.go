@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/changefeed"
+)
+
+var _ changefeed.ChangeFeed = (*ChangeFeed)(nil)
+
+// ChangeFeed is a changefeed.ChangeFeed whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ChangeFeed struct {
+	ChangesFunc func(ctx context.Context, projectID uuid.UUID, since string, wait time.Duration, limit int) (*changefeed.Page, error)
+	PublishFunc func(ctx context.Context, projectID uuid.UUID, event string, data interface{})
+}
+
+// Changes calls ChangesFunc
+func (m *ChangeFeed) Changes(ctx context.Context, projectID uuid.UUID, since string, wait time.Duration, limit int) (*changefeed.Page, error) {
+	if m.ChangesFunc == nil {
+		panic("mocks: ChangeFeed.Changes called but ChangesFunc is not set")
+	}
+	return m.ChangesFunc(ctx, projectID, since, wait, limit)
+}
+
+// Publish calls PublishFunc
+func (m *ChangeFeed) Publish(ctx context.Context, projectID uuid.UUID, event string, data interface{}) {
+	if m.PublishFunc == nil {
+		panic("mocks: ChangeFeed.Publish called but PublishFunc is not set")
+	}
+	m.PublishFunc(ctx, projectID, event, data)
+}
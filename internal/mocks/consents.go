@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+var _ consents.ConsentManager = (*ConsentManager)(nil)
+
+// ConsentManager is a consents.ConsentManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ConsentManager struct {
+	AcceptFunc       func(ctx context.Context, projectID, userID uuid.UUID, document, version string) (*schemas.Consent, error)
+	ListConsentsFunc func(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.Consent, error)
+	OutstandingFunc  func(ctx context.Context, projectID, userID uuid.UUID) ([]consents.Outstanding, error)
+	EnforcedFunc     func(ctx context.Context, projectID uuid.UUID) (bool, error)
+}
+
+// Accept calls AcceptFunc
+func (m *ConsentManager) Accept(ctx context.Context, projectID, userID uuid.UUID, document, version string) (*schemas.Consent, error) {
+	if m.AcceptFunc == nil {
+		panic("mocks: ConsentManager.Accept called but AcceptFunc is not set")
+	}
+	return m.AcceptFunc(ctx, projectID, userID, document, version)
+}
+
+// ListConsents calls ListConsentsFunc
+func (m *ConsentManager) ListConsents(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.Consent, error) {
+	if m.ListConsentsFunc == nil {
+		panic("mocks: ConsentManager.ListConsents called but ListConsentsFunc is not set")
+	}
+	return m.ListConsentsFunc(ctx, projectID, userID)
+}
+
+// Outstanding calls OutstandingFunc
+func (m *ConsentManager) Outstanding(ctx context.Context, projectID, userID uuid.UUID) ([]consents.Outstanding, error) {
+	if m.OutstandingFunc == nil {
+		panic("mocks: ConsentManager.Outstanding called but OutstandingFunc is not set")
+	}
+	return m.OutstandingFunc(ctx, projectID, userID)
+}
+
+// Enforced calls EnforcedFunc
+func (m *ConsentManager) Enforced(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	if m.EnforcedFunc == nil {
+		panic("mocks: ConsentManager.Enforced called but EnforcedFunc is not set")
+	}
+	return m.EnforcedFunc(ctx, projectID)
+}
@@ -0,0 +1,4 @@
+// Package mocks provides in-memory stand-ins for the manager interfaces so
+// endpoints can be exercised without a database. Each mock has one Func field
+// per interface method; set the ones a test expects to be called.
+package mocks
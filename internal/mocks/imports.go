@@ -0,0 +1,71 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/imports"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+var _ imports.ImportManager = (*ImportManager)(nil)
+
+// ImportManager is an imports.ImportManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ImportManager struct {
+	CreateImportFunc    func(ctx context.Context, projectID string, file io.Reader) (*schemas.ImportJob, error)
+	GetImportFunc       func(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error)
+	CancelImportFunc    func(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error)
+	OpenErrorReportFunc func(ctx context.Context, projectID string, jobID uuid.UUID) (io.ReadCloser, error)
+	ErrorReportURLFunc  func(ctx context.Context, projectID string, jobID uuid.UUID) (string, error)
+	StartFunc           func(ctx context.Context)
+}
+
+// CreateImport calls CreateImportFunc
+func (m *ImportManager) CreateImport(ctx context.Context, projectID string, file io.Reader) (*schemas.ImportJob, error) {
+	if m.CreateImportFunc == nil {
+		panic("mocks: ImportManager.CreateImport called but CreateImportFunc is not set")
+	}
+	return m.CreateImportFunc(ctx, projectID, file)
+}
+
+// GetImport calls GetImportFunc
+func (m *ImportManager) GetImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error) {
+	if m.GetImportFunc == nil {
+		panic("mocks: ImportManager.GetImport called but GetImportFunc is not set")
+	}
+	return m.GetImportFunc(ctx, projectID, jobID)
+}
+
+// CancelImport calls CancelImportFunc
+func (m *ImportManager) CancelImport(ctx context.Context, projectID string, jobID uuid.UUID) (*schemas.ImportJob, error) {
+	if m.CancelImportFunc == nil {
+		panic("mocks: ImportManager.CancelImport called but CancelImportFunc is not set")
+	}
+	return m.CancelImportFunc(ctx, projectID, jobID)
+}
+
+// OpenErrorReport calls OpenErrorReportFunc
+func (m *ImportManager) OpenErrorReport(ctx context.Context, projectID string, jobID uuid.UUID) (io.ReadCloser, error) {
+	if m.OpenErrorReportFunc == nil {
+		panic("mocks: ImportManager.OpenErrorReport called but OpenErrorReportFunc is not set")
+	}
+	return m.OpenErrorReportFunc(ctx, projectID, jobID)
+}
+
+// ErrorReportURL calls ErrorReportURLFunc
+func (m *ImportManager) ErrorReportURL(ctx context.Context, projectID string, jobID uuid.UUID) (string, error) {
+	if m.ErrorReportURLFunc == nil {
+		panic("mocks: ImportManager.ErrorReportURL called but ErrorReportURLFunc is not set")
+	}
+	return m.ErrorReportURLFunc(ctx, projectID, jobID)
+}
+
+// Start calls StartFunc
+func (m *ImportManager) Start(ctx context.Context) {
+	if m.StartFunc == nil {
+		panic("mocks: ImportManager.Start called but StartFunc is not set")
+	}
+	m.StartFunc(ctx)
+}
@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/logins"
+)
+
+var _ logins.LoginManager = (*LoginManager)(nil)
+
+// LoginManager is a logins.LoginManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type LoginManager struct {
+	RecordLoginFunc func(ctx context.Context, projectID, userID uuid.UUID, email, method string)
+	ListLoginsFunc  func(ctx context.Context, projectID, userID uuid.UUID, limit int) ([]schemas.LoginEvent, error)
+}
+
+// RecordLogin calls RecordLoginFunc
+func (m *LoginManager) RecordLogin(ctx context.Context, projectID, userID uuid.UUID, email, method string) {
+	if m.RecordLoginFunc == nil {
+		panic("mocks: LoginManager.RecordLogin called but RecordLoginFunc is not set")
+	}
+	m.RecordLoginFunc(ctx, projectID, userID, email, method)
+}
+
+// ListLogins calls ListLoginsFunc
+func (m *LoginManager) ListLogins(ctx context.Context, projectID, userID uuid.UUID, limit int) ([]schemas.LoginEvent, error) {
+	if m.ListLoginsFunc == nil {
+		panic("mocks: LoginManager.ListLogins called but ListLoginsFunc is not set")
+	}
+	return m.ListLoginsFunc(ctx, projectID, userID, limit)
+}
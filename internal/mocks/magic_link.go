@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/magiclink"
+)
+
+var _ magiclink.MagicLinkManager = (*MagicLinkManager)(nil)
+
+// MagicLinkManager is a magiclink.MagicLinkManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type MagicLinkManager struct {
+	RequestLinkFunc func(ctx context.Context, projectID uuid.UUID, email string) error
+	VerifyFunc      func(ctx context.Context, projectID uuid.UUID, token string) (*magiclink.Session, error)
+}
+
+// RequestLink calls RequestLinkFunc
+func (m *MagicLinkManager) RequestLink(ctx context.Context, projectID uuid.UUID, email string) error {
+	if m.RequestLinkFunc == nil {
+		panic("mocks: MagicLinkManager.RequestLink called but RequestLinkFunc is not set")
+	}
+	return m.RequestLinkFunc(ctx, projectID, email)
+}
+
+// Verify calls VerifyFunc
+func (m *MagicLinkManager) Verify(ctx context.Context, projectID uuid.UUID, token string) (*magiclink.Session, error) {
+	if m.VerifyFunc == nil {
+		panic("mocks: MagicLinkManager.Verify called but VerifyFunc is not set")
+	}
+	return m.VerifyFunc(ctx, projectID, token)
+}
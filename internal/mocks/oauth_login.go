@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/yash3004/user_management_service/oauthlogin"
+)
+
+var _ oauthlogin.LoginService = (*LoginService)(nil)
+
+// LoginService is an oauthlogin.LoginService whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type LoginService struct {
+	CompleteLoginFunc func(ctx context.Context, params oauthlogin.CompleteLoginParams) (oauthlogin.LoginResult, error)
+}
+
+// CompleteLogin calls CompleteLoginFunc
+func (m *LoginService) CompleteLogin(ctx context.Context, params oauthlogin.CompleteLoginParams) (oauthlogin.LoginResult, error) {
+	if m.CompleteLoginFunc == nil {
+		panic("mocks: LoginService.CompleteLogin called but CompleteLoginFunc is not set")
+	}
+	return m.CompleteLoginFunc(ctx, params)
+}
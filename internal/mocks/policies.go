@@ -0,0 +1,125 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/policies"
+)
+
+var _ policies.PolicyManager = (*PolicyManager)(nil)
+
+// PolicyManager is a policies.PolicyManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type PolicyManager struct {
+	CreatePolicyFunc              func(ctx context.Context, name, description, resource, action, effect string, projectID *uuid.UUID) (*schemas.Policy, error)
+	GetPolicyFunc                 func(ctx context.Context, id uuid.UUID) (*schemas.Policy, error)
+	GetPolicyIncludingDeletedFunc func(ctx context.Context, id uuid.UUID) (*schemas.Policy, error)
+	GetPolicyByNameFunc           func(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Policy, error)
+	ListPoliciesFunc              func(ctx context.Context, order sorting.Order) ([]schemas.Policy, error)
+	UpdatePolicyFunc              func(ctx context.Context, id uuid.UUID, name, description, resource, action, effect string) (*schemas.Policy, error)
+	PatchPolicyFunc               func(ctx context.Context, id uuid.UUID, patch policies.PolicyPatch) (*schemas.Policy, error)
+	DeletePolicyFunc              func(ctx context.Context, id uuid.UUID) error
+	AuthorizeFunc                 func(ctx context.Context, projectID, userID uuid.UUID, resource, action string) (*policies.Decision, error)
+	AffectedUsersFunc             func(ctx context.Context, id uuid.UUID, sampleSize int) (*policies.AffectedUsers, error)
+	ImpactFunc                    func(ctx context.Context, id uuid.UUID, proposed policies.Definition) (*policies.PolicyImpact, error)
+	RolePoliciesFunc              func(ctx context.Context, projectID, roleID uuid.UUID) ([]schemas.Policy, error)
+}
+
+// CreatePolicy calls CreatePolicyFunc
+func (m *PolicyManager) CreatePolicy(ctx context.Context, name, description, resource, action, effect string, projectID *uuid.UUID) (*schemas.Policy, error) {
+	if m.CreatePolicyFunc == nil {
+		panic("mocks: PolicyManager.CreatePolicy called but CreatePolicyFunc is not set")
+	}
+	return m.CreatePolicyFunc(ctx, name, description, resource, action, effect, projectID)
+}
+
+// GetPolicy calls GetPolicyFunc
+func (m *PolicyManager) GetPolicy(ctx context.Context, id uuid.UUID) (*schemas.Policy, error) {
+	if m.GetPolicyFunc == nil {
+		panic("mocks: PolicyManager.GetPolicy called but GetPolicyFunc is not set")
+	}
+	return m.GetPolicyFunc(ctx, id)
+}
+
+// GetPolicyIncludingDeleted calls GetPolicyIncludingDeletedFunc
+func (m *PolicyManager) GetPolicyIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Policy, error) {
+	if m.GetPolicyIncludingDeletedFunc == nil {
+		panic("mocks: PolicyManager.GetPolicyIncludingDeleted called but GetPolicyIncludingDeletedFunc is not set")
+	}
+	return m.GetPolicyIncludingDeletedFunc(ctx, id)
+}
+
+// GetPolicyByName calls GetPolicyByNameFunc
+func (m *PolicyManager) GetPolicyByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Policy, error) {
+	if m.GetPolicyByNameFunc == nil {
+		panic("mocks: PolicyManager.GetPolicyByName called but GetPolicyByNameFunc is not set")
+	}
+	return m.GetPolicyByNameFunc(ctx, name, projectID)
+}
+
+// ListPolicies calls ListPoliciesFunc
+func (m *PolicyManager) ListPolicies(ctx context.Context, order sorting.Order) ([]schemas.Policy, error) {
+	if m.ListPoliciesFunc == nil {
+		panic("mocks: PolicyManager.ListPolicies called but ListPoliciesFunc is not set")
+	}
+	return m.ListPoliciesFunc(ctx, order)
+}
+
+// UpdatePolicy calls UpdatePolicyFunc
+func (m *PolicyManager) UpdatePolicy(ctx context.Context, id uuid.UUID, name, description, resource, action, effect string) (*schemas.Policy, error) {
+	if m.UpdatePolicyFunc == nil {
+		panic("mocks: PolicyManager.UpdatePolicy called but UpdatePolicyFunc is not set")
+	}
+	return m.UpdatePolicyFunc(ctx, id, name, description, resource, action, effect)
+}
+
+// PatchPolicy calls PatchPolicyFunc
+func (m *PolicyManager) PatchPolicy(ctx context.Context, id uuid.UUID, patch policies.PolicyPatch) (*schemas.Policy, error) {
+	if m.PatchPolicyFunc == nil {
+		panic("mocks: PolicyManager.PatchPolicy called but PatchPolicyFunc is not set")
+	}
+	return m.PatchPolicyFunc(ctx, id, patch)
+}
+
+// DeletePolicy calls DeletePolicyFunc
+func (m *PolicyManager) DeletePolicy(ctx context.Context, id uuid.UUID) error {
+	if m.DeletePolicyFunc == nil {
+		panic("mocks: PolicyManager.DeletePolicy called but DeletePolicyFunc is not set")
+	}
+	return m.DeletePolicyFunc(ctx, id)
+}
+
+// Authorize calls AuthorizeFunc
+func (m *PolicyManager) Authorize(ctx context.Context, projectID, userID uuid.UUID, resource, action string) (*policies.Decision, error) {
+	if m.AuthorizeFunc == nil {
+		panic("mocks: PolicyManager.Authorize called but AuthorizeFunc is not set")
+	}
+	return m.AuthorizeFunc(ctx, projectID, userID, resource, action)
+}
+
+// AffectedUsers calls AffectedUsersFunc
+func (m *PolicyManager) AffectedUsers(ctx context.Context, id uuid.UUID, sampleSize int) (*policies.AffectedUsers, error) {
+	if m.AffectedUsersFunc == nil {
+		panic("mocks: PolicyManager.AffectedUsers called but AffectedUsersFunc is not set")
+	}
+	return m.AffectedUsersFunc(ctx, id, sampleSize)
+}
+
+// Impact calls ImpactFunc
+func (m *PolicyManager) Impact(ctx context.Context, id uuid.UUID, proposed policies.Definition) (*policies.PolicyImpact, error) {
+	if m.ImpactFunc == nil {
+		panic("mocks: PolicyManager.Impact called but ImpactFunc is not set")
+	}
+	return m.ImpactFunc(ctx, id, proposed)
+}
+
+// RolePolicies calls RolePoliciesFunc
+func (m *PolicyManager) RolePolicies(ctx context.Context, projectID, roleID uuid.UUID) ([]schemas.Policy, error) {
+	if m.RolePoliciesFunc == nil {
+		panic("mocks: PolicyManager.RolePolicies called but RolePoliciesFunc is not set")
+	}
+	return m.RolePoliciesFunc(ctx, projectID, roleID)
+}
@@ -0,0 +1,182 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"golang.org/x/oauth2"
+)
+
+var _ projectusers.ProjectUserManager = (*ProjectUserManager)(nil)
+
+// ProjectUserManager is a projectusers.ProjectUserManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ProjectUserManager struct {
+	CreateProjectUserFunc              func(ctx context.Context, projectID string, email, password, firstName, lastName string, roleID uuid.UUID) (*models.DisplayUser, error)
+	GetProjectUserFunc                 func(ctx context.Context, projectID string, userID uuid.UUID, includeDeleted bool) (*models.DisplayUser, error)
+	GetProjectUserByEmailFunc          func(ctx context.Context, projectID string, email string, includeDeleted bool) (*models.DisplayUser, error)
+	ListProjectUsersFunc               func(ctx context.Context, projectID string, includeDeleted bool, status string, order sorting.Order) ([]models.DisplayUser, error)
+	ListDeletedProjectUsersFunc        func(ctx context.Context, projectID string, page, pageSize int) (*projectusers.DeletedUsersPage, error)
+	UpdateProjectUserFunc              func(ctx context.Context, projectID string, userID uuid.UUID, firstName, lastName string, active bool) (*models.DisplayUser, error)
+	DeleteProjectUserFunc              func(ctx context.Context, projectID string, userID uuid.UUID) error
+	RestoreProjectUserFunc             func(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error)
+	SetProjectUserStatusFunc           func(ctx context.Context, projectID string, userID uuid.UUID, status string) (*models.DisplayUser, error)
+	BulkSetProjectUsersActiveFunc      func(ctx context.Context, projectID string, userIDs []uuid.UUID, active bool) (int64, error)
+	CreateOrUpdateOAuthProjectUserFunc func(ctx context.Context, projectID string, userInfo *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error)
+	GetOAuthTokenFunc                  func(ctx context.Context, projectID string, userID uuid.UUID) (*oauth2.Token, error)
+	GenerateTokenFunc                  func(ctx context.Context, projectID string, userID uuid.UUID) (string, time.Time, error)
+	FindDuplicatesFunc                 func(ctx context.Context, projectID string) ([]projectusers.DuplicateCluster, error)
+	MergeProjectUsersFunc              func(ctx context.Context, projectID string, primaryID, duplicateID uuid.UUID) (*projectusers.MergeResult, error)
+	RefreshProjectUserTokensFunc       func(ctx context.Context, projectID string, roleID uuid.UUID, issue bool) (*projectusers.TokenRefresh, error)
+	LoginProjectUserFunc               func(ctx context.Context, projectID, email, password string) (*projectusers.PasswordLogin, error)
+	ChangeProjectUserPasswordFunc      func(ctx context.Context, projectID, email, currentPassword, newPassword string) error
+}
+
+// CreateProjectUser calls CreateProjectUserFunc
+func (m *ProjectUserManager) CreateProjectUser(ctx context.Context, projectID string, email, password, firstName, lastName string, roleID uuid.UUID) (*models.DisplayUser, error) {
+	if m.CreateProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.CreateProjectUser called but CreateProjectUserFunc is not set")
+	}
+	return m.CreateProjectUserFunc(ctx, projectID, email, password, firstName, lastName, roleID)
+}
+
+// GetProjectUser calls GetProjectUserFunc
+func (m *ProjectUserManager) GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID, includeDeleted bool) (*models.DisplayUser, error) {
+	if m.GetProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.GetProjectUser called but GetProjectUserFunc is not set")
+	}
+	return m.GetProjectUserFunc(ctx, projectID, userID, includeDeleted)
+}
+
+// GetProjectUserByEmail calls GetProjectUserByEmailFunc
+func (m *ProjectUserManager) GetProjectUserByEmail(ctx context.Context, projectID string, email string, includeDeleted bool) (*models.DisplayUser, error) {
+	if m.GetProjectUserByEmailFunc == nil {
+		panic("mocks: ProjectUserManager.GetProjectUserByEmail called but GetProjectUserByEmailFunc is not set")
+	}
+	return m.GetProjectUserByEmailFunc(ctx, projectID, email, includeDeleted)
+}
+
+// ListProjectUsers calls ListProjectUsersFunc
+func (m *ProjectUserManager) ListProjectUsers(ctx context.Context, projectID string, includeDeleted bool, status string, order sorting.Order) ([]models.DisplayUser, error) {
+	if m.ListProjectUsersFunc == nil {
+		panic("mocks: ProjectUserManager.ListProjectUsers called but ListProjectUsersFunc is not set")
+	}
+	return m.ListProjectUsersFunc(ctx, projectID, includeDeleted, status, order)
+}
+
+// ListDeletedProjectUsers calls ListDeletedProjectUsersFunc
+func (m *ProjectUserManager) ListDeletedProjectUsers(ctx context.Context, projectID string, page, pageSize int) (*projectusers.DeletedUsersPage, error) {
+	if m.ListDeletedProjectUsersFunc == nil {
+		panic("mocks: ProjectUserManager.ListDeletedProjectUsers called but ListDeletedProjectUsersFunc is not set")
+	}
+	return m.ListDeletedProjectUsersFunc(ctx, projectID, page, pageSize)
+}
+
+// UpdateProjectUser calls UpdateProjectUserFunc
+func (m *ProjectUserManager) UpdateProjectUser(ctx context.Context, projectID string, userID uuid.UUID, firstName, lastName string, active bool) (*models.DisplayUser, error) {
+	if m.UpdateProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.UpdateProjectUser called but UpdateProjectUserFunc is not set")
+	}
+	return m.UpdateProjectUserFunc(ctx, projectID, userID, firstName, lastName, active)
+}
+
+// DeleteProjectUser calls DeleteProjectUserFunc
+func (m *ProjectUserManager) DeleteProjectUser(ctx context.Context, projectID string, userID uuid.UUID) error {
+	if m.DeleteProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.DeleteProjectUser called but DeleteProjectUserFunc is not set")
+	}
+	return m.DeleteProjectUserFunc(ctx, projectID, userID)
+}
+
+// RestoreProjectUser calls RestoreProjectUserFunc
+func (m *ProjectUserManager) RestoreProjectUser(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error) {
+	if m.RestoreProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.RestoreProjectUser called but RestoreProjectUserFunc is not set")
+	}
+	return m.RestoreProjectUserFunc(ctx, projectID, userID)
+}
+
+// SetProjectUserStatus calls SetProjectUserStatusFunc
+func (m *ProjectUserManager) SetProjectUserStatus(ctx context.Context, projectID string, userID uuid.UUID, status string) (*models.DisplayUser, error) {
+	if m.SetProjectUserStatusFunc == nil {
+		panic("mocks: ProjectUserManager.SetProjectUserStatus called but SetProjectUserStatusFunc is not set")
+	}
+	return m.SetProjectUserStatusFunc(ctx, projectID, userID, status)
+}
+
+// BulkSetProjectUsersActive calls BulkSetProjectUsersActiveFunc
+func (m *ProjectUserManager) BulkSetProjectUsersActive(ctx context.Context, projectID string, userIDs []uuid.UUID, active bool) (int64, error) {
+	if m.BulkSetProjectUsersActiveFunc == nil {
+		panic("mocks: ProjectUserManager.BulkSetProjectUsersActive called but BulkSetProjectUsersActiveFunc is not set")
+	}
+	return m.BulkSetProjectUsersActiveFunc(ctx, projectID, userIDs, active)
+}
+
+// CreateOrUpdateOAuthProjectUser calls CreateOrUpdateOAuthProjectUserFunc
+func (m *ProjectUserManager) CreateOrUpdateOAuthProjectUser(ctx context.Context, projectID string, userInfo *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error) {
+	if m.CreateOrUpdateOAuthProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.CreateOrUpdateOAuthProjectUser called but CreateOrUpdateOAuthProjectUserFunc is not set")
+	}
+	return m.CreateOrUpdateOAuthProjectUserFunc(ctx, projectID, userInfo, roleID)
+}
+
+// GetOAuthToken calls GetOAuthTokenFunc
+func (m *ProjectUserManager) GetOAuthToken(ctx context.Context, projectID string, userID uuid.UUID) (*oauth2.Token, error) {
+	if m.GetOAuthTokenFunc == nil {
+		panic("mocks: ProjectUserManager.GetOAuthToken called but GetOAuthTokenFunc is not set")
+	}
+	return m.GetOAuthTokenFunc(ctx, projectID, userID)
+}
+
+// GenerateToken calls GenerateTokenFunc
+func (m *ProjectUserManager) GenerateToken(ctx context.Context, projectID string, userID uuid.UUID) (string, time.Time, error) {
+	if m.GenerateTokenFunc == nil {
+		panic("mocks: ProjectUserManager.GenerateToken called but GenerateTokenFunc is not set")
+	}
+	return m.GenerateTokenFunc(ctx, projectID, userID)
+}
+
+// FindDuplicates calls FindDuplicatesFunc
+func (m *ProjectUserManager) FindDuplicates(ctx context.Context, projectID string) ([]projectusers.DuplicateCluster, error) {
+	if m.FindDuplicatesFunc == nil {
+		panic("mocks: ProjectUserManager.FindDuplicates called but FindDuplicatesFunc is not set")
+	}
+	return m.FindDuplicatesFunc(ctx, projectID)
+}
+
+// MergeProjectUsers calls MergeProjectUsersFunc
+func (m *ProjectUserManager) MergeProjectUsers(ctx context.Context, projectID string, primaryID, duplicateID uuid.UUID) (*projectusers.MergeResult, error) {
+	if m.MergeProjectUsersFunc == nil {
+		panic("mocks: ProjectUserManager.MergeProjectUsers called but MergeProjectUsersFunc is not set")
+	}
+	return m.MergeProjectUsersFunc(ctx, projectID, primaryID, duplicateID)
+}
+
+// RefreshProjectUserTokens calls RefreshProjectUserTokensFunc
+func (m *ProjectUserManager) RefreshProjectUserTokens(ctx context.Context, projectID string, roleID uuid.UUID, issue bool) (*projectusers.TokenRefresh, error) {
+	if m.RefreshProjectUserTokensFunc == nil {
+		panic("mocks: ProjectUserManager.RefreshProjectUserTokens called but RefreshProjectUserTokensFunc is not set")
+	}
+	return m.RefreshProjectUserTokensFunc(ctx, projectID, roleID, issue)
+}
+
+// LoginProjectUser calls LoginProjectUserFunc
+func (m *ProjectUserManager) LoginProjectUser(ctx context.Context, projectID, email, password string) (*projectusers.PasswordLogin, error) {
+	if m.LoginProjectUserFunc == nil {
+		panic("mocks: ProjectUserManager.LoginProjectUser called but LoginProjectUserFunc is not set")
+	}
+	return m.LoginProjectUserFunc(ctx, projectID, email, password)
+}
+
+// ChangeProjectUserPassword calls ChangeProjectUserPasswordFunc
+func (m *ProjectUserManager) ChangeProjectUserPassword(ctx context.Context, projectID, email, currentPassword, newPassword string) error {
+	if m.ChangeProjectUserPasswordFunc == nil {
+		panic("mocks: ProjectUserManager.ChangeProjectUserPassword called but ChangeProjectUserPasswordFunc is not set")
+	}
+	return m.ChangeProjectUserPasswordFunc(ctx, projectID, email, currentPassword, newPassword)
+}
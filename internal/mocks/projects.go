@@ -0,0 +1,162 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+var _ projects.ProjectManager = (*ProjectManager)(nil)
+
+// ProjectManager is a projects.ProjectManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ProjectManager struct {
+	CreateProjectFunc              func(ctx context.Context, name, description, uniqueID, region string) (*schemas.Project, error)
+	GetProjectFunc                 func(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
+	GetProjectIncludingDeletedFunc func(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
+	ResolveProjectFunc             func(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
+	ListProjectsFunc               func(ctx context.Context, order sorting.Order) ([]schemas.Project, error)
+	UpdateProjectFunc              func(ctx context.Context, id uuid.UUID, name, description string) (*schemas.Project, error)
+	DeleteProjectFunc              func(ctx context.Context, id uuid.UUID, export io.Writer) (*projects.DeletePreview, error)
+	PreviewDeleteFunc              func(ctx context.Context, id uuid.UUID) (*projects.DeletePreview, error)
+	GetProjectStatsFunc            func(ctx context.Context, id uuid.UUID) (*projects.ProjectStats, error)
+	UpdateProjectSettingsFunc      func(ctx context.Context, id uuid.UUID, settings schemas.ProjectSettings) (*schemas.Project, error)
+	CloneProjectFunc               func(ctx context.Context, id uuid.UUID, name, uniqueID string) (*projects.CloneResult, error)
+	BootstrapProjectFunc           func(ctx context.Context, b projects.Bootstrap) (*projects.BootstrapResult, error)
+	ValidateUniqueIDFunc           func(ctx context.Context, uniqueID string) (*projects.UniqueIDCheck, error)
+	SetOAuthProviderFunc           func(ctx context.Context, id uuid.UUID, provider string, config schemas.OAuthProviderSettings) (*schemas.Project, error)
+	DeleteOAuthProviderFunc        func(ctx context.Context, id uuid.UUID, provider string) (*schemas.Project, error)
+	TemplateFunc                   func() projects.Template
+}
+
+// CreateProject calls CreateProjectFunc
+func (m *ProjectManager) CreateProject(ctx context.Context, name, description, uniqueID, region string) (*schemas.Project, error) {
+	if m.CreateProjectFunc == nil {
+		panic("mocks: ProjectManager.CreateProject called but CreateProjectFunc is not set")
+	}
+	return m.CreateProjectFunc(ctx, name, description, uniqueID, region)
+}
+
+// GetProject calls GetProjectFunc
+func (m *ProjectManager) GetProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	if m.GetProjectFunc == nil {
+		panic("mocks: ProjectManager.GetProject called but GetProjectFunc is not set")
+	}
+	return m.GetProjectFunc(ctx, id)
+}
+
+// ResolveProject calls ResolveProjectFunc
+func (m *ProjectManager) ResolveProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	if m.ResolveProjectFunc == nil {
+		panic("mocks: ProjectManager.ResolveProject called but ResolveProjectFunc is not set")
+	}
+	return m.ResolveProjectFunc(ctx, id)
+}
+
+// GetProjectIncludingDeleted calls GetProjectIncludingDeletedFunc
+func (m *ProjectManager) GetProjectIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	if m.GetProjectIncludingDeletedFunc == nil {
+		panic("mocks: ProjectManager.GetProjectIncludingDeleted called but GetProjectIncludingDeletedFunc is not set")
+	}
+	return m.GetProjectIncludingDeletedFunc(ctx, id)
+}
+
+// ListProjects calls ListProjectsFunc
+func (m *ProjectManager) ListProjects(ctx context.Context, order sorting.Order) ([]schemas.Project, error) {
+	if m.ListProjectsFunc == nil {
+		panic("mocks: ProjectManager.ListProjects called but ListProjectsFunc is not set")
+	}
+	return m.ListProjectsFunc(ctx, order)
+}
+
+// UpdateProject calls UpdateProjectFunc
+func (m *ProjectManager) UpdateProject(ctx context.Context, id uuid.UUID, name, description string) (*schemas.Project, error) {
+	if m.UpdateProjectFunc == nil {
+		panic("mocks: ProjectManager.UpdateProject called but UpdateProjectFunc is not set")
+	}
+	return m.UpdateProjectFunc(ctx, id, name, description)
+}
+
+// DeleteProject calls DeleteProjectFunc
+func (m *ProjectManager) DeleteProject(ctx context.Context, id uuid.UUID, export io.Writer) (*projects.DeletePreview, error) {
+	if m.DeleteProjectFunc == nil {
+		panic("mocks: ProjectManager.DeleteProject called but DeleteProjectFunc is not set")
+	}
+	return m.DeleteProjectFunc(ctx, id, export)
+}
+
+// PreviewDelete calls PreviewDeleteFunc
+func (m *ProjectManager) PreviewDelete(ctx context.Context, id uuid.UUID) (*projects.DeletePreview, error) {
+	if m.PreviewDeleteFunc == nil {
+		panic("mocks: ProjectManager.PreviewDelete called but PreviewDeleteFunc is not set")
+	}
+	return m.PreviewDeleteFunc(ctx, id)
+}
+
+// GetProjectStats calls GetProjectStatsFunc
+func (m *ProjectManager) GetProjectStats(ctx context.Context, id uuid.UUID) (*projects.ProjectStats, error) {
+	if m.GetProjectStatsFunc == nil {
+		panic("mocks: ProjectManager.GetProjectStats called but GetProjectStatsFunc is not set")
+	}
+	return m.GetProjectStatsFunc(ctx, id)
+}
+
+// UpdateProjectSettings calls UpdateProjectSettingsFunc
+func (m *ProjectManager) UpdateProjectSettings(ctx context.Context, id uuid.UUID, settings schemas.ProjectSettings) (*schemas.Project, error) {
+	if m.UpdateProjectSettingsFunc == nil {
+		panic("mocks: ProjectManager.UpdateProjectSettings called but UpdateProjectSettingsFunc is not set")
+	}
+	return m.UpdateProjectSettingsFunc(ctx, id, settings)
+}
+
+// CloneProject calls CloneProjectFunc
+func (m *ProjectManager) CloneProject(ctx context.Context, id uuid.UUID, name, uniqueID string) (*projects.CloneResult, error) {
+	if m.CloneProjectFunc == nil {
+		panic("mocks: ProjectManager.CloneProject called but CloneProjectFunc is not set")
+	}
+	return m.CloneProjectFunc(ctx, id, name, uniqueID)
+}
+
+// BootstrapProject calls BootstrapProjectFunc
+func (m *ProjectManager) BootstrapProject(ctx context.Context, b projects.Bootstrap) (*projects.BootstrapResult, error) {
+	if m.BootstrapProjectFunc == nil {
+		panic("mocks: ProjectManager.BootstrapProject called but BootstrapProjectFunc is not set")
+	}
+	return m.BootstrapProjectFunc(ctx, b)
+}
+
+// ValidateUniqueID calls ValidateUniqueIDFunc
+func (m *ProjectManager) ValidateUniqueID(ctx context.Context, uniqueID string) (*projects.UniqueIDCheck, error) {
+	if m.ValidateUniqueIDFunc == nil {
+		panic("mocks: ProjectManager.ValidateUniqueID called but ValidateUniqueIDFunc is not set")
+	}
+	return m.ValidateUniqueIDFunc(ctx, uniqueID)
+}
+
+// SetOAuthProvider calls SetOAuthProviderFunc
+func (m *ProjectManager) SetOAuthProvider(ctx context.Context, id uuid.UUID, provider string, config schemas.OAuthProviderSettings) (*schemas.Project, error) {
+	if m.SetOAuthProviderFunc == nil {
+		panic("mocks: ProjectManager.SetOAuthProvider called but SetOAuthProviderFunc is not set")
+	}
+	return m.SetOAuthProviderFunc(ctx, id, provider, config)
+}
+
+// DeleteOAuthProvider calls DeleteOAuthProviderFunc
+func (m *ProjectManager) DeleteOAuthProvider(ctx context.Context, id uuid.UUID, provider string) (*schemas.Project, error) {
+	if m.DeleteOAuthProviderFunc == nil {
+		panic("mocks: ProjectManager.DeleteOAuthProvider called but DeleteOAuthProviderFunc is not set")
+	}
+	return m.DeleteOAuthProviderFunc(ctx, id, provider)
+}
+
+// Template calls TemplateFunc
+func (m *ProjectManager) Template() projects.Template {
+	if m.TemplateFunc == nil {
+		panic("mocks: ProjectManager.Template called but TemplateFunc is not set")
+	}
+	return m.TemplateFunc()
+}
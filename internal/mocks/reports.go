@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/reports"
+)
+
+var _ reports.ReportManager = (*ReportManager)(nil)
+
+// ReportManager is a reports.ReportManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type ReportManager struct {
+	SendNowFunc func(ctx context.Context, projectID uuid.UUID) (*reports.Run, error)
+	StartFunc   func(ctx context.Context)
+}
+
+// SendNow calls SendNowFunc
+func (m *ReportManager) SendNow(ctx context.Context, projectID uuid.UUID) (*reports.Run, error) {
+	if m.SendNowFunc == nil {
+		panic("mocks: ReportManager.SendNow called but SendNowFunc is not set")
+	}
+	return m.SendNowFunc(ctx, projectID)
+}
+
+// Start calls StartFunc
+func (m *ReportManager) Start(ctx context.Context) {
+	if m.StartFunc == nil {
+		panic("mocks: ReportManager.Start called but StartFunc is not set")
+	}
+	m.StartFunc(ctx)
+}
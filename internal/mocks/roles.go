@@ -0,0 +1,126 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+var _ roles.RoleManager = (*RoleManager)(nil)
+
+// RoleManager is a roles.RoleManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type RoleManager struct {
+	CreateRoleFunc              func(ctx context.Context, name, description string, expTime time.Duration, allowedCIDRs []string, projectID *uuid.UUID) (*schemas.Role, error)
+	GetRoleFunc                 func(ctx context.Context, id uuid.UUID) (*schemas.Role, error)
+	GetRoleIncludingDeletedFunc func(ctx context.Context, id uuid.UUID) (*schemas.Role, error)
+	GetRoleByNameFunc           func(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Role, error)
+	ListRolesFunc               func(ctx context.Context, order sorting.Order) ([]schemas.Role, error)
+	UpdateRoleFunc              func(ctx context.Context, id uuid.UUID, name, description string, expTime time.Duration, allowedCIDRs []string) (*schemas.Role, error)
+	RenameRoleFunc              func(ctx context.Context, id uuid.UUID, name string) (*schemas.Role, error)
+	DeleteRoleFunc              func(ctx context.Context, id uuid.UUID) error
+	AssignPolicyToRoleFunc      func(ctx context.Context, roleID, policyID uuid.UUID) error
+	RemovePolicyFromRoleFunc    func(ctx context.Context, roleID, policyID uuid.UUID) error
+	GetExpirationTimeFunc       func(ctx context.Context, id uuid.UUID) (time.Duration, error)
+	UsageFunc                   func(ctx context.Context, projectID uuid.UUID) ([]roles.RoleUsage, error)
+}
+
+// CreateRole calls CreateRoleFunc
+func (m *RoleManager) CreateRole(ctx context.Context, name, description string, expTime time.Duration, allowedCIDRs []string, projectID *uuid.UUID) (*schemas.Role, error) {
+	if m.CreateRoleFunc == nil {
+		panic("mocks: RoleManager.CreateRole called but CreateRoleFunc is not set")
+	}
+	return m.CreateRoleFunc(ctx, name, description, expTime, allowedCIDRs, projectID)
+}
+
+// GetRole calls GetRoleFunc
+func (m *RoleManager) GetRole(ctx context.Context, id uuid.UUID) (*schemas.Role, error) {
+	if m.GetRoleFunc == nil {
+		panic("mocks: RoleManager.GetRole called but GetRoleFunc is not set")
+	}
+	return m.GetRoleFunc(ctx, id)
+}
+
+// GetRoleIncludingDeleted calls GetRoleIncludingDeletedFunc
+func (m *RoleManager) GetRoleIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Role, error) {
+	if m.GetRoleIncludingDeletedFunc == nil {
+		panic("mocks: RoleManager.GetRoleIncludingDeleted called but GetRoleIncludingDeletedFunc is not set")
+	}
+	return m.GetRoleIncludingDeletedFunc(ctx, id)
+}
+
+// GetRoleByName calls GetRoleByNameFunc
+func (m *RoleManager) GetRoleByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Role, error) {
+	if m.GetRoleByNameFunc == nil {
+		panic("mocks: RoleManager.GetRoleByName called but GetRoleByNameFunc is not set")
+	}
+	return m.GetRoleByNameFunc(ctx, name, projectID)
+}
+
+// ListRoles calls ListRolesFunc
+func (m *RoleManager) ListRoles(ctx context.Context, order sorting.Order) ([]schemas.Role, error) {
+	if m.ListRolesFunc == nil {
+		panic("mocks: RoleManager.ListRoles called but ListRolesFunc is not set")
+	}
+	return m.ListRolesFunc(ctx, order)
+}
+
+// UpdateRole calls UpdateRoleFunc
+func (m *RoleManager) UpdateRole(ctx context.Context, id uuid.UUID, name, description string, expTime time.Duration, allowedCIDRs []string) (*schemas.Role, error) {
+	if m.UpdateRoleFunc == nil {
+		panic("mocks: RoleManager.UpdateRole called but UpdateRoleFunc is not set")
+	}
+	return m.UpdateRoleFunc(ctx, id, name, description, expTime, allowedCIDRs)
+}
+
+// RenameRole calls RenameRoleFunc
+func (m *RoleManager) RenameRole(ctx context.Context, id uuid.UUID, name string) (*schemas.Role, error) {
+	if m.RenameRoleFunc == nil {
+		panic("mocks: RoleManager.RenameRole called but RenameRoleFunc is not set")
+	}
+	return m.RenameRoleFunc(ctx, id, name)
+}
+
+// DeleteRole calls DeleteRoleFunc
+func (m *RoleManager) DeleteRole(ctx context.Context, id uuid.UUID) error {
+	if m.DeleteRoleFunc == nil {
+		panic("mocks: RoleManager.DeleteRole called but DeleteRoleFunc is not set")
+	}
+	return m.DeleteRoleFunc(ctx, id)
+}
+
+// AssignPolicyToRole calls AssignPolicyToRoleFunc
+func (m *RoleManager) AssignPolicyToRole(ctx context.Context, roleID, policyID uuid.UUID) error {
+	if m.AssignPolicyToRoleFunc == nil {
+		panic("mocks: RoleManager.AssignPolicyToRole called but AssignPolicyToRoleFunc is not set")
+	}
+	return m.AssignPolicyToRoleFunc(ctx, roleID, policyID)
+}
+
+// RemovePolicyFromRole calls RemovePolicyFromRoleFunc
+func (m *RoleManager) RemovePolicyFromRole(ctx context.Context, roleID, policyID uuid.UUID) error {
+	if m.RemovePolicyFromRoleFunc == nil {
+		panic("mocks: RoleManager.RemovePolicyFromRole called but RemovePolicyFromRoleFunc is not set")
+	}
+	return m.RemovePolicyFromRoleFunc(ctx, roleID, policyID)
+}
+
+// GetExpirationTime calls GetExpirationTimeFunc
+func (m *RoleManager) GetExpirationTime(ctx context.Context, id uuid.UUID) (time.Duration, error) {
+	if m.GetExpirationTimeFunc == nil {
+		panic("mocks: RoleManager.GetExpirationTime called but GetExpirationTimeFunc is not set")
+	}
+	return m.GetExpirationTimeFunc(ctx, id)
+}
+
+// Usage calls UsageFunc
+func (m *RoleManager) Usage(ctx context.Context, projectID uuid.UUID) ([]roles.RoleUsage, error) {
+	if m.UsageFunc == nil {
+		panic("mocks: RoleManager.Usage called but UsageFunc is not set")
+	}
+	return m.UsageFunc(ctx, projectID)
+}
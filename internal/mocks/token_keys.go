@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/yash3004/user_management_service/tokenkeys"
+)
+
+var _ tokenkeys.TokenKeyManager = (*TokenKeyManager)(nil)
+
+// TokenKeyManager is a tokenkeys.TokenKeyManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type TokenKeyManager struct {
+	RotateFunc func(ctx context.Context) (*tokenkeys.RotationResult, error)
+}
+
+// Rotate calls RotateFunc
+func (m *TokenKeyManager) Rotate(ctx context.Context) (*tokenkeys.RotationResult, error) {
+	if m.RotateFunc == nil {
+		panic("mocks: TokenKeyManager.Rotate called but RotateFunc is not set")
+	}
+	return m.RotateFunc(ctx)
+}
@@ -0,0 +1,198 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/users"
+)
+
+var _ users.UserManager = (*UserManager)(nil)
+
+// UserManager is a users.UserManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type UserManager struct {
+	CreateUserFunc              func(ctx context.Context, email, password, firstName, lastName string, roleID, projectID uuid.UUID) (*schemas.User, error)
+	GetUserFunc                 func(ctx context.Context, id uuid.UUID) (*schemas.User, error)
+	GetUserIncludingDeletedFunc func(ctx context.Context, id uuid.UUID) (*schemas.User, error)
+	GetUserByEmailFunc          func(ctx context.Context, email string) (*schemas.User, error)
+	ListUsersFunc               func(ctx context.Context, status, userType string) ([]schemas.User, error)
+	UpdateUserFunc              func(ctx context.Context, id uuid.UUID, firstName, lastName string, active bool) (*schemas.User, error)
+	DeleteUserFunc              func(ctx context.Context, id uuid.UUID) error
+	ChangePasswordFunc          func(ctx context.Context, id uuid.UUID, currentPassword, newPassword string) error
+	SetUserStatusFunc           func(ctx context.Context, id uuid.UUID, status string) (*schemas.User, error)
+	AssignRoleFunc              func(ctx context.Context, userID, roleID uuid.UUID) error
+	CreateOrUpdateOAuthUserFunc func(ctx context.Context, userInfo *oauth.UserInfo, projectID uuid.UUID, roleID uuid.UUID) (*models.DisplayUser, error)
+	AddUserToProjectFunc        func(ctx context.Context, userID, projectID, roleID uuid.UUID) (*schemas.UserProjectMembership, error)
+	RemoveUserFromProjectFunc   func(ctx context.Context, userID, projectID uuid.UUID) error
+	ListUserProjectsFunc        func(ctx context.Context, userID uuid.UUID) ([]schemas.UserProjectMembership, error)
+	GetProjectRoleFunc          func(ctx context.Context, userID, projectID uuid.UUID) (uuid.UUID, error)
+	ValidateNewUserFunc         func(ctx context.Context, email, password string, roleID, projectID uuid.UUID) ([]users.FieldError, error)
+	LookupEmailFunc             func(ctx context.Context, email string) ([]users.EmailMatch, error)
+	CreateServiceAccountFunc    func(ctx context.Context, projectID uuid.UUID, name string, roleID uuid.UUID) (*schemas.User, error)
+	GetServiceAccountFunc       func(ctx context.Context, projectID, id uuid.UUID) (*schemas.User, error)
+	ListServiceAccountsFunc     func(ctx context.Context, projectID uuid.UUID) ([]schemas.User, error)
+}
+
+// CreateUser calls CreateUserFunc
+func (m *UserManager) CreateUser(ctx context.Context, email, password, firstName, lastName string, roleID, projectID uuid.UUID) (*schemas.User, error) {
+	if m.CreateUserFunc == nil {
+		panic("mocks: UserManager.CreateUser called but CreateUserFunc is not set")
+	}
+	return m.CreateUserFunc(ctx, email, password, firstName, lastName, roleID, projectID)
+}
+
+// GetUser calls GetUserFunc
+func (m *UserManager) GetUser(ctx context.Context, id uuid.UUID) (*schemas.User, error) {
+	if m.GetUserFunc == nil {
+		panic("mocks: UserManager.GetUser called but GetUserFunc is not set")
+	}
+	return m.GetUserFunc(ctx, id)
+}
+
+// GetUserIncludingDeleted calls GetUserIncludingDeletedFunc
+func (m *UserManager) GetUserIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.User, error) {
+	if m.GetUserIncludingDeletedFunc == nil {
+		panic("mocks: UserManager.GetUserIncludingDeleted called but GetUserIncludingDeletedFunc is not set")
+	}
+	return m.GetUserIncludingDeletedFunc(ctx, id)
+}
+
+// GetUserByEmail calls GetUserByEmailFunc
+func (m *UserManager) GetUserByEmail(ctx context.Context, email string) (*schemas.User, error) {
+	if m.GetUserByEmailFunc == nil {
+		panic("mocks: UserManager.GetUserByEmail called but GetUserByEmailFunc is not set")
+	}
+	return m.GetUserByEmailFunc(ctx, email)
+}
+
+// ListUsers calls ListUsersFunc
+func (m *UserManager) ListUsers(ctx context.Context, status, userType string) ([]schemas.User, error) {
+	if m.ListUsersFunc == nil {
+		panic("mocks: UserManager.ListUsers called but ListUsersFunc is not set")
+	}
+	return m.ListUsersFunc(ctx, status, userType)
+}
+
+// UpdateUser calls UpdateUserFunc
+func (m *UserManager) UpdateUser(ctx context.Context, id uuid.UUID, firstName, lastName string, active bool) (*schemas.User, error) {
+	if m.UpdateUserFunc == nil {
+		panic("mocks: UserManager.UpdateUser called but UpdateUserFunc is not set")
+	}
+	return m.UpdateUserFunc(ctx, id, firstName, lastName, active)
+}
+
+// DeleteUser calls DeleteUserFunc
+func (m *UserManager) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if m.DeleteUserFunc == nil {
+		panic("mocks: UserManager.DeleteUser called but DeleteUserFunc is not set")
+	}
+	return m.DeleteUserFunc(ctx, id)
+}
+
+// ChangePassword calls ChangePasswordFunc
+func (m *UserManager) ChangePassword(ctx context.Context, id uuid.UUID, currentPassword, newPassword string) error {
+	if m.ChangePasswordFunc == nil {
+		panic("mocks: UserManager.ChangePassword called but ChangePasswordFunc is not set")
+	}
+	return m.ChangePasswordFunc(ctx, id, currentPassword, newPassword)
+}
+
+// SetUserStatus calls SetUserStatusFunc
+func (m *UserManager) SetUserStatus(ctx context.Context, id uuid.UUID, status string) (*schemas.User, error) {
+	if m.SetUserStatusFunc == nil {
+		panic("mocks: UserManager.SetUserStatus called but SetUserStatusFunc is not set")
+	}
+	return m.SetUserStatusFunc(ctx, id, status)
+}
+
+// AssignRole calls AssignRoleFunc
+func (m *UserManager) AssignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	if m.AssignRoleFunc == nil {
+		panic("mocks: UserManager.AssignRole called but AssignRoleFunc is not set")
+	}
+	return m.AssignRoleFunc(ctx, userID, roleID)
+}
+
+// CreateOrUpdateOAuthUser calls CreateOrUpdateOAuthUserFunc
+func (m *UserManager) CreateOrUpdateOAuthUser(ctx context.Context, userInfo *oauth.UserInfo, projectID uuid.UUID, roleID uuid.UUID) (*models.DisplayUser, error) {
+	if m.CreateOrUpdateOAuthUserFunc == nil {
+		panic("mocks: UserManager.CreateOrUpdateOAuthUser called but CreateOrUpdateOAuthUserFunc is not set")
+	}
+	return m.CreateOrUpdateOAuthUserFunc(ctx, userInfo, projectID, roleID)
+}
+
+// AddUserToProject calls AddUserToProjectFunc
+func (m *UserManager) AddUserToProject(ctx context.Context, userID, projectID, roleID uuid.UUID) (*schemas.UserProjectMembership, error) {
+	if m.AddUserToProjectFunc == nil {
+		panic("mocks: UserManager.AddUserToProject called but AddUserToProjectFunc is not set")
+	}
+	return m.AddUserToProjectFunc(ctx, userID, projectID, roleID)
+}
+
+// RemoveUserFromProject calls RemoveUserFromProjectFunc
+func (m *UserManager) RemoveUserFromProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	if m.RemoveUserFromProjectFunc == nil {
+		panic("mocks: UserManager.RemoveUserFromProject called but RemoveUserFromProjectFunc is not set")
+	}
+	return m.RemoveUserFromProjectFunc(ctx, userID, projectID)
+}
+
+// ListUserProjects calls ListUserProjectsFunc
+func (m *UserManager) ListUserProjects(ctx context.Context, userID uuid.UUID) ([]schemas.UserProjectMembership, error) {
+	if m.ListUserProjectsFunc == nil {
+		panic("mocks: UserManager.ListUserProjects called but ListUserProjectsFunc is not set")
+	}
+	return m.ListUserProjectsFunc(ctx, userID)
+}
+
+// GetProjectRole calls GetProjectRoleFunc
+func (m *UserManager) GetProjectRole(ctx context.Context, userID, projectID uuid.UUID) (uuid.UUID, error) {
+	if m.GetProjectRoleFunc == nil {
+		panic("mocks: UserManager.GetProjectRole called but GetProjectRoleFunc is not set")
+	}
+	return m.GetProjectRoleFunc(ctx, userID, projectID)
+}
+
+// ValidateNewUser calls ValidateNewUserFunc
+func (m *UserManager) ValidateNewUser(ctx context.Context, email, password string, roleID, projectID uuid.UUID) ([]users.FieldError, error) {
+	if m.ValidateNewUserFunc == nil {
+		panic("mocks: UserManager.ValidateNewUser called but ValidateNewUserFunc is not set")
+	}
+	return m.ValidateNewUserFunc(ctx, email, password, roleID, projectID)
+}
+
+// LookupEmail calls LookupEmailFunc
+func (m *UserManager) LookupEmail(ctx context.Context, email string) ([]users.EmailMatch, error) {
+	if m.LookupEmailFunc == nil {
+		panic("mocks: UserManager.LookupEmail called but LookupEmailFunc is not set")
+	}
+	return m.LookupEmailFunc(ctx, email)
+}
+
+// CreateServiceAccount calls CreateServiceAccountFunc
+func (m *UserManager) CreateServiceAccount(ctx context.Context, projectID uuid.UUID, name string, roleID uuid.UUID) (*schemas.User, error) {
+	if m.CreateServiceAccountFunc == nil {
+		panic("mocks: UserManager.CreateServiceAccount called but CreateServiceAccountFunc is not set")
+	}
+	return m.CreateServiceAccountFunc(ctx, projectID, name, roleID)
+}
+
+// GetServiceAccount calls GetServiceAccountFunc
+func (m *UserManager) GetServiceAccount(ctx context.Context, projectID, id uuid.UUID) (*schemas.User, error) {
+	if m.GetServiceAccountFunc == nil {
+		panic("mocks: UserManager.GetServiceAccount called but GetServiceAccountFunc is not set")
+	}
+	return m.GetServiceAccountFunc(ctx, projectID, id)
+}
+
+// ListServiceAccounts calls ListServiceAccountsFunc
+func (m *UserManager) ListServiceAccounts(ctx context.Context, projectID uuid.UUID) ([]schemas.User, error) {
+	if m.ListServiceAccountsFunc == nil {
+		panic("mocks: UserManager.ListServiceAccounts called but ListServiceAccountsFunc is not set")
+	}
+	return m.ListServiceAccountsFunc(ctx, projectID)
+}
@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webauthn"
+)
+
+var _ webauthn.WebAuthnManager = (*WebAuthnManager)(nil)
+
+// WebAuthnManager is a webauthn.WebAuthnManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type WebAuthnManager struct {
+	BeginRegistrationFunc  func(ctx context.Context, projectID, userID uuid.UUID) (*webauthn.CreationOptions, error)
+	FinishRegistrationFunc func(ctx context.Context, projectID, userID uuid.UUID, name string, response webauthn.AttestationResponse) (*schemas.WebAuthnCredential, error)
+	BeginLoginFunc         func(ctx context.Context, projectID uuid.UUID) (*webauthn.RequestOptions, error)
+	FinishLoginFunc        func(ctx context.Context, projectID uuid.UUID, response webauthn.AssertionResponse) (*webauthn.Session, error)
+	ListCredentialsFunc    func(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.WebAuthnCredential, error)
+	RenameCredentialFunc   func(ctx context.Context, projectID, userID, id uuid.UUID, name string) (*schemas.WebAuthnCredential, error)
+	DeleteCredentialFunc   func(ctx context.Context, projectID, userID, id uuid.UUID) error
+}
+
+// BeginRegistration calls BeginRegistrationFunc
+func (m *WebAuthnManager) BeginRegistration(ctx context.Context, projectID, userID uuid.UUID) (*webauthn.CreationOptions, error) {
+	if m.BeginRegistrationFunc == nil {
+		panic("mocks: WebAuthnManager.BeginRegistration called but BeginRegistrationFunc is not set")
+	}
+	return m.BeginRegistrationFunc(ctx, projectID, userID)
+}
+
+// FinishRegistration calls FinishRegistrationFunc
+func (m *WebAuthnManager) FinishRegistration(ctx context.Context, projectID, userID uuid.UUID, name string, response webauthn.AttestationResponse) (*schemas.WebAuthnCredential, error) {
+	if m.FinishRegistrationFunc == nil {
+		panic("mocks: WebAuthnManager.FinishRegistration called but FinishRegistrationFunc is not set")
+	}
+	return m.FinishRegistrationFunc(ctx, projectID, userID, name, response)
+}
+
+// BeginLogin calls BeginLoginFunc
+func (m *WebAuthnManager) BeginLogin(ctx context.Context, projectID uuid.UUID) (*webauthn.RequestOptions, error) {
+	if m.BeginLoginFunc == nil {
+		panic("mocks: WebAuthnManager.BeginLogin called but BeginLoginFunc is not set")
+	}
+	return m.BeginLoginFunc(ctx, projectID)
+}
+
+// FinishLogin calls FinishLoginFunc
+func (m *WebAuthnManager) FinishLogin(ctx context.Context, projectID uuid.UUID, response webauthn.AssertionResponse) (*webauthn.Session, error) {
+	if m.FinishLoginFunc == nil {
+		panic("mocks: WebAuthnManager.FinishLogin called but FinishLoginFunc is not set")
+	}
+	return m.FinishLoginFunc(ctx, projectID, response)
+}
+
+// ListCredentials calls ListCredentialsFunc
+func (m *WebAuthnManager) ListCredentials(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.WebAuthnCredential, error) {
+	if m.ListCredentialsFunc == nil {
+		panic("mocks: WebAuthnManager.ListCredentials called but ListCredentialsFunc is not set")
+	}
+	return m.ListCredentialsFunc(ctx, projectID, userID)
+}
+
+// RenameCredential calls RenameCredentialFunc
+func (m *WebAuthnManager) RenameCredential(ctx context.Context, projectID, userID, id uuid.UUID, name string) (*schemas.WebAuthnCredential, error) {
+	if m.RenameCredentialFunc == nil {
+		panic("mocks: WebAuthnManager.RenameCredential called but RenameCredentialFunc is not set")
+	}
+	return m.RenameCredentialFunc(ctx, projectID, userID, id, name)
+}
+
+// DeleteCredential calls DeleteCredentialFunc
+func (m *WebAuthnManager) DeleteCredential(ctx context.Context, projectID, userID, id uuid.UUID) error {
+	if m.DeleteCredentialFunc == nil {
+		panic("mocks: WebAuthnManager.DeleteCredential called but DeleteCredentialFunc is not set")
+	}
+	return m.DeleteCredentialFunc(ctx, projectID, userID, id)
+}
@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+var _ webhooks.WebhookManager = (*WebhookManager)(nil)
+
+// WebhookManager is a webhooks.WebhookManager whose methods call the matching Func field.
+// Calling a method whose Func is unset panics.
+type WebhookManager struct {
+	PublishFunc            func(ctx context.Context, projectID uuid.UUID, event string, data interface{})
+	CreateSubscriptionFunc func(ctx context.Context, projectID uuid.UUID, targetURL string, events []string, format string) (*schemas.WebhookSubscription, error)
+	ListSubscriptionsFunc  func(ctx context.Context, projectID uuid.UUID) ([]schemas.WebhookSubscription, error)
+	DeleteSubscriptionFunc func(ctx context.Context, projectID, id uuid.UUID) error
+}
+
+// Publish calls PublishFunc
+func (m *WebhookManager) Publish(ctx context.Context, projectID uuid.UUID, event string, data interface{}) {
+	if m.PublishFunc == nil {
+		panic("mocks: WebhookManager.Publish called but PublishFunc is not set")
+	}
+	m.PublishFunc(ctx, projectID, event, data)
+}
+
+// CreateSubscription calls CreateSubscriptionFunc
+func (m *WebhookManager) CreateSubscription(ctx context.Context, projectID uuid.UUID, targetURL string, events []string, format string) (*schemas.WebhookSubscription, error) {
+	if m.CreateSubscriptionFunc == nil {
+		panic("mocks: WebhookManager.CreateSubscription called but CreateSubscriptionFunc is not set")
+	}
+	return m.CreateSubscriptionFunc(ctx, projectID, targetURL, events, format)
+}
+
+// ListSubscriptions calls ListSubscriptionsFunc
+func (m *WebhookManager) ListSubscriptions(ctx context.Context, projectID uuid.UUID) ([]schemas.WebhookSubscription, error) {
+	if m.ListSubscriptionsFunc == nil {
+		panic("mocks: WebhookManager.ListSubscriptions called but ListSubscriptionsFunc is not set")
+	}
+	return m.ListSubscriptionsFunc(ctx, projectID)
+}
+
+// DeleteSubscription calls DeleteSubscriptionFunc
+func (m *WebhookManager) DeleteSubscription(ctx context.Context, projectID, id uuid.UUID) error {
+	if m.DeleteSubscriptionFunc == nil {
+		panic("mocks: WebhookManager.DeleteSubscription called but DeleteSubscriptionFunc is not set")
+	}
+	return m.DeleteSubscriptionFunc(ctx, projectID, id)
+}
@@ -16,14 +16,19 @@ type CreateUser struct {
 }
 
 type DisplayUser struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Active    bool      `json:"active"`
-	RoleID    string    `json:"role_id"`
-	ProjectID string    `json:"project_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string     `json:"id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	Active        bool       `json:"active"`
+	Status        string     `json:"status"`
+	Type          string     `json:"type,omitempty"` // Global users only: human or service
+	Transitions   []string   `json:"transitions"`    // Statuses the user may be changed to
+	RoleID        string     `json:"role_id"`
+	ProjectID     string     `json:"project_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // Only set on soft-deleted users
+	DeletedBy     string     `json:"deleted_by,omitempty"` // Who deleted the user: a user ID, or "system"
 }
-
@@ -0,0 +1,117 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id defaults, following the second recommended option of RFC 9106
+const (
+	DefaultArgon2Memory      = 64 * 1024 // KiB
+	DefaultArgon2Iterations  = 3
+	DefaultArgon2Parallelism = 4
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2Prefix     = "$argon2id$"
+)
+
+// Argon2id hashes passwords with argon2id. Hashes use the PHC string format,
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2id struct {
+	Memory      uint32 // KiB
+	Iterations  uint32 // Passes over the memory
+	Parallelism uint8  // Lanes
+}
+
+func (a Argon2id) params() (memory, iterations uint32, parallelism uint8) {
+	memory, iterations, parallelism = a.Memory, a.Iterations, a.Parallelism
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = DefaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = DefaultArgon2Parallelism
+	}
+	return memory, iterations, parallelism
+}
+
+// Name implements Algorithm
+func (Argon2id) Name() string { return AlgorithmArgon2id }
+
+// Hash implements Algorithm
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	memory, iterations, parallelism := a.params()
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, memory, iterations, parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Identifies implements Algorithm
+func (Argon2id) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, argon2Prefix)
+}
+
+// argon2Hash is a parsed argon2id PHC string
+type argon2Hash struct {
+	memory, iterations uint32
+	parallelism        uint8
+	salt, key          []byte
+}
+
+func parseArgon2(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version")
+	}
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, fmt.Errorf("invalid argon2 key")
+	}
+	return &h, nil
+}
+
+// Verify implements Algorithm. The hash's own parameters are used, so hashes
+// made before the configuration changed still verify.
+func (Argon2id) Verify(hash, password string) bool {
+	h, err := parseArgon2(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+// Outdated implements Algorithm
+func (a Argon2id) Outdated(hash string) bool {
+	h, err := parseArgon2(hash)
+	if err != nil {
+		return true
+	}
+	memory, iterations, parallelism := a.params()
+	return h.memory != memory || h.iterations != iterations || h.parallelism != parallelism
+}
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxLength is the longest password in bytes bcrypt hashes
+const bcryptMaxLength = 72
+
+// Bcrypt hashes passwords with bcrypt
+type Bcrypt struct {
+	Cost int // Defaults to bcrypt.DefaultCost
+}
+
+func (b Bcrypt) cost() int {
+	if b.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return b.Cost
+}
+
+func (b Bcrypt) validate() error {
+	if c := b.cost(); c < bcrypt.MinCost || c > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// Name implements Algorithm
+func (Bcrypt) Name() string { return AlgorithmBcrypt }
+
+// Hash implements Algorithm
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost())
+	return string(hash), err
+}
+
+// Identifies implements Algorithm
+func (Bcrypt) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// Verify implements Algorithm
+func (Bcrypt) Verify(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Outdated implements Algorithm
+func (b Bcrypt) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.cost()
+}
@@ -0,0 +1,198 @@
+// Package password hashes and verifies user passwords. New hashes use the
+// configured algorithm; hashes made by any supported algorithm still verify,
+// and Verify reports when one should be replaced so that stored hashes move
+// to the configured algorithm as users log in.
+package password
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// Supported algorithm names
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Algorithm is one way of hashing passwords. Each algorithm writes a
+// recognisable prefix into its hashes ("$2a$" for bcrypt, "$argon2id$" for
+// argon2id), so a stored hash says which algorithm made it.
+type Algorithm interface {
+	// Name returns the algorithm's name
+	Name() string
+	// Hash hashes a password with a random salt
+	Hash(password string) (string, error)
+	// Identifies reports whether hash was made by this algorithm
+	Identifies(hash string) bool
+	// Verify reports whether password matches a hash made by this algorithm
+	Verify(hash, password string) bool
+	// Outdated reports whether a hash made by this algorithm uses other
+	// parameters than currently configured
+	Outdated(hash string) bool
+}
+
+// Config selects and tunes the algorithm for new hashes. Zero parameters use
+// the algorithm's defaults.
+type Config struct {
+	Algorithm  string // AlgorithmBcrypt (default) or AlgorithmArgon2id
+	BcryptCost int
+	Argon2     Argon2id
+	MinLength  int // Shortest password Check accepts; 0 for no minimum
+	// MaxAgeDays is how long a password lasts before it must be changed; 0
+	// for no expiry
+	MaxAgeDays int
+	// HistorySize is how many of a user's latest passwords, the current one
+	// included, a new password must differ from. 0 means
+	// DefaultHistorySize; a negative size allows any password.
+	HistorySize int
+}
+
+// DefaultHistorySize is the HistorySize used when none is configured
+const DefaultHistorySize = 3
+
+// ErrTooShort is returned by Check for a password below the configured
+// minimum length
+var ErrTooShort = apierrors.BadRequest("PASSWORD_TOO_SHORT", "password is shorter than the minimum length")
+
+// ErrTooLong is returned by Check for a password the current algorithm
+// cannot hash in full: bcrypt ignores everything past 72 bytes
+var ErrTooLong = apierrors.BadRequest("PASSWORD_TOO_LONG", "password is longer than 72 bytes")
+
+// ErrReused is returned for a new password that is one of the user's
+// latest ones
+var ErrReused = apierrors.BadRequest("PASSWORD_REUSED", "new password must differ from your recent passwords")
+
+// Hasher hashes new passwords with its current algorithm and verifies hashes
+// made by any supported algorithm
+type Hasher struct {
+	current     Algorithm
+	known       []Algorithm
+	minLength   int
+	maxAgeDays  int
+	historySize int
+}
+
+// NewHasher creates a hasher that hashes with current
+func NewHasher(current Algorithm) *Hasher {
+	return &Hasher{
+		current:     current,
+		known:       []Algorithm{current, Bcrypt{}, Argon2id{}},
+		historySize: DefaultHistorySize,
+	}
+}
+
+// New creates a hasher from configuration
+func New(cfg Config) (*Hasher, error) {
+	if cfg.MinLength < 0 {
+		return nil, fmt.Errorf("password minimum length must not be negative")
+	}
+	if cfg.MaxAgeDays < 0 {
+		return nil, fmt.Errorf("password maximum age must not be negative")
+	}
+	var h *Hasher
+	switch cfg.Algorithm {
+	case "", AlgorithmBcrypt:
+		b := Bcrypt{Cost: cfg.BcryptCost}
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+		h = NewHasher(b)
+	case AlgorithmArgon2id:
+		h = NewHasher(cfg.Argon2)
+	default:
+		return nil, fmt.Errorf("unsupported password algorithm %q", cfg.Algorithm)
+	}
+	h.minLength = cfg.MinLength
+	h.maxAgeDays = cfg.MaxAgeDays
+	switch {
+	case cfg.HistorySize > 0:
+		h.historySize = cfg.HistorySize
+	case cfg.HistorySize < 0:
+		h.historySize = 0
+	}
+	return h, nil
+}
+
+// Default returns a bcrypt hasher with the default cost
+func Default() *Hasher {
+	return NewHasher(Bcrypt{})
+}
+
+// Algorithm returns the name of the algorithm used for new hashes
+func (h *Hasher) Algorithm() string {
+	return h.current.Name()
+}
+
+// Check reports whether a new password is acceptable: at least the
+// configured minimum length, in characters, and no longer than the current
+// algorithm can hash
+func (h *Hasher) Check(password string) error {
+	if len([]rune(password)) < h.minLength {
+		return ErrTooShort
+	}
+	if _, ok := h.current.(Bcrypt); ok && len(password) > bcryptMaxLength {
+		return ErrTooLong
+	}
+	return nil
+}
+
+// MaxAgeDays returns how many days a password lasts before it must be
+// changed, 0 if passwords do not expire
+func (h *Hasher) MaxAgeDays() int {
+	return h.maxAgeDays
+}
+
+// Expiry returns when a password set at changedAt expires: maxAgeDays
+// later, or the configured maximum age later when maxAgeDays is nil, as for
+// a project without an override. The zero time means it never expires.
+func (h *Hasher) Expiry(changedAt time.Time, maxAgeDays *int) time.Time {
+	days := h.maxAgeDays
+	if maxAgeDays != nil {
+		days = *maxAgeDays
+	}
+	if days == 0 {
+		return time.Time{}
+	}
+	return changedAt.AddDate(0, 0, days)
+}
+
+// HistorySize returns how many of a user's latest passwords, the current one
+// included, a new password must differ from; 0 if it may repeat any
+func (h *Hasher) HistorySize() int {
+	return h.historySize
+}
+
+// Hash hashes a password with the current algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify reports whether password matches hash, and if so whether the hash
+// should be replaced by a new one because it was made by another algorithm or
+// with outdated parameters
+func (h *Hasher) Verify(hash, password string) (ok, rehash bool) {
+	for _, alg := range h.known {
+		if !alg.Identifies(hash) {
+			continue
+		}
+		if !alg.Verify(hash, password) {
+			return false, false
+		}
+		return true, alg.Name() != h.current.Name() || h.current.Outdated(hash)
+	}
+	return false, false
+}
+
+// Reused reports whether password matches any of hashes, a user's current
+// and previous passwords
+func (h *Hasher) Reused(password string, hashes ...string) bool {
+	for _, hash := range hashes {
+		if ok, _ := h.Verify(hash, password); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/password"
+)
+
+// cheapArgon2 keeps the tests fast; the parameters do not change the format
+var cheapArgon2 = password.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func hash(t *testing.T, h *password.Hasher, plain string) string {
+	t.Helper()
+
+	hashed, err := h.Hash(plain)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	return hashed
+}
+
+func TestVerify(t *testing.T) {
+	bcrypt := password.NewHasher(password.Bcrypt{Cost: 4})
+	argon2 := password.NewHasher(cheapArgon2)
+	bcryptHash := hash(t, bcrypt, "correct horse")
+	argon2Hash := hash(t, argon2, "correct horse")
+
+	if !strings.HasPrefix(bcryptHash, "$2a$") || !strings.HasPrefix(argon2Hash, "$argon2id$") {
+		t.Fatalf("hashes %q and %q do not carry their algorithm prefix", bcryptHash, argon2Hash)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		hasher   *password.Hasher
+		hash     string
+		password string
+		ok       bool
+		rehash   bool
+	}{
+		{"bcrypt with bcrypt configured", bcrypt, bcryptHash, "correct horse", true, false},
+		{"bcrypt with argon2id configured", argon2, bcryptHash, "correct horse", true, true},
+		{"argon2id with argon2id configured", argon2, argon2Hash, "correct horse", true, false},
+		{"argon2id with bcrypt configured", bcrypt, argon2Hash, "correct horse", true, true},
+		{"bcrypt with a higher cost configured", password.NewHasher(password.Bcrypt{Cost: 5}), bcryptHash, "correct horse", true, true},
+		{"argon2id with more memory configured", password.NewHasher(password.Argon2id{Memory: 128, Iterations: 1, Parallelism: 1}), argon2Hash, "correct horse", true, true},
+		{"wrong password", argon2, bcryptHash, "battery staple", false, false},
+		{"unknown format", argon2, "plaintext", "plaintext", false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash := tc.hasher.Verify(tc.hash, tc.password)
+			if ok != tc.ok || rehash != tc.rehash {
+				t.Fatalf("Verify = %v, rehash %v, want %v, rehash %v", ok, rehash, tc.ok, tc.rehash)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  password.Config
+		want string
+	}{
+		{password.Config{}, password.AlgorithmBcrypt},
+		{password.Config{Algorithm: password.AlgorithmArgon2id}, password.AlgorithmArgon2id},
+	} {
+		h, err := password.New(tc.cfg)
+		if err != nil {
+			t.Fatalf("New(%+v): %v", tc.cfg, err)
+		}
+		if h.Algorithm() != tc.want {
+			t.Errorf("New(%+v) hashes with %s, want %s", tc.cfg, h.Algorithm(), tc.want)
+		}
+	}
+	if _, err := password.New(password.Config{Algorithm: "md5"}); err == nil {
+		t.Error("New accepted an unsupported algorithm")
+	}
+}
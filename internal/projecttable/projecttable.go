@@ -0,0 +1,61 @@
+// Package projecttable names the per-project user tables. Each project keeps
+// its users in a table of its own named after the project's UUID, and the
+// name ends up in SQL, so it is only ever built here: from a UUID, in its
+// canonical lowercase form, and checked against a strict pattern. Building
+// the name anywhere else fails the package's tests.
+package projecttable
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"gorm.io/gorm"
+)
+
+const (
+	prefix = "project_"
+	suffix = "_users"
+)
+
+// pattern matches the only table names Users produces
+var pattern = regexp.MustCompile(`^project_[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}_users$`)
+
+// ErrInvalidProjectID is returned for a project ID that is not a UUID
+var ErrInvalidProjectID = apierrors.BadRequest("INVALID_PROJECT_ID", "project ID must be a UUID")
+
+// Users returns the name of the project's users table
+func Users(projectID uuid.UUID) string {
+	return prefix + projectID.String() + suffix
+}
+
+// UsersFor returns the name of the users table of the project whose ID is
+// given as a string, such as a path parameter. IDs that differ only in case
+// or braces name the same table; anything that is not a UUID is rejected
+// with ErrInvalidProjectID.
+func UsersFor(projectID string) (string, error) {
+	id, err := uuid.Parse(strings.TrimSpace(projectID))
+	if err != nil {
+		return "", ErrInvalidProjectID
+	}
+	return Users(id), nil
+}
+
+// Legacy returns the name older versions gave the project's users table,
+// built from the ID exactly as stored. It only differs from the normalized
+// name for IDs stored in another case, and is used to migrate such tables.
+func Legacy(projectID string) string {
+	return prefix + projectID + suffix
+}
+
+// Valid reports whether name is a users table name in the normalized form
+func Valid(name string) bool {
+	return pattern.MatchString(name)
+}
+
+// Quote returns the table name quoted for db's dialect, for the rare raw SQL
+// statement. Names passed to db.Table are quoted by GORM itself.
+func Quote(db *gorm.DB, name string) string {
+	return db.Statement.Quote(name)
+}
@@ -0,0 +1,97 @@
+package projecttable_test
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+)
+
+// tableNameBuilding matches the ways table names were built before this
+// package existed
+var tableNameBuilding = regexp.MustCompile(`Sprintf\("project_|\+ *"_users"`)
+
+func TestNoTableNamesAreBuiltElsewhere(t *testing.T) {
+	root := filepath.Join("..", "..")
+	own, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, _ := filepath.Abs(path); abs == own || d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			if tableNameBuilding.MatchString(scanner.Text()) {
+				t.Errorf("%s:%d builds a project table name; use internal/projecttable", path, line)
+			}
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsersFor(t *testing.T) {
+	id := uuid.MustParse("0f8fad5b-d9cb-469f-a165-70867728950e")
+	want := "project_0f8fad5b-d9cb-469f-a165-70867728950e_users"
+	if got := projecttable.Users(id); got != want || !projecttable.Valid(got) {
+		t.Fatalf("Users = %q, want the valid %q", got, want)
+	}
+
+	for _, given := range []string{
+		"0f8fad5b-d9cb-469f-a165-70867728950e",
+		"0F8FAD5B-D9CB-469F-A165-70867728950E",
+		"{0f8fad5b-d9cb-469f-a165-70867728950e}",
+		" 0f8fad5b-d9cb-469f-a165-70867728950e ",
+	} {
+		if got, err := projecttable.UsersFor(given); err != nil || got != want {
+			t.Errorf("UsersFor(%q) = %q, %v, want %q", given, got, err, want)
+		}
+	}
+	for _, given := range []string{"", "my_project", "users; DROP TABLE projects", "0f8fad5b"} {
+		_, err := projecttable.UsersFor(given)
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_PROJECT_ID" {
+			t.Errorf("UsersFor(%q) err = %v, want INVALID_PROJECT_ID", given, err)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for name, want := range map[string]bool{
+		"project_0f8fad5b-d9cb-469f-a165-70867728950e_users":                      true,
+		"project_0F8FAD5B-D9CB-469F-A165-70867728950E_users":                      false,
+		"project_my_project_users":                                                false,
+		"project_0f8fad5b-d9cb-469f-a165-70867728950e_users; DROP TABLE projects": false,
+		projecttable.Legacy("0F8FAD5B-D9CB-469F-A165-70867728950E"):               false,
+	} {
+		if got := projecttable.Valid(name); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
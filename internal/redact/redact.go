@@ -0,0 +1,106 @@
+// Package redact keeps personal data out of the logs. Log lines that name a
+// user pass the email address through SafeEmail, and error texts that may
+// quote one, such as a database error on a unique email column, go through
+// Text or Error. How much of an address survives is set once at startup.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Mode is how email addresses are written to the logs
+type Mode string
+
+const (
+	// ModeMask keeps the first character of the local part: j***@example.com
+	ModeMask Mode = "mask"
+	// ModeHash replaces the local part with a short SHA-256 digest, so that
+	// lines about the same address can still be matched up
+	ModeHash Mode = "hash"
+	// ModeOff logs addresses as they are
+	ModeOff Mode = "off"
+)
+
+// DefaultMode is the mode used until SetMode is called
+const DefaultMode = ModeMask
+
+// hashLength is how many hex digits of the digest ModeHash keeps
+const hashLength = 12
+
+var mode atomic.Value
+
+func init() {
+	mode.Store(DefaultMode)
+}
+
+// emailPattern finds email addresses inside free text
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// ParseMode parses a configured mode. The empty string selects DefaultMode.
+func ParseMode(value string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(value))); m {
+	case "":
+		return DefaultMode, nil
+	case ModeMask, ModeHash, ModeOff:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown email redaction mode %q, expected mask, hash or off", value)
+	}
+}
+
+// SetMode sets how email addresses are logged from now on
+func SetMode(m Mode) {
+	mode.Store(m)
+}
+
+// CurrentMode returns the mode in use
+func CurrentMode() Mode {
+	return mode.Load().(Mode)
+}
+
+// SafeEmail returns the form of an email address fit for the logs. The
+// domain is kept, since it is rarely personal and helps when debugging
+// delivery; the local part is masked or hashed. A value without an @ is
+// treated as all local part.
+func SafeEmail(email string) string {
+	m := CurrentMode()
+	if m == ModeOff || email == "" {
+		return email
+	}
+	local, domain, found := strings.Cut(email, "@")
+	switch m {
+	case ModeHash:
+		sum := sha256.Sum256([]byte(strings.ToLower(local)))
+		local = "h:" + hex.EncodeToString(sum[:])[:hashLength]
+	default:
+		if _, size := utf8.DecodeRuneInString(local); size > 0 {
+			local = local[:size] + "***"
+		}
+	}
+	if !found {
+		return local
+	}
+	return local + "@" + domain
+}
+
+// Text returns s with every email address in it passed through SafeEmail
+func Text(s string) string {
+	if CurrentMode() == ModeOff {
+		return s
+	}
+	return emailPattern.ReplaceAllStringFunc(s, SafeEmail)
+}
+
+// Error returns the text of err fit for the logs, or "<nil>" for a nil error
+func Error(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return Text(err.Error())
+}
@@ -0,0 +1,68 @@
+package redact_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/redact"
+)
+
+func withMode(t *testing.T, m redact.Mode) {
+	t.Helper()
+	redact.SetMode(m)
+	t.Cleanup(func() { redact.SetMode(redact.DefaultMode) })
+}
+
+func TestSafeEmail(t *testing.T) {
+	for _, tc := range []struct {
+		mode  redact.Mode
+		email string
+		want  string
+	}{
+		{redact.ModeMask, "jane@example.com", "j***@example.com"},
+		{redact.ModeMask, "élodie@example.fr", "é***@example.fr"},
+		{redact.ModeMask, "jane", "j***"},
+		{redact.ModeMask, "", ""},
+		{redact.ModeOff, "jane@example.com", "jane@example.com"},
+	} {
+		withMode(t, tc.mode)
+		if got := redact.SafeEmail(tc.email); got != tc.want {
+			t.Errorf("%s: SafeEmail(%q) = %q, want %q", tc.mode, tc.email, got, tc.want)
+		}
+	}
+
+	withMode(t, redact.ModeHash)
+	hashed := redact.SafeEmail("Jane@example.com")
+	if !strings.HasPrefix(hashed, "h:") || !strings.HasSuffix(hashed, "@example.com") || strings.Contains(strings.ToLower(hashed), "jane") {
+		t.Errorf("SafeEmail = %q, want a hashed local part at example.com", hashed)
+	}
+	if other := redact.SafeEmail("jane@example.com"); other != hashed {
+		t.Errorf("the hashes of Jane@ and jane@ differ: %q and %q", hashed, other)
+	}
+	if other := redact.SafeEmail("john@example.com"); other == hashed {
+		t.Errorf("jane@ and john@ hash alike: %q", other)
+	}
+}
+
+func TestError(t *testing.T) {
+	withMode(t, redact.ModeMask)
+	err := errors.New(`UNIQUE constraint failed: duplicate "jane@example.com" and "bob.smith+tag@mail.example.org"`)
+	if got, want := redact.Error(err), `UNIQUE constraint failed: duplicate "j***@example.com" and "b***@mail.example.org"`; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if got := redact.Error(nil); got != "<nil>" {
+		t.Errorf("Error(nil) = %q", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for value, want := range map[string]redact.Mode{"": redact.DefaultMode, " Hash ": redact.ModeHash, "off": redact.ModeOff} {
+		if got, err := redact.ParseMode(value); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := redact.ParseMode("scramble"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
@@ -0,0 +1,131 @@
+// Package regions places the users of a project in the database of the
+// region the project was created in. Everything else, including the projects
+// themselves, stays in the primary database; only project user tables are
+// routed.
+package regions
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// Default is the region of projects created without one. Its users live in
+// the primary database.
+const Default = ""
+
+// Resolver picks the database of a region. A region without a database of
+// its own, and Default, use the primary one.
+type Resolver struct {
+	primary   *gorm.DB
+	allowed   []string            // Regions projects may be created in, sorted
+	databases map[string]*gorm.DB // Databases of the regions that have one
+}
+
+// NewResolver creates a resolver for the allowed regions. databases maps the
+// regions with a database of their own to it; every one of them must be
+// allowed.
+func NewResolver(primary *gorm.DB, allowed []string, databases map[string]*gorm.DB) (*Resolver, error) {
+	r := &Resolver{
+		primary:   primary,
+		databases: make(map[string]*gorm.DB, len(databases)),
+	}
+	for _, region := range allowed {
+		if region == Default || strings.TrimSpace(region) != region {
+			return nil, fmt.Errorf("invalid region %q", region)
+		}
+		if !slices.Contains(r.allowed, region) {
+			r.allowed = append(r.allowed, region)
+		}
+	}
+	sort.Strings(r.allowed)
+	for region, db := range databases {
+		if !slices.Contains(r.allowed, region) {
+			return nil, fmt.Errorf("region %q has a database but is not allowed", region)
+		}
+		r.databases[region] = db
+	}
+	return r, nil
+}
+
+// Allowed returns the regions projects may be created in, besides Default
+func (r *Resolver) Allowed() []string {
+	if r == nil {
+		return nil
+	}
+	return slices.Clone(r.allowed)
+}
+
+// Validate checks that a project may be created in region
+func (r *Resolver) Validate(region string) error {
+	if region == Default || slices.Contains(r.Allowed(), region) {
+		return nil
+	}
+	if len(r.Allowed()) == 0 {
+		return apierrors.BadRequest("INVALID_REGION", "no regions are configured; leave region empty")
+	}
+	return apierrors.BadRequest("INVALID_REGION", fmt.Sprintf("region %q is not one of %s", region, strings.Join(r.Allowed(), ", ")))
+}
+
+// DB returns the database holding the users of projects in region. A nil
+// resolver routes nothing and returns nil, for callers to use their primary.
+func (r *Resolver) DB(region string) *gorm.DB {
+	if r == nil {
+		return nil
+	}
+	if db, ok := r.databases[region]; ok {
+		return db
+	}
+	return r.primary
+}
+
+// For returns the database holding the users of projects in region: that of
+// the region, or db, the primary database or a transaction on it, when the
+// region has none of its own, with the context of db. A nil resolver
+// returns db.
+func (r *Resolver) For(db *gorm.DB, region string) *gorm.DB {
+	if r == nil {
+		return db
+	}
+	if regional, ok := r.databases[region]; ok {
+		return regional.WithContext(db.Statement.Context)
+	}
+	return db
+}
+
+// UsersDB returns the database holding the users table of the project with
+// ID projectID, as For does, reading the region of the project, deleted or
+// not, from db. It returns gorm.ErrRecordNotFound for a project that does
+// not exist.
+func (r *Resolver) UsersDB(db *gorm.DB, projectID uuid.UUID) (*gorm.DB, error) {
+	if r == nil || len(r.databases) == 0 {
+		return db, nil
+	}
+	var project schemas.Project
+	if err := db.Unscoped().Select("region").Take(&project, "id = ?", projectID).Error; err != nil {
+		return nil, err
+	}
+	return r.For(db, project.Region), nil
+}
+
+// Databases returns the primary database followed by those of the regions
+// having their own, in region order, for operations that aggregate users
+// across projects of every region
+func (r *Resolver) Databases() []*gorm.DB {
+	if r == nil {
+		return nil
+	}
+	databases := []*gorm.DB{r.primary}
+	for _, region := range r.allowed {
+		if db, ok := r.databases[region]; ok {
+			databases = append(databases, db)
+		}
+	}
+	return databases
+}
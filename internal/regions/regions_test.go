@@ -0,0 +1,67 @@
+package regions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestResolverRoutesRegionsWithADatabase(t *testing.T) {
+	primary, eu := testutil.NewTestDB(t), testutil.NewTestDB(t)
+	resolver, err := regions.NewResolver(primary, []string{"us", "eu", "eu"}, map[string]*gorm.DB{"eu": eu})
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+	if allowed := resolver.Allowed(); len(allowed) != 2 || allowed[0] != "eu" || allowed[1] != "us" {
+		t.Errorf("Allowed = %v, want [eu us]", allowed)
+	}
+
+	for region, want := range map[string]*gorm.DB{"eu": eu, "us": primary, regions.Default: primary} {
+		if got := resolver.DB(region); got != want {
+			t.Errorf("DB(%q) is not the expected database", region)
+		}
+	}
+	ctx := context.WithValue(context.Background(), struct{}{}, "request")
+	tx := primary.WithContext(ctx)
+	if got := resolver.For(tx, "us"); got != tx {
+		t.Error("For a region without a database did not keep the primary")
+	}
+	if got := resolver.For(tx, "eu"); got.Statement.ConnPool != eu.Statement.ConnPool || got.Statement.Context != ctx {
+		t.Error("For the eu region is not its database with the context of the caller")
+	}
+
+	var none *regions.Resolver
+	if none.DB("eu") != nil || none.For(tx, "eu") != tx || none.Allowed() != nil {
+		t.Error("a nil resolver routes something")
+	}
+
+	for _, region := range []string{regions.Default, "us", "eu"} {
+		if err := resolver.Validate(region); err != nil {
+			t.Errorf("Validate(%q): %v", region, err)
+		}
+	}
+	if err := resolver.Validate("mars"); err == nil {
+		t.Error("Validate accepted a region that is not allowed")
+	}
+	if err := none.Validate("eu"); err == nil {
+		t.Error("Validate accepted a region with none configured")
+	}
+}
+
+func TestNewResolverRejects(t *testing.T) {
+	primary := testutil.NewTestDB(t)
+	for name, build := range map[string]func() (*regions.Resolver, error){
+		"the default region": func() (*regions.Resolver, error) { return regions.NewResolver(primary, []string{""}, nil) },
+		"padded names":       func() (*regions.Resolver, error) { return regions.NewResolver(primary, []string{" eu"}, nil) },
+		"a database of a region not allowed": func() (*regions.Resolver, error) {
+			return regions.NewResolver(primary, []string{"us"}, map[string]*gorm.DB{"eu": primary})
+		},
+	} {
+		if _, err := build(); err == nil {
+			t.Errorf("NewResolver accepted %s", name)
+		}
+	}
+}
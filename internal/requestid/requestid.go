@@ -0,0 +1,22 @@
+// Package requestid carries the ID of the HTTP request being served, so log
+// lines written while handling it can be matched to the response the client
+// saw.
+package requestid
+
+import "context"
+
+// Header carries the request ID on requests and responses
+const Header = "X-Request-ID"
+
+type idKey struct{}
+
+// WithID returns a context carrying the request ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the request ID stored by WithID, or "" outside a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
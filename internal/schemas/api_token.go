@@ -0,0 +1,26 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProjectAPIToken lets a tenant's backend call the project's machine-facing
+// endpoints, such as authorization checks. Only the SHA-256 hash of the
+// token is stored; the prefix identifies it in listings. A token issued to a
+// service account also authenticates requests as that account.
+type ProjectAPIToken struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
+	Name       string    `gorm:"size:100;not null"`
+	Prefix     string    `gorm:"size:16;not null"`
+	TokenHash  string    `gorm:"size:64;not null;uniqueIndex"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	ProjectId        uuid.UUID  `gorm:"type:char(36);not null;index"`
+	ServiceAccountId *uuid.UUID `gorm:"type:char(36);index"` // Nil for tokens of the project alone
+}
@@ -0,0 +1,28 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Artifact kinds
+const (
+	ArtifactKindImportUpload  = "import_upload"
+	ArtifactKindImportErrors  = "import_errors"
+	ArtifactKindProjectExport = "project_export"
+)
+
+// Artifact records a blob the service keeps in its blob store. An artifact
+// without ExpiresAt is in use, e.g. the file of an unfinished import, and is
+// kept until its owner lets it expire; expired artifacts are deleted by the
+// cleanup job.
+type Artifact struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primary_key"`
+	Key       string     `gorm:"column:blob_key;size:255;not null;uniqueIndex"`
+	Kind      string     `gorm:"size:50;not null;index"`
+	ProjectId *uuid.UUID `gorm:"type:char(36);index"`
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records a change made through the service
+type AuditEvent struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primary_key"`
+	ProjectId    *uuid.UUID `gorm:"type:char(36);index"` // nil for changes outside a project
+	Actor        string     `gorm:"size:100;index"`      // ID of the authenticated caller, or "anonymous"
+	Action       string     `gorm:"size:50;index"`       // "create", "update", "delete", ...
+	ResourceType string     `gorm:"size:50;index"`       // "project", "role", "policy", ...
+	ResourceID   string     `gorm:"size:100"`
+	Details      string     `gorm:"type:text"`
+	CreatedAt    time.Time  `gorm:"index"`
+
+	// ServiceVersion is the version of the service that recorded the event
+	ServiceVersion string `gorm:"size:100"`
+}
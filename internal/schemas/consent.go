@@ -0,0 +1,22 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Consent records a user accepting a version of one of their project's
+// documents, such as its terms of service. Rows are never changed, so they
+// are the history of what each user accepted and when.
+type Consent struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
+	Document   string    `gorm:"size:50;not null;index:idx_consents_user"` // Document type, e.g. "terms" or "privacy"
+	Version    string    `gorm:"size:50;not null"`
+	IPAddress  string    `gorm:"size:45"` // Of the client the acceptance was recorded from
+	AcceptedAt time.Time `gorm:"not null;index"`
+
+	// Relationships
+	ProjectId uuid.UUID `gorm:"type:char(36);not null;index:idx_consents_user"`
+	UserId    uuid.UUID `gorm:"type:char(36);not null;index:idx_consents_user"`
+}
@@ -0,0 +1,39 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import job statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+	ImportStatusCancelled = "cancelled"
+)
+
+// ImportJob tracks the progress of an asynchronous CSV user import
+type ImportJob struct {
+	ID              uuid.UUID `gorm:"type:char(36);primary_key"`
+	ProjectId       uuid.UUID `gorm:"type:char(36);not null;index"`
+	Status          string    `gorm:"size:20;not null;index"`
+	FilePath        string    `gorm:"size:1000;not null"` // Blob key of the uploaded CSV
+	ErrorReportPath string    `gorm:"size:1000"`          // Blob key of the CSV of rows that failed to import
+	ErrorReportSize int64     // Committed size of the error report, used when resuming
+	Offset          int64     // Byte offset just past the last committed chunk
+	RowsProcessed   int
+	RowsSucceeded   int
+	RowsFailed      int
+	Error           string `gorm:"size:1000"`
+	// Owner is the instance whose worker claimed the job, and LeaseExpiresAt
+	// when the claim lapses unless the worker renews it. A running job whose
+	// lease lapsed was abandoned and may be claimed again.
+	Owner          string     `gorm:"size:100"`
+	LeaseExpiresAt *time.Time `gorm:"index"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FinishedAt     *time.Time
+}
@@ -0,0 +1,22 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginEvent records a successful project user login. The device is
+// identified only by a hash of the client's user agent and accept-language.
+type LoginEvent struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
+	Method     string    `gorm:"size:50;not null"`
+	IPAddress  string    `gorm:"size:45"`
+	DeviceHash string    `gorm:"size:64"`
+	Suspicious bool      `gorm:"not null;default:false"`
+	CreatedAt  time.Time `gorm:"index"`
+
+	// Relationships
+	ProjectId uuid.UUID `gorm:"type:char(36);not null;index"`
+	UserId    uuid.UUID `gorm:"type:char(36);not null;index"`
+}
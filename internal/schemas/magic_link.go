@@ -0,0 +1,24 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MagicLinkToken is a single-use login token emailed to a project user. Only
+// the SHA-256 hash of the token is stored. Failures counts the verifications
+// of the token that failed, such as for another project or after it was
+// used; the token is locked once they reach the configured limit.
+type MagicLinkToken struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
+	TokenHash  string    `gorm:"size:64;not null;uniqueIndex"`
+	Email      string    `gorm:"size:255;not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	ConsumedAt *time.Time
+	Failures   int `gorm:"not null;default:0"`
+	CreatedAt  time.Time
+
+	// Relationships
+	ProjectId uuid.UUID `gorm:"type:char(36);not null;index"`
+}
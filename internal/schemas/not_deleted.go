@@ -0,0 +1,62 @@
+package schemas
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// NotDeletedColumn is a generated column of the user tables that is 1 for a
+// live user and NULL for a soft-deleted one. Unique indexes over the email
+// and this column keep emails unique among live users only, since NULLs
+// never clash: the email of a deleted user can be used again, and restoring
+// the user then fails. The column is not part of the models; it is only
+// ever read by the indexes.
+const NotDeletedColumn = "not_deleted"
+
+// AddNotDeletedColumn adds NotDeletedColumn to a user table that lacks it.
+// The column is virtual, computed rather than stored, so adding it does not
+// rewrite the table.
+func AddNotDeletedColumn(db *gorm.DB, table string) error {
+	if db.Migrator().HasColumn(table, NotDeletedColumn) {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TINYINT GENERATED ALWAYS AS (CASE WHEN %s IS NULL THEN 1 END) VIRTUAL",
+		db.Statement.Quote(table), db.Statement.Quote(NotDeletedColumn), db.Statement.Quote("deleted_at"))).Error
+}
+
+// ProjectUserEmailIndex is the name of the unique index on the email of a
+// per-project user table's live users. Index names include the table, as
+// SQLite's are shared by the whole database.
+func ProjectUserEmailIndex(table string) string {
+	return "uq_" + table + "_email"
+}
+
+// legacyProjectUserEmailIndex is the name GORM gave the unique index on the
+// email alone that older versions created on per-project user tables
+func legacyProjectUserEmailIndex(table string) string {
+	return "idx_" + table + "_email"
+}
+
+// CreateProjectUserEmailIndex makes the email unique among the live users
+// of a per-project user table, replacing the index older versions made on
+// the email alone. It fails if live users already share an email.
+func CreateProjectUserEmailIndex(db *gorm.DB, table string) error {
+	if err := AddNotDeletedColumn(db, table); err != nil {
+		return fmt.Errorf("adding %s to %s: %w", NotDeletedColumn, table, err)
+	}
+	migrator := db.Migrator()
+	index := ProjectUserEmailIndex(table)
+	if !migrator.HasIndex(table, index) {
+		if err := db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s, %s)",
+			db.Statement.Quote(index), db.Statement.Quote(table), db.Statement.Quote("email"), db.Statement.Quote(NotDeletedColumn))).Error; err != nil {
+			return fmt.Errorf("creating %s: %w", index, err)
+		}
+	}
+	if legacy := legacyProjectUserEmailIndex(table); migrator.HasIndex(table, legacy) {
+		if err := migrator.DropIndex(table, legacy); err != nil {
+			return fmt.Errorf("dropping %s: %w", legacy, err)
+		}
+	}
+	return nil
+}
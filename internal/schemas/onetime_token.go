@@ -0,0 +1,20 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OneTimeToken is a random token that can be consumed once before it
+// expires. Purpose separates the features issuing tokens and Subject is what
+// the token stands for. Only the SHA-256 hash of the token is stored.
+type OneTimeToken struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`
+	Purpose    string    `gorm:"size:50;not null;index"`
+	TokenHash  string    `gorm:"size:64;not null;uniqueIndex"`
+	Subject    string    `gorm:"size:1000;not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
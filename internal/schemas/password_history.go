@@ -0,0 +1,19 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHistory keeps the hash of a password a global user has replaced,
+// so that a new password can be checked against the latest ones. Only as
+// many are kept as the history size needs.
+type PasswordHistory struct {
+	ID        uuid.UUID `gorm:"type:char(36);primary_key"`
+	Hash      string    `gorm:"size:255;not null"`
+	CreatedAt time.Time `gorm:"index"` // When the password was replaced
+
+	// Relationships
+	UserId uuid.UUID `gorm:"type:char(36);not null;index"`
+}
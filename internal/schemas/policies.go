@@ -8,15 +8,19 @@ import (
 
 type Policy struct {
 	ID          uuid.UUID `gorm:"type:char(36);primary_key"`
-	Name        string    `gorm:"size:100;uniqueIndex"`
+	Name        string    `gorm:"size:100;uniqueIndex:idx_policies_project_name"`
 	Description string    `gorm:"size:255"`
-	Resource    string    `gorm:"size:100;not null"` // The resource this policy applies to
-	Action      string    `gorm:"size:100;not null"` // The action allowed (e.g., "read", "write")
-	Effect      string    `gorm:"size:20;not null"`  // "allow" or "deny"
+	Resource    string    `gorm:"size:100;not null"`      // The resource this policy applies to
+	Action      string    `gorm:"size:100;not null"`      // The action allowed (e.g., "read", "write")
+	Effect      string    `gorm:"size:20;not null"`       // "allow" or "deny"
+	IsSystem    bool      `gorm:"not null;default:false"` // Seeded from the system policy manifest; see policies.SystemPolicies
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	DeletedBy   *uuid.UUID     `gorm:"type:char(36)"` // Who deleted the policy; nil when not deleted or deleted by the system
 
 	// Relationships
-	RolesId uuid.UUID `gorm:"type:char(36)not null;"`
+	ProjectId *uuid.UUID `gorm:"type:char(36);uniqueIndex:idx_policies_project_name"` // nil for global policies
+	CreatedBy *uuid.UUID `gorm:"type:char(36);index"`                                 // nil when created by the system
+	RolesId   uuid.UUID  `gorm:"type:char(36);not null"`
 }
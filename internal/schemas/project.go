@@ -9,13 +9,17 @@ import (
 
 type Project struct {
 	// Remove gorm.Model and use explicit fields to avoid duplication
-	ID          uuid.UUID `gorm:"type:char(36);primary_key"`
-	Name        string    `gorm:"size:255;not null"`
-	Description string    `gorm:"size:1000"`
-	UniqueID    string    `gorm:"size:50;uniqueIndex;not null"` // This will be used for table naming
+	ID          uuid.UUID       `gorm:"type:char(36);primary_key"`
+	Name        string          `gorm:"size:255;not null"`
+	Description string          `gorm:"size:1000"`
+	UniqueID    string          `gorm:"size:50;uniqueIndex;not null"` // This will be used for table naming
+	Region      string          `gorm:"size:50;not null;default:''"`  // Where the project's users are stored; "" is the primary database
+	Settings    ProjectSettings `gorm:"type:text;serializer:json"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	DeletedBy   *uuid.UUID     `gorm:"type:char(36)"`       // Who deleted the project; nil when not deleted or deleted by the system
+	CreatedBy   *uuid.UUID     `gorm:"type:char(36);index"` // nil when created by the system or anonymously
 
 	// Relationships
 }
@@ -0,0 +1,185 @@
+package schemas
+
+import "github.com/google/uuid"
+
+// ProjectSettings holds per-project configuration, stored as JSON on the project row
+type ProjectSettings struct {
+	// DefaultRoleID is the role given to users that sign up without one
+	DefaultRoleID *uuid.UUID      `json:"default_role_id,omitempty"`
+	Branding      ProjectBranding `json:"branding"`
+
+	// CustomResources registers project-specific policy resources, keyed by
+	// "<namespace>:<name>", with the actions each one supports
+	CustomResources map[string][]string `json:"custom_resources,omitempty"`
+
+	MagicLink       MagicLinkSettings       `json:"magic_link"`
+	WebAuthn        WebAuthnSettings        `json:"webauthn"`
+	SuspiciousLogin SuspiciousLoginSettings `json:"suspicious_login"`
+
+	// OAuthProviders holds project-specific OAuth client credentials keyed by
+	// provider name, used instead of the global configuration. They are
+	// managed through the project's oauth-providers endpoints.
+	OAuthProviders map[string]OAuthProviderSettings `json:"oauth_providers,omitempty"`
+
+	OAuthLogin OAuthLoginSettings `json:"oauth_login"`
+
+	Reports ReportSettings `json:"reports"`
+
+	Passwords PasswordSettings `json:"passwords"`
+
+	CORS CORSSettings `json:"cors"`
+
+	Consents ConsentSettings `json:"consents"`
+}
+
+// ConsentSettings declares the documents a project's users must accept
+type ConsentSettings struct {
+	// Required maps each document type, e.g. "terms", to the version users
+	// must have accepted. Bumping a version asks every user to accept again.
+	Required map[string]string `json:"required,omitempty"`
+
+	// Enforce answers 451 to signed in users of the project who have not
+	// accepted every required version, on all routes but those recording
+	// their consent and changing their password
+	Enforce bool `json:"enforce"`
+}
+
+// CORSSettings lets browsers call the project's routes from origins beyond
+// those allowed for the whole service
+type CORSSettings struct {
+	// AllowedOrigins lists bare origins, e.g. "https://app.example.com"
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+}
+
+// PasswordSettings overrides the global password policy for the project's
+// own users and the global users whose primary project this is
+type PasswordSettings struct {
+	// MaxAgeDays is how long a password lasts before it must be changed; nil
+	// uses the global passwords.max_age_days and 0 turns expiry off
+	MaxAgeDays *int `json:"max_age_days,omitempty"`
+}
+
+// ReportSettings schedules the digest email sent to a project's admins
+type ReportSettings struct {
+	// Schedule is a five-field cron schedule evaluated in UTC, e.g.
+	// "0 9 * * 1" for Mondays at 09:00; empty sends no scheduled digests
+	Schedule string `json:"schedule,omitempty"`
+
+	// Recipients are the email addresses the digest is sent to
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// Scheduled reports whether digests are sent on a schedule
+func (s ReportSettings) Scheduled() bool {
+	return s.Schedule != "" && len(s.Recipients) > 0
+}
+
+// How an OAuth login is handled when the provider has not verified the email
+// and a user with that email already exists
+const (
+	UnverifiedEmailReject   = "reject"   // Refuse the login; the default
+	UnverifiedEmailSeparate = "separate" // Create a new user linked only to the provider identity
+)
+
+// OAuthLoginSettings controls how OAuth logins are matched to existing users
+type OAuthLoginSettings struct {
+	// UnverifiedEmail is UnverifiedEmailReject or UnverifiedEmailSeparate.
+	// An existing user is never linked through an unverified email.
+	UnverifiedEmail string `json:"unverified_email,omitempty"`
+	// Providers limits OAuth logins to these providers; empty offers every
+	// provider with a client, global or the project's own
+	Providers []string `json:"providers,omitempty"`
+}
+
+// Offers reports whether the settings let users log in with the provider
+func (s OAuthLoginSettings) Offers(provider string) bool {
+	if len(s.Providers) == 0 {
+		return true
+	}
+	for _, name := range s.Providers {
+		if name == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthProviderSettings overrides a provider's client for a project. An empty
+// redirect URL or scope list falls back to the global configuration.
+type OAuthProviderSettings struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	RedirectURL  string   `json:"redirect_url,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// Redacted returns a copy of the settings safe to return to API clients, with
+// OAuth client secrets removed
+func (s ProjectSettings) Redacted() ProjectSettings {
+	if len(s.OAuthProviders) == 0 {
+		return s
+	}
+	providers := make(map[string]OAuthProviderSettings, len(s.OAuthProviders))
+	for name, provider := range s.OAuthProviders {
+		provider.ClientSecret = ""
+		providers[name] = provider
+	}
+	s.OAuthProviders = providers
+	return s
+}
+
+// WebAuthnSettings configures passkeys for a project. Passkeys are available
+// once a relying party ID and at least one origin are set.
+type WebAuthnSettings struct {
+	// RPID is the relying party ID, the domain credentials are scoped to
+	// (e.g. "example.com"). It must be the origins' host or a parent of it.
+	RPID string `json:"rp_id,omitempty"`
+	// RPName is shown by authenticators; defaults to the project's display name
+	RPName string `json:"rp_name,omitempty"`
+	// Origins lists the web origins allowed to run ceremonies, e.g.
+	// "https://app.example.com"
+	Origins []string `json:"origins,omitempty"`
+}
+
+// Enabled reports whether passkeys are configured
+func (s WebAuthnSettings) Enabled() bool {
+	return s.RPID != "" && len(s.Origins) > 0
+}
+
+// SuspiciousLoginSettings controls alerts for logins from an IP address or
+// device the user has not logged in from before
+type SuspiciousLoginSettings struct {
+	Enabled bool `json:"enabled"`
+
+	// NotifyUser emails the user a "was this you?" message as well as
+	// publishing the user.suspicious_login event
+	NotifyUser bool `json:"notify_user"`
+
+	// RevokeURL is linked from the email so the user can end their sessions;
+	// the login event ID is appended as the "event" query parameter
+	RevokeURL string `json:"revoke_url,omitempty"`
+
+	// MinHistory is how many earlier logins a user needs before new IPs or
+	// devices are flagged; defaults to 1 so a first login is never flagged
+	MinHistory int `json:"min_history,omitempty"`
+
+	// LookbackDays limits the history compared against; 0 uses all of it
+	LookbackDays int `json:"lookback_days,omitempty"`
+}
+
+// MagicLinkSettings controls password-less email login for a project
+type MagicLinkSettings struct {
+	Enabled bool `json:"enabled"`
+
+	// RedirectURL, when set, is where emailed links point; the token is
+	// appended as the "token" query parameter for the project's frontend to
+	// exchange. Otherwise links point straight at the verify endpoint.
+	RedirectURL string `json:"redirect_url,omitempty"`
+}
+
+// ProjectBranding customizes how a project is presented to its users
+type ProjectBranding struct {
+	DisplayName  string `json:"display_name,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"`
+}
@@ -7,14 +7,41 @@ import (
 	"gorm.io/gorm"
 )
 
+// User statuses. See package userstatus for the allowed changes between them.
+const (
+	UserStatusInvited             = "invited"
+	UserStatusPendingVerification = "pending_verification"
+	UserStatusActive              = "active"
+	UserStatusSuspended           = "suspended"
+	UserStatusPendingDeletion     = "pending_deletion"
+	UserStatusDeactivated         = "deactivated"
+)
+
+// ProjectUserEmailSize is the size of the email column of project users,
+// matching its tag
+const ProjectUserEmailSize = 255
+
 // ProjectUser represents a user specific to a project
 type ProjectUser struct {
-	ID        uuid.UUID `gorm:"type:char(36);primary_key"`
+	ID        uuid.UUID `gorm:"type:char(36);primary_key;index:,composite:changes,priority:2"`
 	Email     string    `gorm:"size:255;not null;"` // Unique email for the user
 	Password  string    `gorm:"size:255"`           // Hashed password for local auth
 	FirstName string    `gorm:"size:100"`
 	LastName  string    `gorm:"size:100"`
-	Active    bool      `gorm:"default:true"`
+	Status    string    `gorm:"size:32;not null;default:active;index"`
+	Active    bool      `gorm:"default:true"` // Mirrors Status == active for older clients; set through SetStatus
+
+	// PasswordChangedAt is when the password was last set; nil for users
+	// created before it was recorded, whose password dates from CreatedAt
+	PasswordChangedAt *time.Time
+	// PasswordHistory holds the hashes of the passwords Password replaced,
+	// newest first, as many as the password history size needs. It is kept
+	// with the user, in the database of the project's region.
+	PasswordHistory []string `gorm:"type:text;serializer:json"`
+
+	// EmailVerified is set once the user has proven they control Email,
+	// e.g. by following a magic link
+	EmailVerified bool `gorm:"default:false"`
 
 	// OAuth related fields
 	OAuthID      string `gorm:"size:100;index"` // ID from OAuth provider
@@ -24,10 +51,17 @@ type ProjectUser struct {
 	TokenExpiry  time.Time
 
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UpdatedAt time.Time      `gorm:"index:,composite:changes,priority:1"` // With ID, the order of the change feed
 	DeletedAt gorm.DeletedAt `gorm:"index"`
+	DeletedBy *uuid.UUID     `gorm:"type:char(36)"` // Who soft-deleted the user; nil when not deleted or deleted by the system
 
 	// Relationships
 	RoleId    uuid.UUID `gorm:"type:char(36);not null;"`
 	ProjectId uuid.UUID `gorm:"type:char(36);not null"`
 }
+
+// SetStatus sets the user's status and keeps Active in step with it
+func (u *ProjectUser) SetStatus(status string) {
+	u.Status = status
+	u.Active = status == UserStatusActive
+}
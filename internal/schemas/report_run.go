@@ -0,0 +1,30 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How a report run was started
+const (
+	ReportTriggerSchedule = "schedule"
+	ReportTriggerManual   = "manual"
+)
+
+// ReportRun records one digest email sent to a project's report recipients.
+// The unique index on the project and scheduled time lets only one instance
+// claim a scheduled run, and keeps a restart from sending it again.
+type ReportRun struct {
+	ID           uuid.UUID `gorm:"type:char(36);primary_key"`
+	ProjectId    uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_report_runs_project_time"`
+	ScheduledFor time.Time `gorm:"not null;uniqueIndex:idx_report_runs_project_time"` // Fire time of the schedule, or when a manual run was asked for
+	TriggeredBy  string    `gorm:"size:20;not null"`                                  // ReportTriggerSchedule or ReportTriggerManual
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	Recipients   int
+	Sent         int
+	Error        string `gorm:"size:1000"` // First delivery error, if any
+	CreatedAt    time.Time
+	FinishedAt   *time.Time
+}
@@ -7,15 +7,19 @@ import (
 )
 
 type Role struct { // Changed from Roles to Role for consistency
-	ID          uuid.UUID `gorm:"type:char(36);primary_key"`
-	Name        string    `gorm:"size:100;uniqueIndex"`
-	Description string    `gorm:"size:255"`
-	Expiration  time.Duration
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID           uuid.UUID `gorm:"type:char(36);primary_key"`
+	Name         string    `gorm:"size:100;uniqueIndex:idx_roles_project_name"`
+	Description  string    `gorm:"size:255"`
+	Expiration   time.Duration
+	AllowedCIDRs []string `gorm:"column:allowed_cidrs;type:text;serializer:json"` // Networks the role may be used from; empty for anywhere
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	DeletedBy    *uuid.UUID     `gorm:"type:char(36)"` // Who deleted the role; nil when not deleted or deleted by the system
 
 	// Relationships
-	Users    uuid.UUID `gorm:"type:char(36);not null"`
-	Policies uuid.UUID `gorm:"type:char(36);not null"`
+	ProjectId *uuid.UUID `gorm:"type:char(36);uniqueIndex:idx_roles_project_name"` // nil for global roles
+	CreatedBy *uuid.UUID `gorm:"type:char(36);index"`                              // nil when created by the system
+	Users     uuid.UUID  `gorm:"type:char(36);not null"`
+	Policies  uuid.UUID  `gorm:"type:char(36);not null"`
 }
@@ -0,0 +1,30 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InProject is a gorm scope restricting project-scoped rows (roles,
+// policies) to a project, or to global rows when projectID is nil
+func InProject(projectID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if projectID == nil {
+			return db.Where("project_id IS NULL")
+		}
+		return db.Where("project_id = ?", *projectID)
+	}
+}
+
+// SoftDeletion is the update that soft-deletes rows at a time, recording who
+// deleted them: a user ID, or nil for the system
+func SoftDeletion(at time.Time, by *uuid.UUID) map[string]interface{} {
+	return map[string]interface{}{"deleted_at": at, "deleted_by": by}
+}
+
+// Restoration is the update that undoes a soft delete
+func Restoration() map[string]interface{} {
+	return map[string]interface{}{"deleted_at": nil, "deleted_by": nil}
+}
@@ -0,0 +1,17 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserProjectMembership grants a global user access to a project beyond its
+// primary ProjectId, with the role the user holds inside that project
+type UserProjectMembership struct {
+	UserId    uuid.UUID `gorm:"type:char(36);primaryKey"`
+	ProjectId uuid.UUID `gorm:"type:char(36);primaryKey;index"`
+	RoleId    uuid.UUID `gorm:"type:char(36);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -6,13 +6,52 @@ import (
 	"time"
 )
 
+// Scopes within which global users' emails are unique
+const (
+	EmailScopeGlobal  = "global"  // One user per email across all projects; the default
+	EmailScopeProject = "project" // One user per email within each project
+)
+
+// Names of the unique indexes enforcing each email scope among live users,
+// over the email and NotDeletedColumn. Only one exists at a time;
+// internal.MigrateEmailScope switches between them.
+const (
+	UserEmailIndex        = "idx_users_live_email"
+	UserProjectEmailIndex = "idx_users_project_live_email"
+)
+
+// Names of the indexes older versions enforced the email scopes with, which
+// counted soft-deleted users too. internal.MigrateEmailScope replaces them.
+const (
+	LegacyUserEmailIndex        = "idx_users_email"
+	LegacyUserProjectEmailIndex = "idx_users_project_email"
+)
+
+// UserEmailSize is the size of the email column of users, matching its tag
+const UserEmailSize = 191
+
+// Types of user
+const (
+	UserTypeHuman   = "human"   // A person, logging in with an email and password; the default
+	UserTypeService = "service" // An automation identity, authenticating only with API tokens
+)
+
 type User struct {
 	ID        uuid.UUID `gorm:"type:char(36);primary_key"`
-	Email     string    `gorm:"uniqueIndex"`
+	Email     string    `gorm:"size:191"` // Unique within the configured email scope; NULL for service accounts
 	Password  string    `gorm:"size:255"` // Hashed password for local auth
 	FirstName string    `gorm:"size:100"`
 	LastName  string    `gorm:"size:100"`
-	Active    bool      `gorm:"default:true"`
+	Status    string    `gorm:"size:32;not null;default:active;index"`
+	Active    bool      `gorm:"default:true"` // Mirrors Status == active for older clients; set through SetStatus
+
+	// Type is UserTypeHuman or UserTypeService. Service accounts have no
+	// email or password, and are left out of user lists unless asked for.
+	Type string `gorm:"size:16;not null;default:human;index"`
+
+	// PasswordChangedAt is when the password was last set; nil for users
+	// created before it was recorded, whose password dates from CreatedAt
+	PasswordChangedAt *time.Time
 
 	// OAuth related fields
 	OAuthID      string `gorm:"size:100;index"` // ID from OAuth provider
@@ -25,8 +64,20 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
+	DeletedBy *uuid.UUID     `gorm:"type:char(36)"` // Who soft-deleted the user; nil when not deleted or deleted by the system
 
 	// Relationships
-	RoleId    uuid.UUID `gorm:"type:char(36)not null;"` // Changed from Roles to Role
+	RoleId    uuid.UUID `gorm:"type:char(36);not null"` // Changed from Roles to Role
 	ProjectId uuid.UUID `gorm:"type:char(36);not null"` // Corrected relationship table name
 }
+
+// SetStatus sets the user's status and keeps Active in step with it
+func (u *User) SetStatus(status string) {
+	u.Status = status
+	u.Active = status == UserStatusActive
+}
+
+// IsServiceAccount reports whether the user is a service account
+func (u *User) IsServiceAccount() bool {
+	return u.Type == UserTypeService
+}
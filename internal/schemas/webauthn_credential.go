@@ -0,0 +1,27 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthnCredential is a passkey enrolled by a project user
+type WebAuthnCredential struct {
+	ID           uuid.UUID `gorm:"type:char(36);primary_key"`
+	CredentialID string    `gorm:"size:255;not null;uniqueIndex"` // Unpadded base64url of the authenticator's credential ID
+	Name         string    `gorm:"size:100"`
+	AAGUID       uuid.UUID `gorm:"type:char(36)"` // Authenticator model, all zeros when not disclosed
+	PublicKey    []byte    `gorm:"not null"`      // CBOR-encoded COSE key
+	Algorithm    int       `gorm:"not null"`      // COSE algorithm identifier
+	SignCount    uint32    `gorm:"not null"`
+	Flags        *uint8    // Authenticator data flags as of the last ceremony; nil for passkeys registered before they were kept
+	Transports   []string  `gorm:"serializer:json"`
+	LastUsedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// Relationships
+	ProjectId uuid.UUID `gorm:"type:char(36);not null;index:idx_webauthn_credentials_user"`
+	UserId    uuid.UUID `gorm:"type:char(36);not null;index:idx_webauthn_credentials_user"`
+}
@@ -0,0 +1,28 @@
+package schemas
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook payload formats
+const (
+	WebhookFormatRaw         = "raw"
+	WebhookFormatCloudEvents = "cloudevents"
+)
+
+// WebhookSubscription delivers a project's events to an HTTP endpoint
+type WebhookSubscription struct {
+	ID        uuid.UUID `gorm:"type:char(36);primary_key"`
+	ProjectId uuid.UUID `gorm:"type:char(36);not null;index"`
+	URL       string    `gorm:"size:2048;not null"`
+	Secret    string    `gorm:"size:255;not null"` // HMAC key for the payload signature
+	Events    []string  `gorm:"type:text;serializer:json"`
+	Format    string    `gorm:"size:20;not null;default:raw"`
+	Enabled   bool      `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
@@ -0,0 +1,56 @@
+// Package sorting orders lists by a field the client picks with the sort
+// query parameter: "created_at" sorts oldest first, "-created_at" newest
+// first. Each list declares the fields it can be sorted by, which are
+// column names, and nothing else reaches the query.
+package sorting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownField is an example of the error Parse returns for a field the
+// list cannot be sorted by
+var ErrUnknownField = unknownField("password", []string{"created_at"})
+
+// Order is the order a list is sorted in
+type Order struct {
+	Field string // Column to sort by; empty for the list's default order
+	Desc  bool
+}
+
+// Parse parses a sort parameter against the fields a list can be sorted
+// by. An empty parameter is the zero Order.
+func Parse(value string, fields []string) (Order, error) {
+	if value == "" {
+		return Order{}, nil
+	}
+	order := Order{Field: value}
+	if strings.HasPrefix(value, "-") {
+		order = Order{Field: value[1:], Desc: true}
+	}
+	for _, field := range fields {
+		if order.Field == field {
+			return order, nil
+		}
+	}
+	return Order{}, unknownField(order.Field, fields)
+}
+
+func unknownField(field string, fields []string) *apierrors.Error {
+	return apierrors.BadRequest("INVALID_SORT", fmt.Sprintf("cannot sort by %q; sort by one of %s, prefixed with - for descending order",
+		field, strings.Join(fields, ", ")))
+}
+
+// Apply sorts a query in the order, breaking ties by ID so that the order is
+// stable. The zero Order leaves the query as it is.
+func (o Order) Apply(db *gorm.DB) *gorm.DB {
+	if o.Field == "" {
+		return db
+	}
+	return db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.Field}, Desc: o.Desc}).Order("id")
+}
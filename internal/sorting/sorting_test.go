@@ -0,0 +1,36 @@
+package sorting_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/sorting"
+)
+
+func TestParse(t *testing.T) {
+	fields := []string{"name", "created_at"}
+
+	for _, tc := range []struct {
+		value string
+		want  sorting.Order
+	}{
+		{"", sorting.Order{}},
+		{"name", sorting.Order{Field: "name"}},
+		{"created_at", sorting.Order{Field: "created_at"}},
+		{"-created_at", sorting.Order{Field: "created_at", Desc: true}},
+	} {
+		order, err := sorting.Parse(tc.value, fields)
+		if err != nil || order != tc.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tc.value, order, err, tc.want)
+		}
+	}
+
+	for _, value := range []string{"password", "-password", "Name", "--name", "-", "name desc"} {
+		_, err := sorting.Parse(value, fields)
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != sorting.ErrUnknownField.Code || apiErr.Status != sorting.ErrUnknownField.Status {
+			t.Errorf("Parse(%q) error = %v, want %s", value, err, sorting.ErrUnknownField.Code)
+		}
+	}
+}
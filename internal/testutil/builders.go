@@ -0,0 +1,281 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DefaultPassword is the plain-text password given to users created by the builders
+const DefaultPassword = "password123"
+
+// ProjectBuilder builds a project together with its roles and project users
+type ProjectBuilder struct {
+	name     string
+	uniqueID string
+	roles    []string
+	users    []userSpec
+	region   string
+	usersDB  *gorm.DB // Holds the user table; nil for the database built in
+}
+
+type userSpec struct {
+	email  string
+	role   string
+	status string
+	oauth  string
+}
+
+// BuiltProject is the result of ProjectBuilder.Build
+type BuiltProject struct {
+	Project schemas.Project
+	Roles   map[string]schemas.Role
+	Users   map[string]schemas.ProjectUser
+}
+
+// AProject starts building a project with sensible defaults
+func AProject() *ProjectBuilder {
+	id := uuid.NewString()[:8]
+	return &ProjectBuilder{
+		name:     "Project " + id,
+		uniqueID: "project_" + id,
+	}
+}
+
+// Named sets the project name
+func (b *ProjectBuilder) Named(name string) *ProjectBuilder {
+	b.name = name
+	return b
+}
+
+// WithUniqueID sets the project unique ID
+func (b *ProjectBuilder) WithUniqueID(uniqueID string) *ProjectBuilder {
+	b.uniqueID = uniqueID
+	return b
+}
+
+// WithRole adds a role; users added afterwards are assigned the most recently added role
+func (b *ProjectBuilder) WithRole(name string) *ProjectBuilder {
+	b.roles = append(b.roles, name)
+	return b
+}
+
+// WithUser adds an active password user holding the most recently added role
+func (b *ProjectBuilder) WithUser(email string) *ProjectBuilder {
+	return b.WithUserInStatus(email, schemas.UserStatusActive)
+}
+
+// WithInactiveUser adds a deactivated password user holding the most recently added role
+func (b *ProjectBuilder) WithInactiveUser(email string) *ProjectBuilder {
+	return b.WithUserInStatus(email, schemas.UserStatusDeactivated)
+}
+
+// WithUserInStatus adds a password user in the given status holding the most
+// recently added role
+func (b *ProjectBuilder) WithUserInStatus(email, status string) *ProjectBuilder {
+	b.users = append(b.users, userSpec{email: email, role: b.currentRole(), status: status})
+	return b
+}
+
+// WithOAuthUser adds an active user signed up through the given OAuth provider
+func (b *ProjectBuilder) WithOAuthUser(email, provider string) *ProjectBuilder {
+	b.users = append(b.users, userSpec{email: email, role: b.currentRole(), status: schemas.UserStatusActive, oauth: provider})
+	return b
+}
+
+// InRegion places the project in region, with its user table and users in
+// usersDB, the database of the region
+func (b *ProjectBuilder) InRegion(region string, usersDB *gorm.DB) *ProjectBuilder {
+	b.region = region
+	b.usersDB = usersDB
+	return b
+}
+
+func (b *ProjectBuilder) currentRole() string {
+	if len(b.roles) == 0 {
+		b.roles = append(b.roles, "Member")
+	}
+	return b.roles[len(b.roles)-1]
+}
+
+// Build persists the project, its user table, roles and users
+func (b *ProjectBuilder) Build(t testing.TB, db *gorm.DB) *BuiltProject {
+	t.Helper()
+
+	now := time.Now()
+	built := &BuiltProject{
+		Project: schemas.Project{
+			ID:        uuid.New(),
+			Name:      b.name,
+			UniqueID:  b.uniqueID,
+			Region:    b.region,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Roles: make(map[string]schemas.Role),
+		Users: make(map[string]schemas.ProjectUser),
+	}
+
+	if err := db.Create(&built.Project).Error; err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	usersDB := db
+	if b.usersDB != nil {
+		usersDB = b.usersDB
+	}
+	CreateProjectUserTable(t, usersDB, built.Project.ID)
+
+	for _, name := range b.roles {
+		built.Roles[name] = ARole(name).Build(t, db)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	for _, spec := range b.users {
+		user := schemas.ProjectUser{
+			ID:        uuid.New(),
+			Email:     spec.email,
+			OAuthType: spec.oauth,
+			RoleId:    built.Roles[spec.role].ID,
+			ProjectId: built.Project.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		user.SetStatus(spec.status)
+		if spec.oauth == "" {
+			user.Password = string(hashed)
+		} else {
+			user.OAuthID = uuid.NewString()
+		}
+
+		active := user.Active
+		if err := usersDB.Table(ProjectUserTable(built.Project.ID)).Create(&user).Error; err != nil {
+			t.Fatalf("failed to create project user: %v", err)
+		}
+		// gorm skips zero values on insert, so the active column default wins
+		// and is copied back into user
+		if !active {
+			user.Active = false
+			if err := usersDB.Table(ProjectUserTable(built.Project.ID)).Where("id = ?", user.ID).
+				Update("active", false).Error; err != nil {
+				t.Fatalf("failed to deactivate project user: %v", err)
+			}
+		}
+		built.Users[spec.email] = user
+	}
+
+	return built
+}
+
+// RoleBuilder builds a role
+type RoleBuilder struct {
+	role schemas.Role
+}
+
+// ARole starts building a role with the given name
+func ARole(name string) *RoleBuilder {
+	return &RoleBuilder{role: schemas.Role{
+		ID:         uuid.New(),
+		Name:       name,
+		Expiration: 24 * time.Hour,
+	}}
+}
+
+// WithExpiration sets the token expiration of the role
+func (b *RoleBuilder) WithExpiration(d time.Duration) *RoleBuilder {
+	b.role.Expiration = d
+	return b
+}
+
+// InProject scopes the role to a project; roles are global otherwise
+func (b *RoleBuilder) InProject(id uuid.UUID) *RoleBuilder {
+	b.role.ProjectId = &id
+	return b
+}
+
+// Build persists the role
+func (b *RoleBuilder) Build(t testing.TB, db *gorm.DB) schemas.Role {
+	t.Helper()
+
+	b.role.CreatedAt = time.Now()
+	b.role.UpdatedAt = b.role.CreatedAt
+	if err := db.Create(&b.role).Error; err != nil {
+		t.Fatalf("failed to create role: %v", err)
+	}
+	return b.role
+}
+
+// PolicyBuilder builds a policy
+type PolicyBuilder struct {
+	policy schemas.Policy
+}
+
+// APolicy starts building an allow policy for resource/action
+func APolicy(name, resource, action string) *PolicyBuilder {
+	return &PolicyBuilder{policy: schemas.Policy{
+		ID:       uuid.New(),
+		Name:     name,
+		Resource: resource,
+		Action:   action,
+		Effect:   "allow",
+	}}
+}
+
+// Denying turns the policy into a deny policy
+func (b *PolicyBuilder) Denying() *PolicyBuilder {
+	b.policy.Effect = "deny"
+	return b
+}
+
+// ForRole attaches the policy to a role
+func (b *PolicyBuilder) ForRole(role schemas.Role) *PolicyBuilder {
+	b.policy.RolesId = role.ID
+	return b
+}
+
+// Build persists the policy
+func (b *PolicyBuilder) Build(t testing.TB, db *gorm.DB) schemas.Policy {
+	t.Helper()
+
+	b.policy.CreatedAt = time.Now()
+	b.policy.UpdatedAt = b.policy.CreatedAt
+	if err := db.Create(&b.policy).Error; err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	return b.policy
+}
+
+// AUser builds a global user with DefaultPassword
+func AUser(t testing.TB, db *gorm.DB, email string, role schemas.Role, project schemas.Project) schemas.User {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	now := time.Now()
+	user := schemas.User{
+		ID:             uuid.New(),
+		Email:          email,
+		Password:       string(hashed),
+		Status:         schemas.UserStatusActive,
+		Active:         true,
+		RoleId:         role.ID,
+		ProjectId:      project.ID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		ExpirationTime: now.Add(role.Expiration),
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
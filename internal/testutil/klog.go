@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+// LogBuffer collects klog output written during a test
+type LogBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns everything logged so far
+func (b *LogBuffer) String() string {
+	klog.Flush()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// CaptureKlog redirects klog output into a buffer until the test finishes
+func CaptureKlog(t testing.TB) *LogBuffer {
+	t.Helper()
+
+	buf := &LogBuffer{}
+	klog.LogToStderr(false)
+	klog.SetOutput(buf)
+
+	t.Cleanup(func() {
+		klog.Flush()
+		klog.LogToStderr(true)
+	})
+
+	return buf
+}
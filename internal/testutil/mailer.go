@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yash3004/user_management_service/mailer"
+)
+
+// FakeMailer is a mailer.Mailer that captures messages instead of sending them
+type FakeMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+// NewFakeMailer creates an empty fake mailer
+func NewFakeMailer() *FakeMailer {
+	return &FakeMailer{}
+}
+
+// Send records the message
+func (f *FakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (f *FakeMailer) Messages() []mailer.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]mailer.Message(nil), f.messages...)
+}
+
+// Last returns the most recently sent message, if any
+func (f *FakeMailer) Last() (mailer.Message, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.messages) == 0 {
+		return mailer.Message{}, false
+	}
+	return f.messages[len(f.messages)-1], true
+}
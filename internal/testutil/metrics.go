@@ -0,0 +1,44 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/metrics"
+)
+
+// MetricValue returns the value of one sample of the registered metrics, as
+// written to /metrics, e.g.
+// ums_token_validation_failures_total{reason="expired"}, or 0 if the sample
+// has not been recorded. Metrics are shared by the whole test binary, so
+// tests compare values before and after what they exercise.
+func MetricValue(t testing.TB, sample string) float64 {
+	t.Helper()
+
+	var buf bytes.Buffer
+	metrics.WriteAll(&buf)
+	return SampleValue(t, buf.Bytes(), sample)
+}
+
+// SampleValue returns the value of a sample in a Prometheus text exposition,
+// or 0 if it is not there
+func SampleValue(t testing.TB, exposition []byte, sample string) float64 {
+	t.Helper()
+
+	scanner := bufio.NewScanner(bytes.NewReader(exposition))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), sample+" ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("sample %s has the value %q: %v", sample, value, err)
+		}
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package testutil
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// QueryCounter counts the SELECT queries run through a database
+type QueryCounter struct {
+	n atomic.Int64
+}
+
+// Count returns how many queries have run since the counter was made
+func (c *QueryCounter) Count() int64 {
+	return c.n.Load()
+}
+
+// CountQueries counts the queries run through db until the test finishes
+func CountQueries(t testing.TB, db *gorm.DB) *QueryCounter {
+	t.Helper()
+
+	counter := &QueryCounter{}
+	name := "testutil:count_queries"
+	if err := db.Callback().Query().After("gorm:query").Register(name, func(*gorm.DB) { counter.n.Add(1) }); err != nil {
+		t.Fatalf("failed to count queries: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Callback().Query().Remove(name) })
+	return counter
+}
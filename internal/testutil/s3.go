@@ -0,0 +1,131 @@
+package testutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/clock"
+)
+
+// Credentials and bucket of FakeS3
+const (
+	FakeS3Bucket    = "artifacts"
+	FakeS3AccessKey = "AKIDFAKE"
+	FakeS3SecretKey = "fake-secret"
+)
+
+// FakeS3 is an in-memory S3-compatible service holding one bucket, addressed
+// path style. It checks that requests carry a SigV4 Authorization header for
+// its access key with the hash of their body, and that presigned URLs have not
+// expired by Clock, but not the signatures themselves.
+type FakeS3 struct {
+	URL   string
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// NewFakeS3 starts a fake S3 service, stopped when the test ends
+func NewFakeS3(t testing.TB) *FakeS3 {
+	t.Helper()
+
+	s := &FakeS3{Clock: clock.Real{}, objects: make(map[string][]byte)}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+	return s
+}
+
+// Options returns the options of an S3 store using the fake service
+func (s *FakeS3) Options() blobstore.S3Options {
+	return blobstore.S3Options{
+		Endpoint:        s.URL,
+		Bucket:          FakeS3Bucket,
+		AccessKeyID:     FakeS3AccessKey,
+		SecretAccessKey: FakeS3SecretKey,
+		PathStyle:       true,
+	}
+}
+
+// Object returns the content stored under key, if any
+func (s *FakeS3) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.objects[key]
+	return content, ok
+}
+
+func (s *FakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+FakeS3Bucket+"/")
+	if !ok || key == "" {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if reason := s.denied(r, body); reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+	case http.MethodGet:
+		content, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// denied returns why a request is refused, or "" if it is allowed
+func (s *FakeS3) denied(r *http.Request, body []byte) string {
+	query := r.URL.Query()
+	if signature := query.Get("X-Amz-Signature"); signature != "" {
+		if r.Method != http.MethodGet || !strings.HasPrefix(query.Get("X-Amz-Credential"), FakeS3AccessKey+"/") {
+			return "SignatureDoesNotMatch"
+		}
+		signed, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+		expires, convErr := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || convErr != nil {
+			return "AuthorizationQueryParametersError"
+		}
+		if !s.Clock.Now().Before(signed.Add(time.Duration(expires) * time.Second)) {
+			return "Request has expired"
+		}
+		return ""
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+FakeS3AccessKey+"/") {
+		return "AccessDenied"
+	}
+	hash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		return "XAmzContentSHA256Mismatch"
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/tokenkeys"
+	"gorm.io/gorm"
+)
+
+// RotateTokenKeyRequest represents the rotate token key request
+type RotateTokenKeyRequest struct{}
+
+// RotateTokenKeyResponse represents the rotate token key response
+type RotateTokenKeyResponse struct {
+	Rotation tokenkeys.RotationResult `json:"rotation"`
+}
+
+// GetTemplatesRequest represents the get project templates request
+type GetTemplatesRequest struct{}
+
+// GetTemplatesResponse represents the get project templates response
+type GetTemplatesResponse struct {
+	Project projects.Template `json:"project"`
+}
+
+// GetConfigRequest represents the get configuration request
+type GetConfigRequest struct{}
+
+// GetConfigResponse represents the get configuration response: the
+// configuration the service runs with, keyed as in its YAML file, with
+// secrets masked
+type GetConfigResponse struct {
+	Config map[string]interface{} `json:"config"`
+}
+
+// GetStatsRequest represents the get system stats request
+type GetStatsRequest struct{}
+
+// GetStatsResponse represents the get system stats response. Records count
+// only when not soft-deleted.
+type GetStatsResponse struct {
+	Users         int64       `json:"users"`         // Global users
+	ProjectUsers  int64       `json:"project_users"` // Users of every live project
+	Projects      int64       `json:"projects"`
+	Roles         int64       `json:"roles"`
+	Policies      int64       `json:"policies"`
+	DBPool        DBPoolStats `json:"db_pool"`
+	StartedAt     time.Time   `json:"started_at"`
+	UptimeSeconds int64       `json:"uptime_seconds"`
+}
+
+// DBPoolStats describes the database connection pool
+type DBPoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"` // 0 for no limit
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`       // Connections waited for in all
+	WaitDurationMillis int64 `json:"wait_duration_ms"` // Time spent waiting for them
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// AdminEndpoint handles service-wide maintenance operations
+type AdminEndpoint struct {
+	DB              *gorm.DB
+	Regions         *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	TokenKeyManager tokenkeys.TokenKeyManager
+	ProjectManager  projects.ProjectManager
+	// StartedAt is when the service started, which uptime counts from
+	StartedAt time.Time
+	// Config returns the redacted configuration; it is set at startup
+	Config func() (map[string]interface{}, error)
+}
+
+// NewAdminEndpoint creates a new admin endpoint, counting uptime from now
+func NewAdminEndpoint(db *gorm.DB, regions *regions.Resolver, tokenKeys tokenkeys.TokenKeyManager, projectManager projects.ProjectManager) *AdminEndpoint {
+	return &AdminEndpoint{
+		DB:              db,
+		Regions:         regions,
+		TokenKeyManager: tokenKeys,
+		ProjectManager:  projectManager,
+		StartedAt:       time.Now(),
+	}
+}
+
+// RotateTokenKey re-encrypts stored OAuth tokens under the primary key
+func (e *AdminEndpoint) RotateTokenKey(ctx context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(RotateTokenKeyRequest); !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	result, err := e.TokenKeyManager.Rotate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return RotateTokenKeyResponse{
+		Rotation: *result,
+	}, nil
+}
+
+// GetTemplates returns the RBAC template new projects start with
+func (e *AdminEndpoint) GetTemplates(ctx context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(GetTemplatesRequest); !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	template := e.ProjectManager.Template()
+	template.Roles = nonNil(template.Roles)
+	return GetTemplatesResponse{
+		Project: template,
+	}, nil
+}
+
+// GetConfig returns the configuration the service runs with, secrets masked
+func (e *AdminEndpoint) GetConfig(ctx context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(GetConfigRequest); !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if e.Config == nil {
+		return nil, errors.New("configuration is not available")
+	}
+
+	config, err := e.Config()
+	if err != nil {
+		log.For(ctx).Errorf("Failed to encode the configuration: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return GetConfigResponse{Config: config}, nil
+}
+
+// GetStats returns aggregate counts of the service's records with the state
+// of its database connection pool, for quick checks besides the metrics
+func (e *AdminEndpoint) GetStats(ctx context.Context, request interface{}) (interface{}, error) {
+	if _, ok := request.(GetStatsRequest); !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	var stats GetStatsResponse
+	db := e.DB.WithContext(ctx)
+	for _, count := range []struct {
+		model interface{}
+		into  *int64
+	}{
+		{&schemas.User{}, &stats.Users},
+		{&schemas.Project{}, &stats.Projects},
+		{&schemas.Role{}, &stats.Roles},
+		{&schemas.Policy{}, &stats.Policies},
+	} {
+		if err := db.Model(count.model).Count(count.into).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, errors.New("internal server error")
+		}
+	}
+
+	// Each project's users are counted in the database of its region
+	var projects []schemas.Project
+	if err := db.Select("id", "region").Find(&projects).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	for _, project := range projects {
+		var users int64
+		if err := e.Regions.For(db, project.Region).Table(projecttable.Users(project.ID)).Where("deleted_at IS NULL").Count(&users).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, errors.New("internal server error")
+		}
+		stats.ProjectUsers += users
+	}
+
+	sqlDB, err := e.DB.DB()
+	if err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	pool := sqlDB.Stats()
+	stats.DBPool = DBPoolStats{
+		MaxOpenConnections: pool.MaxOpenConnections,
+		OpenConnections:    pool.OpenConnections,
+		InUse:              pool.InUse,
+		Idle:               pool.Idle,
+		WaitCount:          pool.WaitCount,
+		WaitDurationMillis: pool.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      pool.MaxIdleClosed,
+		MaxIdleTimeClosed:  pool.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  pool.MaxLifetimeClosed,
+	}
+
+	stats.StartedAt = e.StartedAt
+	stats.UptimeSeconds = int64(time.Since(e.StartedAt).Seconds())
+	return stats, nil
+}
@@ -0,0 +1,133 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/tokenkeys"
+)
+
+func TestRotateTokenKey(t *testing.T) {
+	keys := &mocks.TokenKeyManager{
+		RotateFunc: func(context.Context) (*tokenkeys.RotationResult, error) {
+			return &tokenkeys.RotationResult{KeyID: "k2", Tables: 3, Rotated: 5, Failed: 1}, nil
+		},
+	}
+	endpoint := endpoints.NewAdminEndpoint(nil, nil, keys, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.RotateTokenKey(ctx, endpoints.RotateTokenKeyRequest{})
+	if err != nil {
+		t.Fatalf("RotateTokenKey: %v", err)
+	}
+	want := tokenkeys.RotationResult{KeyID: "k2", Tables: 3, Rotated: 5, Failed: 1}
+	if got := response.(endpoints.RotateTokenKeyResponse).Rotation; got != want {
+		t.Fatalf("rotation = %+v, want %+v", got, want)
+	}
+
+	failure := errors.New("no keys")
+	keys.RotateFunc = func(context.Context) (*tokenkeys.RotationResult, error) { return nil, failure }
+	if _, err := endpoint.RotateTokenKey(ctx, endpoints.RotateTokenKeyRequest{}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RotateTokenKey(ctx, r) })
+}
+
+func TestGetTemplates(t *testing.T) {
+	manager := &mocks.ProjectManager{TemplateFunc: func() projects.Template { return projects.Template{} }}
+	endpoint := endpoints.NewAdminEndpoint(nil, nil, nil, manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetTemplates(ctx, endpoints.GetTemplatesRequest{})
+	if err != nil {
+		t.Fatalf("GetTemplates: %v", err)
+	}
+	if roles := response.(endpoints.GetTemplatesResponse).Project.Roles; roles == nil {
+		t.Fatal("a template without roles lists them as null rather than an empty list")
+	}
+
+	manager.TemplateFunc = func() projects.Template {
+		return projects.Template{Roles: []projects.RoleTemplate{{Name: "admin", Expiration: time.Hour}}}
+	}
+	response, err = endpoint.GetTemplates(ctx, endpoints.GetTemplatesRequest{})
+	if err != nil {
+		t.Fatalf("GetTemplates: %v", err)
+	}
+	if roles := response.(endpoints.GetTemplatesResponse).Project.Roles; len(roles) != 1 || roles[0].Name != "admin" {
+		t.Fatalf("roles = %+v", roles)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetTemplates(ctx, r) })
+}
+
+func TestGetConfig(t *testing.T) {
+	endpoint := endpoints.NewAdminEndpoint(nil, nil, nil, nil)
+	ctx := context.Background()
+	if _, err := endpoint.GetConfig(ctx, endpoints.GetConfigRequest{}); err == nil {
+		t.Fatal("GetConfig succeeded before the configuration was set")
+	}
+
+	endpoint.Config = func() (map[string]interface{}, error) {
+		return map[string]interface{}{"database": map[string]interface{}{"password": "***"}}, nil
+	}
+	response, err := endpoint.GetConfig(ctx, endpoints.GetConfigRequest{})
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if _, ok := response.(endpoints.GetConfigResponse).Config["database"]; !ok {
+		t.Fatalf("config = %+v", response)
+	}
+
+	testutil.CaptureKlog(t)
+	endpoint.Config = func() (map[string]interface{}, error) { return nil, errors.New("boom") }
+	if _, err := endpoint.GetConfig(ctx, endpoints.GetConfigRequest{}); err == nil || err.Error() != "internal server error" {
+		t.Fatalf("err = %v, want the internal server error", err)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetConfig(ctx, r) })
+}
+
+func TestGetStatsCountsProjectUsersInEveryRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	testutil.AProject().WithUser("primary@example.com").Build(t, db)
+	testutil.AProject().InRegion("eu", databases["eu"]).
+		WithUser("a@example.com").WithUser("b@example.com").Build(t, db)
+
+	endpoint := endpoints.NewAdminEndpoint(db, resolver, nil, nil)
+	response, err := endpoint.GetStats(context.Background(), endpoints.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	stats := response.(endpoints.GetStatsResponse)
+	if stats.Projects != 2 || stats.ProjectUsers != 3 {
+		t.Fatalf("stats = %+v, want 2 projects with 3 users", stats)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+	testutil.AUser(t, db, "global@example.com", built.Roles["member"], built.Project)
+
+	endpoint := endpoints.NewAdminEndpoint(db, nil, nil, nil)
+	endpoint.StartedAt = time.Now().Add(-time.Minute)
+	ctx := context.Background()
+
+	response, err := endpoint.GetStats(ctx, endpoints.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	stats := response.(endpoints.GetStatsResponse)
+	if stats.Users != 1 || stats.Projects != 1 || stats.ProjectUsers != 1 || stats.Roles == 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats.UptimeSeconds < 60 || !stats.StartedAt.Equal(endpoint.StartedAt) {
+		t.Fatalf("uptime = %ds since %v", stats.UptimeSeconds, stats.StartedAt)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetStats(ctx, r) })
+}
@@ -0,0 +1,198 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// APIToken represents a project API token in the response
+type APIToken struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	// ServiceAccountID is the service account the token authenticates as;
+	// unset for tokens of the project alone
+	ServiceAccountID string     `json:"service_account_id,omitempty"`
+	Token            string     `json:"token,omitempty"` // Only returned on creation
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// CreateAPITokenRequest represents the create API token request
+type CreateAPITokenRequest struct {
+	ProjectID string `json:"-"` // From URL path
+	Name      string `json:"name"`
+	// ServiceAccountID issues the token to a service account of the project
+	ServiceAccountID string `json:"service_account_id,omitempty"`
+}
+
+// CreateAPITokenResponse represents the create API token response
+type CreateAPITokenResponse struct {
+	Token APIToken `json:"token"`
+}
+
+// ListAPITokensRequest represents the list API tokens request
+type ListAPITokensRequest struct {
+	ProjectID string `json:"-"`
+}
+
+// ListAPITokensResponse represents the list API tokens response
+type ListAPITokensResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// RevokeAPITokenRequest represents the revoke API token request
+type RevokeAPITokenRequest struct {
+	ProjectID string `json:"-"`
+	ID        string `json:"-"`
+}
+
+// RevokeAPITokenResponse represents the revoke API token response
+type RevokeAPITokenResponse struct {
+	Success bool `json:"success"`
+}
+
+// APITokensEndpoint handles project API token endpoints
+type APITokensEndpoint struct {
+	APITokenManager apitokens.APITokenManager
+	// SuperAdmin reports whether a caller is a super admin. Only they manage
+	// service accounts, so only they may issue tokens to one; when nil, no
+	// one may.
+	SuperAdmin func(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// NewAPITokensEndpoint creates a new API tokens endpoint
+func NewAPITokensEndpoint(manager apitokens.APITokenManager) *APITokensEndpoint {
+	return &APITokensEndpoint{
+		APITokenManager: manager,
+	}
+}
+
+// CreateAPIToken issues an API token for a project
+func (e *APITokensEndpoint) CreateAPIToken(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CreateAPITokenRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	var serviceAccountID *uuid.UUID
+	if req.ServiceAccountID != "" {
+		id, err := uuid.Parse(req.ServiceAccountID)
+		if err != nil {
+			return nil, apitokens.ErrServiceAccountNotFound
+		}
+		serviceAccountID = &id
+		if err := e.checkServiceAccountAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	record, token, err := e.APITokenManager.CreateToken(ctx, projectID, req.Name, serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toAPIToken(*record)
+	resp.Token = token
+	return CreateAPITokenResponse{
+		Token: resp,
+	}, nil
+}
+
+// checkServiceAccountAdmin fails unless the caller may manage service
+// accounts. A token issued to one acts with the service account's role,
+// which may be a more privileged one than the caller's.
+func (e *APITokensEndpoint) checkServiceAccountAdmin(ctx context.Context) error {
+	caller := audit.ActorID(ctx)
+	if caller == nil || e.SuperAdmin == nil {
+		return apitokens.ErrServiceAccountTokenForbidden
+	}
+	superAdmin, err := e.SuperAdmin(ctx, *caller)
+	if err != nil {
+		return errors.New("internal server error")
+	}
+	if !superAdmin {
+		return apitokens.ErrServiceAccountTokenForbidden
+	}
+	return nil
+}
+
+// ListAPITokens lists the API tokens of a project
+func (e *APITokensEndpoint) ListAPITokens(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListAPITokensRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	tokens, err := e.APITokenManager.ListTokens(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]APIToken, len(tokens))
+	for i, token := range tokens {
+		list[i] = toAPIToken(token)
+	}
+
+	return ListAPITokensResponse{
+		Tokens: list,
+	}, nil
+}
+
+// RevokeAPIToken revokes a project API token
+func (e *APITokensEndpoint) RevokeAPIToken(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RevokeAPITokenRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	tokenID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid token ID format")
+	}
+
+	if err := e.APITokenManager.RevokeToken(ctx, projectID, tokenID); err != nil {
+		return nil, err
+	}
+
+	return RevokeAPITokenResponse{
+		Success: true,
+	}, nil
+}
+
+func toAPIToken(token schemas.ProjectAPIToken) APIToken {
+	resp := APIToken{
+		ID:         token.ID.String(),
+		ProjectID:  token.ProjectId.String(),
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+	if token.ServiceAccountId != nil {
+		resp.ServiceAccountID = token.ServiceAccountId.String()
+	}
+	return resp
+}
@@ -0,0 +1,148 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestCreateAPIToken(t *testing.T) {
+	projectID, accountID := uuid.New(), uuid.New()
+	at := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	record := schemas.ProjectAPIToken{ID: uuid.New(), Name: "ci", Prefix: "umt_abc", CreatedAt: at, ProjectId: projectID, ServiceAccountId: &accountID}
+	manager := &mocks.APITokenManager{
+		CreateTokenFunc: func(_ context.Context, pid uuid.UUID, name string, account *uuid.UUID) (*schemas.ProjectAPIToken, string, error) {
+			if pid != projectID || name != "ci" || account == nil || *account != accountID {
+				t.Errorf("CreateToken(%v, %q, %v)", pid, name, account)
+			}
+			return &record, "umt_abc.secret", nil
+		},
+	}
+	endpoint := endpoints.NewAPITokensEndpoint(manager)
+	rootID := uuid.New()
+	endpoint.SuperAdmin = func(_ context.Context, userID uuid.UUID) (bool, error) { return userID == rootID, nil }
+	ctx := audit.WithActor(context.Background(), rootID.String())
+
+	response, err := endpoint.CreateAPIToken(ctx, endpoints.CreateAPITokenRequest{ProjectID: projectID.String(), Name: "ci", ServiceAccountID: accountID.String()})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	want := endpoints.APIToken{
+		ID:               record.ID.String(),
+		ProjectID:        projectID.String(),
+		Name:             "ci",
+		Prefix:           "umt_abc",
+		ServiceAccountID: accountID.String(),
+		Token:            "umt_abc.secret",
+		CreatedAt:        at,
+	}
+	if got := response.(endpoints.CreateAPITokenResponse).Token; got != want {
+		t.Fatalf("token = %+v, want %+v", got, want)
+	}
+
+	_, err = endpoint.CreateAPIToken(ctx, endpoints.CreateAPITokenRequest{ProjectID: projectID.String(), ServiceAccountID: "nope"})
+	if err != apitokens.ErrServiceAccountNotFound {
+		t.Fatalf("err = %v, want %v", err, apitokens.ErrServiceAccountNotFound)
+	}
+	for name, caller := range map[string]context.Context{
+		"an anonymous caller":               context.Background(),
+		"a caller who is not a super admin": audit.WithActor(context.Background(), uuid.NewString()),
+	} {
+		_, err = endpoint.CreateAPIToken(caller, endpoints.CreateAPITokenRequest{ProjectID: projectID.String(), Name: "ci", ServiceAccountID: accountID.String()})
+		if err != apitokens.ErrServiceAccountTokenForbidden {
+			t.Errorf("a token for a service account issued by %s: err = %v, want %v", name, err, apitokens.ErrServiceAccountTokenForbidden)
+		}
+	}
+	if _, err := endpoint.CreateAPIToken(ctx, endpoints.CreateAPITokenRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("CreateAPIToken accepted a malformed project ID")
+	}
+
+	failure := errors.New("boom")
+	manager.CreateTokenFunc = func(context.Context, uuid.UUID, string, *uuid.UUID) (*schemas.ProjectAPIToken, string, error) {
+		return nil, "", failure
+	}
+	if _, err := endpoint.CreateAPIToken(ctx, endpoints.CreateAPITokenRequest{ProjectID: projectID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateAPIToken(ctx, r) })
+}
+
+func TestListAPITokens(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.APITokenManager{
+		ListTokensFunc: func(_ context.Context, pid uuid.UUID) ([]schemas.ProjectAPIToken, error) {
+			return []schemas.ProjectAPIToken{{ID: uuid.New(), Name: "ci", ProjectId: pid}}, nil
+		},
+	}
+	endpoint := endpoints.NewAPITokensEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListAPITokens(ctx, endpoints.ListAPITokensRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("ListAPITokens: %v", err)
+	}
+	tokens := response.(endpoints.ListAPITokensResponse).Tokens
+	if len(tokens) != 1 || tokens[0].Name != "ci" || tokens[0].ProjectID != projectID.String() || tokens[0].Token != "" || tokens[0].ServiceAccountID != "" {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+
+	manager.ListTokensFunc = func(context.Context, uuid.UUID) ([]schemas.ProjectAPIToken, error) { return nil, nil }
+	response, err = endpoint.ListAPITokens(ctx, endpoints.ListAPITokensRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("ListAPITokens: %v", err)
+	}
+	if tokens := response.(endpoints.ListAPITokensResponse).Tokens; tokens == nil {
+		t.Fatal("no tokens are listed as null rather than an empty list")
+	}
+	if _, err := endpoint.ListAPITokens(ctx, endpoints.ListAPITokensRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("ListAPITokens accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListAPITokens(ctx, r) })
+}
+
+func TestRevokeAPIToken(t *testing.T) {
+	projectID, tokenID := uuid.New(), uuid.New()
+	var revoked uuid.UUID
+	manager := &mocks.APITokenManager{
+		RevokeTokenFunc: func(_ context.Context, pid, id uuid.UUID) error {
+			if pid != projectID {
+				t.Errorf("project = %v, want %v", pid, projectID)
+			}
+			revoked = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewAPITokensEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.RevokeAPIToken(ctx, endpoints.RevokeAPITokenRequest{ProjectID: projectID.String(), ID: tokenID.String()})
+	if err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if !response.(endpoints.RevokeAPITokenResponse).Success || revoked != tokenID {
+		t.Fatalf("revoked %v, response %+v", revoked, response)
+	}
+
+	for _, bad := range []endpoints.RevokeAPITokenRequest{
+		{ProjectID: "nope", ID: tokenID.String()},
+		{ProjectID: projectID.String(), ID: "nope"},
+	} {
+		if _, err := endpoint.RevokeAPIToken(ctx, bad); err == nil {
+			t.Errorf("RevokeAPIToken(%+v) accepted a malformed ID", bad)
+		}
+	}
+	failure := errors.New("boom")
+	manager.RevokeTokenFunc = func(context.Context, uuid.UUID, uuid.UUID) error { return failure }
+	if _, err := endpoint.RevokeAPIToken(ctx, endpoints.RevokeAPITokenRequest{ProjectID: projectID.String(), ID: tokenID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RevokeAPIToken(ctx, r) })
+}
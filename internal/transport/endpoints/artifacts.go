@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/blobstore"
+)
+
+// artifactDownloadPath is where artifacts are streamed through the API when
+// the storage backend cannot sign URLs
+const artifactDownloadPath = "/api/v1/admin/artifacts/"
+
+// DownloadArtifactRequest represents the download artifact request
+type DownloadArtifactRequest struct {
+	Key string `json:"key"`
+}
+
+// DownloadArtifactResponse carries an artifact to be streamed to the client,
+// or the signed URL the client is redirected to
+type DownloadArtifactResponse struct {
+	Filename    string
+	Content     io.ReadCloser
+	RedirectURL string
+}
+
+// ArtifactsEndpoint handles downloads of stored artifacts
+type ArtifactsEndpoint struct {
+	ArtifactManager artifacts.ArtifactManager
+}
+
+// NewArtifactsEndpoint creates a new artifacts endpoint
+func NewArtifactsEndpoint(manager artifacts.ArtifactManager) *ArtifactsEndpoint {
+	return &ArtifactsEndpoint{
+		ArtifactManager: manager,
+	}
+}
+
+// DownloadArtifact redirects to a signed URL of the artifact, or streams it
+// when the storage backend cannot sign URLs
+func (e *ArtifactsEndpoint) DownloadArtifact(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(DownloadArtifactRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if err := blobstore.ValidateKey(req.Key); err != nil {
+		return nil, artifacts.ErrArtifactNotFound
+	}
+
+	url, err := e.ArtifactManager.SignedURL(ctx, req.Key)
+	if err == nil {
+		return DownloadArtifactResponse{RedirectURL: url}, nil
+	}
+	if !errors.Is(err, blobstore.ErrSignedURLNotSupported) {
+		return nil, err
+	}
+
+	content, err := e.ArtifactManager.Open(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	return DownloadArtifactResponse{
+		Filename: path.Base(req.Key),
+		Content:  content,
+	}, nil
+}
+
+// artifactURL is where a client downloads an artifact: a signed URL when the
+// storage backend supports them, otherwise the API download route
+func artifactURL(ctx context.Context, manager artifacts.ArtifactManager, key string) string {
+	url, err := manager.SignedURL(ctx, key)
+	if err != nil {
+		return artifactDownloadPath + key
+	}
+	return url
+}
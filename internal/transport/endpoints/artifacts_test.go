@@ -0,0 +1,78 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestDownloadArtifactRedirectsToASignedURL(t *testing.T) {
+	manager := &mocks.ArtifactManager{
+		SignedURLFunc: func(_ context.Context, key string) (string, error) {
+			return "https://blobs.example.com/" + key + "?sig=1", nil
+		},
+	}
+	endpoint := endpoints.NewArtifactsEndpoint(manager)
+
+	response, err := endpoint.DownloadArtifact(context.Background(), endpoints.DownloadArtifactRequest{Key: "exports/a.csv"})
+	if err != nil {
+		t.Fatalf("DownloadArtifact: %v", err)
+	}
+	download := response.(endpoints.DownloadArtifactResponse)
+	if download.RedirectURL != "https://blobs.example.com/exports/a.csv?sig=1" || download.Content != nil {
+		t.Fatalf("download = %+v", download)
+	}
+}
+
+func TestDownloadArtifactStreamsWithoutSignedURLs(t *testing.T) {
+	manager := &mocks.ArtifactManager{
+		SignedURLFunc: func(context.Context, string) (string, error) { return "", blobstore.ErrSignedURLNotSupported },
+		OpenFunc: func(_ context.Context, key string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("id,email\n")), nil
+		},
+	}
+	endpoint := endpoints.NewArtifactsEndpoint(manager)
+
+	response, err := endpoint.DownloadArtifact(context.Background(), endpoints.DownloadArtifactRequest{Key: "exports/a.csv"})
+	if err != nil {
+		t.Fatalf("DownloadArtifact: %v", err)
+	}
+	download := response.(endpoints.DownloadArtifactResponse)
+	if download.Filename != "a.csv" || download.RedirectURL != "" {
+		t.Fatalf("download = %+v", download)
+	}
+	data, _ := io.ReadAll(download.Content)
+	if string(data) != "id,email\n" {
+		t.Fatalf("content = %q", data)
+	}
+}
+
+func TestDownloadArtifactErrors(t *testing.T) {
+	failure := errors.New("boom")
+	manager := &mocks.ArtifactManager{
+		SignedURLFunc: func(context.Context, string) (string, error) { return "", failure },
+	}
+	endpoint := endpoints.NewArtifactsEndpoint(manager)
+	ctx := context.Background()
+
+	if _, err := endpoint.DownloadArtifact(ctx, endpoints.DownloadArtifactRequest{Key: "../secret"}); err != artifacts.ErrArtifactNotFound {
+		t.Fatalf("err = %v, want %v for an unclean key", err, artifacts.ErrArtifactNotFound)
+	}
+	if _, err := endpoint.DownloadArtifact(ctx, endpoints.DownloadArtifactRequest{Key: "exports/a.csv"}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	manager.SignedURLFunc = func(context.Context, string) (string, error) { return "", blobstore.ErrSignedURLNotSupported }
+	manager.OpenFunc = func(context.Context, string) (io.ReadCloser, error) { return nil, artifacts.ErrArtifactNotFound }
+	if _, err := endpoint.DownloadArtifact(ctx, endpoints.DownloadArtifactRequest{Key: "exports/a.csv"}); err != artifacts.ErrArtifactNotFound {
+		t.Fatalf("err = %v, want %v", err, artifacts.ErrArtifactNotFound)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DownloadArtifact(ctx, r) })
+}
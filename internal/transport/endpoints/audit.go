@@ -0,0 +1,129 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// AuditEvent represents an audit event in the response
+type AuditEvent struct {
+	ID           string    `json:"id"`
+	ProjectID    string    `json:"project_id,omitempty"`
+	Actor        string    `json:"actor"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Details      string    `json:"details,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	ServiceVersion string `json:"service_version,omitempty"` // Empty for events recorded before versions were tracked
+}
+
+// ListAuditEventsRequest represents the list audit events request. From and
+// To are RFC 3339 timestamps and both bounds are inclusive.
+type ListAuditEventsRequest struct {
+	From         string
+	To           string
+	Actor        string
+	ResourceType string
+	Action       string
+	Query        string // Free-text search of details, resource ID and actor
+	ProjectID    string
+	Page         int
+	PageSize     int
+}
+
+// ListAuditEventsResponse represents the list audit events response
+type ListAuditEventsResponse struct {
+	Events   []AuditEvent `json:"events"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+// AuditEndpoint handles audit-related endpoints
+type AuditEndpoint struct {
+	AuditManager audit.AuditManager
+}
+
+// NewAuditEndpoint creates a new audit endpoint
+func NewAuditEndpoint(manager audit.AuditManager) *AuditEndpoint {
+	return &AuditEndpoint{
+		AuditManager: manager,
+	}
+}
+
+// ListAuditEvents lists audit events, newest first
+func (e *AuditEndpoint) ListAuditEvents(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListAuditEventsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	from, err := parseOptionalTime("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseOptionalTime("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := parseOptionalUUID(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	page, err := e.AuditManager.ListEvents(ctx, audit.Filter{
+		ProjectID:    projectID,
+		From:         from,
+		To:           to,
+		Actor:        req.Actor,
+		ResourceType: req.ResourceType,
+		Action:       req.Action,
+		Query:        req.Query,
+		Page:         req.Page,
+		PageSize:     req.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]AuditEvent, 0, len(page.Events))
+	for _, event := range page.Events {
+		events = append(events, AuditEvent{
+			ID:           event.ID.String(),
+			ProjectID:    optionalUUIDString(event.ProjectId),
+			Actor:        event.Actor,
+			Action:       event.Action,
+			ResourceType: event.ResourceType,
+			ResourceID:   event.ResourceID,
+			Details:      event.Details,
+			CreatedAt:    event.CreatedAt,
+
+			ServiceVersion: event.ServiceVersion,
+		})
+	}
+
+	return ListAuditEventsResponse{
+		Events:   events,
+		Total:    page.Total,
+		Page:     page.Page,
+		PageSize: page.PageSize,
+	}, nil
+}
+
+// parseOptionalTime parses an optional RFC 3339 timestamp, returning nil when it is empty
+func parseOptionalTime(name, s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, apierrors.BadRequest("INVALID_TIMESTAMP", name+" must be an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
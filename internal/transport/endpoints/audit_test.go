@@ -0,0 +1,80 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	projectID := uuid.New()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := schemas.AuditEvent{
+		ID:             uuid.New(),
+		ProjectId:      &projectID,
+		Actor:          "admin",
+		Action:         "create",
+		ResourceType:   "role",
+		ResourceID:     "editor",
+		Details:        "created",
+		CreatedAt:      at,
+		ServiceVersion: "1.2.3",
+	}
+	manager := &mocks.AuditManager{
+		ListEventsFunc: func(_ context.Context, filter audit.Filter) (*audit.Page, error) {
+			if filter.ProjectID == nil || *filter.ProjectID != projectID || filter.From == nil || !filter.From.Equal(at) ||
+				filter.To != nil || filter.Actor != "admin" || filter.Query != "created" || filter.Page != 2 || filter.PageSize != 10 {
+				t.Errorf("filter = %+v", filter)
+			}
+			return &audit.Page{Events: []schemas.AuditEvent{event, {ID: uuid.New()}}, Total: 12, Page: 2, PageSize: 10}, nil
+		},
+	}
+	endpoint := endpoints.NewAuditEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListAuditEvents(ctx, endpoints.ListAuditEventsRequest{
+		From:      at.Format(time.RFC3339),
+		Actor:     "admin",
+		Query:     "created",
+		ProjectID: projectID.String(),
+		Page:      2,
+		PageSize:  10,
+	})
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	page := response.(endpoints.ListAuditEventsResponse)
+	if page.Total != 12 || page.Page != 2 || page.PageSize != 10 || len(page.Events) != 2 {
+		t.Fatalf("page = %+v", page)
+	}
+	want := endpoints.AuditEvent{
+		ID:             event.ID.String(),
+		ProjectID:      projectID.String(),
+		Actor:          "admin",
+		Action:         "create",
+		ResourceType:   "role",
+		ResourceID:     "editor",
+		Details:        "created",
+		CreatedAt:      at,
+		ServiceVersion: "1.2.3",
+	}
+	if page.Events[0] != want {
+		t.Fatalf("event = %+v, want %+v", page.Events[0], want)
+	}
+	if page.Events[1].ProjectID != "" {
+		t.Fatalf("an event outside a project has project %q", page.Events[1].ProjectID)
+	}
+
+	_, err = endpoint.ListAuditEvents(ctx, endpoints.ListAuditEventsRequest{To: "yesterday"})
+	wantCode(t, err, "INVALID_TIMESTAMP")
+	if _, err := endpoint.ListAuditEvents(ctx, endpoints.ListAuditEventsRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("ListAuditEvents accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListAuditEvents(ctx, r) })
+}
@@ -3,21 +3,49 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"net/http"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
 	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/projecttable"
 	"github.com/yash3004/user_management_service/internal/schemas"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
 type AuthEndpoint struct {
 	DB *gorm.DB
+	// Passwords verifies login passwords and re-hashes those stored with an
+	// outdated algorithm; nil means bcrypt
+	Passwords *password.Hasher
+	// Clock tells whether passwords have expired; nil means the system time
+	Clock clock.Clock
+	// Consents tells which documents users logging in have yet to accept;
+	// nil flags none
+	Consents consents.ConsentManager
 }
 
+// PasswordChangeTokenTTL is how long the token issued for an expired
+// password lasts, at most
+const PasswordChangeTokenTTL = auth.PasswordChangeTokenTTL
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// ProjectID picks the user when emails are unique per project and the
+	// email belongs to users in several projects
+	ProjectID string `json:"project_id,omitempty"`
+	// Remember keeps the session cookie set on login for the configured
+	// duration; otherwise it lasts until the browser closes
+	Remember bool `json:"remember,omitempty"`
 }
 
 type LoginResponse struct {
@@ -27,49 +55,143 @@ type LoginResponse struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Role      string `json:"role"`
+	// PasswordExpired is set when the password is past its maximum age. The
+	// token then only lets the user change it, through POST /api/v1/me/password.
+	PasswordExpired bool `json:"password_expired"`
+	// PasswordExpiresAt is when the password expired or will expire; unset
+	// when passwords do not expire
+	PasswordExpiresAt *time.Time `json:"password_expires_at,omitempty"`
+	consentsPending
+	// Remember is the request's Remember, for the transport setting the
+	// session cookie
+	Remember bool `json:"-"`
 }
 
+// ErrInvalidCredentials is returned for an unknown email or a wrong password
+var ErrInvalidCredentials = apierrors.New(http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid email or password")
+
+// ErrProjectRequired is returned for a login without a project ID whose email
+// belongs to users in several projects
+var ErrProjectRequired = apierrors.BadRequest("PROJECT_REQUIRED", "this email is used in several projects; pass project_id to log in")
+
 func (e *AuthEndpoint) Login(ctx context.Context, request interface{}) (interface{}, error) {
 	req, ok := request.(LoginRequest)
 	if !ok {
 		return nil, errors.New("invalid request format")
 	}
 
-	var user schemas.User
-	if err := e.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid email or password")
+	response, err := e.login(ctx, req)
+	auth.RecordLogin(auth.MethodPassword, auth.NoProject, err)
+	return response, err
+}
+
+// login checks the credentials of a global user and issues a token, if the
+// user's role may be used from the client's address
+func (e *AuthEndpoint) login(ctx context.Context, req LoginRequest) (interface{}, error) {
+	// Service accounts have no password and only authenticate with API tokens
+	query := e.DB.Where("email = ? AND type = ?", req.Email, schemas.UserTypeHuman)
+	if req.ProjectID != "" {
+		projectID, err := uuid.Parse(req.ProjectID)
+		if err != nil {
+			return nil, projecttable.ErrInvalidProjectID
 		}
-		klog.Errorf("Database error: %v", err)
+		query = query.Where("project_id = ?", projectID)
+	}
+	// With emails unique per project the email may match a user in each
+	var matches []schemas.User
+	if err := query.Limit(2).Find(&matches).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrInvalidCredentials
+	case 2:
+		return nil, ErrProjectRequired
+	}
+	user := matches[0]
 
-	if !user.Active {
-		return nil, errors.New("account is inactive")
+	if !userstatus.CanLogin(user.Status) {
+		return nil, userstatus.LoginError(user.Status)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+	passwords := e.Passwords
+	if passwords == nil {
+		passwords = password.Default()
+	}
+	ok, rehash := passwords.Verify(user.Password, req.Password)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if rehash {
+		e.upgradePassword(ctx, &user, req.Password, passwords)
 	}
 
 	var role schemas.Role
 	if err := e.DB.First(&role, "id = ?", user.RoleId).Error; err != nil {
-		klog.Errorf("Error fetching role: %v", err)
+		log.For(ctx).Errorf("Error fetching role: %v", err)
 		return nil, errors.New("internal server error")
 	}
-
-	token, err := auth.GenerateToken(user.ID, user.Email, role.ID, user.ProjectId, user.ExpirationTime)
-	if err != nil {
-		klog.Errorf("Error generating token: %v", err)
-		return nil, errors.New("failed to generate authentication token")
+	if err := roles.CheckIP(&role, logins.ClientFromContext(ctx).IPAddress); err != nil {
+		return nil, err
 	}
 
-	return LoginResponse{
-		Token:     token,
+	response := LoginResponse{
 		UserID:    user.ID.String(),
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
 		Role:      role.Name,
-	}, nil
+		Remember:  req.Remember,
+	}
+
+	passwordExpiry, err := users.PasswordExpiry(e.DB, passwords, &user)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, scope := user.ExpirationTime, ""
+	if !passwordExpiry.IsZero() {
+		response.PasswordExpiresAt = &passwordExpiry
+		now := e.now()
+		if !now.Before(passwordExpiry) {
+			response.PasswordExpired = true
+			scope = auth.ScopePasswordChange
+			if limit := now.Add(PasswordChangeTokenTTL); limit.Before(expiresAt) {
+				expiresAt = limit
+			}
+		}
+	}
+
+	response.OutstandingConsents, err = outstandingConsents(ctx, e.Consents, user.ProjectId.String(), user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	response.Token, err = auth.GenerateScopedToken(user.ID, user.Email, role.ID, user.ProjectId, expiresAt, scope)
+	if err != nil {
+		log.For(ctx).Errorf("Error generating token: %v", err)
+		return nil, errors.New("failed to generate authentication token")
+	}
+	return response, nil
+}
+
+func (e *AuthEndpoint) now() time.Time {
+	if e.Clock == nil {
+		return time.Now()
+	}
+	return e.Clock.Now()
+}
+
+// upgradePassword replaces the user's stored hash with one made by the
+// configured algorithm. The login already succeeded, so failures are only
+// logged and the old hash stays usable.
+func (e *AuthEndpoint) upgradePassword(ctx context.Context, user *schemas.User, plain string, passwords *password.Hasher) {
+	hash, err := passwords.Hash(plain)
+	if err != nil {
+		log.For(ctx).Errorf("Failed to re-hash password: %v", err)
+		return
+	}
+	if err := e.DB.Model(user).UpdateColumn("password", hash).Error; err != nil {
+		log.For(ctx).Errorf("Failed to upgrade password hash: %v", err)
+	}
 }
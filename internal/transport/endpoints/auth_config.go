@@ -0,0 +1,130 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// GetAuthConfigRequest represents the request for a project's login page configuration
+type GetAuthConfigRequest struct {
+	ProjectID string `json:"-"` // From URL path
+}
+
+// AuthConfigResponse represents what a hosted login page needs to render
+// for a project. Methods the project has not enabled are left out.
+type AuthConfigResponse struct {
+	ProjectID     uuid.UUID               `json:"project_id"`
+	Branding      schemas.ProjectBranding `json:"branding"`
+	SignupEnabled bool                    `json:"signup_enabled"`
+	Methods       AuthMethods             `json:"methods"`
+}
+
+// AuthMethods lists the login methods enabled for a project
+type AuthMethods struct {
+	MagicLink *MagicLinkMethod `json:"magic_link,omitempty"`
+	Passkey   *PasskeyMethod   `json:"passkey,omitempty"`
+	OAuth     []OAuthMethod    `json:"oauth,omitempty"`
+}
+
+// MagicLinkMethod is where a login page requests a magic link
+type MagicLinkMethod struct {
+	RequestURL string `json:"request_url"`
+}
+
+// PasskeyMethod is where a login page begins a passkey login
+type PasskeyMethod struct {
+	BeginURL string `json:"begin_url"`
+	RPID     string `json:"rp_id"`
+}
+
+// OAuthMethod is an OAuth provider's login link. The URL carries a state
+// token of its own, valid for ExpiresIn seconds and usable once. Scopes are
+// what the provider will ask the user to grant.
+type OAuthMethod struct {
+	Provider  string   `json:"provider"`
+	LoginURL  string   `json:"login_url"`
+	ExpiresIn int64    `json:"expires_in"`
+	Scopes    []string `json:"scopes"`
+}
+
+// AuthConfigEndpoint serves the login page configuration of projects
+type AuthConfigEndpoint struct {
+	Projects projects.ProjectManager
+	OAuth    *OAuthEndpoint
+}
+
+// NewAuthConfigEndpoint creates a new auth config endpoint. OAuth login URLs
+// are made by oauthEndpoint, so they are the ones its login route returns.
+func NewAuthConfigEndpoint(projectManager projects.ProjectManager, oauthEndpoint *OAuthEndpoint) *AuthConfigEndpoint {
+	return &AuthConfigEndpoint{
+		Projects: projectManager,
+		OAuth:    oauthEndpoint,
+	}
+}
+
+// GetAuthConfig assembles a project's login page configuration from its
+// settings and the configured OAuth providers. OAuth logins and sign-ups
+// need the project's default role, given to the users they create; without
+// one neither is offered.
+func (e *AuthConfigEndpoint) GetAuthConfig(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetAuthConfigRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	id, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+	project, ok := projects.ProjectFromContext(ctx)
+	if !ok || project.ID != id {
+		if project, err = e.Projects.GetProject(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	settings := project.Settings
+
+	response := AuthConfigResponse{
+		ProjectID:     project.ID,
+		Branding:      settings.Branding,
+		SignupEnabled: settings.DefaultRoleID != nil,
+	}
+	if response.Branding.DisplayName == "" {
+		response.Branding.DisplayName = project.Name
+	}
+
+	authPath := "/api/v1/" + project.ID.String() + "/auth"
+	if settings.MagicLink.Enabled {
+		response.Methods.MagicLink = &MagicLinkMethod{RequestURL: authPath + "/magic-link"}
+	}
+	if settings.WebAuthn.Enabled() {
+		response.Methods.Passkey = &PasskeyMethod{BeginURL: authPath + "/webauthn/login/begin", RPID: settings.WebAuthn.RPID}
+	}
+
+	if settings.DefaultRoleID != nil {
+		for _, provider := range oauth.SupportedProviders {
+			if e.OAuth.providerSource(settings, provider) == "" {
+				continue
+			}
+			loginURL, err := e.OAuth.loginURL(ctx, project.ID.String(), settings.DefaultRoleID.String(), provider)
+			if err != nil {
+				// One misconfigured provider should not take the login page down
+				log.For(ctx).Errorf("Cannot make %s login URL for project %s: %v", provider, project.ID, err)
+				continue
+			}
+			response.Methods.OAuth = append(response.Methods.OAuth, OAuthMethod{
+				Provider:  provider,
+				LoginURL:  loginURL,
+				ExpiresIn: int64(e.OAuth.stateTTL().Seconds()),
+				Scopes:    e.OAuth.ProviderFactory.Scopes(provider, settings.OAuthProviders[provider].Scopes),
+			})
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,61 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestGetAuthConfig(t *testing.T) {
+	defaultRole := uuid.New()
+	project := &schemas.Project{ID: uuid.New(), Name: "Shop", Settings: schemas.ProjectSettings{
+		DefaultRoleID: &defaultRole,
+		MagicLink:     schemas.MagicLinkSettings{Enabled: true},
+		WebAuthn:      schemas.WebAuthnSettings{RPID: "shop.example.com", Origins: []string{"https://shop.example.com"}},
+	}}
+	oauthEndpoint := newOAuthEndpoint(project, nil)
+	endpoint := endpoints.NewAuthConfigEndpoint(oauthEndpoint.Projects, oauthEndpoint)
+	ctx := context.Background()
+
+	response, err := endpoint.GetAuthConfig(ctx, endpoints.GetAuthConfigRequest{ProjectID: project.ID.String()})
+	if err != nil {
+		t.Fatalf("GetAuthConfig: %v", err)
+	}
+	config := response.(endpoints.AuthConfigResponse)
+	authPath := "/api/v1/" + project.ID.String() + "/auth"
+	if config.ProjectID != project.ID || !config.SignupEnabled || config.Branding.DisplayName != "Shop" {
+		t.Fatalf("config = %+v", config)
+	}
+	if config.Methods.MagicLink == nil || config.Methods.MagicLink.RequestURL != authPath+"/magic-link" {
+		t.Fatalf("magic link = %+v", config.Methods.MagicLink)
+	}
+	if passkey := config.Methods.Passkey; passkey == nil || passkey.BeginURL != authPath+"/webauthn/login/begin" || passkey.RPID != "shop.example.com" {
+		t.Fatalf("passkey = %+v", passkey)
+	}
+	if methods := config.Methods.OAuth; len(methods) != 1 || methods[0].Provider != "google" ||
+		methods[0].LoginURL == "" || methods[0].ExpiresIn != int64(endpoints.OAuthStateTTL.Seconds()) {
+		t.Fatalf("oauth = %+v", methods)
+	}
+
+	project.Settings = schemas.ProjectSettings{Branding: schemas.ProjectBranding{DisplayName: "The Shop"}}
+	response, err = endpoint.GetAuthConfig(projects.WithProject(ctx, project), endpoints.GetAuthConfigRequest{ProjectID: project.ID.String()})
+	if err != nil {
+		t.Fatalf("GetAuthConfig: %v", err)
+	}
+	config = response.(endpoints.AuthConfigResponse)
+	if config.SignupEnabled || config.Branding.DisplayName != "The Shop" || config.Methods.MagicLink != nil || config.Methods.Passkey != nil || config.Methods.OAuth != nil {
+		t.Fatalf("config without login methods = %+v", config)
+	}
+
+	if _, err := endpoint.GetAuthConfig(ctx, endpoints.GetAuthConfigRequest{ProjectID: uuid.NewString()}); err != projects.ErrProjectNotFound {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+	if _, err := endpoint.GetAuthConfig(ctx, endpoints.GetAuthConfigRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("GetAuthConfig accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetAuthConfig(ctx, r) })
+}
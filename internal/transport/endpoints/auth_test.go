@@ -0,0 +1,194 @@
+package endpoints_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+// aLoginUser creates a global user whose tokens last a day
+func aLoginUser(t *testing.T, db *gorm.DB, email string, built *testutil.BuiltProject) schemas.User {
+	t.Helper()
+
+	user := testutil.AUser(t, db, email, built.Roles["member"], built.Project)
+	user.ExpirationTime = time.Now().Add(24 * time.Hour)
+	if err := db.Model(&user).Update("expiration_time", user.ExpirationTime).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestLogin(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	user := aLoginUser(t, db, "ada@example.com", built)
+	endpoint := &endpoints.AuthEndpoint{DB: db}
+	ctx := context.Background()
+
+	response, err := endpoint.Login(ctx, endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	login := response.(endpoints.LoginResponse)
+	if login.UserID != user.ID.String() || login.Role != "member" || login.PasswordExpired || login.PasswordExpiresAt != nil {
+		t.Fatalf("login = %+v", login)
+	}
+	claims, err := auth.ParseToken(login.Token)
+	if err != nil {
+		t.Fatalf("the issued token does not parse: %v", err)
+	}
+	if claims.UserID != user.ID || claims.ProjectId != built.Project.ID || claims.Scope != "" {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	response, err = endpoint.Login(ctx, endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword, ProjectID: built.Project.ID.String()})
+	if err != nil {
+		t.Fatalf("Login within the project: %v", err)
+	}
+	if response.(endpoints.LoginResponse).UserID != user.ID.String() {
+		t.Fatalf("login = %+v", response)
+	}
+}
+
+func TestLoginUpgradesABcryptHashToArgon2(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	user := aLoginUser(t, db, "ada@example.com", built)
+	if !strings.HasPrefix(user.Password, "$2a$") {
+		t.Fatalf("the user's password %q is not a bcrypt hash", user.Password)
+	}
+	endpoint := &endpoints.AuthEndpoint{DB: db, Passwords: password.NewHasher(password.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1})}
+	ctx := context.Background()
+	login := endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword}
+
+	if _, err := endpoint.Login(ctx, login); err != nil {
+		t.Fatalf("Login with a bcrypt hash: %v", err)
+	}
+	var stored schemas.User
+	if err := db.First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Password, "$argon2id$") {
+		t.Fatalf("stored hash = %q after the login, want it upgraded to argon2id", stored.Password)
+	}
+
+	if _, err := endpoint.Login(ctx, login); err != nil {
+		t.Fatalf("Login with the upgraded hash: %v", err)
+	}
+	upgraded := stored.Password
+	if err := db.First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != upgraded {
+		t.Error("a login with a current hash re-hashed it")
+	}
+	if _, err := endpoint.Login(ctx, endpoints.LoginRequest{Email: "ada@example.com", Password: "wrong-password"}); err == nil {
+		t.Error("Login accepted a wrong password against the upgraded hash")
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	suspended := aLoginUser(t, db, "suspended@example.com", built)
+	if err := db.Model(&suspended).Update("status", schemas.UserStatusSuspended).Error; err != nil {
+		t.Fatal(err)
+	}
+	aLoginUser(t, db, "ada@example.com", built)
+	endpoint := &endpoints.AuthEndpoint{DB: db}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		request endpoints.LoginRequest
+		code    string
+	}{
+		{"unknown email", endpoints.LoginRequest{Email: "nobody@example.com", Password: testutil.DefaultPassword}, "INVALID_CREDENTIALS"},
+		{"wrong password", endpoints.LoginRequest{Email: "ada@example.com", Password: "wrong-password"}, "INVALID_CREDENTIALS"},
+		{"other project", endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword, ProjectID: uuid.NewString()}, "INVALID_CREDENTIALS"},
+		{"malformed project", endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword, ProjectID: "nope"}, "INVALID_PROJECT_ID"},
+		{"suspended", endpoints.LoginRequest{Email: "suspended@example.com", Password: testutil.DefaultPassword}, "ACCOUNT_NOT_ACTIVE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := endpoint.Login(ctx, tt.request)
+			wantCode(t, err, tt.code)
+		})
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.Login(ctx, r) })
+}
+
+func TestLoginChecksTheRoleNetworks(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	aLoginUser(t, db, "ada@example.com", built)
+	role := built.Roles["member"]
+	role.AllowedCIDRs = []string{"10.0.0.0/8"}
+	if err := db.Save(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &endpoints.AuthEndpoint{DB: db}
+	request := endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword}
+
+	inside := logins.WithClient(context.Background(), logins.Client{IPAddress: "10.1.2.3"})
+	if _, err := endpoint.Login(inside, request); err != nil {
+		t.Fatalf("Login from an allowed network: %v", err)
+	}
+	outside := logins.WithClient(context.Background(), logins.Client{IPAddress: "192.168.1.1"})
+	if _, err := endpoint.Login(outside, request); err != roles.ErrIPNotAllowed {
+		t.Fatalf("err = %v, want %v", err, roles.ErrIPNotAllowed)
+	}
+}
+
+func TestLoginWithAnExpiredPassword(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	user := aLoginUser(t, db, "ada@example.com", built)
+	maxAge := 30
+	project := built.Project
+	project.Settings.Passwords.MaxAgeDays = &maxAge
+	if err := db.Save(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &endpoints.AuthEndpoint{DB: db}
+	request := endpoints.LoginRequest{Email: "ada@example.com", Password: testutil.DefaultPassword}
+
+	response, err := endpoint.Login(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login := response.(endpoints.LoginResponse); login.PasswordExpired || login.PasswordExpiresAt == nil {
+		t.Fatalf("login with a fresh password = %+v", login)
+	}
+
+	changedAt := time.Now().AddDate(0, 0, -31)
+	if err := db.Model(&user).Update("password_changed_at", changedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	response, err = endpoint.Login(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	login := response.(endpoints.LoginResponse)
+	if !login.PasswordExpired {
+		t.Fatalf("login with an expired password = %+v", login)
+	}
+	claims, err := auth.ParseToken(login.Token)
+	if err != nil {
+		t.Fatalf("the issued token does not parse: %v", err)
+	}
+	if claims.Scope != auth.ScopePasswordChange || claims.ExpiresAt.Time.After(time.Now().Add(endpoints.PasswordChangeTokenTTL)) {
+		t.Fatalf("claims = %+v, want a short token scoped to changing the password", claims)
+	}
+}
@@ -0,0 +1,94 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/policies"
+)
+
+// AuthorizeRequest represents the authorization check request
+type AuthorizeRequest struct {
+	ProjectID string `json:"-"` // From URL path
+	APIToken  string `json:"-"` // From the Authorization header
+	UserID    string `json:"user_id"`
+	Resource  string `json:"resource"`
+	Action    string `json:"action"`
+}
+
+// AuthorizeResponse represents the authorization check response
+type AuthorizeResponse struct {
+	Allowed  bool    `json:"allowed"`
+	Decision string  `json:"decision"` // "allow" or "deny"
+	Reason   string  `json:"reason"`
+	Policy   *Policy `json:"policy"` // The policy that decided, if any
+}
+
+// AuthorizeEndpoint handles authorization checks made by tenants' backends
+type AuthorizeEndpoint struct {
+	PolicyManager   policies.PolicyManager
+	APITokenManager apitokens.APITokenManager
+}
+
+// NewAuthorizeEndpoint creates a new authorize endpoint
+func NewAuthorizeEndpoint(policyManager policies.PolicyManager, tokenManager apitokens.APITokenManager) *AuthorizeEndpoint {
+	return &AuthorizeEndpoint{
+		PolicyManager:   policyManager,
+		APITokenManager: tokenManager,
+	}
+}
+
+// Authorize checks whether a project user may perform an action on one of
+// the project's custom resources
+func (e *AuthorizeEndpoint) Authorize(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(AuthorizeRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	if err := e.APITokenManager.Authenticate(ctx, projectID, req.APIToken); err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	decision, err := e.PolicyManager.Authorize(ctx, projectID, userID, req.Resource, req.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := AuthorizeResponse{
+		Allowed:  decision.Allowed,
+		Decision: "deny",
+		Reason:   decision.Reason,
+	}
+	if decision.Allowed {
+		resp.Decision = "allow"
+	}
+	if p := decision.Policy; p != nil {
+		resp.Policy = &Policy{
+			ID:          p.ID.String(),
+			Name:        p.Name,
+			Description: p.Description,
+			Resource:    p.Resource,
+			Action:      p.Action,
+			Effect:      p.Effect,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			ProjectID:   optionalUUIDString(p.ProjectId),
+			CreatedBy:   creatorString(p.CreatedBy),
+			IsSystem:    p.IsSystem,
+		}
+	}
+	return resp, nil
+}
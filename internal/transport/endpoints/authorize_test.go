@@ -0,0 +1,82 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/policies"
+)
+
+func TestAuthorize(t *testing.T) {
+	projectID, userID := uuid.New(), uuid.New()
+	at := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	policy := &schemas.Policy{ID: uuid.New(), Name: "read-docs", Resource: "docs:document", Action: "read", Effect: "allow", ProjectId: &projectID, CreatedAt: at, UpdatedAt: at}
+	tokens := &mocks.APITokenManager{
+		AuthenticateFunc: func(_ context.Context, id uuid.UUID, token string) error {
+			if id != projectID || token != "secret" {
+				return apitokens.ErrInvalidToken
+			}
+			return nil
+		},
+	}
+	decisions := &mocks.PolicyManager{
+		AuthorizeFunc: func(_ context.Context, pid, uid uuid.UUID, resource, action string) (*policies.Decision, error) {
+			if pid != projectID || uid != userID || resource != "docs:document" {
+				t.Errorf("Authorize(%v, %v, %q, %q)", pid, uid, resource, action)
+			}
+			if action == "read" {
+				return &policies.Decision{Allowed: true, Reason: "allowed by read-docs", Policy: policy}, nil
+			}
+			return &policies.Decision{Reason: "no policy matched"}, nil
+		},
+	}
+	endpoint := endpoints.NewAuthorizeEndpoint(decisions, tokens)
+	ctx := context.Background()
+	request := endpoints.AuthorizeRequest{ProjectID: projectID.String(), APIToken: "secret", UserID: userID.String(), Resource: "docs:document", Action: "read"}
+
+	response, err := endpoint.Authorize(ctx, request)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	allowed := response.(endpoints.AuthorizeResponse)
+	if !allowed.Allowed || allowed.Decision != "allow" || allowed.Reason != "allowed by read-docs" || allowed.Policy == nil {
+		t.Fatalf("response = %+v", allowed)
+	}
+	want := endpoints.Policy{
+		ID: policy.ID.String(), Name: "read-docs", Resource: "docs:document", Action: "read", Effect: "allow",
+		CreatedAt: at, UpdatedAt: at, ProjectID: projectID.String(), CreatedBy: "system",
+	}
+	if *allowed.Policy != want {
+		t.Fatalf("policy = %+v, want %+v", *allowed.Policy, want)
+	}
+
+	request.Action = "write"
+	response, err = endpoint.Authorize(ctx, request)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if denied := response.(endpoints.AuthorizeResponse); denied.Allowed || denied.Decision != "deny" || denied.Policy != nil {
+		t.Fatalf("response = %+v", denied)
+	}
+
+	bad := request
+	bad.APIToken = "wrong"
+	if _, err := endpoint.Authorize(ctx, bad); err != apitokens.ErrInvalidToken {
+		t.Fatalf("err = %v, want %v", err, apitokens.ErrInvalidToken)
+	}
+	for _, bad := range []endpoints.AuthorizeRequest{
+		{ProjectID: "nope", APIToken: "secret", UserID: userID.String()},
+		{ProjectID: projectID.String(), APIToken: "secret", UserID: "nope"},
+	} {
+		if _, err := endpoint.Authorize(ctx, bad); err == nil {
+			t.Errorf("Authorize(%+v) accepted a malformed ID", bad)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.Authorize(ctx, r) })
+}
@@ -0,0 +1,68 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+)
+
+// GetUserChangesRequest represents the user change feed request
+type GetUserChangesRequest struct {
+	ProjectID string        `json:"project_id"`
+	Since     string        `json:"since"` // Cursor of the previous page; empty to start from the beginning
+	Wait      time.Duration `json:"wait"`  // How long to wait for a change when there is none
+	Limit     int           `json:"limit"`
+}
+
+// GetUserChangesResponse represents the user change feed response
+type GetUserChangesResponse struct {
+	Changes []changefeed.Change `json:"changes"`
+	Cursor  string              `json:"cursor"`
+	HasMore bool                `json:"has_more"`
+}
+
+// ChangesEndpoint handles the user change feed
+type ChangesEndpoint struct {
+	ChangeFeed changefeed.ChangeFeed
+	MaxWait    time.Duration // Longest a read may wait, which its response must be allowed to take
+}
+
+// NewChangesEndpoint creates a new changes endpoint
+func NewChangesEndpoint(feed changefeed.ChangeFeed, maxWait time.Duration) *ChangesEndpoint {
+	if maxWait <= 0 {
+		maxWait = changefeed.DefaultMaxWait
+	}
+	return &ChangesEndpoint{
+		ChangeFeed: feed,
+		MaxWait:    maxWait,
+	}
+}
+
+// GetUserChanges returns the changes of a project's users after a cursor,
+// long-polling when there are none yet
+func (e *ChangesEndpoint) GetUserChanges(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetUserChangesRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+
+	page, err := e.ChangeFeed.Changes(ctx, projectID, req.Since, req.Wait, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetUserChangesResponse{
+		Changes: page.Changes,
+		Cursor:  page.Cursor,
+		HasMore: page.HasMore,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestNewChangesEndpointDefaultsTheMaxWait(t *testing.T) {
+	if wait := endpoints.NewChangesEndpoint(nil, 0).MaxWait; wait != changefeed.DefaultMaxWait {
+		t.Fatalf("max wait = %v, want %v", wait, changefeed.DefaultMaxWait)
+	}
+}
+
+func TestGetUserChanges(t *testing.T) {
+	projectID := uuid.New()
+	feed := &mocks.ChangeFeed{
+		ChangesFunc: func(_ context.Context, id uuid.UUID, since string, wait time.Duration, limit int) (*changefeed.Page, error) {
+			if id != projectID || since != "c1" || wait != time.Second || limit != 10 {
+				t.Errorf("Changes(%v, %q, %v, %d)", id, since, wait, limit)
+			}
+			return &changefeed.Page{Changes: []changefeed.Change{{ID: uuid.New(), Type: "updated"}}, Cursor: "c2", HasMore: true}, nil
+		},
+	}
+	endpoint := endpoints.NewChangesEndpoint(feed, time.Minute)
+	ctx := context.Background()
+
+	response, err := endpoint.GetUserChanges(ctx, endpoints.GetUserChangesRequest{
+		ProjectID: projectID.String(),
+		Since:     "c1",
+		Wait:      time.Second,
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("GetUserChanges: %v", err)
+	}
+	page := response.(endpoints.GetUserChangesResponse)
+	if len(page.Changes) != 1 || page.Cursor != "c2" || !page.HasMore {
+		t.Fatalf("page = %+v", page)
+	}
+
+	_, err = endpoint.GetUserChanges(ctx, endpoints.GetUserChangesRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetUserChanges(ctx, r) })
+}
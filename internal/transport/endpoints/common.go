@@ -0,0 +1,91 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("endpoints")
+
+// ErrInvalidRole is returned when a role_id is neither a UUID nor the name of
+// a role visible to the project
+var ErrInvalidRole = apierrors.BadRequest("INVALID_ROLE", "role_id must be a role ID or the name of an existing role")
+
+// parseOptionalUUID parses an optional ID, returning nil when it is empty
+func parseOptionalUUID(s string) (*uuid.UUID, error) {
+	if s == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// optionalUUIDString formats an optional ID, returning "" when it is nil
+func optionalUUIDString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// creatorString names who created an entry: a user ID, or "system" for
+// entries without a recorded creator
+func creatorString(id *uuid.UUID) string {
+	if id == nil {
+		return audit.SystemActor
+	}
+	return id.String()
+}
+
+// deletion reports when and by whom an entry was soft-deleted, or nothing
+// when it has not been
+func deletion(deletedAt gorm.DeletedAt, deletedBy *uuid.UUID) (*time.Time, string) {
+	if !deletedAt.Valid {
+		return nil, ""
+	}
+	return &deletedAt.Time, creatorString(deletedBy)
+}
+
+// resolveRoleID turns a role_id given as either a UUID or a role name into
+// the role's ID. Names are looked up among the project's own roles first,
+// then among global roles. Role names can never be UUIDs, so a UUID is always
+// taken as an ID.
+func resolveRoleID(ctx context.Context, manager roles.RoleManager, value string, projectID uuid.UUID) (uuid.UUID, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		return id, nil
+	}
+	if value == "" {
+		return uuid.Nil, ErrInvalidRole
+	}
+	for _, scope := range []*uuid.UUID{&projectID, nil} {
+		role, err := manager.GetRoleByName(ctx, value, scope)
+		if err == nil {
+			return role.ID, nil
+		}
+		if !errors.Is(err, roles.ErrRoleNotFound) {
+			return uuid.Nil, err
+		}
+	}
+	return uuid.Nil, ErrInvalidRole
+}
+
+// nonNil returns an empty slice in place of nil. Response builders for list
+// endpoints must never hand a nil slice to the encoder: an empty result is
+// 200 with [] rather than null, and NoContentResponse (204) is never used for lists.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,99 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+func TestParseOptionalUUID(t *testing.T) {
+	if id, err := parseOptionalUUID(""); id != nil || err != nil {
+		t.Fatalf("parseOptionalUUID(\"\") = %v, %v", id, err)
+	}
+	if _, err := parseOptionalUUID("nope"); err == nil {
+		t.Fatal("parseOptionalUUID accepted a malformed ID")
+	}
+	want := uuid.New()
+	if id, err := parseOptionalUUID(want.String()); err != nil || *id != want {
+		t.Fatalf("parseOptionalUUID = %v, %v, want %v", id, err, want)
+	}
+	if s := optionalUUIDString(nil); s != "" {
+		t.Fatalf("optionalUUIDString(nil) = %q", s)
+	}
+	if s := optionalUUIDString(&want); s != want.String() {
+		t.Fatalf("optionalUUIDString = %q, want %q", s, want)
+	}
+}
+
+func TestDeletion(t *testing.T) {
+	if at, by := deletion(gorm.DeletedAt{}, nil); at != nil || by != "" {
+		t.Fatalf("deletion of a live entry = %v, %q", at, by)
+	}
+
+	now := time.Now()
+	if at, by := deletion(gorm.DeletedAt{Time: now, Valid: true}, nil); at == nil || !at.Equal(now) || by != "system" {
+		t.Fatalf("deletion without a recorded actor = %v, %q", at, by)
+	}
+	actor := uuid.New()
+	if _, by := deletion(gorm.DeletedAt{Time: now, Valid: true}, &actor); by != actor.String() {
+		t.Fatalf("deleted by %q, want %q", by, actor)
+	}
+}
+
+func TestResolveRoleID(t *testing.T) {
+	projectID := uuid.New()
+	projectRole, globalRole := uuid.New(), uuid.New()
+	manager := &mocks.RoleManager{
+		GetRoleByNameFunc: func(_ context.Context, name string, scope *uuid.UUID) (*schemas.Role, error) {
+			switch {
+			case name == "editor" && scope != nil && *scope == projectID:
+				return &schemas.Role{ID: projectRole}, nil
+			case name == "viewer" && scope == nil:
+				return &schemas.Role{ID: globalRole}, nil
+			case name == "broken":
+				return nil, errors.New("boom")
+			}
+			return nil, roles.ErrRoleNotFound
+		},
+	}
+	ctx := context.Background()
+	id := uuid.New()
+
+	tests := []struct {
+		value   string
+		want    uuid.UUID
+		wantErr bool
+	}{
+		{value: id.String(), want: id},
+		{value: "editor", want: projectRole},
+		{value: "viewer", want: globalRole},
+		{value: "missing", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveRoleID(ctx, manager, tt.value, projectID)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveRoleID(%q) = %v, %v", tt.value, got, err)
+		}
+	}
+	if _, err := resolveRoleID(ctx, manager, "missing", projectID); err != ErrInvalidRole {
+		t.Errorf("err = %v, want %v", err, ErrInvalidRole)
+	}
+}
+
+func TestNonNil(t *testing.T) {
+	if items := nonNil[string](nil); items == nil || len(items) != 0 {
+		t.Fatalf("nonNil(nil) = %#v", items)
+	}
+	if items := nonNil([]int{1}); len(items) != 1 {
+		t.Fatalf("nonNil([1]) = %v", items)
+	}
+}
@@ -0,0 +1,203 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// Consent represents a user's acceptance of a document in API responses
+type Consent struct {
+	ID         string    `json:"id"`
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	IPAddress  string    `json:"ip_address"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// AcceptConsentRequest represents the record project user consent request
+type AcceptConsentRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+	Document  string `json:"document"`
+	Version   string `json:"version"`
+}
+
+// AcceptMyConsentRequest represents the record own consent request
+type AcceptMyConsentRequest struct {
+	User     *schemas.User `json:"-"` // The authenticated caller, nil if none
+	Document string        `json:"document"`
+	Version  string        `json:"version"`
+}
+
+// AcceptConsentResponse represents the record consent response, with the
+// documents still to accept afterwards
+type AcceptConsentResponse struct {
+	Consent     Consent                `json:"consent"`
+	Outstanding []consents.Outstanding `json:"outstanding_consents"`
+}
+
+// ListConsentsRequest represents the list project user consents request
+type ListConsentsRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+}
+
+// ListMyConsentsRequest represents the list own consents request
+type ListMyConsentsRequest struct {
+	User *schemas.User `json:"-"` // The authenticated caller, nil if none
+}
+
+// ListConsentsResponse represents the list consents response: every
+// acceptance, newest first, and the documents still to accept
+type ListConsentsResponse struct {
+	Consents    []Consent              `json:"consents"`
+	Outstanding []consents.Outstanding `json:"outstanding_consents"`
+}
+
+// ConsentsEndpoint handles the endpoints recording the documents users accept
+type ConsentsEndpoint struct {
+	Consents     consents.ConsentManager
+	ProjectUsers projectusers.ProjectUserManager
+}
+
+// NewConsentsEndpoint creates a new consents endpoint
+func NewConsentsEndpoint(consentManager consents.ConsentManager, projectUsers projectusers.ProjectUserManager) *ConsentsEndpoint {
+	return &ConsentsEndpoint{
+		Consents:     consentManager,
+		ProjectUsers: projectUsers,
+	}
+}
+
+func toConsent(consent schemas.Consent) Consent {
+	return Consent{
+		ID:         consent.ID.String(),
+		Document:   consent.Document,
+		Version:    consent.Version,
+		IPAddress:  consent.IPAddress,
+		AcceptedAt: consent.AcceptedAt,
+	}
+}
+
+// AcceptConsent records a project user accepting a document of the project
+func (e *ConsentsEndpoint) AcceptConsent(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(AcceptConsentRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	projectID, userID, err := e.projectUser(ctx, req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return e.accept(ctx, projectID, userID, req.Document, req.Version)
+}
+
+// AcceptMyConsent records the caller accepting a document of its primary
+// project
+func (e *ConsentsEndpoint) AcceptMyConsent(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(AcceptMyConsentRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if req.User == nil {
+		return nil, ErrNotSignedIn
+	}
+	return e.accept(ctx, req.User.ProjectId, req.User.ID, req.Document, req.Version)
+}
+
+func (e *ConsentsEndpoint) accept(ctx context.Context, projectID, userID uuid.UUID, document, version string) (interface{}, error) {
+	consent, err := e.Consents.Accept(ctx, projectID, userID, document, version)
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := e.Consents.Outstanding(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return AcceptConsentResponse{Consent: toConsent(*consent), Outstanding: outstanding}, nil
+}
+
+// ListConsents lists the consents of a project user
+func (e *ConsentsEndpoint) ListConsents(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListConsentsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	projectID, userID, err := e.projectUser(ctx, req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return e.list(ctx, projectID, userID)
+}
+
+// ListMyConsents lists the caller's consents in its primary project
+func (e *ConsentsEndpoint) ListMyConsents(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListMyConsentsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if req.User == nil {
+		return nil, ErrNotSignedIn
+	}
+	return e.list(ctx, req.User.ProjectId, req.User.ID)
+}
+
+func (e *ConsentsEndpoint) list(ctx context.Context, projectID, userID uuid.UUID) (interface{}, error) {
+	accepted, err := e.Consents.ListConsents(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := e.Consents.Outstanding(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	response := ListConsentsResponse{Consents: make([]Consent, 0, len(accepted)), Outstanding: outstanding}
+	for _, consent := range accepted {
+		response.Consents = append(response.Consents, toConsent(consent))
+	}
+	return response, nil
+}
+
+// consentsPending is embedded in the responses of logins to a project
+type consentsPending struct {
+	// OutstandingConsents lists the documents the project requires that the
+	// user has yet to accept, for the frontend to ask for before going on
+	OutstandingConsents []consents.Outstanding `json:"outstanding_consents,omitempty"`
+}
+
+// outstandingConsents returns the documents a user who just logged in has
+// yet to accept, for the login response to flag. There are none without a
+// consent manager, or once the project is gone.
+func outstandingConsents(ctx context.Context, manager consents.ConsentManager, projectID, userID string) ([]consents.Outstanding, error) {
+	if manager == nil {
+		return nil, nil
+	}
+	pid, uid, err := parseProjectAndUser(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := manager.Outstanding(ctx, pid, uid)
+	if errors.Is(err, projects.ErrProjectNotFound) {
+		return nil, nil
+	}
+	return outstanding, err
+}
+
+// projectUser parses the IDs of a project user and checks that the user
+// exists in the project
+func (e *ConsentsEndpoint) projectUser(ctx context.Context, rawProjectID, rawUserID string) (uuid.UUID, uuid.UUID, error) {
+	projectID, userID, err := parseProjectAndUser(rawProjectID, rawUserID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if _, err := e.ProjectUsers.GetProjectUser(ctx, rawProjectID, userID, false); err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return projectID, userID, nil
+}
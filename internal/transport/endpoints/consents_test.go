@@ -0,0 +1,213 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/magiclink"
+	"github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// consentFixture is a project user with a consent manager recording what they
+// accept
+type consentFixture struct {
+	projectID, userID uuid.UUID
+	accepted          []schemas.Consent
+	consents          *mocks.ConsentManager
+	projectUsers      *mocks.ProjectUserManager
+}
+
+func newConsentFixture(t *testing.T) *consentFixture {
+	f := &consentFixture{projectID: uuid.New(), userID: uuid.New()}
+	at := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	check := func(projectID, userID uuid.UUID) {
+		if projectID != f.projectID || userID != f.userID {
+			t.Errorf("called for %v in %v, want %v in %v", userID, projectID, f.userID, f.projectID)
+		}
+	}
+	f.consents = &mocks.ConsentManager{
+		AcceptFunc: func(_ context.Context, projectID, userID uuid.UUID, document, version string) (*schemas.Consent, error) {
+			check(projectID, userID)
+			consent := schemas.Consent{ID: uuid.New(), Document: document, Version: version, IPAddress: "10.0.0.1", AcceptedAt: at, ProjectId: projectID, UserId: userID}
+			f.accepted = append(f.accepted, consent)
+			return &consent, nil
+		},
+		ListConsentsFunc: func(_ context.Context, projectID, userID uuid.UUID) ([]schemas.Consent, error) {
+			check(projectID, userID)
+			return f.accepted, nil
+		},
+		OutstandingFunc: func(_ context.Context, projectID, userID uuid.UUID) ([]consents.Outstanding, error) {
+			check(projectID, userID)
+			if len(f.accepted) > 0 {
+				return []consents.Outstanding{}, nil
+			}
+			return []consents.Outstanding{{Document: "terms", Version: "2"}}, nil
+		},
+	}
+	f.projectUsers = &mocks.ProjectUserManager{
+		GetProjectUserFunc: func(_ context.Context, projectID string, userID uuid.UUID, _ bool) (*models.DisplayUser, error) {
+			if projectID != f.projectID.String() || userID != f.userID {
+				return nil, projectusers.ErrUserNotFound
+			}
+			return &models.DisplayUser{ID: userID.String(), ProjectID: projectID}, nil
+		},
+	}
+	return f
+}
+
+func TestAcceptConsent(t *testing.T) {
+	f := newConsentFixture(t)
+	endpoint := endpoints.NewConsentsEndpoint(f.consents, f.projectUsers)
+	ctx := context.Background()
+
+	response, err := endpoint.AcceptConsent(ctx, endpoints.AcceptConsentRequest{
+		ProjectID: f.projectID.String(),
+		UserID:    f.userID.String(),
+		Document:  "terms",
+		Version:   "2",
+	})
+	if err != nil {
+		t.Fatalf("AcceptConsent: %v", err)
+	}
+	accepted := response.(endpoints.AcceptConsentResponse)
+	want := endpoints.Consent{ID: f.accepted[0].ID.String(), Document: "terms", Version: "2", IPAddress: "10.0.0.1", AcceptedAt: f.accepted[0].AcceptedAt}
+	if accepted.Consent != want || len(accepted.Outstanding) != 0 {
+		t.Fatalf("response = %+v, want %+v with nothing outstanding", accepted, want)
+	}
+
+	if _, err := endpoint.AcceptConsent(ctx, endpoints.AcceptConsentRequest{ProjectID: f.projectID.String(), UserID: uuid.NewString()}); err != projectusers.ErrUserNotFound {
+		t.Fatalf("err = %v, want %v for a user outside the project", err, projectusers.ErrUserNotFound)
+	}
+	for _, bad := range []endpoints.AcceptConsentRequest{
+		{ProjectID: "nope", UserID: f.userID.String()},
+		{ProjectID: f.projectID.String(), UserID: "nope"},
+	} {
+		if _, err := endpoint.AcceptConsent(ctx, bad); err == nil {
+			t.Errorf("AcceptConsent(%+v) accepted a malformed ID", bad)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.AcceptConsent(ctx, r) })
+}
+
+func TestAcceptMyConsent(t *testing.T) {
+	f := newConsentFixture(t)
+	endpoint := endpoints.NewConsentsEndpoint(f.consents, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.AcceptMyConsent(ctx, endpoints.AcceptMyConsentRequest{
+		User:     &schemas.User{ID: f.userID, ProjectId: f.projectID},
+		Document: "privacy",
+		Version:  "1",
+	})
+	if err != nil {
+		t.Fatalf("AcceptMyConsent: %v", err)
+	}
+	if consent := response.(endpoints.AcceptConsentResponse).Consent; consent.Document != "privacy" || consent.Version != "1" {
+		t.Fatalf("consent = %+v", consent)
+	}
+
+	failure := errors.New("unknown document")
+	f.consents.AcceptFunc = func(context.Context, uuid.UUID, uuid.UUID, string, string) (*schemas.Consent, error) {
+		return nil, failure
+	}
+	if _, err := endpoint.AcceptMyConsent(ctx, endpoints.AcceptMyConsentRequest{User: &schemas.User{ID: f.userID, ProjectId: f.projectID}}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.AcceptMyConsent(ctx, endpoints.AcceptMyConsentRequest{}); err != endpoints.ErrNotSignedIn {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrNotSignedIn)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.AcceptMyConsent(ctx, r) })
+}
+
+func TestListConsents(t *testing.T) {
+	f := newConsentFixture(t)
+	endpoint := endpoints.NewConsentsEndpoint(f.consents, f.projectUsers)
+	ctx := context.Background()
+	request := endpoints.ListConsentsRequest{ProjectID: f.projectID.String(), UserID: f.userID.String()}
+
+	response, err := endpoint.ListConsents(ctx, request)
+	if err != nil {
+		t.Fatalf("ListConsents: %v", err)
+	}
+	list := response.(endpoints.ListConsentsResponse)
+	if list.Consents == nil || len(list.Consents) != 0 || len(list.Outstanding) != 1 || list.Outstanding[0].Document != "terms" {
+		t.Fatalf("consents before accepting = %+v", list)
+	}
+
+	if _, err := endpoint.AcceptConsent(ctx, endpoints.AcceptConsentRequest{ProjectID: request.ProjectID, UserID: request.UserID, Document: "terms", Version: "2"}); err != nil {
+		t.Fatalf("AcceptConsent: %v", err)
+	}
+	response, err = endpoint.ListConsents(ctx, request)
+	if err != nil {
+		t.Fatalf("ListConsents: %v", err)
+	}
+	if list := response.(endpoints.ListConsentsResponse); len(list.Consents) != 1 || len(list.Outstanding) != 0 {
+		t.Fatalf("consents after accepting = %+v", list)
+	}
+
+	if _, err := endpoint.ListConsents(ctx, endpoints.ListConsentsRequest{ProjectID: uuid.NewString(), UserID: request.UserID}); err != projectusers.ErrUserNotFound {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+	if _, err := endpoint.ListConsents(ctx, endpoints.ListConsentsRequest{ProjectID: "nope", UserID: request.UserID}); err == nil {
+		t.Fatal("ListConsents accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListConsents(ctx, r) })
+}
+
+func TestListMyConsents(t *testing.T) {
+	f := newConsentFixture(t)
+	endpoint := endpoints.NewConsentsEndpoint(f.consents, nil)
+	ctx := context.Background()
+	me := &schemas.User{ID: f.userID, ProjectId: f.projectID}
+
+	response, err := endpoint.ListMyConsents(ctx, endpoints.ListMyConsentsRequest{User: me})
+	if err != nil {
+		t.Fatalf("ListMyConsents: %v", err)
+	}
+	if list := response.(endpoints.ListConsentsResponse); len(list.Outstanding) != 1 {
+		t.Fatalf("consents = %+v", list)
+	}
+
+	failure := errors.New("boom")
+	f.consents.OutstandingFunc = func(context.Context, uuid.UUID, uuid.UUID) ([]consents.Outstanding, error) { return nil, failure }
+	if _, err := endpoint.ListMyConsents(ctx, endpoints.ListMyConsentsRequest{User: me}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.ListMyConsents(ctx, endpoints.ListMyConsentsRequest{}); err != endpoints.ErrNotSignedIn {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrNotSignedIn)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListMyConsents(ctx, r) })
+}
+
+func TestLoginsFlagNoConsentsOnceTheProjectIsGone(t *testing.T) {
+	f := newConsentFixture(t)
+	f.consents.OutstandingFunc = func(context.Context, uuid.UUID, uuid.UUID) ([]consents.Outstanding, error) {
+		return nil, projects.ErrProjectNotFound
+	}
+	links := &mocks.MagicLinkManager{
+		VerifyFunc: func(context.Context, uuid.UUID, string) (*magiclink.Session, error) {
+			return &magiclink.Session{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour), User: models.DisplayUser{
+				ID:        f.userID.String(),
+				ProjectID: f.projectID.String(),
+			}}, nil
+		},
+	}
+	endpoint := endpoints.NewMagicLinkEndpoint(links, f.consents)
+
+	response, err := endpoint.VerifyMagicLink(context.Background(), endpoints.VerifyMagicLinkRequest{ProjectID: f.projectID.String(), Token: "link"})
+	if err != nil {
+		t.Fatalf("VerifyMagicLink: %v", err)
+	}
+	if outstanding := response.(endpoints.VerifyMagicLinkResponse).OutstandingConsents; outstanding != nil {
+		t.Fatalf("outstanding consents = %+v", outstanding)
+	}
+}
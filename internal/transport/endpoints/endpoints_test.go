@@ -0,0 +1,49 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+// wrongRequest is a request type no endpoint accepts
+type wrongRequest struct{}
+
+// wantCode fails the test unless err is an API error with code
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != code {
+		t.Fatalf("err = %v, want a %s error", err, code)
+	}
+}
+
+// wantInvalidRequest fails the test unless call rejects a request of the
+// wrong type
+func wantInvalidRequest(t *testing.T, call func(request interface{}) (interface{}, error)) {
+	t.Helper()
+
+	if _, err := call(wrongRequest{}); err == nil || err.Error() != "invalid request format" {
+		t.Fatalf("err = %v, want the invalid request format error", err)
+	}
+}
+
+// rolesNamed resolves the role names given, in any scope
+func rolesNamed(named map[string]uuid.UUID) *mocks.RoleManager {
+	return &mocks.RoleManager{
+		GetRoleByNameFunc: func(_ context.Context, name string, _ *uuid.UUID) (*schemas.Role, error) {
+			id, ok := named[name]
+			if !ok {
+				return nil, roles.ErrRoleNotFound
+			}
+			return &schemas.Role{ID: id, Name: name}, nil
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrorExample is the body of an error response
+type ErrorExample struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// ErrorInfo describes one API error a route may answer with
+type ErrorInfo struct {
+	Status  int          `json:"status"`
+	Code    string       `json:"code"`
+	Example ErrorExample `json:"example"`
+}
+
+// RouteErrors lists the API errors of one route
+type RouteErrors struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Errors []ErrorInfo `json:"errors"`
+}
+
+// ErrorCatalogResponse represents the response listing the API's errors.
+// Common lists the errors any route may answer with.
+type ErrorCatalogResponse struct {
+	Common []ErrorInfo   `json:"common"`
+	Routes []RouteErrors `json:"routes"`
+}
+
+// ErrorCatalogEndpoint reports the errors each route may answer with
+type ErrorCatalogEndpoint struct {
+	// Catalog returns the errors; it is set once the router is assembled
+	Catalog func() (*ErrorCatalogResponse, error)
+}
+
+// NewErrorCatalogEndpoint creates a new error catalog endpoint
+func NewErrorCatalogEndpoint() *ErrorCatalogEndpoint {
+	return &ErrorCatalogEndpoint{}
+}
+
+// GetErrorCatalog returns the machine readable error codes of every route,
+// with their HTTP status and an example body
+func (e *ErrorCatalogEndpoint) GetErrorCatalog(_ context.Context, _ interface{}) (interface{}, error) {
+	if e.Catalog == nil {
+		return nil, errors.New("error catalog is not available")
+	}
+	catalog, err := e.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	catalog.Common = nonNil(catalog.Common)
+	catalog.Routes = nonNil(catalog.Routes)
+	for i := range catalog.Routes {
+		catalog.Routes[i].Errors = nonNil(catalog.Routes[i].Errors)
+	}
+	return *catalog, nil
+}
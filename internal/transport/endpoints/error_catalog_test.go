@@ -0,0 +1,27 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestGetErrorCatalog(t *testing.T) {
+	endpoint := endpoints.NewErrorCatalogEndpoint()
+	if _, err := endpoint.GetErrorCatalog(context.Background(), nil); err == nil {
+		t.Fatal("GetErrorCatalog succeeded before the router was assembled")
+	}
+
+	endpoint.Catalog = func() (*endpoints.ErrorCatalogResponse, error) {
+		return &endpoints.ErrorCatalogResponse{Routes: []endpoints.RouteErrors{{Method: "GET", Path: "/api/version"}}}, nil
+	}
+	response, err := endpoint.GetErrorCatalog(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetErrorCatalog: %v", err)
+	}
+	catalog := response.(endpoints.ErrorCatalogResponse)
+	if catalog.Common == nil || len(catalog.Routes) != 1 || catalog.Routes[0].Errors == nil {
+		t.Fatalf("catalog = %+v, want empty lists rather than null", catalog)
+	}
+}
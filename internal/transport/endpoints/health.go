@@ -0,0 +1,57 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/version"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// readinessTimeout bounds the database ping made by the readiness check
+const readinessTimeout = 2 * time.Second
+
+// ErrNotReady is returned by the readiness check while the database is unreachable
+var ErrNotReady = apierrors.New(http.StatusServiceUnavailable, "NOT_READY", "service is not ready")
+
+// HealthResponse represents the liveness and readiness responses
+type HealthResponse struct {
+	Status  string       `json:"status"`
+	Version version.Info `json:"version"`
+}
+
+// HealthEndpoint handles liveness and readiness checks
+type HealthEndpoint struct {
+	DB *gorm.DB
+}
+
+// NewHealthEndpoint creates a new health endpoint
+func NewHealthEndpoint(db *gorm.DB) *HealthEndpoint {
+	return &HealthEndpoint{
+		DB: db,
+	}
+}
+
+// Live reports that the process is serving requests
+func (e *HealthEndpoint) Live(_ context.Context, _ interface{}) (interface{}, error) {
+	return HealthResponse{Status: "ok", Version: version.Get()}, nil
+}
+
+// Ready reports whether the service can reach its database
+func (e *HealthEndpoint) Ready(ctx context.Context, _ interface{}) (interface{}, error) {
+	sqlDB, err := e.DB.DB()
+	if err != nil {
+		klog.Errorf("Readiness check failed: %v", err)
+		return nil, ErrNotReady
+	}
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		klog.Errorf("Readiness check failed: %v", err)
+		return nil, ErrNotReady
+	}
+	return HealthResponse{Status: "ok", Version: version.Get()}, nil
+}
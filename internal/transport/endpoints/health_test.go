@@ -0,0 +1,38 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestLive(t *testing.T) {
+	response, err := endpoints.NewHealthEndpoint(nil).Live(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Live: %v", err)
+	}
+	if status := response.(endpoints.HealthResponse).Status; status != "ok" {
+		t.Fatalf("status = %q, want ok", status)
+	}
+}
+
+func TestReady(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	endpoint := endpoints.NewHealthEndpoint(db)
+
+	if _, err := endpoint.Ready(context.Background(), nil); err != nil {
+		t.Fatalf("Ready: %v", err)
+	}
+
+	testutil.CaptureKlog(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	if _, err := endpoint.Ready(context.Background(), nil); err != endpoints.ErrNotReady {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrNotReady)
+	}
+}
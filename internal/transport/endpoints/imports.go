@@ -0,0 +1,207 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/imports"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// ImportJob represents an import job in the response
+type ImportJob struct {
+	ID             string     `json:"id"`
+	ProjectID      string     `json:"project_id"`
+	Status         string     `json:"status"`
+	RowsProcessed  int        `json:"rows_processed"`
+	RowsSucceeded  int        `json:"rows_succeeded"`
+	RowsFailed     int        `json:"rows_failed"`
+	Error          string     `json:"error,omitempty"`
+	ErrorReportURL string     `json:"error_report_url,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// CreateImportRequest represents the create import request
+type CreateImportRequest struct {
+	ProjectID string    `json:"-"`
+	File      io.Reader `json:"-"`
+}
+
+// CreateImportResponse represents the create import response
+type CreateImportResponse struct {
+	Job ImportJob `json:"job"`
+}
+
+// StatusCode reports that the import was accepted for asynchronous processing
+func (CreateImportResponse) StatusCode() int {
+	return http.StatusAccepted
+}
+
+// GetImportRequest represents the get import request
+type GetImportRequest struct {
+	ProjectID string `json:"project_id"`
+	JobID     string `json:"job_id"`
+}
+
+// GetImportResponse represents the get import response
+type GetImportResponse struct {
+	Job ImportJob `json:"job"`
+}
+
+// CancelImportRequest represents the cancel import request
+type CancelImportRequest struct {
+	ProjectID string `json:"project_id"`
+	JobID     string `json:"job_id"`
+}
+
+// CancelImportResponse represents the cancel import response
+type CancelImportResponse struct {
+	Job ImportJob `json:"job"`
+}
+
+// GetImportErrorReportRequest represents the error report download request
+type GetImportErrorReportRequest struct {
+	ProjectID string `json:"project_id"`
+	JobID     string `json:"job_id"`
+}
+
+// GetImportErrorReportResponse carries the error report CSV to be streamed to
+// the client, or the signed URL the client is redirected to when the storage
+// backend supports them
+type GetImportErrorReportResponse struct {
+	Filename    string
+	Content     io.ReadCloser
+	RedirectURL string
+}
+
+// ImportsEndpoint handles user import endpoints
+type ImportsEndpoint struct {
+	ImportManager imports.ImportManager
+}
+
+// NewImportsEndpoint creates a new imports endpoint
+func NewImportsEndpoint(manager imports.ImportManager) *ImportsEndpoint {
+	return &ImportsEndpoint{
+		ImportManager: manager,
+	}
+}
+
+// CreateImport stores an uploaded CSV and starts importing it asynchronously
+func (e *ImportsEndpoint) CreateImport(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CreateImportRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	job, err := e.ImportManager.CreateImport(ctx, req.ProjectID, req.File)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateImportResponse{
+		Job: toImportJob(job),
+	}, nil
+}
+
+// GetImport reports the progress of an import job
+func (e *ImportsEndpoint) GetImport(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetImportRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	jobID, err := uuid.Parse(req.JobID)
+	if err != nil {
+		return nil, errors.New("invalid job ID format")
+	}
+
+	job, err := e.ImportManager.GetImport(ctx, req.ProjectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetImportResponse{
+		Job: toImportJob(job),
+	}, nil
+}
+
+// CancelImport cancels a running import job
+func (e *ImportsEndpoint) CancelImport(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CancelImportRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	jobID, err := uuid.Parse(req.JobID)
+	if err != nil {
+		return nil, errors.New("invalid job ID format")
+	}
+
+	job, err := e.ImportManager.CancelImport(ctx, req.ProjectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return CancelImportResponse{
+		Job: toImportJob(job),
+	}, nil
+}
+
+// GetImportErrorReport returns the CSV of rows that failed to import
+func (e *ImportsEndpoint) GetImportErrorReport(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetImportErrorReportRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	jobID, err := uuid.Parse(req.JobID)
+	if err != nil {
+		return nil, errors.New("invalid job ID format")
+	}
+
+	url, err := e.ImportManager.ErrorReportURL(ctx, req.ProjectID, jobID)
+	if err == nil {
+		return GetImportErrorReportResponse{RedirectURL: url}, nil
+	}
+	if !errors.Is(err, blobstore.ErrSignedURLNotSupported) {
+		return nil, err
+	}
+
+	content, err := e.ImportManager.OpenErrorReport(ctx, req.ProjectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetImportErrorReportResponse{
+		Filename: "import_" + jobID.String() + "_errors.csv",
+		Content:  content,
+	}, nil
+}
+
+func toImportJob(job *schemas.ImportJob) ImportJob {
+	result := ImportJob{
+		ID:            job.ID.String(),
+		ProjectID:     job.ProjectId.String(),
+		Status:        job.Status,
+		RowsProcessed: job.RowsProcessed,
+		RowsSucceeded: job.RowsSucceeded,
+		RowsFailed:    job.RowsFailed,
+		Error:         job.Error,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+		FinishedAt:    job.FinishedAt,
+	}
+
+	if job.FinishedAt != nil && job.RowsFailed > 0 {
+		result.ErrorReportURL = "/api/" + job.ProjectId.String() + "/users/import/" + job.ID.String() + "/errors"
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/blobstore"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestCreateImport(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ImportManager{
+		CreateImportFunc: func(_ context.Context, pid string, file io.Reader) (*schemas.ImportJob, error) {
+			data, _ := io.ReadAll(file)
+			if pid != projectID.String() || string(data) != "email\n" {
+				t.Errorf("CreateImport(%q, %q)", pid, data)
+			}
+			return &schemas.ImportJob{ID: uuid.New(), ProjectId: projectID, Status: schemas.ImportStatusPending}, nil
+		},
+	}
+	endpoint := endpoints.NewImportsEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.CreateImport(ctx, endpoints.CreateImportRequest{ProjectID: projectID.String(), File: strings.NewReader("email\n")})
+	if err != nil {
+		t.Fatalf("CreateImport: %v", err)
+	}
+	created := response.(endpoints.CreateImportResponse)
+	if created.StatusCode() != http.StatusAccepted || created.Job.Status != schemas.ImportStatusPending || created.Job.ProjectID != projectID.String() {
+		t.Fatalf("response = %+v", created)
+	}
+
+	failure := errors.New("not a csv")
+	manager.CreateImportFunc = func(context.Context, string, io.Reader) (*schemas.ImportJob, error) { return nil, failure }
+	if _, err := endpoint.CreateImport(ctx, endpoints.CreateImportRequest{ProjectID: projectID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateImport(ctx, r) })
+}
+
+func TestGetImport(t *testing.T) {
+	projectID, jobID := uuid.New(), uuid.New()
+	finished := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	job := &schemas.ImportJob{
+		ID: jobID, ProjectId: projectID, Status: schemas.ImportStatusCompleted,
+		RowsProcessed: 3, RowsSucceeded: 2, RowsFailed: 1, FinishedAt: &finished,
+	}
+	manager := &mocks.ImportManager{
+		GetImportFunc: func(_ context.Context, pid string, id uuid.UUID) (*schemas.ImportJob, error) {
+			if pid != projectID.String() || id != jobID {
+				t.Errorf("GetImport(%q, %v)", pid, id)
+			}
+			return job, nil
+		},
+	}
+	endpoint := endpoints.NewImportsEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetImport(ctx, endpoints.GetImportRequest{ProjectID: projectID.String(), JobID: jobID.String()})
+	if err != nil {
+		t.Fatalf("GetImport: %v", err)
+	}
+	got := response.(endpoints.GetImportResponse).Job
+	want := endpoints.ImportJob{
+		ID: jobID.String(), ProjectID: projectID.String(), Status: schemas.ImportStatusCompleted,
+		RowsProcessed: 3, RowsSucceeded: 2, RowsFailed: 1, FinishedAt: &finished,
+		ErrorReportURL: "/api/" + projectID.String() + "/users/import/" + jobID.String() + "/errors",
+	}
+	if got != want {
+		t.Fatalf("job = %+v, want %+v", got, want)
+	}
+
+	job.Status, job.FinishedAt = schemas.ImportStatusRunning, nil
+	response, err = endpoint.GetImport(ctx, endpoints.GetImportRequest{ProjectID: projectID.String(), JobID: jobID.String()})
+	if err != nil {
+		t.Fatalf("GetImport: %v", err)
+	}
+	if url := response.(endpoints.GetImportResponse).Job.ErrorReportURL; url != "" {
+		t.Fatalf("a running job links its error report at %q", url)
+	}
+
+	if _, err := endpoint.GetImport(ctx, endpoints.GetImportRequest{ProjectID: projectID.String(), JobID: "nope"}); err == nil {
+		t.Fatal("GetImport accepted a malformed job ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetImport(ctx, r) })
+}
+
+func TestCancelImport(t *testing.T) {
+	projectID, jobID := uuid.New(), uuid.New()
+	manager := &mocks.ImportManager{
+		CancelImportFunc: func(_ context.Context, pid string, id uuid.UUID) (*schemas.ImportJob, error) {
+			if pid != projectID.String() || id != jobID {
+				t.Errorf("CancelImport(%q, %v)", pid, id)
+			}
+			return &schemas.ImportJob{ID: id, ProjectId: projectID, Status: schemas.ImportStatusCancelled}, nil
+		},
+	}
+	endpoint := endpoints.NewImportsEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.CancelImport(ctx, endpoints.CancelImportRequest{ProjectID: projectID.String(), JobID: jobID.String()})
+	if err != nil {
+		t.Fatalf("CancelImport: %v", err)
+	}
+	if status := response.(endpoints.CancelImportResponse).Job.Status; status != schemas.ImportStatusCancelled {
+		t.Fatalf("status = %q", status)
+	}
+
+	failure := errors.New("already finished")
+	manager.CancelImportFunc = func(context.Context, string, uuid.UUID) (*schemas.ImportJob, error) { return nil, failure }
+	if _, err := endpoint.CancelImport(ctx, endpoints.CancelImportRequest{ProjectID: projectID.String(), JobID: jobID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.CancelImport(ctx, endpoints.CancelImportRequest{ProjectID: projectID.String(), JobID: "nope"}); err == nil {
+		t.Fatal("CancelImport accepted a malformed job ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CancelImport(ctx, r) })
+}
+
+func TestGetImportErrorReport(t *testing.T) {
+	projectID, jobID := uuid.New(), uuid.New()
+	manager := &mocks.ImportManager{
+		ErrorReportURLFunc: func(context.Context, string, uuid.UUID) (string, error) {
+			return "https://blobs.example.com/errors.csv", nil
+		},
+	}
+	endpoint := endpoints.NewImportsEndpoint(manager)
+	ctx := context.Background()
+	request := endpoints.GetImportErrorReportRequest{ProjectID: projectID.String(), JobID: jobID.String()}
+
+	response, err := endpoint.GetImportErrorReport(ctx, request)
+	if err != nil {
+		t.Fatalf("GetImportErrorReport: %v", err)
+	}
+	if url := response.(endpoints.GetImportErrorReportResponse).RedirectURL; url != "https://blobs.example.com/errors.csv" {
+		t.Fatalf("redirect = %q", url)
+	}
+
+	manager.ErrorReportURLFunc = func(context.Context, string, uuid.UUID) (string, error) {
+		return "", blobstore.ErrSignedURLNotSupported
+	}
+	manager.OpenErrorReportFunc = func(context.Context, string, uuid.UUID) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("row,error\n")), nil
+	}
+	response, err = endpoint.GetImportErrorReport(ctx, request)
+	if err != nil {
+		t.Fatalf("GetImportErrorReport: %v", err)
+	}
+	report := response.(endpoints.GetImportErrorReportResponse)
+	if report.Filename != "import_"+jobID.String()+"_errors.csv" || report.Content == nil {
+		t.Fatalf("report = %+v", report)
+	}
+
+	failure := errors.New("no report")
+	manager.OpenErrorReportFunc = func(context.Context, string, uuid.UUID) (io.ReadCloser, error) { return nil, failure }
+	if _, err := endpoint.GetImportErrorReport(ctx, request); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	manager.ErrorReportURLFunc = func(context.Context, string, uuid.UUID) (string, error) { return "", failure }
+	if _, err := endpoint.GetImportErrorReport(ctx, request); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.GetImportErrorReport(ctx, endpoints.GetImportErrorReportRequest{JobID: "nope"}); err == nil {
+		t.Fatal("GetImportErrorReport accepted a malformed job ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetImportErrorReport(ctx, r) })
+}
@@ -0,0 +1,190 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// Defaults of the token introspection batches
+const (
+	DefaultIntrospectionBatch   = 100 // Most tokens a batch may hold
+	DefaultIntrospectionWorkers = 8   // Tokens of a batch introspected at once
+)
+
+// Reasons a token introspects as inactive, besides those of
+// auth.TokenFailureReason for tokens that fail validation
+const (
+	InactiveUserNotFound  = "user_not_found"  // The holder was deleted, or the project
+	InactiveUserNotActive = "user_not_active" // The holder may not log in in its status
+)
+
+// IntrospectBatchRequest names the tokens to introspect
+type IntrospectBatchRequest struct {
+	Tokens []string `json:"tokens"`
+}
+
+// TokenIntrospection is what introspecting a token found, after RFC 7662:
+// whether it is active, and if so whom it was issued to. Inactive tokens
+// only carry the reason.
+type TokenIntrospection struct {
+	Active    bool       `json:"active"`
+	Reason    string     `json:"reason,omitempty"` // Why the token is not active
+	UserID    string     `json:"user_id,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	RoleID    string     `json:"role_id,omitempty"`
+	ProjectID string     `json:"project_id,omitempty"`
+	Scope     string     `json:"scope,omitempty"` // E.g. password_change for tokens only good for changing it
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IntrospectBatchResponse holds the introspection of every token, in the
+// order of the request
+type IntrospectBatchResponse struct {
+	Results []TokenIntrospection `json:"results"`
+	Active  int                  `json:"active"`
+}
+
+// IntrospectionEndpoint tells gateways whether the bearer tokens they are
+// presented are active
+type IntrospectionEndpoint struct {
+	// AuthUsers loads the global users tokens are issued to, ProjectUsers the
+	// project users
+	AuthUsers    *users.AuthUsers
+	ProjectUsers projectusers.ProjectUserManager
+	MaxBatch     int
+	Workers      int
+}
+
+// NewIntrospectionEndpoint creates a new introspection endpoint. A maxBatch
+// or workers of 0 uses the defaults.
+func NewIntrospectionEndpoint(authUsers *users.AuthUsers, projectUsers projectusers.ProjectUserManager, maxBatch, workers int) *IntrospectionEndpoint {
+	if maxBatch <= 0 {
+		maxBatch = DefaultIntrospectionBatch
+	}
+	if workers <= 0 {
+		workers = DefaultIntrospectionWorkers
+	}
+	return &IntrospectionEndpoint{
+		AuthUsers:    authUsers,
+		ProjectUsers: projectUsers,
+		MaxBatch:     maxBatch,
+		Workers:      workers,
+	}
+}
+
+// IntrospectBatch introspects up to MaxBatch tokens, Workers at a time. A
+// token that is invalid, expired or whose holder may no longer log in is
+// inactive; the batch only fails if a holder cannot be looked up.
+func (e *IntrospectionEndpoint) IntrospectBatch(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(IntrospectBatchRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if len(req.Tokens) == 0 {
+		return nil, apierrors.BadRequest("TOKENS_REQUIRED", "tokens must name at least one token")
+	}
+	if len(req.Tokens) > e.MaxBatch {
+		return nil, apierrors.BadRequest("TOO_MANY_TOKENS", fmt.Sprintf("at most %d tokens can be introspected at once", e.MaxBatch))
+	}
+
+	resp := IntrospectBatchResponse{Results: make([]TokenIntrospection, len(req.Tokens))}
+	errs := make([]error, len(req.Tokens))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(e.Workers, len(req.Tokens)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp.Results[i], errs[i] = e.introspect(ctx, req.Tokens[i])
+			}
+		}()
+	}
+	for i := range req.Tokens {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, errors.New("internal server error")
+	}
+	for _, result := range resp.Results {
+		if result.Active {
+			resp.Active++
+		}
+	}
+	return resp, nil
+}
+
+// introspect validates a token and checks that its holder may still log in
+func (e *IntrospectionEndpoint) introspect(ctx context.Context, token string) (TokenIntrospection, error) {
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		return TokenIntrospection{Reason: auth.TokenFailureReason(err)}, nil
+	}
+
+	status, err := e.holderStatus(ctx, claims)
+	if err != nil {
+		return TokenIntrospection{}, err
+	}
+	if status == "" {
+		return TokenIntrospection{Reason: InactiveUserNotFound}, nil
+	}
+	if !userstatus.CanLogin(status) {
+		return TokenIntrospection{Reason: InactiveUserNotActive}, nil
+	}
+
+	result := TokenIntrospection{
+		Active:    true,
+		UserID:    claims.UserID.String(),
+		Email:     claims.Email,
+		RoleID:    claims.RoleId.String(),
+		ProjectID: claims.ProjectId.String(),
+		Scope:     claims.Scope,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = &claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = &claims.ExpiresAt.Time
+	}
+	return result, nil
+}
+
+// holderStatus returns the status of the user a token was issued to: a
+// global user, or else a user of the token's project. It is empty when there
+// is no such user. Service accounts authenticate with API tokens, so a JWT
+// naming one has no holder.
+func (e *IntrospectionEndpoint) holderStatus(ctx context.Context, claims *auth.TokenClaims) (string, error) {
+	authUser, err := e.AuthUsers.Get(ctx, claims.UserID)
+	if err == nil {
+		if user := authUser.User(); user.IsServiceAccount() {
+			return "", nil
+		}
+		return authUser.Status, nil
+	}
+	if !errors.Is(err, users.ErrAuthUserNotFound) {
+		return "", err
+	}
+
+	user, err := e.ProjectUsers.GetProjectUser(ctx, claims.ProjectId.String(), claims.UserID, false)
+	if errors.Is(err, projectusers.ErrUserNotFound) || errors.Is(err, projects.ErrProjectNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Status, nil
+}
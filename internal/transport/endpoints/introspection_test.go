@@ -0,0 +1,102 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// issue signs a token for userID in projectID, expiring at expires
+func issue(t *testing.T, userID, projectID uuid.UUID, expires time.Time) string {
+	t.Helper()
+
+	token, err := auth.GenerateToken(userID, "someone@example.com", uuid.New(), projectID, expires)
+	if err != nil {
+		t.Fatalf("failed to issue a token: %v", err)
+	}
+	return token
+}
+
+func TestIntrospectBatch(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	global := testutil.AUser(t, db, "global@example.com", built.Roles["member"], built.Project)
+	suspended := testutil.AUser(t, db, "suspended@example.com", built.Roles["member"], built.Project)
+	if err := db.Model(&schemas.User{}).Where("id = ?", suspended.ID).Update("status", schemas.UserStatusSuspended).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	projectUser := uuid.New()
+	projectUsers := &mocks.ProjectUserManager{
+		GetProjectUserFunc: func(_ context.Context, projectID string, id uuid.UUID, _ bool) (*models.DisplayUser, error) {
+			if id != projectUser {
+				return nil, projectusers.ErrUserNotFound
+			}
+			return &models.DisplayUser{ID: id.String(), ProjectID: projectID, Status: schemas.UserStatusActive}, nil
+		},
+	}
+	endpoint := endpoints.NewIntrospectionEndpoint(users.NewAuthUsers(db, 0), projectUsers, 0, 0)
+	if endpoint.MaxBatch != endpoints.DefaultIntrospectionBatch || endpoint.Workers != endpoints.DefaultIntrospectionWorkers {
+		t.Fatalf("defaults = %d, %d", endpoint.MaxBatch, endpoint.Workers)
+	}
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+
+	response, err := endpoint.IntrospectBatch(ctx, endpoints.IntrospectBatchRequest{Tokens: []string{
+		issue(t, global.ID, built.Project.ID, later),
+		issue(t, projectUser, built.Project.ID, later),
+		issue(t, suspended.ID, built.Project.ID, later),
+		issue(t, uuid.New(), built.Project.ID, later),
+		issue(t, global.ID, built.Project.ID, time.Now().Add(-time.Hour)),
+		"not-a-token",
+	}})
+	if err != nil {
+		t.Fatalf("IntrospectBatch: %v", err)
+	}
+	batch := response.(endpoints.IntrospectBatchResponse)
+	if batch.Active != 2 {
+		t.Fatalf("active = %d, want 2", batch.Active)
+	}
+	first := batch.Results[0]
+	if !first.Active || first.UserID != global.ID.String() || first.ProjectID != built.Project.ID.String() || first.ExpiresAt == nil || first.IssuedAt == nil {
+		t.Fatalf("global user = %+v", first)
+	}
+	if !batch.Results[1].Active || batch.Results[1].UserID != projectUser.String() {
+		t.Fatalf("project user = %+v", batch.Results[1])
+	}
+	for i, reason := range []string{endpoints.InactiveUserNotActive, endpoints.InactiveUserNotFound, "expired", "malformed"} {
+		if result := batch.Results[i+2]; result.Active || result.Reason != reason || result.UserID != "" {
+			t.Errorf("result %d = %+v, want inactive for %s", i+2, result, reason)
+		}
+	}
+
+	projectUsers.GetProjectUserFunc = func(context.Context, string, uuid.UUID, bool) (*models.DisplayUser, error) {
+		return nil, errors.New("boom")
+	}
+	testutil.CaptureKlog(t)
+	if _, err := endpoint.IntrospectBatch(ctx, endpoints.IntrospectBatchRequest{Tokens: []string{issue(t, uuid.New(), built.Project.ID, later)}}); err == nil {
+		t.Fatal("IntrospectBatch succeeded although a holder could not be looked up")
+	}
+}
+
+func TestIntrospectBatchLimits(t *testing.T) {
+	endpoint := endpoints.NewIntrospectionEndpoint(nil, nil, 2, 1)
+	ctx := context.Background()
+
+	_, err := endpoint.IntrospectBatch(ctx, endpoints.IntrospectBatchRequest{})
+	wantCode(t, err, "TOKENS_REQUIRED")
+	_, err = endpoint.IntrospectBatch(ctx, endpoints.IntrospectBatchRequest{Tokens: []string{"a", "b", "c"}})
+	wantCode(t, err, "TOO_MANY_TOKENS")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.IntrospectBatch(ctx, r) })
+}
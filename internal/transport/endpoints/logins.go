@@ -0,0 +1,77 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/logins"
+)
+
+// ListLoginsRequest represents the list login history request
+type ListLoginsRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+	Limit     int    `json:"-"`
+}
+
+// LoginEvent represents a login in API responses
+type LoginEvent struct {
+	ID         string    `json:"id"`
+	Method     string    `json:"method"`
+	IPAddress  string    `json:"ip_address"`
+	Suspicious bool      `json:"suspicious"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListLoginsResponse represents the list login history response
+type ListLoginsResponse struct {
+	Logins []LoginEvent `json:"logins"`
+}
+
+// LoginsEndpoint handles login history endpoints
+type LoginsEndpoint struct {
+	LoginManager logins.LoginManager
+}
+
+// NewLoginsEndpoint creates a new login history endpoint
+func NewLoginsEndpoint(manager logins.LoginManager) *LoginsEndpoint {
+	return &LoginsEndpoint{
+		LoginManager: manager,
+	}
+}
+
+func toLoginEvent(event schemas.LoginEvent) LoginEvent {
+	return LoginEvent{
+		ID:         event.ID.String(),
+		Method:     event.Method,
+		IPAddress:  event.IPAddress,
+		Suspicious: event.Suspicious,
+		CreatedAt:  event.CreatedAt,
+	}
+}
+
+// ListLogins lists a project user's most recent logins
+func (e *LoginsEndpoint) ListLogins(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListLoginsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := e.LoginManager.ListLogins(ctx, projectID, userID, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := ListLoginsResponse{Logins: make([]LoginEvent, 0, len(events))}
+	for _, event := range events {
+		response.Logins = append(response.Logins, toLoginEvent(event))
+	}
+	return response, nil
+}
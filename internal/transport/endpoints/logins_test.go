@@ -0,0 +1,63 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestListLogins(t *testing.T) {
+	projectID, userID := uuid.New(), uuid.New()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := schemas.LoginEvent{ID: uuid.New(), Method: "password", IPAddress: "10.0.0.1", Suspicious: true, CreatedAt: at}
+	manager := &mocks.LoginManager{
+		ListLoginsFunc: func(_ context.Context, pid, uid uuid.UUID, limit int) ([]schemas.LoginEvent, error) {
+			if pid != projectID || uid != userID || limit != 5 {
+				t.Errorf("ListLogins(%v, %v, %d)", pid, uid, limit)
+			}
+			return []schemas.LoginEvent{event}, nil
+		},
+	}
+	endpoint := endpoints.NewLoginsEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListLogins(ctx, endpoints.ListLoginsRequest{ProjectID: projectID.String(), UserID: userID.String(), Limit: 5})
+	if err != nil {
+		t.Fatalf("ListLogins: %v", err)
+	}
+	want := endpoints.LoginEvent{ID: event.ID.String(), Method: "password", IPAddress: "10.0.0.1", Suspicious: true, CreatedAt: at}
+	if got := response.(endpoints.ListLoginsResponse).Logins; len(got) != 1 || got[0] != want {
+		t.Fatalf("logins = %+v, want [%+v]", got, want)
+	}
+
+	manager.ListLoginsFunc = func(context.Context, uuid.UUID, uuid.UUID, int) ([]schemas.LoginEvent, error) { return nil, nil }
+	response, err = endpoint.ListLogins(ctx, endpoints.ListLoginsRequest{ProjectID: projectID.String(), UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("ListLogins: %v", err)
+	}
+	if got := response.(endpoints.ListLoginsResponse).Logins; got == nil || len(got) != 0 {
+		t.Fatalf("logins = %#v, want an empty list", got)
+	}
+
+	failure := errors.New("boom")
+	manager.ListLoginsFunc = func(context.Context, uuid.UUID, uuid.UUID, int) ([]schemas.LoginEvent, error) { return nil, failure }
+	if _, err := endpoint.ListLogins(ctx, endpoints.ListLoginsRequest{ProjectID: projectID.String(), UserID: userID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	for _, req := range []endpoints.ListLoginsRequest{
+		{ProjectID: "nope", UserID: userID.String()},
+		{ProjectID: projectID.String(), UserID: "nope"},
+	} {
+		if _, err := endpoint.ListLogins(ctx, req); err == nil {
+			t.Errorf("ListLogins(%+v) accepted a malformed ID", req)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListLogins(ctx, r) })
+}
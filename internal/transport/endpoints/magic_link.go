@@ -0,0 +1,107 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/magiclink"
+)
+
+// RequestMagicLinkRequest represents the request magic link request
+type RequestMagicLinkRequest struct {
+	ProjectID string `json:"-"` // From URL path
+	Email     string `json:"email"`
+}
+
+// RequestMagicLinkResponse represents the request magic link response. It is
+// the same whether or not the email belongs to a user.
+type RequestMagicLinkResponse struct {
+	Message string `json:"message"`
+}
+
+// VerifyMagicLinkRequest represents the verify magic link request
+type VerifyMagicLinkRequest struct {
+	ProjectID string `json:"-"`
+	Token     string `json:"-"` // From the token query parameter
+}
+
+// VerifyMagicLinkResponse represents the verify magic link response
+type VerifyMagicLinkResponse struct {
+	Token     string             `json:"token"`
+	User      models.DisplayUser `json:"user"`
+	ExpiresIn int64              `json:"expires_in"`
+	consentsPending
+}
+
+// MagicLinkEndpoint handles magic link login endpoints
+type MagicLinkEndpoint struct {
+	MagicLinkManager magiclink.MagicLinkManager
+	// Consents tells which documents users logging in have yet to accept;
+	// nil flags none
+	Consents consents.ConsentManager
+}
+
+// NewMagicLinkEndpoint creates a new magic link endpoint
+func NewMagicLinkEndpoint(manager magiclink.MagicLinkManager, consentManager consents.ConsentManager) *MagicLinkEndpoint {
+	return &MagicLinkEndpoint{
+		MagicLinkManager: manager,
+		Consents:         consentManager,
+	}
+}
+
+// RequestMagicLink emails a login link to a project user
+func (e *MagicLinkEndpoint) RequestMagicLink(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RequestMagicLinkRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	if err := e.MagicLinkManager.RequestLink(ctx, projectID, req.Email); err != nil {
+		return nil, err
+	}
+
+	return RequestMagicLinkResponse{
+		Message: "if the email belongs to an account, a sign-in link has been sent",
+	}, nil
+}
+
+// VerifyMagicLink exchanges a magic link token for a JWT
+func (e *MagicLinkEndpoint) VerifyMagicLink(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(VerifyMagicLinkRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	session, err := e.MagicLinkManager.Verify(ctx, projectID, req.Token)
+	auth.RecordLogin(auth.MethodMagicLink, projectID.String(), err)
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding, err := outstandingConsents(ctx, e.Consents, session.User.ProjectID, session.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyMagicLinkResponse{
+		Token:           session.Token,
+		User:            session.User,
+		ExpiresIn:       session.ExpiresAt.Unix() - time.Now().Unix(),
+		consentsPending: consentsPending{OutstandingConsents: outstanding},
+	}, nil
+}
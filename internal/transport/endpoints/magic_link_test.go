@@ -0,0 +1,119 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/magiclink"
+	"github.com/yash3004/user_management_service/onetime"
+)
+
+func TestRequestMagicLink(t *testing.T) {
+	projectID := uuid.New()
+	var sent string
+	manager := &mocks.MagicLinkManager{
+		RequestLinkFunc: func(_ context.Context, id uuid.UUID, email string) error {
+			if id != projectID {
+				t.Errorf("project = %v, want %v", id, projectID)
+			}
+			sent = email
+			return nil
+		},
+	}
+	endpoint := endpoints.NewMagicLinkEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.RequestMagicLink(ctx, endpoints.RequestMagicLinkRequest{ProjectID: projectID.String(), Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	if sent != "ada@example.com" || response.(endpoints.RequestMagicLinkResponse).Message == "" {
+		t.Fatalf("sent to %q, response %+v", sent, response)
+	}
+
+	failure := errors.New("boom")
+	manager.RequestLinkFunc = func(context.Context, uuid.UUID, string) error { return failure }
+	if _, err := endpoint.RequestMagicLink(ctx, endpoints.RequestMagicLinkRequest{ProjectID: projectID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.RequestMagicLink(ctx, endpoints.RequestMagicLinkRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("RequestMagicLink accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RequestMagicLink(ctx, r) })
+}
+
+func TestVerifyMagicLink(t *testing.T) {
+	projectID := uuid.New()
+	user := models.DisplayUser{ID: uuid.NewString(), Email: "ada@example.com", ProjectID: projectID.String()}
+	manager := &mocks.MagicLinkManager{
+		VerifyFunc: func(_ context.Context, id uuid.UUID, token string) (*magiclink.Session, error) {
+			if token != "link" {
+				return nil, onetime.ErrInvalidToken
+			}
+			return &magiclink.Session{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour), User: user}, nil
+		},
+	}
+	endpoint := endpoints.NewMagicLinkEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.VerifyMagicLink(ctx, endpoints.VerifyMagicLinkRequest{ProjectID: projectID.String(), Token: "link"})
+	if err != nil {
+		t.Fatalf("VerifyMagicLink: %v", err)
+	}
+	session := response.(endpoints.VerifyMagicLinkResponse)
+	if session.Token != "jwt" || session.User.Email != "ada@example.com" || session.ExpiresIn < 3590 || session.ExpiresIn > 3600 {
+		t.Fatalf("session = %+v", session)
+	}
+
+	if _, err := endpoint.VerifyMagicLink(ctx, endpoints.VerifyMagicLinkRequest{ProjectID: projectID.String(), Token: "stale"}); err != onetime.ErrInvalidToken {
+		t.Fatalf("err = %v, want %v", err, onetime.ErrInvalidToken)
+	}
+	if _, err := endpoint.VerifyMagicLink(ctx, endpoints.VerifyMagicLinkRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("VerifyMagicLink accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.VerifyMagicLink(ctx, r) })
+}
+
+func TestVerifyMagicLinkListsOutstandingConsents(t *testing.T) {
+	projectID, userID := uuid.New(), uuid.New()
+	user := models.DisplayUser{ID: userID.String(), Email: "ada@example.com", ProjectID: projectID.String()}
+	manager := &mocks.MagicLinkManager{
+		VerifyFunc: func(context.Context, uuid.UUID, string) (*magiclink.Session, error) {
+			return &magiclink.Session{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour), User: user}, nil
+		},
+	}
+	consentManager := &mocks.ConsentManager{
+		OutstandingFunc: func(_ context.Context, pid, uid uuid.UUID) ([]consents.Outstanding, error) {
+			if pid != projectID || uid != userID {
+				t.Errorf("Outstanding(%v, %v)", pid, uid)
+			}
+			return []consents.Outstanding{{Document: "terms", Version: "2"}}, nil
+		},
+	}
+	endpoint := endpoints.NewMagicLinkEndpoint(manager, consentManager)
+	ctx := context.Background()
+
+	response, err := endpoint.VerifyMagicLink(ctx, endpoints.VerifyMagicLinkRequest{ProjectID: projectID.String(), Token: "link"})
+	if err != nil {
+		t.Fatalf("VerifyMagicLink: %v", err)
+	}
+	if outstanding := response.(endpoints.VerifyMagicLinkResponse).OutstandingConsents; len(outstanding) != 1 || outstanding[0].Document != "terms" {
+		t.Fatalf("outstanding consents = %+v", outstanding)
+	}
+
+	endpoint.Consents = nil
+	response, err = endpoint.VerifyMagicLink(ctx, endpoints.VerifyMagicLinkRequest{ProjectID: projectID.String(), Token: "link"})
+	if err != nil {
+		t.Fatalf("VerifyMagicLink: %v", err)
+	}
+	if outstanding := response.(endpoints.VerifyMagicLinkResponse).OutstandingConsents; outstanding != nil {
+		t.Fatalf("outstanding consents without a consent manager = %+v", outstanding)
+	}
+}
@@ -0,0 +1,161 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/policies"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// ErrNotSignedIn is returned when a request about the caller carries no
+// authenticated user
+var ErrNotSignedIn = apierrors.New(http.StatusUnauthorized, "NOT_SIGNED_IN", "authentication required")
+
+// GetMyContextRequest represents the get current user context request
+type GetMyContextRequest struct {
+	User *schemas.User `json:"-"` // The authenticated caller, nil if none
+}
+
+// ChangeMyPasswordRequest represents the change own password request
+type ChangeMyPasswordRequest struct {
+	User            *schemas.User `json:"-"` // The authenticated caller, nil if none
+	CurrentPassword string        `json:"current_password"`
+	NewPassword     string        `json:"new_password"`
+}
+
+// ProjectContext is what the caller holds in one of its projects
+type ProjectContext struct {
+	ProjectID string   `json:"project_id"`
+	Primary   bool     `json:"primary"`
+	Role      *Role    `json:"role"` // nil if the role no longer exists
+	Policies  []Policy `json:"policies"`
+}
+
+// GetMyContextResponse represents the get current user context response
+type GetMyContextResponse struct {
+	User     models.DisplayUser `json:"user"`
+	Projects []ProjectContext   `json:"projects"`
+}
+
+// MeEndpoint handles the endpoints a signed-in user calls about themselves
+type MeEndpoint struct {
+	UserManager   users.UserManager
+	RoleManager   roles.RoleManager
+	PolicyManager policies.PolicyManager
+}
+
+// NewMeEndpoint creates a new me endpoint
+func NewMeEndpoint(userManager users.UserManager, roleManager roles.RoleManager, policyManager policies.PolicyManager) *MeEndpoint {
+	return &MeEndpoint{
+		UserManager:   userManager,
+		RoleManager:   roleManager,
+		PolicyManager: policyManager,
+	}
+}
+
+// GetMyContext returns the caller's profile with, for every project it can
+// access, the role it holds there and that role's effective policies. The
+// primary project comes first.
+func (e *MeEndpoint) GetMyContext(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetMyContextRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if req.User == nil {
+		return nil, ErrNotSignedIn
+	}
+	// The request carries only what authentication needs of the user
+	user, err := e.UserManager.GetUser(ctx, req.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := e.UserManager.ListUserProjects(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Several projects may share a global role
+	roleCache := make(map[uuid.UUID]*Role)
+	projects := make([]ProjectContext, len(memberships))
+	for i, m := range memberships {
+		role, seen := roleCache[m.RoleId]
+		if !seen {
+			found, err := e.RoleManager.GetRole(ctx, m.RoleId)
+			if err != nil && !errors.Is(err, roles.ErrRoleNotFound) {
+				return nil, err
+			}
+			if found != nil {
+				role = &Role{
+					ID:           found.ID.String(),
+					Name:         found.Name,
+					Description:  found.Description,
+					AllowedCIDRs: nonNil(found.AllowedCIDRs),
+					CreatedAt:    found.CreatedAt,
+					UpdatedAt:    found.UpdatedAt,
+					ProjectID:    optionalUUIDString(found.ProjectId),
+					CreatedBy:    creatorString(found.CreatedBy),
+				}
+			}
+			roleCache[m.RoleId] = role
+		}
+
+		effective, err := e.PolicyManager.RolePolicies(ctx, m.ProjectId, m.RoleId)
+		if err != nil {
+			return nil, err
+		}
+		policyList := make([]Policy, len(effective))
+		for j, p := range effective {
+			policyList[j] = Policy{
+				ID:          p.ID.String(),
+				Name:        p.Name,
+				Description: p.Description,
+				Resource:    p.Resource,
+				Action:      p.Action,
+				Effect:      p.Effect,
+				CreatedAt:   p.CreatedAt,
+				UpdatedAt:   p.UpdatedAt,
+				ProjectID:   optionalUUIDString(p.ProjectId),
+				CreatedBy:   creatorString(p.CreatedBy),
+				IsSystem:    p.IsSystem,
+			}
+		}
+
+		projects[i] = ProjectContext{
+			ProjectID: m.ProjectId.String(),
+			Primary:   i == 0,
+			Role:      role,
+			Policies:  policyList,
+		}
+	}
+
+	return GetMyContextResponse{
+		User:     displayUser(*user),
+		Projects: projects,
+	}, nil
+}
+
+// ChangeMyPassword changes the caller's password. It is the one route a token
+// issued for an expired password may call; the user logs in again with the
+// new password for a token without that restriction.
+func (e *MeEndpoint) ChangeMyPassword(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ChangeMyPasswordRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if req.User == nil {
+		return nil, ErrNotSignedIn
+	}
+
+	if err := e.UserManager.ChangePassword(ctx, req.User.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		return nil, err
+	}
+	return ChangePasswordResponse{Success: true}, nil
+}
@@ -0,0 +1,134 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+func TestGetMyContext(t *testing.T) {
+	user := &schemas.User{ID: uuid.New(), Email: "ada@example.com", Status: schemas.UserStatusActive}
+	primary, secondary, third := uuid.New(), uuid.New(), uuid.New()
+	sharedRole, deletedRole := uuid.New(), uuid.New()
+	roleLookups := 0
+
+	userManager := &mocks.UserManager{
+		GetUserFunc: func(_ context.Context, id uuid.UUID) (*schemas.User, error) {
+			if id != user.ID {
+				t.Errorf("GetUser(%v)", id)
+			}
+			return user, nil
+		},
+		ListUserProjectsFunc: func(context.Context, uuid.UUID) ([]schemas.UserProjectMembership, error) {
+			return []schemas.UserProjectMembership{
+				{UserId: user.ID, ProjectId: primary, RoleId: sharedRole},
+				{UserId: user.ID, ProjectId: secondary, RoleId: sharedRole},
+				{UserId: user.ID, ProjectId: third, RoleId: deletedRole},
+			}, nil
+		},
+	}
+	roleManager := &mocks.RoleManager{
+		GetRoleFunc: func(_ context.Context, id uuid.UUID) (*schemas.Role, error) {
+			roleLookups++
+			if id == deletedRole {
+				return nil, roles.ErrRoleNotFound
+			}
+			return &schemas.Role{ID: id, Name: "member"}, nil
+		},
+	}
+	policyManager := &mocks.PolicyManager{
+		RolePoliciesFunc: func(_ context.Context, projectID, roleID uuid.UUID) ([]schemas.Policy, error) {
+			if projectID == primary {
+				return []schemas.Policy{{ID: uuid.New(), Name: "read-users", Resource: "users", Action: "read", Effect: "allow", ProjectId: &projectID}}, nil
+			}
+			return nil, nil
+		},
+	}
+	endpoint := endpoints.NewMeEndpoint(userManager, roleManager, policyManager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetMyContext(ctx, endpoints.GetMyContextRequest{User: &schemas.User{ID: user.ID}})
+	if err != nil {
+		t.Fatalf("GetMyContext: %v", err)
+	}
+	me := response.(endpoints.GetMyContextResponse)
+	if me.User.Email != "ada@example.com" || len(me.Projects) != 3 {
+		t.Fatalf("context = %+v", me)
+	}
+	if roleLookups != 2 {
+		t.Fatalf("looked up %d roles, want each distinct role once", roleLookups)
+	}
+
+	first := me.Projects[0]
+	if !first.Primary || first.ProjectID != primary.String() || first.Role == nil || first.Role.Name != "member" || first.Role.AllowedCIDRs == nil {
+		t.Fatalf("primary project = %+v", first)
+	}
+	if len(first.Policies) != 1 || first.Policies[0].Name != "read-users" || first.Policies[0].ProjectID != primary.String() || first.Policies[0].CreatedBy != "system" {
+		t.Fatalf("primary project policies = %+v", first.Policies)
+	}
+	if second := me.Projects[1]; second.Primary || second.Policies == nil || len(second.Policies) != 0 {
+		t.Fatalf("second project = %+v", second)
+	}
+	if me.Projects[2].Role != nil {
+		t.Fatalf("a deleted role is reported as %+v", me.Projects[2].Role)
+	}
+}
+
+func TestGetMyContextErrors(t *testing.T) {
+	failure := errors.New("boom")
+	userManager := &mocks.UserManager{
+		GetUserFunc: func(context.Context, uuid.UUID) (*schemas.User, error) { return nil, failure },
+	}
+	endpoint := endpoints.NewMeEndpoint(userManager, nil, nil)
+	ctx := context.Background()
+
+	if _, err := endpoint.GetMyContext(ctx, endpoints.GetMyContextRequest{}); err != endpoints.ErrNotSignedIn {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrNotSignedIn)
+	}
+	if _, err := endpoint.GetMyContext(ctx, endpoints.GetMyContextRequest{User: &schemas.User{ID: uuid.New()}}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetMyContext(ctx, r) })
+}
+
+func TestChangeMyPassword(t *testing.T) {
+	userID := uuid.New()
+	userManager := &mocks.UserManager{
+		ChangePasswordFunc: func(_ context.Context, id uuid.UUID, current, next string) error {
+			if id != userID || current != "old-password" || next != "new-password" {
+				t.Errorf("ChangePassword(%v, %q, %q)", id, current, next)
+			}
+			return nil
+		},
+	}
+	endpoint := endpoints.NewMeEndpoint(userManager, nil, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ChangeMyPassword(ctx, endpoints.ChangeMyPasswordRequest{
+		User:            &schemas.User{ID: userID},
+		CurrentPassword: "old-password",
+		NewPassword:     "new-password",
+	})
+	if err != nil {
+		t.Fatalf("ChangeMyPassword: %v", err)
+	}
+	if !response.(endpoints.ChangePasswordResponse).Success {
+		t.Fatalf("response = %+v", response)
+	}
+
+	if _, err := endpoint.ChangeMyPassword(ctx, endpoints.ChangeMyPasswordRequest{}); err != endpoints.ErrNotSignedIn {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrNotSignedIn)
+	}
+	failure := errors.New("wrong password")
+	userManager.ChangePasswordFunc = func(context.Context, uuid.UUID, string, string) error { return failure }
+	if _, err := endpoint.ChangeMyPassword(ctx, endpoints.ChangeMyPasswordRequest{User: &schemas.User{ID: userID}}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ChangeMyPassword(ctx, r) })
+}
@@ -0,0 +1,80 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// MaxBulkItems is the most items a bulk request answered item by item may name
+const MaxBulkItems = 1000
+
+// ItemResult is the outcome of one item of a bulk request: the item's ID as
+// the request named it, the status a single request for it would have had,
+// and the error of a failed item
+type ItemResult struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MultiStatusResponse answers a bulk request whose items succeed or fail on
+// their own. It is sent as 200 OK when every item succeeded and as 207
+// Multi-Status otherwise, so clients check Results either way.
+type MultiStatusResponse struct {
+	Results   []ItemResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// StatusCode implements kithttp.StatusCoder
+func (r MultiStatusResponse) StatusCode() int {
+	if r.Failed > 0 {
+		return http.StatusMultiStatus
+	}
+	return http.StatusOK
+}
+
+// add records the outcome of one item: success with status when err is nil,
+// otherwise a failure with the status and code the error would have had as
+// the response to a single request
+func (r *MultiStatusResponse) add(id string, status int, err error) {
+	if err == nil {
+		r.Results = append(r.Results, ItemResult{ID: id, Status: status})
+		r.Succeeded++
+		return
+	}
+
+	result := ItemResult{ID: id, Status: http.StatusInternalServerError, Error: err.Error()}
+	var apiErr *apierrors.Error
+	if errors.As(err, &apiErr) {
+		result.Status = apiErr.StatusCode()
+		result.Code = apiErr.Code
+	}
+	r.Results = append(r.Results, result)
+	r.Failed++
+}
+
+// bulkIDs checks the size of a bulk request and drops repeated IDs, keeping
+// the first of each
+func bulkIDs(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, apierrors.BadRequest("IDS_REQUIRED", "ids must name at least one item")
+	}
+	if len(ids) > MaxBulkItems {
+		return nil, apierrors.BadRequest("TOO_MANY_ITEMS", fmt.Sprintf("at most %d items can be changed at once", MaxBulkItems))
+	}
+
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
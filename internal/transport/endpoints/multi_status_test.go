@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+func TestMultiStatusResponse(t *testing.T) {
+	var response MultiStatusResponse
+	response.add("a", http.StatusOK, nil)
+	if code := response.StatusCode(); code != http.StatusOK {
+		t.Fatalf("status = %d with every item succeeded, want 200", code)
+	}
+
+	response.add("b", http.StatusOK, apierrors.NotFound("user not found"))
+	response.add("c", http.StatusOK, errors.New("boom"))
+	if code := response.StatusCode(); code != http.StatusMultiStatus {
+		t.Fatalf("status = %d with failed items, want 207", code)
+	}
+	if response.Succeeded != 1 || response.Failed != 2 {
+		t.Fatalf("succeeded %d, failed %d", response.Succeeded, response.Failed)
+	}
+
+	want := []ItemResult{
+		{ID: "a", Status: http.StatusOK},
+		{ID: "b", Status: http.StatusNotFound, Code: "NOT_FOUND", Error: "user not found"},
+		{ID: "c", Status: http.StatusInternalServerError, Error: "boom"},
+	}
+	if !reflect.DeepEqual(response.Results, want) {
+		t.Fatalf("results = %+v, want %+v", response.Results, want)
+	}
+}
+
+func TestBulkIDs(t *testing.T) {
+	ids, err := bulkIDs([]string{"a", "b", "a"})
+	if err != nil || !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Fatalf("bulkIDs = %v, %v", ids, err)
+	}
+	if _, err := bulkIDs(nil); err == nil {
+		t.Fatal("bulkIDs accepted no IDs")
+	}
+	if _, err := bulkIDs(make([]string, MaxBulkItems+1)); err == nil {
+		t.Fatal("bulkIDs accepted more than MaxBulkItems")
+	}
+}
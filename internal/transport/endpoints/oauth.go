@@ -2,21 +2,31 @@ package endpoints
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
 	"github.com/yash3004/user_management_service/internal/models"
-	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/oauthlogin"
+	"github.com/yash3004/user_management_service/onetime"
+	"github.com/yash3004/user_management_service/projects"
 )
 
+// OAuthStateTTL is how long a user has to complete an OAuth login unless
+// configured otherwise
+const OAuthStateTTL = 10 * time.Minute
+
 // OAuthLoginRequest represents the OAuth login request
 type OAuthLoginRequest struct {
 	Provider  string `json:"provider"`
 	ProjectID string `json:"project_id"`
 	RoleID    string `json:"role_id"`
-	State     string `json:"state"`
 }
 
 // OAuthLoginResponse represents the OAuth login response
@@ -24,13 +34,48 @@ type OAuthLoginResponse struct {
 	RedirectURL string `json:"redirect_url"`
 }
 
+// ErrProviderNotEnabled is returned for an OAuth login to a project with a
+// provider the project does not offer
+var ErrProviderNotEnabled = apierrors.BadRequest("PROVIDER_NOT_ENABLED", "this oauth provider is not enabled for the project")
+
+// Where the client of a provider a project offers comes from
+const (
+	ProviderSourceProject = "project" // The project's own oauth-providers override
+	ProviderSourceGlobal  = "global"  // The oauth configuration of the service
+)
+
+// ListOAuthProvidersRequest represents the request for the OAuth providers
+// a project offers
+type ListOAuthProvidersRequest struct {
+	ProjectID string `json:"-"` // From URL path
+}
+
+// OAuthProvider is an OAuth provider a project offers
+type OAuthProvider struct {
+	Name   string   `json:"name"`
+	Source string   `json:"source"` // ProviderSourceProject or ProviderSourceGlobal
+	Scopes []string `json:"scopes"`
+}
+
+// ListOAuthProvidersResponse represents the OAuth providers a project offers
+type ListOAuthProvidersResponse struct {
+	ProjectID uuid.UUID       `json:"project_id"`
+	Providers []OAuthProvider `json:"providers"`
+}
+
 // OAuthCallbackRequest represents the OAuth callback request
 type OAuthCallbackRequest struct {
-	Provider  string `json:"provider"`
+	Provider string `json:"provider"`
+	Code     string `json:"code"`
+	State    string `json:"state"`
+}
+
+// oauthState is what an OAuth state token stands for. The state sent to the
+// provider is an opaque one-time token, so it cannot be forged or replayed.
+type oauthState struct {
 	ProjectID string `json:"project_id"`
-	Code      string `json:"code"`
-	State     string `json:"state"`
 	RoleID    string `json:"role_id"`
+	Provider  string `json:"provider"`
 }
 
 // OAuthCallbackResponse represents the OAuth callback response
@@ -38,19 +83,112 @@ type OAuthCallbackResponse struct {
 	Token     string             `json:"token"`
 	User      models.DisplayUser `json:"user"`
 	ExpiresIn int64              `json:"expires_in"`
+	consentsPending
 }
 
 // OAuthEndpoint handles OAuth-related endpoints
 type OAuthEndpoint struct {
-	ProjectUser     projectusers.ProjectUserManager
+	Logins          oauthlogin.LoginService
+	Projects        projects.ProjectManager
+	States          onetime.Store
 	ProviderFactory *oauth.ProviderFactory
+	// StateTTL is how long a user has to complete a login; 0 means
+	// OAuthStateTTL
+	StateTTL time.Duration
+	// Consents tells which documents users logging in have yet to accept;
+	// nil flags none
+	Consents consents.ConsentManager
 }
 
-func NewOAuthEndpoint(userManager projectusers.ProjectUserManager, providerFactory *oauth.ProviderFactory) *OAuthEndpoint {
+func NewOAuthEndpoint(loginService oauthlogin.LoginService, projectManager projects.ProjectManager, states onetime.Store, providerFactory *oauth.ProviderFactory, consentManager consents.ConsentManager) *OAuthEndpoint {
 	return &OAuthEndpoint{
-		ProjectUser:     userManager,
+		Logins:          loginService,
+		Projects:        projectManager,
+		States:          states,
 		ProviderFactory: providerFactory,
+		Consents:        consentManager,
+	}
+}
+
+// projectProvider returns the named provider for a project, built from the
+// project's own client credentials when it has them. Those are built once and
+// reused until the project's overrides change.
+func (e *OAuthEndpoint) projectProvider(ctx context.Context, projectID, name string) (oauth.Provider, error) {
+	id, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+	project, ok := projects.ProjectFromContext(ctx)
+	if !ok || project.ID != id {
+		if project, err = e.Projects.GetProject(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	switch e.providerSource(project.Settings, name) {
+	case "":
+		return nil, ErrProviderNotEnabled
+	case ProviderSourceGlobal:
+		return e.ProviderFactory.GetProvider(name)
+	}
+	override := project.Settings.OAuthProviders[name]
+	return e.ProviderFactory.GetProjectProvider(project.ID, name, &oauth.ProviderConfig{
+		ClientID:     override.ClientID,
+		ClientSecret: override.ClientSecret,
+		RedirectURL:  override.RedirectURL,
+		Scopes:       override.Scopes,
+	})
+}
+
+// providerSource returns where a project's client for a provider comes
+// from, or "" when the project does not offer the provider: there is no
+// client for it, or the project's oauth_login providers leave it out
+func (e *OAuthEndpoint) providerSource(settings schemas.ProjectSettings, name string) string {
+	if !oauth.IsSupported(name) || !settings.OAuthLogin.Offers(name) {
+		return ""
 	}
+	if _, ok := settings.OAuthProviders[name]; ok {
+		return ProviderSourceProject
+	}
+	if e.ProviderFactory.Configured(name) {
+		return ProviderSourceGlobal
+	}
+	return ""
+}
+
+// ListProviders lists the OAuth providers a project offers, for a login page
+// to show. Providers without a client, globally or for the project, are left
+// out, as are those the project's oauth_login providers do not name.
+func (e *OAuthEndpoint) ListProviders(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListOAuthProvidersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	id, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+	project, ok := projects.ProjectFromContext(ctx)
+	if !ok || project.ID != id {
+		if project, err = e.Projects.GetProject(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	response := ListOAuthProvidersResponse{ProjectID: project.ID, Providers: []OAuthProvider{}}
+	for _, name := range oauth.SupportedProviders {
+		source := e.providerSource(project.Settings, name)
+		if source == "" {
+			continue
+		}
+		response.Providers = append(response.Providers, OAuthProvider{
+			Name:   name,
+			Source: source,
+			Scopes: e.ProviderFactory.Scopes(name, project.Settings.OAuthProviders[name].Scopes),
+		})
+	}
+	return response, nil
 }
 
 // Login initiates the OAuth login flow
@@ -60,66 +198,109 @@ func (e *OAuthEndpoint) Login(ctx context.Context, request interface{}) (interfa
 		return nil, errors.New("invalid request format")
 	}
 
-	provider, err := e.ProviderFactory.GetProvider(req.Provider)
+	if _, err := uuid.Parse(req.RoleID); err != nil {
+		return nil, errors.New("invalid role ID format")
+	}
+	redirectURL, err := e.loginURL(ctx, req.ProjectID, req.RoleID, req.Provider)
 	if err != nil {
 		return nil, err
 	}
 
-	redirectURL := provider.GetAuthURL(req.State)
-
 	return OAuthLoginResponse{
 		RedirectURL: redirectURL,
 	}, nil
 }
 
+func (e *OAuthEndpoint) stateTTL() time.Duration {
+	if e.StateTTL <= 0 {
+		return OAuthStateTTL
+	}
+	return e.StateTTL
+}
+
+// loginURL returns the provider's authorization URL for a login to the
+// project, carrying a new state token registered in the one-time store
+func (e *OAuthEndpoint) loginURL(ctx context.Context, projectID, roleID, providerName string) (string, error) {
+	provider, err := e.projectProvider(ctx, projectID, providerName)
+	if err != nil {
+		return "", err
+	}
+
+	subject, err := json.Marshal(oauthState{
+		ProjectID: projectID,
+		RoleID:    roleID,
+		Provider:  providerName,
+	})
+	if err != nil {
+		return "", err
+	}
+	state, err := e.States.Issue(ctx, onetime.PurposeOAuthState, string(subject), e.stateTTL())
+	if err != nil {
+		return "", err
+	}
+
+	return provider.GetAuthURL(state), nil
+}
+
+// Callback completes the OAuth login flow once the provider redirects back
 func (e *OAuthEndpoint) Callback(ctx context.Context, request interface{}) (interface{}, error) {
 	req, ok := request.(OAuthCallbackRequest)
 	if !ok {
 		return nil, errors.New("invalid request format")
 	}
 
-	provider, err := e.ProviderFactory.GetProvider(req.Provider)
-	if err != nil {
-		return nil, err
-	}
+	response, projectID, err := e.callback(ctx, req)
+	auth.RecordLogin(auth.MethodOAuth, projectID, err)
+	return response, err
+}
 
-	// Exchange the code for a token
-	token, err := provider.Exchange(ctx, req.Code)
+// callback completes the login and also returns the project it was made to,
+// which is empty when the state was not valid
+func (e *OAuthEndpoint) callback(ctx context.Context, req OAuthCallbackRequest) (interface{}, string, error) {
+	// The state is consumed before anything else, so a replayed or forged
+	// callback is rejected without contacting the provider
+	subject, err := e.States.Consume(ctx, onetime.PurposeOAuthState, req.State)
 	if err != nil {
-		return nil, errors.New("failed to exchange code for token")
+		return nil, "", err
+	}
+	var state oauthState
+	if err := json.Unmarshal([]byte(subject), &state); err != nil || state.Provider != req.Provider {
+		return nil, "", onetime.ErrInvalidToken
 	}
 
-	userInfo, err := provider.GetUserInfo(ctx, token)
+	provider, err := e.projectProvider(ctx, state.ProjectID, req.Provider)
 	if err != nil {
-		return nil, errors.New("failed to get user info")
+		return nil, state.ProjectID, err
 	}
 
-	projectID := req.ProjectID
-	roleID, err := uuid.Parse(req.RoleID)
+	projectID, err := uuid.Parse(state.ProjectID)
 	if err != nil {
-		return nil, errors.New("invalid role ID format")
+		return nil, state.ProjectID, errors.New("invalid project ID format")
 	}
-
-	// Create or update the user in our system
-	user, err := e.ProjectUser.CreateOrUpdateOAuthProjectUser(ctx, projectID, userInfo, roleID)
+	roleID, err := uuid.Parse(state.RoleID)
 	if err != nil {
-		return nil, err
+		return nil, state.ProjectID, errors.New("invalid role ID format")
 	}
 
-	// Generate a token for the user
-	userID, err := uuid.Parse(user.ID)
+	result, err := e.Logins.CompleteLogin(ctx, oauthlogin.CompleteLoginParams{
+		Provider:  provider,
+		Code:      req.Code,
+		ProjectID: projectID,
+		RoleID:    roleID,
+	})
 	if err != nil {
-		return nil, errors.New("invalid user ID format")
+		return nil, state.ProjectID, err
 	}
 
-	jwtToken, expiresAt, err := e.ProjectUser.GenerateToken(ctx, projectID, userID)
+	outstanding, err := outstandingConsents(ctx, e.Consents, result.User.ProjectID, result.User.ID)
 	if err != nil {
-		return nil, err
+		return nil, state.ProjectID, err
 	}
 
 	return OAuthCallbackResponse{
-		Token:     jwtToken,
-		User:      *user,
-		ExpiresIn: expiresAt.Unix() - time.Now().Unix(),
-	}, nil
+		Token:           result.Token,
+		User:            result.User,
+		ExpiresIn:       result.ExpiresAt.Unix() - time.Now().Unix(),
+		consentsPending: consentsPending{OutstandingConsents: outstanding},
+	}, state.ProjectID, nil
 }
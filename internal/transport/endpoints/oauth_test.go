@@ -0,0 +1,275 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/oauthlogin"
+	"github.com/yash3004/user_management_service/onetime"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// newOAuthEndpoint serves OAuth logins to project with a global Google
+// client, completing them with logins
+func newOAuthEndpoint(project *schemas.Project, logins *mocks.LoginService) *endpoints.OAuthEndpoint {
+	projectManager := &mocks.ProjectManager{
+		GetProjectFunc: func(_ context.Context, id uuid.UUID) (*schemas.Project, error) {
+			if id != project.ID {
+				return nil, projects.ErrProjectNotFound
+			}
+			return project, nil
+		},
+	}
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"google": {ClientID: "global-id", ClientSecret: "secret", RedirectURL: "https://app.example.com/callback"},
+	}, oauth.ClientOptions{})
+	return endpoints.NewOAuthEndpoint(logins, projectManager, onetime.NewMemory(), factory, nil)
+}
+
+// loginState returns the state token of a provider's authorization URL
+func loginState(t *testing.T, redirectURL string) string {
+	t.Helper()
+
+	parsed, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", redirectURL, err)
+	}
+	return parsed.Query().Get("state")
+}
+
+func TestListProviders(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New(), Settings: schemas.ProjectSettings{
+		OAuthProviders: map[string]schemas.OAuthProviderSettings{"github": {ClientID: "project-id"}},
+	}}
+	endpoint := newOAuthEndpoint(project, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListProviders(ctx, endpoints.ListOAuthProvidersRequest{ProjectID: project.ID.String()})
+	if err != nil {
+		t.Fatalf("ListProviders: %v", err)
+	}
+	listed := response.(endpoints.ListOAuthProvidersResponse)
+	if listed.ProjectID != project.ID || len(listed.Providers) != 2 {
+		t.Fatalf("providers = %+v", listed)
+	}
+	if google := listed.Providers[0]; google.Name != "google" || google.Source != endpoints.ProviderSourceGlobal {
+		t.Fatalf("google = %+v", google)
+	}
+	if github := listed.Providers[1]; github.Name != "github" || github.Source != endpoints.ProviderSourceProject {
+		t.Fatalf("github = %+v", github)
+	}
+
+	project.Settings.OAuthLogin.Providers = []string{"github"}
+	response, err = endpoint.ListProviders(ctx, endpoints.ListOAuthProvidersRequest{ProjectID: project.ID.String()})
+	if err != nil {
+		t.Fatalf("ListProviders: %v", err)
+	}
+	if listed := response.(endpoints.ListOAuthProvidersResponse).Providers; len(listed) != 1 || listed[0].Name != "github" {
+		t.Fatalf("providers limited to github = %+v", listed)
+	}
+
+	if _, err := endpoint.ListProviders(ctx, endpoints.ListOAuthProvidersRequest{ProjectID: uuid.NewString()}); err != projects.ErrProjectNotFound {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+	if _, err := endpoint.ListProviders(ctx, endpoints.ListOAuthProvidersRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("ListProviders accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListProviders(ctx, r) })
+}
+
+func TestListProvidersUsesTheProjectInContext(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New()}
+	endpoint := newOAuthEndpoint(project, nil)
+	endpoint.Projects = &mocks.ProjectManager{}
+
+	ctx := projects.WithProject(context.Background(), project)
+	if _, err := endpoint.ListProviders(ctx, endpoints.ListOAuthProvidersRequest{ProjectID: project.ID.String()}); err != nil {
+		t.Fatalf("ListProviders: %v", err)
+	}
+}
+
+func TestOAuthLogin(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New()}
+	endpoint := newOAuthEndpoint(project, nil)
+	ctx := context.Background()
+	roleID := uuid.NewString()
+
+	response, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: "google", ProjectID: project.ID.String(), RoleID: roleID})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if state := loginState(t, response.(endpoints.OAuthLoginResponse).RedirectURL); state == "" {
+		t.Fatal("the authorization URL carries no state")
+	}
+
+	if _, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: "github", ProjectID: project.ID.String(), RoleID: roleID}); err != endpoints.ErrProviderNotEnabled {
+		t.Fatalf("err = %v, want %v for a provider without a client", err, endpoints.ErrProviderNotEnabled)
+	}
+	for _, bad := range []endpoints.OAuthLoginRequest{
+		{Provider: "google", ProjectID: project.ID.String(), RoleID: "nope"},
+		{Provider: "google", ProjectID: "nope", RoleID: roleID},
+	} {
+		if _, err := endpoint.Login(ctx, bad); err == nil {
+			t.Errorf("Login(%+v) accepted a malformed ID", bad)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.Login(ctx, r) })
+}
+
+func TestOAuthLoginRefusesProvidersTheProjectLeavesOut(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New(), Settings: schemas.ProjectSettings{
+		OAuthProviders: map[string]schemas.OAuthProviderSettings{"github": {ClientID: "project-id"}},
+		OAuthLogin:     schemas.OAuthLoginSettings{Providers: []string{"github"}},
+	}}
+	endpoint := newOAuthEndpoint(project, nil)
+	ctx := context.Background()
+	login := func(provider string) error {
+		_, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: provider, ProjectID: project.ID.String(), RoleID: uuid.NewString()})
+		return err
+	}
+
+	if err := login("github"); err != nil {
+		t.Fatalf("Login with github: %v", err)
+	}
+	// Google has a global client, but the project does not offer it
+	if err := login("google"); err != endpoints.ErrProviderNotEnabled {
+		t.Fatalf("err = %v, want %v", err, endpoints.ErrProviderNotEnabled)
+	}
+}
+
+func TestOAuthLoginUsesTheProjectsClient(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New(), Settings: schemas.ProjectSettings{
+		OAuthProviders: map[string]schemas.OAuthProviderSettings{"google": {ClientID: "project-id", ClientSecret: "project-secret"}},
+	}}
+	endpoint := newOAuthEndpoint(project, nil)
+	ctx := context.Background()
+
+	clientID := func() string {
+		t.Helper()
+		response, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: "google", ProjectID: project.ID.String(), RoleID: uuid.NewString()})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		parsed, err := url.Parse(response.(endpoints.OAuthLoginResponse).RedirectURL)
+		if err != nil {
+			t.Fatalf("failed to parse the authorization URL: %v", err)
+		}
+		return parsed.Query().Get("client_id")
+	}
+
+	if got := clientID(); got != "project-id" {
+		t.Fatalf("client_id = %q, want the project's own", got)
+	}
+	project.Settings.OAuthProviders["google"] = schemas.OAuthProviderSettings{ClientID: "rotated-id", ClientSecret: "project-secret"}
+	if got := clientID(); got != "rotated-id" {
+		t.Fatalf("client_id = %q after the override changed, want rotated-id", got)
+	}
+	delete(project.Settings.OAuthProviders, "google")
+	endpoint.ProviderFactory.EvictProject(project.ID)
+	if got := clientID(); got != "global-id" {
+		t.Fatalf("client_id = %q without an override, want the global one", got)
+	}
+}
+
+func TestOAuthCallback(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New()}
+	roleID := uuid.New()
+	user := models.DisplayUser{ID: uuid.NewString(), Email: "ada@example.com", ProjectID: project.ID.String()}
+	logins := &mocks.LoginService{
+		CompleteLoginFunc: func(_ context.Context, params oauthlogin.CompleteLoginParams) (oauthlogin.LoginResult, error) {
+			if params.Provider.GetName() != "google" || params.Code != "code" || params.ProjectID != project.ID || params.RoleID != roleID {
+				t.Errorf("params = %+v", params)
+			}
+			return oauthlogin.LoginResult{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour), User: user}, nil
+		},
+	}
+	endpoint := newOAuthEndpoint(project, logins)
+	ctx := context.Background()
+	login := func() string {
+		response, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: "google", ProjectID: project.ID.String(), RoleID: roleID.String()})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		return loginState(t, response.(endpoints.OAuthLoginResponse).RedirectURL)
+	}
+
+	state := login()
+	response, err := endpoint.Callback(ctx, endpoints.OAuthCallbackRequest{Provider: "google", Code: "code", State: state})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	session := response.(endpoints.OAuthCallbackResponse)
+	if session.Token != "jwt" || session.User.Email != "ada@example.com" || session.ExpiresIn < 3590 || session.ExpiresIn > 3600 {
+		t.Fatalf("session = %+v", session)
+	}
+
+	if _, err := endpoint.Callback(ctx, endpoints.OAuthCallbackRequest{Provider: "google", Code: "code", State: state}); err != onetime.ErrInvalidToken {
+		t.Fatalf("err = %v, want %v for a replayed state", err, onetime.ErrInvalidToken)
+	}
+	if _, err := endpoint.Callback(ctx, endpoints.OAuthCallbackRequest{Provider: "github", Code: "code", State: login()}); err != onetime.ErrInvalidToken {
+		t.Fatalf("err = %v, want %v for a state of another provider", err, onetime.ErrInvalidToken)
+	}
+
+	failure := errors.New("exchange failed")
+	logins.CompleteLoginFunc = func(context.Context, oauthlogin.CompleteLoginParams) (oauthlogin.LoginResult, error) {
+		return oauthlogin.LoginResult{}, failure
+	}
+	if _, err := endpoint.Callback(ctx, endpoints.OAuthCallbackRequest{Provider: "google", Code: "code", State: login()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.Callback(ctx, r) })
+}
+
+func TestOAuthCallbackHonoursTheStateTTL(t *testing.T) {
+	project := &schemas.Project{ID: uuid.New()}
+	logins := &mocks.LoginService{
+		CompleteLoginFunc: func(context.Context, oauthlogin.CompleteLoginParams) (oauthlogin.LoginResult, error) {
+			return oauthlogin.LoginResult{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration // The state TTL in effect
+	}{
+		{"configured", 2 * time.Minute, 2 * time.Minute},
+		{"default", 0, endpoints.OAuthStateTTL},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := newOAuthEndpoint(project, logins)
+			clock := testutil.NewFakeClock(time.Now())
+			states := onetime.NewMemory().(*onetime.Memory)
+			states.Clock = clock
+			endpoint.States, endpoint.StateTTL = states, tc.ttl
+			callback := func(wait time.Duration) error {
+				response, err := endpoint.Login(ctx, endpoints.OAuthLoginRequest{Provider: "google", ProjectID: project.ID.String(), RoleID: uuid.NewString()})
+				if err != nil {
+					t.Fatalf("Login: %v", err)
+				}
+				clock.Advance(wait)
+				state := loginState(t, response.(endpoints.OAuthLoginResponse).RedirectURL)
+				_, err = endpoint.Callback(ctx, endpoints.OAuthCallbackRequest{Provider: "google", Code: "code", State: state})
+				return err
+			}
+
+			if err := callback(tc.want - time.Second); err != nil {
+				t.Errorf("Callback within the TTL: %v", err)
+			}
+			if err := callback(tc.want); err != onetime.ErrInvalidToken {
+				t.Errorf("Callback once the TTL passed: err = %v, want %v", err, onetime.ErrInvalidToken)
+			}
+		})
+	}
+}
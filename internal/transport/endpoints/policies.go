@@ -3,22 +3,30 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"github.com/yash3004/user_management_service/policies"
 )
 
 // Policy represents a policy in the response
 type Policy struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Resource    string    `json:"resource"`
-	Action      string    `json:"action"`
-	Effect      string    `json:"effect"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Resource    string     `json:"resource"`
+	Action      string     `json:"action"`
+	Effect      string     `json:"effect"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ProjectID   string     `json:"project_id,omitempty"`
+	CreatedBy   string     `json:"created_by"`           // User ID of the creator, or "system"
+	IsSystem    bool       `json:"is_system"`            // Seeded by the service; cannot be deleted or renamed
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // Only set on deleted policies
+	DeletedBy   string     `json:"deleted_by,omitempty"` // User ID of the deleter, or "system"
 }
 
 // CreatePolicyRequest represents the create policy request
@@ -28,6 +36,7 @@ type CreatePolicyRequest struct {
 	Resource    string `json:"resource"`
 	Action      string `json:"action"`
 	Effect      string `json:"effect"`
+	ProjectID   string `json:"project_id,omitempty"` // Empty for a global policy
 }
 
 // CreatePolicyResponse represents the create policy response
@@ -37,7 +46,8 @@ type CreatePolicyResponse struct {
 
 // GetPolicyRequest represents the get policy request
 type GetPolicyRequest struct {
-	ID string `json:"id"`
+	ID             string `json:"id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 // GetPolicyResponse represents the get policy response
@@ -45,9 +55,15 @@ type GetPolicyResponse struct {
 	Policy Policy `json:"policy"`
 }
 
+// GetPolicyByNameRequest represents the get policy by name request
+type GetPolicyByNameRequest struct {
+	Name      string `json:"name"`
+	ProjectID string `json:"project_id"` // Empty to look among the global policies
+}
+
 // ListPoliciesRequest represents the list policies request
 type ListPoliciesRequest struct {
-	// Add pagination parameters if needed
+	Sort sorting.Order `json:"-"` // From the sort query parameter
 }
 
 // ListPoliciesResponse represents the list policies response
@@ -70,6 +86,22 @@ type UpdatePolicyResponse struct {
 	Policy Policy `json:"policy"`
 }
 
+// PatchPolicyRequest represents the patch policy request. Fields left out
+// of the body keep their current value.
+type PatchPolicyRequest struct {
+	ID          string  `json:"-"` // From URL path
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Resource    *string `json:"resource,omitempty"`
+	Action      *string `json:"action,omitempty"`
+	Effect      *string `json:"effect,omitempty"`
+}
+
+// PatchPolicyResponse represents the patch policy response
+type PatchPolicyResponse struct {
+	Policy Policy `json:"policy"`
+}
+
 // DeletePolicyRequest represents the delete policy request
 type DeletePolicyRequest struct {
 	ID string `json:"id"`
@@ -80,6 +112,67 @@ type DeletePolicyResponse struct {
 	Success bool `json:"success"`
 }
 
+// BulkDeletePoliciesRequest represents the bulk delete policies request
+type BulkDeletePoliciesRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// PolicyAffectedUsersRequest represents the policy affected users request
+type PolicyAffectedUsersRequest struct {
+	ID     string `json:"-"` // From URL path
+	Sample int    `json:"sample"`
+}
+
+// AffectedUser represents a user whose permissions depend on a policy
+type AffectedUser struct {
+	Kind      string `json:"kind"` // "project_user" or "user"
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	ProjectID string `json:"project_id"`
+}
+
+// PolicyAffectedUsersResponse represents the policy affected users response
+type PolicyAffectedUsersResponse struct {
+	PolicyID     string         `json:"policy_id"`
+	RoleID       string         `json:"role_id"`
+	Total        int64          `json:"total"`
+	ProjectUsers int64          `json:"project_users"`
+	GlobalUsers  int64          `json:"global_users"`
+	Sample       []AffectedUser `json:"sample"`
+}
+
+// PolicyImpactRequest represents the policy impact request: the definition
+// the policy would be changed to
+type PolicyImpactRequest struct {
+	ID       string `json:"-"` // From URL path
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Effect   string `json:"effect"`
+}
+
+// PermissionChange represents a permission a role would gain or lose
+type PermissionChange struct {
+	RoleID    string `json:"role_id"`
+	ProjectID string `json:"project_id"`
+	Resource  string `json:"resource"`
+	Action    string `json:"action"`
+}
+
+// PolicyImpactResponse represents the policy impact response
+type PolicyImpactResponse struct {
+	PolicyID  string             `json:"policy_id"`
+	RoleID    string             `json:"role_id"`
+	Evaluated int                `json:"evaluated"`
+	Gained    []PermissionChange `json:"gained"`
+	Lost      []PermissionChange `json:"lost"`
+}
+
+// ListPolicyActionsResponse represents the list policy actions response
+type ListPolicyActionsResponse struct {
+	Actions  map[string][]string `json:"actions"`
+	Wildcard string              `json:"wildcard"`
+}
+
 // PoliciesEndpoint handles policy-related endpoints
 type PoliciesEndpoint struct {
 	PolicyManager policies.PolicyManager
@@ -99,8 +192,13 @@ func (e *PoliciesEndpoint) CreatePolicy(ctx context.Context, request interface{}
 		return nil, errors.New("invalid request format")
 	}
 
+	projectID, err := parseOptionalUUID(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
 	// Delegate to the policy manager
-	policy, err := e.PolicyManager.CreatePolicy(ctx, req.Name, req.Description, req.Resource, req.Action, req.Effect)
+	policy, err := e.PolicyManager.CreatePolicy(ctx, req.Name, req.Description, req.Resource, req.Action, req.Effect, projectID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +213,9 @@ func (e *PoliciesEndpoint) CreatePolicy(ctx context.Context, request interface{}
 			Effect:      policy.Effect,
 			CreatedAt:   policy.CreatedAt,
 			UpdatedAt:   policy.UpdatedAt,
+			ProjectID:   optionalUUIDString(policy.ProjectId),
+			CreatedBy:   creatorString(policy.CreatedBy),
+			IsSystem:    policy.IsSystem,
 		},
 	}, nil
 }
@@ -133,11 +234,16 @@ func (e *PoliciesEndpoint) GetPolicy(ctx context.Context, request interface{}) (
 	}
 
 	// Delegate to the policy manager
-	policy, err := e.PolicyManager.GetPolicy(ctx, policyID)
+	get := e.PolicyManager.GetPolicy
+	if req.IncludeDeleted {
+		get = e.PolicyManager.GetPolicyIncludingDeleted
+	}
+	policy, err := get(ctx, policyID)
 	if err != nil {
 		return nil, err
 	}
 
+	deletedAt, deletedBy := deletion(policy.DeletedAt, policy.DeletedBy)
 	return GetPolicyResponse{
 		Policy: Policy{
 			ID:          policy.ID.String(),
@@ -148,14 +254,59 @@ func (e *PoliciesEndpoint) GetPolicy(ctx context.Context, request interface{}) (
 			Effect:      policy.Effect,
 			CreatedAt:   policy.CreatedAt,
 			UpdatedAt:   policy.UpdatedAt,
+			ProjectID:   optionalUUIDString(policy.ProjectId),
+			CreatedBy:   creatorString(policy.CreatedBy),
+			IsSystem:    policy.IsSystem,
+			DeletedAt:   deletedAt,
+			DeletedBy:   deletedBy,
+		},
+	}, nil
+}
+
+// GetPolicyByName gets a policy by its exact name
+func (e *PoliciesEndpoint) GetPolicyByName(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetPolicyByNameRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := parseOptionalUUID(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	// Delegate to the policy manager
+	policy, err := e.PolicyManager.GetPolicyByName(ctx, req.Name, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetPolicyResponse{
+		Policy: Policy{
+			ID:          policy.ID.String(),
+			Name:        policy.Name,
+			Description: policy.Description,
+			Resource:    policy.Resource,
+			Action:      policy.Action,
+			Effect:      policy.Effect,
+			CreatedAt:   policy.CreatedAt,
+			UpdatedAt:   policy.UpdatedAt,
+			ProjectID:   optionalUUIDString(policy.ProjectId),
+			CreatedBy:   creatorString(policy.CreatedBy),
+			IsSystem:    policy.IsSystem,
 		},
 	}, nil
 }
 
 // ListPolicies lists all policies
 func (e *PoliciesEndpoint) ListPolicies(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListPoliciesRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
 	// Delegate to the policy manager
-	policiesList, err := e.PolicyManager.ListPolicies(ctx)
+	policiesList, err := e.PolicyManager.ListPolicies(ctx, req.Sort)
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +323,9 @@ func (e *PoliciesEndpoint) ListPolicies(ctx context.Context, request interface{}
 			Effect:      p.Effect,
 			CreatedAt:   p.CreatedAt,
 			UpdatedAt:   p.UpdatedAt,
+			ProjectID:   optionalUUIDString(p.ProjectId),
+			CreatedBy:   creatorString(p.CreatedBy),
+			IsSystem:    p.IsSystem,
 		}
 	}
 
@@ -209,6 +363,49 @@ func (e *PoliciesEndpoint) UpdatePolicy(ctx context.Context, request interface{}
 			Effect:      policy.Effect,
 			CreatedAt:   policy.CreatedAt,
 			UpdatedAt:   policy.UpdatedAt,
+			ProjectID:   optionalUUIDString(policy.ProjectId),
+			CreatedBy:   creatorString(policy.CreatedBy),
+			IsSystem:    policy.IsSystem,
+		},
+	}, nil
+}
+
+// PatchPolicy updates the fields of a policy given in the request
+func (e *PoliciesEndpoint) PatchPolicy(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(PatchPolicyRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	policyID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid policy ID format")
+	}
+
+	policy, err := e.PolicyManager.PatchPolicy(ctx, policyID, policies.PolicyPatch{
+		Name:        req.Name,
+		Description: req.Description,
+		Resource:    req.Resource,
+		Action:      req.Action,
+		Effect:      req.Effect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return PatchPolicyResponse{
+		Policy: Policy{
+			ID:          policy.ID.String(),
+			Name:        policy.Name,
+			Description: policy.Description,
+			Resource:    policy.Resource,
+			Action:      policy.Action,
+			Effect:      policy.Effect,
+			CreatedAt:   policy.CreatedAt,
+			UpdatedAt:   policy.UpdatedAt,
+			ProjectID:   optionalUUIDString(policy.ProjectId),
+			CreatedBy:   creatorString(policy.CreatedBy),
+			IsSystem:    policy.IsSystem,
 		},
 	}, nil
 }
@@ -235,4 +432,120 @@ func (e *PoliciesEndpoint) DeletePolicy(ctx context.Context, request interface{}
 	return DeletePolicyResponse{
 		Success: true,
 	}, nil
-}
\ No newline at end of file
+}
+
+// BulkDeletePolicies deletes many policies at once. Each policy is deleted
+// on its own, so one that fails leaves the others deleted; the response
+// reports every policy's outcome.
+func (e *PoliciesEndpoint) BulkDeletePolicies(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(BulkDeletePoliciesRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	ids, err := bulkIDs(req.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MultiStatusResponse
+	for _, raw := range ids {
+		policyID, err := uuid.Parse(raw)
+		if err != nil {
+			response.add(raw, 0, apierrors.BadRequest("INVALID_POLICY_ID", "invalid policy ID format"))
+			continue
+		}
+		response.add(raw, http.StatusOK, e.PolicyManager.DeletePolicy(ctx, policyID))
+	}
+	return response, nil
+}
+
+// ListPolicyActions lists the valid built-in resource/action pairs
+func (e *PoliciesEndpoint) ListPolicyActions(ctx context.Context, request interface{}) (interface{}, error) {
+	return ListPolicyActionsResponse{
+		Actions:  policies.Actions,
+		Wildcard: policies.Wildcard,
+	}, nil
+}
+
+// PolicyAffectedUsers previews the users whose permissions would change if
+// the policy were updated or deleted
+func (e *PoliciesEndpoint) PolicyAffectedUsers(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(PolicyAffectedUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	policyID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid policy ID format")
+	}
+
+	affected, err := e.PolicyManager.AffectedUsers(ctx, policyID, req.Sample)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := make([]AffectedUser, len(affected.Sample))
+	for i, u := range affected.Sample {
+		sample[i] = AffectedUser{
+			Kind:      u.Kind,
+			UserID:    u.UserID.String(),
+			Email:     u.Email,
+			ProjectID: u.ProjectID.String(),
+		}
+	}
+
+	return PolicyAffectedUsersResponse{
+		PolicyID:     affected.Policy.ID.String(),
+		RoleID:       affected.Policy.RolesId.String(),
+		Total:        affected.Total(),
+		ProjectUsers: affected.ProjectUsers,
+		GlobalUsers:  affected.GlobalUsers,
+		Sample:       sample,
+	}, nil
+}
+
+// PolicyImpact previews which permissions the policy's role would gain or
+// lose if the policy were changed to the requested definition
+func (e *PoliciesEndpoint) PolicyImpact(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(PolicyImpactRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	policyID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid policy ID format")
+	}
+
+	impact, err := e.PolicyManager.Impact(ctx, policyID, policies.Definition{
+		Resource: req.Resource,
+		Action:   req.Action,
+		Effect:   req.Effect,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := PolicyImpactResponse{
+		PolicyID:  impact.Policy.ID.String(),
+		RoleID:    impact.Policy.RolesId.String(),
+		Evaluated: impact.Evaluated,
+		Gained:    []PermissionChange{},
+		Lost:      []PermissionChange{},
+	}
+	for _, c := range impact.Changes {
+		change := PermissionChange{
+			RoleID:    c.RoleID.String(),
+			ProjectID: c.ProjectID.String(),
+			Resource:  c.Resource,
+			Action:    c.Action,
+		}
+		if c.Change == policies.PermissionGained {
+			response.Gained = append(response.Gained, change)
+		} else {
+			response.Lost = append(response.Lost, change)
+		}
+	}
+	return response, nil
+}
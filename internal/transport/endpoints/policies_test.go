@@ -0,0 +1,385 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/policies"
+	"gorm.io/gorm"
+)
+
+// aPolicy builds the policy a manager would return
+func aPolicy(id uuid.UUID, name string) *schemas.Policy {
+	return &schemas.Policy{ID: id, Name: name, Resource: "users", Action: "read", Effect: "allow", RolesId: uuid.New()}
+}
+
+func TestCreatePolicy(t *testing.T) {
+	projectID, creator := uuid.New(), uuid.New()
+	manager := &mocks.PolicyManager{
+		CreatePolicyFunc: func(_ context.Context, name, description, resource, action, effect string, pid *uuid.UUID) (*schemas.Policy, error) {
+			if name != "read-users" || description != "Reads" || resource != "users" || action != "read" || effect != "allow" || pid == nil || *pid != projectID {
+				t.Errorf("CreatePolicy(%q, %q, %q, %q, %q, %v)", name, description, resource, action, effect, pid)
+			}
+			policy := aPolicy(uuid.New(), name)
+			policy.Description, policy.ProjectId, policy.CreatedBy = description, pid, &creator
+			return policy, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.CreatePolicy(ctx, endpoints.CreatePolicyRequest{
+		Name: "read-users", Description: "Reads", Resource: "users", Action: "read", Effect: "allow", ProjectID: projectID.String(),
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+	policy := response.(endpoints.CreatePolicyResponse).Policy
+	if policy.Name != "read-users" || policy.Resource != "users" || policy.ProjectID != projectID.String() || policy.CreatedBy != creator.String() {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	failure := errors.New("boom")
+	manager.CreatePolicyFunc = func(context.Context, string, string, string, string, string, *uuid.UUID) (*schemas.Policy, error) {
+		return nil, failure
+	}
+	if _, err := endpoint.CreatePolicy(ctx, endpoints.CreatePolicyRequest{Name: "x"}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.CreatePolicy(ctx, endpoints.CreatePolicyRequest{Name: "x", ProjectID: "nope"}); err == nil {
+		t.Fatal("CreatePolicy accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreatePolicy(ctx, r) })
+}
+
+func TestGetPolicy(t *testing.T) {
+	policyID := uuid.New()
+	deletedAt := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.PolicyManager{
+		GetPolicyFunc: func(_ context.Context, id uuid.UUID) (*schemas.Policy, error) {
+			policy := aPolicy(id, "live")
+			policy.IsSystem = true
+			return policy, nil
+		},
+		GetPolicyIncludingDeletedFunc: func(_ context.Context, id uuid.UUID) (*schemas.Policy, error) {
+			policy := aPolicy(id, "gone")
+			policy.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+			return policy, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetPolicy(ctx, endpoints.GetPolicyRequest{ID: policyID.String()})
+	if err != nil {
+		t.Fatalf("GetPolicy: %v", err)
+	}
+	if policy := response.(endpoints.GetPolicyResponse).Policy; policy.Name != "live" || !policy.IsSystem || policy.CreatedBy != "system" || policy.DeletedAt != nil {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	response, err = endpoint.GetPolicy(ctx, endpoints.GetPolicyRequest{ID: policyID.String(), IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetPolicy: %v", err)
+	}
+	policy := response.(endpoints.GetPolicyResponse).Policy
+	if policy.Name != "gone" || policy.DeletedAt == nil || !policy.DeletedAt.Equal(deletedAt) || policy.DeletedBy != "system" {
+		t.Fatalf("deleted policy = %+v", policy)
+	}
+
+	if _, err := endpoint.GetPolicy(ctx, endpoints.GetPolicyRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetPolicy accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetPolicy(ctx, r) })
+}
+
+func TestGetPolicyByName(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.PolicyManager{
+		GetPolicyByNameFunc: func(_ context.Context, name string, pid *uuid.UUID) (*schemas.Policy, error) {
+			policy := aPolicy(uuid.New(), name)
+			policy.ProjectId = pid
+			return policy, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetPolicyByName(ctx, endpoints.GetPolicyByNameRequest{Name: "global"})
+	if err != nil {
+		t.Fatalf("GetPolicyByName: %v", err)
+	}
+	if policy := response.(endpoints.GetPolicyResponse).Policy; policy.Name != "global" || policy.ProjectID != "" {
+		t.Fatalf("global policy = %+v", policy)
+	}
+	response, err = endpoint.GetPolicyByName(ctx, endpoints.GetPolicyByNameRequest{Name: "local", ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("GetPolicyByName: %v", err)
+	}
+	if policy := response.(endpoints.GetPolicyResponse).Policy; policy.ProjectID != projectID.String() {
+		t.Fatalf("project policy = %+v", policy)
+	}
+
+	if _, err := endpoint.GetPolicyByName(ctx, endpoints.GetPolicyByNameRequest{Name: "x", ProjectID: "nope"}); err == nil {
+		t.Fatal("GetPolicyByName accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetPolicyByName(ctx, r) })
+}
+
+func TestListPolicies(t *testing.T) {
+	order := sorting.Order{Field: "created_at"}
+	manager := &mocks.PolicyManager{
+		ListPoliciesFunc: func(_ context.Context, got sorting.Order) ([]schemas.Policy, error) {
+			if got != order {
+				t.Errorf("order = %+v, want %+v", got, order)
+			}
+			return []schemas.Policy{*aPolicy(uuid.New(), "a"), *aPolicy(uuid.New(), "b")}, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListPolicies(ctx, endpoints.ListPoliciesRequest{Sort: order})
+	if err != nil {
+		t.Fatalf("ListPolicies: %v", err)
+	}
+	if list := response.(endpoints.ListPoliciesResponse).Policies; len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" {
+		t.Fatalf("policies = %+v", list)
+	}
+
+	manager.ListPoliciesFunc = func(context.Context, sorting.Order) ([]schemas.Policy, error) { return nil, nil }
+	response, err = endpoint.ListPolicies(ctx, endpoints.ListPoliciesRequest{})
+	if err != nil {
+		t.Fatalf("ListPolicies: %v", err)
+	}
+	if list := response.(endpoints.ListPoliciesResponse).Policies; list == nil || len(list) != 0 {
+		t.Fatalf("policies = %#v, want an empty list", list)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListPolicies(ctx, r) })
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	policyID := uuid.New()
+	manager := &mocks.PolicyManager{
+		UpdatePolicyFunc: func(_ context.Context, id uuid.UUID, name, description, resource, action, effect string) (*schemas.Policy, error) {
+			if id != policyID || name != "n" || description != "d" || resource != "roles" || action != "write" || effect != "deny" {
+				t.Errorf("UpdatePolicy(%v, %q, %q, %q, %q, %q)", id, name, description, resource, action, effect)
+			}
+			return &schemas.Policy{ID: id, Name: name, Description: description, Resource: resource, Action: action, Effect: effect}, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdatePolicy(ctx, endpoints.UpdatePolicyRequest{
+		ID: policyID.String(), Name: "n", Description: "d", Resource: "roles", Action: "write", Effect: "deny",
+	})
+	if err != nil {
+		t.Fatalf("UpdatePolicy: %v", err)
+	}
+	if policy := response.(endpoints.UpdatePolicyResponse).Policy; policy.ID != policyID.String() || policy.Effect != "deny" {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	if _, err := endpoint.UpdatePolicy(ctx, endpoints.UpdatePolicyRequest{ID: "nope"}); err == nil {
+		t.Fatal("UpdatePolicy accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdatePolicy(ctx, r) })
+}
+
+func TestPatchPolicy(t *testing.T) {
+	policyID := uuid.New()
+	effect := "deny"
+	manager := &mocks.PolicyManager{
+		PatchPolicyFunc: func(_ context.Context, id uuid.UUID, patch policies.PolicyPatch) (*schemas.Policy, error) {
+			if patch.Name != nil || patch.Description != nil || patch.Resource != nil || patch.Action != nil || patch.Effect == nil || *patch.Effect != effect {
+				t.Errorf("patch = %+v, want only the effect", patch)
+			}
+			policy := aPolicy(id, "p")
+			policy.Effect = *patch.Effect
+			return policy, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.PatchPolicy(ctx, endpoints.PatchPolicyRequest{ID: policyID.String(), Effect: &effect})
+	if err != nil {
+		t.Fatalf("PatchPolicy: %v", err)
+	}
+	if policy := response.(endpoints.PatchPolicyResponse).Policy; policy.Effect != "deny" || policy.Resource != "users" {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	if _, err := endpoint.PatchPolicy(ctx, endpoints.PatchPolicyRequest{ID: "nope"}); err == nil {
+		t.Fatal("PatchPolicy accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.PatchPolicy(ctx, r) })
+}
+
+func TestDeletePolicy(t *testing.T) {
+	policyID := uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.PolicyManager{
+		DeletePolicyFunc: func(_ context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.DeletePolicy(ctx, endpoints.DeletePolicyRequest{ID: policyID.String()})
+	if err != nil {
+		t.Fatalf("DeletePolicy: %v", err)
+	}
+	if !response.(endpoints.DeletePolicyResponse).Success || deleted != policyID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	manager.DeletePolicyFunc = func(context.Context, uuid.UUID) error { return policies.ErrSystemPolicy }
+	if _, err := endpoint.DeletePolicy(ctx, endpoints.DeletePolicyRequest{ID: policyID.String()}); err != policies.ErrSystemPolicy {
+		t.Fatalf("err = %v, want %v", err, policies.ErrSystemPolicy)
+	}
+	if _, err := endpoint.DeletePolicy(ctx, endpoints.DeletePolicyRequest{ID: "nope"}); err == nil {
+		t.Fatal("DeletePolicy accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeletePolicy(ctx, r) })
+}
+
+func TestBulkDeletePolicies(t *testing.T) {
+	ok, system := uuid.New(), uuid.New()
+	manager := &mocks.PolicyManager{
+		DeletePolicyFunc: func(_ context.Context, id uuid.UUID) error {
+			if id == system {
+				return policies.ErrSystemPolicy
+			}
+			return nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.BulkDeletePolicies(ctx, endpoints.BulkDeletePoliciesRequest{
+		IDs: []string{ok.String(), "nope", system.String(), ok.String()},
+	})
+	if err != nil {
+		t.Fatalf("BulkDeletePolicies: %v", err)
+	}
+	result := response.(endpoints.MultiStatusResponse)
+	if result.Succeeded != 1 || result.Failed != 2 || len(result.Results) != 3 || result.StatusCode() != http.StatusMultiStatus {
+		t.Fatalf("result = %+v", result)
+	}
+	if r := result.Results[1]; r.ID != "nope" || r.Code != "INVALID_POLICY_ID" || r.Status != http.StatusBadRequest {
+		t.Fatalf("malformed ID result = %+v", r)
+	}
+
+	_, err = endpoint.BulkDeletePolicies(ctx, endpoints.BulkDeletePoliciesRequest{})
+	wantCode(t, err, "IDS_REQUIRED")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.BulkDeletePolicies(ctx, r) })
+}
+
+func TestListPolicyActions(t *testing.T) {
+	endpoint := endpoints.NewPoliciesEndpoint(&mocks.PolicyManager{})
+
+	response, err := endpoint.ListPolicyActions(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListPolicyActions: %v", err)
+	}
+	actions := response.(endpoints.ListPolicyActionsResponse)
+	if actions.Wildcard != policies.Wildcard || len(actions.Actions) != len(policies.Actions) {
+		t.Fatalf("actions = %+v", actions)
+	}
+}
+
+func TestPolicyAffectedUsers(t *testing.T) {
+	policyID, roleID, userID, projectID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.PolicyManager{
+		AffectedUsersFunc: func(_ context.Context, id uuid.UUID, sample int) (*policies.AffectedUsers, error) {
+			if sample != 3 {
+				t.Errorf("sample = %d, want 3", sample)
+			}
+			return &policies.AffectedUsers{
+				Policy:       schemas.Policy{ID: id, RolesId: roleID},
+				ProjectUsers: 4,
+				GlobalUsers:  1,
+				Sample:       []policies.AffectedUser{{Kind: "project_user", UserID: userID, Email: "a@example.com", ProjectID: projectID}},
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.PolicyAffectedUsers(ctx, endpoints.PolicyAffectedUsersRequest{ID: policyID.String(), Sample: 3})
+	if err != nil {
+		t.Fatalf("PolicyAffectedUsers: %v", err)
+	}
+	affected := response.(endpoints.PolicyAffectedUsersResponse)
+	want := endpoints.AffectedUser{Kind: "project_user", UserID: userID.String(), Email: "a@example.com", ProjectID: projectID.String()}
+	if affected.PolicyID != policyID.String() || affected.RoleID != roleID.String() || affected.Total != 5 || len(affected.Sample) != 1 || affected.Sample[0] != want {
+		t.Fatalf("affected = %+v", affected)
+	}
+
+	if _, err := endpoint.PolicyAffectedUsers(ctx, endpoints.PolicyAffectedUsersRequest{ID: "nope"}); err == nil {
+		t.Fatal("PolicyAffectedUsers accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.PolicyAffectedUsers(ctx, r) })
+}
+
+func TestPolicyImpact(t *testing.T) {
+	policyID, roleID, projectID := uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.PolicyManager{
+		ImpactFunc: func(_ context.Context, id uuid.UUID, proposed policies.Definition) (*policies.PolicyImpact, error) {
+			if proposed != (policies.Definition{Resource: "users", Action: "*", Effect: "allow"}) {
+				t.Errorf("proposed = %+v", proposed)
+			}
+			return &policies.PolicyImpact{
+				Policy:    schemas.Policy{ID: id, RolesId: roleID},
+				Proposed:  proposed,
+				Evaluated: 6,
+				Changes: []policies.PermissionChange{
+					{RoleID: roleID, ProjectID: projectID, Resource: "users", Action: "write", Change: policies.PermissionGained},
+					{RoleID: roleID, ProjectID: projectID, Resource: "roles", Action: "read", Change: policies.PermissionLost},
+				},
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewPoliciesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.PolicyImpact(ctx, endpoints.PolicyImpactRequest{ID: policyID.String(), Resource: "users", Action: "*", Effect: "allow"})
+	if err != nil {
+		t.Fatalf("PolicyImpact: %v", err)
+	}
+	impact := response.(endpoints.PolicyImpactResponse)
+	if impact.PolicyID != policyID.String() || impact.RoleID != roleID.String() || impact.Evaluated != 6 {
+		t.Fatalf("impact = %+v", impact)
+	}
+	if len(impact.Gained) != 1 || impact.Gained[0].Action != "write" || len(impact.Lost) != 1 || impact.Lost[0].Resource != "roles" {
+		t.Fatalf("gained %+v, lost %+v", impact.Gained, impact.Lost)
+	}
+
+	manager.ImpactFunc = func(_ context.Context, id uuid.UUID, _ policies.Definition) (*policies.PolicyImpact, error) {
+		return &policies.PolicyImpact{Policy: schemas.Policy{ID: id}}, nil
+	}
+	response, err = endpoint.PolicyImpact(ctx, endpoints.PolicyImpactRequest{ID: policyID.String()})
+	if err != nil {
+		t.Fatalf("PolicyImpact: %v", err)
+	}
+	if impact := response.(endpoints.PolicyImpactResponse); impact.Gained == nil || impact.Lost == nil {
+		t.Fatalf("impact = %#v, want empty lists when nothing changes", impact)
+	}
+
+	if _, err := endpoint.PolicyImpact(ctx, endpoints.PolicyImpactRequest{ID: "nope"}); err == nil {
+		t.Fatal("PolicyImpact accepted a malformed policy ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.PolicyImpact(ctx, r) })
+}
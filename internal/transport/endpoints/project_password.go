@@ -0,0 +1,103 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/models"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+)
+
+// ProjectLoginRequest represents a project user's password login
+type ProjectLoginRequest struct {
+	ProjectID string `json:"-"` // From URL path
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+// ProjectLoginResponse represents the project user password login response
+type ProjectLoginResponse struct {
+	Token     string             `json:"token"`
+	User      models.DisplayUser `json:"user"`
+	ExpiresIn int64              `json:"expires_in"`
+	// PasswordExpired is set when the password is past its maximum age. The
+	// token then carries the password_change scope, and the user changes it
+	// through POST /api/v1/{projectId}/auth/password.
+	PasswordExpired bool `json:"password_expired"`
+	// PasswordExpiresAt is when the password expired or will expire; unset
+	// when the project's passwords do not expire
+	PasswordExpiresAt *time.Time `json:"password_expires_at,omitempty"`
+	consentsPending
+}
+
+// ChangeProjectPasswordRequest represents a project user's password change,
+// authenticated by the current password
+type ChangeProjectPasswordRequest struct {
+	ProjectID       string `json:"-"` // From URL path
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ProjectPasswordEndpoint handles the password login of project users
+type ProjectPasswordEndpoint struct {
+	ProjectUserManager projectusers.ProjectUserManager
+	// Consents tells which documents users logging in have yet to accept;
+	// nil flags none
+	Consents consents.ConsentManager
+}
+
+// NewProjectPasswordEndpoint creates a new project password endpoint
+func NewProjectPasswordEndpoint(manager projectusers.ProjectUserManager, consentManager consents.ConsentManager) *ProjectPasswordEndpoint {
+	return &ProjectPasswordEndpoint{
+		ProjectUserManager: manager,
+		Consents:           consentManager,
+	}
+}
+
+// Login exchanges a project user's email and password for a JWT
+func (e *ProjectPasswordEndpoint) Login(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ProjectLoginRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	login, err := e.ProjectUserManager.LoginProjectUser(ctx, req.ProjectID, req.Email, req.Password)
+	auth.RecordLogin(auth.MethodPassword, req.ProjectID, err)
+	if err != nil {
+		return nil, err
+	}
+
+	response := ProjectLoginResponse{
+		Token:           login.Token,
+		User:            login.User,
+		ExpiresIn:       login.ExpiresAt.Unix() - time.Now().Unix(),
+		PasswordExpired: login.PasswordExpired,
+	}
+	if !login.PasswordExpiresAt.IsZero() {
+		response.PasswordExpiresAt = &login.PasswordExpiresAt
+	}
+	response.OutstandingConsents, err = outstandingConsents(ctx, e.Consents, login.User.ProjectID, login.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ChangePassword changes a project user's password, including one that has
+// expired. The user logs in again with the new password for a token without
+// the password_change restriction.
+func (e *ProjectPasswordEndpoint) ChangePassword(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ChangeProjectPasswordRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	if err := e.ProjectUserManager.ChangeProjectUserPassword(ctx, req.ProjectID, req.Email, req.CurrentPassword, req.NewPassword); err != nil {
+		return nil, err
+	}
+	return ChangePasswordResponse{Success: true}, nil
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
 	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/roles"
 )
 
 // CreateProjectUserRequest represents the create project user request
@@ -26,8 +30,9 @@ type CreateProjectUserResponse struct {
 
 // GetProjectUserRequest represents the get project user request
 type GetProjectUserRequest struct {
-	ProjectID string `json:"project_id"`
-	UserID    string `json:"user_id"`
+	ProjectID      string `json:"project_id"`
+	UserID         string `json:"user_id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 // GetProjectUserResponse represents the get project user response
@@ -37,7 +42,10 @@ type GetProjectUserResponse struct {
 
 // ListProjectUsersRequest represents the list project users request
 type ListProjectUsersRequest struct {
-	ProjectID string `json:"project_id"`
+	ProjectID      string        `json:"project_id"`
+	IncludeDeleted bool          `json:"include_deleted"`
+	Status         string        `json:"status"` // Only users in this status; empty for all
+	Sort           sorting.Order `json:"-"`      // From the sort query parameter
 }
 
 // ListProjectUsersResponse represents the list project users response
@@ -45,6 +53,21 @@ type ListProjectUsersResponse struct {
 	Users []models.DisplayUser `json:"users"`
 }
 
+// ListDeletedProjectUsersRequest represents the list deleted project users request
+type ListDeletedProjectUsersRequest struct {
+	ProjectID string `json:"-"`
+	Page      int    `json:"-"` // From the page query parameter, 1-based
+	PageSize  int    `json:"-"` // From the page_size query parameter
+}
+
+// ListDeletedProjectUsersResponse represents the list deleted project users response
+type ListDeletedProjectUsersResponse struct {
+	Users    []models.DisplayUser `json:"users"`
+	Total    int64                `json:"total"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+}
+
 // UpdateProjectUserRequest represents the update project user request
 type UpdateProjectUserRequest struct {
 	ProjectID string `json:"project_id"`
@@ -59,6 +82,30 @@ type UpdateProjectUserResponse struct {
 	User models.DisplayUser `json:"user"`
 }
 
+// SetProjectUserStatusRequest represents the set project user status request
+type SetProjectUserStatusRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+	Status    string `json:"status"`
+}
+
+// SetProjectUserStatusResponse represents the set project user status response
+type SetProjectUserStatusResponse struct {
+	User models.DisplayUser `json:"user"`
+}
+
+// BulkSetProjectUsersActiveRequest represents the bulk activate/deactivate project users request
+type BulkSetProjectUsersActiveRequest struct {
+	ProjectID string   `json:"-"`
+	UserIDs   []string `json:"user_ids"`
+	Active    *bool    `json:"active"`
+}
+
+// BulkSetProjectUsersActiveResponse represents the bulk activate/deactivate project users response
+type BulkSetProjectUsersActiveResponse struct {
+	Affected int64 `json:"affected"` // Users whose state changed
+}
+
 // DeleteProjectUserRequest represents the delete project user request
 type DeleteProjectUserRequest struct {
 	ProjectID string `json:"project_id"`
@@ -70,15 +117,63 @@ type DeleteProjectUserResponse struct {
 	Success bool `json:"success"`
 }
 
+// RestoreProjectUserRequest represents the restore project user request
+type RestoreProjectUserRequest struct {
+	ProjectID string `json:"project_id"`
+	UserID    string `json:"user_id"`
+}
+
+// RestoreProjectUserResponse represents the restore project user response
+type RestoreProjectUserResponse struct {
+	User models.DisplayUser `json:"user"`
+}
+
+// FindDuplicateProjectUsersRequest represents the find duplicate project users request
+type FindDuplicateProjectUsersRequest struct {
+	ProjectID string `json:"-"`
+}
+
+// FindDuplicateProjectUsersResponse represents the find duplicate project users response
+type FindDuplicateProjectUsersResponse struct {
+	Clusters []projectusers.DuplicateCluster `json:"clusters"`
+}
+
+// MergeProjectUsersRequest represents the merge project users request
+type MergeProjectUsersRequest struct {
+	ProjectID   string `json:"-"`
+	PrimaryID   string `json:"primary_id"`
+	DuplicateID string `json:"duplicate_id"`
+}
+
+// MergeProjectUsersResponse represents the merge project users response
+type MergeProjectUsersResponse struct {
+	Merge projectusers.MergeResult `json:"merge"`
+}
+
+// RefreshProjectUserTokensRequest represents the request to refresh the
+// tokens of a role's project users
+type RefreshProjectUserTokensRequest struct {
+	ProjectID   string `json:"-"`
+	RoleID      string `json:"role_id"`      // A role ID or name
+	IssueTokens bool   `json:"issue_tokens"` // Also return a fresh token for every user
+}
+
+// RefreshProjectUserTokensResponse represents the refreshed tokens
+type RefreshProjectUserTokensResponse struct {
+	Refresh projectusers.TokenRefresh `json:"refresh"`
+}
+
 // ProjectUsersEndpoint handles project-specific user-related endpoints
 type ProjectUsersEndpoint struct {
 	ProjectUserManager projectusers.ProjectUserManager
+	RoleManager        roles.RoleManager // resolves role_id given as a role name
 }
 
 // NewProjectUsersEndpoint creates a new project users endpoint
-func NewProjectUsersEndpoint(manager projectusers.ProjectUserManager) *ProjectUsersEndpoint {
+func NewProjectUsersEndpoint(manager projectusers.ProjectUserManager, roleManager roles.RoleManager) *ProjectUsersEndpoint {
 	return &ProjectUsersEndpoint{
 		ProjectUserManager: manager,
+		RoleManager:        roleManager,
 	}
 }
 
@@ -89,10 +184,15 @@ func (e *ProjectUsersEndpoint) CreateProjectUser(ctx context.Context, request in
 		return nil, errors.New("invalid request format")
 	}
 
-	// Parse role ID
-	roleID, err := uuid.Parse(req.RoleID)
+	projectID, err := uuid.Parse(req.ProjectID)
 	if err != nil {
-		return nil, errors.New("invalid role ID format")
+		return nil, projecttable.ErrInvalidProjectID
+	}
+
+	// Resolve the role, given as an ID or a name
+	roleID, err := resolveRoleID(ctx, e.RoleManager, req.RoleID, projectID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Delegate to the project user manager
@@ -120,7 +220,7 @@ func (e *ProjectUsersEndpoint) GetProjectUser(ctx context.Context, request inter
 	}
 
 	// Delegate to the project user manager
-	user, err := e.ProjectUserManager.GetProjectUser(ctx, req.ProjectID, userID)
+	user, err := e.ProjectUserManager.GetProjectUser(ctx, req.ProjectID, userID, req.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -138,13 +238,34 @@ func (e *ProjectUsersEndpoint) ListProjectUsers(ctx context.Context, request int
 	}
 
 	// Delegate to the project user manager
-	users, err := e.ProjectUserManager.ListProjectUsers(ctx, req.ProjectID)
+	users, err := e.ProjectUserManager.ListProjectUsers(ctx, req.ProjectID, req.IncludeDeleted, req.Status, req.Sort)
 	if err != nil {
 		return nil, err
 	}
 
 	return ListProjectUsersResponse{
-		Users: users,
+		Users: nonNil(users),
+	}, nil
+}
+
+// ListDeletedProjectUsers lists the soft-deleted users of a project, most
+// recently deleted first, for picking one to restore
+func (e *ProjectUsersEndpoint) ListDeletedProjectUsers(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListDeletedProjectUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	page, err := e.ProjectUserManager.ListDeletedProjectUsers(ctx, req.ProjectID, req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return ListDeletedProjectUsersResponse{
+		Users:    nonNil(page.Users),
+		Total:    page.Total,
+		Page:     page.Page,
+		PageSize: page.PageSize,
 	}, nil
 }
 
@@ -172,6 +293,59 @@ func (e *ProjectUsersEndpoint) UpdateProjectUser(ctx context.Context, request in
 	}, nil
 }
 
+// SetProjectUserStatus moves a user in a project to another status
+func (e *ProjectUsersEndpoint) SetProjectUserStatus(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(SetProjectUserStatusRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	// Delegate to the project user manager
+	user, err := e.ProjectUserManager.SetProjectUserStatus(ctx, req.ProjectID, userID, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return SetProjectUserStatusResponse{
+		User: *user,
+	}, nil
+}
+
+// BulkSetProjectUsersActive activates or deactivates many users in a project at once
+func (e *ProjectUsersEndpoint) BulkSetProjectUsersActive(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(BulkSetProjectUsersActiveRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+	if req.Active == nil {
+		return nil, apierrors.BadRequest("ACTIVE_REQUIRED", "active must be true or false")
+	}
+
+	userIDs := make([]uuid.UUID, len(req.UserIDs))
+	for i, raw := range req.UserIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, apierrors.BadRequest("INVALID_USER_ID", "invalid user ID format: "+raw)
+		}
+		userIDs[i] = id
+	}
+
+	affected, err := e.ProjectUserManager.BulkSetProjectUsersActive(ctx, req.ProjectID, userIDs, *req.Active)
+	if err != nil {
+		return nil, err
+	}
+
+	return BulkSetProjectUsersActiveResponse{
+		Affected: affected,
+	}, nil
+}
+
 // DeleteProjectUser deletes a user from a project-specific user table
 func (e *ProjectUsersEndpoint) DeleteProjectUser(ctx context.Context, request interface{}) (interface{}, error) {
 	req, ok := request.(DeleteProjectUserRequest)
@@ -195,3 +369,97 @@ func (e *ProjectUsersEndpoint) DeleteProjectUser(ctx context.Context, request in
 		Success: true,
 	}, nil
 }
+
+// RestoreProjectUser restores a soft-deleted user in a project-specific user table
+func (e *ProjectUsersEndpoint) RestoreProjectUser(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RestoreProjectUserRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	// Delegate to the project user manager
+	user, err := e.ProjectUserManager.RestoreProjectUser(ctx, req.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return RestoreProjectUserResponse{
+		User: *user,
+	}, nil
+}
+
+// FindDuplicateProjectUsers lists clusters of users in a project that are likely the same person
+func (e *ProjectUsersEndpoint) FindDuplicateProjectUsers(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(FindDuplicateProjectUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	clusters, err := e.ProjectUserManager.FindDuplicates(ctx, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return FindDuplicateProjectUsersResponse{
+		Clusters: clusters,
+	}, nil
+}
+
+// MergeProjectUsers folds a duplicate user in a project into the primary one
+func (e *ProjectUsersEndpoint) MergeProjectUsers(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(MergeProjectUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	primaryID, err := uuid.Parse(req.PrimaryID)
+	if err != nil {
+		return nil, apierrors.BadRequest("INVALID_USER_ID", "primary_id must be a user ID")
+	}
+	duplicateID, err := uuid.Parse(req.DuplicateID)
+	if err != nil {
+		return nil, apierrors.BadRequest("INVALID_USER_ID", "duplicate_id must be a user ID")
+	}
+
+	result, err := e.ProjectUserManager.MergeProjectUsers(ctx, req.ProjectID, primaryID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return MergeProjectUsersResponse{
+		Merge: *result,
+	}, nil
+}
+
+// RefreshProjectUserTokens recomputes the token expiry of a role's users in
+// a project, and issues them fresh tokens when asked to
+func (e *ProjectUsersEndpoint) RefreshProjectUserTokens(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RefreshProjectUserTokensRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+	roleID, err := resolveRoleID(ctx, e.RoleManager, req.RoleID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := e.ProjectUserManager.RefreshProjectUserTokens(ctx, req.ProjectID, roleID, req.IssueTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return RefreshProjectUserTokensResponse{
+		Refresh: *refresh,
+	}, nil
+}
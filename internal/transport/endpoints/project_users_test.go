@@ -0,0 +1,360 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/project_users"
+)
+
+func TestCreateProjectUser(t *testing.T) {
+	projectID, roleID := uuid.New(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		CreateProjectUserFunc: func(_ context.Context, pid, email, password, first, last string, rid uuid.UUID) (*models.DisplayUser, error) {
+			if pid != projectID.String() || email != "a@example.com" || password != "s3cret!!" || first != "Ada" || last != "Lovelace" || rid != roleID {
+				t.Errorf("CreateProjectUser(%q, %q, %q, %q, %q, %v)", pid, email, password, first, last, rid)
+			}
+			return &models.DisplayUser{ID: uuid.NewString(), Email: email, RoleID: rid.String(), ProjectID: pid}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, rolesNamed(map[string]uuid.UUID{"member": roleID}))
+	ctx := context.Background()
+
+	for _, role := range []string{roleID.String(), "member"} {
+		response, err := endpoint.CreateProjectUser(ctx, endpoints.CreateProjectUserRequest{
+			ProjectID: projectID.String(), Email: "a@example.com", Password: "s3cret!!", FirstName: "Ada", LastName: "Lovelace", RoleID: role,
+		})
+		if err != nil {
+			t.Fatalf("CreateProjectUser with role %q: %v", role, err)
+		}
+		if user := response.(endpoints.CreateProjectUserResponse).User; user.Email != "a@example.com" || user.RoleID != roleID.String() {
+			t.Fatalf("user = %+v", user)
+		}
+	}
+
+	_, err := endpoint.CreateProjectUser(ctx, endpoints.CreateProjectUserRequest{ProjectID: projectID.String(), RoleID: "unknown"})
+	wantCode(t, err, "INVALID_ROLE")
+	_, err = endpoint.CreateProjectUser(ctx, endpoints.CreateProjectUserRequest{ProjectID: "nope", RoleID: roleID.String()})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateProjectUser(ctx, r) })
+}
+
+func TestGetProjectUser(t *testing.T) {
+	projectID, userID := uuid.NewString(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		GetProjectUserFunc: func(_ context.Context, pid string, id uuid.UUID, includeDeleted bool) (*models.DisplayUser, error) {
+			if pid != projectID || id != userID {
+				t.Errorf("GetProjectUser(%q, %v)", pid, id)
+			}
+			if !includeDeleted {
+				return nil, projectusers.ErrUserNotFound
+			}
+			return &models.DisplayUser{ID: id.String(), DeletedBy: "system"}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	if _, err := endpoint.GetProjectUser(ctx, endpoints.GetProjectUserRequest{ProjectID: projectID, UserID: userID.String()}); err != projectusers.ErrUserNotFound {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+	response, err := endpoint.GetProjectUser(ctx, endpoints.GetProjectUserRequest{ProjectID: projectID, UserID: userID.String(), IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetProjectUser: %v", err)
+	}
+	if user := response.(endpoints.GetProjectUserResponse).User; user.ID != userID.String() || user.DeletedBy != "system" {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := endpoint.GetProjectUser(ctx, endpoints.GetProjectUserRequest{ProjectID: projectID, UserID: "nope"}); err == nil {
+		t.Fatal("GetProjectUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetProjectUser(ctx, r) })
+}
+
+func TestListProjectUsers(t *testing.T) {
+	projectID := uuid.NewString()
+	order := sorting.Order{Field: "email", Desc: true}
+	manager := &mocks.ProjectUserManager{
+		ListProjectUsersFunc: func(_ context.Context, pid string, includeDeleted bool, status string, got sorting.Order) ([]models.DisplayUser, error) {
+			if pid != projectID || !includeDeleted || status != "active" || got != order {
+				t.Errorf("ListProjectUsers(%q, %v, %q, %+v)", pid, includeDeleted, status, got)
+			}
+			return []models.DisplayUser{{Email: "b@example.com"}, {Email: "a@example.com"}}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListProjectUsers(ctx, endpoints.ListProjectUsersRequest{ProjectID: projectID, IncludeDeleted: true, Status: "active", Sort: order})
+	if err != nil {
+		t.Fatalf("ListProjectUsers: %v", err)
+	}
+	if users := response.(endpoints.ListProjectUsersResponse).Users; len(users) != 2 || users[0].Email != "b@example.com" {
+		t.Fatalf("users = %+v", users)
+	}
+
+	manager.ListProjectUsersFunc = func(context.Context, string, bool, string, sorting.Order) ([]models.DisplayUser, error) {
+		return nil, nil
+	}
+	response, err = endpoint.ListProjectUsers(ctx, endpoints.ListProjectUsersRequest{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("ListProjectUsers: %v", err)
+	}
+	if users := response.(endpoints.ListProjectUsersResponse).Users; users == nil || len(users) != 0 {
+		t.Fatalf("users = %#v, want an empty list", users)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListProjectUsers(ctx, r) })
+}
+
+func TestListDeletedProjectUsers(t *testing.T) {
+	projectID := uuid.NewString()
+	manager := &mocks.ProjectUserManager{
+		ListDeletedProjectUsersFunc: func(_ context.Context, pid string, page, pageSize int) (*projectusers.DeletedUsersPage, error) {
+			if pid != projectID || page != 2 || pageSize != 10 {
+				t.Errorf("ListDeletedProjectUsers(%q, %d, %d)", pid, page, pageSize)
+			}
+			return &projectusers.DeletedUsersPage{Total: 11, Page: page, PageSize: pageSize}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListDeletedProjectUsers(ctx, endpoints.ListDeletedProjectUsersRequest{ProjectID: projectID, Page: 2, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListDeletedProjectUsers: %v", err)
+	}
+	page := response.(endpoints.ListDeletedProjectUsersResponse)
+	if page.Users == nil || len(page.Users) != 0 || page.Total != 11 || page.Page != 2 || page.PageSize != 10 {
+		t.Fatalf("page = %#v", page)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListDeletedProjectUsers(ctx, r) })
+}
+
+func TestUpdateProjectUser(t *testing.T) {
+	projectID, userID := uuid.NewString(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		UpdateProjectUserFunc: func(_ context.Context, pid string, id uuid.UUID, first, last string, active bool) (*models.DisplayUser, error) {
+			if pid != projectID || id != userID || first != "Grace" || last != "Hopper" || !active {
+				t.Errorf("UpdateProjectUser(%q, %v, %q, %q, %v)", pid, id, first, last, active)
+			}
+			return &models.DisplayUser{ID: id.String(), FirstName: first, LastName: last, Active: active}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdateProjectUser(ctx, endpoints.UpdateProjectUserRequest{ProjectID: projectID, UserID: userID.String(), FirstName: "Grace", LastName: "Hopper", Active: true})
+	if err != nil {
+		t.Fatalf("UpdateProjectUser: %v", err)
+	}
+	if user := response.(endpoints.UpdateProjectUserResponse).User; user.FirstName != "Grace" || !user.Active {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := endpoint.UpdateProjectUser(ctx, endpoints.UpdateProjectUserRequest{ProjectID: projectID, UserID: "nope"}); err == nil {
+		t.Fatal("UpdateProjectUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdateProjectUser(ctx, r) })
+}
+
+func TestSetProjectUserStatus(t *testing.T) {
+	projectID, userID := uuid.NewString(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		SetProjectUserStatusFunc: func(_ context.Context, pid string, id uuid.UUID, status string) (*models.DisplayUser, error) {
+			if pid != projectID || id != userID {
+				t.Errorf("SetProjectUserStatus(%q, %v)", pid, id)
+			}
+			return &models.DisplayUser{ID: id.String(), Status: status}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.SetProjectUserStatus(ctx, endpoints.SetProjectUserStatusRequest{ProjectID: projectID, UserID: userID.String(), Status: "suspended"})
+	if err != nil {
+		t.Fatalf("SetProjectUserStatus: %v", err)
+	}
+	if user := response.(endpoints.SetProjectUserStatusResponse).User; user.Status != "suspended" {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := endpoint.SetProjectUserStatus(ctx, endpoints.SetProjectUserStatusRequest{ProjectID: projectID, UserID: "nope"}); err == nil {
+		t.Fatal("SetProjectUserStatus accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.SetProjectUserStatus(ctx, r) })
+}
+
+func TestBulkSetProjectUsersActive(t *testing.T) {
+	projectID := uuid.NewString()
+	first, second := uuid.New(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		BulkSetProjectUsersActiveFunc: func(_ context.Context, pid string, ids []uuid.UUID, active bool) (int64, error) {
+			if pid != projectID || len(ids) != 2 || ids[0] != first || ids[1] != second || active {
+				t.Errorf("BulkSetProjectUsersActive(%q, %v, %v)", pid, ids, active)
+			}
+			return 1, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+	inactive := false
+
+	response, err := endpoint.BulkSetProjectUsersActive(ctx, endpoints.BulkSetProjectUsersActiveRequest{
+		ProjectID: projectID, UserIDs: []string{first.String(), second.String()}, Active: &inactive,
+	})
+	if err != nil {
+		t.Fatalf("BulkSetProjectUsersActive: %v", err)
+	}
+	if affected := response.(endpoints.BulkSetProjectUsersActiveResponse).Affected; affected != 1 {
+		t.Fatalf("affected = %d, want 1", affected)
+	}
+
+	_, err = endpoint.BulkSetProjectUsersActive(ctx, endpoints.BulkSetProjectUsersActiveRequest{ProjectID: projectID, UserIDs: []string{first.String()}})
+	wantCode(t, err, "ACTIVE_REQUIRED")
+	_, err = endpoint.BulkSetProjectUsersActive(ctx, endpoints.BulkSetProjectUsersActiveRequest{ProjectID: projectID, UserIDs: []string{"nope"}, Active: &inactive})
+	wantCode(t, err, "INVALID_USER_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.BulkSetProjectUsersActive(ctx, r) })
+}
+
+func TestDeleteProjectUser(t *testing.T) {
+	projectID, userID := uuid.NewString(), uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.ProjectUserManager{
+		DeleteProjectUserFunc: func(_ context.Context, pid string, id uuid.UUID) error {
+			if pid != projectID {
+				t.Errorf("project = %q, want %q", pid, projectID)
+			}
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteProjectUser(ctx, endpoints.DeleteProjectUserRequest{ProjectID: projectID, UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+	if !response.(endpoints.DeleteProjectUserResponse).Success || deleted != userID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	manager.DeleteProjectUserFunc = func(context.Context, string, uuid.UUID) error { return projectusers.ErrUserNotFound }
+	if _, err := endpoint.DeleteProjectUser(ctx, endpoints.DeleteProjectUserRequest{ProjectID: projectID, UserID: userID.String()}); err != projectusers.ErrUserNotFound {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+	if _, err := endpoint.DeleteProjectUser(ctx, endpoints.DeleteProjectUserRequest{ProjectID: projectID, UserID: "nope"}); err == nil {
+		t.Fatal("DeleteProjectUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteProjectUser(ctx, r) })
+}
+
+func TestRestoreProjectUser(t *testing.T) {
+	projectID, userID := uuid.NewString(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		RestoreProjectUserFunc: func(_ context.Context, pid string, id uuid.UUID) (*models.DisplayUser, error) {
+			return &models.DisplayUser{ID: id.String(), ProjectID: pid}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.RestoreProjectUser(ctx, endpoints.RestoreProjectUserRequest{ProjectID: projectID, UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("RestoreProjectUser: %v", err)
+	}
+	if user := response.(endpoints.RestoreProjectUserResponse).User; user.ID != userID.String() || user.ProjectID != projectID {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := endpoint.RestoreProjectUser(ctx, endpoints.RestoreProjectUserRequest{ProjectID: projectID, UserID: "nope"}); err == nil {
+		t.Fatal("RestoreProjectUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RestoreProjectUser(ctx, r) })
+}
+
+func TestFindDuplicateProjectUsers(t *testing.T) {
+	projectID := uuid.NewString()
+	manager := &mocks.ProjectUserManager{
+		FindDuplicatesFunc: func(_ context.Context, pid string) ([]projectusers.DuplicateCluster, error) {
+			if pid != projectID {
+				t.Errorf("project = %q, want %q", pid, projectID)
+			}
+			return []projectusers.DuplicateCluster{{Confidence: "high", Reasons: []string{"email"}}}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.FindDuplicateProjectUsers(ctx, endpoints.FindDuplicateProjectUsersRequest{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("FindDuplicateProjectUsers: %v", err)
+	}
+	if clusters := response.(endpoints.FindDuplicateProjectUsersResponse).Clusters; len(clusters) != 1 || clusters[0].Confidence != "high" {
+		t.Fatalf("clusters = %+v", clusters)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.FindDuplicateProjectUsers(ctx, r) })
+}
+
+func TestMergeProjectUsers(t *testing.T) {
+	projectID, primaryID, duplicateID := uuid.NewString(), uuid.New(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		MergeProjectUsersFunc: func(_ context.Context, pid string, primary, duplicate uuid.UUID) (*projectusers.MergeResult, error) {
+			if pid != projectID || primary != primaryID || duplicate != duplicateID {
+				t.Errorf("MergeProjectUsers(%q, %v, %v)", pid, primary, duplicate)
+			}
+			return &projectusers.MergeResult{Primary: models.DisplayUser{ID: primary.String()}, DuplicateID: duplicate.String(), LoginEvents: 3, Deactivated: true}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.MergeProjectUsers(ctx, endpoints.MergeProjectUsersRequest{ProjectID: projectID, PrimaryID: primaryID.String(), DuplicateID: duplicateID.String()})
+	if err != nil {
+		t.Fatalf("MergeProjectUsers: %v", err)
+	}
+	merge := response.(endpoints.MergeProjectUsersResponse).Merge
+	if merge.Primary.ID != primaryID.String() || merge.DuplicateID != duplicateID.String() || merge.LoginEvents != 3 || !merge.Deactivated {
+		t.Fatalf("merge = %+v", merge)
+	}
+
+	_, err = endpoint.MergeProjectUsers(ctx, endpoints.MergeProjectUsersRequest{ProjectID: projectID, PrimaryID: "nope", DuplicateID: duplicateID.String()})
+	wantCode(t, err, "INVALID_USER_ID")
+	_, err = endpoint.MergeProjectUsers(ctx, endpoints.MergeProjectUsersRequest{ProjectID: projectID, PrimaryID: primaryID.String(), DuplicateID: "nope"})
+	wantCode(t, err, "INVALID_USER_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.MergeProjectUsers(ctx, r) })
+}
+
+func TestRefreshProjectUserTokens(t *testing.T) {
+	projectID, roleID := uuid.New(), uuid.New()
+	manager := &mocks.ProjectUserManager{
+		RefreshProjectUserTokensFunc: func(_ context.Context, pid string, rid uuid.UUID, issue bool) (*projectusers.TokenRefresh, error) {
+			if pid != projectID.String() || rid != roleID || !issue {
+				t.Errorf("RefreshProjectUserTokens(%q, %v, %v)", pid, rid, issue)
+			}
+			return &projectusers.TokenRefresh{RoleID: rid.String(), Updated: 4}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectUsersEndpoint(manager, rolesNamed(map[string]uuid.UUID{"member": roleID}))
+	ctx := context.Background()
+
+	response, err := endpoint.RefreshProjectUserTokens(ctx, endpoints.RefreshProjectUserTokensRequest{ProjectID: projectID.String(), RoleID: "member", IssueTokens: true})
+	if err != nil {
+		t.Fatalf("RefreshProjectUserTokens: %v", err)
+	}
+	if refresh := response.(endpoints.RefreshProjectUserTokensResponse).Refresh; refresh.RoleID != roleID.String() || refresh.Updated != 4 {
+		t.Fatalf("refresh = %+v", refresh)
+	}
+
+	_, err = endpoint.RefreshProjectUserTokens(ctx, endpoints.RefreshProjectUserTokensRequest{ProjectID: "nope", RoleID: "member"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	_, err = endpoint.RefreshProjectUserTokens(ctx, endpoints.RefreshProjectUserTokensRequest{ProjectID: projectID.String()})
+	wantCode(t, err, "INVALID_ROLE")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RefreshProjectUserTokens(ctx, r) })
+}
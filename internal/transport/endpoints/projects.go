@@ -3,21 +3,33 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"github.com/yash3004/user_management_service/projects"
 	"gorm.io/gorm"
 )
 
 // Project represents a project in the response
 type Project struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	UniqueID    string    `json:"unique_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	UniqueID    string                  `json:"unique_id"`
+	Region      string                  `json:"region,omitempty"` // Where the project's users are stored; empty for the default region
+	Settings    schemas.ProjectSettings `json:"settings"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	DeletedAt   *time.Time              `json:"deleted_at,omitempty"` // Only set on deleted projects
+	DeletedBy   string                  `json:"deleted_by,omitempty"` // User ID of the deleter, or "system"
 }
 
 // CreateProjectRequest represents the create project request
@@ -25,6 +37,7 @@ type CreateProjectRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	UniqueID    string `json:"unique_id"`
+	Region      string `json:"region"` // One of the configured regions; empty for the default
 }
 
 // CreateProjectResponse represents the create project response
@@ -34,7 +47,8 @@ type CreateProjectResponse struct {
 
 // GetProjectRequest represents the get project request
 type GetProjectRequest struct {
-	ID string `json:"id"`
+	ID             string `json:"id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 // GetProjectResponse represents the get project response
@@ -44,7 +58,7 @@ type GetProjectResponse struct {
 
 // ListProjectsRequest represents the list projects request
 type ListProjectsRequest struct {
-	// Add pagination parameters if needed
+	Sort sorting.Order `json:"-"` // From the sort query parameter
 }
 
 // ListProjectsResponse represents the list projects response
@@ -64,25 +78,190 @@ type UpdateProjectResponse struct {
 	Project Project `json:"project"`
 }
 
+// Ways of exporting a project's users before it is deleted
+const (
+	DeleteExportBackup   = "backup"   // Written to the configured backup directory
+	DeleteExportDownload = "download" // Returned as the response body
+)
+
 // DeleteProjectRequest represents the delete project request
 type DeleteProjectRequest struct {
-	ID string `json:"id"`
+	ID     string `json:"id"`
+	Export string `json:"export"` // Empty, DeleteExportBackup or DeleteExportDownload
 }
 
 // DeleteProjectResponse represents the delete project response
 type DeleteProjectResponse struct {
-	Success bool `json:"success"`
+	Success   bool                   `json:"success"`
+	Removed   projects.DeletePreview `json:"removed"`
+	ExportKey string                 `json:"export_key,omitempty"` // Set for backup exports
+	ExportURL string                 `json:"export_url,omitempty"` // Where the backup export can be downloaded
+}
+
+// DeleteProjectDownload is the response of a delete with a download export:
+// the users export, streamed as CSV. Closing Content removes the temporary
+// file behind it.
+type DeleteProjectDownload struct {
+	Filename string
+	Content  io.ReadCloser
+	Removed  projects.DeletePreview
+}
+
+// GetDeletePreviewRequest represents the delete preview request
+type GetDeletePreviewRequest struct {
+	ID string `json:"id"`
+}
+
+// GetDeletePreviewResponse represents the delete preview response
+type GetDeletePreviewResponse struct {
+	Preview projects.DeletePreview `json:"preview"`
+}
+
+// GetProjectStatsRequest represents the get project stats request
+type GetProjectStatsRequest struct {
+	ID string `json:"id"`
+}
+
+// GetProjectStatsResponse represents the get project stats response
+type GetProjectStatsResponse struct {
+	Stats projects.ProjectStats `json:"stats"`
+}
+
+// UpdateProjectSettingsRequest represents the update project settings request
+type UpdateProjectSettingsRequest struct {
+	ID       string                  `json:"-"` // From URL path
+	Settings schemas.ProjectSettings `json:"-"` // The whole request body
+}
+
+// UpdateProjectSettingsResponse represents the update project settings response
+type UpdateProjectSettingsResponse struct {
+	Project Project `json:"project"`
+}
+
+// SetOAuthProviderRequest represents the set project OAuth provider request
+type SetOAuthProviderRequest struct {
+	ID       string                        `json:"-"` // From URL path
+	Provider string                        `json:"-"` // From URL path
+	Config   schemas.OAuthProviderSettings `json:"-"` // The whole request body
+}
+
+// SetOAuthProviderResponse represents the set project OAuth provider response
+type SetOAuthProviderResponse struct {
+	Project Project `json:"project"`
+}
+
+// DeleteOAuthProviderRequest represents the delete project OAuth provider request
+type DeleteOAuthProviderRequest struct {
+	ID       string `json:"-"` // From URL path
+	Provider string `json:"-"` // From URL path
+}
+
+// DeleteOAuthProviderResponse represents the delete project OAuth provider response
+type DeleteOAuthProviderResponse struct {
+	Project Project `json:"project"`
+}
+
+// ValidateUniqueIDRequest represents the validate unique ID request
+type ValidateUniqueIDRequest struct {
+	UniqueID string `json:"unique_id"`
+}
+
+// ValidateUniqueIDResponse represents the validate unique ID response
+type ValidateUniqueIDResponse struct {
+	UniqueID    string   `json:"unique_id"` // Normalized form; empty when invalid
+	Valid       bool     `json:"valid"`
+	Available   bool     `json:"available"`
+	Error       string   `json:"error,omitempty"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// CheckUniqueIDRequest represents the unique ID availability request
+type CheckUniqueIDRequest struct {
+	Value string `json:"value"`
+}
+
+// CheckUniqueIDResponse represents the unique ID availability response
+type CheckUniqueIDResponse struct {
+	Available   bool     `json:"available"`
+	Normalized  string   `json:"normalized"` // Empty when the value is invalid
+	Valid       bool     `json:"valid"`
+	Error       string   `json:"error,omitempty"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// CloneProjectRequest represents the clone project request
+type CloneProjectRequest struct {
+	ID       string `json:"-"` // From URL path
+	Name     string `json:"name"`
+	UniqueID string `json:"unique_id"`
+}
+
+// CloneProjectResponse represents the clone project response. The ID maps
+// are keyed by the source IDs so callers can follow up on the copies.
+type CloneProjectResponse struct {
+	Project     Project           `json:"project"`
+	RoleIDMap   map[string]string `json:"role_id_map"`
+	PolicyIDMap map[string]string `json:"policy_id_map"`
+}
+
+// BootstrapProjectRequest represents the bootstrap project request: a whole
+// tenant in one document
+type BootstrapProjectRequest struct {
+	Name           string                                   `json:"name"`
+	Description    string                                   `json:"description"`
+	UniqueID       string                                   `json:"unique_id"`
+	Settings       schemas.ProjectSettings                  `json:"settings"`
+	Roles          []BootstrapRole                          `json:"roles"`
+	OAuthProviders map[string]schemas.OAuthProviderSettings `json:"oauth_providers"`
+	Webhooks       []BootstrapWebhook                       `json:"webhooks"`
+	Admin          *BootstrapAdmin                          `json:"admin"`
+}
+
+// BootstrapRole is a role a bootstrapped project starts with
+type BootstrapRole struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Expiration  int                       `json:"expiration"` // In hours
+	Default     bool                      `json:"default"`    // Becomes the project's default_role_id
+	Policies    []projects.PolicyTemplate `json:"policies"`
+}
+
+// BootstrapWebhook is a webhook subscription of a bootstrapped project
+type BootstrapWebhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Format string   `json:"format"`
+}
+
+// BootstrapAdmin is the user a bootstrapped project is handed over to
+type BootstrapAdmin struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      string `json:"role"` // A role name; the default role when empty
+}
+
+// BootstrapProjectResponse represents the bootstrap project response. Roles
+// and policies are keyed by name; webhooks are in request order.
+type BootstrapProjectResponse struct {
+	Project     Project               `json:"project"`
+	RoleIDs     map[string]string     `json:"role_ids"`
+	PolicyIDs   map[string]string     `json:"policy_ids"`
+	Webhooks    []WebhookSubscription `json:"webhooks"`
+	AdminUserID string                `json:"admin_user_id,omitempty"`
 }
 
 // ProjectsEndpoint handles project-related endpoints
 type ProjectsEndpoint struct {
-	ProjectManager projects.ProjectManager
+	ProjectManager  projects.ProjectManager
+	ArtifactManager artifacts.ArtifactManager // Keeps backup exports
 }
 
 // NewProjectsEndpoint creates a new projects endpoint
-func NewProjectsEndpoint(manager projects.ProjectManager) *ProjectsEndpoint {
+func NewProjectsEndpoint(manager projects.ProjectManager, artifactManager artifacts.ArtifactManager) *ProjectsEndpoint {
 	return &ProjectsEndpoint{
-		ProjectManager: manager,
+		ProjectManager:  manager,
+		ArtifactManager: artifactManager,
 	}
 }
 
@@ -94,7 +273,7 @@ func (e *ProjectsEndpoint) CreateProject(ctx context.Context, request interface{
 	}
 
 	// Delegate to the project manager
-	project, err := e.ProjectManager.CreateProject(ctx, req.Name, req.Description, req.UniqueID)
+	project, err := e.ProjectManager.CreateProject(ctx, req.Name, req.Description, req.UniqueID, req.Region)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +284,8 @@ func (e *ProjectsEndpoint) CreateProject(ctx context.Context, request interface{
 			Name:        project.Name,
 			Description: project.Description,
 			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
 			CreatedAt:   project.CreatedAt,
 			UpdatedAt:   project.UpdatedAt,
 		},
@@ -125,27 +306,41 @@ func (e *ProjectsEndpoint) GetProject(ctx context.Context, request interface{})
 	}
 
 	// Delegate to the project manager
-	project, err := e.ProjectManager.GetProject(ctx, projectID)
+	get := e.ProjectManager.GetProject
+	if req.IncludeDeleted {
+		get = e.ProjectManager.GetProjectIncludingDeleted
+	}
+	project, err := get(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
 
+	deletedAt, deletedBy := deletion(project.DeletedAt, project.DeletedBy)
 	return GetProjectResponse{
 		Project: Project{
 			ID:          project.ID.String(),
 			Name:        project.Name,
 			Description: project.Description,
 			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
 			CreatedAt:   project.CreatedAt,
 			UpdatedAt:   project.UpdatedAt,
+			DeletedAt:   deletedAt,
+			DeletedBy:   deletedBy,
 		},
 	}, nil
 }
 
 // ListProjects lists all projects
 func (e *ProjectsEndpoint) ListProjects(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListProjectsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
 	// Delegate to the project manager
-	projectsList, err := e.ProjectManager.ListProjects(ctx)
+	projectsList, err := e.ProjectManager.ListProjects(ctx, req.Sort)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +353,8 @@ func (e *ProjectsEndpoint) ListProjects(ctx context.Context, request interface{}
 			Name:        p.Name,
 			Description: p.Description,
 			UniqueID:    p.UniqueID,
+			Region:      p.Region,
+			Settings:    p.Settings.Redacted(),
 			CreatedAt:   p.CreatedAt,
 			UpdatedAt:   p.UpdatedAt,
 		}
@@ -193,6 +390,8 @@ func (e *ProjectsEndpoint) UpdateProject(ctx context.Context, request interface{
 			Name:        project.Name,
 			Description: project.Description,
 			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
 			CreatedAt:   project.CreatedAt,
 			UpdatedAt:   project.UpdatedAt,
 		},
@@ -212,25 +411,429 @@ func (e *ProjectsEndpoint) DeleteProject(ctx context.Context, request interface{
 		return nil, errors.New("invalid project ID format")
 	}
 
+	switch req.Export {
+	case "":
+		removed, err := e.ProjectManager.DeleteProject(ctx, projectID, nil)
+		if err != nil {
+			return nil, err
+		}
+		return DeleteProjectResponse{Success: true, Removed: *removed}, nil
+	case DeleteExportBackup:
+		return e.deleteWithBackup(ctx, projectID)
+	case DeleteExportDownload:
+		return e.deleteWithDownload(ctx, projectID)
+	default:
+		return nil, apierrors.BadRequest("INVALID_EXPORT", "export must be backup or download")
+	}
+}
+
+// deleteWithBackup exports the users to a temporary file before deleting
+// and keeps the export as an artifact for the retention period
+func (e *ProjectsEndpoint) deleteWithBackup(ctx context.Context, projectID uuid.UUID) (interface{}, error) {
+	file, err := os.CreateTemp("", "project-*-users.csv")
+	if err != nil {
+		log.For(ctx).Errorf("Failed to create export file: %v", err)
+		return nil, projects.ErrExportFailed
+	}
+	defer (&removeOnClose{File: file}).Close()
+
+	removed, err := e.ProjectManager.DeleteProject(ctx, projectID, file)
+	if err != nil {
+		return nil, err
+	}
+	response := DeleteProjectResponse{Success: true, Removed: *removed}
+
+	// The project is already gone, so a failure to keep the export is
+	// logged and the delete reported without it
+	key := fmt.Sprintf("exports/project-%s-users-%s.csv", projectID, time.Now().UTC().Format("20060102T150405Z"))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.For(ctx).Errorf("Failed to rewind export file: %v", err)
+		return response, nil
+	}
+	if err := e.ArtifactManager.Save(ctx, key, schemas.ArtifactKindProjectExport, &projectID, true, file); err != nil {
+		log.For(ctx).Errorf("Failed to store backup export %s: %v", key, err)
+		return response, nil
+	}
+	response.ExportKey = key
+	response.ExportURL = artifactURL(ctx, e.ArtifactManager, key)
+
+	return response, nil
+}
+
+// deleteWithDownload exports the users to a temporary file before deleting
+// and returns the file for the client to download
+func (e *ProjectsEndpoint) deleteWithDownload(ctx context.Context, projectID uuid.UUID) (interface{}, error) {
+	file, err := os.CreateTemp("", "project-*-users.csv")
+	if err != nil {
+		log.For(ctx).Errorf("Failed to create export file: %v", err)
+		return nil, projects.ErrExportFailed
+	}
+	content := &removeOnClose{File: file}
+
+	removed, err := e.ProjectManager.DeleteProject(ctx, projectID, file)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		content.Close()
+		log.For(ctx).Errorf("Failed to rewind export file: %v", err)
+		return nil, errors.New("project deleted but its users export could not be returned")
+	}
+
+	return DeleteProjectDownload{
+		Filename: fmt.Sprintf("project-%s-users.csv", projectID),
+		Content:  content,
+		Removed:  *removed,
+	}, nil
+}
+
+// removeOnClose deletes a temporary file once it has been read
+type removeOnClose struct {
+	*os.File
+}
+
+func (f *removeOnClose) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
+// GetDeletePreview reports what deleting a project would remove
+func (e *ProjectsEndpoint) GetDeletePreview(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetDeletePreviewRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	preview, err := e.ProjectManager.PreviewDelete(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDeletePreviewResponse{
+		Preview: *preview,
+	}, nil
+}
+
+// GetProjectStats gets aggregate user statistics for a project
+func (e *ProjectsEndpoint) GetProjectStats(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetProjectStatsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	// Delegate to the project manager
+	stats, err := e.ProjectManager.GetProjectStats(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetProjectStatsResponse{
+		Stats: *stats,
+	}, nil
+}
+
+// UpdateProjectSettings replaces the settings of a project
+func (e *ProjectsEndpoint) UpdateProjectSettings(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(UpdateProjectSettingsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	// Delegate to the project manager
+	project, err := e.ProjectManager.UpdateProjectSettings(ctx, projectID, req.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return UpdateProjectSettingsResponse{
+		Project: Project{
+			ID:          project.ID.String(),
+			Name:        project.Name,
+			Description: project.Description,
+			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.UpdatedAt,
+		},
+	}, nil
+}
+
+// SetOAuthProvider configures a project's own client for an OAuth provider
+func (e *ProjectsEndpoint) SetOAuthProvider(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(SetOAuthProviderRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	// Delegate to the project manager
+	project, err := e.ProjectManager.SetOAuthProvider(ctx, projectID, req.Provider, req.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return SetOAuthProviderResponse{
+		Project: Project{
+			ID:          project.ID.String(),
+			Name:        project.Name,
+			Description: project.Description,
+			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.UpdatedAt,
+		},
+	}, nil
+}
+
+// DeleteOAuthProvider removes a project's OAuth provider override
+func (e *ProjectsEndpoint) DeleteOAuthProvider(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(DeleteOAuthProviderRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
 	// Delegate to the project manager
-	err = e.ProjectManager.DeleteProject(ctx, projectID)
+	project, err := e.ProjectManager.DeleteOAuthProvider(ctx, projectID, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeleteOAuthProviderResponse{
+		Project: Project{
+			ID:          project.ID.String(),
+			Name:        project.Name,
+			Description: project.Description,
+			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.UpdatedAt,
+		},
+	}, nil
+}
+
+// ValidateUniqueID normalizes a unique ID and reports whether a project could
+// be created with it, suggesting free ones close to it otherwise
+func (e *ProjectsEndpoint) ValidateUniqueID(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ValidateUniqueIDRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	check, err := e.ProjectManager.ValidateUniqueID(ctx, req.UniqueID)
 	if err != nil {
 		return nil, err
 	}
 
-	return DeleteProjectResponse{
-		Success: true,
+	return ValidateUniqueIDResponse{
+		UniqueID:    check.UniqueID,
+		Valid:       check.Valid,
+		Available:   check.Available,
+		Error:       check.Error,
+		Suggestions: check.Suggestions,
 	}, nil
 }
 
+// CheckUniqueID reports whether a unique ID typed for a new project is free,
+// as ValidateUniqueID does, in the shape of the availability check
+func (e *ProjectsEndpoint) CheckUniqueID(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CheckUniqueIDRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	check, err := e.ProjectManager.ValidateUniqueID(ctx, req.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return CheckUniqueIDResponse{
+		Available:   check.Available,
+		Normalized:  check.UniqueID,
+		Valid:       check.Valid,
+		Error:       check.Error,
+		Suggestions: check.Suggestions,
+	}, nil
+}
+
+// CloneProject copies a project's settings, roles and policies into a new project
+func (e *ProjectsEndpoint) CloneProject(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CloneProjectRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	// Parse UUID
+	projectID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	// Delegate to the project manager
+	result, err := e.ProjectManager.CloneProject(ctx, projectID, req.Name, req.UniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDMap := make(map[string]string, len(result.RoleIDs))
+	for from, to := range result.RoleIDs {
+		roleIDMap[from.String()] = to.String()
+	}
+	policyIDMap := make(map[string]string, len(result.PolicyIDs))
+	for from, to := range result.PolicyIDs {
+		policyIDMap[from.String()] = to.String()
+	}
+
+	project := result.Project
+	return CloneProjectResponse{
+		Project: Project{
+			ID:          project.ID.String(),
+			Name:        project.Name,
+			Description: project.Description,
+			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.UpdatedAt,
+		},
+		RoleIDMap:   roleIDMap,
+		PolicyIDMap: policyIDMap,
+	}, nil
+}
+
+// BootstrapProject provisions a project with its settings, roles, OAuth
+// providers, webhooks and invited admin in one request
+func (e *ProjectsEndpoint) BootstrapProject(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(BootstrapProjectRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	bootstrap := projects.Bootstrap{
+		Name:           req.Name,
+		Description:    req.Description,
+		UniqueID:       req.UniqueID,
+		Settings:       req.Settings,
+		Roles:          make([]projects.RoleTemplate, len(req.Roles)),
+		OAuthProviders: req.OAuthProviders,
+		Webhooks:       make([]projects.BootstrapWebhook, len(req.Webhooks)),
+	}
+	for i, role := range req.Roles {
+		expiration, err := addHours(role.Expiration)
+		if err != nil {
+			var apiErr *apierrors.Error
+			if errors.As(err, &apiErr) {
+				return nil, apiErr.At(fmt.Sprintf("roles[%d].expiration", i))
+			}
+			return nil, err
+		}
+		bootstrap.Roles[i] = projects.RoleTemplate{
+			Name:        role.Name,
+			Description: role.Description,
+			Expiration:  expiration,
+			Default:     role.Default,
+			Policies:    role.Policies,
+		}
+	}
+	for i, hook := range req.Webhooks {
+		bootstrap.Webhooks[i] = projects.BootstrapWebhook{
+			URL:    hook.URL,
+			Events: hook.Events,
+			Format: hook.Format,
+		}
+	}
+	if admin := req.Admin; admin != nil {
+		bootstrap.Admin = &projects.BootstrapAdmin{
+			Email:     admin.Email,
+			FirstName: admin.FirstName,
+			LastName:  admin.LastName,
+			Role:      admin.Role,
+		}
+	}
+
+	// Delegate to the project manager
+	result, err := e.ProjectManager.BootstrapProject(ctx, bootstrap)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs := make(map[string]string, len(result.RoleIDs))
+	for name, id := range result.RoleIDs {
+		roleIDs[name] = id.String()
+	}
+	policyIDs := make(map[string]string, len(result.PolicyIDs))
+	for name, id := range result.PolicyIDs {
+		policyIDs[name] = id.String()
+	}
+	hooks := make([]WebhookSubscription, len(result.Webhooks))
+	for i, sub := range result.Webhooks {
+		hooks[i] = toWebhookSubscription(sub)
+		hooks[i].Secret = sub.Secret
+	}
+	var adminUserID string
+	if result.AdminID != nil {
+		adminUserID = result.AdminID.String()
+	}
+
+	project := result.Project
+	return BootstrapProjectResponse{
+		Project: Project{
+			ID:          project.ID.String(),
+			Name:        project.Name,
+			Description: project.Description,
+			UniqueID:    project.UniqueID,
+			Region:      project.Region,
+			Settings:    project.Settings.Redacted(),
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.UpdatedAt,
+		},
+		RoleIDs:     roleIDs,
+		PolicyIDs:   policyIDs,
+		Webhooks:    hooks,
+		AdminUserID: adminUserID,
+	}, nil
+}
 
 // CreateProjectUserTable creates a new user table for a project
 func CreateProjectUserTable(db *gorm.DB, projectID string) error {
 	// Define the project user table structure
 	type ProjectUser struct {
 		ID        uuid.UUID `gorm:"type:char(36);primary_key"`
-		Email     string    `gorm:"uniqueIndex"`
-		Password  string    `gorm:"size:255"` // Hashed password for local auth
+		Email     string    `gorm:"size:255;not null"` // Unique among live users, see schemas.CreateProjectUserEmailIndex
+		Password  string    `gorm:"size:255"`          // Hashed password for local auth
 		FirstName string    `gorm:"size:100"`
 		LastName  string    `gorm:"size:100"`
 		Active    bool      `gorm:"default:true"`
@@ -252,18 +855,24 @@ func CreateProjectUserTable(db *gorm.DB, projectID string) error {
 	}
 
 	// Create the table with project-specific name
-	tableName := "project_" + projectID + "_users"
-	
+	tableName, err := projecttable.UsersFor(projectID)
+	if err != nil {
+		return err
+	}
+
 	// Check if table already exists
 	if db.Migrator().HasTable(tableName) {
 		return errors.New("project user table already exists")
 	}
-	
+
 	// Create the table
-	err := db.Table(tableName).Migrator().CreateTable(&ProjectUser{})
+	err = db.Table(tableName).Migrator().CreateTable(&ProjectUser{})
 	if err != nil {
 		return err
 	}
-	
+	if err := schemas.CreateProjectUserEmailIndex(db, tableName); err != nil {
+		return err
+	}
+
 	return nil
-}
\ No newline at end of file
+}
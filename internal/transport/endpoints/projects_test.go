@@ -0,0 +1,565 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+// aSchemaProject builds the project a manager would return, with an OAuth
+// client secret the responses must leave out
+func aSchemaProject(id uuid.UUID) *schemas.Project {
+	return &schemas.Project{
+		ID:       id,
+		Name:     "Shop",
+		UniqueID: "shop",
+		Region:   "eu",
+		Settings: schemas.ProjectSettings{OAuthProviders: map[string]schemas.OAuthProviderSettings{
+			"google": {ClientID: "client", ClientSecret: "secret"},
+		}},
+	}
+}
+
+// wantRedacted checks a project response against the project it maps
+func wantRedacted(t *testing.T, got endpoints.Project, want *schemas.Project) {
+	t.Helper()
+
+	if got.ID != want.ID.String() || got.Name != want.Name || got.UniqueID != want.UniqueID || got.Region != want.Region {
+		t.Fatalf("project = %+v, want %+v", got, want)
+	}
+	if provider := got.Settings.OAuthProviders["google"]; provider.ClientID != "client" || provider.ClientSecret != "" {
+		t.Fatalf("google provider = %+v, want the client secret redacted", provider)
+	}
+}
+
+func TestCreateProject(t *testing.T) {
+	var created *schemas.Project
+	manager := &mocks.ProjectManager{
+		CreateProjectFunc: func(_ context.Context, name, description, uniqueID, region string) (*schemas.Project, error) {
+			if name != "Shop" || description != "Store" || uniqueID != "shop" || region != "eu" {
+				t.Errorf("CreateProject(%q, %q, %q, %q)", name, description, uniqueID, region)
+			}
+			created = aSchemaProject(uuid.New())
+			return created, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.CreateProject(ctx, endpoints.CreateProjectRequest{Name: "Shop", Description: "Store", UniqueID: "shop", Region: "eu"})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	wantRedacted(t, response.(endpoints.CreateProjectResponse).Project, created)
+
+	manager.CreateProjectFunc = func(context.Context, string, string, string, string) (*schemas.Project, error) {
+		return nil, projects.ErrProjectQuotaExceeded
+	}
+	if _, err := endpoint.CreateProject(ctx, endpoints.CreateProjectRequest{Name: "Shop"}); err != projects.ErrProjectQuotaExceeded {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectQuotaExceeded)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateProject(ctx, r) })
+}
+
+func TestGetProject(t *testing.T) {
+	projectID, deleter := uuid.New(), uuid.New()
+	deletedAt := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.ProjectManager{
+		GetProjectFunc: func(context.Context, uuid.UUID) (*schemas.Project, error) { return nil, projects.ErrProjectNotFound },
+		GetProjectIncludingDeletedFunc: func(_ context.Context, id uuid.UUID) (*schemas.Project, error) {
+			project := aSchemaProject(id)
+			project.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+			project.DeletedBy = &deleter
+			return project, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	if _, err := endpoint.GetProject(ctx, endpoints.GetProjectRequest{ID: projectID.String()}); err != projects.ErrProjectNotFound {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+	response, err := endpoint.GetProject(ctx, endpoints.GetProjectRequest{ID: projectID.String(), IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetProject: %v", err)
+	}
+	project := response.(endpoints.GetProjectResponse).Project
+	wantRedacted(t, project, aSchemaProject(projectID))
+	if project.DeletedAt == nil || !project.DeletedAt.Equal(deletedAt) || project.DeletedBy != deleter.String() {
+		t.Fatalf("deleted project = %+v", project)
+	}
+
+	if _, err := endpoint.GetProject(ctx, endpoints.GetProjectRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetProject accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetProject(ctx, r) })
+}
+
+func TestListProjects(t *testing.T) {
+	order := sorting.Order{Field: "name"}
+	first, second := aSchemaProject(uuid.New()), aSchemaProject(uuid.New())
+	manager := &mocks.ProjectManager{
+		ListProjectsFunc: func(_ context.Context, got sorting.Order) ([]schemas.Project, error) {
+			if got != order {
+				t.Errorf("order = %+v, want %+v", got, order)
+			}
+			return []schemas.Project{*first, *second}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListProjects(ctx, endpoints.ListProjectsRequest{Sort: order})
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	list := response.(endpoints.ListProjectsResponse).Projects
+	if len(list) != 2 {
+		t.Fatalf("projects = %+v", list)
+	}
+	wantRedacted(t, list[0], first)
+	wantRedacted(t, list[1], second)
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListProjects(ctx, r) })
+}
+
+func TestUpdateProject(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ProjectManager{
+		UpdateProjectFunc: func(_ context.Context, id uuid.UUID, name, description string) (*schemas.Project, error) {
+			if id != projectID || name != "Shop" || description != "New" {
+				t.Errorf("UpdateProject(%v, %q, %q)", id, name, description)
+			}
+			project := aSchemaProject(id)
+			project.Description = description
+			return project, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdateProject(ctx, endpoints.UpdateProjectRequest{ID: projectID.String(), Name: "Shop", Description: "New"})
+	if err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	project := response.(endpoints.UpdateProjectResponse).Project
+	wantRedacted(t, project, aSchemaProject(projectID))
+	if project.Description != "New" {
+		t.Fatalf("description = %q, want New", project.Description)
+	}
+
+	if _, err := endpoint.UpdateProject(ctx, endpoints.UpdateProjectRequest{ID: "nope"}); err == nil {
+		t.Fatal("UpdateProject accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdateProject(ctx, r) })
+}
+
+// exportingProjects is a project manager whose deletes write export as the
+// users export
+func exportingProjects(t *testing.T, projectID uuid.UUID, export string) *mocks.ProjectManager {
+	return &mocks.ProjectManager{
+		DeleteProjectFunc: func(_ context.Context, id uuid.UUID, w io.Writer) (*projects.DeletePreview, error) {
+			if id != projectID {
+				t.Errorf("deleted %v, want %v", id, projectID)
+			}
+			if w != nil {
+				if _, err := io.WriteString(w, export); err != nil {
+					return nil, err
+				}
+			}
+			return &projects.DeletePreview{Users: 2, Roles: 1}, nil
+		},
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	projectID := uuid.New()
+	endpoint := endpoints.NewProjectsEndpoint(exportingProjects(t, projectID, "email\n"), nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: projectID.String()})
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if deleted := response.(endpoints.DeleteProjectResponse); !deleted.Success || deleted.Removed.Users != 2 || deleted.ExportKey != "" {
+		t.Fatalf("response = %+v", deleted)
+	}
+
+	_, err = endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: projectID.String(), Export: "email"})
+	wantCode(t, err, "INVALID_EXPORT")
+	if _, err := endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: "nope"}); err == nil {
+		t.Fatal("DeleteProject accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteProject(ctx, r) })
+}
+
+func TestDeleteProjectBackup(t *testing.T) {
+	projectID := uuid.New()
+	var saved string
+	store := &mocks.ArtifactManager{
+		SaveFunc: func(_ context.Context, key, kind string, pid *uuid.UUID, expires bool, content io.Reader) error {
+			if kind != schemas.ArtifactKindProjectExport || pid == nil || *pid != projectID || !expires {
+				t.Errorf("Save(%q, %q, %v, %v)", key, kind, pid, expires)
+			}
+			data, err := io.ReadAll(content)
+			saved = string(data)
+			return err
+		},
+		SignedURLFunc: func(context.Context, string) (string, error) { return "", errors.New("not signed") },
+	}
+	endpoint := endpoints.NewProjectsEndpoint(exportingProjects(t, projectID, "email\na@example.com\n"), store)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: projectID.String(), Export: endpoints.DeleteExportBackup})
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	deleted := response.(endpoints.DeleteProjectResponse)
+	if !strings.HasPrefix(deleted.ExportKey, "exports/project-"+projectID.String()+"-users-") || deleted.ExportURL != "/api/v1/admin/artifacts/"+deleted.ExportKey {
+		t.Fatalf("response = %+v", deleted)
+	}
+	if saved != "email\na@example.com\n" {
+		t.Fatalf("saved export = %q", saved)
+	}
+
+	// The project is gone either way, so a failed save still reports the delete
+	store.SaveFunc = func(context.Context, string, string, *uuid.UUID, bool, io.Reader) error {
+		return errors.New("disk full")
+	}
+	response, err = endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: projectID.String(), Export: endpoints.DeleteExportBackup})
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if deleted := response.(endpoints.DeleteProjectResponse); !deleted.Success || deleted.ExportKey != "" || deleted.ExportURL != "" {
+		t.Fatalf("response = %+v, want the delete without an export", deleted)
+	}
+}
+
+func TestDeleteProjectDownload(t *testing.T) {
+	projectID := uuid.New()
+	endpoint := endpoints.NewProjectsEndpoint(exportingProjects(t, projectID, "email\na@example.com\n"), nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteProject(ctx, endpoints.DeleteProjectRequest{ID: projectID.String(), Export: endpoints.DeleteExportDownload})
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	download := response.(endpoints.DeleteProjectDownload)
+	if download.Filename != "project-"+projectID.String()+"-users.csv" || download.Removed.Users != 2 {
+		t.Fatalf("download = %+v", download)
+	}
+	data, err := io.ReadAll(download.Content)
+	if err != nil {
+		t.Fatalf("failed to read the export: %v", err)
+	}
+	if string(data) != "email\na@example.com\n" {
+		t.Fatalf("export = %q", data)
+	}
+	file, ok := download.Content.(interface{ Name() string })
+	if !ok {
+		t.Fatalf("content %T is not a file", download.Content)
+	}
+	download.Content.Close()
+	if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+		t.Fatalf("export file %s still there after close: %v", file.Name(), err)
+	}
+}
+
+func TestGetDeletePreview(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ProjectManager{
+		PreviewDeleteFunc: func(_ context.Context, id uuid.UUID) (*projects.DeletePreview, error) {
+			return &projects.DeletePreview{Users: 3, Webhooks: 1}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.GetDeletePreview(ctx, endpoints.GetDeletePreviewRequest{ID: projectID.String()})
+	if err != nil {
+		t.Fatalf("GetDeletePreview: %v", err)
+	}
+	if preview := response.(endpoints.GetDeletePreviewResponse).Preview; preview != (projects.DeletePreview{Users: 3, Webhooks: 1}) {
+		t.Fatalf("preview = %+v", preview)
+	}
+
+	if _, err := endpoint.GetDeletePreview(ctx, endpoints.GetDeletePreviewRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetDeletePreview accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetDeletePreview(ctx, r) })
+}
+
+func TestGetProjectStats(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ProjectManager{
+		GetProjectStatsFunc: func(_ context.Context, id uuid.UUID) (*projects.ProjectStats, error) {
+			return &projects.ProjectStats{TotalUsers: 5, ActiveUsers: 4}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.GetProjectStats(ctx, endpoints.GetProjectStatsRequest{ID: projectID.String()})
+	if err != nil {
+		t.Fatalf("GetProjectStats: %v", err)
+	}
+	if stats := response.(endpoints.GetProjectStatsResponse).Stats; stats.TotalUsers != 5 || stats.ActiveUsers != 4 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	if _, err := endpoint.GetProjectStats(ctx, endpoints.GetProjectStatsRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetProjectStats accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetProjectStats(ctx, r) })
+}
+
+func TestUpdateProjectSettings(t *testing.T) {
+	projectID := uuid.New()
+	settings := aSchemaProject(projectID).Settings
+	manager := &mocks.ProjectManager{
+		UpdateProjectSettingsFunc: func(_ context.Context, id uuid.UUID, got schemas.ProjectSettings) (*schemas.Project, error) {
+			if got.OAuthProviders["google"].ClientSecret != "secret" {
+				t.Errorf("settings = %+v, want the secret passed through", got)
+			}
+			project := aSchemaProject(id)
+			project.Settings = got
+			return project, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdateProjectSettings(ctx, endpoints.UpdateProjectSettingsRequest{ID: projectID.String(), Settings: settings})
+	if err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+	wantRedacted(t, response.(endpoints.UpdateProjectSettingsResponse).Project, aSchemaProject(projectID))
+
+	if _, err := endpoint.UpdateProjectSettings(ctx, endpoints.UpdateProjectSettingsRequest{ID: "nope"}); err == nil {
+		t.Fatal("UpdateProjectSettings accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdateProjectSettings(ctx, r) })
+}
+
+func TestSetOAuthProvider(t *testing.T) {
+	projectID := uuid.New()
+	config := schemas.OAuthProviderSettings{ClientID: "client", ClientSecret: "secret"}
+	manager := &mocks.ProjectManager{
+		SetOAuthProviderFunc: func(_ context.Context, id uuid.UUID, provider string, got schemas.OAuthProviderSettings) (*schemas.Project, error) {
+			if provider != "google" || got.ClientSecret != "secret" {
+				t.Errorf("SetOAuthProvider(%q, %+v)", provider, got)
+			}
+			return aSchemaProject(id), nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.SetOAuthProvider(ctx, endpoints.SetOAuthProviderRequest{ID: projectID.String(), Provider: "google", Config: config})
+	if err != nil {
+		t.Fatalf("SetOAuthProvider: %v", err)
+	}
+	wantRedacted(t, response.(endpoints.SetOAuthProviderResponse).Project, aSchemaProject(projectID))
+
+	if _, err := endpoint.SetOAuthProvider(ctx, endpoints.SetOAuthProviderRequest{ID: "nope", Provider: "google"}); err == nil {
+		t.Fatal("SetOAuthProvider accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.SetOAuthProvider(ctx, r) })
+}
+
+func TestDeleteOAuthProvider(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ProjectManager{
+		DeleteOAuthProviderFunc: func(_ context.Context, id uuid.UUID, provider string) (*schemas.Project, error) {
+			if provider != "github" {
+				t.Errorf("provider = %q, want github", provider)
+			}
+			return aSchemaProject(id), nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteOAuthProvider(ctx, endpoints.DeleteOAuthProviderRequest{ID: projectID.String(), Provider: "github"})
+	if err != nil {
+		t.Fatalf("DeleteOAuthProvider: %v", err)
+	}
+	wantRedacted(t, response.(endpoints.DeleteOAuthProviderResponse).Project, aSchemaProject(projectID))
+
+	if _, err := endpoint.DeleteOAuthProvider(ctx, endpoints.DeleteOAuthProviderRequest{ID: "nope"}); err == nil {
+		t.Fatal("DeleteOAuthProvider accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteOAuthProvider(ctx, r) })
+}
+
+func TestValidateUniqueID(t *testing.T) {
+	manager := &mocks.ProjectManager{
+		ValidateUniqueIDFunc: func(_ context.Context, raw string) (*projects.UniqueIDCheck, error) {
+			if raw != " Shop " {
+				t.Errorf("unique ID = %q", raw)
+			}
+			return &projects.UniqueIDCheck{UniqueID: "shop", Valid: true, Suggestions: []string{"shop_2", "shop_3"}}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ValidateUniqueID(ctx, endpoints.ValidateUniqueIDRequest{UniqueID: " Shop "})
+	if err != nil {
+		t.Fatalf("ValidateUniqueID: %v", err)
+	}
+	check := response.(endpoints.ValidateUniqueIDResponse)
+	if check.Available || !check.Valid || check.UniqueID != "shop" || !reflect.DeepEqual(check.Suggestions, []string{"shop_2", "shop_3"}) {
+		t.Fatalf("check = %+v", check)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ValidateUniqueID(ctx, r) })
+}
+
+func TestCheckUniqueID(t *testing.T) {
+	manager := &mocks.ProjectManager{
+		ValidateUniqueIDFunc: func(_ context.Context, raw string) (*projects.UniqueIDCheck, error) {
+			if raw != "Acme-Prod" {
+				t.Errorf("unique ID = %q", raw)
+			}
+			return &projects.UniqueIDCheck{UniqueID: "", Error: "unique_id may only contain letters, digits and underscores", Suggestions: []string{"acme_prod"}}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.CheckUniqueID(ctx, endpoints.CheckUniqueIDRequest{Value: "Acme-Prod"})
+	if err != nil {
+		t.Fatalf("CheckUniqueID: %v", err)
+	}
+	check := response.(endpoints.CheckUniqueIDResponse)
+	if check.Available || check.Valid || check.Normalized != "" || check.Error == "" || !reflect.DeepEqual(check.Suggestions, []string{"acme_prod"}) {
+		t.Fatalf("check = %+v", check)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CheckUniqueID(ctx, r) })
+}
+
+func TestCloneProject(t *testing.T) {
+	sourceID, cloneID := uuid.New(), uuid.New()
+	fromRole, toRole, fromPolicy, toPolicy := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.ProjectManager{
+		CloneProjectFunc: func(_ context.Context, id uuid.UUID, name, uniqueID string) (*projects.CloneResult, error) {
+			if id != sourceID || name != "Shop" || uniqueID != "shop" {
+				t.Errorf("CloneProject(%v, %q, %q)", id, name, uniqueID)
+			}
+			return &projects.CloneResult{
+				Project:   aSchemaProject(cloneID),
+				RoleIDs:   map[uuid.UUID]uuid.UUID{fromRole: toRole},
+				PolicyIDs: map[uuid.UUID]uuid.UUID{fromPolicy: toPolicy},
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.CloneProject(ctx, endpoints.CloneProjectRequest{ID: sourceID.String(), Name: "Shop", UniqueID: "shop"})
+	if err != nil {
+		t.Fatalf("CloneProject: %v", err)
+	}
+	clone := response.(endpoints.CloneProjectResponse)
+	wantRedacted(t, clone.Project, aSchemaProject(cloneID))
+	if clone.RoleIDMap[fromRole.String()] != toRole.String() || clone.PolicyIDMap[fromPolicy.String()] != toPolicy.String() {
+		t.Fatalf("role map %v, policy map %v", clone.RoleIDMap, clone.PolicyIDMap)
+	}
+
+	if _, err := endpoint.CloneProject(ctx, endpoints.CloneProjectRequest{ID: "nope"}); err == nil {
+		t.Fatal("CloneProject accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CloneProject(ctx, r) })
+}
+
+func TestBootstrapProject(t *testing.T) {
+	projectID, roleID, policyID, hookID, adminID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.ProjectManager{
+		BootstrapProjectFunc: func(_ context.Context, b projects.Bootstrap) (*projects.BootstrapResult, error) {
+			if b.Name != "Shop" || b.UniqueID != "shop" || len(b.Roles) != 1 || b.Roles[0].Expiration != 12*time.Hour || !b.Roles[0].Default || len(b.Roles[0].Policies) != 1 {
+				t.Errorf("bootstrap = %+v", b)
+			}
+			if len(b.Webhooks) != 1 || b.Webhooks[0].URL != "https://hooks.example.com" || b.Webhooks[0].Format != "slack" {
+				t.Errorf("webhooks = %+v", b.Webhooks)
+			}
+			if b.Admin == nil || b.Admin.Email != "owner@example.com" || b.Admin.Role != "admin" {
+				t.Errorf("admin = %+v", b.Admin)
+			}
+			return &projects.BootstrapResult{
+				Project:   aSchemaProject(projectID),
+				RoleIDs:   map[string]uuid.UUID{"admin": roleID},
+				PolicyIDs: map[string]uuid.UUID{"manage": policyID},
+				Webhooks:  []schemas.WebhookSubscription{{ID: hookID, ProjectId: projectID, URL: "https://hooks.example.com", Secret: "whsec", Format: "slack"}},
+				AdminID:   &adminID,
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewProjectsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	request := endpoints.BootstrapProjectRequest{
+		Name:     "Shop",
+		UniqueID: "shop",
+		Roles: []endpoints.BootstrapRole{{
+			Name: "admin", Expiration: 12, Default: true,
+			Policies: []projects.PolicyTemplate{{Name: "manage", Resource: "users", Action: "*", Effect: "allow"}},
+		}},
+		Webhooks: []endpoints.BootstrapWebhook{{URL: "https://hooks.example.com", Format: "slack"}},
+		Admin:    &endpoints.BootstrapAdmin{Email: "owner@example.com", Role: "admin"},
+	}
+	response, err := endpoint.BootstrapProject(ctx, request)
+	if err != nil {
+		t.Fatalf("BootstrapProject: %v", err)
+	}
+	result := response.(endpoints.BootstrapProjectResponse)
+	wantRedacted(t, result.Project, aSchemaProject(projectID))
+	if result.RoleIDs["admin"] != roleID.String() || result.PolicyIDs["manage"] != policyID.String() || result.AdminUserID != adminID.String() {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(result.Webhooks) != 1 || result.Webhooks[0].ID != hookID.String() || result.Webhooks[0].Secret != "whsec" || result.Webhooks[0].Events == nil {
+		t.Fatalf("webhooks = %+v, want the subscription with its secret", result.Webhooks)
+	}
+
+	request.Roles = append(request.Roles, endpoints.BootstrapRole{Name: "broken", Expiration: -1})
+	_, err = endpoint.BootstrapProject(ctx, request)
+	wantCode(t, err, "INVALID_EXPIRATION")
+	var apiErr *apierrors.Error
+	if errors.As(err, &apiErr) && apiErr.Field != "roles[1].expiration" {
+		t.Fatalf("field = %q, want roles[1].expiration", apiErr.Field)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.BootstrapProject(ctx, r) })
+}
+
+func TestCreateProjectUserTable(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	projectID := uuid.NewString()
+
+	if err := endpoints.CreateProjectUserTable(db, projectID); err != nil {
+		t.Fatalf("CreateProjectUserTable: %v", err)
+	}
+	table, _ := projecttable.UsersFor(projectID)
+	if !db.Migrator().HasTable(table) {
+		t.Fatalf("table %s was not created", table)
+	}
+	if err := endpoints.CreateProjectUserTable(db, projectID); err == nil {
+		t.Fatal("CreateProjectUserTable created the same table twice")
+	}
+	if err := endpoints.CreateProjectUserTable(db, "nope"); err != projecttable.ErrInvalidProjectID {
+		t.Fatalf("err = %v, want %v", err, projecttable.ErrInvalidProjectID)
+	}
+}
@@ -0,0 +1,54 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/reports"
+)
+
+// SendReportRequest represents the send report now request
+type SendReportRequest struct {
+	ProjectID string `json:"-"` // From URL path
+}
+
+// SendReportResponse represents the send report now response
+type SendReportResponse struct {
+	Report reports.Run `json:"report"`
+}
+
+// ReportsEndpoint handles project digest report endpoints
+type ReportsEndpoint struct {
+	ReportManager reports.ReportManager
+}
+
+// NewReportsEndpoint creates a new reports endpoint
+func NewReportsEndpoint(manager reports.ReportManager) *ReportsEndpoint {
+	return &ReportsEndpoint{
+		ReportManager: manager,
+	}
+}
+
+// SendReport sends a project's digest to its report recipients at once
+func (e *ReportsEndpoint) SendReport(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(SendReportRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+
+	run, err := e.ReportManager.SendNow(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return SendReportResponse{
+		Report: *run,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/reports"
+)
+
+func TestSendReport(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.ReportManager{
+		SendNowFunc: func(_ context.Context, id uuid.UUID) (*reports.Run, error) {
+			if id != projectID {
+				t.Errorf("project = %v, want %v", id, projectID)
+			}
+			return &reports.Run{ID: "run", Trigger: "manual"}, nil
+		},
+	}
+	endpoint := endpoints.NewReportsEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.SendReport(ctx, endpoints.SendReportRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("SendReport: %v", err)
+	}
+	if run := response.(endpoints.SendReportResponse).Report; run.ID != "run" {
+		t.Fatalf("report = %+v", run)
+	}
+
+	_, err = endpoint.SendReport(ctx, endpoints.SendReportRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.SendReport(ctx, r) })
+}
@@ -0,0 +1,99 @@
+package endpoints
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Responses control how they are written by implementing any of:
+//
+//	StatusCode() int                the status, 200 OK otherwise
+//	Headers() http.Header           headers set before the status is written
+//	StreamTo(w io.Writer) error     the body, written instead of the JSON encoding
+//
+// The first two are go-kit's kithttp.StatusCoder and kithttp.Headerer.
+
+// NoContentResponse answers with 204 No Content. Endpoints with nothing to
+// return return it rather than nil, which is treated as a bug.
+type NoContentResponse struct{}
+
+// StatusCode implements kithttp.StatusCoder
+func (NoContentResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+// downloadStatus is the status of a download: a redirect to its signed URL
+// when there is one
+func downloadStatus(redirectURL string) int {
+	if redirectURL != "" {
+		return http.StatusFound
+	}
+	return http.StatusOK
+}
+
+// downloadHeaders are the headers of a file sent as an attachment, or of the
+// redirect to its signed URL
+func downloadHeaders(contentType, filename, redirectURL string) http.Header {
+	header := make(http.Header)
+	if redirectURL != "" {
+		header.Set("Location", redirectURL)
+		return header
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return header
+}
+
+// streamContent copies content to w and closes it. A redirect has no content.
+func streamContent(w io.Writer, content io.ReadCloser) error {
+	if content == nil {
+		return nil
+	}
+	defer content.Close()
+	_, err := io.Copy(w, content)
+	return err
+}
+
+// StatusCode implements kithttp.StatusCoder
+func (r DownloadArtifactResponse) StatusCode() int {
+	return downloadStatus(r.RedirectURL)
+}
+
+// Headers implements kithttp.Headerer
+func (r DownloadArtifactResponse) Headers() http.Header {
+	return downloadHeaders("application/octet-stream", r.Filename, r.RedirectURL)
+}
+
+// StreamTo writes the artifact
+func (r DownloadArtifactResponse) StreamTo(w io.Writer) error {
+	return streamContent(w, r.Content)
+}
+
+// StatusCode implements kithttp.StatusCoder
+func (r GetImportErrorReportResponse) StatusCode() int {
+	return downloadStatus(r.RedirectURL)
+}
+
+// Headers implements kithttp.Headerer
+func (r GetImportErrorReportResponse) Headers() http.Header {
+	return downloadHeaders("text/csv; charset=utf-8", r.Filename, r.RedirectURL)
+}
+
+// StreamTo writes the error report
+func (r GetImportErrorReportResponse) StreamTo(w io.Writer) error {
+	return streamContent(w, r.Content)
+}
+
+// Headers implements kithttp.Headerer. X-Deleted-Users counts the users in
+// the export.
+func (r DeleteProjectDownload) Headers() http.Header {
+	header := downloadHeaders("text/csv; charset=utf-8", r.Filename, "")
+	header.Set("X-Deleted-Users", strconv.FormatInt(r.Removed.Users, 10))
+	return header
+}
+
+// StreamTo writes the users export
+func (r DeleteProjectDownload) StreamTo(w io.Writer) error {
+	return streamContent(w, r.Content)
+}
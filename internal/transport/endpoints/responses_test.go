@@ -0,0 +1,75 @@
+package endpoints
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// closer records whether it was closed
+type closer struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestNoContentResponse(t *testing.T) {
+	if code := (NoContentResponse{}).StatusCode(); code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", code)
+	}
+}
+
+func TestDownloadResponses(t *testing.T) {
+	redirect := DownloadArtifactResponse{RedirectURL: "https://blobs.example.com/a"}
+	if code := redirect.StatusCode(); code != http.StatusFound {
+		t.Fatalf("redirect status = %d, want 302", code)
+	}
+	if location := redirect.Headers().Get("Location"); location != redirect.RedirectURL {
+		t.Fatalf("Location = %q", location)
+	}
+	var out strings.Builder
+	if err := redirect.StreamTo(&out); err != nil || out.Len() != 0 {
+		t.Fatalf("a redirect streamed %q, %v", out.String(), err)
+	}
+
+	content := &closer{Reader: strings.NewReader("errors")}
+	report := GetImportErrorReportResponse{Filename: "errors.csv", Content: content}
+	if code := report.StatusCode(); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	header := report.Headers()
+	if header.Get("Content-Type") != "text/csv; charset=utf-8" || header.Get("Content-Disposition") != `attachment; filename="errors.csv"` {
+		t.Fatalf("headers = %v", header)
+	}
+	if err := report.StreamTo(&out); err != nil || out.String() != "errors" || !content.closed {
+		t.Fatalf("streamed %q, %v, closed %v", out.String(), err, content.closed)
+	}
+
+	artifact := DownloadArtifactResponse{Filename: "a.bin"}
+	if contentType := artifact.Headers().Get("Content-Type"); contentType != "application/octet-stream" {
+		t.Fatalf("Content-Type = %q", contentType)
+	}
+}
+
+func TestDeleteProjectDownload(t *testing.T) {
+	download := DeleteProjectDownload{
+		Filename: "shop.csv",
+		Content:  io.NopCloser(strings.NewReader("id\n")),
+		Removed:  projects.DeletePreview{Users: 3},
+	}
+	header := download.Headers()
+	if header.Get("X-Deleted-Users") != "3" || header.Get("Content-Disposition") != `attachment; filename="shop.csv"` {
+		t.Fatalf("headers = %v", header)
+	}
+	var out strings.Builder
+	if err := download.StreamTo(&out); err != nil || out.String() != "id\n" {
+		t.Fatalf("streamed %q, %v", out.String(), err)
+	}
+}
@@ -3,25 +3,36 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"github.com/yash3004/user_management_service/roles"
 )
 
 type Role struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	Expiration  time.Duration `json:"expiration"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	Expiration   time.Duration `json:"expiration"`
+	AllowedCIDRs []string      `json:"allowed_cidrs"` // Networks the role may be used from; empty for anywhere
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	ProjectID    string        `json:"project_id,omitempty"`
+	CreatedBy    string        `json:"created_by"`           // User ID of the creator, or "system"
+	DeletedAt    *time.Time    `json:"deleted_at,omitempty"` // Only set on deleted roles
+	DeletedBy    string        `json:"deleted_by,omitempty"` // User ID of the deleter, or "system"
 }
 
 type CreateRoleRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Expiration  int    `json:"expiration"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Expiration   int      `json:"expiration"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"` // Empty to allow every network
+	ProjectID    string   `json:"project_id,omitempty"`    // Empty for a global role
 }
 
 type CreateRoleResponse struct {
@@ -29,28 +40,51 @@ type CreateRoleResponse struct {
 }
 
 type GetRoleRequest struct {
-	ID string `json:"id"`
+	ID             string `json:"id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 type GetRoleResponse struct {
 	Role Role `json:"role"`
 }
 
+// GetRoleByNameRequest represents the get role by name request
+type GetRoleByNameRequest struct {
+	Name      string `json:"name"`
+	ProjectID string `json:"project_id"` // Empty to look among the global roles
+}
+
+type ListRolesRequest struct {
+	Sort sorting.Order `json:"-"` // From the sort query parameter
+}
+
 type ListRolesResponse struct {
 	Roles []Role `json:"roles"`
 }
 
 type UpdateRoleRequest struct {
-	ID          string `json:"-"` // From URL path
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Expiration  int    `json:"expiration"`
+	ID           string   `json:"-"` // From URL path
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Expiration   int      `json:"expiration"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"` // Replaces the role's networks; empty to allow every network
 }
 
 type UpdateRoleResponse struct {
 	Role Role `json:"role"`
 }
 
+// RenameRoleRequest represents the rename role request
+type RenameRoleRequest struct {
+	ID   string `json:"-"` // From URL path
+	Name string `json:"name"`
+}
+
+// RenameRoleResponse represents the rename role response
+type RenameRoleResponse struct {
+	Role Role `json:"role"`
+}
+
 type DeleteRoleRequest struct {
 	ID string `json:"id"`
 }
@@ -59,6 +93,26 @@ type DeleteRoleResponse struct {
 	Success bool `json:"success"`
 }
 
+// GetRoleUsageRequest represents the request for the role usage of a project
+type GetRoleUsageRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+// RoleUsage represents how many of a project's users hold a role
+type RoleUsage struct {
+	RoleID    string `json:"role_id"`
+	Name      string `json:"name"`                 // Empty when the role no longer exists
+	ProjectID string `json:"project_id,omitempty"` // Empty for a global role
+	Deleted   bool   `json:"deleted,omitempty"`
+	Users     int64  `json:"users"`
+}
+
+// GetRoleUsageResponse represents the role usage of a project
+type GetRoleUsageResponse struct {
+	Roles []RoleUsage `json:"roles"`
+	Total int64       `json:"total"` // Users counted across the roles
+}
+
 type RolesEndpoint struct {
 	RoleManager roles.RoleManager
 }
@@ -75,19 +129,31 @@ func (e *RolesEndpoint) CreateRole(ctx context.Context, request interface{}) (in
 		return nil, errors.New("invalid request format")
 	}
 
-	role, err := e.RoleManager.CreateRole(ctx, req.Name, req.Description, addHours(req.Expiration))
+	projectID, err := parseOptionalUUID(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	expiration, err := addHours(req.Expiration)
+	if err != nil {
+		return nil, err
+	}
+	role, err := e.RoleManager.CreateRole(ctx, req.Name, req.Description, expiration, req.AllowedCIDRs, projectID)
 	if err != nil {
 		return nil, err
 	}
 
 	return CreateRoleResponse{
 		Role: Role{
-			ID:          role.ID.String(),
-			Name:        role.Name,
-			Description: role.Description,
-			Expiration:  role.Expiration,
-			CreatedAt:   role.CreatedAt,
-			UpdatedAt:   role.UpdatedAt,
+			ID:           role.ID.String(),
+			Name:         role.Name,
+			Description:  role.Description,
+			AllowedCIDRs: nonNil(role.AllowedCIDRs),
+			Expiration:   role.Expiration,
+			CreatedAt:    role.CreatedAt,
+			UpdatedAt:    role.UpdatedAt,
+			ProjectID:    optionalUUIDString(role.ProjectId),
+			CreatedBy:    creatorString(role.CreatedBy),
 		},
 	}, nil
 }
@@ -103,24 +169,70 @@ func (e *RolesEndpoint) GetRole(ctx context.Context, request interface{}) (inter
 		return nil, errors.New("invalid role ID format")
 	}
 
-	role, err := e.RoleManager.GetRole(ctx, roleID)
+	get := e.RoleManager.GetRole
+	if req.IncludeDeleted {
+		get = e.RoleManager.GetRoleIncludingDeleted
+	}
+	role, err := get(ctx, roleID)
 	if err != nil {
 		return nil, err
 	}
 
+	deletedAt, deletedBy := deletion(role.DeletedAt, role.DeletedBy)
 	return GetRoleResponse{
 		Role: Role{
-			ID:          role.ID.String(),
-			Name:        role.Name,
-			Description: role.Description,
-			CreatedAt:   role.CreatedAt,
-			UpdatedAt:   role.UpdatedAt,
+			ID:           role.ID.String(),
+			Name:         role.Name,
+			Description:  role.Description,
+			AllowedCIDRs: nonNil(role.AllowedCIDRs),
+			CreatedAt:    role.CreatedAt,
+			UpdatedAt:    role.UpdatedAt,
+			ProjectID:    optionalUUIDString(role.ProjectId),
+			CreatedBy:    creatorString(role.CreatedBy),
+			DeletedAt:    deletedAt,
+			DeletedBy:    deletedBy,
+		},
+	}, nil
+}
+
+// GetRoleByName gets a role by its exact name
+func (e *RolesEndpoint) GetRoleByName(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetRoleByNameRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := parseOptionalUUID(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	role, err := e.RoleManager.GetRoleByName(ctx, req.Name, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetRoleResponse{
+		Role: Role{
+			ID:           role.ID.String(),
+			Name:         role.Name,
+			Description:  role.Description,
+			AllowedCIDRs: nonNil(role.AllowedCIDRs),
+			CreatedAt:    role.CreatedAt,
+			UpdatedAt:    role.UpdatedAt,
+			ProjectID:    optionalUUIDString(role.ProjectId),
+			CreatedBy:    creatorString(role.CreatedBy),
 		},
 	}, nil
 }
 
 func (e *RolesEndpoint) ListRoles(ctx context.Context, request interface{}) (interface{}, error) {
-	rolesList, err := e.RoleManager.ListRoles(ctx)
+	req, ok := request.(ListRolesRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	rolesList, err := e.RoleManager.ListRoles(ctx, req.Sort)
 	if err != nil {
 		return nil, err
 	}
@@ -128,11 +240,14 @@ func (e *RolesEndpoint) ListRoles(ctx context.Context, request interface{}) (int
 	roles := make([]Role, len(rolesList))
 	for i, r := range rolesList {
 		roles[i] = Role{
-			ID:          r.ID.String(),
-			Name:        r.Name,
-			Description: r.Description,
-			CreatedAt:   r.CreatedAt,
-			UpdatedAt:   r.UpdatedAt,
+			ID:           r.ID.String(),
+			Name:         r.Name,
+			Description:  r.Description,
+			AllowedCIDRs: nonNil(r.AllowedCIDRs),
+			CreatedAt:    r.CreatedAt,
+			UpdatedAt:    r.UpdatedAt,
+			ProjectID:    optionalUUIDString(r.ProjectId),
+			CreatedBy:    creatorString(r.CreatedBy),
 		}
 	}
 
@@ -152,18 +267,57 @@ func (e *RolesEndpoint) UpdateRole(ctx context.Context, request interface{}) (in
 		return nil, errors.New("invalid role ID format")
 	}
 
-	role, err := e.RoleManager.UpdateRole(ctx, roleID, req.Name, req.Description, addHours(req.Expiration))
+	expiration, err := addHours(req.Expiration)
+	if err != nil {
+		return nil, err
+	}
+	role, err := e.RoleManager.UpdateRole(ctx, roleID, req.Name, req.Description, expiration, req.AllowedCIDRs)
 	if err != nil {
 		return nil, err
 	}
 
 	return UpdateRoleResponse{
 		Role: Role{
-			ID:          role.ID.String(),
-			Name:        role.Name,
-			Description: role.Description,
-			CreatedAt:   role.CreatedAt,
-			UpdatedAt:   role.UpdatedAt,
+			ID:           role.ID.String(),
+			Name:         role.Name,
+			Description:  role.Description,
+			AllowedCIDRs: nonNil(role.AllowedCIDRs),
+			CreatedAt:    role.CreatedAt,
+			UpdatedAt:    role.UpdatedAt,
+			ProjectID:    optionalUUIDString(role.ProjectId),
+			CreatedBy:    creatorString(role.CreatedBy),
+		},
+	}, nil
+}
+
+// RenameRole changes only the name of a role
+func (e *RolesEndpoint) RenameRole(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RenameRoleRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	roleID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid role ID format")
+	}
+
+	role, err := e.RoleManager.RenameRole(ctx, roleID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return RenameRoleResponse{
+		Role: Role{
+			ID:           role.ID.String(),
+			Name:         role.Name,
+			Description:  role.Description,
+			AllowedCIDRs: nonNil(role.AllowedCIDRs),
+			Expiration:   role.Expiration,
+			CreatedAt:    role.CreatedAt,
+			UpdatedAt:    role.UpdatedAt,
+			ProjectID:    optionalUUIDString(role.ProjectId),
+			CreatedBy:    creatorString(role.CreatedBy),
 		},
 	}, nil
 }
@@ -189,6 +343,47 @@ func (e *RolesEndpoint) DeleteRole(ctx context.Context, request interface{}) (in
 	}, nil
 }
 
-func addHours(hours int) time.Duration {
-	return time.Duration(hours) * time.Hour
+// maxExpirationHours is the most hours a time.Duration holds
+const maxExpirationHours = int64(math.MaxInt64 / time.Hour)
+
+// addHours converts a role expiration given in hours, rejecting negative
+// ones and ones too long to represent, which would wrap around
+func addHours(hours int) (time.Duration, error) {
+	if hours < 0 {
+		return 0, roles.ErrNegativeExpiration
+	}
+	if int64(hours) > maxExpirationHours {
+		return 0, apierrors.BadRequest("INVALID_EXPIRATION", "role expiration is too long")
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// GetRoleUsage counts a project's users by role
+func (e *RolesEndpoint) GetRoleUsage(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(GetRoleUsageRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+
+	usage, err := e.RoleManager.Usage(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := GetRoleUsageResponse{Roles: make([]RoleUsage, len(usage))}
+	for i, u := range usage {
+		response.Roles[i] = RoleUsage{RoleID: u.RoleID.String(), Users: u.Users}
+		if u.Role != nil {
+			response.Roles[i].Name = u.Role.Name
+			response.Roles[i].ProjectID = optionalUUIDString(u.Role.ProjectId)
+			response.Roles[i].Deleted = u.Role.DeletedAt.Valid
+		}
+		response.Total += u.Users
+	}
+	return response, nil
 }
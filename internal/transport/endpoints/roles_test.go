@@ -0,0 +1,284 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+func TestCreateRole(t *testing.T) {
+	projectID, creator := uuid.New(), uuid.New()
+	manager := &mocks.RoleManager{
+		CreateRoleFunc: func(_ context.Context, name, description string, expiration time.Duration, cidrs []string, pid *uuid.UUID) (*schemas.Role, error) {
+			if name != "editor" || expiration != 2*time.Hour || len(cidrs) != 1 || pid == nil || *pid != projectID {
+				t.Errorf("CreateRole(%q, %v, %v, %v)", name, expiration, cidrs, pid)
+			}
+			return &schemas.Role{ID: uuid.New(), Name: name, Description: description, Expiration: expiration, AllowedCIDRs: cidrs, ProjectId: pid, CreatedBy: &creator}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.CreateRole(ctx, endpoints.CreateRoleRequest{
+		Name:         "editor",
+		Description:  "Edits",
+		Expiration:   2,
+		AllowedCIDRs: []string{"10.0.0.0/8"},
+		ProjectID:    projectID.String(),
+	})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	role := response.(endpoints.CreateRoleResponse).Role
+	if role.Name != "editor" || role.Expiration != 2*time.Hour || role.ProjectID != projectID.String() || role.CreatedBy != creator.String() {
+		t.Fatalf("role = %+v", role)
+	}
+
+	_, err = endpoint.CreateRole(ctx, endpoints.CreateRoleRequest{Name: "editor", Expiration: -1})
+	wantCode(t, err, "INVALID_EXPIRATION")
+	_, err = endpoint.CreateRole(ctx, endpoints.CreateRoleRequest{Name: "editor", Expiration: math.MaxInt})
+	wantCode(t, err, "INVALID_EXPIRATION")
+	if _, err := endpoint.CreateRole(ctx, endpoints.CreateRoleRequest{Name: "editor", ProjectID: "nope"}); err == nil {
+		t.Fatal("CreateRole accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateRole(ctx, r) })
+}
+
+func TestGetRole(t *testing.T) {
+	roleID, deleter := uuid.New(), uuid.New()
+	deletedAt := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.RoleManager{
+		GetRoleFunc: func(context.Context, uuid.UUID) (*schemas.Role, error) { return nil, roles.ErrRoleNotFound },
+		GetRoleIncludingDeletedFunc: func(_ context.Context, id uuid.UUID) (*schemas.Role, error) {
+			return &schemas.Role{ID: id, Name: "old", DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}, DeletedBy: &deleter}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	if _, err := endpoint.GetRole(ctx, endpoints.GetRoleRequest{ID: roleID.String()}); err != roles.ErrRoleNotFound {
+		t.Fatalf("err = %v, want %v for a deleted role", err, roles.ErrRoleNotFound)
+	}
+	response, err := endpoint.GetRole(ctx, endpoints.GetRoleRequest{ID: roleID.String(), IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetRole: %v", err)
+	}
+	role := response.(endpoints.GetRoleResponse).Role
+	if role.ID != roleID.String() || role.DeletedAt == nil || !role.DeletedAt.Equal(deletedAt) || role.DeletedBy != deleter.String() || role.CreatedBy != "system" {
+		t.Fatalf("role = %+v", role)
+	}
+	if role.AllowedCIDRs == nil || role.ProjectID != "" {
+		t.Fatalf("role = %+v, want no networks as an empty list and no project", role)
+	}
+
+	if _, err := endpoint.GetRole(ctx, endpoints.GetRoleRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetRole accepted a malformed role ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetRole(ctx, r) })
+}
+
+func TestGetRoleByName(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.RoleManager{
+		GetRoleByNameFunc: func(_ context.Context, name string, pid *uuid.UUID) (*schemas.Role, error) {
+			if pid == nil {
+				return &schemas.Role{ID: uuid.New(), Name: name}, nil
+			}
+			return &schemas.Role{ID: uuid.New(), Name: name, ProjectId: pid}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetRoleByName(ctx, endpoints.GetRoleByNameRequest{Name: "admin"})
+	if err != nil {
+		t.Fatalf("GetRoleByName: %v", err)
+	}
+	if role := response.(endpoints.GetRoleResponse).Role; role.Name != "admin" || role.ProjectID != "" {
+		t.Fatalf("global role = %+v", role)
+	}
+	response, err = endpoint.GetRoleByName(ctx, endpoints.GetRoleByNameRequest{Name: "editor", ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("GetRoleByName: %v", err)
+	}
+	if role := response.(endpoints.GetRoleResponse).Role; role.ProjectID != projectID.String() {
+		t.Fatalf("project role = %+v", role)
+	}
+
+	manager.GetRoleByNameFunc = func(context.Context, string, *uuid.UUID) (*schemas.Role, error) { return nil, roles.ErrRoleNotFound }
+	if _, err := endpoint.GetRoleByName(ctx, endpoints.GetRoleByNameRequest{Name: "missing"}); err != roles.ErrRoleNotFound {
+		t.Fatalf("err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+	if _, err := endpoint.GetRoleByName(ctx, endpoints.GetRoleByNameRequest{Name: "admin", ProjectID: "nope"}); err == nil {
+		t.Fatal("GetRoleByName accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetRoleByName(ctx, r) })
+}
+
+func TestListRoles(t *testing.T) {
+	order := sorting.Order{Field: "name", Desc: true}
+	manager := &mocks.RoleManager{
+		ListRolesFunc: func(_ context.Context, got sorting.Order) ([]schemas.Role, error) {
+			if got != order {
+				t.Errorf("order = %+v, want %+v", got, order)
+			}
+			return []schemas.Role{{ID: uuid.New(), Name: "viewer"}, {ID: uuid.New(), Name: "admin"}}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListRoles(ctx, endpoints.ListRolesRequest{Sort: order})
+	if err != nil {
+		t.Fatalf("ListRoles: %v", err)
+	}
+	if list := response.(endpoints.ListRolesResponse).Roles; len(list) != 2 || list[0].Name != "viewer" || list[1].Name != "admin" {
+		t.Fatalf("roles = %+v", list)
+	}
+
+	failure := errors.New("boom")
+	manager.ListRolesFunc = func(context.Context, sorting.Order) ([]schemas.Role, error) { return nil, failure }
+	if _, err := endpoint.ListRoles(ctx, endpoints.ListRolesRequest{Sort: order}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListRoles(ctx, r) })
+}
+
+func TestUpdateRole(t *testing.T) {
+	roleID := uuid.New()
+	manager := &mocks.RoleManager{
+		UpdateRoleFunc: func(_ context.Context, id uuid.UUID, name, description string, expiration time.Duration, cidrs []string) (*schemas.Role, error) {
+			if id != roleID || name != "editor" || description != "Edits more" || expiration != 3*time.Hour || cidrs != nil {
+				t.Errorf("UpdateRole(%v, %q, %q, %v, %v)", id, name, description, expiration, cidrs)
+			}
+			return &schemas.Role{ID: id, Name: name, Description: description, Expiration: expiration}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdateRole(ctx, endpoints.UpdateRoleRequest{ID: roleID.String(), Name: "editor", Description: "Edits more", Expiration: 3})
+	if err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if role := response.(endpoints.UpdateRoleResponse).Role; role.ID != roleID.String() || role.Description != "Edits more" || role.AllowedCIDRs == nil {
+		t.Fatalf("role = %+v", role)
+	}
+
+	_, err = endpoint.UpdateRole(ctx, endpoints.UpdateRoleRequest{ID: roleID.String(), Expiration: -1})
+	wantCode(t, err, "INVALID_EXPIRATION")
+	if _, err := endpoint.UpdateRole(ctx, endpoints.UpdateRoleRequest{ID: "nope"}); err == nil {
+		t.Fatal("UpdateRole accepted a malformed role ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdateRole(ctx, r) })
+}
+
+func TestRenameRole(t *testing.T) {
+	roleID := uuid.New()
+	manager := &mocks.RoleManager{
+		RenameRoleFunc: func(_ context.Context, id uuid.UUID, name string) (*schemas.Role, error) {
+			return &schemas.Role{ID: id, Name: name, Expiration: time.Hour}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.RenameRole(ctx, endpoints.RenameRoleRequest{ID: roleID.String(), Name: "writer"})
+	if err != nil {
+		t.Fatalf("RenameRole: %v", err)
+	}
+	if role := response.(endpoints.RenameRoleResponse).Role; role.Name != "writer" || role.Expiration != time.Hour {
+		t.Fatalf("role = %+v", role)
+	}
+
+	manager.RenameRoleFunc = func(context.Context, uuid.UUID, string) (*schemas.Role, error) { return nil, roles.ErrRoleNotFound }
+	if _, err := endpoint.RenameRole(ctx, endpoints.RenameRoleRequest{ID: roleID.String(), Name: "writer"}); err != roles.ErrRoleNotFound {
+		t.Fatalf("err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+	if _, err := endpoint.RenameRole(ctx, endpoints.RenameRoleRequest{ID: "nope"}); err == nil {
+		t.Fatal("RenameRole accepted a malformed role ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RenameRole(ctx, r) })
+}
+
+func TestDeleteRole(t *testing.T) {
+	roleID := uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.RoleManager{
+		DeleteRoleFunc: func(_ context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteRole(ctx, endpoints.DeleteRoleRequest{ID: roleID.String()})
+	if err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if !response.(endpoints.DeleteRoleResponse).Success || deleted != roleID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	manager.DeleteRoleFunc = func(context.Context, uuid.UUID) error { return roles.ErrRoleNotFound }
+	if _, err := endpoint.DeleteRole(ctx, endpoints.DeleteRoleRequest{ID: roleID.String()}); err != roles.ErrRoleNotFound {
+		t.Fatalf("err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+	if _, err := endpoint.DeleteRole(ctx, endpoints.DeleteRoleRequest{ID: "nope"}); err == nil {
+		t.Fatal("DeleteRole accepted a malformed role ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteRole(ctx, r) })
+}
+
+func TestGetRoleUsage(t *testing.T) {
+	projectID := uuid.New()
+	live, deleted, gone := uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.RoleManager{
+		UsageFunc: func(_ context.Context, pid uuid.UUID) ([]roles.RoleUsage, error) {
+			if pid != projectID {
+				t.Errorf("project = %v, want %v", pid, projectID)
+			}
+			return []roles.RoleUsage{
+				{RoleID: live, Role: &schemas.Role{ID: live, Name: "member", ProjectId: &projectID}, Users: 5},
+				{RoleID: deleted, Role: &schemas.Role{ID: deleted, Name: "old", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}, Users: 2},
+				{RoleID: gone, Users: 1},
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewRolesEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.GetRoleUsage(ctx, endpoints.GetRoleUsageRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("GetRoleUsage: %v", err)
+	}
+	usage := response.(endpoints.GetRoleUsageResponse)
+	want := []endpoints.RoleUsage{
+		{RoleID: live.String(), Name: "member", ProjectID: projectID.String(), Users: 5},
+		{RoleID: deleted.String(), Name: "old", Deleted: true, Users: 2},
+		{RoleID: gone.String(), Users: 1},
+	}
+	if usage.Total != 8 || len(usage.Roles) != len(want) {
+		t.Fatalf("usage = %+v", usage)
+	}
+	for i := range want {
+		if usage.Roles[i] != want[i] {
+			t.Errorf("usage %d = %+v, want %+v", i, usage.Roles[i], want[i])
+		}
+	}
+
+	_, err = endpoint.GetRoleUsage(ctx, endpoints.GetRoleUsageRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetRoleUsage(ctx, r) })
+}
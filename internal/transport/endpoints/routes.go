@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+)
+
+// Values of RouteInfo.Auth
+const (
+	AuthPublic = "public" // Anyone may call the route
+	AuthBearer = "bearer" // The route needs a user's bearer token
+)
+
+// RouteInfo describes one route of the API. Role, Resource and Action are
+// set when the route requires them of the caller's role.
+type RouteInfo struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Auth     string `json:"auth"`
+	Role     string `json:"role,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Action   string `json:"action,omitempty"`
+	// Self is the path variable naming the project user who may also call
+	// the route about themselves
+	Self string `json:"self,omitempty"`
+	// IncludeDeleted is the role or resource:action also required to see
+	// soft-deleted records with ?include_deleted=true
+	IncludeDeleted string `json:"include_deleted,omitempty"`
+	// Deprecated routes still work but name the Successor to use instead
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Successor  string `json:"successor,omitempty"`
+}
+
+// ListRoutesResponse represents the response listing the API's routes
+type ListRoutesResponse struct {
+	Routes []RouteInfo `json:"routes"`
+}
+
+// RoutesEndpoint reports the routes the server serves
+type RoutesEndpoint struct {
+	// List returns the routes; it is set once the router is assembled
+	List func() ([]RouteInfo, error)
+}
+
+// NewRoutesEndpoint creates a new routes endpoint
+func NewRoutesEndpoint() *RoutesEndpoint {
+	return &RoutesEndpoint{}
+}
+
+// ListRoutes returns every route with its method, path and the
+// authorization it requires
+func (e *RoutesEndpoint) ListRoutes(_ context.Context, _ interface{}) (interface{}, error) {
+	if e.List == nil {
+		return nil, errors.New("routes are not available")
+	}
+	routes, err := e.List()
+	if err != nil {
+		return nil, err
+	}
+	return ListRoutesResponse{Routes: nonNil(routes)}, nil
+}
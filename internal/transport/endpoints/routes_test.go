@@ -0,0 +1,42 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+func TestListRoutes(t *testing.T) {
+	endpoint := endpoints.NewRoutesEndpoint()
+	if _, err := endpoint.ListRoutes(context.Background(), nil); err == nil {
+		t.Fatal("ListRoutes succeeded before the router was assembled")
+	}
+
+	endpoint.List = func() ([]endpoints.RouteInfo, error) { return nil, nil }
+	response, err := endpoint.ListRoutes(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListRoutes: %v", err)
+	}
+	if routes := response.(endpoints.ListRoutesResponse).Routes; routes == nil {
+		t.Fatal("no routes are listed as null rather than an empty list")
+	}
+
+	endpoint.List = func() ([]endpoints.RouteInfo, error) {
+		return []endpoints.RouteInfo{{Method: "GET", Path: "/api/version", Auth: endpoints.AuthPublic}}, nil
+	}
+	response, err = endpoint.ListRoutes(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListRoutes: %v", err)
+	}
+	if routes := response.(endpoints.ListRoutesResponse).Routes; len(routes) != 1 || routes[0].Path != "/api/version" {
+		t.Fatalf("routes = %+v", routes)
+	}
+
+	failure := errors.New("no router")
+	endpoint.List = func() ([]endpoints.RouteInfo, error) { return nil, failure }
+	if _, err := endpoint.ListRoutes(context.Background(), nil); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+}
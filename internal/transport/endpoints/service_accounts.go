@@ -0,0 +1,143 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// CreateServiceAccountRequest represents the create service account request
+type CreateServiceAccountRequest struct {
+	ProjectID string `json:"-"` // From URL path
+	Name      string `json:"name"`
+	RoleID    string `json:"role_id"` // A role ID or name
+}
+
+// CreateServiceAccountResponse represents the create service account response
+type CreateServiceAccountResponse struct {
+	ServiceAccount models.DisplayUser `json:"service_account"`
+}
+
+// ListServiceAccountsRequest represents the list service accounts request
+type ListServiceAccountsRequest struct {
+	ProjectID string `json:"-"`
+}
+
+// ListServiceAccountsResponse represents the list service accounts response
+type ListServiceAccountsResponse struct {
+	ServiceAccounts []models.DisplayUser `json:"service_accounts"`
+}
+
+// DeleteServiceAccountRequest represents the delete service account request
+type DeleteServiceAccountRequest struct {
+	ProjectID string `json:"-"`
+	ID        string `json:"-"`
+}
+
+// DeleteServiceAccountResponse represents the delete service account response
+type DeleteServiceAccountResponse struct {
+	Success bool `json:"success"`
+}
+
+// ServiceAccountsEndpoint handles the service accounts of projects
+type ServiceAccountsEndpoint struct {
+	UserManager users.UserManager
+	RoleManager roles.RoleManager // resolves role_id given as a role name
+}
+
+// NewServiceAccountsEndpoint creates a new service accounts endpoint
+func NewServiceAccountsEndpoint(manager users.UserManager, roleManager roles.RoleManager) *ServiceAccountsEndpoint {
+	return &ServiceAccountsEndpoint{
+		UserManager: manager,
+		RoleManager: roleManager,
+	}
+}
+
+// CreateServiceAccount creates a service account in a project. It cannot
+// log in; it authenticates with API tokens issued to it.
+func (e *ServiceAccountsEndpoint) CreateServiceAccount(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CreateServiceAccountRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+	roleID, err := resolveRoleID(ctx, e.RoleManager, req.RoleID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := e.UserManager.CreateServiceAccount(ctx, projectID, req.Name, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateServiceAccountResponse{
+		ServiceAccount: displayUser(*account),
+	}, nil
+}
+
+// ListServiceAccounts lists the service accounts of a project
+func (e *ServiceAccountsEndpoint) ListServiceAccounts(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListServiceAccountsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+
+	accounts, err := e.UserManager.ListServiceAccounts(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.DisplayUser, len(accounts))
+	for i, account := range accounts {
+		list[i] = displayUser(account)
+	}
+
+	return ListServiceAccountsResponse{
+		ServiceAccounts: list,
+	}, nil
+}
+
+// DeleteServiceAccount deletes a service account of a project, revoking the
+// API tokens issued to it
+func (e *ServiceAccountsEndpoint) DeleteServiceAccount(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(DeleteServiceAccountRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, projecttable.ErrInvalidProjectID
+	}
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, users.ErrServiceAccountNotFound
+	}
+
+	// Only a service account of the project may be deleted here
+	if _, err := e.UserManager.GetServiceAccount(ctx, projectID, id); err != nil {
+		return nil, err
+	}
+	if err := e.UserManager.DeleteUser(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return DeleteServiceAccountResponse{
+		Success: true,
+	}, nil
+}
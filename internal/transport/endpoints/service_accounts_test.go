@@ -0,0 +1,114 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/users"
+)
+
+func TestCreateServiceAccount(t *testing.T) {
+	projectID, roleID := uuid.New(), uuid.New()
+	manager := &mocks.UserManager{
+		CreateServiceAccountFunc: func(_ context.Context, pid uuid.UUID, name string, rid uuid.UUID) (*schemas.User, error) {
+			if pid != projectID || name != "ci" || rid != roleID {
+				t.Errorf("CreateServiceAccount(%v, %q, %v)", pid, name, rid)
+			}
+			return &schemas.User{ID: uuid.New(), FirstName: name, Type: schemas.UserTypeService, Status: "active", RoleId: rid, ProjectId: pid}, nil
+		},
+	}
+	endpoint := endpoints.NewServiceAccountsEndpoint(manager, rolesNamed(map[string]uuid.UUID{"deployer": roleID}))
+	ctx := context.Background()
+
+	response, err := endpoint.CreateServiceAccount(ctx, endpoints.CreateServiceAccountRequest{ProjectID: projectID.String(), Name: "ci", RoleID: "deployer"})
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	account := response.(endpoints.CreateServiceAccountResponse).ServiceAccount
+	if account.Type != schemas.UserTypeService || account.RoleID != roleID.String() || account.ProjectID != projectID.String() {
+		t.Fatalf("service account = %+v", account)
+	}
+
+	_, err = endpoint.CreateServiceAccount(ctx, endpoints.CreateServiceAccountRequest{ProjectID: projectID.String(), Name: "ci", RoleID: "missing"})
+	wantCode(t, err, "INVALID_ROLE")
+	_, err = endpoint.CreateServiceAccount(ctx, endpoints.CreateServiceAccountRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateServiceAccount(ctx, r) })
+}
+
+func TestListServiceAccounts(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.UserManager{
+		ListServiceAccountsFunc: func(_ context.Context, pid uuid.UUID) ([]schemas.User, error) {
+			return []schemas.User{{ID: uuid.New(), FirstName: "ci", Type: schemas.UserTypeService, ProjectId: pid}}, nil
+		},
+	}
+	endpoint := endpoints.NewServiceAccountsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListServiceAccounts(ctx, endpoints.ListServiceAccountsRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("ListServiceAccounts: %v", err)
+	}
+	if accounts := response.(endpoints.ListServiceAccountsResponse).ServiceAccounts; len(accounts) != 1 || accounts[0].FirstName != "ci" {
+		t.Fatalf("service accounts = %+v", accounts)
+	}
+
+	manager.ListServiceAccountsFunc = func(context.Context, uuid.UUID) ([]schemas.User, error) { return nil, nil }
+	response, err = endpoint.ListServiceAccounts(ctx, endpoints.ListServiceAccountsRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("ListServiceAccounts: %v", err)
+	}
+	if accounts := response.(endpoints.ListServiceAccountsResponse).ServiceAccounts; accounts == nil {
+		t.Fatal("no service accounts are listed as null rather than an empty list")
+	}
+	_, err = endpoint.ListServiceAccounts(ctx, endpoints.ListServiceAccountsRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListServiceAccounts(ctx, r) })
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	projectID, accountID := uuid.New(), uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.UserManager{
+		GetServiceAccountFunc: func(_ context.Context, pid, id uuid.UUID) (*schemas.User, error) {
+			if pid != projectID || id != accountID {
+				return nil, users.ErrServiceAccountNotFound
+			}
+			return &schemas.User{ID: id}, nil
+		},
+		DeleteUserFunc: func(_ context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewServiceAccountsEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteServiceAccount(ctx, endpoints.DeleteServiceAccountRequest{ProjectID: projectID.String(), ID: accountID.String()})
+	if err != nil {
+		t.Fatalf("DeleteServiceAccount: %v", err)
+	}
+	if !response.(endpoints.DeleteServiceAccountResponse).Success || deleted != accountID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	deleted = uuid.Nil
+	other := uuid.New()
+	if _, err := endpoint.DeleteServiceAccount(ctx, endpoints.DeleteServiceAccountRequest{ProjectID: other.String(), ID: accountID.String()}); err != users.ErrServiceAccountNotFound {
+		t.Fatalf("err = %v, want %v for another project's account", err, users.ErrServiceAccountNotFound)
+	}
+	if deleted != uuid.Nil {
+		t.Fatal("another project's service account was deleted")
+	}
+	if _, err := endpoint.DeleteServiceAccount(ctx, endpoints.DeleteServiceAccountRequest{ProjectID: projectID.String(), ID: "nope"}); err != users.ErrServiceAccountNotFound {
+		t.Fatalf("err = %v, want %v", err, users.ErrServiceAccountNotFound)
+	}
+	_, err = endpoint.DeleteServiceAccount(ctx, endpoints.DeleteServiceAccountRequest{ProjectID: "nope"})
+	wantCode(t, err, "INVALID_PROJECT_ID")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteServiceAccount(ctx, r) })
+}
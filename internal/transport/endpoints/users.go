@@ -3,9 +3,13 @@ package endpoints
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/roles"
 	"github.com/yash3004/user_management_service/users"
 )
 
@@ -26,13 +30,20 @@ type CreateUserResponse struct {
 }
 
 type GetUserRequest struct {
-	ID string `json:"id"`
+	ID             string `json:"id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 type GetUserResponse struct {
 	User models.DisplayUser `json:"user"`
 }
 
+// ListUsersRequest represents the list users request
+type ListUsersRequest struct {
+	Status string `json:"status"` // Only users in this status; empty for all
+	Type   string `json:"type"`   // human, the default, or service for service accounts
+}
+
 type ListUsersResponse struct {
 	Users []models.DisplayUser `json:"users"`
 }
@@ -51,6 +62,17 @@ type UpdateUserResponse struct {
 	User models.DisplayUser `json:"user"`
 }
 
+// SetUserStatusRequest represents the set user status request
+type SetUserStatusRequest struct {
+	ID     string `json:"-"` // From URL path
+	Status string `json:"status"`
+}
+
+// SetUserStatusResponse represents the set user status response
+type SetUserStatusResponse struct {
+	User models.DisplayUser `json:"user"`
+}
+
 type DeleteUserRequest struct {
 	ProjectId string `json:"project_id"`
 	ID        string `json:"id"`
@@ -73,11 +95,13 @@ type ChangePasswordResponse struct {
 
 type UsersEndpoint struct {
 	UserManager users.UserManager
+	RoleManager roles.RoleManager // resolves role_id given as a role name
 }
 
-func NewUsersEndpoint(manager users.UserManager) *UsersEndpoint {
+func NewUsersEndpoint(manager users.UserManager, roleManager roles.RoleManager) *UsersEndpoint {
 	return &UsersEndpoint{
 		UserManager: manager,
+		RoleManager: roleManager,
 	}
 }
 
@@ -87,14 +111,14 @@ func (e *UsersEndpoint) CreateUser(ctx context.Context, request interface{}) (in
 		return nil, errors.New("invalid request format")
 	}
 
-	roleID, err := uuid.Parse(req.RoleID)
+	projectID, err := uuid.Parse(req.ProjectID)
 	if err != nil {
-		return nil, errors.New("invalid role ID format")
+		return nil, errors.New("invalid project ID format")
 	}
 
-	projectID, err := uuid.Parse(req.ProjectID)
+	roleID, err := resolveRoleID(ctx, e.RoleManager, req.RoleID, projectID)
 	if err != nil {
-		return nil, errors.New("invalid project ID format")
+		return nil, err
 	}
 
 	user, err := e.UserManager.CreateUser(ctx, req.Email, req.Password, req.FirstName, req.LastName, roleID, projectID)
@@ -103,20 +127,122 @@ func (e *UsersEndpoint) CreateUser(ctx context.Context, request interface{}) (in
 	}
 
 	return CreateUserResponse{
-		User: models.DisplayUser{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Active:    user.Active,
-			RoleID:    user.RoleId.String(),
-			ProjectID: user.ProjectId.String(),
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
+		User: displayUser(*user),
+	}, nil
+}
+
+// ValidateUserRequest is a user to check as CreateUser would, without
+// creating it
+type ValidateUserRequest struct {
+	ProjectID string `json:"project_id"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	RoleID    string `json:"role_id"`
+}
+
+// ValidateUserResponse lists the fields creating the user would reject
+type ValidateUserResponse struct {
+	Valid  bool               `json:"valid"`
+	Errors []users.FieldError `json:"errors"`
+}
+
+// ValidateUser dry-runs a user creation. Rejected fields are the result, not
+// an error: the response is always a 200 unless the checks themselves fail.
+func (e *UsersEndpoint) ValidateUser(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ValidateUserRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	var fields []users.FieldError
+	reported := map[string]bool{}
+	reject := func(field, code, message string) {
+		fields = append(fields, users.FieldError{Field: field, Code: code, Message: message})
+		reported[field] = true
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		reject("project_id", "INVALID_PROJECT_ID", "invalid project ID format")
+	}
+	roleID, err := resolveRoleID(ctx, e.RoleManager, req.RoleID, projectID)
+	if errors.Is(err, ErrInvalidRole) {
+		reject("role_id", ErrInvalidRole.Code, ErrInvalidRole.Message)
+	} else if err != nil {
+		return nil, err
+	}
+
+	checked, err := e.UserManager.ValidateNewUser(ctx, req.Email, req.Password, roleID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range checked {
+		if !reported[f.Field] {
+			fields = append(fields, f)
+		}
+	}
+
+	return ValidateUserResponse{
+		Valid:  len(fields) == 0,
+		Errors: nonNil(fields),
 	}, nil
 }
 
+// LookupUsersRequest is the email to look for across projects
+type LookupUsersRequest struct {
+	Email string `json:"-"` // From the email query parameter
+}
+
+// UserMatch is a place a user with the looked up email exists
+type UserMatch struct {
+	Kind        string     `json:"kind"` // user, membership or project_user
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	ProjectID   string     `json:"project_id"`
+	ProjectName string     `json:"project_name,omitempty"`
+	RoleID      string     `json:"role_id"`
+	RoleName    string     `json:"role_name,omitempty"`
+	Status      string     `json:"status"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// LookupUsersResponse lists every match, global users first
+type LookupUsersResponse struct {
+	Matches []UserMatch `json:"matches"`
+}
+
+// LookupUsers finds where a user with an email exists, across the global
+// users and every project
+func (e *UsersEndpoint) LookupUsers(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(LookupUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	found, err := e.UserManager.LookupEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]UserMatch, len(found))
+	for i, match := range found {
+		matches[i] = UserMatch{
+			Kind:        match.Kind,
+			UserID:      match.UserID.String(),
+			Email:       match.Email,
+			ProjectID:   match.ProjectID.String(),
+			ProjectName: match.ProjectName,
+			RoleID:      match.RoleID.String(),
+			RoleName:    match.RoleName,
+			Status:      match.Status,
+			LastLoginAt: match.LastLoginAt,
+		}
+	}
+	return LookupUsersResponse{Matches: matches}, nil
+}
+
 func (e *UsersEndpoint) GetUser(ctx context.Context, request interface{}) (interface{}, error) {
 	req, ok := request.(GetUserRequest)
 	if !ok {
@@ -128,46 +254,55 @@ func (e *UsersEndpoint) GetUser(ctx context.Context, request interface{}) (inter
 		return nil, errors.New("invalid user ID format")
 	}
 
-	user, err := e.UserManager.GetUser(ctx, userID)
+	get := e.UserManager.GetUser
+	if req.IncludeDeleted {
+		get = e.UserManager.GetUserIncludingDeleted
+	}
+	user, err := get(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	return GetUserResponse{
-		User: models.DisplayUser{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Active:    user.Active,
-			RoleID:    user.RoleId.String(),
-			ProjectID: user.ProjectId.String(),
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
+		User: displayUser(*user),
 	}, nil
 }
 
+// displayUser converts a user into its public representation
+func displayUser(u schemas.User) models.DisplayUser {
+	display := models.DisplayUser{
+		ID:          u.ID.String(),
+		Email:       u.Email,
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		Active:      u.Active,
+		Status:      u.Status,
+		Type:        u.Type,
+		Transitions: userstatus.Next(u.Status),
+		RoleID:      u.RoleId.String(),
+		ProjectID:   u.ProjectId.String(),
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+	display.DeletedAt, display.DeletedBy = deletion(u.DeletedAt, u.DeletedBy)
+	return display
+}
+
 // ListUsers lists all users
 func (e *UsersEndpoint) ListUsers(ctx context.Context, request interface{}) (interface{}, error) {
-	usersList, err := e.UserManager.ListUsers(ctx)
+	req, ok := request.(ListUsersRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	usersList, err := e.UserManager.ListUsers(ctx, req.Status, req.Type)
 	if err != nil {
 		return nil, err
 	}
 
 	users := make([]models.DisplayUser, len(usersList))
 	for i, u := range usersList {
-		users[i] = models.DisplayUser{
-			ID:        u.ID.String(),
-			Email:     u.Email,
-			FirstName: u.FirstName,
-			LastName:  u.LastName,
-			Active:    u.Active,
-			RoleID:    u.RoleId.String(),
-			ProjectID: u.ProjectId.String(),
-			CreatedAt: u.CreatedAt,
-			UpdatedAt: u.UpdatedAt,
-		}
+		users[i] = displayUser(u)
 	}
 
 	return ListUsersResponse{
@@ -192,17 +327,29 @@ func (e *UsersEndpoint) UpdateUser(ctx context.Context, request interface{}) (in
 	}
 
 	return UpdateUserResponse{
-		User: models.DisplayUser{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Active:    user.Active,
-			RoleID:    user.RoleId.String(),
-			ProjectID: user.ProjectId.String(),
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
+		User: displayUser(*user),
+	}, nil
+}
+
+// SetUserStatus moves a user to another status
+func (e *UsersEndpoint) SetUserStatus(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(SetUserStatusRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	userID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	user, err := e.UserManager.SetUserStatus(ctx, userID, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return SetUserStatusResponse{
+		User: displayUser(*user),
 	}, nil
 }
 
@@ -247,3 +394,138 @@ func (e *UsersEndpoint) ChangePassword(ctx context.Context, request interface{})
 		Success: true,
 	}, nil
 }
+
+// ProjectMembership represents a project a user has access to
+type ProjectMembership struct {
+	ProjectID string    `json:"project_id"`
+	RoleID    string    `json:"role_id"`
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddUserToProjectRequest represents the add user to project request
+type AddUserToProjectRequest struct {
+	UserID    string `json:"-"` // From URL path
+	ProjectID string `json:"project_id"`
+	RoleID    string `json:"role_id"`
+}
+
+// AddUserToProjectResponse represents the add user to project response
+type AddUserToProjectResponse struct {
+	Membership ProjectMembership `json:"membership"`
+}
+
+// RemoveUserFromProjectRequest represents the remove user from project request
+type RemoveUserFromProjectRequest struct {
+	UserID    string `json:"-"`
+	ProjectID string `json:"-"`
+}
+
+// RemoveUserFromProjectResponse represents the remove user from project response
+type RemoveUserFromProjectResponse struct {
+	Success bool `json:"success"`
+}
+
+// ListUserProjectsRequest represents the list user projects request
+type ListUserProjectsRequest struct {
+	UserID string `json:"-"`
+}
+
+// ListUserProjectsResponse represents the list user projects response
+type ListUserProjectsResponse struct {
+	Projects []ProjectMembership `json:"projects"`
+}
+
+// AddUserToProject grants a user access to an additional project
+func (e *UsersEndpoint) AddUserToProject(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(AddUserToProjectRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	roleID, err := uuid.Parse(req.RoleID)
+	if err != nil {
+		return nil, errors.New("invalid role ID format")
+	}
+
+	membership, err := e.UserManager.AddUserToProject(ctx, userID, projectID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return AddUserToProjectResponse{
+		Membership: ProjectMembership{
+			ProjectID: membership.ProjectId.String(),
+			RoleID:    membership.RoleId.String(),
+			CreatedAt: membership.CreatedAt,
+		},
+	}, nil
+}
+
+// RemoveUserFromProject revokes a user's access to an additional project
+func (e *UsersEndpoint) RemoveUserFromProject(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RemoveUserFromProjectRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	if err := e.UserManager.RemoveUserFromProject(ctx, userID, projectID); err != nil {
+		return nil, err
+	}
+
+	return RemoveUserFromProjectResponse{
+		Success: true,
+	}, nil
+}
+
+// ListUserProjects lists every project a user can access
+func (e *UsersEndpoint) ListUserProjects(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListUserProjectsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	memberships, err := e.UserManager.ListUserProjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]ProjectMembership, len(memberships))
+	for i, m := range memberships {
+		projects[i] = ProjectMembership{
+			ProjectID: m.ProjectId.String(),
+			RoleID:    m.RoleId.String(),
+			Primary:   i == 0,
+			CreatedAt: m.CreatedAt,
+		}
+	}
+
+	return ListUserProjectsResponse{
+		Projects: projects,
+	}, nil
+}
@@ -0,0 +1,433 @@
+package endpoints_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/users"
+	"gorm.io/gorm"
+)
+
+// aSchemaUser builds the global user a manager would return
+func aSchemaUser(id uuid.UUID, email string) *schemas.User {
+	return &schemas.User{
+		ID:        id,
+		Email:     email,
+		FirstName: "Ada",
+		Status:    schemas.UserStatusActive,
+		Active:    true,
+		Type:      schemas.UserTypeHuman,
+		RoleId:    uuid.New(),
+		ProjectId: uuid.New(),
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	projectID, roleID := uuid.New(), uuid.New()
+	manager := &mocks.UserManager{
+		CreateUserFunc: func(_ context.Context, email, password, first, last string, rid, pid uuid.UUID) (*schemas.User, error) {
+			if email != "a@example.com" || password != "s3cret!!" || first != "Ada" || last != "Lovelace" || rid != roleID || pid != projectID {
+				t.Errorf("CreateUser(%q, %q, %q, %q, %v, %v)", email, password, first, last, rid, pid)
+			}
+			user := aSchemaUser(uuid.New(), email)
+			user.RoleId, user.ProjectId = rid, pid
+			return user, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, rolesNamed(map[string]uuid.UUID{"member": roleID}))
+	ctx := context.Background()
+
+	response, err := endpoint.CreateUser(ctx, endpoints.CreateUserRequest{
+		ProjectID: projectID.String(), Email: "a@example.com", Password: "s3cret!!", FirstName: "Ada", LastName: "Lovelace", RoleID: "member",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user := response.(endpoints.CreateUserResponse).User
+	if user.Email != "a@example.com" || user.RoleID != roleID.String() || user.ProjectID != projectID.String() {
+		t.Fatalf("user = %+v", user)
+	}
+	if !reflect.DeepEqual(user.Transitions, userstatus.Next(schemas.UserStatusActive)) {
+		t.Fatalf("transitions = %v, want %v", user.Transitions, userstatus.Next(schemas.UserStatusActive))
+	}
+
+	_, err = endpoint.CreateUser(ctx, endpoints.CreateUserRequest{ProjectID: projectID.String(), RoleID: "unknown"})
+	wantCode(t, err, "INVALID_ROLE")
+	if _, err := endpoint.CreateUser(ctx, endpoints.CreateUserRequest{ProjectID: "nope", RoleID: roleID.String()}); err == nil {
+		t.Fatal("CreateUser accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateUser(ctx, r) })
+}
+
+func TestValidateUser(t *testing.T) {
+	projectID, roleID := uuid.New(), uuid.New()
+	manager := &mocks.UserManager{
+		ValidateNewUserFunc: func(_ context.Context, email, password string, rid, pid uuid.UUID) ([]users.FieldError, error) {
+			var fields []users.FieldError
+			if email == "taken@example.com" {
+				fields = append(fields, users.FieldError{Field: "email", Code: "CONFLICT"})
+			}
+			if rid == uuid.Nil {
+				fields = append(fields, users.FieldError{Field: "role_id", Code: "ROLE_NOT_FOUND"})
+			}
+			if pid == uuid.Nil {
+				fields = append(fields, users.FieldError{Field: "project_id", Code: "PROJECT_NOT_FOUND"})
+			}
+			return fields, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, rolesNamed(map[string]uuid.UUID{"member": roleID}))
+	ctx := context.Background()
+
+	response, err := endpoint.ValidateUser(ctx, endpoints.ValidateUserRequest{ProjectID: projectID.String(), Email: "a@example.com", RoleID: "member"})
+	if err != nil {
+		t.Fatalf("ValidateUser: %v", err)
+	}
+	if result := response.(endpoints.ValidateUserResponse); !result.Valid || result.Errors == nil || len(result.Errors) != 0 {
+		t.Fatalf("result = %#v, want valid with an empty error list", result)
+	}
+
+	// A field the endpoint already rejected is reported once, with the
+	// endpoint's own code
+	response, err = endpoint.ValidateUser(ctx, endpoints.ValidateUserRequest{ProjectID: "nope", Email: "taken@example.com", RoleID: "unknown"})
+	if err != nil {
+		t.Fatalf("ValidateUser: %v", err)
+	}
+	result := response.(endpoints.ValidateUserResponse)
+	want := []users.FieldError{
+		{Field: "project_id", Code: "INVALID_PROJECT_ID"},
+		{Field: "role_id", Code: "INVALID_ROLE"},
+		{Field: "email", Code: "CONFLICT"},
+	}
+	if result.Valid || len(result.Errors) != len(want) {
+		t.Fatalf("result = %+v", result)
+	}
+	for i := range want {
+		if result.Errors[i].Field != want[i].Field || result.Errors[i].Code != want[i].Code {
+			t.Errorf("error %d = %+v, want %+v", i, result.Errors[i], want[i])
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ValidateUser(ctx, r) })
+}
+
+func TestLookupUsers(t *testing.T) {
+	userID, projectID, roleID := uuid.New(), uuid.New(), uuid.New()
+	lastLogin := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.UserManager{
+		LookupEmailFunc: func(_ context.Context, email string) ([]users.EmailMatch, error) {
+			if email == "" {
+				return nil, users.ErrLookupEmailRequired
+			}
+			return []users.EmailMatch{{
+				Kind: "project_user", UserID: userID, Email: email, ProjectID: projectID, ProjectName: "Shop",
+				RoleID: roleID, RoleName: "member", Status: schemas.UserStatusActive, LastLoginAt: &lastLogin,
+			}}, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.LookupUsers(ctx, endpoints.LookupUsersRequest{Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("LookupUsers: %v", err)
+	}
+	matches := response.(endpoints.LookupUsersResponse).Matches
+	want := endpoints.UserMatch{
+		Kind: "project_user", UserID: userID.String(), Email: "a@example.com", ProjectID: projectID.String(), ProjectName: "Shop",
+		RoleID: roleID.String(), RoleName: "member", Status: schemas.UserStatusActive, LastLoginAt: &lastLogin,
+	}
+	if len(matches) != 1 || !reflect.DeepEqual(matches[0], want) {
+		t.Fatalf("matches = %+v, want %+v", matches, want)
+	}
+
+	_, err = endpoint.LookupUsers(ctx, endpoints.LookupUsersRequest{})
+	wantCode(t, err, "EMAIL_REQUIRED")
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.LookupUsers(ctx, r) })
+}
+
+func TestGetUser(t *testing.T) {
+	userID, deleter := uuid.New(), uuid.New()
+	deletedAt := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.UserManager{
+		GetUserFunc: func(_ context.Context, id uuid.UUID) (*schemas.User, error) {
+			return aSchemaUser(id, "live@example.com"), nil
+		},
+		GetUserIncludingDeletedFunc: func(_ context.Context, id uuid.UUID) (*schemas.User, error) {
+			user := aSchemaUser(id, "gone@example.com")
+			user.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+			user.DeletedBy = &deleter
+			return user, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.GetUser(ctx, endpoints.GetUserRequest{ID: userID.String()})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user := response.(endpoints.GetUserResponse).User; user.Email != "live@example.com" || user.Type != schemas.UserTypeHuman || user.DeletedAt != nil {
+		t.Fatalf("user = %+v", user)
+	}
+
+	response, err = endpoint.GetUser(ctx, endpoints.GetUserRequest{ID: userID.String(), IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	user := response.(endpoints.GetUserResponse).User
+	if user.Email != "gone@example.com" || user.DeletedAt == nil || !user.DeletedAt.Equal(deletedAt) || user.DeletedBy != deleter.String() {
+		t.Fatalf("deleted user = %+v", user)
+	}
+
+	if _, err := endpoint.GetUser(ctx, endpoints.GetUserRequest{ID: "nope"}); err == nil {
+		t.Fatal("GetUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.GetUser(ctx, r) })
+}
+
+func TestListUsers(t *testing.T) {
+	manager := &mocks.UserManager{
+		ListUsersFunc: func(_ context.Context, status, userType string) ([]schemas.User, error) {
+			if status != schemas.UserStatusSuspended || userType != schemas.UserTypeService {
+				t.Errorf("ListUsers(%q, %q)", status, userType)
+			}
+			return []schemas.User{*aSchemaUser(uuid.New(), "a@example.com"), *aSchemaUser(uuid.New(), "b@example.com")}, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListUsers(ctx, endpoints.ListUsersRequest{Status: schemas.UserStatusSuspended, Type: schemas.UserTypeService})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if list := response.(endpoints.ListUsersResponse).Users; len(list) != 2 || list[0].Email != "a@example.com" || list[1].Email != "b@example.com" {
+		t.Fatalf("users = %+v", list)
+	}
+
+	manager.ListUsersFunc = func(context.Context, string, string) ([]schemas.User, error) { return nil, users.ErrUnknownUserType }
+	if _, err := endpoint.ListUsers(ctx, endpoints.ListUsersRequest{Type: "robot"}); err != users.ErrUnknownUserType {
+		t.Fatalf("err = %v, want %v", err, users.ErrUnknownUserType)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListUsers(ctx, r) })
+}
+
+func TestUpdateUser(t *testing.T) {
+	userID := uuid.New()
+	manager := &mocks.UserManager{
+		UpdateUserFunc: func(_ context.Context, id uuid.UUID, first, last string, active bool) (*schemas.User, error) {
+			if id != userID || first != "Grace" || last != "Hopper" || active {
+				t.Errorf("UpdateUser(%v, %q, %q, %v)", id, first, last, active)
+			}
+			user := aSchemaUser(id, "a@example.com")
+			user.FirstName, user.LastName, user.Active = first, last, active
+			return user, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.UpdateUser(ctx, endpoints.UpdateUserRequest{ID: userID.String(), FirstName: "Grace", LastName: "Hopper"})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if user := response.(endpoints.UpdateUserResponse).User; user.FirstName != "Grace" || user.Active {
+		t.Fatalf("user = %+v", user)
+	}
+
+	if _, err := endpoint.UpdateUser(ctx, endpoints.UpdateUserRequest{ID: "nope"}); err == nil {
+		t.Fatal("UpdateUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.UpdateUser(ctx, r) })
+}
+
+func TestSetUserStatus(t *testing.T) {
+	userID := uuid.New()
+	manager := &mocks.UserManager{
+		SetUserStatusFunc: func(_ context.Context, id uuid.UUID, status string) (*schemas.User, error) {
+			user := aSchemaUser(id, "a@example.com")
+			user.Status, user.Active = status, false
+			return user, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.SetUserStatus(ctx, endpoints.SetUserStatusRequest{ID: userID.String(), Status: schemas.UserStatusSuspended})
+	if err != nil {
+		t.Fatalf("SetUserStatus: %v", err)
+	}
+	user := response.(endpoints.SetUserStatusResponse).User
+	if user.Status != schemas.UserStatusSuspended || !reflect.DeepEqual(user.Transitions, userstatus.Next(schemas.UserStatusSuspended)) {
+		t.Fatalf("user = %+v", user)
+	}
+
+	manager.SetUserStatusFunc = func(context.Context, uuid.UUID, string) (*schemas.User, error) {
+		return nil, userstatus.ErrUnknownStatus
+	}
+	_, err = endpoint.SetUserStatus(ctx, endpoints.SetUserStatusRequest{ID: userID.String(), Status: "asleep"})
+	wantCode(t, err, "INVALID_STATUS")
+	if _, err := endpoint.SetUserStatus(ctx, endpoints.SetUserStatusRequest{ID: "nope"}); err == nil {
+		t.Fatal("SetUserStatus accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.SetUserStatus(ctx, r) })
+}
+
+func TestDeleteUser(t *testing.T) {
+	userID := uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.UserManager{
+		DeleteUserFunc: func(_ context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteUser(ctx, endpoints.DeleteUserRequest{ID: userID.String()})
+	if err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if !response.(endpoints.DeleteUserResponse).Success || deleted != userID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	if _, err := endpoint.DeleteUser(ctx, endpoints.DeleteUserRequest{ID: "nope"}); err == nil {
+		t.Fatal("DeleteUser accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteUser(ctx, r) })
+}
+
+func TestChangePassword(t *testing.T) {
+	userID := uuid.New()
+	manager := &mocks.UserManager{
+		ChangePasswordFunc: func(_ context.Context, id uuid.UUID, current, next string) error {
+			if id != userID || current != "old-password" {
+				t.Errorf("ChangePassword(%v, %q)", id, current)
+			}
+			if next == "old-password" {
+				return users.ErrPasswordReused
+			}
+			return nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ChangePassword(ctx, endpoints.ChangePasswordRequest{ID: userID.String(), CurrentPassword: "old-password", NewPassword: "new-password"})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if !response.(endpoints.ChangePasswordResponse).Success {
+		t.Fatalf("response = %+v", response)
+	}
+
+	_, err = endpoint.ChangePassword(ctx, endpoints.ChangePasswordRequest{ID: userID.String(), CurrentPassword: "old-password", NewPassword: "old-password"})
+	wantCode(t, err, "PASSWORD_REUSED")
+	if _, err := endpoint.ChangePassword(ctx, endpoints.ChangePasswordRequest{ID: "nope"}); err == nil {
+		t.Fatal("ChangePassword accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ChangePassword(ctx, r) })
+}
+
+func TestAddUserToProject(t *testing.T) {
+	userID, projectID, roleID := uuid.New(), uuid.New(), uuid.New()
+	createdAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.UserManager{
+		AddUserToProjectFunc: func(_ context.Context, uid, pid, rid uuid.UUID) (*schemas.UserProjectMembership, error) {
+			if uid != userID || pid != projectID || rid != roleID {
+				t.Errorf("AddUserToProject(%v, %v, %v)", uid, pid, rid)
+			}
+			return &schemas.UserProjectMembership{UserId: uid, ProjectId: pid, RoleId: rid, CreatedAt: createdAt}, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.AddUserToProject(ctx, endpoints.AddUserToProjectRequest{UserID: userID.String(), ProjectID: projectID.String(), RoleID: roleID.String()})
+	if err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+	want := endpoints.ProjectMembership{ProjectID: projectID.String(), RoleID: roleID.String(), CreatedAt: createdAt}
+	if membership := response.(endpoints.AddUserToProjectResponse).Membership; membership != want {
+		t.Fatalf("membership = %+v, want %+v", membership, want)
+	}
+
+	for _, bad := range []endpoints.AddUserToProjectRequest{
+		{UserID: "nope", ProjectID: projectID.String(), RoleID: roleID.String()},
+		{UserID: userID.String(), ProjectID: "nope", RoleID: roleID.String()},
+		{UserID: userID.String(), ProjectID: projectID.String(), RoleID: "member"},
+	} {
+		if _, err := endpoint.AddUserToProject(ctx, bad); err == nil {
+			t.Errorf("AddUserToProject accepted %+v", bad)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.AddUserToProject(ctx, r) })
+}
+
+func TestRemoveUserFromProject(t *testing.T) {
+	userID, projectID := uuid.New(), uuid.New()
+	manager := &mocks.UserManager{
+		RemoveUserFromProjectFunc: func(_ context.Context, uid, pid uuid.UUID) error {
+			if uid != userID || pid != projectID {
+				t.Errorf("RemoveUserFromProject(%v, %v)", uid, pid)
+			}
+			return nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.RemoveUserFromProject(ctx, endpoints.RemoveUserFromProjectRequest{UserID: userID.String(), ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("RemoveUserFromProject: %v", err)
+	}
+	if !response.(endpoints.RemoveUserFromProjectResponse).Success {
+		t.Fatalf("response = %+v", response)
+	}
+
+	manager.RemoveUserFromProjectFunc = func(context.Context, uuid.UUID, uuid.UUID) error { return users.ErrNotProjectMember }
+	if _, err := endpoint.RemoveUserFromProject(ctx, endpoints.RemoveUserFromProjectRequest{UserID: userID.String(), ProjectID: projectID.String()}); err != users.ErrNotProjectMember {
+		t.Fatalf("err = %v, want %v", err, users.ErrNotProjectMember)
+	}
+	if _, err := endpoint.RemoveUserFromProject(ctx, endpoints.RemoveUserFromProjectRequest{UserID: "nope", ProjectID: projectID.String()}); err == nil {
+		t.Fatal("RemoveUserFromProject accepted a malformed user ID")
+	}
+	if _, err := endpoint.RemoveUserFromProject(ctx, endpoints.RemoveUserFromProjectRequest{UserID: userID.String(), ProjectID: "nope"}); err == nil {
+		t.Fatal("RemoveUserFromProject accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RemoveUserFromProject(ctx, r) })
+}
+
+func TestListUserProjects(t *testing.T) {
+	userID, primary, extra := uuid.New(), uuid.New(), uuid.New()
+	manager := &mocks.UserManager{
+		ListUserProjectsFunc: func(_ context.Context, uid uuid.UUID) ([]schemas.UserProjectMembership, error) {
+			return []schemas.UserProjectMembership{{UserId: uid, ProjectId: primary}, {UserId: uid, ProjectId: extra}}, nil
+		},
+	}
+	endpoint := endpoints.NewUsersEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.ListUserProjects(ctx, endpoints.ListUserProjectsRequest{UserID: userID.String()})
+	if err != nil {
+		t.Fatalf("ListUserProjects: %v", err)
+	}
+	list := response.(endpoints.ListUserProjectsResponse).Projects
+	if len(list) != 2 || list[0].ProjectID != primary.String() || !list[0].Primary || list[1].Primary {
+		t.Fatalf("projects = %+v, want the first one primary", list)
+	}
+
+	if _, err := endpoint.ListUserProjects(ctx, endpoints.ListUserProjectsRequest{UserID: "nope"}); err == nil {
+		t.Fatal("ListUserProjects accepted a malformed user ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListUserProjects(ctx, r) })
+}
@@ -0,0 +1,25 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/yash3004/user_management_service/internal/version"
+)
+
+// VersionResponse represents the build version response
+type VersionResponse struct {
+	version.Info
+}
+
+// VersionEndpoint reports the running build
+type VersionEndpoint struct{}
+
+// NewVersionEndpoint creates a new version endpoint
+func NewVersionEndpoint() *VersionEndpoint {
+	return &VersionEndpoint{}
+}
+
+// GetVersion returns the build version, commit, build time and Go version
+func (e *VersionEndpoint) GetVersion(_ context.Context, _ interface{}) (interface{}, error) {
+	return VersionResponse{Info: version.Get()}, nil
+}
@@ -0,0 +1,19 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/version"
+)
+
+func TestGetVersion(t *testing.T) {
+	response, err := endpoints.NewVersionEndpoint().GetVersion(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if got := response.(endpoints.VersionResponse).Info; got != version.Get() {
+		t.Fatalf("version = %+v, want %+v", got, version.Get())
+	}
+}
@@ -0,0 +1,305 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webauthn"
+)
+
+// WebAuthnCredential represents a passkey in the response
+type WebAuthnCredential struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	AAGUID     string     `json:"aaguid"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// BeginWebAuthnRegistrationRequest represents the begin passkey registration request
+type BeginWebAuthnRegistrationRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+}
+
+// BeginWebAuthnRegistrationResponse wraps the options for navigator.credentials.create
+type BeginWebAuthnRegistrationResponse struct {
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+// FinishWebAuthnRegistrationRequest represents the finish passkey registration request
+type FinishWebAuthnRegistrationRequest struct {
+	ProjectID  string                       `json:"-"`
+	UserID     string                       `json:"-"`
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+// WebAuthnCredentialResponse represents a single passkey response
+type WebAuthnCredentialResponse struct {
+	Credential WebAuthnCredential `json:"credential"`
+}
+
+// BeginWebAuthnLoginRequest represents the begin passkey login request
+type BeginWebAuthnLoginRequest struct {
+	ProjectID string `json:"-"`
+}
+
+// BeginWebAuthnLoginResponse wraps the options for navigator.credentials.get
+type BeginWebAuthnLoginResponse struct {
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+// FinishWebAuthnLoginRequest represents the finish passkey login request
+type FinishWebAuthnLoginRequest struct {
+	ProjectID  string                     `json:"-"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+// FinishWebAuthnLoginResponse represents the finish passkey login response
+type FinishWebAuthnLoginResponse struct {
+	Token     string             `json:"token"`
+	User      models.DisplayUser `json:"user"`
+	ExpiresIn int64              `json:"expires_in"`
+	consentsPending
+}
+
+// ListWebAuthnCredentialsRequest represents the list passkeys request
+type ListWebAuthnCredentialsRequest struct {
+	ProjectID string `json:"-"`
+	UserID    string `json:"-"`
+}
+
+// ListWebAuthnCredentialsResponse represents the list passkeys response
+type ListWebAuthnCredentialsResponse struct {
+	Credentials []WebAuthnCredential `json:"credentials"`
+}
+
+// RenameWebAuthnCredentialRequest represents the rename passkey request
+type RenameWebAuthnCredentialRequest struct {
+	ProjectID    string `json:"-"`
+	UserID       string `json:"-"`
+	CredentialID string `json:"-"`
+	Name         string `json:"name"`
+}
+
+// DeleteWebAuthnCredentialRequest represents the delete passkey request
+type DeleteWebAuthnCredentialRequest struct {
+	ProjectID    string `json:"-"`
+	UserID       string `json:"-"`
+	CredentialID string `json:"-"`
+}
+
+// DeleteWebAuthnCredentialResponse represents the delete passkey response
+type DeleteWebAuthnCredentialResponse struct {
+	Success bool `json:"success"`
+}
+
+// WebAuthnEndpoint handles passkey endpoints
+type WebAuthnEndpoint struct {
+	WebAuthnManager webauthn.WebAuthnManager
+	// Consents tells which documents users logging in have yet to accept;
+	// nil flags none
+	Consents consents.ConsentManager
+}
+
+// NewWebAuthnEndpoint creates a new WebAuthn endpoint
+func NewWebAuthnEndpoint(manager webauthn.WebAuthnManager, consentManager consents.ConsentManager) *WebAuthnEndpoint {
+	return &WebAuthnEndpoint{
+		WebAuthnManager: manager,
+		Consents:        consentManager,
+	}
+}
+
+func toWebAuthnCredential(cred schemas.WebAuthnCredential) WebAuthnCredential {
+	return WebAuthnCredential{
+		ID:         cred.ID.String(),
+		Name:       cred.Name,
+		AAGUID:     cred.AAGUID.String(),
+		Transports: nonNil(cred.Transports),
+		CreatedAt:  cred.CreatedAt,
+		LastUsedAt: cred.LastUsedAt,
+	}
+}
+
+// parseProjectAndUser parses the project and user IDs from a request path
+func parseProjectAndUser(projectID, userID string) (uuid.UUID, uuid.UUID, error) {
+	pid, err := uuid.Parse(projectID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid project ID format")
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid user ID format")
+	}
+	return pid, uid, nil
+}
+
+// BeginRegistration starts enrolling a passkey for a project user
+func (e *WebAuthnEndpoint) BeginRegistration(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(BeginWebAuthnRegistrationRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := e.WebAuthnManager.BeginRegistration(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return BeginWebAuthnRegistrationResponse{PublicKey: *options}, nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the passkey
+func (e *WebAuthnEndpoint) FinishRegistration(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(FinishWebAuthnRegistrationRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := e.WebAuthnManager.FinishRegistration(ctx, projectID, userID, req.Name, req.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return WebAuthnCredentialResponse{Credential: toWebAuthnCredential(*cred)}, nil
+}
+
+// BeginLogin starts a passkey login
+func (e *WebAuthnEndpoint) BeginLogin(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(BeginWebAuthnLoginRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	options, err := e.WebAuthnManager.BeginLogin(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return BeginWebAuthnLoginResponse{PublicKey: *options}, nil
+}
+
+// FinishLogin verifies a passkey assertion and issues a JWT
+func (e *WebAuthnEndpoint) FinishLogin(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(FinishWebAuthnLoginRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	session, err := e.WebAuthnManager.FinishLogin(ctx, projectID, req.Credential)
+	auth.RecordLogin(auth.MethodPasskey, projectID.String(), err)
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding, err := outstandingConsents(ctx, e.Consents, session.User.ProjectID, session.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return FinishWebAuthnLoginResponse{
+		Token:           session.Token,
+		User:            session.User,
+		ExpiresIn:       session.ExpiresAt.Unix() - time.Now().Unix(),
+		consentsPending: consentsPending{OutstandingConsents: outstanding},
+	}, nil
+}
+
+// ListCredentials lists a project user's passkeys
+func (e *WebAuthnEndpoint) ListCredentials(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListWebAuthnCredentialsRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := e.WebAuthnManager.ListCredentials(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := ListWebAuthnCredentialsResponse{Credentials: make([]WebAuthnCredential, 0, len(creds))}
+	for _, cred := range creds {
+		response.Credentials = append(response.Credentials, toWebAuthnCredential(cred))
+	}
+	return response, nil
+}
+
+// RenameCredential renames a project user's passkey
+func (e *WebAuthnEndpoint) RenameCredential(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(RenameWebAuthnCredentialRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(req.CredentialID)
+	if err != nil {
+		return nil, errors.New("invalid credential ID format")
+	}
+
+	cred, err := e.WebAuthnManager.RenameCredential(ctx, projectID, userID, id, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return WebAuthnCredentialResponse{Credential: toWebAuthnCredential(*cred)}, nil
+}
+
+// DeleteCredential deletes a project user's passkey
+func (e *WebAuthnEndpoint) DeleteCredential(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(DeleteWebAuthnCredentialRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, userID, err := parseProjectAndUser(req.ProjectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(req.CredentialID)
+	if err != nil {
+		return nil, errors.New("invalid credential ID format")
+	}
+
+	if err := e.WebAuthnManager.DeleteCredential(ctx, projectID, userID, id); err != nil {
+		return nil, err
+	}
+
+	return DeleteWebAuthnCredentialResponse{Success: true}, nil
+}
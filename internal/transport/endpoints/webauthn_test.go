@@ -0,0 +1,213 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/webauthn"
+)
+
+// passkeyOwner is the project user the passkey tests enroll
+type passkeyOwner struct {
+	projectID, userID uuid.UUID
+}
+
+func (o passkeyOwner) check(t *testing.T, projectID, userID uuid.UUID) {
+	t.Helper()
+	if projectID != o.projectID || userID != o.userID {
+		t.Errorf("called for %v in %v, want %v in %v", userID, projectID, o.userID, o.projectID)
+	}
+}
+
+// badOwners are requests naming a malformed project or user ID
+func (o passkeyOwner) badOwners() [][2]string {
+	return [][2]string{{"nope", o.userID.String()}, {o.projectID.String(), "nope"}}
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	owner := passkeyOwner{uuid.New(), uuid.New()}
+	created := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+	aaguid := uuid.New()
+	manager := &mocks.WebAuthnManager{
+		BeginRegistrationFunc: func(_ context.Context, projectID, userID uuid.UUID) (*webauthn.CreationOptions, error) {
+			owner.check(t, projectID, userID)
+			return &webauthn.CreationOptions{Challenge: []byte("challenge"), Timeout: 60000}, nil
+		},
+		FinishRegistrationFunc: func(_ context.Context, projectID, userID uuid.UUID, name string, response webauthn.AttestationResponse) (*schemas.WebAuthnCredential, error) {
+			owner.check(t, projectID, userID)
+			if name != "laptop" || response.ID != "cred" {
+				t.Errorf("FinishRegistration(%q, %q)", name, response.ID)
+			}
+			return &schemas.WebAuthnCredential{ID: uuid.New(), Name: name, AAGUID: aaguid, CreatedAt: created}, nil
+		},
+	}
+	endpoint := endpoints.NewWebAuthnEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.BeginRegistration(ctx, endpoints.BeginWebAuthnRegistrationRequest{ProjectID: owner.projectID.String(), UserID: owner.userID.String()})
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	if options := response.(endpoints.BeginWebAuthnRegistrationResponse).PublicKey; string(options.Challenge) != "challenge" || options.Timeout != 60000 {
+		t.Fatalf("options = %+v", options)
+	}
+
+	finish := endpoints.FinishWebAuthnRegistrationRequest{ProjectID: owner.projectID.String(), UserID: owner.userID.String(), Name: "laptop"}
+	finish.Credential.ID = "cred"
+	response, err = endpoint.FinishRegistration(ctx, finish)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	credential := response.(endpoints.WebAuthnCredentialResponse).Credential
+	if credential.Name != "laptop" || credential.AAGUID != aaguid.String() || credential.Transports == nil || !credential.CreatedAt.Equal(created) {
+		t.Fatalf("credential = %+v", credential)
+	}
+
+	for _, ids := range owner.badOwners() {
+		if _, err := endpoint.BeginRegistration(ctx, endpoints.BeginWebAuthnRegistrationRequest{ProjectID: ids[0], UserID: ids[1]}); err == nil {
+			t.Errorf("BeginRegistration accepted %v", ids)
+		}
+		if _, err := endpoint.FinishRegistration(ctx, endpoints.FinishWebAuthnRegistrationRequest{ProjectID: ids[0], UserID: ids[1]}); err == nil {
+			t.Errorf("FinishRegistration accepted %v", ids)
+		}
+	}
+	failure := errors.New("bad attestation")
+	manager.FinishRegistrationFunc = func(context.Context, uuid.UUID, uuid.UUID, string, webauthn.AttestationResponse) (*schemas.WebAuthnCredential, error) {
+		return nil, failure
+	}
+	if _, err := endpoint.FinishRegistration(ctx, finish); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.BeginRegistration(ctx, r) })
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.FinishRegistration(ctx, r) })
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.WebAuthnManager{
+		BeginLoginFunc: func(_ context.Context, id uuid.UUID) (*webauthn.RequestOptions, error) {
+			return &webauthn.RequestOptions{Challenge: []byte("challenge"), RelyingPartyID: "shop.example.com"}, nil
+		},
+		FinishLoginFunc: func(_ context.Context, id uuid.UUID, response webauthn.AssertionResponse) (*webauthn.Session, error) {
+			if id != projectID || response.ID != "cred" {
+				t.Errorf("FinishLogin(%v, %q)", id, response.ID)
+			}
+			return &webauthn.Session{Token: "jwt", ExpiresAt: time.Now().Add(time.Hour), User: models.DisplayUser{
+				ID:        uuid.NewString(),
+				ProjectID: projectID.String(),
+			}}, nil
+		},
+	}
+	endpoint := endpoints.NewWebAuthnEndpoint(manager, nil)
+	ctx := context.Background()
+
+	response, err := endpoint.BeginLogin(ctx, endpoints.BeginWebAuthnLoginRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if options := response.(endpoints.BeginWebAuthnLoginResponse).PublicKey; options.RelyingPartyID != "shop.example.com" {
+		t.Fatalf("options = %+v", options)
+	}
+
+	finish := endpoints.FinishWebAuthnLoginRequest{ProjectID: projectID.String()}
+	finish.Credential.ID = "cred"
+	response, err = endpoint.FinishLogin(ctx, finish)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if session := response.(endpoints.FinishWebAuthnLoginResponse); session.Token != "jwt" || session.ExpiresIn < 3590 || session.OutstandingConsents != nil {
+		t.Fatalf("session = %+v", session)
+	}
+
+	failure := errors.New("bad signature")
+	manager.FinishLoginFunc = func(context.Context, uuid.UUID, webauthn.AssertionResponse) (*webauthn.Session, error) {
+		return nil, failure
+	}
+	if _, err := endpoint.FinishLogin(ctx, finish); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.BeginLogin(ctx, endpoints.BeginWebAuthnLoginRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("BeginLogin accepted a malformed project ID")
+	}
+	if _, err := endpoint.FinishLogin(ctx, endpoints.FinishWebAuthnLoginRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("FinishLogin accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.BeginLogin(ctx, r) })
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.FinishLogin(ctx, r) })
+}
+
+func TestWebAuthnCredentials(t *testing.T) {
+	owner := passkeyOwner{uuid.New(), uuid.New()}
+	credentialID := uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.WebAuthnManager{
+		ListCredentialsFunc: func(_ context.Context, projectID, userID uuid.UUID) ([]schemas.WebAuthnCredential, error) {
+			owner.check(t, projectID, userID)
+			return []schemas.WebAuthnCredential{{ID: credentialID, Name: "laptop", Transports: []string{"internal"}}}, nil
+		},
+		RenameCredentialFunc: func(_ context.Context, projectID, userID, id uuid.UUID, name string) (*schemas.WebAuthnCredential, error) {
+			owner.check(t, projectID, userID)
+			return &schemas.WebAuthnCredential{ID: id, Name: name}, nil
+		},
+		DeleteCredentialFunc: func(_ context.Context, projectID, userID, id uuid.UUID) error {
+			owner.check(t, projectID, userID)
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewWebAuthnEndpoint(manager, nil)
+	ctx := context.Background()
+	projectID, userID := owner.projectID.String(), owner.userID.String()
+
+	response, err := endpoint.ListCredentials(ctx, endpoints.ListWebAuthnCredentialsRequest{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		t.Fatalf("ListCredentials: %v", err)
+	}
+	if list := response.(endpoints.ListWebAuthnCredentialsResponse).Credentials; len(list) != 1 || list[0].ID != credentialID.String() || list[0].Transports[0] != "internal" {
+		t.Fatalf("credentials = %+v", list)
+	}
+
+	response, err = endpoint.RenameCredential(ctx, endpoints.RenameWebAuthnCredentialRequest{ProjectID: projectID, UserID: userID, CredentialID: credentialID.String(), Name: "phone"})
+	if err != nil {
+		t.Fatalf("RenameCredential: %v", err)
+	}
+	if credential := response.(endpoints.WebAuthnCredentialResponse).Credential; credential.Name != "phone" || credential.ID != credentialID.String() {
+		t.Fatalf("credential = %+v", credential)
+	}
+
+	response, err = endpoint.DeleteCredential(ctx, endpoints.DeleteWebAuthnCredentialRequest{ProjectID: projectID, UserID: userID, CredentialID: credentialID.String()})
+	if err != nil {
+		t.Fatalf("DeleteCredential: %v", err)
+	}
+	if !response.(endpoints.DeleteWebAuthnCredentialResponse).Success || deleted != credentialID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+
+	if _, err := endpoint.RenameCredential(ctx, endpoints.RenameWebAuthnCredentialRequest{ProjectID: projectID, UserID: userID, CredentialID: "nope"}); err == nil {
+		t.Error("RenameCredential accepted a malformed credential ID")
+	}
+	if _, err := endpoint.DeleteCredential(ctx, endpoints.DeleteWebAuthnCredentialRequest{ProjectID: projectID, UserID: userID, CredentialID: "nope"}); err == nil {
+		t.Error("DeleteCredential accepted a malformed credential ID")
+	}
+	for _, ids := range owner.badOwners() {
+		if _, err := endpoint.ListCredentials(ctx, endpoints.ListWebAuthnCredentialsRequest{ProjectID: ids[0], UserID: ids[1]}); err == nil {
+			t.Errorf("ListCredentials accepted %v", ids)
+		}
+		if _, err := endpoint.RenameCredential(ctx, endpoints.RenameWebAuthnCredentialRequest{ProjectID: ids[0], UserID: ids[1], CredentialID: credentialID.String()}); err == nil {
+			t.Errorf("RenameCredential accepted %v", ids)
+		}
+		if _, err := endpoint.DeleteCredential(ctx, endpoints.DeleteWebAuthnCredentialRequest{ProjectID: ids[0], UserID: ids[1], CredentialID: credentialID.String()}); err == nil {
+			t.Errorf("DeleteCredential accepted %v", ids)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListCredentials(ctx, r) })
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.RenameCredential(ctx, r) })
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteCredential(ctx, r) })
+}
@@ -0,0 +1,179 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+// WebhookSubscription represents a webhook subscription in the response
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Format    string    `json:"format"`
+	Enabled   bool      `json:"enabled"`
+	Secret    string    `json:"secret,omitempty"` // Only returned on creation
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest represents the create webhook request
+type CreateWebhookRequest struct {
+	ProjectID string   `json:"-"` // From URL path
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Format    string   `json:"format"` // "raw" (default) or "cloudevents"
+}
+
+// CreateWebhookResponse represents the create webhook response
+type CreateWebhookResponse struct {
+	Webhook WebhookSubscription `json:"webhook"`
+}
+
+// ListWebhooksRequest represents the list webhooks request
+type ListWebhooksRequest struct {
+	ProjectID string `json:"-"`
+}
+
+// ListWebhooksResponse represents the list webhooks response
+type ListWebhooksResponse struct {
+	Webhooks []WebhookSubscription `json:"webhooks"`
+}
+
+// DeleteWebhookRequest represents the delete webhook request
+type DeleteWebhookRequest struct {
+	ProjectID string `json:"-"`
+	ID        string `json:"-"`
+}
+
+// DeleteWebhookResponse represents the delete webhook response
+type DeleteWebhookResponse struct {
+	Success bool `json:"success"`
+}
+
+// ListWebhookEventsResponse represents the webhook event catalog response
+type ListWebhookEventsResponse struct {
+	Events []webhooks.EventInfo `json:"events"`
+}
+
+// WebhooksEndpoint handles webhook-related endpoints
+type WebhooksEndpoint struct {
+	WebhookManager webhooks.WebhookManager
+}
+
+// NewWebhooksEndpoint creates a new webhooks endpoint
+func NewWebhooksEndpoint(manager webhooks.WebhookManager) *WebhooksEndpoint {
+	return &WebhooksEndpoint{
+		WebhookManager: manager,
+	}
+}
+
+// CreateWebhook subscribes a URL to a project's events
+func (e *WebhooksEndpoint) CreateWebhook(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(CreateWebhookRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	sub, err := e.WebhookManager.CreateSubscription(ctx, projectID, req.URL, req.Events, req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := toWebhookSubscription(*sub)
+	webhook.Secret = sub.Secret
+	return CreateWebhookResponse{
+		Webhook: webhook,
+	}, nil
+}
+
+// ListWebhooks lists the webhook subscriptions of a project
+func (e *WebhooksEndpoint) ListWebhooks(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(ListWebhooksRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	subs, err := e.WebhookManager.ListSubscriptions(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]WebhookSubscription, len(subs))
+	for i, sub := range subs {
+		list[i] = toWebhookSubscription(sub)
+	}
+
+	return ListWebhooksResponse{
+		Webhooks: list,
+	}, nil
+}
+
+// DeleteWebhook deletes a webhook subscription
+func (e *WebhooksEndpoint) DeleteWebhook(ctx context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(DeleteWebhookRequest)
+	if !ok {
+		return nil, errors.New("invalid request format")
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		return nil, errors.New("invalid project ID format")
+	}
+
+	webhookID, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, errors.New("invalid webhook ID format")
+	}
+
+	if err := e.WebhookManager.DeleteSubscription(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	return DeleteWebhookResponse{
+		Success: true,
+	}, nil
+}
+
+// ListWebhookEvents lists the events that can be subscribed to
+func (e *WebhooksEndpoint) ListWebhookEvents(ctx context.Context, request interface{}) (interface{}, error) {
+	events := make([]webhooks.EventInfo, 0, len(webhooks.Catalog))
+	for _, info := range webhooks.Catalog {
+		events = append(events, info)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+
+	return ListWebhookEventsResponse{
+		Events: events,
+	}, nil
+}
+
+func toWebhookSubscription(sub schemas.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        sub.ID.String(),
+		ProjectID: sub.ProjectId.String(),
+		URL:       sub.URL,
+		Events:    nonNil(sub.Events),
+		Format:    sub.Format,
+		Enabled:   sub.Enabled,
+		CreatedAt: sub.CreatedAt,
+		UpdatedAt: sub.UpdatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package endpoints_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	projectID := uuid.New()
+	at := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	manager := &mocks.WebhookManager{
+		CreateSubscriptionFunc: func(_ context.Context, pid uuid.UUID, url string, events []string, format string) (*schemas.WebhookSubscription, error) {
+			if pid != projectID || url != "https://hooks.example.com" || len(events) != 1 || format != "cloudevents" {
+				t.Errorf("CreateSubscription(%v, %q, %v, %q)", pid, url, events, format)
+			}
+			return &schemas.WebhookSubscription{
+				ID: uuid.New(), ProjectId: pid, URL: url, Secret: "whsec", Events: events,
+				Format: format, Enabled: true, CreatedAt: at, UpdatedAt: at,
+			}, nil
+		},
+	}
+	endpoint := endpoints.NewWebhooksEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.CreateWebhook(ctx, endpoints.CreateWebhookRequest{
+		ProjectID: projectID.String(),
+		URL:       "https://hooks.example.com",
+		Events:    []string{"user.created"},
+		Format:    "cloudevents",
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	webhook := response.(endpoints.CreateWebhookResponse).Webhook
+	if webhook.ProjectID != projectID.String() || webhook.Secret != "whsec" || webhook.Format != "cloudevents" || !webhook.Enabled || !webhook.CreatedAt.Equal(at) {
+		t.Fatalf("webhook = %+v", webhook)
+	}
+
+	failure := errors.New("invalid url")
+	manager.CreateSubscriptionFunc = func(context.Context, uuid.UUID, string, []string, string) (*schemas.WebhookSubscription, error) {
+		return nil, failure
+	}
+	if _, err := endpoint.CreateWebhook(ctx, endpoints.CreateWebhookRequest{ProjectID: projectID.String()}); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, err := endpoint.CreateWebhook(ctx, endpoints.CreateWebhookRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("CreateWebhook accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.CreateWebhook(ctx, r) })
+}
+
+func TestListWebhooks(t *testing.T) {
+	projectID := uuid.New()
+	manager := &mocks.WebhookManager{
+		ListSubscriptionsFunc: func(_ context.Context, pid uuid.UUID) ([]schemas.WebhookSubscription, error) {
+			return []schemas.WebhookSubscription{{ID: uuid.New(), ProjectId: pid, URL: "https://hooks.example.com", Secret: "whsec"}}, nil
+		},
+	}
+	endpoint := endpoints.NewWebhooksEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.ListWebhooks(ctx, endpoints.ListWebhooksRequest{ProjectID: projectID.String()})
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	list := response.(endpoints.ListWebhooksResponse).Webhooks
+	if len(list) != 1 || list[0].Secret != "" || list[0].Events == nil {
+		t.Fatalf("webhooks = %+v, want the secret left out and no events as an empty list", list)
+	}
+	if _, err := endpoint.ListWebhooks(ctx, endpoints.ListWebhooksRequest{ProjectID: "nope"}); err == nil {
+		t.Fatal("ListWebhooks accepted a malformed project ID")
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.ListWebhooks(ctx, r) })
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	projectID, webhookID := uuid.New(), uuid.New()
+	var deleted uuid.UUID
+	manager := &mocks.WebhookManager{
+		DeleteSubscriptionFunc: func(_ context.Context, pid, id uuid.UUID) error {
+			if pid != projectID {
+				t.Errorf("project = %v, want %v", pid, projectID)
+			}
+			deleted = id
+			return nil
+		},
+	}
+	endpoint := endpoints.NewWebhooksEndpoint(manager)
+	ctx := context.Background()
+
+	response, err := endpoint.DeleteWebhook(ctx, endpoints.DeleteWebhookRequest{ProjectID: projectID.String(), ID: webhookID.String()})
+	if err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if !response.(endpoints.DeleteWebhookResponse).Success || deleted != webhookID {
+		t.Fatalf("deleted %v, response %+v", deleted, response)
+	}
+	for _, bad := range []endpoints.DeleteWebhookRequest{
+		{ProjectID: "nope", ID: webhookID.String()},
+		{ProjectID: projectID.String(), ID: "nope"},
+	} {
+		if _, err := endpoint.DeleteWebhook(ctx, bad); err == nil {
+			t.Errorf("DeleteWebhook(%+v) accepted a malformed ID", bad)
+		}
+	}
+	wantInvalidRequest(t, func(r interface{}) (interface{}, error) { return endpoint.DeleteWebhook(ctx, r) })
+}
+
+func TestListWebhookEvents(t *testing.T) {
+	response, err := endpoints.NewWebhooksEndpoint(nil).ListWebhookEvents(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListWebhookEvents: %v", err)
+	}
+	events := response.(endpoints.ListWebhookEventsResponse).Events
+	if len(events) != len(webhooks.Catalog) {
+		t.Fatalf("listed %d events, want %d", len(events), len(webhooks.Catalog))
+	}
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Name
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Fatalf("events are not sorted by name: %v", names)
+	}
+	if first := events[0]; !reflect.DeepEqual(first, webhooks.Catalog[first.Name]) {
+		t.Fatalf("event %+v does not match the catalog", first)
+	}
+}
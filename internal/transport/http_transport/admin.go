@@ -0,0 +1,72 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/tokenkeys"
+)
+
+// AddAdminRoutes registers the service maintenance routes
+func AddAdminRoutes(r *mux.Router, ep *endpoints.AdminEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/rotate-token-key",
+			Endpoint: ep.RotateTokenKey,
+			Decode:   decodeRotateTokenKeyRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RotateTokenKeyRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				tokenkeys.ErrNotConfigured,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/templates",
+			Endpoint: ep.GetTemplates,
+			Decode:   decodeGetTemplatesRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetTemplatesRequest{},
+			Requires: AdminOnly,
+		},
+		{
+			Method:   "GET",
+			Path:     "/stats",
+			Endpoint: ep.GetStats,
+			Decode:   decodeGetStatsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetStatsRequest{},
+			Requires: AdminOnly,
+		},
+		{
+			Method:   "GET",
+			Path:     "/config",
+			Endpoint: ep.GetConfig,
+			Decode:   decodeGetConfigRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetConfigRequest{},
+			Requires: AdminOnly,
+		},
+	})
+}
+
+func decodeRotateTokenKeyRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return endpoints.RotateTokenKeyRequest{}, nil
+}
+
+func decodeGetTemplatesRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return endpoints.GetTemplatesRequest{}, nil
+}
+
+func decodeGetStatsRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return endpoints.GetStatsRequest{}, nil
+}
+
+func decodeGetConfigRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return endpoints.GetConfigRequest{}, nil
+}
@@ -0,0 +1,86 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddAPITokenRoutes registers the project API token routes on the projects
+// router. Managing a project's tokens needs projects:write in it.
+func AddAPITokenRoutes(r *mux.Router, ep *endpoints.APITokensEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/{projectId}/api-tokens",
+			Endpoint: ep.ListAPITokens,
+			Decode:   decodeListAPITokensRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListAPITokensRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+		},
+		{
+			Method:   "POST",
+			Path:     "/{projectId}/api-tokens",
+			Endpoint: ep.CreateAPIToken,
+			Decode:   decodeCreateAPITokenRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateAPITokenRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("VALIDATION_FAILED", "name is required"),
+				apitokens.ErrServiceAccountNotFound,
+				apitokens.ErrServiceAccountTokenForbidden,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{projectId}/api-tokens/{tokenId}",
+			Endpoint: ep.RevokeAPIToken,
+			Decode:   decodeRevokeAPITokenRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RevokeAPITokenRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.NotFound("API token not found"),
+			},
+		},
+	})
+}
+
+func decodeListAPITokensRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListAPITokensRequest{ProjectID: projectId}, nil
+}
+
+func decodeCreateAPITokenRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var req endpoints.CreateAPITokenRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ProjectID = projectId
+	return req, nil
+}
+
+func decodeRevokeAPITokenRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := mux.Vars(r)["tokenId"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.RevokeAPITokenRequest{ProjectID: projectId, ID: id}, nil
+}
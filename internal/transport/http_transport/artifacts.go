@@ -0,0 +1,38 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/artifacts"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddArtifactRoutes registers the download route of stored artifacts
+func AddArtifactRoutes(r *mux.Router, ep *endpoints.ArtifactsEndpoint) {
+	mount(r, []Route{
+		// GET - Download an artifact, e.g. the export of a deleted project
+		{
+			Method:   "GET",
+			Path:     "/{key:.+}",
+			Endpoint: ep.DownloadArtifact,
+			Decode:   decodeDownloadArtifactRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DownloadArtifactRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				artifacts.ErrArtifactNotFound,
+			},
+		},
+	})
+}
+
+func decodeDownloadArtifactRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	key, ok := mux.Vars(r)["key"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.DownloadArtifactRequest{Key: key}, nil
+}
@@ -0,0 +1,65 @@
+package http_transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddAuditRoutes registers the audit log routes. The events name actors by
+// email and IP address, so only super admins may read them.
+func AddAuditRoutes(r *mux.Router, ep *endpoints.AuditEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "",
+			Endpoint: ep.ListAuditEvents,
+			Decode:   decodeListAuditEventsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListAuditEventsRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("INVALID_PAGINATION", "page must be an integer"),
+				apierrors.BadRequest("INVALID_TIMESTAMP", "from must be an RFC 3339 timestamp"),
+				apierrors.BadRequest("INVALID_RANGE", "from must not be after to"),
+				apierrors.BadRequest("INVALID_QUERY", fmt.Sprintf("q must be at most %d characters", audit.MaxQueryLength)),
+			},
+		},
+	})
+}
+
+func decodeListAuditEventsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	request := endpoints.ListAuditEventsRequest{
+		From:         q.Get("from"),
+		To:           q.Get("to"),
+		Actor:        q.Get("actor"),
+		ResourceType: q.Get("resource_type"),
+		Action:       q.Get("action"),
+		Query:        q.Get("q"),
+		ProjectID:    q.Get("project_id"),
+	}
+
+	var err error
+	if request.Page, err = queryInt(q.Get("page")); err != nil {
+		return nil, apierrors.BadRequest("INVALID_PAGINATION", "page must be an integer")
+	}
+	if request.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		return nil, apierrors.BadRequest("INVALID_PAGINATION", "page_size must be an integer")
+	}
+	return request, nil
+}
+
+// queryInt parses an optional integer query parameter, returning 0 when it is empty
+func queryInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
@@ -2,30 +2,76 @@ package http_transport
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
 	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	sessionauth "github.com/yash3004/user_management_service/auth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
-	"gorm.io/gorm"
+	"github.com/yash3004/user_management_service/roles"
 )
 
-func AddAuthRoutes(r *mux.Router, db *gorm.DB) {
-	authEndpoint := &endpoints.AuthEndpoint{DB: db}
-
-	r.Methods("POST").Path("/login").Handler(kithttp.NewServer(
-		authEndpoint.Login,
-		decodeLoginRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+// AddAuthRoutes registers the login of global users. A successful login also
+// sets the session cookie of sessions, which lasts the browser session
+// unless the login asks to be remembered; nil sets none.
+func AddAuthRoutes(r *mux.Router, ep *endpoints.AuthEndpoint, sessions *sessionauth.SessionManager) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/login",
+			Endpoint: ep.Login,
+			Decode:   decodeLoginRequest,
+			Encode:   encodeLoginResponse(sessions),
+			Request:  endpoints.LoginRequest{},
+			Options:  append(defaultServerOptions(), kithttp.ServerBefore(withRequest)),
+			Errors: []*apierrors.Error{
+				projecttable.ErrInvalidProjectID,
+				endpoints.ErrInvalidCredentials,
+				endpoints.ErrProjectRequired,
+				errAccountNotActive,
+				roles.ErrIPNotAllowed,
+			},
+		},
+	})
 }
 
 func decodeLoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var request endpoints.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if err := decodeJSONBody(r, &request); err != nil {
 		return nil, err
 	}
 	return request, nil
-}
\ No newline at end of file
+}
+
+// requestKey is the context key of the request being served, for encoders
+// that need it
+type requestKey struct{}
+
+// withRequest stores the request in its own context
+func withRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// encodeLoginResponse sets the session cookie of the user who logged in and
+// writes the response. A token restricted to changing an expired password
+// gets no session.
+func encodeLoginResponse(sessions *sessionauth.SessionManager) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		login, ok := response.(endpoints.LoginResponse)
+		r, hasRequest := ctx.Value(requestKey{}).(*http.Request)
+		if ok && hasRequest && sessions != nil && !login.PasswordExpired {
+			userID, err := uuid.Parse(login.UserID)
+			if err != nil {
+				return err
+			}
+			if err := sessions.Login(ctx, w, r, &schemas.User{ID: userID}, login.Remember); err != nil {
+				return err
+			}
+		}
+		return encodeResponse(ctx, w, response)
+	}
+}
@@ -0,0 +1,38 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// authConfigMaxAge is how long browsers may reuse a login page configuration.
+// It is private to the browser: every response carries OAuth states of its
+// own, which must not be shared between users by a proxy.
+const authConfigMaxAge = "60"
+
+// AddAuthConfigRoutes registers the login page configuration route on the
+// /{projectId}/auth router
+func AddAuthConfigRoutes(r *mux.Router, ep *endpoints.AuthConfigEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/config",
+			Endpoint: ep.GetAuthConfig,
+			Decode:   decodeGetAuthConfigRequest,
+			Encode:   encodeAuthConfigResponse,
+			Request:  endpoints.GetAuthConfigRequest{},
+		},
+	})
+}
+
+func decodeGetAuthConfigRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.GetAuthConfigRequest{ProjectID: mux.Vars(r)["projectId"]}, nil
+}
+
+func encodeAuthConfigResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Cache-Control", "private, max-age="+authConfigMaxAge)
+	return encodeResponse(ctx, w, response)
+}
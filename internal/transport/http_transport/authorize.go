@@ -0,0 +1,84 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/ratelimit"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+var (
+	authorizeDuration = metrics.NewHistogram("ums_authorize_duration_seconds",
+		"Latency of authorization check requests.", metrics.DefaultBuckets)
+	authorizeDecisions = metrics.NewCounter("ums_authorize_decisions_total",
+		"Authorization checks answered, by decision.", "decision")
+)
+
+// AddAuthorizeRoutes registers the authorization check on the API router.
+// Checks are rate limited per project by limiter, when set.
+func AddAuthorizeRoutes(r *mux.Router, ep *endpoints.AuthorizeEndpoint, limiter *ratelimit.Limiter) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/{projectId}/authorize",
+			Endpoint: ep.Authorize,
+			Decode:   decodeAuthorizeRequest,
+			Encode:   encodeAuthorizeResponse,
+			Request:  endpoints.AuthorizeRequest{},
+			Wrap: func(next http.Handler) http.Handler {
+				return ObserveDuration(authorizeDuration)(RateLimit(limiter, projectKey)(next))
+			},
+			Errors: []*apierrors.Error{
+				apitokens.ErrInvalidToken,
+				ErrRateLimited,
+				errUnknownResource,
+				errUnknownAction,
+				apierrors.NotFound("user not found"),
+			},
+		},
+	})
+}
+
+// projectKey rate limits by the project in the path
+func projectKey(r *http.Request) string {
+	return mux.Vars(r)["projectId"]
+}
+
+// ObserveDuration records how long each request takes
+func ObserveDuration(h *metrics.Histogram) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			defer func() { h.Observe(time.Since(start).Seconds()) }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func decodeAuthorizeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var req endpoints.AuthorizeRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ProjectID = projectId
+	req.APIToken, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return req, nil
+}
+
+func encodeAuthorizeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if resp, ok := response.(endpoints.AuthorizeResponse); ok {
+		authorizeDecisions.Inc(resp.Decision)
+	}
+	return encodeResponse(ctx, w, response)
+}
@@ -0,0 +1,78 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/changefeed"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddUserChangeRoutes adds the user change feed to the project user router.
+// It must be registered before the project user routes so "/changes" isn't
+// taken as a user ID.
+func AddUserChangeRoutes(r *mux.Router, ep *endpoints.ChangesEndpoint) {
+	mount(r, []Route{
+		// GET - Read the changes after a cursor, waiting for one if need be
+		{
+			Method:       "GET",
+			Path:         "/changes",
+			Endpoint:     ep.GetUserChanges,
+			Decode:       decodeGetUserChangesRequest,
+			Encode:       encodeResponse,
+			Request:      endpoints.GetUserChangesRequest{},
+			Wrap:         longPoll(ep.MaxWait),
+			Requires:     Requirement{Resource: "users", Action: "read"},
+			ExampleQuery: "wait=30s&limit=100",
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("INVALID_QUERY", "wait must be a duration such as 30s"),
+				changefeed.ErrInvalidCursor,
+			},
+		},
+	})
+}
+
+func decodeGetUserChangesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	q := r.URL.Query()
+	request := endpoints.GetUserChangesRequest{
+		ProjectID: projectID,
+		Since:     q.Get("since"),
+	}
+	if wait := q.Get("wait"); wait != "" {
+		// A bare number is taken as seconds
+		if seconds, err := strconv.Atoi(wait); err == nil {
+			request.Wait = time.Duration(seconds) * time.Second
+		} else if request.Wait, err = time.ParseDuration(wait); err != nil {
+			return nil, apierrors.BadRequest("INVALID_QUERY", "wait must be a duration such as 30s")
+		}
+		if request.Wait < 0 {
+			return nil, apierrors.BadRequest("INVALID_QUERY", "wait must not be negative")
+		}
+	}
+	if request.Limit, err = queryInt(q.Get("limit")); err != nil {
+		return nil, apierrors.BadRequest("INVALID_QUERY", "limit must be an integer")
+	}
+	return request, nil
+}
+
+// longPoll lets a route hold its response for up to maxWait, extending the
+// server's write timeout for it
+func longPoll(maxWait time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Writers that cannot extend their deadline keep the server's
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(maxWait + 15*time.Second))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,31 +3,153 @@ package http_transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/logging"
 )
 
+var log = logging.Named("http")
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	Field     string `json:"field,omitempty"`      // JSON path of the request field at fault, when there is one
+	RequestID string `json:"request_id,omitempty"` // Only on INTERNAL errors, for quoting in reports
+}
+
+// streamer is a response that writes its own body, such as a file download,
+// instead of being encoded as JSON
+type streamer interface {
+	StreamTo(w io.Writer) error
 }
 
-// encodeResponse encodes the response as JSON
+// encodeResponse writes an endpoint's response. Responses implementing
+// kithttp.Headerer set their headers and those implementing
+// kithttp.StatusCoder choose their status code (e.g. 202 Accepted); bulk
+// endpoints return endpoints.MultiStatusResponse, which is 207 Multi-Status
+// when some of its items failed. A streamer writes its own body; anything
+// else is encoded as JSON, before the status is written so that a response
+// that cannot be encoded still answers 500. Once a stream has begun its
+// status cannot change, so a failing stream is only logged.
+// Endpoints with nothing to return return endpoints.NoContentResponse, for
+// 204 No Content; a nil response is a bug and answers 500. List endpoints
+// always encode an empty result as 200 with an empty array.
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if response == nil {
-		w.WriteHeader(http.StatusNoContent)
+		log.For(ctx).Errorf("%s returned a nil response; return endpoints.NoContentResponse for 204", routeName(ctx))
+		return errors.New("internal server error")
+	}
+
+	status := http.StatusOK
+	if sc, ok := response.(kithttp.StatusCoder); ok {
+		status = sc.StatusCode()
+	}
+	if h, ok := response.(kithttp.Headerer); ok {
+		for key, values := range h.Headers() {
+			w.Header()[key] = values
+		}
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
 		return nil
 	}
-	return json.NewEncoder(w).Encode(response)
+
+	if s, ok := response.(streamer); ok {
+		w.WriteHeader(status)
+		if err := s.StreamTo(w); err != nil {
+			log.For(ctx).Errorf("%s failed to stream its response: %v", routeName(ctx), err)
+		}
+		return nil
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.For(ctx).Errorf("%s failed to encode its response: %v", routeName(ctx), err)
+		return errors.New("internal server error")
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+	return nil
 }
 
-// encodeError encodes an error response
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+// routeName names the declared route being served, for logs
+func routeName(ctx context.Context) string {
+	d, ok := ctx.Value(declaredRouteKey{}).(*declaredRoute)
+	if !ok {
+		return "route"
+	}
+	return d.route.Method + " " + d.template
+}
+
+// encodeError encodes an error response. Errors from the apierrors package
+// carry their own status and code; anything else is a 500. Codes the route
+// being served does not declare are reported, as the error catalog misses
+// them.
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	status := http.StatusInternalServerError
+	resp := ErrorResponse{Error: err.Error()}
+
+	var apiErr *apierrors.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode()
+		resp.Code = apiErr.Code
+		resp.Field = apiErr.Field
+		reportUndeclared(ctx, apiErr)
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ErrRequestBodyRequired is returned by decoders when a request that needs a
+// JSON body arrives without one
+var ErrRequestBodyRequired = apierrors.BadRequest("REQUEST_BODY_REQUIRED", "request body required")
+
+// decodeJSONBody decodes the JSON request body into v. An empty body is a
+// 400 "request body required" and malformed JSON a 400 "invalid request
+// body", rather than surfacing the decoder error as a 500. The error names
+// the offending field or byte offset but never quotes the body, since the
+// message is logged and bodies carry emails and passwords.
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ErrRequestBodyRequired
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrRequestBodyRequired
+		}
+		return apierrors.BadRequest("INVALID_REQUEST_BODY", "invalid request body: "+describeJSONError(err))
+	}
+	return nil
+}
+
+// describeJSONError describes a decoding error by position and field name
+// only. The decoder's own messages may quote parts of the input.
+func describeJSONError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("field %q has the wrong type at offset %d", typeErr.Field, typeErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("value has the wrong type at offset %d", typeErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected end of JSON"
+	default:
+		return "malformed JSON"
+	}
 }
 
 // defaultServerOptions returns the default server options
@@ -35,4 +157,4 @@ func defaultServerOptions() []kithttp.ServerOption {
 	return []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 	}
-}
\ No newline at end of file
+}
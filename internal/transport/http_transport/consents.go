@@ -0,0 +1,125 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+)
+
+// AddConsentRoutes registers the routes recording a project user's consents
+// on the /{projectId}/users router
+func AddConsentRoutes(r *mux.Router, ep *endpoints.ConsentsEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/{user_id}/consents",
+			Endpoint: ep.AcceptConsent,
+			Decode:   decodeAcceptConsentRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.AcceptConsentRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				projectusers.ErrUserNotFound,
+				consents.ErrUnknownDocument,
+				consents.ErrVersionMismatch,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/{user_id}/consents",
+			Endpoint: ep.ListConsents,
+			Decode:   decodeListConsentsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListConsentsRequest{},
+			Requires: Requirement{Resource: "users", Action: "read"},
+			Errors: []*apierrors.Error{
+				projectusers.ErrUserNotFound,
+			},
+		},
+	})
+}
+
+// AddMyConsentRoutes registers the routes a signed-in user records its own
+// consents with on the /me router. They stay open to users whose project
+// enforces consents they have yet to give.
+func AddMyConsentRoutes(r *mux.Router, ep *endpoints.ConsentsEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/consents",
+			Endpoint: ep.AcceptMyConsent,
+			Decode:   decodeAcceptMyConsentRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.AcceptMyConsentRequest{},
+			Requires: SignedInToConsent,
+			Errors: []*apierrors.Error{
+				endpoints.ErrNotSignedIn,
+				consents.ErrUnknownDocument,
+				consents.ErrVersionMismatch,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/consents",
+			Endpoint: ep.ListMyConsents,
+			Decode:   decodeListMyConsentsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListMyConsentsRequest{},
+			Requires: SignedInToConsent,
+			Errors: []*apierrors.Error{
+				endpoints.ErrNotSignedIn,
+			},
+		},
+	})
+}
+
+func decodeAcceptConsentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var req endpoints.AcceptConsentRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ProjectID = projectID
+	req.UserID = mux.Vars(r)["user_id"]
+	return req, nil
+}
+
+func decodeListConsentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListConsentsRequest{
+		ProjectID: projectID,
+		UserID:    mux.Vars(r)["user_id"],
+	}, nil
+}
+
+func decodeAcceptMyConsentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.AcceptMyConsentRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	if user, ok := r.Context().Value(auth.UserContextKey).(schemas.User); ok {
+		req.User = &user
+	}
+	return req, nil
+}
+
+func decodeListMyConsentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ListMyConsentsRequest
+	if user, ok := r.Context().Value(auth.UserContextKey).(schemas.User); ok {
+		req.User = &user
+	}
+	return req, nil
+}
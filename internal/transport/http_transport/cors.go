@@ -0,0 +1,106 @@
+package http_transport
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// corsMaxAge is how long browsers may cache a preflight answer
+const corsMaxAge = 10 * time.Minute
+
+// Headers browsers are allowed to send and to read on cross-origin requests
+var (
+	corsAllowedHeaders = strings.Join([]string{"Authorization", "Content-Type", "Accept-Language", requestid.Header}, ", ")
+	corsExposedHeaders = strings.Join([]string{requestid.Header, "Location", "Retry-After", "Deprecation", "Sunset", "Link"}, ", ")
+)
+
+// CORS lets browsers call the API from the given origins, and, on routes
+// under {projectId}, also from the origins in the project's cors settings.
+// It wraps the whole router, which it uses to find the route of a request,
+// so that preflight requests are answered for routes that do not accept
+// OPTIONS. Requests from other origins are passed on without CORS headers,
+// which makes browsers refuse them.
+func CORS(origins []string, router *mux.Router, manager projects.ProjectManager) mux.MiddlewareFunc {
+	global := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		global[strings.ToLower(origin)] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+
+			method := r.Method
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				method = r.Header.Get("Access-Control-Request-Method")
+			}
+			var match mux.RouteMatch
+			if !router.Match(withMethod(r, method), &match) || match.MatchErr != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !global[strings.ToLower(origin)] && !projectAllowsOrigin(r, match.Vars["projectId"], manager, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			if !preflight {
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", method)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// withMethod returns the request as if it had been made with method, for
+// matching the route a preflight request asks about
+func withMethod(r *http.Request, method string) *http.Request {
+	if r.Method == method {
+		return r
+	}
+	clone := r.Clone(r.Context())
+	clone.Method = method
+	return clone
+}
+
+// projectAllowsOrigin reports whether the project a route is under lists
+// origin in its cors settings. Unknown projects allow nothing; the route
+// answers 404 for them.
+func projectAllowsOrigin(r *http.Request, rawID string, manager projects.ProjectManager, origin string) bool {
+	if rawID == "" {
+		return false
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return false
+	}
+	project, err := manager.ResolveProject(r.Context(), id)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range project.Settings.CORS.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package http_transport_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestCORSAllowsAProjectsOriginsOnlyOnItsOwnRoutes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	shop := testutil.AProject().Build(t, db).Project
+	other := testutil.AProject().Build(t, db).Project
+	const (
+		shopOrigin   = "https://shop.example.com"
+		globalOrigin = "https://admin.example.com"
+	)
+	settings := schemas.ProjectSettings{CORS: schemas.CORSSettings{AllowedOrigins: []string{shopOrigin}}}
+	if _, err := manager.UpdateProjectSettings(context.Background(), shop.ID, settings); err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/{projectId}/auth/magic-link", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodGet)
+	handler := http_transport.CORS([]string{globalOrigin}, router, manager)(router)
+
+	magicLink := func(id string) string { return "/api/v1/" + id + "/auth/magic-link" }
+	for _, tc := range []struct {
+		name    string
+		method  string
+		path    string
+		origin  string
+		allowed bool
+	}{
+		{"project origin on its own route", http.MethodPost, magicLink(shop.ID.String()), shopOrigin, true},
+		{"project origin on another project's route", http.MethodPost, magicLink(other.ID.String()), shopOrigin, false},
+		{"project origin on a route without a project", http.MethodGet, "/api/v1/projects", shopOrigin, false},
+		{"global origin on a project's route", http.MethodPost, magicLink(other.ID.String()), globalOrigin, true},
+		{"global origin on a route without a project", http.MethodGet, "/api/v1/projects", globalOrigin, true},
+		{"unknown origin", http.MethodPost, magicLink(shop.ID.String()), "https://evil.example.com", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := ""
+			if tc.allowed {
+				want = tc.origin
+			}
+
+			actual := httptest.NewRequest(tc.method, tc.path, nil)
+			actual.Header.Set("Origin", tc.origin)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, actual)
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != want {
+				t.Errorf("%s %s: Access-Control-Allow-Origin = %q, want %q", tc.method, tc.path, got, want)
+			}
+			if recorder.Code != http.StatusNoContent {
+				t.Errorf("%s %s = %d, want the route to be served either way", tc.method, tc.path, recorder.Code)
+			}
+
+			preflight := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+			preflight.Header.Set("Origin", tc.origin)
+			preflight.Header.Set("Access-Control-Request-Method", tc.method)
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, preflight)
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != want {
+				t.Errorf("preflight of %s %s: Access-Control-Allow-Origin = %q, want %q", tc.method, tc.path, got, want)
+			}
+			if tc.allowed && (recorder.Code != http.StatusNoContent || recorder.Header().Get("Access-Control-Allow-Methods") != tc.method) {
+				t.Errorf("preflight of %s %s = %d allowing %q, want 204 allowing %s", tc.method, tc.path,
+					recorder.Code, recorder.Header().Get("Access-Control-Allow-Methods"), tc.method)
+			}
+		})
+	}
+
+	for _, origin := range []string{"shop.example.com", "https://shop.example.com/app", "ftp://shop.example.com"} {
+		settings := schemas.ProjectSettings{CORS: schemas.CORSSettings{AllowedOrigins: []string{origin}}}
+		_, err := manager.UpdateProjectSettings(context.Background(), shop.ID, settings)
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_CORS_SETTINGS" {
+			t.Errorf("allowing origin %q: err = %v, want INVALID_CORS_SETTINGS", origin, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package http_transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/metrics"
+)
+
+// Deprecation marks a route that is kept working for existing clients but
+// has a successor they should move to
+type Deprecation struct {
+	// Since is when the route was deprecated
+	Since time.Time
+	// Successor is the path of the route replacing it, on the same router
+	// and with the same path variables
+	Successor string
+}
+
+// deprecatedRequests counts the requests served by deprecated routes, so
+// they can be retired once clients have moved off them
+var deprecatedRequests = metrics.NewCounter("ums_deprecated_requests_total",
+	"Requests served by deprecated routes, by route.", "method", "path")
+
+// wrap serves a deprecated route mounted at template, announcing it in the
+// Deprecation header (RFC 9745) with a Link to the successor and counting
+// the request. prefix is the path the route's router is mounted at.
+func (d *Deprecation) wrap(method, template, prefix string) mux.MiddlewareFunc {
+	since := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deprecatedRequests.Inc(method, template)
+
+			vars := mux.Vars(r)
+			successor := pathVar.ReplaceAllStringFunc(prefix+d.Successor, func(v string) string {
+				return vars[pathVar.FindStringSubmatch(v)[1]]
+			})
+			w.Header().Set("Deprecation", since)
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deprecatedAt returns a copy of route served at the legacy path, deprecated
+// since the given time in favor of route
+func deprecatedAt(route Route, path string, since time.Time) Route {
+	route.Deprecated = &Deprecation{Since: since, Successor: route.Path}
+	route.Path = path
+	return route
+}
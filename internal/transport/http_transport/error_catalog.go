@@ -0,0 +1,158 @@
+package http_transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/roles"
+	"k8s.io/klog/v2"
+)
+
+// undeclaredErrors counts errors answered by routes that do not declare them,
+// which the error catalog therefore misses
+var undeclaredErrors = metrics.NewCounter("ums_undeclared_errors_total",
+	"API errors answered by a route whose declaration does not list them, by route and code.", "method", "path", "code")
+
+// errInvalidRequestBody is an example of the error decodeJSONBody returns for
+// a malformed body
+var errInvalidRequestBody = apierrors.BadRequest("INVALID_REQUEST_BODY", "invalid request body: malformed JSON at offset 1")
+
+// Examples of errors whose message depends on the request, for declaring
+// them on routes
+var (
+	errUnknownResource = apierrors.BadRequest("UNKNOWN_RESOURCE", `unknown resource "rols"; did you mean "roles"?`)
+	errUnknownAction   = apierrors.BadRequest("UNKNOWN_ACTION", `unknown action "raed" for resource "roles"; did you mean "read"?`)
+	errIDsRequired     = apierrors.BadRequest("IDS_REQUIRED", "ids must name at least one item")
+	errTooManyItems    = apierrors.BadRequest("TOO_MANY_ITEMS", fmt.Sprintf("at most %d items can be changed at once", endpoints.MaxBulkItems))
+	errInvalidCIDR     = apierrors.BadRequest("INVALID_CIDR", "invalid CIDR 10.0.0.0/33; use a block such as 10.0.0.0/8 or 2001:db8::/32")
+
+	errInvalidStatusTransition = apierrors.New(http.StatusConflict, "INVALID_STATUS_TRANSITION", "cannot change status from deactivated to invited")
+	errConcurrentStatusChange  = apierrors.Conflict("user status was changed concurrently")
+	errAccountNotActive        = apierrors.New(http.StatusForbidden, userstatus.LoginErrorCode, "account is suspended")
+)
+
+// commonErrors may be answered by any route, before it is matched
+var commonErrors = []*apierrors.Error{ErrServerBusy}
+
+// impliedErrors returns the errors a route mounted at template may answer
+// with besides those it declares: the network check of signed in routes, the
+// project lookup of {projectId} routes, and the errors of request bodies, include_deleted and sort for the
+// decoders that read them, found by decoding requests without a body and
+// with a malformed include_deleted or sort
+func (route Route) impliedErrors(template string) []*apierrors.Error {
+	var implied []*apierrors.Error
+	if !route.Requires.Public() {
+		implied = append(implied, roles.ErrIPNotAllowed)
+	}
+	if strings.Contains(template, "{projectId}") {
+		implied = append(implied, projects.ErrProjectNotFound)
+	}
+	if _, err := route.decodeExample(template, route.ExampleQuery, ""); errors.Is(err, ErrRequestBodyRequired) {
+		implied = append(implied, ErrRequestBodyRequired, errInvalidRequestBody)
+	}
+	query := "include_deleted=maybe"
+	if route.ExampleQuery != "" {
+		query = route.ExampleQuery + "&" + query
+	}
+	if _, err := route.decodeExample(template, query, route.ExampleBody); errors.Is(err, ErrInvalidIncludeDeleted) {
+		implied = append(implied, ErrInvalidIncludeDeleted)
+	}
+	query = "sort=-"
+	if route.ExampleQuery != "" {
+		query = route.ExampleQuery + "&" + query
+	}
+	var apiErr *apierrors.Error
+	if _, err := route.decodeExample(template, query, route.ExampleBody); errors.As(err, &apiErr) && apiErr.Code == sorting.ErrUnknownField.Code {
+		implied = append(implied, sorting.ErrUnknownField)
+	}
+	return implied
+}
+
+// catalogErrors returns every error the route may answer with, once each
+func (route Route) catalogErrors(template string) []*apierrors.Error {
+	var all []*apierrors.Error
+	seen := make(map[apierrors.Error]bool)
+	for _, err := range append(route.impliedErrors(template), route.Errors...) {
+		if !seen[*err] {
+			seen[*err] = true
+			all = append(all, err)
+		}
+	}
+	return all
+}
+
+// declares reports whether the route lists an error with code among those it
+// declares or implies
+func (d *declaredRoute) declares(code string) bool {
+	for _, err := range d.route.Errors {
+		if err.Code == code {
+			return true
+		}
+	}
+	d.impliedOnce.Do(func() { d.implied = d.route.impliedErrors(d.template) })
+	for _, err := range d.implied {
+		if err.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// reportUndeclared counts and logs an API error answered by the route being
+// served when the route does not declare it
+func reportUndeclared(ctx context.Context, apiErr *apierrors.Error) {
+	d, ok := ctx.Value(declaredRouteKey{}).(*declaredRoute)
+	if !ok || d.declares(apiErr.Code) {
+		return
+	}
+	undeclaredErrors.Inc(d.route.Method, d.template, apiErr.Code)
+	klog.Warningf("%s %s answered with undeclared error code %s; add it to the route's Errors", d.route.Method, d.template, apiErr.Code)
+}
+
+// ErrorCatalog returns the API errors of every declared route registered on
+// r, in registration order, and those any route may answer with
+func ErrorCatalog(r *mux.Router) (*endpoints.ErrorCatalogResponse, error) {
+	catalog := &endpoints.ErrorCatalogResponse{Common: errorInfos(commonErrors)}
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		declared, ok := route.GetHandler().(*declaredRoute)
+		if !ok {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		catalog.Routes = append(catalog.Routes, endpoints.RouteErrors{
+			Method: declared.route.Method,
+			Path:   template,
+			Errors: errorInfos(declared.route.catalogErrors(template)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking routes: %w", err)
+	}
+	return catalog, nil
+}
+
+func errorInfos(errs []*apierrors.Error) []endpoints.ErrorInfo {
+	infos := make([]endpoints.ErrorInfo, len(errs))
+	for i, err := range errs {
+		infos[i] = endpoints.ErrorInfo{
+			Status:  err.Status,
+			Code:    err.Code,
+			Example: endpoints.ErrorExample{Error: err.Message, Code: err.Code},
+		}
+	}
+	return infos
+}
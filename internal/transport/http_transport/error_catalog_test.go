@@ -0,0 +1,115 @@
+package http_transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+var (
+	errDeclared   = apierrors.Conflict("declared by the route")
+	errUndeclared = apierrors.BadRequest("NOT_IN_THE_CATALOG", "missing from the route's declaration")
+)
+
+// catalogRouter mounts a route declaring errDeclared whose endpoint answers
+// with the error its {id} names
+func catalogRouter() *mux.Router {
+	router := mux.NewRouter()
+	http_transport.Mount(router, []http_transport.Route{{
+		Method: "GET",
+		Path:   "/{projectId}/things/{id}",
+		Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+			switch request.(exampleRequest).ID {
+			case "declared":
+				return nil, errDeclared
+			case "implied":
+				return nil, projects.ErrProjectNotFound
+			case "undeclared":
+				return nil, errUndeclared
+			}
+			return map[string]string{}, nil
+		},
+		Decode:  decodeExample,
+		Encode:  func(_ context.Context, w http.ResponseWriter, _ interface{}) error { return nil },
+		Request: exampleRequest{},
+		Errors:  []*apierrors.Error{errDeclared},
+	}})
+	return router
+}
+
+func TestUndeclaredErrorsAreReported(t *testing.T) {
+	router := catalogRouter()
+	sample := func(code string) string {
+		return `ums_undeclared_errors_total{method="GET",path="/{projectId}/things/{id}",code="` + code + `"}`
+	}
+
+	for _, tc := range []struct {
+		id       string
+		code     string
+		reported bool
+	}{
+		{"declared", errDeclared.Code, false},
+		{"implied", projects.ErrProjectNotFound.Code, false},
+		{"undeclared", errUndeclared.Code, true},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			logs := testutil.CaptureKlog(t)
+			before := testutil.MetricValue(t, sample(tc.code))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/project/things/"+tc.id, nil))
+			if !strings.Contains(recorder.Body.String(), tc.code) {
+				t.Fatalf("response = %d %s, want %s", recorder.Code, recorder.Body, tc.code)
+			}
+
+			want := 0.0
+			if tc.reported {
+				want = 1
+			}
+			if got := testutil.MetricValue(t, sample(tc.code)) - before; got != want {
+				t.Errorf("ums_undeclared_errors_total rose by %v, want reported %v", got, tc.reported)
+			}
+			if warned := strings.Contains(logs.String(), "undeclared error code "+tc.code); warned != tc.reported {
+				t.Errorf("warned %v, want %v; logs:\n%s", warned, tc.reported, logs.String())
+			}
+		})
+	}
+}
+
+func TestErrorCatalogListsDeclaredAndImpliedErrors(t *testing.T) {
+	catalog, err := http_transport.ErrorCatalog(catalogRouter())
+	if err != nil {
+		t.Fatalf("ErrorCatalog: %v", err)
+	}
+	if len(catalog.Common) == 0 {
+		t.Error("the catalog lists no errors common to every route")
+	}
+	if len(catalog.Routes) != 1 || catalog.Routes[0].Path != "/{projectId}/things/{id}" {
+		t.Fatalf("routes = %+v, want the one mounted", catalog.Routes)
+	}
+
+	codes := make(map[string]int)
+	for _, info := range catalog.Routes[0].Errors {
+		codes[info.Code] = info.Status
+		if info.Example.Code != info.Code || info.Example.Error == "" {
+			t.Errorf("the example of %s is %+v", info.Code, info.Example)
+		}
+	}
+	if codes[errDeclared.Code] != http.StatusConflict {
+		t.Errorf("catalog = %v, want the declared %s with 409", codes, errDeclared.Code)
+	}
+	if codes[projects.ErrProjectNotFound.Code] != http.StatusNotFound {
+		t.Errorf("catalog = %v, want the project lookup of {projectId} routes", codes)
+	}
+	if _, ok := codes[errUndeclared.Code]; ok {
+		t.Errorf("catalog = %v, lists an error the route does not declare", codes)
+	}
+}
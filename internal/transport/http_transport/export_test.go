@@ -0,0 +1,15 @@
+package http_transport
+
+import "github.com/gorilla/mux"
+
+// RouteTables exposes routeTables to the external tests
+var RouteTables = routeTables
+
+// Mount exposes mount to the external tests, which declare broken routes
+// of their own
+func Mount(r *mux.Router, routes []Route) {
+	mount(r, routes)
+}
+
+// EncodeResponse exposes encodeResponse to the external tests
+var EncodeResponse = encodeResponse
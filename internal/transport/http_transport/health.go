@@ -0,0 +1,38 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddHealthRoutes registers the liveness and readiness probes on the root
+// router
+func AddHealthRoutes(r *mux.Router, ep *endpoints.HealthEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/healthz",
+			Endpoint: ep.Live,
+			Decode:   decodeHealthRequest,
+			Encode:   encodeResponse,
+		},
+		{
+			Method:   "GET",
+			Path:     "/readyz",
+			Endpoint: ep.Ready,
+			Decode:   decodeHealthRequest,
+			Encode:   encodeResponse,
+			Errors: []*apierrors.Error{
+				endpoints.ErrNotReady,
+			},
+		},
+	})
+}
+
+func decodeHealthRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
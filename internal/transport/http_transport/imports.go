@@ -0,0 +1,142 @@
+package http_transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// maxImportMemory is the amount of a multipart upload kept in memory before spilling to disk
+const maxImportMemory = 32 << 20
+
+// AddImportRoutes adds the asynchronous user import routes to the project user router.
+// They must be registered before the project user routes so "/import" isn't taken as a role ID.
+func AddImportRoutes(r *mux.Router, ep *endpoints.ImportsEndpoint) {
+	mount(r, []Route{
+		// POST - Upload a CSV and start an import job
+		{
+			Method:   "POST",
+			Path:     "/import",
+			Endpoint: ep.CreateImport,
+			Decode:   decodeCreateImportRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateImportRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+		},
+		// GET - Get the progress of an import job
+		{
+			Method:   "GET",
+			Path:     "/import/{jobId}",
+			Endpoint: ep.GetImport,
+			Decode:   decodeGetImportRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetImportRequest{},
+			Requires: Requirement{Resource: "users", Action: "read"},
+		},
+		// GET - Download the error report of a finished import job
+		{
+			Method:   "GET",
+			Path:     "/import/{jobId}/errors",
+			Endpoint: ep.GetImportErrorReport,
+			Decode:   decodeGetImportErrorReportRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetImportErrorReportRequest{},
+			Requires: Requirement{Resource: "users", Action: "read"},
+		},
+		// POST - Cancel an import job
+		{
+			Method:   "POST",
+			Path:     "/import/{jobId}/cancel",
+			Endpoint: ep.CancelImport,
+			Decode:   decodeCancelImportRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CancelImportRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+		},
+	})
+}
+
+// decodeCreateImportRequest accepts either a multipart upload with a "file" field or a raw CSV body
+func decodeCreateImportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportMemory); err != nil {
+			return nil, errors.New("invalid multipart upload")
+		}
+		upload, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("missing file field")
+		}
+		file = upload
+	}
+
+	return endpoints.CreateImportRequest{
+		ProjectID: projectID,
+		File:      file,
+	}, nil
+}
+
+func decodeGetImportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	jobID, ok := mux.Vars(r)["jobId"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	return endpoints.GetImportRequest{
+		ProjectID: projectID,
+		JobID:     jobID,
+	}, nil
+}
+
+func decodeCancelImportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	jobID, ok := mux.Vars(r)["jobId"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	return endpoints.CancelImportRequest{
+		ProjectID: projectID,
+		JobID:     jobID,
+	}, nil
+}
+
+func decodeGetImportErrorReportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	jobID, ok := mux.Vars(r)["jobId"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	return endpoints.GetImportErrorReportRequest{
+		ProjectID: projectID,
+		JobID:     jobID,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package http_transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddIntrospectionRoutes registers token introspection on the /auth router
+// of the API, for gateways validating the tokens they are presented
+func AddIntrospectionRoutes(r *mux.Router, ep *endpoints.IntrospectionEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/introspect-batch",
+			Endpoint: ep.IntrospectBatch,
+			Decode:   decodeIntrospectBatchRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.IntrospectBatchRequest{},
+			Requires: Requirement{Resource: "tokens", Action: "introspect"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("TOKENS_REQUIRED", "tokens must name at least one token"),
+				apierrors.BadRequest("TOO_MANY_TOKENS", fmt.Sprintf("at most %d tokens can be introspected at once", endpoints.DefaultIntrospectionBatch)),
+			},
+		},
+	})
+}
+
+func decodeIntrospectBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.IntrospectBatchRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
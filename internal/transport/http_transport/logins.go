@@ -0,0 +1,45 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddLoginHistoryRoutes registers login history routes on the
+// /{projectId}/users router
+func AddLoginHistoryRoutes(r *mux.Router, ep *endpoints.LoginsEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/{user_id}/logins",
+			Endpoint: ep.ListLogins,
+			Decode:   decodeListLoginsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListLoginsRequest{},
+			Requires: Requirement{Resource: "users", Action: "read"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("INVALID_PAGINATION", "limit must be an integer"),
+			},
+		},
+	})
+}
+
+func decodeListLoginsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		return nil, apierrors.BadRequest("INVALID_PAGINATION", "limit must be an integer")
+	}
+	return endpoints.ListLoginsRequest{
+		ProjectID: projectID,
+		UserID:    mux.Vars(r)["user_id"],
+		Limit:     limit,
+	}, nil
+}
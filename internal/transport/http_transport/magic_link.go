@@ -0,0 +1,62 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/magiclink"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+// AddMagicLinkRoutes registers the magic link login routes on the
+// /{projectId}/auth router
+func AddMagicLinkRoutes(r *mux.Router, ep *endpoints.MagicLinkEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/magic-link",
+			Endpoint: ep.RequestMagicLink,
+			Decode:   decodeRequestMagicLinkRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RequestMagicLinkRequest{},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("EMAIL_REQUIRED", "email is required"),
+				magiclink.ErrDisabled,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/magic-link/verify",
+			Endpoint: ep.VerifyMagicLink,
+			Decode:   decodeVerifyMagicLinkRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.VerifyMagicLinkRequest{},
+			Errors: []*apierrors.Error{
+				magiclink.ErrDisabled,
+				magiclink.ErrInvalidToken,
+				magiclink.ErrTooManyAttempts,
+				errAccountNotActive,
+				roles.ErrIPNotAllowed,
+			},
+		},
+	})
+}
+
+func decodeRequestMagicLinkRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.RequestMagicLinkRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = mux.Vars(r)["projectId"]
+	return request, nil
+}
+
+func decodeVerifyMagicLinkRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.VerifyMagicLinkRequest{
+		ProjectID: mux.Vars(r)["projectId"],
+		Token:     r.URL.Query().Get("token"),
+	}, nil
+}
@@ -0,0 +1,66 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// AddMeRoutes registers the routes a signed-in user calls about themselves
+func AddMeRoutes(r *mux.Router, ep *endpoints.MeEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/context",
+			Endpoint: ep.GetMyContext,
+			Decode:   decodeGetMyContextRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetMyContextRequest{},
+			Requires: SignedIn,
+			Errors: []*apierrors.Error{
+				endpoints.ErrNotSignedIn,
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/password",
+			Endpoint: ep.ChangeMyPassword,
+			Decode:   decodeChangeMyPasswordRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ChangeMyPasswordRequest{},
+			Requires: SignedInToChangePassword,
+			Errors: []*apierrors.Error{
+				endpoints.ErrNotSignedIn,
+				password.ErrTooShort,
+				password.ErrTooLong,
+				users.ErrPasswordReused,
+			},
+		},
+	})
+}
+
+func decodeGetMyContextRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.GetMyContextRequest
+	if user, ok := r.Context().Value(auth.UserContextKey).(schemas.User); ok {
+		req.User = &user
+	}
+	return req, nil
+}
+
+func decodeChangeMyPasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ChangeMyPasswordRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	if user, ok := r.Context().Value(auth.UserContextKey).(schemas.User); ok {
+		req.User = &user
+	}
+	return req, nil
+}
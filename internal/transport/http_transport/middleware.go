@@ -0,0 +1,418 @@
+package http_transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/apitokens"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/ratelimit"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/internal/version"
+	"github.com/yash3004/user_management_service/logins"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
+	"gorm.io/gorm"
+)
+
+// APIVersionHeader reports which API version served a response
+const APIVersionHeader = "X-API-Version"
+
+// APIVersion tags every response with the API version that served it
+func APIVersion(version string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Deprecated marks responses from a legacy route prefix with the Deprecation
+// and Sunset headers (RFC 9745, RFC 8594) and links to the successor prefix.
+// A zero sunset omits the Sunset header.
+func Deprecated(sunset time.Time, successor string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuditActor stores the authenticated caller in the request context so that
+// audit events record who made a change. Requests without a valid bearer
+// token are recorded as anonymous; authentication itself is enforced elsewhere.
+func AuditActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Project API tokens are not JWTs and are not tried as one
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && !strings.HasPrefix(token, apitokens.TokenPrefix) {
+			if userID, err := auth.ValidateToken(token); err == nil {
+				r = r.WithContext(audit.WithActor(r.Context(), userID.String()))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TrustedProxies are the networks of the load balancers and reverse proxies
+// in front of the service, whose X-Forwarded-For headers name the client
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses the CIDR blocks of the trusted proxies
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	trusted := make(TrustedProxies, 0, len(cidrs))
+	for _, raw := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", raw, err)
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+	return trusted, nil
+}
+
+func (t TrustedProxies) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP resolves the address of the client behind the trusted proxies.
+// It is the connection's remote address unless that is a trusted proxy;
+// then X-Forwarded-For is read from the right, as each proxy appends the
+// peer it got the request from, and the first address that is not a
+// trusted proxy is the client. The header is ignored from any other peer,
+// as the caller could have written it.
+func (t TrustedProxies) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !t.trusts(addr) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Whatever is left of a malformed entry cannot be vouched for
+			break
+		}
+		addr = hop.Unmap()
+		if !t.trusts(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+type clientIPContextKey struct{}
+
+// ClientIP stores the caller's IP address, as seen through the trusted
+// proxies, in the request context. The rate limits, the networks of roles
+// and the login history take the address from there, so it must run before
+// Authorization, which checks the networks.
+func ClientIP(trusted TrustedProxies) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPContextKey{}, trusted.clientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientInfo stores the caller's IP address and device headers in the request
+// context for the login history. The IP is the one ClientIP resolved.
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(logins.WithClient(r.Context(), logins.Client{
+			IPAddress:      clientIPKey(r),
+			UserAgent:      r.UserAgent(),
+			AcceptLanguage: r.Header.Get("Accept-Language"),
+		}))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ErrServerBusy is returned when every request slot is in use
+var ErrServerBusy = apierrors.New(http.StatusServiceUnavailable, "SERVER_BUSY", "server is at capacity, try again later")
+
+// ConcurrencyLimit caps the number of requests served at once. Requests over
+// the limit are turned away with 503 and a Retry-After header rather than
+// queued. A slot is freed when its handler returns, including by panicking.
+// A maxInFlight of zero or less disables the limit.
+func ConcurrencyLimit(maxInFlight int, retryAfter time.Duration) mux.MiddlewareFunc {
+	if maxInFlight <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	slots := make(chan struct{}, maxInFlight)
+	retrySeconds := strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds()))))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", retrySeconds)
+				encodeError(r.Context(), ErrServerBusy, w)
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ErrRateLimited is returned when a caller has used up its request allowance
+var ErrRateLimited = apierrors.New(http.StatusTooManyRequests, "RATE_LIMITED", "too many requests, try again later")
+
+// RateLimit turns away requests over limiter's allowance for their key with
+// 429 and a Retry-After header. A nil limiter allows everything.
+func RateLimit(limiter *ratelimit.Limiter, key func(*http.Request) string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := limiter.Allow(key(r)); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+				encodeError(r.Context(), ErrRateLimited, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIPKey rate limits by the client IP that ClientIP resolved, or by
+// the connection's remote address on requests it did not see
+func clientIPKey(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return TrustedProxies(nil).clientIP(r)
+}
+
+// allowedNetworks refuses signed in users calling from an address their
+// role does not allow, with 403 IP_NOT_ALLOWED. The role checked is the
+// user's global role and, on routes under a project the user is a member
+// of, the role it holds there too. It runs after AuthMiddleware, which
+// puts the user in the context. Like the rate limits it takes the client
+// IP that ClientIP resolved.
+func allowedNetworks(db *gorm.DB, networks *roles.Networks) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(auth.UserContextKey).(schemas.User)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip := clientIPKey(r)
+			roleIDs := []uuid.UUID{user.RoleId}
+			if projectID, err := uuid.Parse(mux.Vars(r)["projectId"]); err == nil && projectID != user.ProjectId {
+				// Non-members are refused by the policy check that follows
+				if roleID, err := users.ProjectRole(r.Context(), db, &user, projectID); err == nil {
+					roleIDs = append(roleIDs, roleID)
+				} else if !errors.Is(err, users.ErrNotProjectMember) {
+					encodeError(r.Context(), err, w)
+					return
+				}
+			}
+			for _, roleID := range roleIDs {
+				if err := networks.Check(r.Context(), roleID, ip); err != nil {
+					encodeError(r.Context(), err, w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ProjectUserFinder finds the project users that Self routes let through
+type ProjectUserFinder interface {
+	GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID, includeDeleted bool) (*models.DisplayUser, error)
+}
+
+// projectUserSelf lets the project user named by the path variable self
+// through to next, when the bearer token was issued to them for the project
+// of the path, they may still log in and their role allows the client IP.
+// Every other request goes to others.
+func projectUserSelf(finder ProjectUserFinder, networks *roles.Networks, self string, others http.Handler) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || strings.HasPrefix(token, apitokens.TokenPrefix) {
+				others.ServeHTTP(w, r)
+				return
+			}
+			claims, err := auth.ParseToken(token)
+			vars := mux.Vars(r)
+			if err != nil || claims.Scope != "" || claims.UserID.String() != vars[self] || claims.ProjectId.String() != vars["projectId"] {
+				others.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := finder.GetProjectUser(r.Context(), vars["projectId"], claims.UserID, false)
+			if errors.Is(err, projectusers.ErrUserNotFound) || errors.Is(err, projects.ErrProjectNotFound) {
+				others.ServeHTTP(w, r)
+				return
+			} else if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			}
+			if !userstatus.CanLogin(user.Status) {
+				http.Error(w, "User account is "+strings.ReplaceAll(user.Status, "_", " "), http.StatusForbidden)
+				return
+			}
+			if roleID, err := uuid.Parse(user.RoleID); err == nil {
+				if err := networks.Check(r.Context(), roleID, clientIPKey(r)); err != nil {
+					encodeError(r.Context(), err, w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(logging.WithValues(r.Context(), "user_id", user.ID)))
+		})
+	}
+}
+
+// requireConsents refuses signed in users whose primary project enforces
+// consents they have yet to give, with 451 CONSENT_REQUIRED. Service
+// accounts accept no documents and are let through. It runs after
+// AuthMiddleware, which puts the user in the context.
+func requireConsents(manager consents.ConsentManager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(auth.UserContextKey).(schemas.User)
+			if !ok || user.IsServiceAccount() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			enforced, err := manager.Enforced(r.Context(), user.ProjectId)
+			if errors.Is(err, projects.ErrProjectNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			} else if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			}
+			if enforced {
+				outstanding, err := manager.Outstanding(r.Context(), user.ProjectId, user.ID)
+				if err != nil {
+					encodeError(r.Context(), err, w)
+					return
+				}
+				if len(outstanding) > 0 {
+					encodeError(r.Context(), consents.ErrConsentRequired, w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ProjectContext resolves the {projectId} route variable to its project and
+// stores it in the request context, so handlers never see a project that does
+// not exist. Unknown, malformed and deleted project IDs are answered with 404
+// before the handler runs. Routes without a {projectId} variable pass through.
+// Projects are looked up with ResolveProject, which may serve them from its
+// cache for a few seconds after a change made on another instance.
+func ProjectContext(manager projects.ProjectManager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := mux.Vars(r)["projectId"]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				encodeError(r.Context(), projects.ErrProjectNotFound, w)
+				return
+			}
+			project, err := manager.ResolveProject(r.Context(), id)
+			if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(projects.WithProject(r.Context(), project)))
+		})
+	}
+}
+
+// ServerHeader names the service and its version in the Server header of
+// every response
+func ServerHeader(product string) mux.MiddlewareFunc {
+	value := product + "/" + version.Get().Version
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxRequestIDLength bounds request IDs accepted from callers
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID, stored in the request context and
+// echoed in the X-Request-ID response header. Subsystem loggers add it to
+// the lines they write for the request. A caller-supplied ID is kept
+// when it is short printable ASCII, so IDs from a proxy carry through;
+// anything else is replaced with a fresh UUID.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestid.Header, id)
+		ctx := logging.WithValues(requestid.WithID(r.Context(), id), "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
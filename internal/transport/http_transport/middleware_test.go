@@ -0,0 +1,170 @@
+package http_transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+	"github.com/yash3004/user_management_service/internal/version"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// blockingHandler holds each request until release is closed, reporting on
+// entered once it is being served. A request to /panic panics instead.
+type blockingHandler struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/panic" {
+		panic("handler failed")
+	}
+	h.entered <- struct{}{}
+	<-h.release
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func serve(handler http.Handler, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	const limit = 2
+	inner := &blockingHandler{entered: make(chan struct{}), release: make(chan struct{})}
+	handler := http_transport.ConcurrencyLimit(limit, 2500*time.Millisecond)(inner)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve(handler, "/").Code
+		}()
+		select {
+		case <-inner.entered:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("request %d was not served", i+1)
+		}
+	}
+
+	rejected := serve(handler, "/")
+	if rejected.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request %d got %d, want 503", limit+1, rejected.Code)
+	}
+	if got := rejected.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("Retry-After = %q, want the 2.5s rounded up to 3", got)
+	}
+	var body http_transport.ErrorResponse
+	if err := json.NewDecoder(rejected.Body).Decode(&body); err != nil || body.Code != "SERVER_BUSY" {
+		t.Errorf("body = %+v (%v), want SERVER_BUSY", body, err)
+	}
+
+	close(inner.release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusNoContent {
+			t.Errorf("an admitted request got %d, want 204", code)
+		}
+	}
+
+	// Every slot is free again, including those of panicking handlers
+	for i := 0; i < limit; i++ {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("the handler did not panic")
+				}
+			}()
+			serve(handler, "/panic")
+		}()
+	}
+	done := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		go func() { done <- serve(handler, "/").Code }()
+	}
+	for i := 0; i < limit; i++ {
+		<-inner.entered
+	}
+	for i := 0; i < limit; i++ {
+		if code := <-done; code != http.StatusNoContent {
+			t.Errorf("a request after the slots were freed got %d, want 204", code)
+		}
+	}
+}
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	inner := &blockingHandler{}
+	if handler := http_transport.ConcurrencyLimit(0, time.Second)(inner); handler != http.Handler(inner) {
+		t.Fatal("a limit of 0 wrapped the handler")
+	}
+}
+
+func TestProjectContext(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	live := testutil.AProject().Build(t, db).Project
+	deleted := testutil.AProject().Build(t, db).Project
+	if _, err := manager.DeleteProject(context.Background(), deleted.ID, nil); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+
+	var reached []uuid.UUID
+	router := mux.NewRouter()
+	router.Use(http_transport.ProjectContext(manager))
+	router.HandleFunc("/api/v1/{projectId}/users", func(w http.ResponseWriter, r *http.Request) {
+		project, ok := projects.ProjectFromContext(r.Context())
+		if !ok {
+			t.Error("the handler found no project in the context")
+			return
+		}
+		reached = append(reached, project.ID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.HandleFunc("/api/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		name string
+		path string
+		want int
+	}{
+		{"live project", "/api/v1/" + live.ID.String() + "/users", http.StatusNoContent},
+		{"unknown project", "/api/v1/" + uuid.NewString() + "/users", http.StatusNotFound},
+		{"malformed project ID", "/api/v1/not-a-uuid/users", http.StatusNotFound},
+		{"deleted project", "/api/v1/" + deleted.ID.String() + "/users", http.StatusNotFound},
+		{"route without a project", "/api/v1/projects", http.StatusNoContent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := serve(router, tc.path).Code; got != tc.want {
+				t.Fatalf("GET %s = %d, want %d", tc.path, got, tc.want)
+			}
+		})
+	}
+	if len(reached) != 1 || reached[0] != live.ID {
+		t.Errorf("the handler was reached for projects %v, want only the live one", reached)
+	}
+}
+
+func TestServerHeader(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	recorder := serve(http_transport.ServerHeader("user-management-service")(inner), "/")
+	if got, want := recorder.Header().Get("Server"), "user-management-service/"+version.Get().Version; got != want {
+		t.Fatalf("Server = %q, want %q", got, want)
+	}
+}
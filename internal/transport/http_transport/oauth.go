@@ -2,90 +2,97 @@ package http_transport
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/requestid"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/oauthlogin"
+	"github.com/yash3004/user_management_service/onetime"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/roles"
 	"k8s.io/klog/v2"
 )
 
-// OAuthState represents the data structure to be encoded in the OAuth state parameter
-type OAuthState struct {
-	ProjectID string `json:"project_id"`
-	RoleId    string `json:"role_id"`
-	Nonce     string `json:"nonce"` // For CSRF protection
-}
-
 func AddOAuthRoutes(r *mux.Router, ep *endpoints.OAuthEndpoint) {
-	r.Methods("GET").Path("/{projectId}/{roleId}/login/{provider}").Handler(kithttp.NewServer(
-		ep.Login,
-		decodeOAuthLoginRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("GET").Path("/callback/{provider}").Handler(kithttp.NewServer(
-		ep.Callback,
-		decodeOAuthCallbackRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-}
-
-// generateNonce creates a random string for CSRF protection
-func generateNonce(length int) (string, error) {
-	bytes := make([]byte, length)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
-	}
-	return base64.URLEncoding.EncodeToString(bytes), nil
-}
-
-// encodeOAuthState encodes project ID and other data into a secure state string
-func encodeOAuthState(projectID string, RoleId string) (string, error) {
-	// Generate a random nonce for CSRF protection
-	nonce, err := generateNonce(16)
-	if err != nil {
-		return "", err
-	}
-
-	// Create state object with project ID and nonce
-	stateObj := OAuthState{
-		ProjectID: projectID,
-		RoleId:    RoleId,
-		Nonce:     nonce,
-	}
-
-	// Serialize to JSON
-	stateJSON, err := json.Marshal(stateObj)
-	if err != nil {
-		return "", err
-	}
-
-	// Encode to base64 URL-safe string
-	return base64.URLEncoding.EncodeToString(stateJSON), nil
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/{projectId}/{roleId}/login/{provider}",
+			Endpoint: ep.Login,
+			Decode:   decodeOAuthLoginRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.OAuthLoginRequest{},
+			Errors: []*apierrors.Error{
+				endpoints.ErrProviderNotEnabled,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/callback/{provider}",
+			Endpoint: ep.Callback,
+			Decode:   decodeOAuthCallbackRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.OAuthCallbackRequest{},
+			Options:  []kithttp.ServerOption{kithttp.ServerErrorEncoder(encodeOAuthLoginError)},
+
+			ExampleQuery: "code=example&state=example",
+			Errors: []*apierrors.Error{
+				onetime.ErrInvalidToken,
+				projects.ErrProjectNotFound,
+				endpoints.ErrProviderNotEnabled,
+				oauth.ErrProviderUnavailable,
+				apierrors.New(http.StatusBadGateway, "OAUTH_PROVIDER_ERROR", "could not complete login with github"),
+				apierrors.BadRequest("INVALID_ROLE", "role 5f0c6b8e-3c1a-4f7e-9d1b-2a6f0e4c8b7d cannot be used to log in to this project"),
+				apierrors.New(http.StatusInternalServerError, "USER_CREATION_FAILED", "failed to create or update the user"),
+				oauth.ErrEmailDomainNotAllowed,
+				oauth.ErrEmailNotVerified,
+				oauth.ErrFieldTooLong,
+				errAccountNotActive,
+				roles.ErrIPNotAllowed,
+			},
+		},
+		// GET - The providers a login page for the project should show
+		{
+			Method:   "GET",
+			Path:     "/{projectId}/providers",
+			Endpoint: ep.ListProviders,
+			Decode:   decodeListOAuthProvidersRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListOAuthProvidersRequest{},
+		},
+	})
 }
 
-// decodeOAuthState decodes the state parameter back into structured data
-func decodeOAuthState(state string) (*OAuthState, error) {
-	// Decode from base64
-	stateJSON, err := base64.URLEncoding.DecodeString(state)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse JSON
-	var stateObj OAuthState
-	if err := json.Unmarshal(stateJSON, &stateObj); err != nil {
-		return nil, err
+// encodeOAuthLoginError maps the oauthlogin error types to API errors. Their
+// causes are logged by the service and not shown to the client.
+func encodeOAuthLoginError(ctx context.Context, err error, w http.ResponseWriter) {
+	var providerErr *oauthlogin.ProviderError
+	var roleErr *oauthlogin.RoleResolutionError
+	var userErr *oauthlogin.UserCreationError
+	var apiErr *apierrors.Error
+
+	switch {
+	case errors.Is(err, oauth.ErrProviderUnavailable):
+		err = oauth.ErrProviderUnavailable
+	case errors.As(err, &providerErr):
+		err = apierrors.New(http.StatusBadGateway, "OAUTH_PROVIDER_ERROR", "could not complete login with "+providerErr.Provider)
+	case errors.As(err, &roleErr):
+		err = apierrors.BadRequest("INVALID_ROLE", "role "+roleErr.RoleID.String()+" cannot be used to log in to this project")
+	case errors.As(err, &userErr):
+		if errors.As(userErr.Err, &apiErr) {
+			err = apiErr
+			break
+		}
+		klog.Errorf("OAuth login failed (request %s): %v", requestid.FromContext(ctx), redact.Error(err))
+		err = apierrors.New(http.StatusInternalServerError, "USER_CREATION_FAILED", "failed to create or update the user")
 	}
-
-	return &stateObj, nil
+	encodeError(ctx, err, w)
 }
 
 // decodeOAuthLoginRequest decodes the OAuth login request
@@ -107,21 +114,17 @@ func decodeOAuthLoginRequest(_ context.Context, r *http.Request) (interface{}, e
 		return nil, err
 	}
 
-	// Encode project ID into state parameter
-	state, err := encodeOAuthState(projectID, roleID)
-	if err != nil {
-		klog.Errorf("Error encoding OAuth state: %v", err)
-		return nil, err
-	}
-
 	return endpoints.OAuthLoginRequest{
 		Provider:  provider,
 		ProjectID: projectID,
-		State:     state,
 		RoleID:    roleID,
 	}, nil
 }
 
+func decodeListOAuthProvidersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.ListOAuthProvidersRequest{ProjectID: mux.Vars(r)["projectId"]}, nil
+}
+
 func decodeOAuthCallbackRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	provider, ok := vars["provider"]
@@ -140,24 +143,9 @@ func decodeOAuthCallbackRequest(_ context.Context, r *http.Request) (interface{}
 		return nil, errors.New("missing state parameter")
 	}
 
-	// Decode the state to extract the project ID
-	stateObj, err := decodeOAuthState(state)
-	if err != nil {
-		klog.Errorf("Error decoding OAuth state: %v", err)
-		return nil, errors.New("invalid state parameter")
-	}
-
-	// Extract the project ID from the state
-	projectID := stateObj.ProjectID
-
 	return endpoints.OAuthCallbackRequest{
-		Provider:  provider,
-		ProjectID: projectID,
-		Code:      code,
-		State:     state, // Pass original state for verification if needed
-		RoleID:    stateObj.RoleId,
+		Provider: provider,
+		Code:     code,
+		State:    state,
 	}, nil
 }
-
-// In your main code where you handle the OAuth response, ensure your OAuthEndpoint.Login
-// uses the state that was generated in decodeOAuthLoginRequest
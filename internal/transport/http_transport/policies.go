@@ -4,56 +4,292 @@ import (
 	"context"
 	"net/http"
 
-	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 
+	"github.com/yash3004/user_management_service/internal/apierrors"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/policies"
 )
 
 func AddPolicyRoutes(r *mux.Router, ep *endpoints.PoliciesEndpoint) {
-	// GET - List all policies
-	r.Methods("GET").Path("").Handler(kithttp.NewServer(
-		ep.ListPolicies,
-		decodeListPoliciesRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+	mount(r, []Route{
+		// GET - List all policies
+		{
+			Method:   "GET",
+			Path:     "",
+			Endpoint: ep.ListPolicies,
+			Decode:   decodeListPoliciesRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListPoliciesRequest{},
+			Requires: Requirement{Resource: "policies", Action: "read"},
+		},
+		// POST - Create new policy
+		{
+			Method:   "POST",
+			Path:     "",
+			Endpoint: ep.CreatePolicy,
+			Decode:   decodeCreatePolicyRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreatePolicyRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				policies.ErrNameIsUUID,
+				policies.ErrInvalidEffect,
+				apierrors.Conflict("policy with this name already exists"),
+				errUnknownResource,
+				errUnknownAction,
+			},
+		},
+		// GET - List the valid resource/action pairs; registered before /{id}
+		{
+			Method:   "GET",
+			Path:     "/actions",
+			Endpoint: ep.ListPolicyActions,
+			Decode:   decodeListPolicyActionsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListPoliciesRequest{},
+			Requires: Requirement{Resource: "policies", Action: "read"},
+		},
+		// GET - Look a policy up by its URL-escaped name; registered before /{id}
+		{
+			Method:   "GET",
+			Path:     "/by-name/{name:.+}",
+			Endpoint: ep.GetPolicyByName,
+			Decode:   decodeGetPolicyByNameRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetPolicyByNameRequest{},
+			Requires: Requirement{Resource: "policies", Action: "read"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+			},
+		},
+		// POST - Delete many policies, reporting each one's outcome
+		{
+			Method:   "POST",
+			Path:     "/bulk-delete",
+			Endpoint: ep.BulkDeletePolicies,
+			Decode:   decodeBulkDeletePoliciesRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.BulkDeletePoliciesRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				errIDsRequired,
+				errTooManyItems,
+			},
+		},
+		{
+			Method:             "GET",
+			Path:               "/{id}",
+			Endpoint:           ep.GetPolicy,
+			Decode:             decodeGetPolicyRequest,
+			Encode:             encodeResponse,
+			Request:            endpoints.GetPolicyRequest{},
+			Requires:           Requirement{Resource: "policies", Action: "read"},
+			RequiresForDeleted: Requirement{Resource: "policies", Action: "read"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/{id}/affected-users",
+			Endpoint: ep.PolicyAffectedUsers,
+			Decode:   decodePolicyAffectedUsersRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.PolicyAffectedUsersRequest{},
+			Requires: Requirement{Resource: "policies", Action: "read"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+				apierrors.BadRequest("INVALID_QUERY", "sample must be an integer"),
+			},
+		},
+		// POST - Preview the permissions a change to the policy would grant
+		// or revoke, without saving it
+		{
+			Method:   "POST",
+			Path:     "/{id}/impact",
+			Endpoint: ep.PolicyImpact,
+			Decode:   decodePolicyImpactRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.PolicyImpactRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+				policies.ErrInvalidEffect,
+				errUnknownResource,
+				errUnknownAction,
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}",
+			Endpoint: ep.UpdatePolicy,
+			Decode:   decodeUpdatePolicyRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.UpdatePolicyRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+				policies.ErrNameIsUUID,
+				policies.ErrInvalidEffect,
+				policies.ErrSystemPolicy,
+				apierrors.Conflict("another policy with this name already exists"),
+				errUnknownResource,
+				errUnknownAction,
+			},
+		},
+		// PATCH - Update only the fields given in the body
+		{
+			Method:   "PATCH",
+			Path:     "/{id}",
+			Endpoint: ep.PatchPolicy,
+			Decode:   decodePatchPolicyRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.PatchPolicyRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+				policies.ErrNameIsUUID,
+				policies.ErrInvalidEffect,
+				policies.ErrSystemPolicy,
+				apierrors.Conflict("another policy with this name already exists"),
+				errUnknownResource,
+				errUnknownAction,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{id}",
+			Endpoint: ep.DeletePolicy,
+			Decode:   decodeDeletePolicyRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeletePolicyRequest{},
+			Requires: Requirement{Resource: "policies", Action: "write"},
+			Errors: []*apierrors.Error{
+				policies.ErrPolicyNotFound,
+				policies.ErrSystemPolicy,
+			},
+		},
+	})
+}
 
-	// POST - Create new policy
-	r.Methods("POST").Path("").Handler(kithttp.NewServer(
-		ep.CreatePolicy,
-		decodeCreatePolicyRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+func decodeListPoliciesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	sort, err := parseSort(r, policies.SortFields)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListPoliciesRequest{Sort: sort}, nil
+}
 
-	r.Methods("PUT").Path("/{id}").Handler(kithttp.NewServer(
-		ep.UpdatePolicy,
-		decodeUpdatePolicyRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+func decodeListPolicyActionsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.ListPoliciesRequest{}, nil
+}
+
+func decodeGetPolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.GetPolicyRequest{ID: id, IncludeDeleted: includeDeleted}, nil
+}
 
-	r.Methods("DELETE").Path("/{id}").Handler(kithttp.NewServer(
-		ep.DeletePolicy,
-		decodeDeletePolicyRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+func decodeGetPolicyByNameRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.GetPolicyByNameRequest{
+		Name:      name,
+		ProjectID: r.URL.Query().Get("project_id"),
+	}, nil
 }
 
-func decodeListPoliciesRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
-	return nil, nil
+func decodePolicyAffectedUsersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	sample, err := queryInt(r.URL.Query().Get("sample"))
+	if err != nil {
+		return nil, apierrors.BadRequest("INVALID_QUERY", "sample must be an integer")
+	}
+	return endpoints.PolicyAffectedUsersRequest{ID: id, Sample: sample}, nil
+}
+
+func decodePolicyImpactRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.PolicyImpactRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = id
+
+	return req, nil
 }
 
-func decodeCreatePolicyRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
-	return nil, nil
+func decodeCreatePolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.CreatePolicyRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeUpdatePolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.UpdatePolicyRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = id
+
+	return req, nil
+}
+
+func decodePatchPolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.PatchPolicyRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = id
+
+	return req, nil
 }
 
-func decodeUpdatePolicyRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
-	return nil, nil
+func decodeDeletePolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.DeletePolicyRequest{ID: id}, nil
 }
 
-func decodeDeletePolicyRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
-	return nil, nil
+func decodeBulkDeletePoliciesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.BulkDeletePoliciesRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
 }
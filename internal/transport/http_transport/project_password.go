@@ -0,0 +1,66 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+// AddProjectPasswordRoutes registers the password login routes of project
+// users on the /{projectId}/auth router
+func AddProjectPasswordRoutes(r *mux.Router, ep *endpoints.ProjectPasswordEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/login",
+			Endpoint: ep.Login,
+			Decode:   decodeProjectLoginRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ProjectLoginRequest{},
+			Errors: []*apierrors.Error{
+				projectusers.ErrInvalidCredentials,
+				errAccountNotActive,
+				roles.ErrIPNotAllowed,
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/password",
+			Endpoint: ep.ChangePassword,
+			Decode:   decodeChangeProjectPasswordRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ChangeProjectPasswordRequest{},
+			Errors: []*apierrors.Error{
+				projectusers.ErrInvalidCredentials,
+				errAccountNotActive,
+				password.ErrTooShort,
+				password.ErrTooLong,
+				projectusers.ErrPasswordReused,
+			},
+		},
+	})
+}
+
+func decodeProjectLoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.ProjectLoginRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = mux.Vars(r)["projectId"]
+	return request, nil
+}
+
+func decodeChangeProjectPasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.ChangeProjectPasswordRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = mux.Vars(r)["projectId"]
+	return request, nil
+}
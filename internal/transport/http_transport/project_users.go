@@ -2,65 +2,223 @@ package http_transport
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
-	"k8s.io/klog/v2"
-
-	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/roles"
 )
 
 // AddProjectUserRoutes adds project-specific user routes to the router
 func AddProjectUserRoutes(r *mux.Router, ep *endpoints.ProjectUsersEndpoint) {
-	// GET - Get a specific user in a project
-	r.Methods("GET").Path("/{user_id}").Handler(kithttp.NewServer(
-		ep.GetProjectUser,
-		decodeGetProjectUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	// GET - List all users in a project
-	r.Methods("GET").Path("").Handler(kithttp.NewServer(
-		ep.ListProjectUsers,
-		decodeListProjectUsersRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	// POST - Create a new user in a project
-	r.Methods("POST").Path("/{roleId}").Handler(kithttp.NewServer(
-		ep.CreateProjectUser,
-		decodeCreateProjectUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	// PUT - Update a user in a project
-	r.Methods("PUT").Path("/{user_id}").Handler(kithttp.NewServer(
-		ep.UpdateProjectUser,
-		decodeUpdateProjectUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	// DELETE - Delete a user from a project
-	r.Methods("DELETE").Path("/{user_id}").Handler(kithttp.NewServer(
-		ep.DeleteProjectUser,
-		decodeDeleteProjectUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+	mount(r, []Route{
+		// GET - List clusters of likely duplicate users in a project. Mounted
+		// before /{user_id}, which would match it too.
+		{
+			Method:   "GET",
+			Path:     "/duplicates",
+			Endpoint: ep.FindDuplicateProjectUsers,
+			Decode:   decodeFindDuplicateProjectUsersRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.FindDuplicateProjectUsersRequest{},
+			Requires: AdminOnly,
+		},
+		// POST - Merge a duplicate user into another. Mounted before /{roleId}.
+		{
+			Method:   "POST",
+			Path:     "/merge",
+			Endpoint: ep.MergeProjectUsers,
+			Decode:   decodeMergeProjectUsersRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.MergeProjectUsersRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("INVALID_USER_ID", "primary_id must be a user ID"),
+				apierrors.BadRequest("SAME_USER", "a user cannot be merged into itself"),
+				apierrors.NotFound("user 5f0c6b8e-3c1a-4f7e-9d1b-2a6f0e4c8b7d not found in this project; users can only be merged within one project"),
+				apierrors.Conflict("the primary user is deactivated"),
+				projectusers.ErrOAuthIdentityConflict,
+				errConcurrentStatusChange,
+			},
+		},
+		// POST - Recompute the token expiry of a role's users after its
+		// expiration changed, optionally issuing them fresh tokens
+		{
+			Method:   "POST",
+			Path:     "/tokens/refresh",
+			Endpoint: ep.RefreshProjectUserTokens,
+			Decode:   decodeRefreshProjectUserTokensRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RefreshProjectUserTokensRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				endpoints.ErrInvalidRole,
+				roles.ErrRoleNotFound,
+				apierrors.BadRequest("TOO_MANY_USERS", fmt.Sprintf("tokens can be issued for at most %d users at once; refresh without issue_tokens", projectusers.MaxBulkUsers)),
+			},
+		},
+		// GET - List the soft-deleted users of a project, a page at a time.
+		// Mounted before /{user_id}.
+		{
+			Method:   "GET",
+			Path:     "/deleted",
+			Endpoint: ep.ListDeletedProjectUsers,
+			Decode:   decodeListDeletedProjectUsersRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListDeletedProjectUsersRequest{},
+			Requires: Requirement{Resource: "users", Action: "read"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("INVALID_PAGINATION", "page must be an integer"),
+			},
+		},
+		// GET - Get a specific user in a project
+		{
+			Method:             "GET",
+			Path:               "/{user_id}",
+			Endpoint:           ep.GetProjectUser,
+			Decode:             decodeGetProjectUserRequest,
+			Encode:             encodeResponse,
+			Request:            endpoints.GetProjectUserRequest{},
+			Requires:           Requirement{Resource: "users", Action: "read", Self: "user_id"},
+			RequiresForDeleted: Requirement{Resource: "users", Action: "read"},
+		},
+		// GET - List all users in a project
+		{
+			Method:             "GET",
+			Path:               "",
+			Endpoint:           ep.ListProjectUsers,
+			Decode:             decodeListProjectUsersRequest,
+			Encode:             encodeResponse,
+			Request:            endpoints.ListProjectUsersRequest{},
+			Requires:           Requirement{Resource: "users", Action: "read"},
+			RequiresForDeleted: Requirement{Resource: "users", Action: "read"},
+			Errors: []*apierrors.Error{
+				userstatus.ErrUnknownStatus,
+			},
+		},
+		// POST - Create a new user in a project
+		{
+			Method:   "POST",
+			Path:     "/{roleId}",
+			Endpoint: ep.CreateProjectUser,
+			Decode:   decodeCreateProjectUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateProjectUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				endpoints.ErrInvalidRole,
+				projectusers.ErrEmailTaken,
+			},
+		},
+		// PUT - Update a user in a project
+		{
+			Method:   "PUT",
+			Path:     "/{user_id}",
+			Endpoint: ep.UpdateProjectUser,
+			Decode:   decodeUpdateProjectUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.UpdateProjectUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				errInvalidStatusTransition,
+			},
+		},
+		// PUT - Move a user in a project to another status
+		{
+			Method:   "PUT",
+			Path:     "/{user_id}/status",
+			Endpoint: ep.SetProjectUserStatus,
+			Decode:   decodeSetProjectUserStatusRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.SetProjectUserStatusRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				userstatus.ErrUnknownStatus,
+				errInvalidStatusTransition,
+				errConcurrentStatusChange,
+			},
+		},
+		// POST - Activate or deactivate many users in a project at once
+		{
+			Method:   "POST",
+			Path:     "/bulk/active",
+			Endpoint: ep.BulkSetProjectUsersActive,
+			Decode:   decodeBulkSetProjectUsersActiveRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.BulkSetProjectUsersActiveRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("ACTIVE_REQUIRED", "active must be true or false"),
+				apierrors.BadRequest("INVALID_USER_ID", "invalid user ID format: 42"),
+				apierrors.BadRequest("USER_IDS_REQUIRED", "user_ids must name at least one user"),
+				apierrors.BadRequest("TOO_MANY_USERS", fmt.Sprintf("at most %d users can be changed at once", projectusers.MaxBulkUsers)),
+				apierrors.NotFound("user 5f0c6b8e-3c1a-4f7e-9d1b-2a6f0e4c8b7d not found in this project"),
+				errInvalidStatusTransition,
+				errConcurrentStatusChange,
+			},
+		},
+		// DELETE - Delete a user from a project
+		{
+			Method:   "DELETE",
+			Path:     "/{user_id}",
+			Endpoint: ep.DeleteProjectUser,
+			Decode:   decodeDeleteProjectUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteProjectUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "delete"},
+		},
+		// POST - Restore a soft-deleted user in a project
+		{
+			Method:   "POST",
+			Path:     "/{user_id}/restore",
+			Endpoint: ep.RestoreProjectUser,
+			Decode:   decodeRestoreProjectUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RestoreProjectUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.Conflict("user is not deleted"),
+				projectusers.ErrRestoreEmailTaken,
+			},
+		},
+	})
+}
+
+// ErrInvalidIncludeDeleted is returned for an include_deleted query parameter
+// that is not a boolean
+var ErrInvalidIncludeDeleted = apierrors.BadRequest("INVALID_QUERY", "include_deleted must be a boolean")
+
+// parseIncludeDeleted reports whether the include_deleted query parameter is set
+func parseIncludeDeleted(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ErrInvalidIncludeDeleted
+	}
+	return b, nil
+}
+
+// parseSort parses the sort query parameter of a list that can be sorted by
+// fields, rejecting any other field with sorting.ErrUnknownField's code
+func parseSort(r *http.Request, fields []string) (sorting.Order, error) {
+	return sorting.Parse(r.URL.Query().Get("sort"), fields)
 }
 
 // decodeGetProjectUserRequest decodes the get project user request
-func decodeGetProjectUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeGetProjectUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	projectID, err := GetProjectIDFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
 		return nil, err
 	}
 
@@ -69,42 +227,77 @@ func decodeGetProjectUserRequest(_ context.Context, r *http.Request) (interface{
 		return nil, ErrBadRouting
 	}
 
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		return nil, err
+	}
+
 	return endpoints.GetProjectUserRequest{
-		ProjectID: projectID,
-		UserID:    userID,
+		ProjectID:      projectID,
+		UserID:         userID,
+		IncludeDeleted: includeDeleted,
 	}, nil
 }
 
 // decodeListProjectUsersRequest decodes the list project users request
-func decodeListProjectUsersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeListProjectUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	projectID, err := GetProjectIDFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		return nil, err
+	}
+	sort, err := parseSort(r, projectusers.SortFields)
+	if err != nil {
 		return nil, err
 	}
 
 	return endpoints.ListProjectUsersRequest{
-		ProjectID: projectID,
+		ProjectID:      projectID,
+		IncludeDeleted: includeDeleted,
+		Status:         r.URL.Query().Get("status"),
+		Sort:           sort,
 	}, nil
 }
 
+func decodeListDeletedProjectUsersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.URL.Query()
+	request := endpoints.ListDeletedProjectUsersRequest{ProjectID: projectID}
+	if request.Page, err = queryInt(q.Get("page")); err != nil {
+		return nil, apierrors.BadRequest("INVALID_PAGINATION", "page must be an integer")
+	}
+	if request.PageSize, err = queryInt(q.Get("page_size")); err != nil {
+		return nil, apierrors.BadRequest("INVALID_PAGINATION", "page_size must be an integer")
+	}
+	return request, nil
+}
+
 // decodeCreateProjectUserRequest decodes the create project user request
-func decodeCreateProjectUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeCreateProjectUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	projectID, err := GetProjectIDFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
 		return nil, err
 	}
 
 	roleId, err := GetRoleIdFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting role ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting role ID from request: %v", err)
 		return nil, err
 	}
 
 	var req endpoints.CreateProjectUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		klog.Errorf("Error decoding request body: %v", err)
+	if err := decodeJSONBody(r, &req); err != nil {
+		log.For(ctx).Errorf("Error decoding request body: %v", err)
 		return nil, err
 	}
 
@@ -114,11 +307,11 @@ func decodeCreateProjectUserRequest(_ context.Context, r *http.Request) (interfa
 }
 
 // decodeUpdateProjectUserRequest decodes the update project user request
-func decodeUpdateProjectUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeUpdateProjectUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	projectID, err := GetProjectIDFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
 		return nil, err
 	}
 
@@ -128,8 +321,32 @@ func decodeUpdateProjectUserRequest(_ context.Context, r *http.Request) (interfa
 	}
 
 	var req endpoints.UpdateProjectUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		klog.Errorf("Error decoding request body: %v", err)
+	if err := decodeJSONBody(r, &req); err != nil {
+		log.For(ctx).Errorf("Error decoding request body: %v", err)
+		return nil, err
+	}
+
+	req.ProjectID = projectID
+	req.UserID = userID
+	return req, nil
+}
+
+// decodeSetProjectUserStatusRequest decodes the set project user status request
+func decodeSetProjectUserStatusRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	userID, ok := vars["user_id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.SetProjectUserStatusRequest
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
 
@@ -138,12 +355,29 @@ func decodeUpdateProjectUserRequest(_ context.Context, r *http.Request) (interfa
 	return req, nil
 }
 
+// decodeBulkSetProjectUsersActiveRequest decodes the bulk activate/deactivate project users request
+func decodeBulkSetProjectUsersActiveRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	var req endpoints.BulkSetProjectUsersActiveRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+
+	req.ProjectID = projectID
+	return req, nil
+}
+
 // decodeDeleteProjectUserRequest decodes the delete project user request
-func decodeDeleteProjectUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeDeleteProjectUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	projectID, err := GetProjectIDFromRequest(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
 		return nil, err
 	}
 
@@ -157,3 +391,70 @@ func decodeDeleteProjectUserRequest(_ context.Context, r *http.Request) (interfa
 		UserID:    userID,
 	}, nil
 }
+
+// decodeRestoreProjectUserRequest decodes the restore project user request
+func decodeRestoreProjectUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	userID, ok := vars["user_id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	return endpoints.RestoreProjectUserRequest{
+		ProjectID: projectID,
+		UserID:    userID,
+	}, nil
+}
+
+// decodeFindDuplicateProjectUsersRequest decodes the find duplicate project users request
+func decodeFindDuplicateProjectUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	return endpoints.FindDuplicateProjectUsersRequest{
+		ProjectID: projectID,
+	}, nil
+}
+
+// decodeMergeProjectUsersRequest decodes the merge project users request
+func decodeMergeProjectUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		log.For(ctx).Errorf("Error getting project ID from request: %v", err)
+		return nil, err
+	}
+
+	var req endpoints.MergeProjectUsersRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+
+	req.ProjectID = projectID
+	return req, nil
+}
+
+// decodeRefreshProjectUserTokensRequest decodes the refresh project user
+// tokens request
+func decodeRefreshProjectUserTokensRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var req endpoints.RefreshProjectUserTokensRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+
+	req.ProjectID = projectID
+	return req, nil
+}
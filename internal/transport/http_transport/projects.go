@@ -2,55 +2,266 @@ package http_transport
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
+	"time"
 
-	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/ratelimit"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/projects"
 )
 
-func AddProjectRoutes(r *mux.Router, projects *endpoints.ProjectsEndpoint) {
-	r.Methods("POST").Path("/create").Handler(kithttp.NewServer(
-		projects.CreateProject,
-		decodeCreateProjectRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("GET").Path("/get/{id}").Handler(kithttp.NewServer(
-		projects.GetProject,
-		decodeGetProjectRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("GET").Path("/list").Handler(kithttp.NewServer(
-		projects.ListProjects,
-		decodeListProjectsRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("PUT").Path("/update/{id}").Handler(kithttp.NewServer(
-		projects.UpdateProject,
-		decodeUpdateProjectRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("DELETE").Path("/delete/{id}").Handler(kithttp.NewServer(
-		projects.DeleteProject,
-		decodeDeleteProjectRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+// projectPathsDeprecated is when the verb-style project paths, such as
+// /get/{id}, were deprecated in favor of the RESTful ones
+var projectPathsDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// AddProjectRoutes adds the project routes. Unique ID checks are rate
+// limited per client IP by checkLimiter, when set.
+func AddProjectRoutes(r *mux.Router, ep *endpoints.ProjectsEndpoint, checkLimiter *ratelimit.Limiter) {
+	create := Route{
+		Method:   "POST",
+		Path:     "",
+		Endpoint: ep.CreateProject,
+		Decode:   decodeCreateProjectRequest,
+		Encode:   encodeResponse,
+		Request:  endpoints.CreateProjectRequest{},
+		Requires: SignedIn,
+		Errors: []*apierrors.Error{
+			projects.ErrProjectQuotaExceeded,
+			apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id may only contain letters, digits and underscores"),
+			apierrors.BadRequest("INVALID_REGION", `region "mars" is not one of eu, us`),
+			apierrors.Conflict("project with this unique ID already exists"),
+		},
+	}
+	get := Route{
+		Method:             "GET",
+		Path:               "/{id}",
+		Endpoint:           ep.GetProject,
+		Decode:             decodeGetProjectRequest,
+		Encode:             encodeResponse,
+		Request:            endpoints.GetProjectRequest{},
+		Requires:           Requirement{Resource: "projects", Action: "read", Project: "id"},
+		RequiresForDeleted: Requirement{Resource: "projects", Action: "read"},
+		Errors: []*apierrors.Error{
+			projects.ErrProjectNotFound,
+		},
+	}
+	list := Route{
+		Method:   "GET",
+		Path:     "",
+		Endpoint: ep.ListProjects,
+		Decode:   decodeListProjectsRequest,
+		Encode:   encodeResponse,
+		Request:  endpoints.ListProjectsRequest{},
+		Requires: Requirement{Resource: "projects", Action: "read"},
+	}
+	update := Route{
+		Method:   "PUT",
+		Path:     "/{id}",
+		Endpoint: ep.UpdateProject,
+		Decode:   decodeUpdateProjectRequest,
+		Encode:   encodeResponse,
+		Request:  endpoints.UpdateProjectRequest{},
+		Requires: Requirement{Resource: "projects", Action: "write", Project: "id"},
+		Errors: []*apierrors.Error{
+			projects.ErrProjectNotFound,
+		},
+	}
+	remove := Route{
+		Method:   "DELETE",
+		Path:     "/{id}",
+		Endpoint: ep.DeleteProject,
+		Decode:   decodeDeleteProjectRequest,
+		Encode:   encodeResponse,
+		Request:  endpoints.DeleteProjectRequest{},
+		Requires: Requirement{Resource: "projects", Action: "delete", Project: "id"},
+		Errors: []*apierrors.Error{
+			projects.ErrProjectNotFound,
+			apierrors.BadRequest("INVALID_EXPORT", "export must be backup or download"),
+			projects.ErrExportFailed,
+		},
+	}
+
+	mount(r, []Route{
+		deprecatedAt(create, "/create", projectPathsDeprecated),
+		// POST - Provision a project with its settings, roles, OAuth
+		// providers, webhooks and invited admin at once. Validation errors
+		// carry the JSON path of the offending field in "field".
+		{
+			Method:   "POST",
+			Path:     "/bootstrap",
+			Endpoint: ep.BootstrapProject,
+			Decode:   decodeBootstrapProjectRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.BootstrapProjectRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				projects.ErrProjectQuotaExceeded,
+				apierrors.BadRequest("VALIDATION_FAILED", "admin role must name one of roles"),
+				apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id may only contain letters, digits and underscores"),
+				apierrors.Conflict("project with this unique ID already exists"),
+				apierrors.BadRequest("INVALID_EXPIRATION", "role expiration must not be negative"),
+				errUnknownResource,
+				errUnknownAction,
+				apierrors.BadRequest("INVALID_RESOURCE", "custom resource \"billing\" must be of the form <namespace>:<name>"),
+				apierrors.BadRequest("INVALID_REDIRECT_URL", "magic link redirect_url must be an absolute http(s) URL"),
+				apierrors.BadRequest("INVALID_OAUTH_LOGIN_SETTINGS", "oauth_login unverified_email must be reject or separate"),
+				apierrors.BadRequest("INVALID_SUSPICIOUS_LOGIN_SETTINGS", "suspicious_login min_history and lookback_days cannot be negative"),
+				apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn needs at least one origin"),
+				apierrors.BadRequest("INVALID_REPORT_SETTINGS", "reports recipient ops is not an email address"),
+				apierrors.BadRequest("INVALID_PASSWORD_SETTINGS", "passwords max_age_days cannot be negative"),
+				apierrors.BadRequest("INVALID_CORS_SETTINGS", "cors origin app.example.com must be a bare http(s)://host[:port]"),
+				apierrors.BadRequest("INVALID_CONSENT_SETTINGS", "consents version of terms must be 1 to 50 characters"),
+				apierrors.BadRequest("UNSUPPORTED_PROVIDER", "oauth provider myspace is not supported"),
+				apierrors.BadRequest("INVALID_OAUTH_PROVIDER", "client_id and client_secret are required"),
+				apierrors.BadRequest("INVALID_SCOPES", "unknown github oauth scopes: repo; known scopes are read:user, user:email"),
+				apierrors.BadRequest("UNKNOWN_EVENT", "unknown event user.renamed"),
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/unique-id/validate",
+			Endpoint: ep.ValidateUniqueID,
+			Decode:   decodeValidateUniqueIDRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ValidateUniqueIDRequest{},
+			Wrap:     RateLimit(checkLimiter, clientIPKey),
+			Requires: SignedIn,
+			Errors: []*apierrors.Error{
+				ErrRateLimited,
+			},
+		},
+		{
+			Method:       "GET",
+			Path:         "/check-unique-id",
+			Endpoint:     ep.CheckUniqueID,
+			Decode:       decodeCheckUniqueIDRequest,
+			Encode:       encodeResponse,
+			Request:      endpoints.CheckUniqueIDRequest{},
+			Wrap:         RateLimit(checkLimiter, clientIPKey),
+			Requires:     SignedIn,
+			ExampleQuery: "value=acme_prod",
+			Errors: []*apierrors.Error{
+				ErrRateLimited,
+			},
+		},
+		deprecatedAt(get, "/get/{id}", projectPathsDeprecated),
+		deprecatedAt(list, "/list", projectPathsDeprecated),
+		deprecatedAt(update, "/update/{id}", projectPathsDeprecated),
+		deprecatedAt(remove, "/delete/{id}", projectPathsDeprecated),
+		{
+			Method:   "GET",
+			Path:     "/{id}/delete-preview",
+			Endpoint: ep.GetDeletePreview,
+			Decode:   decodeGetDeletePreviewRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetDeletePreviewRequest{},
+			Requires: Requirement{Resource: "projects", Action: "delete", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/{id}/stats",
+			Endpoint: ep.GetProjectStats,
+			Decode:   decodeGetProjectStatsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetProjectStatsRequest{},
+			Requires: Requirement{Resource: "projects", Action: "read", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}/settings",
+			Endpoint: ep.UpdateProjectSettings,
+			Decode:   decodeUpdateProjectSettingsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.UpdateProjectSettingsRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+				apierrors.BadRequest("INVALID_RESOURCE", "custom resource \"billing\" must be of the form <namespace>:<name>"),
+				apierrors.BadRequest("INVALID_REDIRECT_URL", "magic link redirect_url must be an absolute http(s) URL"),
+				apierrors.BadRequest("INVALID_OAUTH_LOGIN_SETTINGS", "oauth_login unverified_email must be reject or separate"),
+				apierrors.BadRequest("INVALID_SUSPICIOUS_LOGIN_SETTINGS", "suspicious_login min_history and lookback_days cannot be negative"),
+				apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn needs at least one origin"),
+				apierrors.BadRequest("INVALID_REPORT_SETTINGS", "reports recipient ops is not an email address"),
+				apierrors.BadRequest("INVALID_PASSWORD_SETTINGS", "passwords max_age_days cannot be negative"),
+				apierrors.BadRequest("INVALID_CORS_SETTINGS", "cors origin app.example.com must be a bare http(s)://host[:port]"),
+				apierrors.BadRequest("INVALID_CONSENT_SETTINGS", "consents version of terms must be 1 to 50 characters"),
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}/oauth-providers/{provider}",
+			Endpoint: ep.SetOAuthProvider,
+			Decode:   decodeSetOAuthProviderRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.SetOAuthProviderRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+				apierrors.BadRequest("UNSUPPORTED_PROVIDER", "oauth provider myspace is not supported"),
+				apierrors.BadRequest("INVALID_OAUTH_PROVIDER", "client_id and client_secret are required"),
+				apierrors.BadRequest("INVALID_REDIRECT_URL", "oauth redirect_url must be an absolute http(s) URL"),
+				apierrors.BadRequest("INVALID_SCOPES", "unknown github oauth scopes: repo; known scopes are read:user, user:email"),
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{id}/oauth-providers/{provider}",
+			Endpoint: ep.DeleteOAuthProvider,
+			Decode:   decodeDeleteOAuthProviderRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteOAuthProviderRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+				apierrors.NotFound("oauth provider override not found"),
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/{id}/clone",
+			Endpoint: ep.CloneProject,
+			Decode:   decodeCloneProjectRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CloneProjectRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write", Project: "id"},
+			Errors: []*apierrors.Error{
+				projects.ErrProjectNotFound,
+				projects.ErrProjectQuotaExceeded,
+				apierrors.BadRequest("VALIDATION_FAILED", "name and unique_id are required"),
+				apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id may only contain letters, digits and underscores"),
+				apierrors.Conflict("project with this unique ID already exists"),
+			},
+		},
+		// Mounted last so that /{id} does not capture /list and the other
+		// single-segment paths above
+		create,
+		get,
+		list,
+		update,
+		remove,
+	})
 }
 
 // Request decoders
 func decodeCreateProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var request endpoints.CreateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func decodeBootstrapProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.BootstrapProjectRequest
+	if err := decodeJSONBody(r, &request); err != nil {
 		return nil, err
 	}
 	return request, nil
@@ -58,19 +269,28 @@ func decodeCreateProjectRequest(_ context.Context, r *http.Request) (interface{}
 
 func decodeGetProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		return nil, err
+	}
 	return endpoints.GetProjectRequest{
-		ID: vars["id"],
+		ID:             vars["id"],
+		IncludeDeleted: includeDeleted,
 	}, nil
 }
 
 func decodeListProjectsRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	return endpoints.ListProjectsRequest{}, nil
+	sort, err := parseSort(r, projects.SortFields)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListProjectsRequest{Sort: sort}, nil
 }
 
 func decodeUpdateProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	var request endpoints.UpdateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if err := decodeJSONBody(r, &request); err != nil {
 		return nil, err
 	}
 	request.ID = vars["id"]
@@ -80,6 +300,72 @@ func decodeUpdateProjectRequest(_ context.Context, r *http.Request) (interface{}
 func decodeDeleteProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	return endpoints.DeleteProjectRequest{
+		ID:     vars["id"],
+		Export: r.URL.Query().Get("export"),
+	}, nil
+}
+
+func decodeGetDeletePreviewRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.GetDeletePreviewRequest{
 		ID: vars["id"],
 	}, nil
-}
\ No newline at end of file
+}
+
+func decodeGetProjectStatsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.GetProjectStatsRequest{
+		ID: vars["id"],
+	}, nil
+}
+
+func decodeUpdateProjectSettingsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var request endpoints.UpdateProjectSettingsRequest
+	if err := decodeJSONBody(r, &request.Settings); err != nil {
+		return nil, err
+	}
+	request.ID = vars["id"]
+	return request, nil
+}
+
+func decodeSetOAuthProviderRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var request endpoints.SetOAuthProviderRequest
+	if err := decodeJSONBody(r, &request.Config); err != nil {
+		return nil, err
+	}
+	request.ID = vars["id"]
+	request.Provider = vars["provider"]
+	return request, nil
+}
+
+func decodeDeleteOAuthProviderRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.DeleteOAuthProviderRequest{
+		ID:       vars["id"],
+		Provider: vars["provider"],
+	}, nil
+}
+
+func decodeValidateUniqueIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.ValidateUniqueIDRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func decodeCheckUniqueIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.CheckUniqueIDRequest{Value: r.URL.Query().Get("value")}, nil
+}
+
+func decodeCloneProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var request endpoints.CloneProjectRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ID = vars["id"]
+	return request, nil
+}
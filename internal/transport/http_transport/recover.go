@@ -0,0 +1,144 @@
+package http_transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"k8s.io/klog/v2"
+)
+
+// ErrInternal answers a request whose handler panicked
+var ErrInternal = apierrors.New(http.StatusInternalServerError, "INTERNAL", "internal server error")
+
+// panicsRecovered counts the panics Recover caught
+var panicsRecovered = metrics.NewCounter("ums_panics_recovered_total",
+	"Panics in request handlers recovered and answered with 500 INTERNAL.")
+
+// PanicReport describes a panic in a request handler
+type PanicReport struct {
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	At        time.Time `json:"at"`
+}
+
+// PanicReporter is told about every panic Recover catches, to pass it on to
+// an error tracker. It is called on the request's goroutine once the
+// response is written, so it should hand slow work off.
+type PanicReporter interface {
+	ReportPanic(report PanicReport)
+}
+
+// Recover keeps a panic in a handler from dropping the connection without an
+// answer. It logs the panic with its stack and the request ID, counts it,
+// tells the reporter (which may be nil) and answers 500 with code INTERNAL
+// and the request ID. Wrap it around everything but RequestID, so that
+// kithttp and plain handlers, and the other middleware, are all covered. A
+// handler that already started its response cannot be answered; its
+// connection is aborted instead, so the client does not take a truncated
+// body for a whole one.
+func Recover(reporter PanicReporter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &startedWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				report := PanicReport{
+					RequestID: requestid.FromContext(r.Context()),
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Panic:     fmt.Sprint(p),
+					Stack:     string(debug.Stack()),
+					At:        time.Now().UTC(),
+				}
+				panicsRecovered.Inc()
+				klog.Errorf("Panic serving %s %s (request %s): %s\n%s", report.Method, report.Path, report.RequestID, report.Panic, report.Stack)
+
+				if !rw.started {
+					w.Header().Set("Content-Type", "application/json; charset=utf-8")
+					w.WriteHeader(ErrInternal.StatusCode())
+					json.NewEncoder(w).Encode(ErrorResponse{Error: ErrInternal.Message, Code: ErrInternal.Code, RequestID: report.RequestID})
+				}
+				if reporter != nil {
+					reporter.ReportPanic(report)
+				}
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// startedWriter notes whether a response has been started
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap gives http.ResponseController the underlying writer
+func (w *startedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WebhookPanicReporter posts every panic report as JSON to a URL, such as an
+// error tracker's ingestion endpoint. Reports are sent in the background and
+// one that fails is only logged.
+type WebhookPanicReporter struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookPanicReporter creates a reporter posting to url, giving up on a
+// report after timeout
+func NewWebhookPanicReporter(url string, timeout time.Duration) *WebhookPanicReporter {
+	return &WebhookPanicReporter{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+// ReportPanic posts the report
+func (p *WebhookPanicReporter) ReportPanic(report PanicReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		klog.Errorf("Cannot encode the panic report of request %s: %v", report.RequestID, err)
+		return
+	}
+	go func() {
+		resp, err := p.Client.Post(p.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			klog.Errorf("Cannot send the panic report of request %s: %v", report.RequestID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			klog.Errorf("Panic report of request %s was answered with %s", report.RequestID, resp.Status)
+		}
+	}()
+}
@@ -0,0 +1,136 @@
+package http_transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/metrics"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+// panicReports collects the reports Recover hands on
+type panicReports struct {
+	mu      sync.Mutex
+	reports []http_transport.PanicReport
+}
+
+func (p *panicReports) ReportPanic(report http_transport.PanicReport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reports = append(p.reports, report)
+}
+
+// panicsRecovered reads the panic counter from the metrics exposition
+func panicsRecovered(t *testing.T) float64 {
+	t.Helper()
+
+	var exposition bytes.Buffer
+	metrics.WriteAll(&exposition)
+	for _, line := range strings.Split(exposition.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "ums_panics_recovered_total "); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", line, err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestRecoverAnswersPanicsWith500AndKeepsServing(t *testing.T) {
+	router := mux.NewRouter()
+	router.Handle("/kit", kithttp.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { panic("endpoint failed") },
+		func(context.Context, *http.Request) (interface{}, error) { return nil, nil },
+		kithttp.EncodeJSONResponse,
+	))
+	router.HandleFunc("/plain", func(http.ResponseWriter, *http.Request) { panic("handler failed") })
+	router.HandleFunc("/partial", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"users": [`))
+		// Sent, so the client cannot retry the request as never answered
+		http.NewResponseController(w).Flush()
+		panic("failed halfway")
+	})
+	router.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	reporter := &panicReports{}
+	server := httptest.NewServer(http_transport.RequestID(http_transport.Recover(reporter)(router)))
+	t.Cleanup(server.Close)
+	before := panicsRecovered(t)
+
+	for _, path := range []string{"/kit", "/plain"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var body http_transport.ErrorResponse
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		id := resp.Header.Get(requestid.Header)
+		if resp.StatusCode != http.StatusInternalServerError || err != nil || body.Code != "INTERNAL" || body.RequestID == "" || body.RequestID != id {
+			t.Errorf("GET %s = %d %+v (%v), want 500 INTERNAL with request ID %q", path, resp.StatusCode, body, err, id)
+		}
+
+		resp, err = http.Get(server.URL + "/ok")
+		if err != nil || resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("GET /ok after a panic in %s = %v, %v", path, resp, err)
+		}
+		resp.Body.Close()
+	}
+
+	// The response was started, so the client must not see a whole one
+	if resp, err := http.Get(server.URL + "/partial"); err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			t.Error("a panic after a partial write ended the response cleanly")
+		}
+	}
+
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	if len(reporter.reports) != 3 {
+		t.Fatalf("reported %d panics, want 3", len(reporter.reports))
+	}
+	if report := reporter.reports[1]; report.Path != "/plain" || report.Panic != "handler failed" || report.RequestID == "" || !strings.Contains(report.Stack, "goroutine") {
+		t.Errorf("report = %+v, want the path, panic value, request ID and stack", report)
+	}
+	if got := panicsRecovered(t) - before; got != 3 {
+		t.Errorf("the panic counter rose by %v, want 3", got)
+	}
+}
+
+func TestWebhookPanicReporterPostsTheReport(t *testing.T) {
+	received := make(chan http_transport.PanicReport, 1)
+	tracker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report http_transport.PanicReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("failed to decode the report: %v", err)
+		}
+		received <- report
+	}))
+	t.Cleanup(tracker.Close)
+
+	http_transport.NewWebhookPanicReporter(tracker.URL, time.Second).ReportPanic(http_transport.PanicReport{RequestID: "req-1", Panic: "boom"})
+	select {
+	case report := <-received:
+		if report.RequestID != "req-1" || report.Panic != "boom" {
+			t.Errorf("report = %+v", report)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the report was not posted")
+	}
+}
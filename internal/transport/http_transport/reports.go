@@ -0,0 +1,40 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/reports"
+)
+
+// AddReportRoutes registers the project digest report routes on the projects router
+func AddReportRoutes(r *mux.Router, ep *endpoints.ReportsEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/{projectId}/reports/send-now",
+			Endpoint: ep.SendReport,
+			Decode:   decodeSendReportRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.SendReportRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				projecttable.ErrInvalidProjectID,
+				reports.ErrNoRecipients,
+				apierrors.Conflict("a report of this project is being sent"),
+			},
+		},
+	})
+}
+
+func decodeSendReportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.SendReportRequest{ProjectID: projectId}, nil
+}
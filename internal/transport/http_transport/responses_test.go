@@ -0,0 +1,88 @@
+package http_transport_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+// created is a response with a status of its own
+type created struct {
+	ID string `json:"id"`
+}
+
+func (created) StatusCode() int { return http.StatusCreated }
+
+// tagged is a response setting a header
+type tagged struct {
+	Name string `json:"name"`
+}
+
+func (tagged) Headers() http.Header { return http.Header{"Etag": {`"v1"`}} }
+
+// stream is a response writing its own body, failing with err after it
+type stream struct {
+	body string
+	err  error
+}
+
+func (s stream) Headers() http.Header { return http.Header{"Content-Type": {"text/csv"}} }
+
+func (s stream) StreamTo(w io.Writer) error {
+	io.WriteString(w, s.body)
+	return s.err
+}
+
+func TestEncodeResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		response interface{}
+		status   int
+		header   string // Header as "Name: value"
+		body     string
+		failed   bool
+	}{
+		{"plain", struct {
+			OK bool `json:"ok"`
+		}{true}, http.StatusOK, "Content-Type: application/json; charset=utf-8", "{\"ok\":true}\n", false},
+		{"status coder", created{ID: "1"}, http.StatusCreated, "Content-Type: application/json; charset=utf-8", "{\"id\":\"1\"}\n", false},
+		{"headerer", tagged{Name: "a"}, http.StatusOK, `Etag: "v1"`, "{\"name\":\"a\"}\n", false},
+		{"streamer", stream{body: "id\n1\n"}, http.StatusOK, "Content-Type: text/csv", "id\n1\n", false},
+		// Streaming had begun, so the failure cannot change the status
+		{"failing streamer", stream{body: "id\n", err: errors.New("disk gone")}, http.StatusOK, "Content-Type: text/csv", "id\n", false},
+		{"no content", endpoints.NoContentResponse{}, http.StatusNoContent, "", "", false},
+		{"nil", nil, 0, "", "", true},
+		{"unencodable", struct{ C chan int }{make(chan int)}, 0, "", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			err := http_transport.EncodeResponse(context.Background(), recorder, tc.response)
+			if tc.failed {
+				// Nothing was written, so the error can still be answered
+				if err == nil || recorder.Body.Len() != 0 || recorder.Header().Get("Content-Type") != "" {
+					t.Fatalf("EncodeResponse = %v having written %q, want an error and nothing written", err, recorder.Body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncodeResponse: %v", err)
+			}
+			if recorder.Code != tc.status || recorder.Body.String() != tc.body {
+				t.Errorf("response = %d %q, want %d %q", recorder.Code, recorder.Body, tc.status, tc.body)
+			}
+			if tc.header != "" {
+				name, value, _ := strings.Cut(tc.header, ": ")
+				if got := recorder.Header().Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
@@ -2,37 +2,166 @@ package http_transport
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
-	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/roles"
 )
 
 func AddRoleRoutes(r *mux.Router, ep *endpoints.RolesEndpoint) {
-	r.Methods("POST").Path("").Handler(kithttp.NewServer(
-		ep.CreateRole,
-		decodeCreateRoleRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("PUT").Path("/{id}").Handler(kithttp.NewServer(
-		ep.UpdateRole,
-		decodeUpdateRoleRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("DELETE").Path("/{id}").Handler(kithttp.NewServer(
-		ep.DeleteRole,
-		decodeDeleteRoleRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
+	mount(r, []Route{
+		// GET - List all roles
+		{
+			Method:   "GET",
+			Path:     "",
+			Endpoint: ep.ListRoles,
+			Decode:   decodeListRolesRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListRolesRequest{},
+			Requires: Requirement{Resource: "roles", Action: "read"},
+		},
+		// GET - Look a role up by its URL-escaped name; registered before /{id}
+		{
+			Method:   "GET",
+			Path:     "/by-name/{name:.+}",
+			Endpoint: ep.GetRoleByName,
+			Decode:   decodeGetRoleByNameRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetRoleByNameRequest{},
+			Requires: Requirement{Resource: "roles", Action: "read"},
+			Errors: []*apierrors.Error{
+				roles.ErrRoleNotFound,
+			},
+		},
+		{
+			Method:             "GET",
+			Path:               "/{id}",
+			Endpoint:           ep.GetRole,
+			Decode:             decodeGetRoleRequest,
+			Encode:             encodeResponse,
+			Request:            endpoints.GetRoleRequest{},
+			Requires:           Requirement{Resource: "roles", Action: "read"},
+			RequiresForDeleted: Requirement{Resource: "roles", Action: "read"},
+			Errors: []*apierrors.Error{
+				roles.ErrRoleNotFound,
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "",
+			Endpoint: ep.CreateRole,
+			Decode:   decodeCreateRoleRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateRoleRequest{},
+			Requires: Requirement{Resource: "roles", Action: "write"},
+			Errors: []*apierrors.Error{
+				roles.ErrNameIsUUID,
+				roles.ErrNegativeExpiration,
+				errInvalidCIDR,
+				apierrors.Conflict("role with this name already exists"),
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}",
+			Endpoint: ep.UpdateRole,
+			Decode:   decodeUpdateRoleRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.UpdateRoleRequest{},
+			Requires: Requirement{Resource: "roles", Action: "write"},
+			Errors: []*apierrors.Error{
+				roles.ErrRoleNotFound,
+				roles.ErrNameIsUUID,
+				roles.ErrNegativeExpiration,
+				errInvalidCIDR,
+				apierrors.Conflict("another role with this name already exists"),
+			},
+		},
+		// POST - Rename a role, leaving its other fields as they are
+		{
+			Method:      "POST",
+			Path:        "/{id}/rename",
+			Endpoint:    ep.RenameRole,
+			Decode:      decodeRenameRoleRequest,
+			Encode:      encodeResponse,
+			Request:     endpoints.RenameRoleRequest{},
+			Requires:    Requirement{Resource: "roles", Action: "write"},
+			ExampleBody: `{"name": "editor"}`,
+			Errors: []*apierrors.Error{
+				roles.ErrRoleNotFound,
+				apierrors.BadRequest("INVALID_NAME", "role name is required"),
+				roles.ErrNameIsUUID,
+				roles.ErrNameTaken,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{id}",
+			Endpoint: ep.DeleteRole,
+			Decode:   decodeDeleteRoleRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteRoleRequest{},
+			Requires: Requirement{Resource: "roles", Action: "write"},
+			Errors: []*apierrors.Error{
+				roles.ErrRoleNotFound,
+			},
+		},
+	})
 }
 
+// AddProjectRoleRoutes adds the routes about the roles held in a project
+func AddProjectRoleRoutes(r *mux.Router, ep *endpoints.RolesEndpoint) {
+	mount(r, []Route{
+		// GET - Count the project's users by role
+		{
+			Method:   "GET",
+			Path:     "/usage",
+			Endpoint: ep.GetRoleUsage,
+			Decode:   decodeGetRoleUsageRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.GetRoleUsageRequest{},
+			Requires: Requirement{Resource: "roles", Action: "read"},
+			Errors: []*apierrors.Error{
+				projecttable.ErrInvalidProjectID,
+			},
+		},
+	})
+}
+
+func decodeListRolesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	sort, err := parseSort(r, roles.SortFields)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListRolesRequest{Sort: sort}, nil
+}
+
+func decodeGetRoleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	includeDeleted, err := parseIncludeDeleted(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.GetRoleRequest{ID: id, IncludeDeleted: includeDeleted}, nil
+}
+
+func decodeGetRoleByNameRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.GetRoleByNameRequest{
+		Name:      name,
+		ProjectID: r.URL.Query().Get("project_id"),
+	}, nil
+}
 
 func decodeUpdateRoleRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
@@ -42,7 +171,7 @@ func decodeUpdateRoleRequest(ctx_ context.Context, r *http.Request) (interface{}
 	}
 
 	var req endpoints.UpdateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
 	req.ID = id
@@ -53,12 +182,26 @@ func decodeUpdateRoleRequest(ctx_ context.Context, r *http.Request) (interface{}
 
 func decodeCreateRoleRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
 	var req endpoints.CreateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
 	return req, nil
 }
 
+func decodeRenameRoleRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.RenameRoleRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = id
+	return req, nil
+}
+
 func decodeDeleteRoleRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
@@ -70,3 +213,11 @@ func decodeDeleteRoleRequest(ctx_ context.Context, r *http.Request) (interface{}
 		ID: id,
 	}, nil
 }
+
+func decodeGetRoleUsageRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.GetRoleUsageRequest{ProjectID: projectID}, nil
+}
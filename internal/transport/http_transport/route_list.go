@@ -0,0 +1,44 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddRouteListRoutes registers the route listing on the API router
+func AddRouteListRoutes(r *mux.Router, ep *endpoints.RoutesEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/routes",
+			Endpoint: ep.ListRoutes,
+			Decode:   decodeListRoutesRequest,
+			Encode:   encodeResponse,
+			Requires: AdminOnly,
+		},
+	})
+}
+
+// AddErrorCatalogRoutes registers the error catalog on the API router
+func AddErrorCatalogRoutes(r *mux.Router, ep *endpoints.ErrorCatalogEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/error-catalog",
+			Endpoint: ep.GetErrorCatalog,
+			Decode:   decodeGetErrorCatalogRequest,
+			Encode:   encodeResponse,
+		},
+	})
+}
+
+func decodeListRoutesRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeGetErrorCatalogRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
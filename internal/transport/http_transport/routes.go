@@ -0,0 +1,458 @@
+package http_transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/consents"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/users"
+	"gorm.io/gorm"
+)
+
+// Route declares one API route: the endpoint serving it, the decoder that
+// builds the endpoint's request and the encoder that writes its response.
+// Each AddXxxRoutes function mounts a table of them.
+type Route struct {
+	Method   string
+	Path     string
+	Endpoint endpoint.Endpoint
+	Decode   kithttp.DecodeRequestFunc
+	Encode   kithttp.EncodeResponseFunc
+	// Request is the zero value of the request the endpoint expects, and
+	// so of what Decode must produce; nil for endpoints taking none
+	Request interface{}
+	// Options replace defaultServerOptions when set
+	Options []kithttp.ServerOption
+	// Wrap, when set, wraps the route's handler, e.g. to rate limit it
+	Wrap mux.MiddlewareFunc
+	// Requires is what the caller must hold; Authorization enforces it and
+	// ListRoutes reports it. The zero value leaves the route public.
+	Requires Requirement
+	// RequiresForDeleted is demanded on top of Requires of requests asking
+	// for soft-deleted records with ?include_deleted=true
+	RequiresForDeleted Requirement
+	// ExampleQuery and ExampleBody make up the minimal valid request
+	// VerifyRoutes decodes; the body defaults to {} for POST, PUT and PATCH
+	ExampleQuery string
+	ExampleBody  string
+	// Errors are the API errors the route may answer with, listed by the
+	// error catalog with their message as the example. Those every route of
+	// its kind may return are added by impliedErrors and need not be listed.
+	Errors []*apierrors.Error
+	// Deprecated, when set, marks the route as deprecated in the route list
+	// and in the headers of its responses
+	Deprecated *Deprecation
+}
+
+// Requirement is the authorization a route demands of its caller: a valid
+// bearer token of an active user and, on top of that, the named role or a
+// role allowing the policy's resource and action
+type Requirement struct {
+	Role     string
+	Resource string
+	Action   string
+	// Authenticated demands the bearer token alone, for routes any signed-in
+	// user may call about themselves
+	Authenticated bool
+	// PasswordChange also accepts tokens issued for an expired password,
+	// which every other route refuses
+	PasswordChange bool
+	// ConsentPending also lets through users whose project enforces consents
+	// they have yet to give, which every other route refuses with 451
+	ConsentPending bool
+	// Project names the path variable holding the project the policy is
+	// checked in, for routes that do not call it projectId
+	Project string
+	// Self names the path variable holding the ID of the project user the
+	// route is about. That user may call it with a token issued to them for
+	// the project of the path; anyone else needs the rest of the requirement.
+	Self string
+}
+
+// AdminOnly restricts a route to the SuperAdmin role
+var AdminOnly = Requirement{Role: auth.SuperAdminRole}
+
+// SignedIn restricts a route to callers with a valid bearer token, whatever
+// their role
+var SignedIn = Requirement{Authenticated: true}
+
+// SignedInToChangePassword is SignedIn also letting through users whose
+// password expired, for the route they change it with. Users yet to give
+// their consents may change it too.
+var SignedInToChangePassword = Requirement{Authenticated: true, PasswordChange: true, ConsentPending: true}
+
+// SignedInToConsent is SignedIn also letting through users yet to give the
+// consents their project enforces, for the routes they give them with
+var SignedInToConsent = Requirement{Authenticated: true, ConsentPending: true}
+
+// Public reports whether the requirement lets anyone through
+func (q Requirement) Public() bool {
+	return q == Requirement{}
+}
+
+// declaredRoute is the handler mount registers. It keeps the declaration so
+// that VerifyRoutes can check the route on the assembled router.
+type declaredRoute struct {
+	http.Handler
+	route    Route
+	table    string // The AddXxxRoutes function that mounted the route
+	template string // Full path template the route is mounted at
+
+	impliedOnce sync.Once
+	implied     []*apierrors.Error
+}
+
+// declaredRouteKey is the context key of the declared route being served
+type declaredRouteKey struct{}
+
+// ServeHTTP serves the route with its declaration in the request context, so
+// that encodeError can tell the errors it declares from the others
+func (d *declaredRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), declaredRouteKey{}, d)))
+}
+
+// routeTables names every AddXxxRoutes function. VerifyRoutes reports those
+// with no route on the router, so a table written but never mounted fails
+// the check rather than leaving its routes unreachable.
+var routeTables = []string{
+	"AddAPITokenRoutes",
+	"AddAdminRoutes",
+	"AddArtifactRoutes",
+	"AddAuditRoutes",
+	"AddAuthConfigRoutes",
+	"AddAuthRoutes",
+	"AddAuthorizeRoutes",
+	"AddConsentRoutes",
+	"AddErrorCatalogRoutes",
+	"AddHealthRoutes",
+	"AddImportRoutes",
+	"AddIntrospectionRoutes",
+	"AddLoginHistoryRoutes",
+	"AddMagicLinkRoutes",
+	"AddMeRoutes",
+	"AddMyConsentRoutes",
+	"AddOAuthRoutes",
+	"AddPolicyRoutes",
+	"AddProjectPasswordRoutes",
+	"AddProjectRoleRoutes",
+	"AddProjectRoutes",
+	"AddProjectUserRoutes",
+	"AddReportRoutes",
+	"AddRoleRoutes",
+	"AddRouteListRoutes",
+	"AddServiceAccountRoutes",
+	"AddUserChangeRoutes",
+	"AddUserLookupRoutes",
+	"AddUserMembershipRoutes",
+	"AddUserRoutes",
+	"AddUserValidationRoutes",
+	"AddVersionRoutes",
+	"AddWebAuthnCredentialRoutes",
+	"AddWebAuthnLoginRoutes",
+	"AddWebhookRoutes",
+}
+
+// callerName returns the unqualified name of the function calling the caller
+// of callerName
+func callerName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// mount registers routes on r in order, so more specific paths must come
+// before the patterns that would also match them. The routes are recorded
+// as the table of the AddXxxRoutes function calling mount.
+func mount(r *mux.Router, routes []Route) {
+	table := callerName()
+	for _, route := range routes {
+		options := route.Options
+		if options == nil {
+			options = defaultServerOptions()
+		}
+		var handler http.Handler = kithttp.NewServer(route.Endpoint, route.Decode, route.Encode, options...)
+		if route.Wrap != nil {
+			handler = route.Wrap(handler)
+		}
+		declared := &declaredRoute{Handler: handler, route: route, table: table}
+		muxRoute := r.Methods(route.Method).Path(route.Path).Handler(declared)
+		declared.template, _ = muxRoute.GetPathTemplate()
+		if route.Deprecated != nil {
+			prefix := strings.TrimSuffix(declared.template, route.Path)
+			declared.Handler = route.Deprecated.wrap(route.Method, declared.template, prefix)(handler)
+		}
+	}
+}
+
+// exampleID fills every path variable of the requests VerifyRoutes decodes
+const exampleID = "00000000-0000-4000-8000-000000000001"
+
+// pathVar matches a variable in a mux path template, with or without a pattern
+var pathVar = regexp.MustCompile(`\{([^{}:]+)(?::[^{}]*)?\}`)
+
+// VerifyRoutes checks the wiring of every declared route on r: that it has an
+// endpoint, a decoder and an encoder, and that decoding a minimal valid
+// request yields the request type the endpoint expects. It also checks that
+// every table in routeTables has routes on r. It returns an error listing
+// every broken route and unmounted table, or nil if there are none. Routes
+// registered without a declaration, such as /metrics, are skipped.
+func VerifyRoutes(r *mux.Router) error {
+	var broken []string
+	mounted := make(map[string]bool)
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		declared, ok := route.GetHandler().(*declaredRoute)
+		if !ok {
+			return nil
+		}
+		mounted[declared.table] = true
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		if err := declared.route.verify(template); err != nil {
+			broken = append(broken, fmt.Sprintf("%s %s: %v", declared.route.Method, template, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walking routes: %w", err)
+	}
+	for _, table := range routeTables {
+		if !mounted[table] {
+			broken = append(broken, fmt.Sprintf("%s: not mounted", table))
+		}
+	}
+	if len(broken) > 0 {
+		return fmt.Errorf("%d broken routes:\n\t%s", len(broken), strings.Join(broken, "\n\t"))
+	}
+	return nil
+}
+
+// verify checks one route mounted at the full path template
+func (route Route) verify(template string) error {
+	var missing []string
+	if route.Endpoint == nil {
+		missing = append(missing, "endpoint")
+	}
+	if route.Decode == nil {
+		missing = append(missing, "decoder")
+	}
+	if route.Encode == nil {
+		missing = append(missing, "encoder")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no %s", strings.Join(missing, ", "))
+	}
+	for _, requires := range []Requirement{route.Requires, route.RequiresForDeleted} {
+		if (requires.Resource == "") != (requires.Action == "") {
+			return fmt.Errorf("required policy needs both a resource and an action")
+		}
+	}
+	statuses := make(map[string]int)
+	for _, declared := range route.Errors {
+		if declared.Code == "" || declared.Status < 400 {
+			return fmt.Errorf("declared error %q needs a code and an error status", declared.Message)
+		}
+		if status, ok := statuses[declared.Code]; ok && status != declared.Status {
+			return fmt.Errorf("error code %s is declared with statuses %d and %d", declared.Code, status, declared.Status)
+		}
+		statuses[declared.Code] = declared.Status
+	}
+
+	body := route.ExampleBody
+	switch route.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		if body == "" {
+			body = "{}"
+		}
+	}
+	request, err := route.decodeExample(template, route.ExampleQuery, body)
+	if err != nil {
+		return fmt.Errorf("decoding an example request: %v", err)
+	}
+	if got, want := reflect.TypeOf(request), reflect.TypeOf(route.Request); got != want {
+		return fmt.Errorf("decoder produces %v, endpoint expects %v", got, want)
+	}
+	return nil
+}
+
+// decodeExample runs the route's decoder on a request with the given query
+// and body, turning a panic into an error so that one broken decoder does not
+// hide the others
+func (route Route) decodeExample(template, query, body string) (request interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("decoder panicked: %v", p)
+		}
+	}()
+
+	vars := make(map[string]string)
+	path := pathVar.ReplaceAllStringFunc(template, func(v string) string {
+		vars[pathVar.FindStringSubmatch(v)[1]] = exampleID
+		return exampleID
+	})
+	if query != "" {
+		path += "?" + query
+	}
+
+	var reader io.Reader = http.NoBody
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(route.Method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return route.Decode(context.Background(), mux.SetURLVars(req, vars))
+}
+
+// ListRoutes returns every route registered on r with a method, in
+// registration order, with the authorization each declared route requires.
+// Routes registered without a declaration, such as /metrics, are public.
+func ListRoutes(r *mux.Router) ([]endpoints.RouteInfo, error) {
+	var routes []endpoints.RouteInfo
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		var requires, forDeleted Requirement
+		var deprecated *Deprecation
+		var prefix string
+		if declared, ok := route.GetHandler().(*declaredRoute); ok {
+			requires, forDeleted = declared.route.Requires, declared.route.RequiresForDeleted
+			deprecated, prefix = declared.route.Deprecated, strings.TrimSuffix(template, declared.route.Path)
+		}
+		for _, method := range methods {
+			info := endpoints.RouteInfo{
+				Method:   method,
+				Path:     template,
+				Auth:     endpoints.AuthPublic,
+				Role:     requires.Role,
+				Resource: requires.Resource,
+				Action:   requires.Action,
+				Self:     requires.Self,
+			}
+			if !requires.Public() {
+				info.Auth = endpoints.AuthBearer
+			}
+			if forDeleted.Role != "" {
+				info.IncludeDeleted = forDeleted.Role
+			} else if forDeleted.Resource != "" {
+				info.IncludeDeleted = forDeleted.Resource + ":" + forDeleted.Action
+			}
+			if deprecated != nil {
+				info.Deprecated = true
+				info.Successor = prefix + deprecated.Successor
+			}
+			routes = append(routes, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking routes: %w", err)
+	}
+	return routes, nil
+}
+
+// Authorization enforces the Requires of the declared route a request
+// matched, and its RequiresForDeleted when the request includes deleted
+// records: the caller is authenticated through authUsers, or against db
+// when it is nil, from a network its role allows, as loaded by networks or
+// from db when it is nil, and must hold the required roles and policies.
+// Unless the route is ConsentPending, callers must also have given the
+// consents their project enforces, as told by consentManager; nil enforces
+// none. The project user a Self route is about is looked up in projectUsers;
+// nil lets no project user through. It must be installed on the router the
+// routes are matched by, or a parent of it.
+func Authorization(db *gorm.DB, authUsers *users.AuthUsers, networks *roles.Networks, consentManager consents.ConsentManager, projectUsers ProjectUserFinder) mux.MiddlewareFunc {
+	if authUsers == nil {
+		authUsers = users.NewAuthUsers(db, 0)
+	}
+	if networks == nil {
+		networks = roles.NewNetworks(db, 0)
+	}
+	authenticate := auth.AuthMiddleware(authUsers)
+	authenticateForPasswordChange := auth.PasswordChangeMiddleware(authUsers)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var requirements []Requirement
+			if route := mux.CurrentRoute(r); route != nil {
+				if declared, ok := route.GetHandler().(*declaredRoute); ok {
+					requirements = append(requirements, declared.route.Requires)
+					// A malformed include_deleted is left for the decoder to reject
+					if includeDeleted, _ := parseIncludeDeleted(r); includeDeleted {
+						requirements = append(requirements, declared.route.RequiresForDeleted)
+					}
+				}
+			}
+
+			handler, public, passwordChange, consentPending, self := next, true, false, false, ""
+			for _, requires := range requirements {
+				if requires.Public() {
+					continue
+				}
+				public = false
+				passwordChange = passwordChange || requires.PasswordChange
+				consentPending = consentPending || requires.ConsentPending
+				if requires.Self != "" {
+					self = requires.Self
+				}
+				if requires.Resource != "" && requires.Project != "" {
+					handler = auth.ProjectPolicyMiddleware(db, authUsers, requires.Project, requires.Resource, requires.Action)(handler)
+				} else if requires.Resource != "" {
+					handler = auth.PolicyMiddleware(db, authUsers, requires.Resource, requires.Action)(handler)
+				}
+				if requires.Role != "" {
+					handler = auth.RoleMiddleware(db, authUsers, requires.Role)(handler)
+				}
+			}
+			if public {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !consentPending && consentManager != nil {
+				handler = requireConsents(consentManager)(handler)
+			}
+			handler = allowedNetworks(db, networks)(handler)
+			if passwordChange {
+				handler = authenticateForPasswordChange(handler)
+			} else {
+				handler = authenticate(handler)
+			}
+			if self != "" && projectUsers != nil {
+				handler = projectUserSelf(projectUsers, networks, self, handler)(next)
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
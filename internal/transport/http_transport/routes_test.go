@@ -0,0 +1,155 @@
+package http_transport_test
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/internal/transport/http_transport"
+)
+
+type exampleRequest struct{ ID string }
+
+func nop(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+func decodeExample(_ context.Context, r *http.Request) (interface{}, error) {
+	return exampleRequest{ID: mux.Vars(r)["id"]}, nil
+}
+
+func encodeNothing(context.Context, http.ResponseWriter, interface{}) error { return nil }
+
+func TestVerifyRoutesReportsBrokenRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		route http_transport.Route
+		want  string
+	}{
+		{
+			"no decoder",
+			http_transport.Route{Endpoint: nop, Encode: encodeNothing, Request: exampleRequest{}},
+			"no decoder",
+		},
+		{
+			"no endpoint or encoder",
+			http_transport.Route{Decode: decodeExample, Request: exampleRequest{}},
+			"no endpoint, encoder",
+		},
+		{
+			"the wrong request type",
+			http_transport.Route{Endpoint: nop, Decode: decodeExample, Encode: encodeNothing, Request: endpoints.GetUserRequest{}},
+			"decoder produces http_transport_test.exampleRequest, endpoint expects endpoints.GetUserRequest",
+		},
+		{
+			"a failing decoder",
+			http_transport.Route{
+				Endpoint: nop,
+				Decode: func(context.Context, *http.Request) (interface{}, error) {
+					return nil, errors.New("no project in the path")
+				},
+				Encode:  encodeNothing,
+				Request: exampleRequest{},
+			},
+			"decoding an example request: no project in the path",
+		},
+		{
+			"a panicking decoder",
+			http_transport.Route{
+				Endpoint: nop,
+				Decode: func(context.Context, *http.Request) (interface{}, error) {
+					panic("nil body")
+				},
+				Encode:  encodeNothing,
+				Request: exampleRequest{},
+			},
+			"decoding an example request: decoder panicked: nil body",
+		},
+		{
+			"half a policy",
+			http_transport.Route{
+				Endpoint: nop,
+				Decode:   decodeExample,
+				Encode:   encodeNothing,
+				Request:  exampleRequest{},
+				Requires: http_transport.Requirement{Resource: "users"},
+			},
+			"required policy needs both a resource and an action",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			healthy := http_transport.Route{Method: "GET", Path: "/healthy/{id}", Endpoint: nop, Decode: decodeExample, Encode: encodeNothing, Request: exampleRequest{}}
+			broken := tc.route
+			broken.Method, broken.Path = "GET", "/broken/{id}"
+			http_transport.Mount(router, []http_transport.Route{healthy, broken})
+
+			err := http_transport.VerifyRoutes(router)
+			if err == nil {
+				t.Fatal("VerifyRoutes passed a broken route")
+			}
+			if want := "GET /broken/{id}: " + tc.want; !strings.Contains(err.Error(), want) {
+				t.Errorf("VerifyRoutes = %v, want it to report %q", err, want)
+			}
+			if strings.Contains(err.Error(), "/healthy") {
+				t.Errorf("VerifyRoutes = %v, which reports the healthy route", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRoutesReportsUnmountedTables(t *testing.T) {
+	router := mux.NewRouter()
+	http_transport.AddVersionRoutes(router, endpoints.NewVersionEndpoint())
+
+	err := http_transport.VerifyRoutes(router)
+	if err == nil {
+		t.Fatal("VerifyRoutes passed a router missing every table but one")
+	}
+	for _, table := range http_transport.RouteTables {
+		reported := strings.Contains(err.Error(), table+": not mounted")
+		if want := table != "AddVersionRoutes"; reported != want {
+			t.Errorf("%s reported as unmounted: %v, want %v", table, reported, want)
+		}
+	}
+}
+
+// TestRouteTablesAreComplete checks that routeTables names every
+// AddXxxRoutes function of the package, so that none escapes VerifyRoutes
+func TestRouteTablesAreComplete(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var declared []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Add") && strings.HasSuffix(fn.Name.Name, "Routes") {
+				declared = append(declared, fn.Name.Name)
+			}
+		}
+	}
+	sort.Strings(declared)
+
+	listed := append([]string(nil), http_transport.RouteTables...)
+	sort.Strings(listed)
+	if strings.Join(declared, " ") != strings.Join(listed, " ") {
+		t.Errorf("routeTables lists\n\t%v\nbut the package declares\n\t%v", listed, declared)
+	}
+}
@@ -0,0 +1,87 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/users"
+)
+
+// AddServiceAccountRoutes registers the service account routes on the
+// projects router
+func AddServiceAccountRoutes(r *mux.Router, ep *endpoints.ServiceAccountsEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/{projectId}/service-accounts",
+			Endpoint: ep.ListServiceAccounts,
+			Decode:   decodeListServiceAccountsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListServiceAccountsRequest{},
+			Requires: AdminOnly,
+		},
+		// POST - Create a service account; issue it API tokens through
+		// POST /{projectId}/api-tokens with its ID as service_account_id
+		{
+			Method:   "POST",
+			Path:     "/{projectId}/service-accounts",
+			Endpoint: ep.CreateServiceAccount,
+			Decode:   decodeCreateServiceAccountRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateServiceAccountRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				users.ErrServiceAccountNameRequired,
+				endpoints.ErrInvalidRole,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{projectId}/service-accounts/{id}",
+			Endpoint: ep.DeleteServiceAccount,
+			Decode:   decodeDeleteServiceAccountRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteServiceAccountRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				users.ErrServiceAccountNotFound,
+			},
+		},
+	})
+}
+
+func decodeListServiceAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListServiceAccountsRequest{ProjectID: projectID}, nil
+}
+
+func decodeCreateServiceAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var req endpoints.CreateServiceAccountRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ProjectID = projectID
+	return req, nil
+}
+
+func decodeDeleteServiceAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectID, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.DeleteServiceAccountRequest{ProjectID: projectID, ID: id}, nil
+}
@@ -4,56 +4,102 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/password"
 	"github.com/yash3004/user_management_service/internal/transport/endpoints"
-	"k8s.io/klog/v2"
-
-	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/users"
 )
 
+// AddUserRoutes registers the routes managing global users, mounted under
+// /users
 func AddUserRoutes(r *mux.Router, ep *endpoints.UsersEndpoint) {
-
-	// GET - List all users
-	r.Methods("GET").Path("/{id}").Handler(kithttp.NewServer(
-		ep.GetUser,
-		decodeGetUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	// POST - Create new user
-	r.Methods("POST").Path("").Handler(kithttp.NewServer(
-		ep.CreateUser,
-		decodeCreateUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("PUT").Path("/{id}").Handler(kithttp.NewServer(
-		ep.UpdateUser,
-		decodeUpdateUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("DELETE").Path("/{id}").Handler(kithttp.NewServer(
-		ep.DeleteUser,
-		decodeDeleteUserRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
-	r.Methods("POST").Path("/{id}/change-password").Handler(kithttp.NewServer(
-		ep.ChangePassword,
-		decodeChangePasswordRequest,
-		encodeResponse,
-		defaultServerOptions()...,
-	))
-
+	mount(r, []Route{
+		// GET - Get a user
+		{
+			Method:             "GET",
+			Path:               "/{id}",
+			Endpoint:           ep.GetUser,
+			Decode:             decodeGetUserRequest,
+			Encode:             encodeResponse,
+			Request:            endpoints.GetUserRequest{},
+			Requires:           Requirement{Resource: "users", Action: "read"},
+			RequiresForDeleted: Requirement{Resource: "users", Action: "read"},
+			Errors: []*apierrors.Error{
+				users.ErrUserNotFound,
+			},
+		},
+		// POST - Create new user
+		{
+			Method:   "POST",
+			Path:     "",
+			Endpoint: ep.CreateUser,
+			Decode:   decodeCreateUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				endpoints.ErrInvalidRole,
+				users.ErrEmailTaken,
+				password.ErrTooShort,
+				password.ErrTooLong,
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}",
+			Endpoint: ep.UpdateUser,
+			Decode:   decodeUpdateUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.UpdateUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				errInvalidStatusTransition,
+			},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{id}/status",
+			Endpoint: ep.SetUserStatus,
+			Decode:   decodeSetUserStatusRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.SetUserStatusRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				userstatus.ErrUnknownStatus,
+				errInvalidStatusTransition,
+				errConcurrentStatusChange,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{id}",
+			Endpoint: ep.DeleteUser,
+			Decode:   decodeDeleteUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "delete"},
+		},
+		{
+			Method:   "POST",
+			Path:     "/{id}/change-password",
+			Endpoint: ep.ChangePassword,
+			Decode:   decodeChangePasswordRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ChangePasswordRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+			Errors: []*apierrors.Error{
+				password.ErrTooShort,
+				password.ErrTooLong,
+				users.ErrPasswordReused,
+			},
+		},
+	})
 }
 
 func decodeGetUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -62,30 +108,25 @@ func decodeGetUserRequest(_ context.Context, r *http.Request) (interface{}, erro
 	if !ok {
 		return nil, ErrBadRouting
 	}
-	return endpoints.GetUserRequest{ID: id}, nil
-}
-
-func decodeCreateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	projectId, err := GetProjectIDFromRequest(r)
+	includeDeleted, err := parseIncludeDeleted(r)
 	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
 		return nil, err
 	}
+	return endpoints.GetUserRequest{ID: id, IncludeDeleted: includeDeleted}, nil
+}
+
+// decodeCreateUserRequest decodes a global user, whose project_id names the
+// project they are created in
+func decodeCreateUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	var req endpoints.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		klog.Errorf("Error decoding request body: %v", err)
+	if err := decodeJSONBody(r, &req); err != nil {
+		log.For(ctx).Errorf("Error decoding request body: %v", err)
 		return nil, err
 	}
-	req.ProjectID = projectId
 	return req, nil
 }
 
 func decodeUpdateUserRequest(ctx_ context.Context, r *http.Request) (interface{}, error) {
-	projectId, err := GetProjectIDFromRequest(r)
-	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
-		return nil, err
-	}
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok {
@@ -93,33 +134,169 @@ func decodeUpdateUserRequest(ctx_ context.Context, r *http.Request) (interface{}
 	}
 
 	var req endpoints.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
 	req.ID = id
-	req.ProjectId = projectId
 
 	return req, nil
 }
 
+func decodeSetUserStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.SetUserStatusRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = id
+	return req, nil
+}
+
 func decodeDeleteUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	projectId, err := GetProjectIDFromRequest(r)
-	if err != nil {
-		klog.Errorf("Error getting project ID from request: %v", err)
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.DeleteUserRequest{ID: id}, nil
+}
+
+// AddUserValidationRoutes registers the route dry-running a user creation
+func AddUserValidationRoutes(r *mux.Router, ep *endpoints.UsersEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/validate",
+			Endpoint: ep.ValidateUser,
+			Decode:   decodeValidateUserRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ValidateUserRequest{},
+			Requires: Requirement{Resource: "users", Action: "write"},
+		},
+	})
+}
+
+func decodeValidateUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req endpoints.ValidateUserRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// AddUserMembershipRoutes registers the routes managing the additional
+// projects a global user belongs to. Granting a project role is an
+// escalation, so only super admins may do it.
+func AddUserMembershipRoutes(r *mux.Router, ep *endpoints.UsersEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/{id}/projects",
+			Endpoint: ep.ListUserProjects,
+			Decode:   decodeListUserProjectsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListUserProjectsRequest{},
+			Requires: AdminOnly,
+		},
+		{
+			Method:   "POST",
+			Path:     "/{id}/projects",
+			Endpoint: ep.AddUserToProject,
+			Decode:   decodeAddUserToProjectRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.AddUserToProjectRequest{},
+			Requires: AdminOnly,
+			Errors: []*apierrors.Error{
+				users.ErrRoleOfAnotherProject,
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{id}/projects/{projectId}",
+			Endpoint: ep.RemoveUserFromProject,
+			Decode:   decodeRemoveUserFromProjectRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RemoveUserFromProjectRequest{},
+			Requires: AdminOnly,
+		},
+	})
+}
+
+// AddUserLookupRoutes registers the route finding a user's email across
+// projects, mounted under /admin/users
+func AddUserLookupRoutes(r *mux.Router, ep *endpoints.UsersEndpoint) {
+	mount(r, []Route{
+		{
+			Method:       "GET",
+			Path:         "/lookup",
+			Endpoint:     ep.LookupUsers,
+			Decode:       decodeLookupUsersRequest,
+			Encode:       encodeResponse,
+			Request:      endpoints.LookupUsersRequest{},
+			Requires:     Requirement{Resource: "users", Action: "lookup"},
+			ExampleQuery: "email=alice@example.com",
+			Errors: []*apierrors.Error{
+				users.ErrLookupEmailRequired,
+			},
+		},
+	})
+}
+
+func decodeLookupUsersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.LookupUsersRequest{Email: r.URL.Query().Get("email")}, nil
+}
+
+func decodeListUserProjectsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.ListUserProjectsRequest{UserID: id}, nil
+}
+
+func decodeAddUserToProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	var req endpoints.AddUserToProjectRequest
+	if err := decodeJSONBody(r, &req); err != nil {
 		return nil, err
 	}
+	req.UserID = id
+
+	return req, nil
+}
+
+func decodeRemoveUserFromProjectRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
 	if !ok {
 		return nil, ErrBadRouting
 	}
-	return endpoints.DeleteUserRequest{ID: id, ProjectId: projectId}, nil
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.RemoveUserFromProjectRequest{UserID: id, ProjectID: projectId}, nil
 }
 
 // ErrBadRouting is returned when the route cannot be determined from the URL
 var ErrBadRouting = errors.New("inconsistent mapping between route and handler")
 
 func GetProjectIDFromRequest(r *http.Request) (string, error) {
+	// Prefer the project resolved by ProjectContext, whose ID is canonical
+	if project, ok := projects.ProjectFromContext(r.Context()); ok {
+		return project.ID.String(), nil
+	}
 	vars := mux.Vars(r)
 	projectID, ok := vars["projectId"]
 	if !ok {
@@ -146,7 +323,7 @@ func decodeChangePasswordRequest(_ context.Context, r *http.Request) (interface{
 	}
 
 	var req endpoints.ChangePasswordRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSONBody(r, &req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,26 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddVersionRoutes registers the build version route on the API router
+func AddVersionRoutes(r *mux.Router, ep *endpoints.VersionEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/version",
+			Endpoint: ep.GetVersion,
+			Decode:   decodeVersionRequest,
+			Encode:   encodeResponse,
+		},
+	})
+}
+
+func decodeVersionRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
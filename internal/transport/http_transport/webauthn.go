@@ -0,0 +1,180 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/webauthn"
+)
+
+// AddWebAuthnCredentialRoutes registers passkey enrollment and management
+// routes on the /{projectId}/users router. A project user manages their own
+// passkeys with the token they logged in with; admins of the project need
+// users:write, or users:read to list them.
+func AddWebAuthnCredentialRoutes(r *mux.Router, ep *endpoints.WebAuthnEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/{user_id}/webauthn/register/begin",
+			Endpoint: ep.BeginRegistration,
+			Decode:   decodeBeginWebAuthnRegistrationRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.BeginWebAuthnRegistrationRequest{},
+			Requires: Requirement{Resource: "users", Action: "write", Self: "user_id"},
+			Errors: []*apierrors.Error{
+				webauthn.ErrDisabled,
+				errAccountNotActive,
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/{user_id}/webauthn/register/finish",
+			Endpoint: ep.FinishRegistration,
+			Decode:   decodeFinishWebAuthnRegistrationRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.FinishWebAuthnRegistrationRequest{},
+			Requires: Requirement{Resource: "users", Action: "write", Self: "user_id"},
+			Errors: []*apierrors.Error{
+				webauthn.ErrDisabled,
+				webauthn.ErrUnknownChallenge,
+				webauthn.ErrInvalidCredential,
+				apierrors.Conflict("credential is already registered"),
+			},
+		},
+		{
+			Method:   "GET",
+			Path:     "/{user_id}/webauthn/credentials",
+			Endpoint: ep.ListCredentials,
+			Decode:   decodeListWebAuthnCredentialsRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListWebAuthnCredentialsRequest{},
+			Requires: Requirement{Resource: "users", Action: "read", Self: "user_id"},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/{user_id}/webauthn/credentials/{credentialId}",
+			Endpoint: ep.RenameCredential,
+			Decode:   decodeRenameWebAuthnCredentialRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.RenameWebAuthnCredentialRequest{},
+			Requires: Requirement{Resource: "users", Action: "write", Self: "user_id"},
+			Errors: []*apierrors.Error{
+				webauthn.ErrCredentialNotFound,
+				apierrors.BadRequest("NAME_REQUIRED", "name is required"),
+				apierrors.BadRequest("NAME_TOO_LONG", "name must be at most 100 characters"),
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{user_id}/webauthn/credentials/{credentialId}",
+			Endpoint: ep.DeleteCredential,
+			Decode:   decodeDeleteWebAuthnCredentialRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteWebAuthnCredentialRequest{},
+			Requires: Requirement{Resource: "users", Action: "write", Self: "user_id"},
+			Errors: []*apierrors.Error{
+				webauthn.ErrCredentialNotFound,
+			},
+		},
+	})
+}
+
+// AddWebAuthnLoginRoutes registers passkey login routes on the
+// /{projectId}/auth router
+func AddWebAuthnLoginRoutes(r *mux.Router, ep *endpoints.WebAuthnEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "POST",
+			Path:     "/webauthn/login/begin",
+			Endpoint: ep.BeginLogin,
+			Decode:   decodeBeginWebAuthnLoginRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.BeginWebAuthnLoginRequest{},
+			Errors: []*apierrors.Error{
+				webauthn.ErrDisabled,
+			},
+		},
+		{
+			Method:   "POST",
+			Path:     "/webauthn/login/finish",
+			Endpoint: ep.FinishLogin,
+			Decode:   decodeFinishWebAuthnLoginRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.FinishWebAuthnLoginRequest{},
+			Errors: []*apierrors.Error{
+				webauthn.ErrDisabled,
+				webauthn.ErrUnknownChallenge,
+				webauthn.ErrInvalidCredential,
+				errAccountNotActive,
+				roles.ErrIPNotAllowed,
+			},
+		},
+	})
+}
+
+func decodeBeginWebAuthnRegistrationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.BeginWebAuthnRegistrationRequest{
+		ProjectID: vars["projectId"],
+		UserID:    vars["user_id"],
+	}, nil
+}
+
+func decodeFinishWebAuthnRegistrationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var request endpoints.FinishWebAuthnRegistrationRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = vars["projectId"]
+	request.UserID = vars["user_id"]
+	return request, nil
+}
+
+func decodeListWebAuthnCredentialsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.ListWebAuthnCredentialsRequest{
+		ProjectID: vars["projectId"],
+		UserID:    vars["user_id"],
+	}, nil
+}
+
+func decodeRenameWebAuthnCredentialRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	var request endpoints.RenameWebAuthnCredentialRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = vars["projectId"]
+	request.UserID = vars["user_id"]
+	request.CredentialID = vars["credentialId"]
+	return request, nil
+}
+
+func decodeDeleteWebAuthnCredentialRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return endpoints.DeleteWebAuthnCredentialRequest{
+		ProjectID:    vars["projectId"],
+		UserID:       vars["user_id"],
+		CredentialID: vars["credentialId"],
+	}, nil
+}
+
+func decodeBeginWebAuthnLoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return endpoints.BeginWebAuthnLoginRequest{
+		ProjectID: mux.Vars(r)["projectId"],
+	}, nil
+}
+
+func decodeFinishWebAuthnLoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request endpoints.FinishWebAuthnLoginRequest
+	if err := decodeJSONBody(r, &request); err != nil {
+		return nil, err
+	}
+	request.ProjectID = mux.Vars(r)["projectId"]
+	return request, nil
+}
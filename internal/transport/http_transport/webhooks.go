@@ -0,0 +1,97 @@
+package http_transport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/transport/endpoints"
+)
+
+// AddWebhookRoutes registers the webhook subscription routes on the projects
+// router. Managing a project's subscriptions needs projects:write in it.
+func AddWebhookRoutes(r *mux.Router, ep *endpoints.WebhooksEndpoint) {
+	mount(r, []Route{
+		{
+			Method:   "GET",
+			Path:     "/webhooks/events",
+			Endpoint: ep.ListWebhookEvents,
+			Decode:   decodeListWebhookEventsRequest,
+			Encode:   encodeResponse,
+			Request:  struct{}{},
+			Requires: SignedIn,
+		},
+		{
+			Method:   "GET",
+			Path:     "/{projectId}/webhooks",
+			Endpoint: ep.ListWebhooks,
+			Decode:   decodeListWebhooksRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.ListWebhooksRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+		},
+		{
+			Method:   "POST",
+			Path:     "/{projectId}/webhooks",
+			Endpoint: ep.CreateWebhook,
+			Decode:   decodeCreateWebhookRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.CreateWebhookRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.BadRequest("VALIDATION_FAILED", "url must be an absolute http or https URL"),
+				apierrors.BadRequest("UNKNOWN_EVENT", "unknown event user.renamed"),
+			},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/{projectId}/webhooks/{webhookId}",
+			Endpoint: ep.DeleteWebhook,
+			Decode:   decodeDeleteWebhookRequest,
+			Encode:   encodeResponse,
+			Request:  endpoints.DeleteWebhookRequest{},
+			Requires: Requirement{Resource: "projects", Action: "write"},
+			Errors: []*apierrors.Error{
+				apierrors.NotFound("webhook subscription not found"),
+			},
+		},
+	})
+}
+
+func decodeListWebhookEventsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+func decodeListWebhooksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.ListWebhooksRequest{ProjectID: projectId}, nil
+}
+
+func decodeCreateWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var req endpoints.CreateWebhookRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	req.ProjectID = projectId
+	return req, nil
+}
+
+func decodeDeleteWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	projectId, err := GetProjectIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := mux.Vars(r)["webhookId"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+	return endpoints.DeleteWebhookRequest{ProjectID: projectId, ID: id}, nil
+}
@@ -0,0 +1,128 @@
+// Package userstatus is the lifecycle of users and project users. A user's
+// status says whether they may log in, and the state machine here is the one
+// place that decides which status changes are allowed.
+package userstatus
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// All lists every status, in lifecycle order
+var All = []string{
+	schemas.UserStatusInvited,
+	schemas.UserStatusPendingVerification,
+	schemas.UserStatusActive,
+	schemas.UserStatusSuspended,
+	schemas.UserStatusPendingDeletion,
+	schemas.UserStatusDeactivated,
+}
+
+// transitions maps each status to the statuses it may change to
+var transitions = map[string][]string{
+	schemas.UserStatusInvited: {
+		schemas.UserStatusPendingVerification,
+		schemas.UserStatusActive,
+		schemas.UserStatusDeactivated,
+	},
+	schemas.UserStatusPendingVerification: {
+		schemas.UserStatusActive,
+		schemas.UserStatusDeactivated,
+	},
+	schemas.UserStatusActive: {
+		schemas.UserStatusSuspended,
+		schemas.UserStatusPendingDeletion,
+		schemas.UserStatusDeactivated,
+	},
+	schemas.UserStatusSuspended: {
+		schemas.UserStatusActive,
+		schemas.UserStatusPendingDeletion,
+		schemas.UserStatusDeactivated,
+	},
+	schemas.UserStatusPendingDeletion: {
+		schemas.UserStatusActive,
+		schemas.UserStatusDeactivated,
+	},
+	schemas.UserStatusDeactivated: {
+		schemas.UserStatusActive,
+		schemas.UserStatusPendingDeletion,
+	},
+}
+
+// ErrUnknownStatus is returned for a status that is not one of All
+var ErrUnknownStatus = apierrors.BadRequest("INVALID_STATUS", "status must be one of "+strings.Join(All, ", "))
+
+// Valid reports whether status is a known status
+func Valid(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// Next returns the statuses a user in status may change to
+func Next(status string) []string {
+	return append([]string{}, transitions[status]...)
+}
+
+// CanTransition reports whether a user may change from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns nil if a user may change from one status to another, a 400
+// for an unknown target status and a 409 for a change the lifecycle forbids
+func Check(from, to string) error {
+	if !Valid(to) {
+		return ErrUnknownStatus
+	}
+	if !CanTransition(from, to) {
+		return apierrors.New(http.StatusConflict, "INVALID_STATUS_TRANSITION",
+			fmt.Sprintf("cannot change status from %s to %s", from, to))
+	}
+	return nil
+}
+
+// CanLogin reports whether a user in status may log in
+func CanLogin(status string) bool {
+	return status == schemas.UserStatusActive
+}
+
+// ActivatedByEmail reports whether proving control of the email address, e.g.
+// by following a magic link, activates a user in status
+func ActivatedByEmail(status string) bool {
+	return status == schemas.UserStatusInvited || status == schemas.UserStatusPendingVerification
+}
+
+// LoginErrorCode is the code of the errors LoginError returns
+const LoginErrorCode = "ACCOUNT_NOT_ACTIVE"
+
+// LoginError is the error returned when a user in status tries to log in
+func LoginError(status string) error {
+	return apierrors.New(http.StatusForbidden, LoginErrorCode, "account is "+strings.ReplaceAll(status, "_", " "))
+}
+
+// FromActive maps the legacy active flag to a status
+func FromActive(active bool) string {
+	if active {
+		return schemas.UserStatusActive
+	}
+	return schemas.UserStatusDeactivated
+}
+
+// ApplyActive returns the status a user in current ends up in when a client
+// sets the legacy active flag. Leaving the flag as it is keeps the status, so
+// updating the profile of a suspended user does not deactivate them.
+func ApplyActive(current string, active bool) string {
+	if active == CanLogin(current) {
+		return current
+	}
+	return FromActive(active)
+}
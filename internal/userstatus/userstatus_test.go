@@ -0,0 +1,111 @@
+package userstatus_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+)
+
+const (
+	invited   = schemas.UserStatusInvited
+	pending   = schemas.UserStatusPendingVerification
+	active    = schemas.UserStatusActive
+	suspended = schemas.UserStatusSuspended
+	deleting  = schemas.UserStatusPendingDeletion
+	gone      = schemas.UserStatusDeactivated
+)
+
+// allowed is the lifecycle, written out independently of the package's table
+var allowed = map[string]map[string]bool{
+	invited:   {pending: true, active: true, gone: true},
+	pending:   {active: true, gone: true},
+	active:    {suspended: true, deleting: true, gone: true},
+	suspended: {active: true, deleting: true, gone: true},
+	deleting:  {active: true, gone: true},
+	gone:      {active: true, deleting: true},
+}
+
+// TestCheckEveryTransition checks every pair of statuses, including staying
+// in the same one, which is never a transition
+func TestCheckEveryTransition(t *testing.T) {
+	if len(userstatus.All) != len(allowed) {
+		t.Fatalf("the lifecycle has %d statuses, the test knows %d", len(userstatus.All), len(allowed))
+	}
+	for _, from := range userstatus.All {
+		for _, to := range userstatus.All {
+			want := allowed[from][to]
+			if got := userstatus.CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+
+			err := userstatus.Check(from, to)
+			if want {
+				if err != nil {
+					t.Errorf("Check(%s, %s) = %v, want nil", from, to, err)
+				}
+				continue
+			}
+			var apiErr *apierrors.Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusConflict || apiErr.Code != "INVALID_STATUS_TRANSITION" {
+				t.Errorf("Check(%s, %s) = %v, want a 409 INVALID_STATUS_TRANSITION", from, to, err)
+			}
+		}
+	}
+}
+
+func TestNextListsTheAllowedTransitions(t *testing.T) {
+	for _, from := range userstatus.All {
+		next := userstatus.Next(from)
+		if len(next) != len(allowed[from]) {
+			t.Errorf("Next(%s) = %v, want %d statuses", from, next, len(allowed[from]))
+		}
+		for _, to := range next {
+			if !allowed[from][to] {
+				t.Errorf("Next(%s) lists %s", from, to)
+			}
+		}
+	}
+}
+
+func TestCheckRejectsAnUnknownStatus(t *testing.T) {
+	if err := userstatus.Check(active, "archived"); err != userstatus.ErrUnknownStatus {
+		t.Fatalf("Check to an unknown status = %v, want %v", err, userstatus.ErrUnknownStatus)
+	}
+	if userstatus.Valid("archived") || userstatus.CanTransition("archived", active) {
+		t.Fatal("an unknown status is part of the lifecycle")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	for _, status := range userstatus.All {
+		if got := userstatus.CanLogin(status); got != (status == active) {
+			t.Errorf("CanLogin(%s) = %v", status, got)
+		}
+		if got := userstatus.ActivatedByEmail(status); got != (status == invited || status == pending) {
+			t.Errorf("ActivatedByEmail(%s) = %v", status, got)
+		}
+	}
+}
+
+func TestActiveFlag(t *testing.T) {
+	for _, tc := range []struct {
+		current string
+		active  bool
+		want    string
+	}{
+		{active, true, active},
+		{active, false, gone},
+		{suspended, false, suspended},
+		{invited, false, invited},
+		{suspended, true, active},
+		{gone, true, active},
+	} {
+		if got := userstatus.ApplyActive(tc.current, tc.active); got != tc.want {
+			t.Errorf("ApplyActive(%s, %v) = %s, want %s", tc.current, tc.active, got, tc.want)
+		}
+	}
+}
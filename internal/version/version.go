@@ -0,0 +1,50 @@
+// Package version reports which build of the service is running. The values
+// are set at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/yash3004/user_management_service/internal/version.Version=v1.2.3" ./cmd/server
+//
+// and fall back to "dev" and "unknown" in builds that do not set them.
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Set with -ldflags "-X ..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the running build's version information
+func Get() Info {
+	return Info{
+		Version:   orDefault(Version, "dev"),
+		Commit:    orDefault(Commit, "unknown"),
+		BuildTime: orDefault(BuildTime, "unknown"),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String renders the build for logs and the version subcommand
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.BuildTime, i.GoVersion)
+}
+
+// orDefault guards against -X flags that set a variable to the empty string
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
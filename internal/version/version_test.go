@@ -0,0 +1,46 @@
+package version_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/version"
+)
+
+// inject sets the build variables the way -ldflags -X does, restoring them
+// when the test ends
+func inject(t *testing.T, v, commit, buildTime string) {
+	t.Helper()
+
+	saved := [3]string{version.Version, version.Commit, version.BuildTime}
+	t.Cleanup(func() {
+		version.Version, version.Commit, version.BuildTime = saved[0], saved[1], saved[2]
+	})
+	version.Version, version.Commit, version.BuildTime = v, commit, buildTime
+}
+
+func TestGet(t *testing.T) {
+	for _, tc := range []struct {
+		name                   string
+		version, commit, built string
+		want                   version.Info
+	}{
+		{"injected", "v1.2.3", "abc1234", "2026-03-01T09:00:00Z", version.Info{Version: "v1.2.3", Commit: "abc1234", BuildTime: "2026-03-01T09:00:00Z"}},
+		{"set to empty strings", "", "", "", version.Info{Version: "dev", Commit: "unknown", BuildTime: "unknown"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inject(t, tc.version, tc.commit, tc.built)
+			tc.want.GoVersion = runtime.Version()
+			if got := version.Get(); got != tc.want {
+				t.Fatalf("Get() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	got := version.Get()
+	if got.Version != "dev" || got.Commit != "unknown" || got.BuildTime != "unknown" {
+		t.Fatalf("a build without -ldflags reports %+v, want dev and unknown", got)
+	}
+}
@@ -0,0 +1,34 @@
+package logins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Client describes where a request came from
+type Client struct {
+	IPAddress      string
+	UserAgent      string
+	AcceptLanguage string
+}
+
+// DeviceHash fingerprints the client's device. It is only a hash of the user
+// agent and accept-language, so it tells browsers apart rather than machines.
+func (c Client) DeviceHash() string {
+	sum := sha256.Sum256([]byte(c.UserAgent + "\n" + c.AcceptLanguage))
+	return hex.EncodeToString(sum[:])
+}
+
+type clientKey struct{}
+
+// WithClient returns a context carrying the client making the request
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client stored by WithClient
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
@@ -0,0 +1,259 @@
+// Package logins keeps the login history of project users and flags logins
+// from an IP address or device a user has not used before. The check runs in
+// the background so it never delays the login response.
+package logins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/mailer"
+	"github.com/yash3004/user_management_service/webhooks"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("logins")
+
+// Login methods recorded on login events
+const (
+	MethodMagicLink = "magic_link"
+	MethodWebAuthn  = "webauthn"
+	MethodOAuth     = "oauth" // Suffixed with ":<provider>"
+)
+
+// DefaultMinHistory is the number of earlier logins needed before new IPs or
+// devices are flagged, for projects that do not set one
+const DefaultMinHistory = 1
+
+// LoginRecorder records successful logins
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, projectID, userID uuid.UUID, email, method string)
+}
+
+// LoginManager records logins and reads back a user's login history
+type LoginManager interface {
+	LoginRecorder
+	ListLogins(ctx context.Context, projectID, userID uuid.UUID, limit int) ([]schemas.LoginEvent, error)
+}
+
+// Limits on the number of login events returned by ListLogins
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// NopRecorder discards logins
+type NopRecorder struct{}
+
+// RecordLogin implements LoginRecorder
+func (NopRecorder) RecordLogin(context.Context, uuid.UUID, uuid.UUID, string, string) {}
+
+// SuspiciousLogin is the data of the user.suspicious_login event
+type SuspiciousLogin struct {
+	LoginEventID string    `json:"login_event_id"`
+	UserID       string    `json:"user_id"`
+	Email        string    `json:"email"`
+	Method       string    `json:"method"`
+	IPAddress    string    `json:"ip_address"`
+	NewIP        bool      `json:"new_ip"`
+	NewDevice    bool      `json:"new_device"`
+	At           time.Time `json:"at"`
+}
+
+// Manager implements the LoginManager interface
+type Manager struct {
+	DB     *gorm.DB
+	Clock  clock.Clock
+	Events webhooks.Publisher
+	Mailer mailer.Mailer
+
+	wg sync.WaitGroup
+}
+
+// NewManager creates a new login manager
+func NewManager(db *gorm.DB, events webhooks.Publisher, m mailer.Mailer) LoginManager {
+	return &Manager{
+		DB:     db,
+		Clock:  clock.Real{},
+		Events: events,
+		Mailer: m,
+	}
+}
+
+// RecordLogin stores the login and checks it against the user's history in
+// the background
+func (m *Manager) RecordLogin(ctx context.Context, projectID, userID uuid.UUID, email, method string) {
+	client := ClientFromContext(ctx)
+	now := m.Clock.Now()
+	ctx = context.WithoutCancel(ctx)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.record(ctx, projectID, userID, email, method, client, now)
+	}()
+}
+
+// Wait blocks until logins recorded so far have been processed
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// ListLogins returns a user's most recent logins, newest first. A limit of
+// zero or less means DefaultListLimit; it is capped at MaxListLimit.
+func (m *Manager) ListLogins(ctx context.Context, projectID, userID uuid.UUID, limit int) ([]schemas.LoginEvent, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	events := []schemas.LoginEvent{}
+	if err := m.DB.
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	return events, nil
+}
+
+func (m *Manager) record(ctx context.Context, projectID, userID uuid.UUID, email, method string, client Client, now time.Time) {
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return
+	}
+	settings := project.Settings.SuspiciousLogin
+
+	event := schemas.LoginEvent{
+		ID:         uuid.New(),
+		Method:     method,
+		IPAddress:  client.IPAddress,
+		DeviceHash: client.DeviceHash(),
+		CreatedAt:  now,
+		ProjectId:  projectID,
+		UserId:     userID,
+	}
+
+	var newIP, newDevice bool
+	if settings.Enabled {
+		var err error
+		newIP, newDevice, err = m.compare(event, settings)
+		if err != nil {
+			log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+			return
+		}
+		event.Suspicious = newIP || newDevice
+	}
+
+	if err := m.DB.Create(&event).Error; err != nil {
+		log.For(ctx).Errorf("Failed to record login event: %v", redact.Error(err))
+		return
+	}
+	if !event.Suspicious {
+		return
+	}
+
+	m.Events.Publish(ctx, projectID, webhooks.EventUserSuspiciousLogin, SuspiciousLogin{
+		LoginEventID: event.ID.String(),
+		UserID:       userID.String(),
+		Email:        email,
+		Method:       method,
+		IPAddress:    event.IPAddress,
+		NewIP:        newIP,
+		NewDevice:    newDevice,
+		At:           now,
+	})
+
+	if settings.NotifyUser {
+		m.notify(ctx, &project, email, event, newIP, newDevice)
+	}
+}
+
+// compare reports whether the login's IP address and device are missing from
+// the user's history. Users with too little history are never flagged.
+func (m *Manager) compare(event schemas.LoginEvent, settings schemas.SuspiciousLoginSettings) (newIP, newDevice bool, err error) {
+	history := func() *gorm.DB {
+		q := m.DB.Model(&schemas.LoginEvent{}).Where("project_id = ? AND user_id = ?", event.ProjectId, event.UserId)
+		if settings.LookbackDays > 0 {
+			q = q.Where("created_at >= ?", event.CreatedAt.AddDate(0, 0, -settings.LookbackDays))
+		}
+		return q
+	}
+
+	minHistory := settings.MinHistory
+	if minHistory <= 0 {
+		minHistory = DefaultMinHistory
+	}
+	var total int64
+	if err := history().Count(&total).Error; err != nil {
+		return false, false, err
+	}
+	if total < int64(minHistory) {
+		return false, false, nil
+	}
+
+	if event.IPAddress != "" {
+		var seen int64
+		if err := history().Where("ip_address = ?", event.IPAddress).Count(&seen).Error; err != nil {
+			return false, false, err
+		}
+		newIP = seen == 0
+	}
+
+	var seen int64
+	if err := history().Where("device_hash = ?", event.DeviceHash).Count(&seen).Error; err != nil {
+		return false, false, err
+	}
+	newDevice = seen == 0
+
+	return newIP, newDevice, nil
+}
+
+// notify emails the user about a suspicious login
+func (m *Manager) notify(ctx context.Context, project *schemas.Project, email string, event schemas.LoginEvent, newIP, newDevice bool) {
+	name := project.Settings.Branding.DisplayName
+	if name == "" {
+		name = project.Name
+	}
+
+	var what []string
+	if newIP {
+		what = append(what, "IP address")
+	}
+	if newDevice {
+		what = append(what, "device")
+	}
+
+	action := "please contact the " + name + " administrators."
+	if revoke := project.Settings.SuspiciousLogin.RevokeURL; revoke != "" {
+		if u, err := url.Parse(revoke); err == nil {
+			q := u.Query()
+			q.Set("event", event.ID.String())
+			u.RawQuery = q.Encode()
+			action = "sign out of all your sessions here:\n\n" + u.String()
+		}
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "New sign-in to " + name + " - was this you?",
+		Body: fmt.Sprintf("Your %s account was just signed in to from a new %s.\n\nTime: %s\nIP address: %s\n\nIf this was you, you can ignore this email. If not, %s\n",
+			name, strings.Join(what, " and "), event.CreatedAt.UTC().Format(time.RFC1123), event.IPAddress, action),
+	}
+	if err := m.Mailer.Send(ctx, msg); err != nil {
+		log.For(ctx).Errorf("Failed to send suspicious login email: %v", redact.Error(err))
+	}
+}
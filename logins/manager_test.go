@@ -0,0 +1,83 @@
+package logins_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0"
+
+func TestOnlyALoginFromANewIPIsSuspicious(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+	project, user := built.Project, built.Users["a@example.com"]
+	project.Settings.SuspiciousLogin = schemas.SuspiciousLoginSettings{
+		Enabled:    true,
+		NotifyUser: true,
+		RevokeURL:  "https://app.example.com/sessions/revoke",
+	}
+	if err := db.Save(&project).Error; err != nil {
+		t.Fatalf("failed to enable suspicious login alerts: %v", err)
+	}
+
+	// Publish runs on the recording goroutine, which Wait joins before the
+	// events are read
+	var events []logins.SuspiciousLogin
+	publisher := &mocks.WebhookManager{
+		PublishFunc: func(_ context.Context, projectID uuid.UUID, event string, data interface{}) {
+			if projectID != project.ID || event != webhooks.EventUserSuspiciousLogin {
+				t.Errorf("published %s to project %s", event, projectID)
+			}
+			events = append(events, data.(logins.SuspiciousLogin))
+		},
+	}
+	mailer := testutil.NewFakeMailer()
+	manager := logins.NewManager(db, publisher, mailer).(*logins.Manager)
+	clock := testutil.NewFakeClock(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	manager.Clock = clock
+
+	login := func(ip string) {
+		t.Helper()
+		ctx := logins.WithClient(context.Background(), logins.Client{IPAddress: ip, UserAgent: browser, AcceptLanguage: "en"})
+		manager.RecordLogin(ctx, project.ID, user.ID, user.Email, logins.MethodMagicLink)
+		manager.Wait()
+		clock.Advance(time.Hour)
+	}
+
+	login("203.0.113.7")
+	if len(events) != 0 || len(mailer.Messages()) != 0 {
+		t.Fatalf("the first login published %d events and sent %d emails, want none", len(events), len(mailer.Messages()))
+	}
+
+	login("198.51.100.23")
+	if len(events) != 1 {
+		t.Fatalf("the login from a new IP published %d events, want 1", len(events))
+	}
+	if event := events[0]; !event.NewIP || event.NewDevice || event.IPAddress != "198.51.100.23" || event.Email != "a@example.com" {
+		t.Errorf("event = %+v, want a new IP on a known device", event)
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0].To != "a@example.com" {
+		t.Fatalf("emails = %+v, want one to the user", messages)
+	}
+	if body := messages[0].Body; !strings.Contains(body, "198.51.100.23") || !strings.Contains(body, "revoke?event="+events[0].LoginEventID) {
+		t.Errorf("the email does not name the IP or link to revoking sessions:\n%s", body)
+	}
+
+	var history []schemas.LoginEvent
+	if err := db.Order("created_at").Find(&history, "user_id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Suspicious || !history[1].Suspicious || history[1].ID.String() != events[0].LoginEventID {
+		t.Errorf("login events = %+v, want only the second flagged", history)
+	}
+}
@@ -0,0 +1,425 @@
+// Package magiclink implements password-less email login for project users.
+// A user asks for a link, receives a single-use token by email and exchanges
+// it for the same JWT a password login would issue.
+package magiclink
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/mailer"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("magiclink")
+
+// Defaults applied to zero Options fields
+const (
+	DefaultTTL              = 15 * time.Minute
+	DefaultRateLimit        = 3
+	DefaultRateWindow       = 15 * time.Minute
+	DefaultMaxFailures      = 10
+	DefaultFailureWindow    = 15 * time.Minute
+	DefaultMaxTokenFailures = 5
+)
+
+// tokenBytes is how much randomness a token carries, and tokenLength the
+// length of its encoding. Anything else cannot be a token.
+const tokenBytes = 32
+
+var tokenLength = base64.RawURLEncoding.EncodedLen(tokenBytes)
+
+var (
+	// ErrDisabled is returned when the project has not enabled magic links
+	ErrDisabled = apierrors.New(http.StatusForbidden, "MAGIC_LINK_DISABLED", "magic link login is not enabled for this project")
+
+	// ErrInvalidToken is returned for unknown, expired, consumed or
+	// other-project tokens alike, so a caller cannot tell them apart
+	ErrInvalidToken = apierrors.New(http.StatusUnauthorized, "INVALID_MAGIC_LINK", "magic link is invalid or has expired")
+
+	// ErrTooManyAttempts is returned to a client that has failed too many
+	// verifications recently, before its token is even looked at
+	ErrTooManyAttempts = apierrors.New(http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "too many invalid magic links, try again later")
+)
+
+// Session is the result of exchanging a magic link
+type Session struct {
+	Token     string
+	ExpiresAt time.Time
+	User      models.DisplayUser
+}
+
+// MagicLinkManager defines the interface for magic link login
+type MagicLinkManager interface {
+	// RequestLink emails a login link to email if it belongs to an active,
+	// invited or pending verification user of the project. It reports success
+	// whether or not a link was sent.
+	RequestLink(ctx context.Context, projectID uuid.UUID, email string) error
+	// Verify consumes a token issued for the project and logs its user in
+	Verify(ctx context.Context, projectID uuid.UUID, token string) (*Session, error)
+}
+
+// Options configures the magic link manager
+type Options struct {
+	BaseURL    string        // Public URL of the service, used when a project sets no redirect URL
+	TTL        time.Duration // Lifetime of an emailed token
+	RateLimit  int           // Links sent per project and email within RateWindow
+	RateWindow time.Duration
+
+	MaxFailures      int // Failed verifications per client IP within FailureWindow
+	FailureWindow    time.Duration
+	MaxTokenFailures int // Failed verifications after which a token is locked
+}
+
+// Manager implements the MagicLinkManager interface
+type Manager struct {
+	DB      *gorm.DB
+	Regions *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Clock   clock.Clock
+	Mailer  mailer.Mailer
+	Logins  logins.LoginRecorder
+
+	opts Options
+
+	mu       sync.Mutex
+	requests map[string][]time.Time // Send times per project and email, within the window
+	failures map[string][]time.Time // Failed verification times per client IP, within the window
+}
+
+// NewManager creates a new magic link manager
+func NewManager(db *gorm.DB, regions *regions.Resolver, m mailer.Mailer, recorder logins.LoginRecorder, opts Options) MagicLinkManager {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.RateLimit <= 0 {
+		opts.RateLimit = DefaultRateLimit
+	}
+	if opts.RateWindow <= 0 {
+		opts.RateWindow = DefaultRateWindow
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = DefaultMaxFailures
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = DefaultFailureWindow
+	}
+	if opts.MaxTokenFailures <= 0 {
+		opts.MaxTokenFailures = DefaultMaxTokenFailures
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	return &Manager{
+		DB:       db,
+		Regions:  regions,
+		Clock:    clock.Real{},
+		Mailer:   m,
+		Logins:   recorder,
+		opts:     opts,
+		requests: make(map[string][]time.Time),
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// HashToken returns the at-rest form of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken generates a random URL-safe token
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// enabledProject loads the project and checks that magic links are enabled
+func (m *Manager) enabledProject(projectID uuid.UUID) (*schemas.Project, error) {
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.NotFound("project not found")
+		}
+		log.Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if !project.Settings.MagicLink.Enabled {
+		return nil, ErrDisabled
+	}
+	return &project, nil
+}
+
+// allow reports whether another link may be sent for key, recording the
+// attempt when it may
+func (m *Manager) allow(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Clock.Now()
+	cutoff := now.Add(-m.opts.RateWindow)
+
+	recent := m.requests[key][:0]
+	for _, t := range m.requests[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= m.opts.RateLimit {
+		m.requests[key] = recent
+		return false
+	}
+	m.requests[key] = append(recent, now)
+	return true
+}
+
+// recentFailures drops the failures of ip that fell out of the window and
+// returns the others. m.mu must be held.
+func (m *Manager) recentFailures(ip string) []time.Time {
+	cutoff := m.Clock.Now().Add(-m.opts.FailureWindow)
+
+	recent := m.failures[ip][:0]
+	for _, t := range m.failures[ip] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		delete(m.failures, ip)
+		return nil
+	}
+	m.failures[ip] = recent
+	return recent
+}
+
+// throttled reports whether ip has failed MaxFailures verifications within
+// the window
+func (m *Manager) throttled(ip string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.recentFailures(ip)) >= m.opts.MaxFailures
+}
+
+// fail records a failed verification of the token hashed as hash from ip. A
+// token that exists counts the failure too, and is locked once it reaches
+// MaxTokenFailures: Verify no longer accepts it, even from its project.
+func (m *Manager) fail(ip, hash string) {
+	m.mu.Lock()
+	failures := append(m.recentFailures(ip), m.Clock.Now())
+	m.failures[ip] = failures
+	m.mu.Unlock()
+	if len(failures) == m.opts.MaxFailures {
+		log.Infof("Magic link verification from %s throttled after %d failures", ip, len(failures))
+	}
+
+	if hash == "" {
+		return
+	}
+	if err := m.DB.Model(&schemas.MagicLinkToken{}).Where("token_hash = ?", hash).
+		Update("failures", gorm.Expr("failures + 1")).Error; err != nil {
+		log.Errorf("Failed to count magic link failure: %v", redact.Error(err))
+	}
+}
+
+// RequestLink emails a single-use login link. Unknown or inactive emails and
+// rate-limited requests succeed silently so the endpoint cannot be used to
+// discover accounts.
+func (m *Manager) RequestLink(ctx context.Context, projectID uuid.UUID, email string) error {
+	project, err := m.enabledProject(projectID)
+	if err != nil {
+		return err
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return apierrors.BadRequest("EMAIL_REQUIRED", "email is required")
+	}
+
+	var user schemas.ProjectUser
+	if err := m.Regions.For(m.DB, project.Region).Table(projecttable.Users(projectID)).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	if !userstatus.CanLogin(user.Status) && !userstatus.ActivatedByEmail(user.Status) {
+		return nil
+	}
+
+	if !m.allow(projectID.String() + "|" + strings.ToLower(email)) {
+		log.For(ctx).Infof("Magic link for %s in project %s rate limited", redact.SafeEmail(email), projectID)
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.For(ctx).Errorf("Failed to generate magic link token: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+
+	now := m.Clock.Now()
+	record := schemas.MagicLinkToken{
+		ID:        uuid.New(),
+		TokenHash: HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: now.Add(m.opts.TTL),
+		CreatedAt: now,
+		ProjectId: projectID,
+	}
+	if err := m.DB.Create(&record).Error; err != nil {
+		log.For(ctx).Errorf("Failed to store magic link token: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+
+	name := project.Settings.Branding.DisplayName
+	if name == "" {
+		name = project.Name
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link for " + name,
+		Body: fmt.Sprintf("Use the link below to sign in to %s. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not ask for this link you can ignore this email.\n",
+			name, int(m.opts.TTL.Minutes()), m.link(project, token)),
+	}
+	if err := m.Mailer.Send(ctx, msg); err != nil {
+		log.For(ctx).Errorf("Failed to send magic link: %v", redact.Error(err))
+		return errors.New("failed to send magic link")
+	}
+
+	return nil
+}
+
+// link builds the URL emailed for a token
+func (m *Manager) link(project *schemas.Project, token string) string {
+	if redirect := project.Settings.MagicLink.RedirectURL; redirect != "" {
+		if u, err := url.Parse(redirect); err == nil {
+			q := u.Query()
+			q.Set("token", token)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return fmt.Sprintf("%s/api/v1/%s/auth/magic-link/verify?token=%s", m.opts.BaseURL, project.ID, url.QueryEscape(token))
+}
+
+// Verify consumes a token and issues a JWT for its user, marking the user's
+// email as verified. A client that has failed MaxFailures verifications
+// within the failure window is turned away with ErrTooManyAttempts until the
+// oldest of them falls out of it.
+func (m *Manager) Verify(ctx context.Context, projectID uuid.UUID, token string) (*Session, error) {
+	project, err := m.enabledProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := logins.ClientFromContext(ctx).IPAddress
+	if m.throttled(ip) {
+		return nil, ErrTooManyAttempts
+	}
+	if len(token) != tokenLength {
+		m.fail(ip, "")
+		return nil, ErrInvalidToken
+	}
+
+	now := m.Clock.Now()
+	hash := HashToken(token)
+
+	// Consuming with a conditional update makes concurrent verifications of
+	// the same token race on a single row; only one of them can win
+	result := m.DB.Model(&schemas.MagicLinkToken{}).
+		Where("token_hash = ? AND project_id = ? AND consumed_at IS NULL AND expires_at > ? AND failures < ?", hash, projectID, now, m.opts.MaxTokenFailures).
+		Update("consumed_at", now)
+	if result.Error != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(result.Error))
+		return nil, errors.New("internal server error")
+	}
+	if result.RowsAffected == 0 {
+		m.fail(ip, hash)
+		return nil, ErrInvalidToken
+	}
+
+	var record schemas.MagicLinkToken
+	if err := m.DB.First(&record, "token_hash = ?", hash).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	users := m.Regions.For(m.DB, project.Region)
+	table := projecttable.Users(projectID)
+	var user schemas.ProjectUser
+	if err := users.Table(table).Where("email = ?", record.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	activate := userstatus.ActivatedByEmail(user.Status)
+	if !userstatus.CanLogin(user.Status) && !activate {
+		return nil, userstatus.LoginError(user.Status)
+	}
+
+	// Following the link proves control of the email address, which also
+	// completes an invitation or a pending verification
+	if !user.EmailVerified || activate {
+		user.EmailVerified = true
+		user.UpdatedAt = now
+		if activate {
+			user.SetStatus(schemas.UserStatusActive)
+		}
+		if err := users.Table(table).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"email_verified": true, "status": user.Status, "active": user.Active, "updated_at": now}).Error; err != nil {
+			log.For(ctx).Errorf("Failed to mark email verified: %v", redact.Error(err))
+			return nil, errors.New("internal server error")
+		}
+	}
+
+	jwt, expiresAt, err := auth.IssueProjectUserToken(ctx, m.DB, user, now)
+	if err != nil {
+		return nil, err
+	}
+	m.Logins.RecordLogin(ctx, projectID, user.ID, user.Email, logins.MethodMagicLink)
+
+	return &Session{
+		Token:     jwt,
+		ExpiresAt: expiresAt,
+		User: models.DisplayUser{
+			ID:            user.ID.String(),
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Active:        user.Active,
+			Status:        user.Status,
+			Transitions:   userstatus.Next(user.Status),
+			RoleID:        user.RoleId.String(),
+			ProjectID:     user.ProjectId.String(),
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+	}, nil
+}
@@ -0,0 +1,244 @@
+package magiclink_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/magiclink"
+	"gorm.io/gorm"
+)
+
+// fixture is a magic link manager over a project that has magic links
+// enabled and one user, a@example.com
+type fixture struct {
+	manager *magiclink.Manager
+	clock   *testutil.FakeClock
+	mailer  *testutil.FakeMailer
+	project schemas.Project
+	user    schemas.ProjectUser
+	logins  []string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+	enableMagicLinks(t, db, &built.Project)
+
+	// The clock starts at the real time, which the issued JWTs are checked against
+	f := &fixture{
+		clock:   testutil.NewFakeClock(time.Now()),
+		mailer:  testutil.NewFakeMailer(),
+		project: built.Project,
+		user:    built.Users["a@example.com"],
+	}
+	recorder := &mocks.LoginManager{
+		RecordLoginFunc: func(_ context.Context, _, _ uuid.UUID, email, method string) {
+			f.logins = append(f.logins, email+" "+method)
+		},
+	}
+	f.manager = magiclink.NewManager(db, nil, f.mailer, recorder, magiclink.Options{BaseURL: "https://ums.example.com/"}).(*magiclink.Manager)
+	f.manager.Clock = f.clock
+	return f
+}
+
+func enableMagicLinks(t *testing.T, db *gorm.DB, project *schemas.Project) {
+	t.Helper()
+
+	project.Settings.MagicLink.Enabled = true
+	if err := db.Save(project).Error; err != nil {
+		t.Fatalf("failed to enable magic links: %v", err)
+	}
+}
+
+// linkPattern finds the link in a magic link email
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+// requestToken asks for a link and returns the token of the email it sends
+func (f *fixture) requestToken(t *testing.T) string {
+	t.Helper()
+
+	sent := len(f.mailer.Messages())
+	if err := f.manager.RequestLink(context.Background(), f.project.ID, "a@example.com"); err != nil {
+		t.Fatalf("RequestLink: %v", err)
+	}
+	messages := f.mailer.Messages()
+	if len(messages) != sent+1 {
+		t.Fatalf("%d emails were sent, want 1", len(messages)-sent)
+	}
+	msg := messages[len(messages)-1]
+	if msg.To != "a@example.com" {
+		t.Fatalf("the link was emailed to %s", msg.To)
+	}
+
+	link, err := url.Parse(linkPattern.FindString(msg.Body))
+	if err != nil {
+		t.Fatalf("the email has no link: %v\n%s", err, msg.Body)
+	}
+	if want := "/api/v1/" + f.project.ID.String() + "/auth/magic-link/verify"; link.Host != "ums.example.com" || link.Path != want {
+		t.Fatalf("link = %s, want https://ums.example.com%s", link, want)
+	}
+	token := link.Query().Get("token")
+	if token == "" {
+		t.Fatalf("link %s carries no token", link)
+	}
+	return token
+}
+
+func TestVerifyLogsTheUserIn(t *testing.T) {
+	f := newFixture(t)
+	token := f.requestToken(t)
+
+	session, err := f.manager.Verify(context.Background(), f.project.ID, token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	claims, err := auth.ParseToken(session.Token)
+	if err != nil {
+		t.Fatalf("the session token does not parse: %v", err)
+	}
+	if claims.UserID != f.user.ID || claims.ProjectId != f.project.ID {
+		t.Errorf("claims = %+v, want user %s of project %s", claims, f.user.ID, f.project.ID)
+	}
+	if !session.User.EmailVerified {
+		t.Error("following the link did not verify the email")
+	}
+	if len(f.logins) != 1 || f.logins[0] != "a@example.com "+logins.MethodMagicLink {
+		t.Errorf("recorded logins %v, want one magic link login", f.logins)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		verify func(t *testing.T, f *fixture, token string) error
+	}{
+		{"reuse", func(t *testing.T, f *fixture, token string) error {
+			if _, err := f.manager.Verify(context.Background(), f.project.ID, token); err != nil {
+				t.Fatalf("first Verify: %v", err)
+			}
+			_, err := f.manager.Verify(context.Background(), f.project.ID, token)
+			return err
+		}},
+		{"expired", func(t *testing.T, f *fixture, token string) error {
+			f.clock.Advance(magiclink.DefaultTTL)
+			_, err := f.manager.Verify(context.Background(), f.project.ID, token)
+			return err
+		}},
+		{"another project", func(t *testing.T, f *fixture, token string) error {
+			other := testutil.AProject().Build(t, f.manager.DB).Project
+			enableMagicLinks(t, f.manager.DB, &other)
+			_, err := f.manager.Verify(context.Background(), other.ID, token)
+			return err
+		}},
+		{"garbage", func(t *testing.T, f *fixture, token string) error {
+			_, err := f.manager.Verify(context.Background(), f.project.ID, strings.Repeat("x", len(token)))
+			return err
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			token := f.requestToken(t)
+			if err := tc.verify(t, f, token); !errors.Is(err, magiclink.ErrInvalidToken) {
+				t.Fatalf("Verify = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyJustBeforeExpiry(t *testing.T) {
+	f := newFixture(t)
+	token := f.requestToken(t)
+
+	f.clock.Advance(magiclink.DefaultTTL - time.Second)
+	if _, err := f.manager.Verify(context.Background(), f.project.ID, token); err != nil {
+		t.Fatalf("Verify within the TTL: %v", err)
+	}
+}
+
+func TestRequestLinkSendsNothing(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if err := f.manager.RequestLink(ctx, f.project.ID, "nobody@example.com"); err != nil {
+		t.Fatalf("RequestLink of an unknown email: %v", err)
+	}
+	if messages := f.mailer.Messages(); len(messages) != 0 {
+		t.Fatalf("%d emails were sent to an unknown address", len(messages))
+	}
+
+	disabled := testutil.AProject().WithUser("b@example.com").Build(t, f.manager.DB)
+	if err := f.manager.RequestLink(ctx, disabled.Project.ID, "b@example.com"); !errors.Is(err, magiclink.ErrDisabled) {
+		t.Fatalf("RequestLink in a project without magic links = %v, want ErrDisabled", err)
+	}
+
+	for i := 0; i < magiclink.DefaultRateLimit; i++ {
+		f.requestToken(t)
+	}
+	if err := f.manager.RequestLink(ctx, f.project.ID, "a@example.com"); err != nil {
+		t.Fatalf("rate limited RequestLink: %v", err)
+	}
+	if messages := f.mailer.Messages(); len(messages) != magiclink.DefaultRateLimit {
+		t.Fatalf("%d emails were sent, want the rate limit of %d", len(messages), magiclink.DefaultRateLimit)
+	}
+}
+
+func TestVerifyThrottlesAClientAfterRepeatedFailures(t *testing.T) {
+	f := newFixture(t)
+	attacker := logins.WithClient(context.Background(), logins.Client{IPAddress: "203.0.113.7"})
+	other := logins.WithClient(context.Background(), logins.Client{IPAddress: "198.51.100.1"})
+	token := f.requestToken(t)
+	wrong := strings.Repeat("x", len(token))
+
+	for i := 0; i < magiclink.DefaultMaxFailures; i++ {
+		if _, err := f.manager.Verify(attacker, f.project.ID, wrong); !errors.Is(err, magiclink.ErrInvalidToken) {
+			t.Fatalf("wrong token %d: err = %v, want ErrInvalidToken", i+1, err)
+		}
+	}
+	// Throttled, the client is turned away even with a valid token
+	if _, err := f.manager.Verify(attacker, f.project.ID, token); !errors.Is(err, magiclink.ErrTooManyAttempts) {
+		t.Fatalf("Verify once throttled = %v, want ErrTooManyAttempts", err)
+	}
+	if _, err := f.manager.Verify(other, f.project.ID, token); err != nil {
+		t.Fatalf("Verify from another client: %v", err)
+	}
+
+	f.clock.Advance(magiclink.DefaultFailureWindow)
+	if _, err := f.manager.Verify(attacker, f.project.ID, f.requestToken(t)); err != nil {
+		t.Fatalf("Verify once the failures fell out of the window: %v", err)
+	}
+}
+
+func TestTokenLocksAfterRepeatedFailures(t *testing.T) {
+	f := newFixture(t)
+	other := testutil.AProject().Build(t, f.manager.DB).Project
+	enableMagicLinks(t, f.manager.DB, &other)
+	token := f.requestToken(t)
+
+	// Each failure comes from a client of its own, so none is throttled
+	for i := 0; i < magiclink.DefaultMaxTokenFailures; i++ {
+		ctx := logins.WithClient(context.Background(), logins.Client{IPAddress: fmt.Sprintf("203.0.113.%d", i+1)})
+		if _, err := f.manager.Verify(ctx, other.ID, token); !errors.Is(err, magiclink.ErrInvalidToken) {
+			t.Fatalf("Verify in another project = %v, want ErrInvalidToken", err)
+		}
+	}
+	if _, err := f.manager.Verify(context.Background(), f.project.ID, token); !errors.Is(err, magiclink.ErrInvalidToken) {
+		t.Fatalf("Verify of a locked token = %v, want ErrInvalidToken", err)
+	}
+	if _, err := f.manager.Verify(context.Background(), f.project.ID, f.requestToken(t)); err != nil {
+		t.Fatalf("Verify of a fresh token: %v", err)
+	}
+}
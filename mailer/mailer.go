@@ -0,0 +1,86 @@
+// Package mailer sends transactional email such as magic links. Without SMTP
+// settings messages are only logged, which keeps local development free of
+// a mail server.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/redact"
+	"k8s.io/klog/v2"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Options configures the mailer built by New
+type Options struct {
+	From     string
+	Host     string // SMTP host; empty logs messages instead of sending them
+	Port     int
+	Username string
+	Password string
+}
+
+// New returns an SMTP mailer, or a LogMailer when no SMTP host is configured
+func New(opts Options) Mailer {
+	if opts.Host == "" {
+		return LogMailer{}
+	}
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	return &SMTPMailer{opts: opts}
+}
+
+// LogMailer writes messages to the log instead of sending them
+type LogMailer struct{}
+
+// Send logs the message. Email addresses in the recipient and body are
+// redacted like any others; the links in the body are kept for development.
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	klog.Infof("Email to %s: %s\n%s", redact.SafeEmail(msg.To), msg.Subject, redact.Text(msg.Body))
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server
+type SMTPMailer struct {
+	opts Options
+}
+
+// Send delivers the message over SMTP
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+
+	var auth smtp.Auth
+	if m.opts.Username != "" {
+		auth = smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.opts.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	if err := smtp.SendMail(addr, auth, m.opts.From, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("sending mail to %s: %w", msg.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package oauthlogin
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/models"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+)
+
+// ProjectAccounts keeps OAuth users in their project's user table
+type ProjectAccounts struct {
+	Users projectusers.ProjectUserManager
+}
+
+// Upsert implements Accounts
+func (a ProjectAccounts) Upsert(ctx context.Context, projectID uuid.UUID, info *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error) {
+	return a.Users.CreateOrUpdateOAuthProjectUser(ctx, projectID.String(), info, roleID)
+}
+
+// IssueToken implements Accounts
+func (a ProjectAccounts) IssueToken(ctx context.Context, projectID, userID uuid.UUID) (string, time.Time, error) {
+	return a.Users.GenerateToken(ctx, projectID.String(), userID)
+}
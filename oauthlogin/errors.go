@@ -0,0 +1,51 @@
+package oauthlogin
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Stages of a login at which the provider can fail
+const (
+	StageExchange = "code exchange"
+	StageUserInfo = "user info"
+)
+
+// ProviderError is a failure reported by, or a bad answer from, the OAuth
+// provider
+type ProviderError struct {
+	Provider string
+	Stage    string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("oauth provider %s: %s failed: %v", e.Provider, e.Stage, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// RoleResolutionError means the role requested for the login does not exist
+// or belongs to another project
+type RoleResolutionError struct {
+	RoleID uuid.UUID
+	Err    error
+}
+
+func (e *RoleResolutionError) Error() string {
+	return fmt.Sprintf("role %s cannot be used for this login: %v", e.RoleID, e.Err)
+}
+
+func (e *RoleResolutionError) Unwrap() error { return e.Err }
+
+// UserCreationError is a failure to create or update the user logging in
+type UserCreationError struct {
+	Err error
+}
+
+func (e *UserCreationError) Error() string {
+	return fmt.Sprintf("creating oauth user: %v", e.Err)
+}
+
+func (e *UserCreationError) Unwrap() error { return e.Err }
@@ -0,0 +1,148 @@
+// Package oauthlogin completes an OAuth login once the provider has sent the
+// user back: it exchanges the authorization code, reads the user's profile,
+// resolves the role they sign up with, creates or refreshes the user and
+// issues their token. Where users are kept is left to an Accounts
+// implementation, so project users and global users share the same flow.
+package oauthlogin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/logins"
+)
+
+var log = logging.Named("oauthlogin")
+
+// LoginService completes OAuth logins
+type LoginService interface {
+	CompleteLogin(ctx context.Context, params CompleteLoginParams) (LoginResult, error)
+}
+
+// Accounts stores the users logging in with OAuth
+type Accounts interface {
+	// Upsert creates the user described by info with roleID, or refreshes the
+	// OAuth details of the existing user with the same email
+	Upsert(ctx context.Context, projectID uuid.UUID, info *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error)
+	// IssueToken issues a login token for the user
+	IssueToken(ctx context.Context, projectID, userID uuid.UUID) (string, time.Time, error)
+}
+
+// RoleGetter looks up roles; roles.RoleManager satisfies it
+type RoleGetter interface {
+	GetRole(ctx context.Context, id uuid.UUID) (*schemas.Role, error)
+}
+
+// DomainChecker decides whether an email may log in with a provider;
+// *oauth.ProviderFactory satisfies it
+type DomainChecker interface {
+	CheckEmailDomain(provider, email string) error
+}
+
+// CompleteLoginParams describes a login returning from the provider
+type CompleteLoginParams struct {
+	Provider  oauth.Provider // Already configured for the project
+	Code      string         // Authorization code from the callback
+	ProjectID uuid.UUID
+	RoleID    uuid.UUID // Role given to users created by this login
+}
+
+// LoginResult is a completed login
+type LoginResult struct {
+	Token     string
+	ExpiresAt time.Time
+	User      models.DisplayUser
+}
+
+// Manager implements the LoginService interface
+type Manager struct {
+	Accounts Accounts
+	Roles    RoleGetter
+	Domains  DomainChecker
+	Logins   logins.LoginRecorder
+}
+
+// NewManager creates a new OAuth login service
+func NewManager(accounts Accounts, roles RoleGetter, domains DomainChecker, recorder logins.LoginRecorder) LoginService {
+	return &Manager{
+		Accounts: accounts,
+		Roles:    roles,
+		Domains:  domains,
+		Logins:   recorder,
+	}
+}
+
+// CompleteLogin runs the login from the authorization code to the issued
+// token. Provider failures are logged with the request ID and returned as a
+// *ProviderError, an unusable role as a *RoleResolutionError and a failure to
+// store the user as a *UserCreationError. Domain and account status checks
+// return their own API errors.
+func (m *Manager) CompleteLogin(ctx context.Context, params CompleteLoginParams) (LoginResult, error) {
+	name := params.Provider.GetName()
+
+	token, err := params.Provider.Exchange(ctx, params.Code)
+	if err != nil {
+		return LoginResult{}, m.providerError(ctx, name, StageExchange, err)
+	}
+	info, err := params.Provider.GetUserInfo(ctx, token)
+	if err != nil {
+		return LoginResult{}, m.providerError(ctx, name, StageUserInfo, err)
+	}
+	if err := m.Domains.CheckEmailDomain(name, info.Email); err != nil {
+		return LoginResult{}, err
+	}
+	if info.Email == "" {
+		return LoginResult{}, m.providerError(ctx, name, StageUserInfo, errors.New("provider returned no email address"))
+	}
+
+	if err := m.resolveRole(ctx, params.ProjectID, params.RoleID); err != nil {
+		return LoginResult{}, err
+	}
+	info.Token = token
+
+	user, err := m.Accounts.Upsert(ctx, params.ProjectID, info, params.RoleID)
+	if err != nil {
+		return LoginResult{}, &UserCreationError{Err: err}
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return LoginResult{}, &UserCreationError{Err: err}
+	}
+
+	jwtToken, expiresAt, err := m.Accounts.IssueToken(ctx, params.ProjectID, userID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	m.Logins.RecordLogin(ctx, params.ProjectID, userID, user.Email, logins.MethodOAuth+":"+name)
+
+	return LoginResult{
+		Token:     jwtToken,
+		ExpiresAt: expiresAt,
+		User:      *user,
+	}, nil
+}
+
+// resolveRole checks the role exists and may be used in the project: it is
+// either global or the project's own
+func (m *Manager) resolveRole(ctx context.Context, projectID, roleID uuid.UUID) error {
+	role, err := m.Roles.GetRole(ctx, roleID)
+	if err != nil {
+		return &RoleResolutionError{RoleID: roleID, Err: err}
+	}
+	if role.ProjectId != nil && *role.ProjectId != projectID {
+		return &RoleResolutionError{RoleID: roleID, Err: errors.New("role belongs to another project")}
+	}
+	return nil
+}
+
+func (m *Manager) providerError(ctx context.Context, provider, stage string, err error) error {
+	log.For(ctx).Errorf("OAuth %s %s failed: %v", provider, stage, redact.Error(err))
+	return &ProviderError{Provider: provider, Stage: stage, Err: err}
+}
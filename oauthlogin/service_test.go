@@ -0,0 +1,374 @@
+package oauthlogin_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/requestid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/oauthlogin"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/roles"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// fakeProvider answers the code exchange and profile lookup with fixed
+// results
+type fakeProvider struct {
+	name        string // "google" when empty
+	exchangeErr error
+	info        *oauth.UserInfo
+	infoErr     error
+}
+
+func (p *fakeProvider) GetAuthURL(state string) string {
+	return "https://idp.example.com/auth?state=" + state
+}
+
+func (p *fakeProvider) Exchange(_ context.Context, code string) (*oauth2.Token, error) {
+	if p.exchangeErr != nil {
+		return nil, p.exchangeErr
+	}
+	return &oauth2.Token{AccessToken: "access-" + code}, nil
+}
+
+func (p *fakeProvider) GetUserInfo(context.Context, *oauth2.Token) (*oauth.UserInfo, error) {
+	return p.info, p.infoErr
+}
+
+func (p *fakeProvider) GetName() string {
+	if p.name == "" {
+		return "google"
+	}
+	return p.name
+}
+
+// recordedLogin is a login a fixture's recorder was told about
+type recordedLogin struct {
+	userID uuid.UUID
+	method string
+}
+
+// fixture is a project with a role, whose users log in with OAuth
+type fixture struct {
+	db        *gorm.DB
+	service   oauthlogin.LoginService
+	projectID uuid.UUID
+	roleID    uuid.UUID
+	logins    *[]recordedLogin
+}
+
+// newFixture builds the login service over a real project user table, for a
+// role of the project. example.com is the only allowed google domain; github
+// allows every domain.
+func newFixture(t *testing.T, accounts func(projectusers.ProjectUserManager) oauthlogin.Accounts) fixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	role := built.Roles["Member"]
+	role.ProjectId = &built.Project.ID
+	if err := db.Save(&role).Error; err != nil {
+		t.Fatalf("failed to scope the role to the project: %v", err)
+	}
+	users := projectusers.NewManager(db, nil, projects.NewManager(db, projects.Options{}), nil, nil, nil, nil)
+	factory := oauth.NewProviderFactory(map[string]oauth.ProviderConfig{
+		"google": {ClientID: "client", ClientSecret: "secret", RedirectURL: "https://app.example.com/callback", AllowedDomains: []string{"example.com"}},
+	}, oauth.ClientOptions{})
+	roleGetter := &mocks.RoleManager{
+		GetRoleFunc: func(_ context.Context, id uuid.UUID) (*schemas.Role, error) {
+			if id != role.ID {
+				return nil, roles.ErrRoleNotFound
+			}
+			return &role, nil
+		},
+	}
+
+	recorded := &[]recordedLogin{}
+	recorder := &mocks.LoginManager{
+		RecordLoginFunc: func(_ context.Context, _, userID uuid.UUID, _, method string) {
+			*recorded = append(*recorded, recordedLogin{userID: userID, method: method})
+		},
+	}
+
+	var store oauthlogin.Accounts = oauthlogin.ProjectAccounts{Users: users}
+	if accounts != nil {
+		store = accounts(users)
+	}
+	return fixture{
+		db:        db,
+		service:   oauthlogin.NewManager(store, roleGetter, factory, recorder),
+		projectID: built.Project.ID,
+		roleID:    role.ID,
+		logins:    recorded,
+	}
+}
+
+// params is a login through provider with the fixture's project and role
+func (f fixture) params(provider oauth.Provider) oauthlogin.CompleteLoginParams {
+	return oauthlogin.CompleteLoginParams{Provider: provider, Code: "code", ProjectID: f.projectID, RoleID: f.roleID}
+}
+
+// ada is the profile of a user in the allowed domain
+func ada() *oauth.UserInfo {
+	return &oauth.UserInfo{ID: "google-1", Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Provider: "google", EmailVerified: true}
+}
+
+func TestCompleteLogin(t *testing.T) {
+	f := newFixture(t, nil)
+
+	result, err := f.service.CompleteLogin(context.Background(), f.params(&fakeProvider{info: ada()}))
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if result.User.Email != "ada@example.com" || result.User.RoleID != f.roleID.String() || result.User.ProjectID != f.projectID.String() {
+		t.Fatalf("user = %+v", result.User)
+	}
+
+	claims, err := auth.ParseToken(result.Token)
+	if err != nil {
+		t.Fatalf("the login token does not verify: %v", err)
+	}
+	if claims.UserID.String() != result.User.ID || claims.Email != "ada@example.com" || claims.RoleId != f.roleID || claims.ProjectId != f.projectID {
+		t.Fatalf("claims = %+v, want the logged in user", claims)
+	}
+	if claims.ExpiresAt == nil || !claims.ExpiresAt.Time.Equal(result.ExpiresAt.Truncate(time.Second)) || !result.ExpiresAt.After(time.Now()) {
+		t.Fatalf("token expires %v, result expires %v", claims.ExpiresAt, result.ExpiresAt)
+	}
+
+	if len(*f.logins) != 1 || (*f.logins)[0].userID.String() != result.User.ID || (*f.logins)[0].method != logins.MethodOAuth+":google" {
+		t.Fatalf("recorded logins = %+v", *f.logins)
+	}
+
+	// Logging in again refreshes the same user
+	again, err := f.service.CompleteLogin(context.Background(), f.params(&fakeProvider{info: ada()}))
+	if err != nil {
+		t.Fatalf("second CompleteLogin: %v", err)
+	}
+	if again.User.ID != result.User.ID {
+		t.Fatalf("second login created user %s, want %s", again.User.ID, result.User.ID)
+	}
+}
+
+func TestCompleteLoginProviderErrors(t *testing.T) {
+	cause := errors.New("invalid_grant")
+	noEmail := ada()
+	noEmail.Email = ""
+	tests := []struct {
+		name     string
+		provider *fakeProvider
+		stage    string
+		cause    error
+	}{
+		{"exchange", &fakeProvider{exchangeErr: cause}, oauthlogin.StageExchange, cause},
+		{"user info", &fakeProvider{infoErr: cause}, oauthlogin.StageUserInfo, cause},
+		{"no email", &fakeProvider{name: "github", info: noEmail}, oauthlogin.StageUserInfo, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, nil)
+			logs := testutil.CaptureKlog(t)
+			// As the RequestID middleware stores it
+			ctx := logging.WithValues(requestid.WithID(context.Background(), "req-123"), "request_id", "req-123")
+
+			_, err := f.service.CompleteLogin(ctx, f.params(tt.provider))
+			var providerErr *oauthlogin.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("err = %v, want a *ProviderError", err)
+			}
+			if providerErr.Provider != tt.provider.GetName() || providerErr.Stage != tt.stage {
+				t.Fatalf("provider error = %+v, want %s at %q", providerErr, tt.provider.GetName(), tt.stage)
+			}
+			if tt.cause != nil && !errors.Is(err, tt.cause) {
+				t.Fatalf("err = %v, want it to wrap %v", err, tt.cause)
+			}
+			if !strings.Contains(logs.String(), "req-123") {
+				t.Fatalf("log %q does not name the request", logs.String())
+			}
+			if len(*f.logins) != 0 {
+				t.Fatalf("recorded logins = %+v, want none", *f.logins)
+			}
+		})
+	}
+}
+
+// countingAccounts stores users in Accounts, counting the upserts
+type countingAccounts struct {
+	oauthlogin.Accounts
+	upserts *int
+}
+
+func (a countingAccounts) Upsert(ctx context.Context, projectID uuid.UUID, info *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error) {
+	*a.upserts++
+	return a.Accounts.Upsert(ctx, projectID, info, roleID)
+}
+
+// tokenAccounts stores users in Accounts, keeping the provider token of
+// the last upsert
+type tokenAccounts struct {
+	oauthlogin.Accounts
+	token **oauth2.Token
+}
+
+func (a tokenAccounts) Upsert(ctx context.Context, projectID uuid.UUID, info *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error) {
+	*a.token = info.Token
+	return a.Accounts.Upsert(ctx, projectID, info, roleID)
+}
+
+func TestCompleteLoginHandsOnTheProviderToken(t *testing.T) {
+	var token *oauth2.Token
+	f := newFixture(t, func(users projectusers.ProjectUserManager) oauthlogin.Accounts {
+		return tokenAccounts{Accounts: oauthlogin.ProjectAccounts{Users: users}, token: &token}
+	})
+
+	if _, err := f.service.CompleteLogin(context.Background(), f.params(&fakeProvider{info: ada()})); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if token == nil || token.AccessToken != "access-code" {
+		t.Fatalf("the accounts were given token %+v, want the exchanged one", token)
+	}
+}
+
+func TestCompleteLoginDomainAllowlist(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		provider string
+		email    string
+		allowed  bool
+	}{
+		{"allowed domain", "google", "ada@example.com", true},
+		{"allowed domain in another case", "google", "ada@EXAMPLE.com", true},
+		{"another domain", "google", "ada@elsewhere.com", false},
+		{"subdomain", "google", "ada@mail.example.com", false},
+		{"lookalike domain", "google", "ada@notexample.com", false},
+		{"provider without an allowlist", "github", "ada@elsewhere.com", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			upserts := 0
+			f := newFixture(t, func(users projectusers.ProjectUserManager) oauthlogin.Accounts {
+				return countingAccounts{Accounts: oauthlogin.ProjectAccounts{Users: users}, upserts: &upserts}
+			})
+			info := ada()
+			info.Email, info.Provider = tc.email, tc.provider
+
+			_, err := f.service.CompleteLogin(context.Background(), f.params(&fakeProvider{name: tc.provider, info: info}))
+			if tc.allowed {
+				if err != nil || upserts != 1 {
+					t.Fatalf("CompleteLogin = %v after %d upserts, want the user stored", err, upserts)
+				}
+				return
+			}
+			if err != oauth.ErrEmailDomainNotAllowed {
+				t.Fatalf("err = %v, want %v", err, oauth.ErrEmailDomainNotAllowed)
+			}
+			if upserts != 0 || len(*f.logins) != 0 {
+				t.Fatalf("a rejected login stored %d users and recorded %d logins, want none", upserts, len(*f.logins))
+			}
+		})
+	}
+}
+
+func TestCompleteLoginRoleResolutionErrors(t *testing.T) {
+	f := newFixture(t, nil)
+
+	params := f.params(&fakeProvider{info: ada()})
+	params.RoleID = uuid.New()
+	_, err := f.service.CompleteLogin(context.Background(), params)
+	var roleErr *oauthlogin.RoleResolutionError
+	if !errors.As(err, &roleErr) || roleErr.RoleID != params.RoleID || !errors.Is(err, roles.ErrRoleNotFound) {
+		t.Fatalf("err = %v, want a *RoleResolutionError for a missing role", err)
+	}
+
+	// The fixture's role is scoped to its own project
+	params = f.params(&fakeProvider{info: ada()})
+	params.ProjectID = uuid.New()
+	_, err = f.service.CompleteLogin(context.Background(), params)
+	if !errors.As(err, &roleErr) || roleErr.RoleID != f.roleID {
+		t.Fatalf("err = %v, want a *RoleResolutionError for another project's role", err)
+	}
+}
+
+// failingAccounts stores no users
+type failingAccounts struct {
+	upsert func() (*models.DisplayUser, error)
+	tokens oauthlogin.Accounts
+}
+
+func (a failingAccounts) Upsert(context.Context, uuid.UUID, *oauth.UserInfo, uuid.UUID) (*models.DisplayUser, error) {
+	return a.upsert()
+}
+
+func (a failingAccounts) IssueToken(ctx context.Context, projectID, userID uuid.UUID) (string, time.Time, error) {
+	return a.tokens.IssueToken(ctx, projectID, userID)
+}
+
+func TestCompleteLoginUserCreationErrors(t *testing.T) {
+	cause := errors.New("disk full")
+	tests := []struct {
+		name   string
+		upsert func() (*models.DisplayUser, error)
+		cause  error
+	}{
+		{"store fails", func() (*models.DisplayUser, error) { return nil, cause }, cause},
+		{"bad user ID", func() (*models.DisplayUser, error) { return &models.DisplayUser{ID: "nope"}, nil }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, func(users projectusers.ProjectUserManager) oauthlogin.Accounts {
+				return failingAccounts{upsert: tt.upsert, tokens: oauthlogin.ProjectAccounts{Users: users}}
+			})
+
+			_, err := f.service.CompleteLogin(context.Background(), f.params(&fakeProvider{info: ada()}))
+			var creationErr *oauthlogin.UserCreationError
+			if !errors.As(err, &creationErr) {
+				t.Fatalf("err = %v, want a *UserCreationError", err)
+			}
+			if tt.cause != nil && !errors.Is(err, tt.cause) {
+				t.Fatalf("err = %v, want it to wrap %v", err, tt.cause)
+			}
+			if len(*f.logins) != 0 {
+				t.Fatalf("recorded logins = %+v, want none", *f.logins)
+			}
+		})
+	}
+}
+
+func TestCompleteLoginInactiveUser(t *testing.T) {
+	var users projectusers.ProjectUserManager
+	f := newFixture(t, func(u projectusers.ProjectUserManager) oauthlogin.Accounts {
+		users = u
+		return oauthlogin.ProjectAccounts{Users: u}
+	})
+	ctx := context.Background()
+
+	first, err := f.service.CompleteLogin(ctx, f.params(&fakeProvider{info: ada()}))
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	userID := uuid.MustParse(first.User.ID)
+	if _, err := users.SetProjectUserStatus(ctx, f.projectID.String(), userID, schemas.UserStatusSuspended); err != nil {
+		t.Fatalf("SetProjectUserStatus: %v", err)
+	}
+
+	_, err = f.service.CompleteLogin(ctx, f.params(&fakeProvider{info: ada()}))
+	var apiErr interface{ StatusCode() int }
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "suspended") {
+		t.Fatalf("err = %v, want the account status error", err)
+	}
+	if len(*f.logins) != 1 {
+		t.Fatalf("recorded logins = %+v, want only the first", *f.logins)
+	}
+}
@@ -0,0 +1,127 @@
+package oauthlogin_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"golang.org/x/oauth2"
+)
+
+// googleStub answers Google's token and userinfo endpoints, the latter with
+// a fixed profile
+type googleStub struct {
+	userinfo string
+}
+
+func (s googleStub) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body string
+	switch r.URL.Host + r.URL.Path {
+	case "oauth2.googleapis.com/token":
+		body = `{"access_token":"access","token_type":"Bearer","expires_in":3600}`
+	case "www.googleapis.com/oauth2/v3/userinfo":
+		body = s.userinfo
+	default:
+		return nil, errors.New("unexpected request to " + r.URL.String())
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+// googleProvider is the real Google provider, talking to a stub that
+// reports ada@example.com with the given verification
+func googleProvider(verified bool) oauth.Provider {
+	userinfo := `{"sub":"google-attacker","email":"ada@example.com","given_name":"Ada","email_verified":false}`
+	if verified {
+		userinfo = strings.Replace(userinfo, "false", "true", 1)
+	}
+	return oauth.NewGoogleProvider(oauth.ProviderConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+		HTTPClient:   &http.Client{Transport: googleStub{userinfo: userinfo}},
+	})
+}
+
+func TestUnverifiedGoogleEmailCannotTakeOverAnAccount(t *testing.T) {
+	f := newFixture(t, nil)
+	ctx := context.Background()
+	table := testutil.ProjectUserTable(f.projectID)
+	victim := schemas.ProjectUser{
+		ID:        uuid.New(),
+		Email:     "ada@example.com",
+		Password:  "hash",
+		Status:    schemas.UserStatusActive,
+		Active:    true,
+		RoleId:    f.roleID,
+		ProjectId: f.projectID,
+	}
+	if err := f.db.Table(table).Create(&victim).Error; err != nil {
+		t.Fatalf("failed to create the password user: %v", err)
+	}
+	unlinked := func() {
+		t.Helper()
+		var stored schemas.ProjectUser
+		if err := f.db.Table(table).First(&stored, "id = ?", victim.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.OAuthID != "" || stored.OAuthType != "" || stored.AccessToken != "" {
+			t.Fatalf("the password user was linked to %s identity %q", stored.OAuthType, stored.OAuthID)
+		}
+	}
+
+	if info, err := googleProvider(false).GetUserInfo(ctx, &oauth2.Token{AccessToken: "access"}); err != nil || info.EmailVerified {
+		t.Fatalf("GetUserInfo = %+v, %v, want the email unverified", info, err)
+	}
+
+	_, err := f.service.CompleteLogin(ctx, f.params(googleProvider(false)))
+	if !errors.Is(err, oauth.ErrEmailNotVerified) {
+		t.Fatalf("err = %v, want %v", err, oauth.ErrEmailNotVerified)
+	}
+	unlinked()
+	if len(*f.logins) != 0 {
+		t.Fatalf("recorded logins = %+v, want none", *f.logins)
+	}
+
+	// A project that opts into separate accounts gets a new user instead
+	var project schemas.Project
+	if err := f.db.First(&project, "id = ?", f.projectID).Error; err != nil {
+		t.Fatal(err)
+	}
+	project.Settings.OAuthLogin.UnverifiedEmail = schemas.UnverifiedEmailSeparate
+	if err := f.db.Save(&project).Error; err != nil {
+		t.Fatalf("failed to allow separate accounts: %v", err)
+	}
+	separate, err := f.service.CompleteLogin(ctx, f.params(googleProvider(false)))
+	if err != nil {
+		t.Fatalf("CompleteLogin with separate accounts: %v", err)
+	}
+	if separate.User.ID == victim.ID.String() || separate.User.Email != "google-attacker@google.invalid" {
+		t.Fatalf("the unverified login signed in as %+v, want a new user with a placeholder email", separate.User)
+	}
+	unlinked()
+
+	// Only a verified email links the password user. Deleting the separate
+	// user frees the Google identity, so the login falls back to the email.
+	if err := f.db.Table(table).Where("id = ?", separate.User.ID).Delete(&schemas.ProjectUser{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	linked, err := f.service.CompleteLogin(ctx, f.params(googleProvider(true)))
+	if err != nil {
+		t.Fatalf("CompleteLogin with a verified email: %v", err)
+	}
+	if linked.User.ID != victim.ID.String() {
+		t.Fatalf("the verified login signed in as %s, want the password user %s", linked.User.ID, victim.ID)
+	}
+}
@@ -0,0 +1,93 @@
+package onetime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/clock"
+)
+
+// memoryToken is a token held by the in-memory store
+type memoryToken struct {
+	purpose   string
+	subject   string
+	expiresAt time.Time
+	consumed  bool
+}
+
+// Memory implements the Store interface in the memory of one instance. Its
+// tokens are lost on restart and only redeem on the instance that issued
+// them, so it suits single instance deployments and development.
+type Memory struct {
+	Clock clock.Clock
+
+	mu     sync.Mutex
+	tokens map[string]memoryToken // By token hash
+}
+
+// NewMemory creates a new in-memory one-time token store
+func NewMemory() Store {
+	return &Memory{
+		Clock:  clock.Real{},
+		tokens: map[string]memoryToken{},
+	}
+}
+
+// Issue stores a new token and returns it. Only its hash is kept.
+func (m *Memory) Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error) {
+	if purpose == "" || ttl <= 0 {
+		return "", errors.New("one-time tokens need a purpose and a positive ttl")
+	}
+	if len(subject) > MaxSubjectLength {
+		return "", errors.New("one-time token subject is too long")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.For(ctx).Errorf("Failed to generate one-time token: %v", err)
+		return "", errors.New("internal server error")
+	}
+
+	now := m.Clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	m.tokens[hashToken(token)] = memoryToken{
+		purpose:   purpose,
+		subject:   subject,
+		expiresAt: now.Add(ttl),
+	}
+	return token, nil
+}
+
+// Consume marks the token consumed under the store's lock, so only one
+// caller gets the subject back. Consumed tokens are kept until they expire
+// to turn replays away.
+func (m *Memory) Consume(ctx context.Context, purpose, token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidToken
+	}
+	hash := hashToken(token)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.tokens[hash]
+	if !ok || record.consumed || record.purpose != purpose || !m.Clock.Now().Before(record.expiresAt) {
+		return "", ErrInvalidToken
+	}
+	record.consumed = true
+	m.tokens[hash] = record
+	return record.subject, nil
+}
+
+// sweep forgets the tokens that have expired, which can no longer be
+// redeemed or replayed. The caller holds the lock.
+func (m *Memory) sweep(now time.Time) {
+	for hash, record := range m.tokens {
+		if !now.Before(record.expiresAt) {
+			delete(m.tokens, hash)
+		}
+	}
+}
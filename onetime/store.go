@@ -0,0 +1,180 @@
+// Package onetime issues random tokens that can be redeemed exactly once
+// before they expire. Features needing plain "issue, validate once, expire"
+// semantics, such as OAuth state, share it instead of each keeping their own
+// table and consumption logic. Magic links keep their own table, since their
+// tokens are bound to a project and locked after repeated failures.
+package onetime
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("onetime")
+
+// Purposes of the tokens issued by the service. A token only redeems for
+// the purpose it was issued for.
+const (
+	PurposeOAuthState = "oauth_state"
+)
+
+// Backends keeping the tokens
+const (
+	BackendDatabase = "database"
+	BackendMemory   = "memory"
+)
+
+// MaxSubjectLength is the longest subject a token can carry
+const MaxSubjectLength = 1000
+
+// retention is how long expired and consumed tokens are kept before
+// being swept
+const retention = 24 * time.Hour
+
+// ErrInvalidToken is returned for unknown, expired, already consumed and
+// other-purpose tokens alike, so a caller cannot tell them apart
+var ErrInvalidToken = apierrors.New(http.StatusUnauthorized, "INVALID_TOKEN", "token is invalid, expired or already used")
+
+// Store issues and consumes one-time tokens
+type Store interface {
+	// Issue returns a new token for purpose that stands for subject and can be
+	// consumed once within ttl
+	Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error)
+	// Consume redeems a token issued for purpose and returns its subject.
+	// Of any number of concurrent calls with the same token, at most one
+	// succeeds.
+	Consume(ctx context.Context, purpose, token string) (string, error)
+}
+
+// Manager implements the Store interface
+type Manager struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+
+	// spent remembers recently redeemed token hashes so replays are turned
+	// away without a database round trip. It is only an optimisation: the
+	// conditional update in Consume is what guarantees a single redemption.
+	spent *cache.TTL[string, struct{}]
+}
+
+// NewManager creates a new one-time token store
+func NewManager(db *gorm.DB) Store {
+	return &Manager{
+		DB:    db,
+		Clock: clock.Real{},
+		spent: cache.NewTTL[string, struct{}](10 * time.Minute),
+	}
+}
+
+// New creates a one-time token store keeping its tokens in backend: in
+// memory for BackendMemory (the default), or in db for BackendDatabase
+func New(db *gorm.DB, backend string) (Store, error) {
+	switch backend {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendDatabase:
+		return NewManager(db), nil
+	default:
+		return nil, fmt.Errorf("unknown one-time token backend %q, must be %s or %s", backend, BackendDatabase, BackendMemory)
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue stores a new token and returns it. Only its hash is kept.
+func (m *Manager) Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error) {
+	if purpose == "" || ttl <= 0 {
+		return "", errors.New("one-time tokens need a purpose and a positive ttl")
+	}
+	if len(subject) > MaxSubjectLength {
+		return "", errors.New("one-time token subject is too long")
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.For(ctx).Errorf("Failed to generate one-time token: %v", err)
+		return "", errors.New("internal server error")
+	}
+
+	now := m.Clock.Now()
+	record := schemas.OneTimeToken{
+		ID:        uuid.New(),
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Subject:   subject,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := m.DB.Create(&record).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return "", errors.New("internal server error")
+	}
+
+	m.sweep(now)
+	return token, nil
+}
+
+// Consume marks the token consumed with a single conditional update, so only
+// the caller whose update changes the row gets the subject back
+func (m *Manager) Consume(ctx context.Context, purpose, token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidToken
+	}
+	hash := hashToken(token)
+	if _, spent := m.spent.Get(hash); spent {
+		return "", ErrInvalidToken
+	}
+
+	now := m.Clock.Now()
+	result := m.DB.Model(&schemas.OneTimeToken{}).
+		Where("token_hash = ? AND purpose = ? AND consumed_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("consumed_at", now)
+	if result.Error != nil {
+		log.For(ctx).Errorf("Database error: %v", result.Error)
+		return "", errors.New("internal server error")
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrInvalidToken
+	}
+	m.spent.Set(hash, struct{}{})
+
+	var record schemas.OneTimeToken
+	if err := m.DB.Select("subject").First(&record, "token_hash = ?", hash).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return "", errors.New("internal server error")
+	}
+	return record.Subject, nil
+}
+
+// sweep deletes tokens that expired more than the retention period ago
+func (m *Manager) sweep(now time.Time) {
+	if err := m.DB.Where("expires_at < ?", now.Add(-retention)).Delete(&schemas.OneTimeToken{}).Error; err != nil {
+		log.Errorf("Failed to sweep one-time tokens: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package onetime_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/onetime"
+)
+
+// backends returns each store with its clock set to clock
+func backends(t *testing.T, clock *testutil.FakeClock) map[string]onetime.Store {
+	t.Helper()
+
+	database := onetime.NewManager(testutil.NewTestDB(t)).(*onetime.Manager)
+	database.Clock = clock
+	memory := onetime.NewMemory().(*onetime.Memory)
+	memory.Clock = clock
+	return map[string]onetime.Store{
+		onetime.BackendDatabase: database,
+		onetime.BackendMemory:   memory,
+	}
+}
+
+func TestConcurrentConsumeRedeemsOnce(t *testing.T) {
+	const consumers = 20
+	for name, store := range backends(t, testutil.NewFakeClock(time.Now())) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			token, err := store.Issue(ctx, onetime.PurposeOAuthState, "subject", time.Minute)
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			results := make(chan error, consumers)
+			for i := 0; i < consumers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					subject, err := store.Consume(ctx, onetime.PurposeOAuthState, token)
+					if err == nil && subject != "subject" {
+						t.Errorf("Consume returned subject %q", subject)
+					}
+					results <- err
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(results)
+
+			redeemed := 0
+			for err := range results {
+				switch {
+				case err == nil:
+					redeemed++
+				case !errors.Is(err, onetime.ErrInvalidToken):
+					t.Errorf("Consume = %v, want success or ErrInvalidToken", err)
+				}
+			}
+			if redeemed != 1 {
+				t.Fatalf("%d of %d concurrent consumers redeemed the token, want 1", redeemed, consumers)
+			}
+		})
+	}
+}
+
+func TestConsumeRejects(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	for name, store := range backends(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			issue := func() string {
+				t.Helper()
+				token, err := store.Issue(ctx, onetime.PurposeOAuthState, "subject", time.Minute)
+				if err != nil {
+					t.Fatalf("Issue: %v", err)
+				}
+				return token
+			}
+
+			if _, err := store.Consume(ctx, "other_purpose", issue()); !errors.Is(err, onetime.ErrInvalidToken) {
+				t.Errorf("Consume for another purpose = %v, want ErrInvalidToken", err)
+			}
+			if _, err := store.Consume(ctx, onetime.PurposeOAuthState, "unknown"); !errors.Is(err, onetime.ErrInvalidToken) {
+				t.Errorf("Consume of an unknown token = %v, want ErrInvalidToken", err)
+			}
+
+			expiring := issue()
+			clock.Advance(time.Minute)
+			if _, err := store.Consume(ctx, onetime.PurposeOAuthState, expiring); !errors.Is(err, onetime.ErrInvalidToken) {
+				t.Errorf("Consume of an expired token = %v, want ErrInvalidToken", err)
+			}
+
+			if _, err := store.Issue(ctx, "", "subject", time.Minute); err == nil {
+				t.Error("Issue accepted an empty purpose")
+			}
+			if _, err := store.Issue(ctx, onetime.PurposeOAuthState, "subject", 0); err == nil {
+				t.Error("Issue accepted a zero ttl")
+			}
+		})
+	}
+}
+
+func TestNewPicksTheBackend(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	for _, tc := range []struct {
+		backend string
+		want    onetime.Store
+	}{
+		{"", &onetime.Memory{}},
+		{onetime.BackendDatabase, &onetime.Manager{}},
+		{onetime.BackendMemory, &onetime.Memory{}},
+	} {
+		store, err := onetime.New(db, tc.backend)
+		if err != nil {
+			t.Fatalf("New(%q): %v", tc.backend, err)
+		}
+		if fmt.Sprintf("%T", store) != fmt.Sprintf("%T", tc.want) {
+			t.Errorf("New(%q) = %T, want %T", tc.backend, store, tc.want)
+		}
+	}
+	if _, err := onetime.New(db, "redis"); err == nil {
+		t.Error("New accepted an unknown backend")
+	}
+}
@@ -0,0 +1,118 @@
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// Wildcard matches every action of a resource; as a resource it matches
+// every resource and may only be combined with the wildcard action
+const Wildcard = "*"
+
+// Actions lists the valid actions for each built-in resource
+var Actions = map[string][]string{
+	"projects": {"read", "write", "delete"},
+	"users":    {"read", "write", "delete", "impersonate", "lookup"},
+	"roles":    {"read", "write"},
+	"policies": {"read", "write"},
+	"tokens":   {"introspect"},
+}
+
+// maxSuggestionDistance is how many edits a near-miss may be from a valid name
+const maxSuggestionDistance = 2
+
+// ValidateAction checks a resource/action pair against the built-in registry
+// and the given custom resources (from the project settings). Typos are
+// rejected with suggestions for the closest valid names.
+func ValidateAction(resource, action string, custom map[string][]string) error {
+	if resource == Wildcard {
+		if action != Wildcard {
+			return apierrors.BadRequest("UNKNOWN_ACTION", "the wildcard resource only accepts the wildcard action")
+		}
+		return nil
+	}
+
+	actions, ok := Actions[resource]
+	if !ok {
+		actions, ok = custom[resource]
+	}
+	if !ok {
+		known := make([]string, 0, len(Actions)+len(custom))
+		for name := range Actions {
+			known = append(known, name)
+		}
+		for name := range custom {
+			known = append(known, name)
+		}
+		return unknownError("UNKNOWN_RESOURCE", fmt.Sprintf("unknown resource %q", resource), resource, known)
+	}
+
+	if action == Wildcard {
+		return nil
+	}
+	for _, a := range actions {
+		if a == action {
+			return nil
+		}
+	}
+
+	return unknownError("UNKNOWN_ACTION", fmt.Sprintf("unknown action %q for resource %q", action, resource), action, actions)
+}
+
+// ValidateNamespacedResource checks the name of a custom resource, which must
+// be "<namespace>:<name>" so it cannot shadow a built-in resource
+func ValidateNamespacedResource(name string) error {
+	namespace, resource, ok := strings.Cut(name, ":")
+	if !ok || namespace == "" || resource == "" || strings.Contains(resource, ":") {
+		return apierrors.BadRequest("INVALID_RESOURCE", fmt.Sprintf("custom resource %q must be of the form <namespace>:<name>", name))
+	}
+	return nil
+}
+
+func unknownError(code, msg, got string, known []string) *apierrors.Error {
+	if suggestions := suggest(got, known); len(suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %q?", strings.Join(suggestions, `" or "`))
+	}
+	return apierrors.BadRequest(code, msg)
+}
+
+// suggest returns the known names closest to got, if any are near enough
+func suggest(got string, known []string) []string {
+	best := maxSuggestionDistance + 1
+	var out []string
+	for _, k := range known {
+		d := levenshtein(strings.ToLower(got), k)
+		switch {
+		case d < best:
+			best = d
+			out = []string{k}
+		case d == best:
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
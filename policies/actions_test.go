@@ -0,0 +1,116 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestValidateAction(t *testing.T) {
+	custom := map[string][]string{"docs:document": {"read", "publish"}}
+
+	for _, tc := range []struct {
+		name       string
+		resource   string
+		action     string
+		code       string // Empty when the pair is valid
+		suggestion string
+	}{
+		{"built-in pair", "users", "impersonate", "", ""},
+		{"wildcard action", "projects", policies.Wildcard, "", ""},
+		{"wildcard resource and action", policies.Wildcard, policies.Wildcard, "", ""},
+		{"custom pair", "docs:document", "publish", "", ""},
+		{"custom wildcard action", "docs:document", policies.Wildcard, "", ""},
+		{"action typo", "users", "raed", "UNKNOWN_ACTION", `did you mean "read"?`},
+		{"resource typo", "userz", "read", "UNKNOWN_RESOURCE", `did you mean "users"?`},
+		{"custom action typo", "docs:document", "publsh", "UNKNOWN_ACTION", `did you mean "publish"?`},
+		{"unrelated action", "roles", "impersonate", "UNKNOWN_ACTION", ""},
+		{"wildcard resource with an action", policies.Wildcard, "read", "UNKNOWN_ACTION", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policies.ValidateAction(tc.resource, tc.action, custom)
+			if tc.code == "" {
+				if err != nil {
+					t.Fatalf("ValidateAction(%q, %q) = %v, want nil", tc.resource, tc.action, err)
+				}
+				return
+			}
+
+			var apiErr *apierrors.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != tc.code {
+				t.Fatalf("ValidateAction(%q, %q) = %v, want %s", tc.resource, tc.action, err, tc.code)
+			}
+			if tc.suggestion != "" && !strings.Contains(apiErr.Message, tc.suggestion) {
+				t.Errorf("message %q does not suggest %s", apiErr.Message, tc.suggestion)
+			}
+			if tc.suggestion == "" && strings.Contains(apiErr.Message, "did you mean") {
+				t.Errorf("message %q suggests a name that is not near", apiErr.Message)
+			}
+		})
+	}
+}
+
+func TestValidateNamespacedResource(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"docs:document":   true,
+		"document":        false,
+		":document":       false,
+		"docs:":           false,
+		"docs:a:b":        false,
+		"billing:invoice": true,
+	} {
+		if err := policies.ValidateNamespacedResource(name); (err == nil) != valid {
+			t.Errorf("ValidateNamespacedResource(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+}
+
+func TestCustomResourceRoundTrip(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	project := testutil.AProject().Build(t, db).Project
+	projectManager := projects.NewManager(db, projects.Options{})
+	manager := policies.NewManager(db, nil)
+	ctx := context.Background()
+
+	settings := project.Settings
+	settings.CustomResources = map[string][]string{"invoice": {"approve"}}
+	if _, err := projectManager.UpdateProjectSettings(ctx, project.ID, settings); err == nil {
+		t.Fatal("UpdateProjectSettings accepted a custom resource without a namespace")
+	}
+	settings.CustomResources = map[string][]string{"billing:invoice": {"approve", "void"}}
+	if _, err := projectManager.UpdateProjectSettings(ctx, project.ID, settings); err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+
+	created, err := manager.CreatePolicy(ctx, "approve invoices", "", "billing:invoice", "approve", "allow", &project.ID)
+	if err != nil {
+		t.Fatalf("CreatePolicy of a custom resource: %v", err)
+	}
+	got, err := manager.GetPolicy(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetPolicy: %v", err)
+	}
+	if got.Resource != "billing:invoice" || got.Action != "approve" {
+		t.Errorf("read back %s/%s, want billing:invoice/approve", got.Resource, got.Action)
+	}
+
+	_, err = manager.UpdatePolicy(ctx, created.ID, "approve invoices", "", "billing:invoice", "vod", "allow")
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "UNKNOWN_ACTION" || !strings.Contains(apiErr.Message, `"void"`) {
+		t.Fatalf("UpdatePolicy with a typo = %v, want UNKNOWN_ACTION suggesting void", err)
+	}
+	if _, err := manager.UpdatePolicy(ctx, created.ID, "approve invoices", "", "billing:invoice", "void", "allow"); err != nil {
+		t.Fatalf("UpdatePolicy: %v", err)
+	}
+
+	// The resource belongs to the project; other scopes do not know it
+	if _, err := manager.CreatePolicy(ctx, "global invoices", "", "billing:invoice", "approve", "allow", nil); err == nil {
+		t.Fatal("CreatePolicy accepted another project's custom resource globally")
+	}
+}
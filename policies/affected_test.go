@@ -0,0 +1,78 @@
+package policies_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+)
+
+func TestAffectedUsersReportsTheHoldersOfTheRole(t *testing.T) {
+	const holders = 5
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	role := testutil.ARole("Editor").Build(t, db)
+	other := testutil.ARole("Viewer").Build(t, db)
+	project := testutil.AProject().Build(t, db).Project
+	elsewhere := testutil.AProject().Build(t, db).Project
+
+	for i := 0; i < holders; i++ {
+		addProjectUser(t, db, project.ID, fmt.Sprintf("holder%d@example.com", i), role.ID)
+	}
+	addProjectUser(t, db, project.ID, "viewer@example.com", other.ID)
+	addProjectUser(t, db, project.ID, "deleted@example.com", role.ID)
+	if err := db.Table(testutil.ProjectUserTable(project.ID)).Where("email = ?", "deleted@example.com").
+		Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	addProjectUser(t, db, elsewhere.ID, "elsewhere@example.com", role.ID)
+
+	// A global user holding the role directly, and one through a membership
+	testutil.AUser(t, db, "primary@example.com", role, project)
+	member := testutil.AUser(t, db, "member@example.com", other, elsewhere)
+	if err := db.Create(&schemas.UserProjectMembership{UserId: member.ID, ProjectId: project.ID, RoleId: role.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	policy, err := manager.CreatePolicy(ctx, "edit users", "", "users", "write", "allow", &project.ID)
+	if err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+	if err := db.Model(policy).Update("roles_id", role.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	affected, err := manager.AffectedUsers(ctx, policy.ID, 0)
+	if err != nil {
+		t.Fatalf("AffectedUsers: %v", err)
+	}
+	if affected.ProjectUsers != holders || affected.GlobalUsers != 2 || affected.Total() != holders+2 {
+		t.Fatalf("affected = %d project and %d global users, want %d and 2", affected.ProjectUsers, affected.GlobalUsers, holders)
+	}
+	for _, u := range affected.Sample {
+		if u.Email == "viewer@example.com" || u.Email == "deleted@example.com" || u.Email == "elsewhere@example.com" {
+			t.Errorf("the sample includes %s", u.Email)
+		}
+	}
+	if len(affected.Sample) != holders+2 {
+		t.Errorf("sample has %d users, want all %d", len(affected.Sample), holders+2)
+	}
+
+	limited, err := manager.AffectedUsers(ctx, policy.ID, 3)
+	if err != nil {
+		t.Fatalf("AffectedUsers: %v", err)
+	}
+	if len(limited.Sample) != 3 || limited.Total() != holders+2 {
+		t.Errorf("a sample of 3 has %d users of %d, want 3 of %d", len(limited.Sample), limited.Total(), holders+2)
+	}
+
+	if _, err := manager.AffectedUsers(ctx, uuid.New(), 0); err == nil {
+		t.Error("AffectedUsers found an unknown policy")
+	}
+}
@@ -0,0 +1,140 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"gorm.io/gorm"
+)
+
+// Reasons reported with a decision
+const (
+	ReasonMatchedPolicy  = "matched_policy"
+	ReasonNoMatch        = "no_matching_policy"
+	ReasonInactiveUser   = "inactive_user"
+	ReasonExplicitDenial = "explicit_deny"
+)
+
+// Decision is the outcome of an authorization check
+type Decision struct {
+	Allowed bool
+	Reason  string
+	Policy  *schemas.Policy // The policy that decided; nil when none matched
+}
+
+// Evaluate decides whether the policies grant action on resource. A matching
+// deny overrides any allow, and nothing is allowed unless a policy says so.
+func Evaluate(policies []schemas.Policy, resource, action string) Decision {
+	var allow *schemas.Policy
+	for i := range policies {
+		p := &policies[i]
+		if !matches(p.Resource, resource) || !matches(p.Action, action) {
+			continue
+		}
+		if p.Effect == "deny" {
+			return Decision{Allowed: false, Reason: ReasonExplicitDenial, Policy: p}
+		}
+		if allow == nil && p.Effect == "allow" {
+			allow = p
+		}
+	}
+	if allow != nil {
+		return Decision{Allowed: true, Reason: ReasonMatchedPolicy, Policy: allow}
+	}
+	return Decision{Allowed: false, Reason: ReasonNoMatch}
+}
+
+func matches(pattern, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
+
+// rolePoliciesKey identifies the policies that apply to a role in a project
+type rolePoliciesKey struct {
+	projectID uuid.UUID
+	roleID    uuid.UUID
+}
+
+// Authorize checks whether a project user may perform action on one of the
+// project's custom resources, using the policies of the user's role
+func (m *Manager) Authorize(ctx context.Context, projectID, userID uuid.UUID, resource, action string) (*Decision, error) {
+	custom, err := m.customResources(&projectID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := custom[resource]; !ok {
+		known := make([]string, 0, len(custom))
+		for name := range custom {
+			known = append(known, name)
+		}
+		sort.Strings(known)
+		return nil, unknownError("UNKNOWN_RESOURCE", fmt.Sprintf("resource %q is not a custom resource of this project", resource), resource, known)
+	}
+	if action == Wildcard {
+		return nil, apierrors.BadRequest("UNKNOWN_ACTION", "authorization checks need a specific action")
+	}
+	if err := ValidateAction(resource, action, custom); err != nil {
+		return nil, err
+	}
+
+	users, err := m.Regions.UsersDB(m.DB, projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apierrors.NotFound("project not found")
+	} else if err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	var user schemas.ProjectUser
+	if err := users.Table(projecttable.Users(projectID)).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.NotFound("user not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if !userstatus.CanLogin(user.Status) {
+		decision := Decision{Allowed: false, Reason: ReasonInactiveUser}
+		RecordDecision(resource, action, decision)
+		return &decision, nil
+	}
+
+	policies, err := m.rolePolicies(projectID, user.RoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := Evaluate(policies, resource, action)
+	RecordDecision(resource, action, decision)
+	return &decision, nil
+}
+
+// RolePolicies returns the effective policies of a role in a project: the
+// ones Authorize evaluates for the role's holders
+func (m *Manager) RolePolicies(ctx context.Context, projectID, roleID uuid.UUID) ([]schemas.Policy, error) {
+	return m.rolePolicies(projectID, roleID)
+}
+
+// rolePolicies returns the policies attached to a role that apply in a
+// project: its own and global ones. Results are cached briefly.
+func (m *Manager) rolePolicies(projectID, roleID uuid.UUID) ([]schemas.Policy, error) {
+	key := rolePoliciesKey{projectID: projectID, roleID: roleID}
+	if policies, ok := m.policyCache.Get(key); ok {
+		return policies, nil
+	}
+
+	var policies []schemas.Policy
+	if err := m.DB.Where("roles_id = ? AND (project_id = ? OR project_id IS NULL)", roleID, projectID).
+		Order("created_at").Find(&policies).Error; err != nil {
+		log.Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	m.policyCache.Set(key, policies)
+	return policies, nil
+}
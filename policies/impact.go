@@ -0,0 +1,145 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// Kinds of permission change
+const (
+	PermissionGained = "gained"
+	PermissionLost   = "lost"
+)
+
+// Definition is the part of a policy that decides what it grants
+type Definition struct {
+	Resource string
+	Action   string
+	Effect   string
+}
+
+// PermissionChange is a resource/action pair a role would be allowed or
+// refused after a policy change, in a project
+type PermissionChange struct {
+	RoleID    uuid.UUID
+	ProjectID uuid.UUID
+	Resource  string
+	Action    string
+	Change    string // PermissionGained or PermissionLost
+}
+
+// PolicyImpact is the outcome of evaluating a proposed policy definition
+// against the current one
+type PolicyImpact struct {
+	Policy    schemas.Policy
+	Proposed  Definition
+	Evaluated int // Decisions evaluated under both definitions
+	Changes   []PermissionChange
+}
+
+// Impact previews what changing a policy to the proposed definition would
+// do, without saving it. There is no record of past decisions to replay, so
+// the decisions are synthesized: every resource/action pair of the registry
+// and of the project's custom resources, for the policy's role, in each
+// project the policy applies to. Each is evaluated against the role's
+// policies as they are and with the proposed definition in place of the
+// policy's, and the pairs whose outcome differs are reported.
+func (m *Manager) Impact(ctx context.Context, id uuid.UUID, proposed Definition) (*PolicyImpact, error) {
+	policy, err := m.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposed.Effect != "allow" && proposed.Effect != "deny" {
+		return nil, ErrInvalidEffect
+	}
+	custom, err := m.customResources(policy.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateAction(proposed.Resource, proposed.Action, custom); err != nil {
+		return nil, err
+	}
+
+	changed := *policy
+	changed.Resource, changed.Action, changed.Effect = proposed.Resource, proposed.Action, proposed.Effect
+
+	projectIDs := []uuid.UUID{}
+	if policy.ProjectId != nil {
+		projectIDs = append(projectIDs, *policy.ProjectId)
+	} else if err := m.DB.Model(&schemas.Project{}).Order("created_at").Pluck("id", &projectIDs).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	impact := &PolicyImpact{Policy: *policy, Proposed: proposed, Changes: []PermissionChange{}}
+	for _, projectID := range projectIDs {
+		current, err := m.rolePolicies(projectID, policy.RolesId)
+		if err != nil {
+			return nil, err
+		}
+		custom, err := m.customResources(&projectID)
+		if err != nil {
+			return nil, err
+		}
+
+		overlay := withPolicy(current, changed)
+		for _, pair := range actionPairs(custom) {
+			impact.Evaluated++
+			before := Evaluate(current, pair[0], pair[1]).Allowed
+			after := Evaluate(overlay, pair[0], pair[1]).Allowed
+			if before == after {
+				continue
+			}
+			change := PermissionChange{RoleID: policy.RolesId, ProjectID: projectID, Resource: pair[0], Action: pair[1], Change: PermissionGained}
+			if before {
+				change.Change = PermissionLost
+			}
+			impact.Changes = append(impact.Changes, change)
+		}
+	}
+
+	return impact, nil
+}
+
+// withPolicy returns a copy of policies with policy in place of the one
+// with its ID, or added when there is none
+func withPolicy(policies []schemas.Policy, policy schemas.Policy) []schemas.Policy {
+	overlay := make([]schemas.Policy, 0, len(policies)+1)
+	replaced := false
+	for _, p := range policies {
+		if p.ID == policy.ID {
+			p, replaced = policy, true
+		}
+		overlay = append(overlay, p)
+	}
+	if !replaced {
+		overlay = append(overlay, policy)
+	}
+	return overlay
+}
+
+// actionPairs lists every resource/action pair of the built-in registry and
+// the custom resources, sorted
+func actionPairs(custom map[string][]string) [][2]string {
+	var pairs [][2]string
+	add := func(registry map[string][]string) {
+		for resource, actions := range registry {
+			for _, action := range actions {
+				pairs = append(pairs, [2]string{resource, action})
+			}
+		}
+	}
+	add(Actions)
+	add(custom)
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
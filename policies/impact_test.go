@@ -0,0 +1,186 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+	"gorm.io/gorm"
+)
+
+// inProject scopes a built policy to the project
+func inProject(t *testing.T, db *gorm.DB, policy schemas.Policy, project schemas.Project) schemas.Policy {
+	t.Helper()
+
+	if err := db.Model(&policy).Update("project_id", project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+// changes renders the changes as "<project> <change> <resource>:<action>"
+// for comparison, with the projects given by name
+func changes(impact *policies.PolicyImpact, names map[uuid.UUID]string) []string {
+	rendered := []string{}
+	for _, c := range impact.Changes {
+		rendered = append(rendered, fmt.Sprintf("%s %s %s:%s", names[c.ProjectID], c.Change, c.Resource, c.Action))
+	}
+	return rendered
+}
+
+func TestImpact(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	built := testutil.AProject().WithRole("Editor").WithRole("Viewer").Build(t, db)
+	project := withCustomResources(t, db, built.Project)
+	editor, viewer := built.Roles["Editor"], built.Roles["Viewer"]
+	names := map[uuid.UUID]string{project.ID: "project"}
+
+	// The editor reads users, manages roles and may never delete users; the
+	// viewer's policy shows that only the edited policy's role is evaluated
+	readUsers := inProject(t, db, testutil.APolicy("read users", "users", "read").ForRole(editor).Build(t, db), project)
+	manageRoles := inProject(t, db, testutil.APolicy("manage roles", "roles", policies.Wildcard).ForRole(editor).Build(t, db), project)
+	noDeletes := inProject(t, db, testutil.APolicy("no deletes", "users", "delete").ForRole(editor).Denying().Build(t, db), project)
+	inProject(t, db, testutil.APolicy("viewer reads", "users", "read").ForRole(viewer).Build(t, db), project)
+
+	for _, tc := range []struct {
+		name     string
+		policy   schemas.Policy
+		proposed policies.Definition
+		want     []string
+	}{
+		{
+			"no change",
+			readUsers, policies.Definition{Resource: "users", Action: "read", Effect: "allow"},
+			[]string{},
+		},
+		{
+			"an allow turned into a deny",
+			readUsers, policies.Definition{Resource: "users", Action: "read", Effect: "deny"},
+			[]string{"project lost users:read"},
+		},
+		{
+			"an allow widened to every action",
+			readUsers, policies.Definition{Resource: "users", Action: policies.Wildcard, Effect: "allow"},
+			[]string{"project gained users:impersonate", "project gained users:lookup", "project gained users:write"},
+		},
+		{
+			"a gain already granted by another policy",
+			readUsers, policies.Definition{Resource: "roles", Action: "read", Effect: "allow"},
+			[]string{"project lost users:read"},
+		},
+		{
+			"a lifted deny",
+			noDeletes, policies.Definition{Resource: "users", Action: "delete", Effect: "allow"},
+			[]string{"project gained users:delete"},
+		},
+		{
+			"a wildcard narrowed",
+			manageRoles, policies.Definition{Resource: "roles", Action: "read", Effect: "allow"},
+			[]string{"project lost roles:write"},
+		},
+		{
+			"a deny of everything",
+			manageRoles, policies.Definition{Resource: policies.Wildcard, Action: policies.Wildcard, Effect: "deny"},
+			[]string{"project lost roles:read", "project lost roles:write", "project lost users:read"},
+		},
+		{
+			"a custom resource",
+			manageRoles, policies.Definition{Resource: "docs:document", Action: policies.Wildcard, Effect: "allow"},
+			[]string{"project gained docs:document:read", "project gained docs:document:write", "project lost roles:read", "project lost roles:write"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			impact, err := manager.Impact(context.Background(), tc.policy.ID, tc.proposed)
+			if err != nil {
+				t.Fatalf("Impact: %v", err)
+			}
+			if got := changes(impact, names); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("changes = %q, want %q", got, tc.want)
+			}
+			for _, c := range impact.Changes {
+				if c.RoleID != editor.ID {
+					t.Errorf("a change was reported for role %s, want only the editor", c.RoleID)
+				}
+			}
+			// Every built-in pair and the two custom ones
+			if want := 13 + 2; impact.Evaluated != want {
+				t.Errorf("%d decisions were evaluated, want %d", impact.Evaluated, want)
+			}
+		})
+	}
+
+	// Nothing is saved
+	stored, err := manager.GetPolicy(context.Background(), readUsers.ID)
+	if err != nil {
+		t.Fatalf("GetPolicy: %v", err)
+	}
+	if stored.Resource != "users" || stored.Action != "read" || stored.Effect != "allow" {
+		t.Errorf("policy = %s:%s %s after the previews, want it unchanged", stored.Resource, stored.Action, stored.Effect)
+	}
+}
+
+func TestImpactOfAGlobalPolicy(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	role := testutil.ARole("Support").Build(t, db)
+	first := withCustomResources(t, db, testutil.AProject().Build(t, db).Project)
+	second := testutil.AProject().Build(t, db).Project
+	names := map[uuid.UUID]string{first.ID: "first", second.ID: "second"}
+
+	// The role may also write users in the second project only
+	global := testutil.APolicy("read users", "users", "read").ForRole(role).Build(t, db)
+	inProject(t, db, testutil.APolicy("write users", "users", "write").ForRole(role).Build(t, db), second)
+
+	impact, err := manager.Impact(context.Background(), global.ID, policies.Definition{Resource: "users", Action: policies.Wildcard, Effect: "deny"})
+	if err != nil {
+		t.Fatalf("Impact: %v", err)
+	}
+	want := []string{"first lost users:read", "second lost users:read", "second lost users:write"}
+	if got := changes(impact, names); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	if want := 13 + 2 + 13; impact.Evaluated != want {
+		t.Errorf("%d decisions were evaluated, want %d", impact.Evaluated, want)
+	}
+}
+
+func TestImpactRejects(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	built := testutil.AProject().WithRole("Editor").Build(t, db)
+	policy := inProject(t, db, testutil.APolicy("read users", "users", "read").ForRole(built.Roles["Editor"]).Build(t, db), built.Project)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		proposed policies.Definition
+		code     string
+	}{
+		{"an unknown effect", policies.Definition{Resource: "users", Action: "read", Effect: "maybe"}, policies.ErrInvalidEffect.Code},
+		{"an unknown resource", policies.Definition{Resource: "usres", Action: "read", Effect: "allow"}, "UNKNOWN_RESOURCE"},
+		{"an unknown action", policies.Definition{Resource: "users", Action: "raed", Effect: "allow"}, "UNKNOWN_ACTION"},
+		{"a custom resource of another project", policies.Definition{Resource: "docs:document", Action: "read", Effect: "allow"}, "UNKNOWN_RESOURCE"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.Impact(ctx, policy.ID, tc.proposed)
+			var apiErr *apierrors.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != tc.code {
+				t.Fatalf("err = %v, want %s", err, tc.code)
+			}
+		})
+	}
+
+	_, err := manager.Impact(ctx, uuid.New(), policies.Definition{Resource: "users", Action: "read", Effect: "allow"})
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "NOT_FOUND" {
+		t.Fatalf("Impact of an unknown policy = %v, want NOT_FOUND", err)
+	}
+}
@@ -6,46 +6,96 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
+var log = logging.Named("policies")
+
 // PolicyManager defines the interface for policy management operations
 type PolicyManager interface {
-	CreatePolicy(ctx context.Context, name, description, resource, action, effect string) (*schemas.Policy, error)
+	CreatePolicy(ctx context.Context, name, description, resource, action, effect string, projectID *uuid.UUID) (*schemas.Policy, error)
 	GetPolicy(ctx context.Context, id uuid.UUID) (*schemas.Policy, error)
-	ListPolicies(ctx context.Context) ([]schemas.Policy, error)
+	GetPolicyIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Policy, error)
+	GetPolicyByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Policy, error)
+	ListPolicies(ctx context.Context, order sorting.Order) ([]schemas.Policy, error)
 	UpdatePolicy(ctx context.Context, id uuid.UUID, name, description, resource, action, effect string) (*schemas.Policy, error)
+	PatchPolicy(ctx context.Context, id uuid.UUID, patch PolicyPatch) (*schemas.Policy, error)
 	DeletePolicy(ctx context.Context, id uuid.UUID) error
+	Authorize(ctx context.Context, projectID, userID uuid.UUID, resource, action string) (*Decision, error)
+	AffectedUsers(ctx context.Context, id uuid.UUID, sampleSize int) (*AffectedUsers, error)
+	Impact(ctx context.Context, id uuid.UUID, proposed Definition) (*PolicyImpact, error)
+	RolePolicies(ctx context.Context, projectID, roleID uuid.UUID) ([]schemas.Policy, error)
 }
 
+// ErrPolicyNotFound is returned when a policy does not exist or has been
+// deleted
+var ErrPolicyNotFound = apierrors.NotFound("policy not found")
+
+// ErrInvalidEffect is returned for a policy effect other than allow or deny
+var ErrInvalidEffect = apierrors.BadRequest("INVALID_EFFECT", "effect must be either 'allow' or 'deny'")
+
+// ErrNameIsUUID is returned for a policy name that parses as a UUID, so a
+// name can never be mistaken for an ID
+var ErrNameIsUUID = apierrors.BadRequest("INVALID_NAME", "policy name must not be a UUID")
+
+// policyCacheTTL is how long a role's policies are served from memory for
+// authorization checks. Changes made through this manager apply at once;
+// assigning a policy to a role shows up once the entry expires.
+const policyCacheTTL = 10 * time.Second
+
 // Manager implements the PolicyManager interface
 type Manager struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Regions *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Clock   clock.Clock
+
+	policyCache *cache.TTL[rolePoliciesKey, []schemas.Policy]
 }
 
 // NewManager creates a new policy manager
-func NewManager(db *gorm.DB) PolicyManager {
+func NewManager(db *gorm.DB, regions *regions.Resolver) PolicyManager {
 	return &Manager{
-		DB: db,
+		DB:          db,
+		Regions:     regions,
+		Clock:       clock.Real{},
+		policyCache: cache.NewTTL[rolePoliciesKey, []schemas.Policy](policyCacheTTL),
 	}
 }
 
-// CreatePolicy creates a new policy
-func (m *Manager) CreatePolicy(ctx context.Context, name, description, resource, action, effect string) (*schemas.Policy, error) {
+// CreatePolicy creates a new policy, scoped to a project when projectID is set
+func (m *Manager) CreatePolicy(ctx context.Context, name, description, resource, action, effect string, projectID *uuid.UUID) (*schemas.Policy, error) {
+	if _, err := uuid.Parse(name); err == nil {
+		return nil, ErrNameIsUUID
+	}
+	custom, err := m.customResources(projectID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if policy with the same name already exists
 	var existingPolicy schemas.Policy
-	if err := m.DB.Where("name = ?", name).First(&existingPolicy).Error; err == nil {
-		return nil, errors.New("policy with this name already exists")
+	if err := m.DB.Scopes(schemas.InProject(projectID)).Where("name = ?", name).First(&existingPolicy).Error; err == nil {
+		return nil, apierrors.Conflict("policy with this name already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
 	// Validate effect
 	if effect != "allow" && effect != "deny" {
-		return nil, errors.New("effect must be either 'allow' or 'deny'")
+		return nil, ErrInvalidEffect
+	}
+
+	if err := ValidateAction(resource, action, custom); err != nil {
+		return nil, err
 	}
 
 	// Create new policy
@@ -56,36 +106,79 @@ func (m *Manager) CreatePolicy(ctx context.Context, name, description, resource,
 		Resource:    resource,
 		Action:      action,
 		Effect:      effect,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ProjectId:   projectID,
+		CreatedBy:   audit.ActorID(ctx),
+		CreatedAt:   m.Clock.Now(),
+		UpdatedAt:   m.Clock.Now(),
 	}
 
 	if err := m.DB.Create(&policy).Error; err != nil {
-		klog.Errorf("Failed to create policy: %v", err)
+		log.For(ctx).Errorf("Failed to create policy: %v", err)
 		return nil, errors.New("failed to create policy")
 	}
 
+	m.policyCache.Purge()
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    policy.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourcePolicy,
+		ResourceID:   policy.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	return &policy, nil
 }
 
 // GetPolicy gets a policy by ID
 func (m *Manager) GetPolicy(ctx context.Context, id uuid.UUID) (*schemas.Policy, error) {
+	return m.getPolicy(m.DB, id)
+}
+
+// GetPolicyIncludingDeleted gets a policy by ID even if it has been soft-deleted
+func (m *Manager) GetPolicyIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Policy, error) {
+	return m.getPolicy(m.DB.Unscoped(), id)
+}
+
+func (m *Manager) getPolicy(db *gorm.DB, id uuid.UUID) (*schemas.Policy, error) {
 	var policy schemas.Policy
-	if err := m.DB.First(&policy, "id = ?", id).Error; err != nil {
+	if err := db.First(&policy, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("policy not found")
+			return nil, ErrPolicyNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 	return &policy, nil
 }
 
-// ListPolicies lists all policies
-func (m *Manager) ListPolicies(ctx context.Context) ([]schemas.Policy, error) {
+// GetPolicyByName gets the policy with exactly this name among the project's
+// policies, or among the global policies when projectID is nil. The lookup
+// uses the unique name index; since the column may compare without case, the
+// name is checked again so the match is case-sensitive.
+func (m *Manager) GetPolicyByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Policy, error) {
+	var policy schemas.Policy
+	if err := m.DB.Scopes(schemas.InProject(projectID)).Where("name = ?", name).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPolicyNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if policy.Name != name {
+		return nil, ErrPolicyNotFound
+	}
+	return &policy, nil
+}
+
+// SortFields are the fields ListPolicies can sort by
+var SortFields = []string{"name", "resource", "action", "created_at", "updated_at"}
+
+// ListPolicies lists all policies in order
+func (m *Manager) ListPolicies(ctx context.Context, order sorting.Order) ([]schemas.Policy, error) {
 	var policies []schemas.Policy
-	if err := m.DB.Find(&policies).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+	if err := order.Apply(m.DB).Find(&policies).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 	return policies, nil
@@ -93,26 +186,41 @@ func (m *Manager) ListPolicies(ctx context.Context) ([]schemas.Policy, error) {
 
 // UpdatePolicy updates a policy
 func (m *Manager) UpdatePolicy(ctx context.Context, id uuid.UUID, name, description, resource, action, effect string) (*schemas.Policy, error) {
-	// Check if another policy with the same name already exists
-	var existingPolicy schemas.Policy
-	if err := m.DB.Where("name = ? AND id != ?", name, id).First(&existingPolicy).Error; err == nil {
-		return nil, errors.New("another policy with this name already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
-		return nil, errors.New("internal server error")
+	if _, err := uuid.Parse(name); err == nil {
+		return nil, ErrNameIsUUID
 	}
-
 	// Validate effect
 	if effect != "allow" && effect != "deny" {
-		return nil, errors.New("effect must be either 'allow' or 'deny'")
+		return nil, ErrInvalidEffect
 	}
 
 	var policy schemas.Policy
 	if err := m.DB.First(&policy, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("policy not found")
+			return nil, ErrPolicyNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	if policy.IsSystem && name != policy.Name {
+		return nil, ErrSystemPolicy
+	}
+
+	custom, err := m.customResources(policy.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateAction(resource, action, custom); err != nil {
+		return nil, err
+	}
+
+	// Check if another policy with the same name already exists
+	var existingPolicy schemas.Policy
+	if err := m.DB.Scopes(schemas.InProject(policy.ProjectId)).Where("name = ? AND id != ?", name, id).First(&existingPolicy).Error; err == nil {
+		return nil, apierrors.Conflict("another policy with this name already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
@@ -122,13 +230,23 @@ func (m *Manager) UpdatePolicy(ctx context.Context, id uuid.UUID, name, descript
 	policy.Resource = resource
 	policy.Action = action
 	policy.Effect = effect
-	policy.UpdatedAt = time.Now()
+	policy.UpdatedAt = m.Clock.Now()
 
 	if err := m.DB.Save(&policy).Error; err != nil {
-		klog.Errorf("Failed to update policy: %v", err)
+		log.For(ctx).Errorf("Failed to update policy: %v", err)
 		return nil, errors.New("failed to update policy")
 	}
 
+	m.policyCache.Purge()
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    policy.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourcePolicy,
+		ResourceID:   policy.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	return &policy, nil
 }
 
@@ -138,17 +256,49 @@ func (m *Manager) DeletePolicy(ctx context.Context, id uuid.UUID) error {
 	var policy schemas.Policy
 	if err := m.DB.First(&policy, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("policy not found")
+			return ErrPolicyNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
+	if policy.IsSystem {
+		return ErrSystemPolicy
+	}
 
 	// Delete policy
-	if err := m.DB.Delete(&policy).Error; err != nil {
-		klog.Errorf("Failed to delete policy: %v", err)
+	if err := m.DB.Model(&policy).Updates(schemas.SoftDeletion(m.Clock.Now(), audit.ActorID(ctx))).Error; err != nil {
+		log.For(ctx).Errorf("Failed to delete policy: %v", err)
 		return errors.New("failed to delete policy")
 	}
 
+	m.policyCache.Purge()
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    policy.ProjectId,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourcePolicy,
+		ResourceID:   policy.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	return nil
-}
\ No newline at end of file
+}
+
+// customResources returns the custom resources registered in the settings of
+// a project, or none for global policies
+func (m *Manager) customResources(projectID *uuid.UUID) (map[string][]string, error) {
+	if projectID == nil {
+		return nil, nil
+	}
+
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", *projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("project not found")
+		}
+		log.Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	return project.Settings.CustomResources, nil
+}
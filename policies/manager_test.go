@@ -0,0 +1,326 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+	"gorm.io/gorm"
+)
+
+func TestEvaluate(t *testing.T) {
+	allowRead := schemas.Policy{Name: "read", Resource: "users", Action: "read", Effect: "allow"}
+	allowAll := schemas.Policy{Name: "all", Resource: policies.Wildcard, Action: policies.Wildcard, Effect: "allow"}
+	denyDelete := schemas.Policy{Name: "no delete", Resource: "users", Action: "delete", Effect: "deny"}
+
+	for _, tc := range []struct {
+		name     string
+		policies []schemas.Policy
+		action   string
+		allowed  bool
+		reason   string
+		decider  string
+	}{
+		{"no policies", nil, "read", false, policies.ReasonNoMatch, ""},
+		{"matching allow", []schemas.Policy{allowRead}, "read", true, policies.ReasonMatchedPolicy, "read"},
+		{"no matching action", []schemas.Policy{allowRead}, "update", false, policies.ReasonNoMatch, ""},
+		{"wildcards", []schemas.Policy{allowAll}, "update", true, policies.ReasonMatchedPolicy, "all"},
+		{"deny overrides allow", []schemas.Policy{allowAll, denyDelete}, "delete", false, policies.ReasonExplicitDenial, "no delete"},
+		{"first allow decides", []schemas.Policy{allowRead, allowAll}, "read", true, policies.ReasonMatchedPolicy, "read"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decision := policies.Evaluate(tc.policies, "users", tc.action)
+			if decision.Allowed != tc.allowed || decision.Reason != tc.reason {
+				t.Fatalf("decision = %+v, want allowed %v for %s", decision, tc.allowed, tc.reason)
+			}
+			if name := policyName(decision.Policy); name != tc.decider {
+				t.Fatalf("decided by %q, want %q", name, tc.decider)
+			}
+		})
+	}
+}
+
+func policyName(p *schemas.Policy) string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+func TestCreatePolicy(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	project := withCustomResources(t, db, testutil.AProject().Build(t, db).Project)
+	ctx := context.Background()
+
+	if _, err := manager.CreatePolicy(ctx, "read users", "", "users", "read", "allow", nil); err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+	if _, err := manager.CreatePolicy(ctx, "read documents", "", "docs:document", "read", "allow", &project.ID); err != nil {
+		t.Fatalf("CreatePolicy of a custom resource: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		policy    string
+		resource  string
+		action    string
+		effect    string
+		projectID *uuid.UUID
+		want      error
+	}{
+		{"duplicate name", "read users", "users", "read", "allow", nil, nil},
+		{"name is a UUID", uuid.NewString(), "users", "read", "allow", nil, policies.ErrNameIsUUID},
+		{"invalid effect", "maybe", "users", "read", "maybe", nil, policies.ErrInvalidEffect},
+		{"unknown resource", "typo", "userz", "read", "allow", nil, nil},
+		{"unknown action", "typo", "users", "raed", "allow", nil, nil},
+		{"custom resource of another scope", "global documents", "docs:document", "read", "allow", nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.CreatePolicy(ctx, tc.policy, "", tc.resource, tc.action, tc.effect, tc.projectID)
+			if err == nil {
+				t.Fatal("CreatePolicy succeeded")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeletePolicy(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	role := testutil.ARole("Editor").Build(t, db)
+	policy := testutil.APolicy("edit", "users", "update").ForRole(role).Build(t, db)
+	system := testutil.APolicy("system", "users", "read").ForRole(role).Build(t, db)
+	if err := db.Model(&system).Update("is_system", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := manager.DeletePolicy(ctx, system.ID); !errors.Is(err, policies.ErrSystemPolicy) {
+		t.Fatalf("err = %v, want %v", err, policies.ErrSystemPolicy)
+	}
+	if err := manager.DeletePolicy(ctx, policy.ID); err != nil {
+		t.Fatalf("DeletePolicy: %v", err)
+	}
+	if _, err := manager.GetPolicy(ctx, policy.ID); !errors.Is(err, policies.ErrPolicyNotFound) {
+		t.Fatalf("err = %v, want %v", err, policies.ErrPolicyNotFound)
+	}
+	if _, err := manager.GetPolicyIncludingDeleted(ctx, policy.ID); err != nil {
+		t.Fatalf("GetPolicyIncludingDeleted: %v", err)
+	}
+	if err := manager.DeletePolicy(ctx, policy.ID); !errors.Is(err, policies.ErrPolicyNotFound) {
+		t.Fatalf("err = %v, want %v", err, policies.ErrPolicyNotFound)
+	}
+}
+
+func TestGetPolicyByName(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	project := testutil.AProject().Build(t, db).Project
+	role := testutil.ARole("Editor").Build(t, db)
+	global := testutil.APolicy("read users", "users", "read").ForRole(role).Build(t, db)
+	local := testutil.APolicy("read users", "users", "read").ForRole(role).Build(t, db)
+	if err := db.Model(&local).Update("project_id", project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if found, err := manager.GetPolicyByName(ctx, "read users", nil); err != nil || found.ID != global.ID {
+		t.Fatalf("GetPolicyByName without a project = %v, %v, want the global policy", found, err)
+	}
+	if found, err := manager.GetPolicyByName(ctx, "read users", &project.ID); err != nil || found.ID != local.ID {
+		t.Fatalf("GetPolicyByName in the project = %v, %v, want the project's policy", found, err)
+	}
+	for _, name := range []string{"Read Users", "read", ""} {
+		if _, err := manager.GetPolicyByName(ctx, name, nil); !errors.Is(err, policies.ErrPolicyNotFound) {
+			t.Errorf("GetPolicyByName(%q) = %v, want %v", name, err, policies.ErrPolicyNotFound)
+		}
+	}
+	other := uuid.New()
+	if _, err := manager.GetPolicyByName(ctx, "read users", &other); !errors.Is(err, policies.ErrPolicyNotFound) {
+		t.Errorf("GetPolicyByName in another project = %v, want %v", err, policies.ErrPolicyNotFound)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	built := testutil.AProject().WithRole("Editor").WithUser("a@example.com").WithInactiveUser("b@example.com").Build(t, db)
+	project := withCustomResources(t, db, built.Project)
+	role := built.Roles["Editor"]
+	ctx := context.Background()
+
+	for _, p := range []struct {
+		name, action, effect string
+	}{
+		{"read documents", "read", "allow"},
+		{"write documents", "write", "deny"},
+	} {
+		policy, err := manager.CreatePolicy(ctx, p.name, "", "docs:document", p.action, p.effect, &project.ID)
+		if err != nil {
+			t.Fatalf("CreatePolicy: %v", err)
+		}
+		if err := db.Model(policy).Update("roles_id", role.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := built.Users["a@example.com"].ID
+	for _, tc := range []struct {
+		name    string
+		userID  uuid.UUID
+		action  string
+		allowed bool
+		reason  string
+	}{
+		{"allowed", active, "read", true, policies.ReasonMatchedPolicy},
+		{"denied", active, "write", false, policies.ReasonExplicitDenial},
+		{"inactive user", built.Users["b@example.com"].ID, "read", false, policies.ReasonInactiveUser},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			effect := "deny"
+			if tc.allowed {
+				effect = "allow"
+			}
+			sample := fmt.Sprintf(`ums_policy_decisions_total{resource="docs:document",action=%q,effect=%q}`, tc.action, effect)
+			before := testutil.MetricValue(t, sample)
+
+			decision, err := manager.Authorize(ctx, project.ID, tc.userID, "docs:document", tc.action)
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if decision.Allowed != tc.allowed || decision.Reason != tc.reason {
+				t.Fatalf("decision = %+v, want allowed %v for %s", decision, tc.allowed, tc.reason)
+			}
+			if got := testutil.MetricValue(t, sample) - before; got != 1 {
+				t.Errorf("%s grew by %v, want 1", sample, got)
+			}
+		})
+	}
+
+	if _, err := manager.Authorize(ctx, project.ID, active, "users", "read"); err == nil {
+		t.Error("Authorize checked a built-in resource")
+	}
+	if _, err := manager.Authorize(ctx, project.ID, active, "docs:document", policies.Wildcard); err == nil {
+		t.Error("Authorize checked the wildcard action")
+	}
+	if _, err := manager.Authorize(ctx, project.ID, uuid.New(), "docs:document", "read"); err == nil {
+		t.Error("Authorize checked an unknown user")
+	}
+}
+
+// withCustomResources registers the docs:document resource, with read and
+// write actions, in the project's settings
+func withCustomResources(t *testing.T, db *gorm.DB, project schemas.Project) schemas.Project {
+	t.Helper()
+
+	project.Settings.CustomResources = map[string][]string{"docs:document": {"read", "write"}}
+	if err := db.Save(&project).Error; err != nil {
+		t.Fatalf("failed to save the project settings: %v", err)
+	}
+	return project
+}
+
+func TestCustomResourcesAreIsolatedBetweenProjects(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	a := testutil.AProject().WithRole("Editor").WithUser("a@example.com").Build(t, db)
+	b := testutil.AProject().WithUser("b@example.com").Build(t, db)
+	ctx := context.Background()
+
+	// Both projects declare docs:document, and their users share a role
+	projectA := withCustomResources(t, db, a.Project)
+	projectB := withCustomResources(t, db, b.Project)
+	role := a.Roles["Editor"]
+	userB := b.Users["b@example.com"].ID
+	if err := db.Table(testutil.ProjectUserTable(projectB.ID)).Where("id = ?", userB).Update("role_id", role.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := manager.CreatePolicy(ctx, "read documents", "", "docs:document", "read", "allow", &projectA.ID)
+	if err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+	if err := db.Model(policy).Update("roles_id", role.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	decision, err := manager.Authorize(ctx, projectA.ID, a.Users["a@example.com"].ID, "docs:document", "read")
+	if err != nil || !decision.Allowed {
+		t.Fatalf("Authorize in the policy's project = %+v, %v, want allowed", decision, err)
+	}
+	decision, err = manager.Authorize(ctx, projectB.ID, userB, "docs:document", "read")
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if decision.Allowed || decision.Reason != policies.ReasonNoMatch {
+		t.Fatalf("decision in the other project = %+v, want no matching policy", decision)
+	}
+
+	// A project that does not declare the resource cannot use it at all
+	other := testutil.AProject().Build(t, db).Project
+	if _, err := manager.CreatePolicy(ctx, "borrowed documents", "", "docs:document", "read", "allow", &other.ID); err == nil {
+		t.Error("CreatePolicy accepted a resource the project does not declare")
+	}
+	_, err = manager.Authorize(ctx, other.ID, uuid.New(), "docs:document", "read")
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "UNKNOWN_RESOURCE" {
+		t.Errorf("Authorize of an undeclared resource = %v, want UNKNOWN_RESOURCE", err)
+	}
+}
+
+func TestPatchPolicy(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	role := testutil.ARole("Editor").Build(t, db)
+	policy := testutil.APolicy("edit users", "users", "write").ForRole(role).Build(t, db)
+	ctx := context.Background()
+	ptr := func(s string) *string { return &s }
+
+	patched, err := manager.PatchPolicy(ctx, policy.ID, policies.PolicyPatch{Effect: ptr("deny")})
+	if err != nil {
+		t.Fatalf("PatchPolicy: %v", err)
+	}
+	if patched.Effect != "deny" || patched.Name != "edit users" || patched.Resource != "users" || patched.Action != "write" {
+		t.Errorf("patching the effect gave %+v, want only the effect changed", patched)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		patch policies.PolicyPatch
+		want  error
+	}{
+		{"invalid effect", policies.PolicyPatch{Effect: ptr("maybe")}, policies.ErrInvalidEffect},
+		{"action unknown to the current resource", policies.PolicyPatch{Action: ptr("raed")}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.PatchPolicy(ctx, policy.ID, tc.patch)
+			if err == nil {
+				t.Fatal("PatchPolicy succeeded")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+	stored, err := manager.GetPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("GetPolicy: %v", err)
+	}
+	if stored.Effect != "deny" || stored.Action != "write" {
+		t.Errorf("policy after the rejected patches = %+v, want it unchanged", stored)
+	}
+
+	if _, err := manager.PatchPolicy(ctx, uuid.New(), policies.PolicyPatch{Effect: ptr("allow")}); !errors.Is(err, policies.ErrPolicyNotFound) {
+		t.Errorf("err = %v, want %v", err, policies.ErrPolicyNotFound)
+	}
+}
@@ -0,0 +1,28 @@
+package policies
+
+import "github.com/yash3004/user_management_service/internal/metrics"
+
+// maxResourceLabels bounds the resource label of ums_policy_decisions_total.
+// Custom resources are declared per project, so their number is open ended.
+const maxResourceLabels = 200
+
+var (
+	policyDecisions = metrics.NewCounter("ums_policy_decisions_total",
+		"Authorization decisions of the policy engine, by resource, action and effect.", "resource", "action", "effect")
+	resourceLabels = metrics.NewLabelCap(maxResourceLabels)
+)
+
+// RecordDecision counts a decision of an authorization check or of a route's
+// policy check. Once resources are no longer labeled individually, their
+// actions are not either.
+func RecordDecision(resource, action string, decision Decision) {
+	effect := "deny"
+	if decision.Allowed {
+		effect = "allow"
+	}
+	resource = resourceLabels.Value(resource)
+	if resource == metrics.OtherLabel {
+		action = metrics.OtherLabel
+	}
+	policyDecisions.Inc(resource, action, effect)
+}
@@ -0,0 +1,52 @@
+package policies
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// PolicyPatch is a partial update of a policy. Nil fields keep their
+// current value.
+type PolicyPatch struct {
+	Name        *string
+	Description *string
+	Resource    *string
+	Action      *string
+	Effect      *string
+}
+
+// PatchPolicy updates the fields of a policy that the patch sets. The result
+// is validated as a whole, as by UpdatePolicy, so a new action must suit the
+// resource even when only one of them is patched.
+func (m *Manager) PatchPolicy(ctx context.Context, id uuid.UUID, patch PolicyPatch) (*schemas.Policy, error) {
+	var policy schemas.Policy
+	if err := m.DB.First(&policy, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPolicyNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	name, description, resource, action, effect := policy.Name, policy.Description, policy.Resource, policy.Action, policy.Effect
+	if patch.Name != nil {
+		name = *patch.Name
+	}
+	if patch.Description != nil {
+		description = *patch.Description
+	}
+	if patch.Resource != nil {
+		resource = *patch.Resource
+	}
+	if patch.Action != nil {
+		action = *patch.Action
+	}
+	if patch.Effect != nil {
+		effect = *patch.Effect
+	}
+	return m.UpdatePolicy(ctx, id, name, description, resource, action, effect)
+}
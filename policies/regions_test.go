@@ -0,0 +1,67 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+	"gorm.io/gorm"
+)
+
+func TestAffectedUsersCountsUsersInEveryRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	role := testutil.ARole("Editor").Build(t, db)
+	policy := testutil.APolicy("edit", "users", "update").ForRole(role).Build(t, db)
+
+	primary := testutil.AProject().Build(t, db)
+	regional := testutil.AProject().InRegion("eu", databases["eu"]).Build(t, db)
+	addProjectUser(t, db, primary.Project.ID, "primary@example.com", role.ID)
+	addProjectUser(t, databases["eu"], regional.Project.ID, "regional@example.com", role.ID)
+
+	manager := policies.NewManager(db, resolver)
+	affected, err := manager.AffectedUsers(context.Background(), policy.ID, 0)
+	if err != nil {
+		t.Fatalf("AffectedUsers: %v", err)
+	}
+	if affected.ProjectUsers != 2 {
+		t.Fatalf("project users = %d, want 2", affected.ProjectUsers)
+	}
+	emails := map[string]bool{}
+	for _, u := range affected.Sample {
+		emails[u.Email] = true
+	}
+	if !emails["primary@example.com"] || !emails["regional@example.com"] {
+		t.Fatalf("sample = %+v, want the users of both regions", affected.Sample)
+	}
+}
+
+// addProjectUser adds a user holding roleID to the project's table in db
+func addProjectUser(t *testing.T, db *gorm.DB, projectID uuid.UUID, email string, roleID uuid.UUID) {
+	t.Helper()
+
+	user := schemas.ProjectUser{ID: uuid.New(), Email: email, RoleId: roleID, ProjectId: projectID}
+	if err := db.Table(testutil.ProjectUserTable(projectID)).Create(&user).Error; err != nil {
+		t.Fatalf("failed to create project user: %v", err)
+	}
+}
+
+func TestAuthorizeReadsTheUserInTheProjectRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	built := testutil.AProject().InRegion("eu", databases["eu"]).WithRole("Editor").WithUser("a@example.com").Build(t, db)
+	project := withCustomResources(t, db, built.Project)
+	testutil.APolicy("read documents", "docs:document", "read").ForRole(built.Roles["Editor"]).Build(t, db)
+
+	manager := policies.NewManager(db, resolver)
+	decision, err := manager.Authorize(context.Background(), project.ID, built.Users["a@example.com"].ID, "docs:document", "read")
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if !decision.Allowed {
+		t.Fatalf("decision = %+v, want the regional user allowed", decision)
+	}
+}
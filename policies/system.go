@@ -0,0 +1,186 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+// ErrSystemPolicy is returned for deleting or renaming a system policy
+var ErrSystemPolicy = apierrors.New(http.StatusConflict, "SYSTEM_POLICY", "system policies cannot be deleted or renamed")
+
+// SystemPoliciesVersion is the version of SystemPolicies; raise it with
+// every change to the manifest
+const SystemPoliciesVersion = 1
+
+// SystemPolicy is a global policy of the SuperAdmin role that the service
+// creates and keeps as defined here
+type SystemPolicy struct {
+	Name        string
+	Description string
+	Resource    string
+	Action      string
+	Effect      string
+}
+
+// SystemPolicies is the manifest of system policies. Entries may be added
+// in later versions; existing installs get them on their next start.
+var SystemPolicies = []SystemPolicy{
+	{Name: "AllProjectsAccess", Description: "Full access to manage projects", Resource: "projects", Action: Wildcard, Effect: "allow"},
+	{Name: "AllRolesAccess", Description: "Full access to manage roles", Resource: "roles", Action: Wildcard, Effect: "allow"},
+	{Name: "AllPoliciesAccess", Description: "Full access to manage policies", Resource: "policies", Action: Wildcard, Effect: "allow"},
+}
+
+// SystemPolicyDrift is a system policy that no longer matches the manifest
+type SystemPolicyDrift struct {
+	Name     string
+	Fields   []string // The columns that differ, e.g. "effect"
+	Reverted bool
+}
+
+// SystemPolicyReport is what ReconcileSystemPolicies found and did
+type SystemPolicyReport struct {
+	Version int
+	Created []string
+	Drifted []SystemPolicyDrift
+}
+
+// ReconcileSystemPolicies brings the system policies in line with
+// SystemPolicies. It is safe to run on every start: it creates the
+// SuperAdmin role and the policies that are missing, marks a global policy
+// that already has a manifest name as a system policy, and reports the
+// policies whose definition drifted from the manifest. Drifted policies are
+// only changed back when revert is set. Every change is audited.
+func ReconcileSystemPolicies(ctx context.Context, db *gorm.DB, now time.Time, revert bool) (*SystemPolicyReport, error) {
+	report := &SystemPolicyReport{Version: SystemPoliciesVersion}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		roleID, err := superAdminRole(tx, now)
+		if err != nil {
+			return err
+		}
+		for _, want := range SystemPolicies {
+			var policy schemas.Policy
+			err := tx.Scopes(schemas.InProject(nil)).Where("name = ?", want.Name).First(&policy).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				policy = schemas.Policy{
+					ID:          uuid.New(),
+					Name:        want.Name,
+					Description: want.Description,
+					Resource:    want.Resource,
+					Action:      want.Action,
+					Effect:      want.Effect,
+					IsSystem:    true,
+					RolesId:     roleID,
+					CreatedAt:   now,
+					UpdatedAt:   now,
+				}
+				if err := tx.Create(&policy).Error; err != nil {
+					return fmt.Errorf("creating system policy %s: %w", want.Name, err)
+				}
+				report.Created = append(report.Created, want.Name)
+				audit.Record(ctx, tx, audit.Entry{
+					Action:       audit.ActionCreate,
+					ResourceType: audit.ResourcePolicy,
+					ResourceID:   policy.ID.String(),
+					Details:      fmt.Sprintf("system policy, manifest version %d", SystemPoliciesVersion),
+					At:           now,
+				})
+				continue
+			} else if err != nil {
+				return fmt.Errorf("loading system policy %s: %w", want.Name, err)
+			}
+
+			if !policy.IsSystem {
+				if err := tx.Model(&policy).Update("is_system", true).Error; err != nil {
+					return fmt.Errorf("marking %s as a system policy: %w", want.Name, err)
+				}
+			}
+			fields := policyDrift(policy, want, roleID)
+			if len(fields) == 0 {
+				continue
+			}
+			drift := SystemPolicyDrift{Name: want.Name, Fields: fields}
+			if revert {
+				if err := tx.Model(&policy).Updates(map[string]interface{}{
+					"description": want.Description,
+					"resource":    want.Resource,
+					"action":      want.Action,
+					"effect":      want.Effect,
+					"roles_id":    roleID,
+					"updated_at":  now,
+				}).Error; err != nil {
+					return fmt.Errorf("reverting system policy %s: %w", want.Name, err)
+				}
+				drift.Reverted = true
+				audit.Record(ctx, tx, audit.Entry{
+					Action:       audit.ActionUpdate,
+					ResourceType: audit.ResourcePolicy,
+					ResourceID:   policy.ID.String(),
+					Details:      "reverted drift in " + strings.Join(fields, ", ") + " to the system policy manifest",
+					At:           now,
+				})
+			}
+			report.Drifted = append(report.Drifted, drift)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// superAdminRole returns the ID of the global SuperAdmin role, creating the
+// role when there is none
+func superAdminRole(tx *gorm.DB, now time.Time) (uuid.UUID, error) {
+	var role schemas.Role
+	err := tx.Scopes(schemas.InProject(nil)).Where("name = ?", roles.SuperAdminRole).First(&role).Error
+	if err == nil {
+		return role.ID, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, fmt.Errorf("loading the %s role: %w", roles.SuperAdminRole, err)
+	}
+	role = schemas.Role{
+		ID:          uuid.New(),
+		Name:        roles.SuperAdminRole,
+		Description: "Allowed everything",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := tx.Create(&role).Error; err != nil {
+		return uuid.Nil, fmt.Errorf("creating the %s role: %w", roles.SuperAdminRole, err)
+	}
+	return role.ID, nil
+}
+
+// policyDrift lists the columns of a system policy that differ from its
+// manifest entry
+func policyDrift(policy schemas.Policy, want SystemPolicy, roleID uuid.UUID) []string {
+	var fields []string
+	if policy.Description != want.Description {
+		fields = append(fields, "description")
+	}
+	if policy.Resource != want.Resource {
+		fields = append(fields, "resource")
+	}
+	if policy.Action != want.Action {
+		fields = append(fields, "action")
+	}
+	if policy.Effect != want.Effect {
+		fields = append(fields, "effect")
+	}
+	if policy.RolesId != roleID {
+		fields = append(fields, "role")
+	}
+	return fields
+}
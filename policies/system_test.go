@@ -0,0 +1,129 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/policies"
+	"gorm.io/gorm"
+)
+
+// reconciledAt is when the tests reconcile the system policies
+var reconciledAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// systemPolicy loads a global system policy by name
+func systemPolicy(t *testing.T, db *gorm.DB, name string) schemas.Policy {
+	t.Helper()
+
+	var policy schemas.Policy
+	if err := db.Scopes(schemas.InProject(nil)).Where("name = ?", name).First(&policy).Error; err != nil {
+		t.Fatalf("loading system policy %s: %v", name, err)
+	}
+	return policy
+}
+
+func TestReconcileSystemPolicies(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	ctx := context.Background()
+	reconcile := func(revert bool) *policies.SystemPolicyReport {
+		t.Helper()
+		report, err := policies.ReconcileSystemPolicies(ctx, db, reconciledAt, revert)
+		if err != nil {
+			t.Fatalf("ReconcileSystemPolicies: %v", err)
+		}
+		return report
+	}
+
+	report := reconcile(false)
+	if len(report.Created) != len(policies.SystemPolicies) || len(report.Drifted) != 0 {
+		t.Fatalf("first run = %+v, want every system policy created", report)
+	}
+	for _, want := range policies.SystemPolicies {
+		if policy := systemPolicy(t, db, want.Name); !policy.IsSystem || policy.Effect != want.Effect {
+			t.Errorf("%s = %+v, want a system policy as in the manifest", want.Name, policy)
+		}
+	}
+	if report := reconcile(false); len(report.Created) != 0 || len(report.Drifted) != 0 {
+		t.Fatalf("second run = %+v, want nothing done", report)
+	}
+
+	drifted := systemPolicy(t, db, "AllProjectsAccess")
+	if err := db.Model(&drifted).Update("effect", "deny").Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []policies.SystemPolicyDrift{{Name: "AllProjectsAccess", Fields: []string{"effect"}}}
+	if report := reconcile(false); !reflect.DeepEqual(report.Drifted, want) {
+		t.Errorf("drift = %+v, want %+v", report.Drifted, want)
+	}
+	if policy := systemPolicy(t, db, "AllProjectsAccess"); policy.Effect != "deny" {
+		t.Errorf("effect = %q, want the drift kept without revert", policy.Effect)
+	}
+	want[0].Reverted = true
+	if report := reconcile(true); !reflect.DeepEqual(report.Drifted, want) {
+		t.Errorf("drift = %+v, want %+v", report.Drifted, want)
+	}
+	if policy := systemPolicy(t, db, "AllProjectsAccess"); policy.Effect != "allow" {
+		t.Errorf("effect = %q, want it reverted to allow", policy.Effect)
+	}
+	if report := reconcile(false); len(report.Drifted) != 0 {
+		t.Errorf("drift after reverting = %+v, want none", report.Drifted)
+	}
+}
+
+func TestReconcileSystemPoliciesAddsNewManifestEntries(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	ctx := context.Background()
+	if _, err := policies.ReconcileSystemPolicies(ctx, db, reconciledAt, false); err != nil {
+		t.Fatalf("ReconcileSystemPolicies: %v", err)
+	}
+
+	// As a later release would
+	original := policies.SystemPolicies
+	t.Cleanup(func() { policies.SystemPolicies = original })
+	added := policies.SystemPolicy{Name: "AllUsersAccess", Description: "Full access to manage users", Resource: "users", Action: policies.Wildcard, Effect: "allow"}
+	policies.SystemPolicies = append(append([]policies.SystemPolicy(nil), original...), added)
+
+	report, err := policies.ReconcileSystemPolicies(ctx, db, reconciledAt, false)
+	if err != nil {
+		t.Fatalf("ReconcileSystemPolicies: %v", err)
+	}
+	if !reflect.DeepEqual(report.Created, []string{added.Name}) || len(report.Drifted) != 0 {
+		t.Fatalf("upgrade = %+v, want only %s created", report, added.Name)
+	}
+	if policy := systemPolicy(t, db, added.Name); !policy.IsSystem || policy.RolesId != systemPolicy(t, db, "AllRolesAccess").RolesId {
+		t.Errorf("%s = %+v, want a system policy of the SuperAdmin role", added.Name, policy)
+	}
+}
+
+func TestSystemPoliciesCannotBeDeletedOrRenamed(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := policies.NewManager(db, nil)
+	ctx := context.Background()
+	if _, err := policies.ReconcileSystemPolicies(ctx, db, reconciledAt, false); err != nil {
+		t.Fatalf("ReconcileSystemPolicies: %v", err)
+	}
+	policy := systemPolicy(t, db, "AllRolesAccess")
+	rename := "SomeRolesAccess"
+	description := "Manage roles"
+
+	if err := manager.DeletePolicy(ctx, policy.ID); !errors.Is(err, policies.ErrSystemPolicy) {
+		t.Errorf("DeletePolicy: err = %v, want %v", err, policies.ErrSystemPolicy)
+	}
+	if _, err := manager.UpdatePolicy(ctx, policy.ID, rename, policy.Description, policy.Resource, policy.Action, policy.Effect); !errors.Is(err, policies.ErrSystemPolicy) {
+		t.Errorf("UpdatePolicy renaming: err = %v, want %v", err, policies.ErrSystemPolicy)
+	}
+	if _, err := manager.PatchPolicy(ctx, policy.ID, policies.PolicyPatch{Name: &rename}); !errors.Is(err, policies.ErrSystemPolicy) {
+		t.Errorf("PatchPolicy renaming: err = %v, want %v", err, policies.ErrSystemPolicy)
+	}
+	if _, err := manager.PatchPolicy(ctx, policy.ID, policies.PolicyPatch{Description: &description}); err != nil {
+		t.Errorf("PatchPolicy of the description: %v", err)
+	}
+	if stored := systemPolicy(t, db, "AllRolesAccess"); stored.Description != description {
+		t.Errorf("description = %q, want %q", stored.Description, description)
+	}
+}
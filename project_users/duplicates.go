@@ -0,0 +1,369 @@
+package projectusers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webhooks"
+	"gorm.io/gorm"
+)
+
+// Confidence levels of a duplicate cluster
+const (
+	// ConfidenceHigh clusters are linked by email or OAuth identity alone
+	ConfidenceHigh = "high"
+	// ConfidenceMedium clusters need a name match to hold together
+	ConfidenceMedium = "medium"
+)
+
+// Reasons two users are taken for the same person
+const (
+	MatchEmail         = "normalized_email"
+	MatchOAuthID       = "oauth_id"
+	MatchNameAndDomain = "name_and_domain"
+)
+
+// ErrOAuthIdentityConflict is returned when both users of a merge are linked
+// to different OAuth identities, of which the primary can only keep one
+var ErrOAuthIdentityConflict = apierrors.New(http.StatusConflict, "OAUTH_IDENTITY_CONFLICT", "both users are linked to different OAuth identities")
+
+// DuplicateCluster is a group of users that are likely the same person
+type DuplicateCluster struct {
+	Confidence string               `json:"confidence"`
+	Reasons    []string             `json:"reasons"` // Every kind of match found within the cluster
+	Users      []models.DisplayUser `json:"users"`   // Oldest first
+}
+
+// MergeResult reports what a merge moved to the primary user
+type MergeResult struct {
+	Primary       models.DisplayUser `json:"primary"`
+	DuplicateID   string             `json:"duplicate_id"`
+	LoginEvents   int64              `json:"login_events"`   // Login history entries re-pointed
+	Passkeys      int64              `json:"passkeys"`       // Passkeys re-pointed
+	OAuthIdentity bool               `json:"oauth_identity"` // Whether the OAuth identity moved
+	Deactivated   bool               `json:"deactivated"`    // Whether this merge deactivated the duplicate
+}
+
+// normalizeEmail reduces an address to the mailbox it delivers to: case and
+// +tags are dropped, and for Gmail, which ignores them, the dots as well
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+// emailDomain returns the domain of a normalized address
+func emailDomain(normalized string) string {
+	_, domain, _ := strings.Cut(normalized, "@")
+	return domain
+}
+
+// normalizeName reduces a full name to its lowercase letter and digit words in
+// sorted order, so "Doe, Jane" and "jane doe" match. It returns "" for a name
+// with no words.
+func normalizeName(firstName, lastName string) string {
+	words := strings.FieldsFunc(strings.ToLower(firstName+" "+lastName), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// clusters is a union-find over user indexes
+type clusters []int
+
+func newClusters(n int) clusters {
+	c := make(clusters, n)
+	for i := range c {
+		c[i] = i
+	}
+	return c
+}
+
+func (c clusters) find(i int) int {
+	for c[i] != i {
+		c[i] = c[c[i]]
+		i = c[i]
+	}
+	return i
+}
+
+func (c clusters) union(i, j int) {
+	c[c.find(i)] = c.find(j)
+}
+
+// FindDuplicates groups the project's live users that are likely the same
+// person: by normalized email, by OAuth identity, and by the same name at the
+// same email domain. A cluster is of high confidence if its users are linked
+// by email and OAuth matches alone. Deleted and deactivated users, which
+// includes the duplicates of past merges, are left out.
+func (m *ProjectUserManagerImpl) FindDuplicates(ctx context.Context, projectID string) ([]DuplicateCluster, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	var users []schemas.ProjectUser
+	if err := db.Table(tableName).Where("status <> ?", schemas.UserStatusDeactivated).
+		Order("created_at, id").Find(&users).Error; err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	all := newClusters(len(users))
+	strong := newClusters(len(users))
+	reasons := make(map[int]map[string]bool)
+	link := func(keys map[string]int, key string, i int, reason string, c ...clusters) {
+		first, seen := keys[key]
+		if !seen {
+			keys[key] = i
+			return
+		}
+		for _, set := range c {
+			set.union(first, i)
+		}
+		if reasons[i] == nil {
+			reasons[i] = make(map[string]bool)
+		}
+		reasons[i][reason] = true
+	}
+
+	byEmail := make(map[string]int)
+	byOAuth := make(map[string]int)
+	byName := make(map[string]int)
+	for i, u := range users {
+		email := normalizeEmail(u.Email)
+		link(byEmail, email, i, MatchEmail, all, strong)
+		if u.OAuthType != "" && u.OAuthID != "" {
+			link(byOAuth, u.OAuthType+"\x00"+u.OAuthID, i, MatchOAuthID, all, strong)
+		}
+		if name := normalizeName(u.FirstName, u.LastName); name != "" {
+			link(byName, name+"\x00"+emailDomain(email), i, MatchNameAndDomain, all)
+		}
+	}
+
+	// Group by root, keeping users in creation order
+	members := make(map[int][]int)
+	var roots []int
+	for i := range users {
+		root := all.find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	result := []DuplicateCluster{}
+	for _, root := range roots {
+		indexes := members[root]
+		if len(indexes) < 2 {
+			continue
+		}
+		cluster := DuplicateCluster{Confidence: ConfidenceHigh}
+		found := make(map[string]bool)
+		strongRoot := strong.find(indexes[0])
+		for _, i := range indexes {
+			if strong.find(i) != strongRoot {
+				cluster.Confidence = ConfidenceMedium
+			}
+			for reason := range reasons[i] {
+				found[reason] = true
+			}
+			cluster.Users = append(cluster.Users, toDisplayUser(users[i]))
+		}
+		for _, reason := range []string{MatchEmail, MatchOAuthID, MatchNameAndDomain} {
+			if found[reason] {
+				cluster.Reasons = append(cluster.Reasons, reason)
+			}
+		}
+		result = append(result, cluster)
+	}
+
+	// High confidence first; clusters are already ordered by their oldest user
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Confidence == ConfidenceHigh && result[j].Confidence != ConfidenceHigh
+	})
+	return result, nil
+}
+
+// MergeProjectUsers folds the duplicate user into the primary one: the
+// duplicate's login history, passkeys and OAuth identity are moved to the
+// primary and the duplicate is deactivated, all in one transaction. Both users
+// must belong to the project; a user of another project is not found, so
+// merging across projects is refused. Merging again changes nothing.
+func (m *ProjectUserManagerImpl) MergeProjectUsers(ctx context.Context, projectID string, primaryID, duplicateID uuid.UUID) (*MergeResult, error) {
+	if primaryID == duplicateID {
+		return nil, apierrors.BadRequest("SAME_USER", "a user cannot be merged into itself")
+	}
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	result := &MergeResult{DuplicateID: duplicateID.String()}
+	var primary, duplicate schemas.ProjectUser
+	var previous string
+	now := m.Clock.Now()
+	// tx updates the users table and primaryTx the login events and passkeys
+	// in DB, the same transaction unless the project's users are in a
+	// regional database
+	merge := func(primaryTx, tx *gorm.DB) error {
+		for _, u := range []struct {
+			id   uuid.UUID
+			user *schemas.ProjectUser
+		}{{primaryID, &primary}, {duplicateID, &duplicate}} {
+			if err := tx.Table(tableName).Where("id = ?", u.id).First(u.user).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return apierrors.NotFound("user " + u.id.String() + " not found in this project; users can only be merged within one project")
+				}
+				m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+				return errors.New("internal server error")
+			}
+		}
+		if primary.Status == schemas.UserStatusDeactivated {
+			return apierrors.Conflict("the primary user is deactivated")
+		}
+		moveOAuth := duplicate.OAuthID != ""
+		if moveOAuth && primary.OAuthID != "" &&
+			(primary.OAuthType != duplicate.OAuthType || primary.OAuthID != duplicate.OAuthID) {
+			return ErrOAuthIdentityConflict
+		}
+
+		events := primaryTx.Model(&schemas.LoginEvent{}).
+			Where("project_id = ? AND user_id = ?", primary.ProjectId, duplicate.ID).
+			Update("user_id", primary.ID)
+		if events.Error != nil {
+			m.Log.For(ctx).Errorf("Failed to move login events: %v", redact.Error(events.Error))
+			return errors.New("failed to merge users")
+		}
+		result.LoginEvents = events.RowsAffected
+
+		passkeys := primaryTx.Model(&schemas.WebAuthnCredential{}).
+			Where("project_id = ? AND user_id = ?", primary.ProjectId, duplicate.ID).
+			Update("user_id", primary.ID)
+		if passkeys.Error != nil {
+			m.Log.For(ctx).Errorf("Failed to move passkeys: %v", redact.Error(passkeys.Error))
+			return errors.New("failed to merge users")
+		}
+		result.Passkeys = passkeys.RowsAffected
+
+		if moveOAuth {
+			if primary.OAuthID == "" {
+				primary.OAuthID = duplicate.OAuthID
+				primary.OAuthType = duplicate.OAuthType
+				primary.AccessToken = duplicate.AccessToken
+				primary.RefreshToken = duplicate.RefreshToken
+				primary.TokenExpiry = duplicate.TokenExpiry
+				primary.UpdatedAt = now
+				if err := tx.Table(tableName).Where("id = ?", primary.ID).Updates(map[string]interface{}{
+					"o_auth_id":     primary.OAuthID,
+					"o_auth_type":   primary.OAuthType,
+					"access_token":  primary.AccessToken,
+					"refresh_token": primary.RefreshToken,
+					"token_expiry":  primary.TokenExpiry,
+					"updated_at":    primary.UpdatedAt,
+				}).Error; err != nil {
+					m.Log.For(ctx).Errorf("Failed to move OAuth identity: %v", redact.Error(err))
+					return errors.New("failed to merge users")
+				}
+				result.OAuthIdentity = true
+			}
+			// The duplicate lets go of the identity, so that a login through it
+			// finds the primary
+			duplicate.OAuthID, duplicate.OAuthType = "", ""
+			duplicate.AccessToken, duplicate.RefreshToken = "", ""
+			duplicate.TokenExpiry = time.Time{}
+		}
+
+		previous = duplicate.Status
+		if previous != schemas.UserStatusDeactivated {
+			duplicate.SetStatus(schemas.UserStatusDeactivated)
+			result.Deactivated = true
+		}
+		if moveOAuth || result.Deactivated {
+			duplicate.UpdatedAt = now
+			update := tx.Table(tableName).Where("id = ? AND status = ?", duplicate.ID, previous).Updates(map[string]interface{}{
+				"status":        duplicate.Status,
+				"active":        duplicate.Active,
+				"o_auth_id":     duplicate.OAuthID,
+				"o_auth_type":   duplicate.OAuthType,
+				"access_token":  duplicate.AccessToken,
+				"refresh_token": duplicate.RefreshToken,
+				"token_expiry":  duplicate.TokenExpiry,
+				"updated_at":    duplicate.UpdatedAt,
+			})
+			if update.Error != nil {
+				m.Log.For(ctx).Errorf("Failed to deactivate merged user: %v", redact.Error(update.Error))
+				return errors.New("failed to merge users")
+			}
+			if update.RowsAffected == 0 {
+				return apierrors.Conflict("user status was changed concurrently")
+			}
+		}
+		return nil
+	}
+	// The regional transaction commits just before the primary one
+	err = m.DB.Transaction(func(primaryTx *gorm.DB) error {
+		if db == m.DB {
+			return merge(primaryTx, primaryTx)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			return merge(primaryTx, tx)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Primary = toDisplayUser(primary)
+	if result.LoginEvents == 0 && result.Passkeys == 0 && !result.OAuthIdentity && !result.Deactivated {
+		return result, nil
+	}
+
+	if result.Deactivated {
+		m.Events.Publish(ctx, duplicate.ProjectId, webhooks.EventUserStatusChanged, toDisplayUser(duplicate))
+	}
+	if result.OAuthIdentity {
+		m.Events.Publish(ctx, primary.ProjectId, webhooks.EventUserUpdated, result.Primary)
+	}
+	details := fmt.Sprintf("merged into %s: %d login events, %d passkeys", primary.ID, result.LoginEvents, result.Passkeys)
+	if result.OAuthIdentity {
+		details += ", OAuth identity"
+	}
+	if result.Deactivated {
+		details += "; " + previous + " -> " + duplicate.Status
+	}
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &duplicate.ProjectId,
+		Action:       audit.ActionMerge,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   duplicate.ID.String(),
+		Details:      details,
+		At:           now,
+	})
+	return result, nil
+}
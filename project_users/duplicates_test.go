@@ -0,0 +1,273 @@
+package projectusers_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"gorm.io/gorm"
+)
+
+// update sets columns of a project user directly in its table
+func update(t *testing.T, db *gorm.DB, user schemas.ProjectUser, columns map[string]interface{}) {
+	t.Helper()
+	if err := db.Table(testutil.ProjectUserTable(user.ProjectId)).Where("id = ?", user.ID).Updates(columns).Error; err != nil {
+		t.Fatalf("failed to update user %s: %v", user.Email, err)
+	}
+}
+
+// projectUser reads a project user back from its table
+func projectUser(t *testing.T, db *gorm.DB, user schemas.ProjectUser) schemas.ProjectUser {
+	t.Helper()
+	var stored schemas.ProjectUser
+	if err := db.Table(testutil.ProjectUserTable(user.ProjectId)).First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// emails returns the sorted emails of a cluster's users
+func emails(cluster projectusers.DuplicateCluster) string {
+	var out []string
+	for _, u := range cluster.Users {
+		out = append(out, u.Email)
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestFindDuplicates(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").
+		WithUser("Jane.Doe+work@gmail.com").WithUser("janedoe@gmail.com").
+		WithOAuthUser("sam@example.com", "github").WithOAuthUser("samuel@example.org", "github").
+		WithUser("bob@corp.example").WithUser("robert@corp.example").WithUser("bob.smith@other.example").
+		WithUser("alone@example.com").
+		WithUser("gone@example.com").WithInactiveUser("Gone@Example.com").
+		Build(t, db)
+	users := built.Users
+	update(t, db, users["samuel@example.org"], map[string]interface{}{"o_auth_id": users["sam@example.com"].OAuthID})
+	for _, email := range []string{"bob@corp.example", "robert@corp.example", "bob.smith@other.example"} {
+		update(t, db, users[email], map[string]interface{}{"first_name": "Bob", "last_name": "Smith"})
+	}
+	update(t, db, users["robert@corp.example"], map[string]interface{}{"first_name": " BOB ", "last_name": "smith"})
+
+	clusters, err := newManager(db, nil, &recorder{}).FindDuplicates(context.Background(), built.Project.ID.String())
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+
+	want := []struct {
+		emails     string
+		confidence string
+		reasons    string
+	}{
+		{"Jane.Doe+work@gmail.com janedoe@gmail.com", projectusers.ConfidenceHigh, projectusers.MatchEmail},
+		{"sam@example.com samuel@example.org", projectusers.ConfidenceHigh, projectusers.MatchOAuthID},
+		{"bob@corp.example robert@corp.example", projectusers.ConfidenceMedium, projectusers.MatchNameAndDomain},
+	}
+	got := make(map[string]projectusers.DuplicateCluster)
+	for _, cluster := range clusters {
+		got[emails(cluster)] = cluster
+	}
+	if len(clusters) != len(want) {
+		t.Errorf("found %d clusters, want %d", len(clusters), len(want))
+	}
+	for _, w := range want {
+		cluster, ok := got[w.emails]
+		if !ok {
+			t.Errorf("no cluster of %s among %v", w.emails, clusters)
+			continue
+		}
+		if cluster.Confidence != w.confidence || strings.Join(cluster.Reasons, ",") != w.reasons {
+			t.Errorf("cluster of %s is %s by %v, want %s by %s", w.emails, cluster.Confidence, cluster.Reasons, w.confidence, w.reasons)
+		}
+	}
+	if clusters[len(clusters)-1].Confidence != projectusers.ConfidenceMedium {
+		t.Error("high confidence clusters are not listed first")
+	}
+}
+
+// mergeFixture is a project with a primary user and a duplicate that has
+// login events, a passkey and an OAuth identity, and another project whose
+// user has the same email
+type mergeFixture struct {
+	db        *gorm.DB
+	manager   projectusers.ProjectUserManager
+	events    *recorder
+	project   string
+	primary   schemas.ProjectUser
+	duplicate schemas.ProjectUser
+	stranger  schemas.ProjectUser
+}
+
+func newMergeFixture(t *testing.T) *mergeFixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("ann@example.com").WithOAuthUser("ann.lee@example.com", "google").Build(t, db)
+	other := testutil.AProject().WithRole("member").WithUser("ann@example.com").Build(t, db)
+	f := &mergeFixture{
+		db:        db,
+		events:    &recorder{},
+		project:   built.Project.ID.String(),
+		primary:   built.Users["ann@example.com"],
+		duplicate: built.Users["ann.lee@example.com"],
+		stranger:  other.Users["ann@example.com"],
+	}
+	f.manager = newManager(db, nil, f.events)
+	update(t, db, f.duplicate, map[string]interface{}{"access_token": "sealed-access", "refresh_token": "sealed-refresh"})
+
+	for i := 0; i < 3; i++ {
+		f.create(t, &schemas.LoginEvent{ID: uuid.New(), Method: "oauth", ProjectId: built.Project.ID, UserId: f.duplicate.ID, CreatedAt: time.Now()})
+	}
+	f.create(t, &schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: built.Project.ID, UserId: f.primary.ID, CreatedAt: time.Now()})
+	f.create(t, &schemas.WebAuthnCredential{ID: uuid.New(), CredentialID: "duplicate-key", PublicKey: []byte{1}, ProjectId: built.Project.ID, UserId: f.duplicate.ID})
+	// The other project's user must keep its own history
+	f.create(t, &schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: other.Project.ID, UserId: f.stranger.ID, CreatedAt: time.Now()})
+	return f
+}
+
+func (f *mergeFixture) create(t *testing.T, row interface{}) {
+	t.Helper()
+	if err := f.db.Create(row).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// owned counts the rows of model belonging to user
+func (f *mergeFixture) owned(t *testing.T, model interface{}, user schemas.ProjectUser) int64 {
+	t.Helper()
+	var n int64
+	if err := f.db.Model(model).Where("project_id = ? AND user_id = ?", user.ProjectId, user.ID).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func (f *mergeFixture) merges(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := f.db.Model(&schemas.AuditEvent{}).Where("action = ?", audit.ActionMerge).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMergeProjectUsers(t *testing.T) {
+	f := newMergeFixture(t)
+	ctx := context.Background()
+
+	result, err := f.manager.MergeProjectUsers(ctx, f.project, f.primary.ID, f.duplicate.ID)
+	if err != nil {
+		t.Fatalf("MergeProjectUsers: %v", err)
+	}
+	if result.LoginEvents != 3 || result.Passkeys != 1 || !result.OAuthIdentity || !result.Deactivated {
+		t.Errorf("result = %+v, want 3 login events, a passkey, the OAuth identity and a deactivation", result)
+	}
+
+	if n := f.owned(t, &schemas.LoginEvent{}, f.primary); n != 4 {
+		t.Errorf("the primary has %d login events, want its own and the duplicate's 3", n)
+	}
+	if n := f.owned(t, &schemas.LoginEvent{}, f.duplicate); n != 0 {
+		t.Errorf("the duplicate kept %d login events", n)
+	}
+	if n := f.owned(t, &schemas.WebAuthnCredential{}, f.primary); n != 1 {
+		t.Errorf("the primary has %d passkeys, want the duplicate's", n)
+	}
+	if n := f.owned(t, &schemas.LoginEvent{}, f.stranger); n != 1 {
+		t.Errorf("the other project's user has %d login events, want its own 1", n)
+	}
+
+	primary, duplicate := projectUser(t, f.db, f.primary), projectUser(t, f.db, f.duplicate)
+	if primary.OAuthID != f.duplicate.OAuthID || primary.OAuthType != "google" || primary.AccessToken != "sealed-access" || primary.RefreshToken != "sealed-refresh" {
+		t.Errorf("primary = %+v, want the duplicate's OAuth identity and tokens", primary)
+	}
+	if primary.Status != schemas.UserStatusActive {
+		t.Errorf("primary status = %s, want active", primary.Status)
+	}
+	if duplicate.Status != schemas.UserStatusDeactivated || duplicate.Active || duplicate.OAuthID != "" || duplicate.AccessToken != "" {
+		t.Errorf("duplicate = %+v, want deactivated without an OAuth identity", duplicate)
+	}
+	if f.merges(t) != 1 {
+		t.Errorf("%d merges were audited, want 1", f.merges(t))
+	}
+	if len(f.events.events) != 2 {
+		t.Errorf("published %v, want the status change and the primary's update", f.events.events)
+	}
+
+	// Merging again is a no-op
+	again, err := f.manager.MergeProjectUsers(ctx, f.project, f.primary.ID, f.duplicate.ID)
+	if err != nil {
+		t.Fatalf("merging again: %v", err)
+	}
+	if again.LoginEvents != 0 || again.Passkeys != 0 || again.OAuthIdentity || again.Deactivated {
+		t.Errorf("merging again = %+v, want nothing moved", again)
+	}
+	if f.merges(t) != 1 || len(f.events.events) != 2 {
+		t.Errorf("merging again audited or published: %d merges, events %v", f.merges(t), f.events.events)
+	}
+	if after := projectUser(t, f.db, f.primary); after.OAuthID != primary.OAuthID || after.Status != primary.Status {
+		t.Errorf("merging again changed the primary: %+v", after)
+	}
+}
+
+func TestMergeProjectUsersRefuses(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		prepare func(t *testing.T, f *mergeFixture) (primary, duplicate uuid.UUID)
+		code    string
+	}{
+		{"the same user", func(t *testing.T, f *mergeFixture) (uuid.UUID, uuid.UUID) {
+			return f.duplicate.ID, f.duplicate.ID
+		}, "SAME_USER"},
+		{"a user of another project as duplicate", func(t *testing.T, f *mergeFixture) (uuid.UUID, uuid.UUID) {
+			return f.primary.ID, f.stranger.ID
+		}, "NOT_FOUND"},
+		{"a user of another project as primary", func(t *testing.T, f *mergeFixture) (uuid.UUID, uuid.UUID) {
+			return f.stranger.ID, f.duplicate.ID
+		}, "NOT_FOUND"},
+		{"a deactivated primary", func(t *testing.T, f *mergeFixture) (uuid.UUID, uuid.UUID) {
+			update(t, f.db, f.primary, map[string]interface{}{"status": schemas.UserStatusDeactivated, "active": false})
+			return f.primary.ID, f.duplicate.ID
+		}, "CONFLICT"},
+		{"two OAuth identities", func(t *testing.T, f *mergeFixture) (uuid.UUID, uuid.UUID) {
+			update(t, f.db, f.primary, map[string]interface{}{"o_auth_type": "github", "o_auth_id": "12345"})
+			return f.primary.ID, f.duplicate.ID
+		}, projectusers.ErrOAuthIdentityConflict.Code},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMergeFixture(t)
+			primary, duplicate := tc.prepare(t, f)
+
+			_, err := f.manager.MergeProjectUsers(ctx, f.project, primary, duplicate)
+			var apiErr *apierrors.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != tc.code {
+				t.Fatalf("err = %v, want %s", err, tc.code)
+			}
+			// Nothing moved, including what a failed transaction had begun
+			if n := f.owned(t, &schemas.LoginEvent{}, f.duplicate); n != 3 {
+				t.Errorf("the duplicate has %d login events, want its 3", n)
+			}
+			if n := f.owned(t, &schemas.WebAuthnCredential{}, f.duplicate); n != 1 {
+				t.Errorf("the duplicate has %d passkeys, want its 1", n)
+			}
+			if stored := projectUser(t, f.db, f.duplicate); stored.Status != schemas.UserStatusActive || stored.OAuthID != f.duplicate.OAuthID {
+				t.Errorf("duplicate = %+v, want it unchanged", stored)
+			}
+			if f.merges(t) != 0 || len(f.events.events) != 0 {
+				t.Errorf("a refused merge audited %d merges and published %v", f.merges(t), f.events.events)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package projectusers
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many users are read per query while exporting
+const exportBatchSize = 500
+
+// ExportColumns is the header row of a users export
+var ExportColumns = []string{
+	"id", "email", "first_name", "last_name", "status", "email_verified",
+	"role_id", "oauth_type", "created_at", "updated_at", "deleted_at",
+}
+
+// ExportUsers writes every user of the project, soft-deleted ones included,
+// to w as CSV and returns how many were written. Passwords and OAuth tokens
+// are never exported. db may be a transaction, so the export sees the same
+// rows as the rest of it.
+func ExportUsers(db *gorm.DB, projectID uuid.UUID, w io.Writer) (int64, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write(ExportColumns); err != nil {
+		return 0, err
+	}
+
+	var written int64
+	var batch []schemas.ProjectUser
+	err := db.Table(projecttable.Users(projectID)).
+		Unscoped().
+		Omit("password", "password_history", "access_token", "refresh_token").
+		Order("id").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, user := range batch {
+				if err := out.Write(exportRow(user)); err != nil {
+					return err
+				}
+				written++
+			}
+			out.Flush()
+			return out.Error()
+		}).Error
+	if err != nil {
+		return written, err
+	}
+
+	out.Flush()
+	return written, out.Error()
+}
+
+func exportRow(user schemas.ProjectUser) []string {
+	deletedAt := ""
+	if user.DeletedAt.Valid {
+		deletedAt = user.DeletedAt.Time.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		user.ID.String(),
+		user.Email,
+		user.FirstName,
+		user.LastName,
+		user.Status,
+		strconv.FormatBool(user.EmailVerified),
+		user.RoleId.String(),
+		user.OAuthType,
+		user.CreatedAt.UTC().Format(time.RFC3339),
+		user.UpdatedAt.UTC().Format(time.RFC3339),
+		deletedAt,
+	}
+}
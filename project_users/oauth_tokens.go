@@ -0,0 +1,86 @@
+package projectusers
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// ErrNoOAuthToken is returned for a user without stored OAuth tokens
+var ErrNoOAuthToken = apierrors.NotFound("no OAuth token is stored for this user")
+
+// sealOAuthTokens stores the tokens the provider granted for a login in
+// user, encrypted under the primary token key. Nothing is stored without
+// token keys, or when a token would not fit its column once encrypted. A
+// login without a refresh token keeps the one stored before, since
+// providers only grant one on the first consent.
+func (m *ProjectUserManagerImpl) sealOAuthTokens(ctx context.Context, user *schemas.ProjectUser, userInfo *oauth.UserInfo) {
+	if m.TokenKeys == nil || userInfo.Token == nil {
+		return
+	}
+	access, err := m.TokenKeys.Encrypt(userInfo.Token.AccessToken)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to encrypt the %s access token of user %s: %v", userInfo.Provider, user.ID, err)
+		return
+	}
+	refresh, err := m.TokenKeys.Encrypt(userInfo.Token.RefreshToken)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to encrypt the %s refresh token of user %s: %v", userInfo.Provider, user.ID, err)
+		return
+	}
+	if utf8.RuneCountInString(access) > oauth.MaxTokenLength || utf8.RuneCountInString(refresh) > oauth.MaxTokenLength {
+		m.Log.For(ctx).Warningf("The %s tokens of user %s are too long to store once encrypted; they are not stored", userInfo.Provider, user.ID)
+		return
+	}
+	user.AccessToken = access
+	if refresh != "" {
+		user.RefreshToken = refresh
+	}
+}
+
+// GetOAuthToken returns the OAuth tokens stored for a project user by their
+// last login, decrypted. Tokens encrypted under a retired key decrypt as
+// long as that key is configured.
+func (m *ProjectUserManagerImpl) GetOAuthToken(ctx context.Context, projectID string, userID uuid.UUID) (*oauth2.Token, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user schemas.ProjectUser
+	if err := m.usersDB(project).Table(tableName).Select("id", "access_token", "refresh_token").
+		Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if user.AccessToken == "" && user.RefreshToken == "" {
+		return nil, ErrNoOAuthToken
+	}
+	if m.TokenKeys == nil {
+		m.Log.For(ctx).Errorf("User %s has stored OAuth tokens, but no token keys are configured", userID)
+		return nil, errors.New("OAuth token keys are not configured")
+	}
+
+	access, err := m.TokenKeys.Decrypt(user.AccessToken)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to decrypt the access token of user %s: %v", userID, err)
+		return nil, errors.New("failed to decrypt OAuth token")
+	}
+	refresh, err := m.TokenKeys.Decrypt(user.RefreshToken)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to decrypt the refresh token of user %s: %v", userID, err)
+		return nil, errors.New("failed to decrypt OAuth token")
+	}
+	return &oauth2.Token{AccessToken: access, RefreshToken: refresh}, nil
+}
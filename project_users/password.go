@@ -0,0 +1,178 @@
+package projectusers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCredentials is returned for an unknown email or a wrong password
+var ErrInvalidCredentials = apierrors.New(http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid email or password")
+
+// ErrPasswordReused is returned by ChangeProjectUserPassword for a new
+// password that is one of the user's latest ones
+var ErrPasswordReused = password.ErrReused
+
+// PasswordLogin is a project user signed in with their password
+type PasswordLogin struct {
+	Token     string
+	ExpiresAt time.Time
+	User      models.DisplayUser
+	// PasswordExpired is set when the password is past its maximum age. The
+	// token is then restricted to auth.ScopePasswordChange.
+	PasswordExpired bool
+	// PasswordExpiresAt is when the password expired or will expire; zero
+	// when the project's passwords do not expire
+	PasswordExpiresAt time.Time
+}
+
+// LoginProjectUser checks a project user's email and password and issues a
+// token. A password past the maximum age of the project, or else of the
+// passwords configuration, still signs the user in, but only into a token
+// restricted to changing it.
+func (m *ProjectUserManagerImpl) LoginProjectUser(ctx context.Context, projectID, email, plain string) (*PasswordLogin, error) {
+	project, user, err := m.checkPassword(ctx, projectID, email, plain)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.Clock.Now()
+	login := &PasswordLogin{PasswordExpiresAt: m.passwordExpiry(project, user)}
+	login.PasswordExpired = !login.PasswordExpiresAt.IsZero() && !now.Before(login.PasswordExpiresAt)
+	issue := auth.IssueProjectUserToken
+	if login.PasswordExpired {
+		issue = auth.IssueProjectUserPasswordChangeToken
+	}
+	login.Token, login.ExpiresAt, err = issue(ctx, m.DB, *user, now)
+	if err != nil {
+		return nil, err
+	}
+	login.User = toDisplayUser(*user)
+	return login, nil
+}
+
+// ChangeProjectUserPassword replaces a project user's password, expired or
+// not, once the current one is given. The new password must not be one of
+// the user's password history size latest ones, the current one included.
+func (m *ProjectUserManagerImpl) ChangeProjectUserPassword(ctx context.Context, projectID, email, currentPassword, newPassword string) error {
+	project, user, err := m.checkPassword(ctx, projectID, email, currentPassword)
+	if err != nil {
+		return err
+	}
+	if err := m.Passwords.Check(newPassword); err != nil {
+		return err
+	}
+
+	size := m.Passwords.HistorySize()
+	history := append([]string{user.Password}, user.PasswordHistory...)
+	if len(history) > size {
+		history = history[:size]
+	}
+	if m.Passwords.Reused(newPassword, history...) {
+		return ErrPasswordReused
+	}
+
+	hashedPassword, err := m.Passwords.Hash(newPassword)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to hash password: %v", redact.Error(err))
+		return errors.New("failed to process password")
+	}
+
+	// The replaced password leads the history, which keeps those the next
+	// change is checked against besides the then current password
+	if len(history) >= size && size > 0 {
+		history = history[:size-1]
+	}
+	now := m.Clock.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	user.PasswordHistory = history
+	user.UpdatedAt = now
+	if err := m.usersDB(project).Table(projecttable.Users(project.ID)).Where("id = ?", user.ID).
+		Select("password", "password_changed_at", "password_history", "updated_at").Updates(user).Error; err != nil {
+		m.Log.For(ctx).Errorf("Failed to update password: %v", redact.Error(err))
+		return errors.New("failed to update password")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		Details:      "changed password",
+		At:           now,
+	})
+	return nil
+}
+
+// checkPassword finds the live project user with email and checks that they
+// may sign in with the plain password. An unknown email and a wrong password
+// are both ErrInvalidCredentials, so that neither tells which emails exist.
+func (m *ProjectUserManagerImpl) checkPassword(ctx context.Context, projectID, email, plain string) (*schemas.Project, *schemas.ProjectUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	db := m.usersDB(project)
+
+	var user schemas.ProjectUser
+	if err := usersTable(db, tableName, false).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidCredentials
+		}
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, nil, errors.New("internal server error")
+	}
+
+	// Users signed up through OAuth have no password, which nothing verifies
+	ok, rehash := m.Passwords.Verify(user.Password, plain)
+	if !ok {
+		return nil, nil, ErrInvalidCredentials
+	}
+	if !userstatus.CanLogin(user.Status) {
+		return nil, nil, userstatus.LoginError(user.Status)
+	}
+	if rehash {
+		m.upgradePassword(ctx, db, tableName, &user, plain)
+	}
+	return project, &user, nil
+}
+
+// upgradePassword replaces the user's stored hash with one made by the
+// configured algorithm. The password was verified, so failures are only
+// logged and the old hash stays usable.
+func (m *ProjectUserManagerImpl) upgradePassword(ctx context.Context, db *gorm.DB, tableName string, user *schemas.ProjectUser, plain string) {
+	hash, err := m.Passwords.Hash(plain)
+	if err != nil {
+		m.Log.For(ctx).Errorf("Failed to re-hash password: %v", redact.Error(err))
+		return
+	}
+	if err := db.Table(tableName).Where("id = ?", user.ID).UpdateColumn("password", hash).Error; err != nil {
+		m.Log.For(ctx).Errorf("Failed to upgrade password hash: %v", redact.Error(err))
+		return
+	}
+	user.Password = hash
+}
+
+// passwordExpiry returns when a project user's password expires: the
+// project's maximum age, or else the configured one, after it was last set.
+// The zero time means it never expires.
+func (m *ProjectUserManagerImpl) passwordExpiry(project *schemas.Project, user *schemas.ProjectUser) time.Time {
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return m.Passwords.Expiry(changedAt, project.Settings.Passwords.MaxAgeDays)
+}
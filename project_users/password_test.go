@@ -0,0 +1,195 @@
+package projectusers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+// newPasswordManager returns a project user manager on db with a fake clock
+// set to start, hashing with the cheapest bcrypt cost under cfg
+func newPasswordManager(t *testing.T, db *gorm.DB, cfg password.Config, start time.Time) (*projectusers.ProjectUserManagerImpl, *testutil.FakeClock) {
+	t.Helper()
+
+	cfg.BcryptCost = 4
+	passwords, err := password.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create the hasher: %v", err)
+	}
+	manager := projectusers.NewManager(db, nil, projects.NewManager(db, projects.Options{}), nil, passwords, nil, nil).(*projectusers.ProjectUserManagerImpl)
+	clock := testutil.NewFakeClock(start)
+	manager.Clock = clock
+	return manager, clock
+}
+
+func TestProjectUserPasswordExpires(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	// Tokens are checked against the real time, so the clock starts in the
+	// past and reaches about now when the password expires
+	start := time.Now().AddDate(0, 0, -31).Truncate(time.Second)
+	manager, clock := newPasswordManager(t, db, password.Config{MaxAgeDays: 30}, start)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	if _, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "first password", "", "", built.Roles["Member"].ID); err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	login, err := manager.LoginProjectUser(ctx, projectID, "a@example.com", "first password")
+	if err != nil {
+		t.Fatalf("LoginProjectUser: %v", err)
+	}
+	if login.PasswordExpired || !login.PasswordExpiresAt.Equal(start.AddDate(0, 0, 30)) {
+		t.Fatalf("login with a fresh password = %+v, want it to expire on %v", login, start.AddDate(0, 0, 30))
+	}
+
+	clock.Advance(31 * 24 * time.Hour)
+	login, err = manager.LoginProjectUser(ctx, projectID, "a@example.com", "first password")
+	if err != nil {
+		t.Fatalf("LoginProjectUser: %v", err)
+	}
+	if !login.PasswordExpired {
+		t.Fatalf("login with an expired password = %+v", login)
+	}
+	claims, err := auth.ParseToken(login.Token)
+	if err != nil {
+		t.Fatalf("the issued token does not parse: %v", err)
+	}
+	if claims.Scope != auth.ScopePasswordChange || login.ExpiresAt.After(clock.Now().Add(auth.PasswordChangeTokenTTL)) {
+		t.Fatalf("claims = %+v, want a short token scoped to changing the password", claims)
+	}
+
+	if err := manager.ChangeProjectUserPassword(ctx, projectID, "a@example.com", "first password", "second password"); err != nil {
+		t.Fatalf("ChangeProjectUserPassword: %v", err)
+	}
+	login, err = manager.LoginProjectUser(ctx, projectID, "a@example.com", "second password")
+	if err != nil {
+		t.Fatalf("LoginProjectUser: %v", err)
+	}
+	if login.PasswordExpired || !login.PasswordExpiresAt.Equal(clock.Now().AddDate(0, 0, 30)) {
+		t.Fatalf("login after the change = %+v, want it to expire on %v", login, clock.Now().AddDate(0, 0, 30))
+	}
+	if claims, err := auth.ParseToken(login.Token); err != nil || claims.Scope != "" {
+		t.Fatalf("claims = %+v, %v, want an unrestricted token", claims, err)
+	}
+}
+
+func TestProjectSettingsOverrideThePasswordMaxAge(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	start := time.Now().AddDate(0, 0, -31).Truncate(time.Second)
+	manager, clock := newPasswordManager(t, db, password.Config{MaxAgeDays: 30}, start)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name       string
+		maxAgeDays int
+		expired    bool
+	}{
+		{"no expiry", 0, false},
+		{"a longer age", 60, false},
+		{"a shorter age", 10, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(start)
+			built := testutil.AProject().WithRole("Member").Build(t, db)
+			project := built.Project
+			project.Settings.Passwords.MaxAgeDays = &tt.maxAgeDays
+			if err := db.Save(&project).Error; err != nil {
+				t.Fatal(err)
+			}
+			projectID := project.ID.String()
+			if _, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "first password", "", "", built.Roles["Member"].ID); err != nil {
+				t.Fatalf("CreateProjectUser: %v", err)
+			}
+
+			clock.Advance(31 * 24 * time.Hour)
+			login, err := manager.LoginProjectUser(ctx, projectID, "a@example.com", "first password")
+			if err != nil {
+				t.Fatalf("LoginProjectUser: %v", err)
+			}
+			if login.PasswordExpired != tt.expired {
+				t.Errorf("PasswordExpired = %v, want %v", login.PasswordExpired, tt.expired)
+			}
+			if tt.maxAgeDays == 0 && !login.PasswordExpiresAt.IsZero() {
+				t.Errorf("PasswordExpiresAt = %v, want none", login.PasswordExpiresAt)
+			}
+		})
+	}
+}
+
+func TestChangeProjectUserPassword(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, clock := newPasswordManager(t, db, password.Config{MinLength: 8, HistorySize: 3}, time.Now())
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	created, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "password 0", "", "", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	current := "password 0"
+	change := func(newPassword string) error {
+		t.Helper()
+		clock.Advance(time.Minute)
+		err := manager.ChangeProjectUserPassword(ctx, projectID, "a@example.com", current, newPassword)
+		if err == nil {
+			current = newPassword
+		}
+		return err
+	}
+
+	if err := manager.ChangeProjectUserPassword(ctx, projectID, "a@example.com", "wrong password", "password 1"); !errors.Is(err, projectusers.ErrInvalidCredentials) {
+		t.Fatalf("a wrong current password: err = %v, want %v", err, projectusers.ErrInvalidCredentials)
+	}
+	if err := manager.ChangeProjectUserPassword(ctx, projectID, "b@example.com", "password 0", "password 1"); !errors.Is(err, projectusers.ErrInvalidCredentials) {
+		t.Fatalf("an unknown email: err = %v, want %v", err, projectusers.ErrInvalidCredentials)
+	}
+	if err := change("short"); !errors.Is(err, password.ErrTooShort) {
+		t.Fatalf("a short password: err = %v, want %v", err, password.ErrTooShort)
+	}
+	if err := change("password 0"); !errors.Is(err, projectusers.ErrPasswordReused) {
+		t.Fatalf("the current password: err = %v, want %v", err, projectusers.ErrPasswordReused)
+	}
+
+	// With a history of 3, each password comes back after two others
+	for _, next := range []string{"password 1", "password 2"} {
+		if err := change(next); err != nil {
+			t.Fatalf("changing to %q: %v", next, err)
+		}
+	}
+	for _, recent := range []string{"password 0", "password 1"} {
+		if err := change(recent); !errors.Is(err, projectusers.ErrPasswordReused) {
+			t.Fatalf("changing back to %q: err = %v, want %v", recent, err, projectusers.ErrPasswordReused)
+		}
+	}
+	if err := change("password 3"); err != nil {
+		t.Fatalf("changing to password 3: %v", err)
+	}
+	if err := change("password 0"); err != nil {
+		t.Fatalf("changing back to the password from three changes ago: %v", err)
+	}
+
+	var stored schemas.ProjectUser
+	if err := db.Table(testutil.ProjectUserTable(built.Project.ID)).First(&stored, "id = ?", created.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.PasswordHistory) != 2 {
+		t.Errorf("history keeps %d hashes, want the 2 the next change is checked against", len(stored.PasswordHistory))
+	}
+	if stored.PasswordChangedAt == nil || !stored.PasswordChangedAt.Equal(clock.Now()) {
+		t.Errorf("PasswordChangedAt = %v, want %v", stored.PasswordChangedAt, clock.Now())
+	}
+	if _, err := manager.LoginProjectUser(ctx, projectID, "a@example.com", "password 0"); err != nil {
+		t.Errorf("logging in with the new password: %v", err)
+	}
+}
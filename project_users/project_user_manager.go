@@ -4,62 +4,197 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
 	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
 	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/tokenkeys"
+	"github.com/yash3004/user_management_service/webhooks"
+	"golang.org/x/oauth2"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
 // ProjectUserManager defines the interface for project-specific user management operations
 type ProjectUserManager interface {
 	CreateProjectUser(ctx context.Context, projectID string, email, password, firstName, lastName string, roleID uuid.UUID) (*models.DisplayUser, error)
-	GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error)
-	GetProjectUserByEmail(ctx context.Context, projectID string, email string) (*models.DisplayUser, error)
-	ListProjectUsers(ctx context.Context, projectID string) ([]models.DisplayUser, error)
+	GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID, includeDeleted bool) (*models.DisplayUser, error)
+	GetProjectUserByEmail(ctx context.Context, projectID string, email string, includeDeleted bool) (*models.DisplayUser, error)
+	ListProjectUsers(ctx context.Context, projectID string, includeDeleted bool, status string, order sorting.Order) ([]models.DisplayUser, error)
+	ListDeletedProjectUsers(ctx context.Context, projectID string, page, pageSize int) (*DeletedUsersPage, error)
 	UpdateProjectUser(ctx context.Context, projectID string, userID uuid.UUID, firstName, lastName string, active bool) (*models.DisplayUser, error)
 	DeleteProjectUser(ctx context.Context, projectID string, userID uuid.UUID) error
+	RestoreProjectUser(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error)
+	SetProjectUserStatus(ctx context.Context, projectID string, userID uuid.UUID, status string) (*models.DisplayUser, error)
+	BulkSetProjectUsersActive(ctx context.Context, projectID string, userIDs []uuid.UUID, active bool) (int64, error)
 	CreateOrUpdateOAuthProjectUser(ctx context.Context, projectID string, userInfo *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error)
+	GetOAuthToken(ctx context.Context, projectID string, userID uuid.UUID) (*oauth2.Token, error)
 	GenerateToken(ctx context.Context, projectID string, userID uuid.UUID) (string, time.Time, error)
+	FindDuplicates(ctx context.Context, projectID string) ([]DuplicateCluster, error)
+	MergeProjectUsers(ctx context.Context, projectID string, primaryID, duplicateID uuid.UUID) (*MergeResult, error)
+	RefreshProjectUserTokens(ctx context.Context, projectID string, roleID uuid.UUID, issue bool) (*TokenRefresh, error)
+	LoginProjectUser(ctx context.Context, projectID, email, password string) (*PasswordLogin, error)
+	ChangeProjectUserPassword(ctx context.Context, projectID, email, currentPassword, newPassword string) error
+}
+
+// ErrUserNotFound is returned for a user that is not in the project
+var ErrUserNotFound = apierrors.NotFound("user not found in this project")
+
+// ErrEmailTaken is returned when creating a user with the email of another
+// live user of the project
+var ErrEmailTaken = apierrors.Conflict("user with this email already exists in this project")
+
+// ErrRestoreEmailTaken is returned when restoring a user whose email has been
+// taken by another user since it was deleted
+var ErrRestoreEmailTaken = apierrors.Conflict("another user with this email already exists in this project")
+
+// ProjectLookup finds projects. The projects manager implements it; it is
+// declared here because the projects package itself depends on this one.
+type ProjectLookup interface {
+	GetProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
 }
 
 // ProjectUserManagerImpl implements the ProjectUserManager interface
 type ProjectUserManagerImpl struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Regions   *regions.Resolver // Routes the users tables; nil keeps them in DB
+	Projects  ProjectLookup
+	Clock     clock.Clock
+	Events    webhooks.Publisher
+	Passwords *password.Hasher
+	TokenKeys *tokenkeys.KeyRing // Encrypts the stored OAuth tokens; nil stores none
+	Log       logging.Logger
 }
 
-func NewManager(db *gorm.DB) ProjectUserManager {
+// NewManager creates a new project user manager. The users tables are in the
+// databases regions routes the projects' regions to; it may be nil to keep
+// them all in db. Events may be nil when no webhooks should be published,
+// passwords nil to hash with bcrypt, tokenKeys nil to store no OAuth tokens
+// and log nil to log as the "projectusers" subsystem.
+func NewManager(db *gorm.DB, regions *regions.Resolver, projects ProjectLookup, events webhooks.Publisher, passwords *password.Hasher, tokenKeys *tokenkeys.KeyRing, log logging.Logger) ProjectUserManager {
+	if events == nil {
+		events = webhooks.NopPublisher{}
+	}
+	if passwords == nil {
+		passwords = password.Default()
+	}
+	if log == nil {
+		log = logging.Named("projectusers")
+	}
 	return &ProjectUserManagerImpl{
-		DB: db,
+		DB:        db,
+		Regions:   regions,
+		Projects:  projects,
+		Clock:     clock.Real{},
+		Events:    events,
+		Passwords: passwords,
+		TokenKeys: tokenKeys,
+		Log:       log,
 	}
 }
 
-// getProjectUserTableName returns the table name for a specific project
-func getProjectUserTableName(projectID string) string {
-	return fmt.Sprintf("project_%s_users", projectID)
+// toDisplayUser converts a project user into its public representation
+func toDisplayUser(u schemas.ProjectUser) models.DisplayUser {
+	var deletedAt *time.Time
+	var deletedBy string
+	if u.DeletedAt.Valid {
+		deletedAt = &u.DeletedAt.Time
+		deletedBy = audit.SystemActor
+		if u.DeletedBy != nil {
+			deletedBy = u.DeletedBy.String()
+		}
+	}
+	return models.DisplayUser{
+		ID:            u.ID.String(),
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Active:        u.Active,
+		Status:        u.Status,
+		Transitions:   userstatus.Next(u.Status),
+		RoleID:        u.RoleId.String(),
+		ProjectID:     u.ProjectId.String(),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		DeletedAt:     deletedAt,
+		DeletedBy:     deletedBy,
+	}
+}
+
+// projectUsersTable checks that the project exists and returns it with the
+// name of its users table. Every method goes through it before touching the
+// table, so a missing project is a 404 rather than a missing-table error.
+func (m *ProjectUserManagerImpl) projectUsersTable(ctx context.Context, projectID string) (*schemas.Project, string, error) {
+	id, err := uuid.Parse(strings.TrimSpace(projectID))
+	if err != nil {
+		return nil, "", projecttable.ErrInvalidProjectID
+	}
+	project, err := m.Projects.GetProject(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return project, projecttable.Users(project.ID), nil
+}
+
+// usersDB returns the database holding the users table of project: that of
+// its region, or DB. Audit events, login events and everything else of the
+// project stay in DB.
+func (m *ProjectUserManagerImpl) usersDB(project *schemas.Project) *gorm.DB {
+	if db := m.Regions.DB(project.Region); db != nil {
+		return db
+	}
+	return m.DB
+}
+
+// usersTable queries a project user table in db. Soft-deleted users are
+// excluded unless includeDeleted is set.
+func usersTable(db *gorm.DB, tableName string, includeDeleted bool) *gorm.DB {
+	if includeDeleted {
+		return db.Table(tableName).Unscoped()
+	}
+	return db.Table(tableName)
 }
 
 // CreateProjectUser creates a new user in a project-specific user table
 func (m *ProjectUserManagerImpl) CreateProjectUser(ctx context.Context, projectID string, email, password, firstName, lastName string, roleID uuid.UUID) (*models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
 
-	// Check if user with the same email already exists
+	// Check if user with the same email already exists. A soft-deleted user
+	// with the email is recreated in place rather than duplicated.
 	var existingUser schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("email = ?", email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user with this email already exists in this project")
+	deleted := false
+	if err := usersTable(db, tableName, true).Where("email = ?", email).First(&existingUser).Error; err == nil {
+		if !existingUser.DeletedAt.Valid {
+			return nil, ErrEmailTaken
+		}
+		deleted = true
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := m.Passwords.Hash(password)
 	if err != nil {
-		klog.Errorf("Failed to hash password: %v", err)
+		m.Log.For(ctx).Errorf("Failed to hash password: %v", redact.Error(err))
 		return nil, errors.New("failed to process password")
 	}
 
@@ -69,210 +204,563 @@ func (m *ProjectUserManagerImpl) CreateProjectUser(ctx context.Context, projectI
 		return nil, errors.New("invalid project ID format")
 	}
 
+	if deleted {
+		return m.recreateProjectUser(ctx, db, tableName, existingUser, hashedPassword, firstName, lastName, roleID)
+	}
+
 	// Create new user
+	now := m.Clock.Now()
 	user := schemas.ProjectUser{
-		ID:          uuid.New(),
-		Email:       email,
-		Password:    string(hashedPassword),
-		FirstName:   firstName,
-		LastName:    lastName,
-		Active:      true,
-		RoleId:      roleID,
-		ProjectId:   projectUUID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		TokenExpiry: time.Now().Add(24 * time.Hour), // Set token expiry to 24 hours
-	}
-
-	if err := m.DB.Table(tableName).Create(&user).Error; err != nil {
-		klog.Errorf("Failed to create user: %v", err)
+		ID:                uuid.New(),
+		Email:             email,
+		Password:          hashedPassword,
+		PasswordChangedAt: &now,
+		FirstName:         firstName,
+		LastName:          lastName,
+		Status:            schemas.UserStatusActive,
+		Active:            true,
+		RoleId:            roleID,
+		ProjectId:         projectUUID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		TokenExpiry:       now.Add(24 * time.Hour), // Set token expiry to 24 hours
+	}
+
+	if err := db.Table(tableName).Create(&user).Error; err != nil {
+		// Another user took the email since it was checked
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		m.Log.For(ctx).Errorf("Failed to create user: %v", redact.Error(err))
 		return nil, errors.New("failed to create user")
 	}
 
-	return &models.DisplayUser{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Active:    user.Active,
-		RoleID:    user.RoleId.String(),
-		ProjectID: user.ProjectId.String(),
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserCreated, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
+}
+
+// recreateProjectUser brings back a soft-deleted user whose email is being
+// signed up again, replacing its credentials and profile
+func (m *ProjectUserManagerImpl) recreateProjectUser(ctx context.Context, db *gorm.DB, tableName string, user schemas.ProjectUser, hashedPassword, firstName, lastName string, roleID uuid.UUID) (*models.DisplayUser, error) {
+	now := m.Clock.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	user.PasswordHistory = nil
+	user.FirstName = firstName
+	user.LastName = lastName
+	user.SetStatus(schemas.UserStatusActive)
+	user.EmailVerified = false
+	user.RoleId = roleID
+	user.OAuthID = ""
+	user.OAuthType = ""
+	user.AccessToken = ""
+	user.RefreshToken = ""
+	user.UpdatedAt = now
+	user.TokenExpiry = now.Add(24 * time.Hour)
+	user.DeletedAt = gorm.DeletedAt{}
+	user.DeletedBy = nil
+
+	if err := db.Table(tableName).Unscoped().Save(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		m.Log.For(ctx).Errorf("Failed to recreate user: %v", redact.Error(err))
+		return nil, errors.New("failed to create user")
+	}
+
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserCreated, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		Details:      "recreated soft-deleted user",
+		At:           m.Clock.Now(),
+	})
+
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
 }
 
 // GetProjectUser gets a user from a project-specific user table by ID
-func (m *ProjectUserManagerImpl) GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+func (m *ProjectUserManagerImpl) GetProjectUser(ctx context.Context, projectID string, userID uuid.UUID, includeDeleted bool) (*models.DisplayUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
 
 	var user schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := usersTable(db, tableName, includeDeleted).Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found in this project")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
-	return &models.DisplayUser{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Active:    user.Active,
-		RoleID:    user.RoleId.String(),
-		ProjectID: user.ProjectId.String(),
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
 }
 
 // GetProjectUserByEmail gets a user from a project-specific user table by email
-func (m *ProjectUserManagerImpl) GetProjectUserByEmail(ctx context.Context, projectID string, email string) (*models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+func (m *ProjectUserManagerImpl) GetProjectUserByEmail(ctx context.Context, projectID string, email string, includeDeleted bool) (*models.DisplayUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
 
 	var user schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := usersTable(db, tableName, includeDeleted).Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found in this project")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
-	return &models.DisplayUser{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Active:    user.Active,
-		RoleID:    user.RoleId.String(),
-		ProjectID: user.ProjectId.String(),
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
 }
 
-// ListProjectUsers lists all users in a project-specific user table
-func (m *ProjectUserManagerImpl) ListProjectUsers(ctx context.Context, projectID string) ([]models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+// SortFields are the fields ListProjectUsers can sort by
+var SortFields = []string{"email", "first_name", "last_name", "status", "created_at", "updated_at"}
+
+// ListProjectUsers lists all users in a project-specific user table in
+// order, only those in status if it is set
+func (m *ProjectUserManagerImpl) ListProjectUsers(ctx context.Context, projectID string, includeDeleted bool, status string, order sorting.Order) ([]models.DisplayUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	query := usersTable(db, tableName, includeDeleted)
+	if status != "" {
+		if !userstatus.Valid(status) {
+			return nil, userstatus.ErrUnknownStatus
+		}
+		query = query.Where("status = ?", status)
+	}
 
 	var projectUsers []schemas.ProjectUser
-	if err := m.DB.Table(tableName).Find(&projectUsers).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+	if err := order.Apply(query).Find(&projectUsers).Error; err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
 	users := make([]models.DisplayUser, len(projectUsers))
 	for i, u := range projectUsers {
-		users[i] = models.DisplayUser{
-			ID:        u.ID.String(),
-			Email:     u.Email,
-			FirstName: u.FirstName,
-			LastName:  u.LastName,
-			Active:    u.Active,
-			RoleID:    u.RoleId.String(),
-			ProjectID: u.ProjectId.String(),
-			CreatedAt: u.CreatedAt,
-			UpdatedAt: u.UpdatedAt,
-		}
+		users[i] = toDisplayUser(u)
 	}
 
 	return users, nil
 }
 
+// Pagination defaults for the list of deleted users
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// DeletedUsersPage is one page of a project's soft-deleted users, most
+// recently deleted first
+type DeletedUsersPage struct {
+	Users    []models.DisplayUser
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// ListDeletedProjectUsers lists the soft-deleted users of a project, which
+// RestoreProjectUser can bring back. Page is 1-based and the page size is
+// capped at MaxPageSize.
+func (m *ProjectUserManagerImpl) ListDeletedProjectUsers(ctx context.Context, projectID string, page, pageSize int) (*DeletedUsersPage, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	query := db.Table(tableName).Unscoped().Where("deleted_at IS NOT NULL")
+	result := &DeletedUsersPage{Page: page, PageSize: pageSize}
+	if err := query.Count(&result.Total).Error; err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	var deleted []schemas.ProjectUser
+	if err := query.Order("deleted_at DESC").Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&deleted).Error; err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	result.Users = make([]models.DisplayUser, len(deleted))
+	for i, u := range deleted {
+		result.Users[i] = toDisplayUser(u)
+	}
+	return result, nil
+}
+
 // UpdateProjectUser updates a user in a project-specific user table
 func (m *ProjectUserManagerImpl) UpdateProjectUser(ctx context.Context, projectID string, userID uuid.UUID, firstName, lastName string, active bool) (*models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
 
 	var user schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found in this project")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
+	// The legacy active flag moves the user between active and deactivated
+	if status := userstatus.ApplyActive(user.Status, active); status != user.Status {
+		if err := userstatus.Check(user.Status, status); err != nil {
+			return nil, err
+		}
+		user.SetStatus(status)
+	}
+
 	// Update user fields
 	user.FirstName = firstName
 	user.LastName = lastName
-	user.Active = active
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = m.Clock.Now()
 
-	if err := m.DB.Table(tableName).Save(&user).Error; err != nil {
-		klog.Errorf("Failed to update user: %v", err)
+	if err := db.Table(tableName).Save(&user).Error; err != nil {
+		m.Log.For(ctx).Errorf("Failed to update user: %v", redact.Error(err))
 		return nil, errors.New("failed to update user")
 	}
 
-	return &models.DisplayUser{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Active:    user.Active,
-		RoleID:    user.RoleId.String(),
-		ProjectID: user.ProjectId.String(),
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserUpdated, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
 }
 
 // DeleteProjectUser deletes a user from a project-specific user table
 func (m *ProjectUserManagerImpl) DeleteProjectUser(ctx context.Context, projectID string, userID uuid.UUID) error {
-	tableName := getProjectUserTableName(projectID)
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	db := m.usersDB(project)
 
 	// Check if user exists
 	var user schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found in this project")
+			return ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return errors.New("internal server error")
 	}
 
-	// Delete user (soft delete with gorm)
-	if err := m.DB.Table(tableName).Delete(&user).Error; err != nil {
-		klog.Errorf("Failed to delete user: %v", err)
+	// Soft delete, stamping updated_at too so that the delete shows in the
+	// change feed
+	now := m.Clock.Now()
+	deletion := schemas.SoftDeletion(now, audit.ActorID(ctx))
+	deletion["updated_at"] = now
+	if err := db.Table(tableName).Where("id = ?", user.ID).Updates(deletion).Error; err != nil {
+		m.Log.For(ctx).Errorf("Failed to delete user: %v", redact.Error(err))
 		return errors.New("failed to delete user")
 	}
+	user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	user.DeletedBy = audit.ActorID(ctx)
+	user.UpdatedAt = now
+	m.Log.For(ctx).V(4).Infof("Soft-deleted user %s of project %s", user.ID, user.ProjectId)
+
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserDeleted, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
 
 	return nil
 }
 
-// CreateOrUpdateOAuthProjectUser creates or updates a user from OAuth provider information in a project-specific user table
+// RestoreProjectUser undoes the soft delete of a project user. It fails with
+// a conflict if another user has since been created with the same email.
+func (m *ProjectUserManagerImpl) RestoreProjectUser(ctx context.Context, projectID string, userID uuid.UUID) (*models.DisplayUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	var user schemas.ProjectUser
+	if err := usersTable(db, tableName, true).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if !user.DeletedAt.Valid {
+		return nil, apierrors.Conflict("user is not deleted")
+	}
+
+	var count int64
+	if err := db.Table(tableName).Model(&schemas.ProjectUser{}).Where("email = ? AND id != ?", user.Email, user.ID).Count(&count).Error; err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if count > 0 {
+		return nil, ErrRestoreEmailTaken
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.DeletedBy = nil
+	user.UpdatedAt = m.Clock.Now()
+	restoration := schemas.Restoration()
+	restoration["updated_at"] = user.UpdatedAt
+	if err := db.Table(tableName).Unscoped().Where("id = ?", user.ID).Updates(restoration).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrRestoreEmailTaken
+		}
+		m.Log.For(ctx).Errorf("Failed to restore user: %v", redact.Error(err))
+		return nil, errors.New("failed to restore user")
+	}
+
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserRestored, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionRestore,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
+}
+
+// SetProjectUserStatus moves a user to another status. Changes the lifecycle
+// does not allow fail with a conflict.
+func (m *ProjectUserManagerImpl) SetProjectUserStatus(ctx context.Context, projectID string, userID uuid.UUID, status string) (*models.DisplayUser, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	var user schemas.ProjectUser
+	if err := db.Table(tableName).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	previous := user.Status
+	if err := userstatus.Check(previous, status); err != nil {
+		return nil, err
+	}
+	user.SetStatus(status)
+	user.UpdatedAt = m.Clock.Now()
+
+	// The status is only changed if no one else changed it meanwhile
+	result := db.Table(tableName).Where("id = ? AND status = ?", user.ID, previous).
+		Updates(map[string]interface{}{"status": user.Status, "active": user.Active, "updated_at": user.UpdatedAt})
+	if result.Error != nil {
+		m.Log.For(ctx).Errorf("Failed to update user status: %v", redact.Error(result.Error))
+		return nil, errors.New("failed to update user status")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apierrors.Conflict("user status was changed concurrently")
+	}
+
+	m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserStatusChanged, toDisplayUser(user))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionStatusChange,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   user.ID.String(),
+		Details:      previous + " -> " + status,
+		At:           m.Clock.Now(),
+	})
+
+	displayUser := toDisplayUser(user)
+	return &displayUser, nil
+}
+
+// MaxBulkUsers is the most users a bulk operation may name
+const MaxBulkUsers = 1000
+
+// BulkSetProjectUsersActive activates or deactivates the named users in one
+// transaction and returns how many changed. Users already in the requested
+// state are left alone. If any user is unknown or the lifecycle forbids their
+// change, nothing is changed.
+func (m *ProjectUserManagerImpl) BulkSetProjectUsersActive(ctx context.Context, projectID string, userIDs []uuid.UUID, active bool) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, apierrors.BadRequest("USER_IDS_REQUIRED", "user_ids must name at least one user")
+	}
+	if len(userIDs) > MaxBulkUsers {
+		return 0, apierrors.BadRequest("TOO_MANY_USERS", fmt.Sprintf("at most %d users can be changed at once", MaxBulkUsers))
+	}
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+	db := m.usersDB(project)
+
+	unique := make(map[uuid.UUID]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		unique[id] = struct{}{}
+	}
+
+	var changed []schemas.ProjectUser
+	var previous []string
+	now := m.Clock.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var users []schemas.ProjectUser
+		if err := tx.Table(tableName).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+			return errors.New("internal server error")
+		}
+		if len(users) != len(unique) {
+			found := make(map[uuid.UUID]bool, len(users))
+			for _, u := range users {
+				found[u.ID] = true
+			}
+			for id := range unique {
+				if !found[id] {
+					return apierrors.NotFound("user " + id.String() + " not found in this project")
+				}
+			}
+		}
+
+		for _, user := range users {
+			status := userstatus.ApplyActive(user.Status, active)
+			if status == user.Status {
+				continue
+			}
+			if err := userstatus.Check(user.Status, status); err != nil {
+				return fmt.Errorf("user %s: %w", user.ID, err)
+			}
+			from := user.Status
+			user.SetStatus(status)
+			user.UpdatedAt = now
+
+			result := tx.Table(tableName).Where("id = ? AND status = ?", user.ID, from).
+				Updates(map[string]interface{}{"status": user.Status, "active": user.Active, "updated_at": user.UpdatedAt})
+			if result.Error != nil {
+				m.Log.For(ctx).Errorf("Failed to update user status: %v", redact.Error(result.Error))
+				return errors.New("failed to update users")
+			}
+			if result.RowsAffected == 0 {
+				return apierrors.Conflict("user status was changed concurrently")
+			}
+			changed = append(changed, user)
+			previous = append(previous, from)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, user := range changed {
+		m.Events.Publish(ctx, user.ProjectId, webhooks.EventUserStatusChanged, toDisplayUser(user))
+		audit.Record(ctx, m.DB, audit.Entry{
+			ProjectID:    &user.ProjectId,
+			Action:       audit.ActionStatusChange,
+			ResourceType: audit.ResourceProjectUser,
+			ResourceID:   user.ID.String(),
+			Details:      previous[i] + " -> " + user.Status + " (bulk)",
+			At:           now,
+		})
+	}
+	return int64(len(changed)), nil
+}
+
+// CreateOrUpdateOAuthProjectUser creates or updates a user from OAuth provider information in a project-specific user table.
+// Names too long to store are cut; an ID or email too long fails with
+// oauth.ErrFieldTooLong.
 func (m *ProjectUserManagerImpl) CreateOrUpdateOAuthProjectUser(ctx context.Context, projectID string, userInfo *oauth.UserInfo, roleID uuid.UUID) (*models.DisplayUser, error) {
-	tableName := getProjectUserTableName(projectID)
+	if err := userInfo.Fit(schemas.ProjectUserEmailSize); err != nil {
+		m.Log.For(ctx).Errorf("OAuth user from %s does not fit: %v", userInfo.Provider, err)
+		return nil, oauth.ErrFieldTooLong
+	}
 
-	// Check if user with the same email already exists
-	var existingUser schemas.ProjectUser
-	if err := m.DB.Table(tableName).Where("email = ?", userInfo.Email).First(&existingUser).Error; err == nil {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	existingUser, separate, err := m.findOAuthUser(ctx, project, tableName, userInfo)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
 		// User exists, update OAuth information
 		existingUser.FirstName = userInfo.FirstName
 		existingUser.LastName = userInfo.LastName
 		existingUser.OAuthID = userInfo.ID
 		existingUser.OAuthType = userInfo.Provider
-		existingUser.UpdatedAt = time.Now()
+		if userInfo.EmailVerified && existingUser.Email == userInfo.Email {
+			existingUser.EmailVerified = true
+		}
+		m.sealOAuthTokens(ctx, existingUser, userInfo)
+		existingUser.UpdatedAt = m.Clock.Now()
 
-		if err := m.DB.Table(tableName).Save(&existingUser).Error; err != nil {
-			klog.Errorf("Failed to update user: %v", err)
+		if err := db.Table(tableName).Save(existingUser).Error; err != nil {
+			m.Log.For(ctx).Errorf("Failed to update user: %v", redact.Error(err))
 			return nil, errors.New("failed to update user")
 		}
 
+		m.Events.Publish(ctx, existingUser.ProjectId, webhooks.EventUserUpdated, toDisplayUser(*existingUser))
+		audit.Record(ctx, m.DB, audit.Entry{
+			ProjectID:    &existingUser.ProjectId,
+			Action:       audit.ActionUpdate,
+			ResourceType: audit.ResourceProjectUser,
+			ResourceID:   existingUser.ID.String(),
+			At:           m.Clock.Now(),
+		})
+
 		// Return the updated user
-		return &models.DisplayUser{
-			ID:        existingUser.ID.String(),
-			Email:     existingUser.Email,
-			FirstName: existingUser.FirstName,
-			LastName:  existingUser.LastName,
-			Active:    existingUser.Active,
-			RoleID:    existingUser.RoleId.String(),
-			ProjectID: existingUser.ProjectId.String(),
-			CreatedAt: existingUser.CreatedAt,
-			UpdatedAt: existingUser.UpdatedAt,
-		}, nil
+		displayUser := toDisplayUser(*existingUser)
+		return &displayUser, nil
 	}
 
 	// Parse project ID
@@ -281,51 +769,117 @@ func (m *ProjectUserManagerImpl) CreateOrUpdateOAuthProjectUser(ctx context.Cont
 		return nil, errors.New("invalid project ID format")
 	}
 
+	// Emails are unique among live users, so a separate user cannot share
+	// the existing user's. It gets an address under the reserved .invalid
+	// domain, named after the provider identity, which never receives mail.
+	email := userInfo.Email
+	if separate {
+		email = separateOAuthEmail(userInfo)
+	}
+
 	// Create new user
 	newUser := schemas.ProjectUser{
-		ID:          uuid.New(),
-		Email:       userInfo.Email,
-		FirstName:   userInfo.FirstName,
-		LastName:    userInfo.LastName,
-		Active:      true,
-		OAuthID:     userInfo.ID,
-		OAuthType:   userInfo.Provider,
-		RoleId:      roleID,
-		ProjectId:   projectUUID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		TokenExpiry: time.Now().Add(24 * time.Hour), // Set token expiry to 24 hours
-	}
-
-	if err := m.DB.Table(tableName).Create(&newUser).Error; err != nil {
-		klog.Errorf("Failed to create user: %v", err)
+		ID:            uuid.New(),
+		Email:         email,
+		FirstName:     userInfo.FirstName,
+		LastName:      userInfo.LastName,
+		Status:        schemas.UserStatusActive,
+		Active:        true,
+		OAuthID:       userInfo.ID,
+		OAuthType:     userInfo.Provider,
+		EmailVerified: userInfo.EmailVerified,
+		RoleId:        roleID,
+		ProjectId:     projectUUID,
+		CreatedAt:     m.Clock.Now(),
+		UpdatedAt:     m.Clock.Now(),
+		TokenExpiry:   m.Clock.Now().Add(24 * time.Hour), // Set token expiry to 24 hours
+	}
+	m.sealOAuthTokens(ctx, &newUser, userInfo)
+
+	if err := db.Table(tableName).Create(&newUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		m.Log.For(ctx).Errorf("Failed to create user: %v", redact.Error(err))
 		return nil, errors.New("failed to create user")
 	}
 
+	m.Events.Publish(ctx, newUser.ProjectId, webhooks.EventUserCreated, toDisplayUser(newUser))
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &newUser.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceProjectUser,
+		ResourceID:   newUser.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	// Return the created user
-	return &models.DisplayUser{
-		ID:        newUser.ID.String(),
-		Email:     newUser.Email,
-		FirstName: newUser.FirstName,
-		LastName:  newUser.LastName,
-		Active:    newUser.Active,
-		RoleID:    newUser.RoleId.String(),
-		ProjectID: newUser.ProjectId.String(),
-		CreatedAt: newUser.CreatedAt,
-		UpdatedAt: newUser.UpdatedAt,
-	}, nil
+	displayUser := toDisplayUser(newUser)
+	return &displayUser, nil
+}
+
+// findOAuthUser returns the user an OAuth login belongs to: the one already
+// linked to the provider identity, else the one with the same email, or nil
+// for a new user. Linking by email is only trusted when the provider has
+// verified the email; otherwise anyone who registered the address with the
+// provider could take the account over. The project's oauth_login settings
+// decide whether such a login is refused or gets a separate user, which is
+// reported by separate.
+func (m *ProjectUserManagerImpl) findOAuthUser(ctx context.Context, project *schemas.Project, tableName string, userInfo *oauth.UserInfo) (user *schemas.ProjectUser, separate bool, err error) {
+	db := m.usersDB(project)
+	var found schemas.ProjectUser
+	if userInfo.Provider != "" && userInfo.ID != "" {
+		err := db.Table(tableName).Where("o_auth_type = ? AND o_auth_id = ?", userInfo.Provider, userInfo.ID).First(&found).Error
+		if err == nil {
+			m.Log.For(ctx).V(4).Infof("OAuth login matches user %s by its %s identity", found.ID, userInfo.Provider)
+			return &found, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+			return nil, false, errors.New("internal server error")
+		}
+	}
+
+	err = db.Table(tableName).Where("email = ?", userInfo.Email).First(&found).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, false, errors.New("internal server error")
+	}
+	if userInfo.EmailVerified {
+		m.Log.For(ctx).V(4).Infof("OAuth login matches user %s by its verified email", found.ID)
+		return &found, false, nil
+	}
+
+	m.Log.For(ctx).V(4).Infof("OAuth login has the email of user %s, but %s has not verified it", found.ID, userInfo.Provider)
+	if project.Settings.OAuthLogin.UnverifiedEmail == schemas.UnverifiedEmailSeparate {
+		return nil, true, nil
+	}
+	return nil, false, oauth.ErrEmailNotVerified
+}
+
+// separateOAuthEmail is the email of a user created for an OAuth identity
+// whose unverified email belongs to another user
+func separateOAuthEmail(userInfo *oauth.UserInfo) string {
+	return userInfo.ID + "@" + userInfo.Provider + ".invalid"
 }
 
+// GenerateToken issues a login JWT for a project user
 func (m *ProjectUserManagerImpl) GenerateToken(ctx context.Context, projectId string, userID uuid.UUID) (string, time.Time, error) {
-	// Check if user exists
-	var user schemas.User
-	projectTable := getProjectUserTableName(projectId)
-	if err := m.DB.Table(projectTable).First(&user, "id = ?", userID).Error; err != nil {
-		klog.Errorf("User not found: %v", err)
+	var user schemas.ProjectUser
+	project, projectTable, err := m.projectUsersTable(ctx, projectId)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := m.usersDB(project).Table(projectTable).First(&user, "id = ?", userID).Error; err != nil {
+		m.Log.For(ctx).Errorf("User not found: %v", redact.Error(err))
 		return "", time.Time{}, errors.New("user not found")
 	}
+	if !userstatus.CanLogin(user.Status) {
+		return "", time.Time{}, userstatus.LoginError(user.Status)
+	}
 
-	// For now, just return a placeholder
-	expiresAt := time.Now().Add(24 * time.Hour)
-	return "jwt-token-placeholder", expiresAt, nil
+	return auth.IssueProjectUserToken(ctx, m.DB, user, m.Clock.Now())
 }
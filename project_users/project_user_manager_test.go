@@ -0,0 +1,458 @@
+package projectusers_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/tokenkeys"
+	"github.com/yash3004/user_management_service/webhooks"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// recorder records the events published to it
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Publish(_ context.Context, _ uuid.UUID, event string, _ interface{}) {
+	r.events = append(r.events, event)
+}
+
+// newManager returns a project user manager on db, publishing to events
+func newManager(db *gorm.DB, resolver *regions.Resolver, events webhooks.Publisher) projectusers.ProjectUserManager {
+	return projectusers.NewManager(db, resolver, projects.NewManager(db, projects.Options{Regions: resolver}), events, nil, nil, nil)
+}
+
+func TestCreateProjectUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	events := &recorder{}
+	manager := newManager(db, nil, events)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID := built.Project.ID.String()
+	roleID := built.Roles["Member"].ID
+	ctx := context.Background()
+
+	user, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "password", "Ann", "Lee", roleID)
+	if err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	if user.Status != schemas.UserStatusActive || !user.Active || user.ProjectID != projectID {
+		t.Errorf("user = %+v, want an active user of the project", user)
+	}
+	if len(events.events) != 1 || events.events[0] != webhooks.EventUserCreated {
+		t.Errorf("events = %v, want %s", events.events, webhooks.EventUserCreated)
+	}
+
+	if _, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "password", "", "", roleID); !errors.Is(err, projectusers.ErrEmailTaken) {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrEmailTaken)
+	}
+	if _, err := manager.CreateProjectUser(ctx, "not a uuid", "b@example.com", "password", "", "", roleID); !errors.Is(err, projecttable.ErrInvalidProjectID) {
+		t.Fatalf("err = %v, want %v", err, projecttable.ErrInvalidProjectID)
+	}
+	if _, err := manager.CreateProjectUser(ctx, uuid.NewString(), "b@example.com", "password", "", "", roleID); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+}
+
+func TestCreateProjectUserRecreatesADeletedUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	deleted := built.Users["a@example.com"]
+	ctx := context.Background()
+
+	if err := manager.DeleteProjectUser(ctx, projectID, deleted.ID); err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+	recreated, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "password", "New", "Name", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	if recreated.ID != deleted.ID.String() || recreated.FirstName != "New" || recreated.DeletedAt != nil {
+		t.Fatalf("user = %+v, want the deleted user brought back", recreated)
+	}
+}
+
+func TestDeleteAndRestoreProjectUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	user := built.Users["a@example.com"]
+	ctx := context.Background()
+
+	if err := manager.DeleteProjectUser(ctx, projectID, user.ID); err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+	if _, err := manager.GetProjectUser(ctx, projectID, user.ID, false); !errors.Is(err, projectusers.ErrUserNotFound) {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+	found, err := manager.GetProjectUser(ctx, projectID, user.ID, true)
+	if err != nil {
+		t.Fatalf("GetProjectUser including deleted: %v", err)
+	}
+	if found.DeletedAt == nil || found.DeletedBy == "" {
+		t.Errorf("user = %+v, want it marked deleted", found)
+	}
+	if err := manager.DeleteProjectUser(ctx, projectID, user.ID); !errors.Is(err, projectusers.ErrUserNotFound) {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+
+	restored, err := manager.RestoreProjectUser(ctx, projectID, user.ID)
+	if err != nil {
+		t.Fatalf("RestoreProjectUser: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("user = %+v, want it restored", restored)
+	}
+}
+
+func TestListAndLookupLeaveOutDeletedUsers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").WithUser("b@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	if err := manager.DeleteProjectUser(ctx, projectID, built.Users["a@example.com"].ID); err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+
+	for includeDeleted, want := range map[bool]int{false: 1, true: 2} {
+		users, err := manager.ListProjectUsers(ctx, projectID, includeDeleted, "", sorting.Order{})
+		if err != nil {
+			t.Fatalf("ListProjectUsers: %v", err)
+		}
+		if len(users) != want {
+			t.Errorf("ListProjectUsers(include_deleted=%v) listed %d users, want %d", includeDeleted, len(users), want)
+		}
+	}
+
+	if _, err := manager.GetProjectUserByEmail(ctx, projectID, "a@example.com", false); !errors.Is(err, projectusers.ErrUserNotFound) {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrUserNotFound)
+	}
+	found, err := manager.GetProjectUserByEmail(ctx, projectID, "a@example.com", true)
+	if err != nil {
+		t.Fatalf("GetProjectUserByEmail including deleted: %v", err)
+	}
+	if found.DeletedAt == nil {
+		t.Errorf("user = %+v, want it marked deleted", found)
+	}
+}
+
+func TestRestoreProjectUserWhoseEmailWasTaken(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	user := built.Users["a@example.com"]
+	ctx := context.Background()
+
+	if err := manager.DeleteProjectUser(ctx, projectID, user.ID); err != nil {
+		t.Fatalf("DeleteProjectUser: %v", err)
+	}
+	// The soft-deleted row is recreated in place, so the new owner of the
+	// email is inserted directly
+	other := schemas.ProjectUser{ID: uuid.New(), Email: "a@example.com", RoleId: built.Roles["Member"].ID, ProjectId: built.Project.ID}
+	if err := db.Table(projecttable.Users(built.Project.ID)).Create(&other).Error; err != nil {
+		t.Fatalf("failed to take the email: %v", err)
+	}
+
+	if _, err := manager.RestoreProjectUser(ctx, projectID, user.ID); !errors.Is(err, projectusers.ErrRestoreEmailTaken) {
+		t.Fatalf("err = %v, want %v", err, projectusers.ErrRestoreEmailTaken)
+	}
+}
+
+func TestSetProjectUserStatus(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	user := built.Users["a@example.com"]
+	ctx := context.Background()
+
+	suspended, err := manager.SetProjectUserStatus(ctx, projectID, user.ID, schemas.UserStatusSuspended)
+	if err != nil {
+		t.Fatalf("SetProjectUserStatus: %v", err)
+	}
+	if suspended.Status != schemas.UserStatusSuspended || suspended.Active {
+		t.Errorf("user = %+v, want it suspended and inactive", suspended)
+	}
+	if _, err := manager.SetProjectUserStatus(ctx, projectID, user.ID, schemas.UserStatusInvited); err == nil {
+		t.Fatal("SetProjectUserStatus moved a suspended user back to invited")
+	}
+}
+
+func TestBulkSetProjectUsersActive(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").
+		WithUser("a@example.com").WithUser("b@example.com").WithInactiveUser("c@example.com").
+		Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	ids := []uuid.UUID{built.Users["a@example.com"].ID, built.Users["b@example.com"].ID, built.Users["c@example.com"].ID}
+	changed, err := manager.BulkSetProjectUsersActive(ctx, projectID, ids, false)
+	if err != nil {
+		t.Fatalf("BulkSetProjectUsersActive: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("changed %d users, want the 2 active ones", changed)
+	}
+
+	if _, err := manager.BulkSetProjectUsersActive(ctx, projectID, nil, false); err == nil {
+		t.Error("BulkSetProjectUsersActive accepted no users")
+	}
+	// Nothing changes when one of the users is unknown
+	if _, err := manager.BulkSetProjectUsersActive(ctx, projectID, append(ids, uuid.New()), true); err == nil {
+		t.Fatal("BulkSetProjectUsersActive accepted an unknown user")
+	}
+	user, err := manager.GetProjectUser(ctx, projectID, ids[0], false)
+	if err != nil {
+		t.Fatalf("GetProjectUser: %v", err)
+	}
+	if user.Active {
+		t.Error("a failed bulk change activated a user")
+	}
+}
+
+func TestBulkDeactivationChangesOnlyTheNamedUsers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	events := &recorder{}
+	manager := newManager(db, nil, events)
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"}
+	builder := testutil.AProject().WithRole("Member")
+	for _, email := range emails {
+		builder = builder.WithUser(email)
+	}
+	built := builder.Build(t, db)
+	other := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	named := []uuid.UUID{built.Users["a@example.com"].ID, built.Users["c@example.com"].ID}
+	changed, err := manager.BulkSetProjectUsersActive(ctx, projectID, named, false)
+	if err != nil {
+		t.Fatalf("BulkSetProjectUsersActive: %v", err)
+	}
+	if changed != 2 || len(events.events) != 2 {
+		t.Errorf("changed %d users and published %d events, want 2 of each", changed, len(events.events))
+	}
+
+	for _, email := range emails {
+		user, err := manager.GetProjectUser(ctx, projectID, built.Users[email].ID, false)
+		if err != nil {
+			t.Fatalf("GetProjectUser(%s): %v", email, err)
+		}
+		wantActive := email == "b@example.com" || email == "d@example.com"
+		if user.Active != wantActive {
+			t.Errorf("%s active = %v, want %v", email, user.Active, wantActive)
+		}
+	}
+	otherUser, err := manager.GetProjectUser(ctx, other.Project.ID.String(), other.Users["a@example.com"].ID, false)
+	if err != nil {
+		t.Fatalf("GetProjectUser in the other project: %v", err)
+	}
+	if !otherUser.Active {
+		t.Error("the user with the same email in another project was deactivated")
+	}
+
+	// Another project's user cannot be named through this project
+	if _, err := manager.BulkSetProjectUsersActive(ctx, projectID, []uuid.UUID{other.Users["a@example.com"].ID}, false); err == nil {
+		t.Error("BulkSetProjectUsersActive deactivated a user of another project")
+	}
+}
+
+func TestMissingProjectIsNotFound(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := newManager(db, nil, nil)
+	built := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	deleted := testutil.AProject().WithRole("Member").WithUser("a@example.com").Build(t, db)
+	if _, err := projects.NewManager(db, projects.Options{}).DeleteProject(context.Background(), deleted.Project.ID, nil); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	userID, roleID := built.Users["a@example.com"].ID, built.Roles["Member"].ID
+	ctx := context.Background()
+
+	calls := map[string]func(projectID string) error{
+		"UpdateProjectUser": func(projectID string) error {
+			_, err := manager.UpdateProjectUser(ctx, projectID, userID, "A", "User", true)
+			return err
+		},
+		"GetProjectUser": func(projectID string) error {
+			_, err := manager.GetProjectUser(ctx, projectID, userID, false)
+			return err
+		},
+		"ListProjectUsers": func(projectID string) error {
+			_, err := manager.ListProjectUsers(ctx, projectID, false, "", sorting.Order{})
+			return err
+		},
+		"DeleteProjectUser": func(projectID string) error {
+			return manager.DeleteProjectUser(ctx, projectID, userID)
+		},
+		"SetProjectUserStatus": func(projectID string) error {
+			_, err := manager.SetProjectUserStatus(ctx, projectID, userID, schemas.UserStatusSuspended)
+			return err
+		},
+		"CreateProjectUser": func(projectID string) error {
+			_, err := manager.CreateProjectUser(ctx, projectID, "b@example.com", "password", "", "", roleID)
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			for _, projectID := range []string{uuid.NewString(), deleted.Project.ID.String()} {
+				if err := call(projectID); !errors.Is(err, projects.ErrProjectNotFound) {
+					t.Errorf("project %s: err = %v, want %v", projectID, err, projects.ErrProjectNotFound)
+				}
+			}
+			if err := call("not-a-uuid"); !errors.Is(err, projecttable.ErrInvalidProjectID) {
+				t.Errorf("malformed project ID: err = %v, want %v", err, projecttable.ErrInvalidProjectID)
+			}
+		})
+	}
+
+	// The user of the live project was left alone
+	user, err := manager.GetProjectUser(ctx, built.Project.ID.String(), userID, false)
+	if err != nil || user.Status != schemas.UserStatusActive {
+		t.Fatalf("GetProjectUser = %+v, %v, want the user untouched", user, err)
+	}
+}
+
+func TestProjectUsersOfARegionalProject(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	manager := newManager(db, resolver, nil)
+	built := testutil.AProject().InRegion("eu", databases["eu"]).WithRole("Member").Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	user, err := manager.CreateProjectUser(ctx, projectID, "a@example.com", "password", "", "", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateProjectUser: %v", err)
+	}
+	var count int64
+	if err := databases["eu"].Table(projecttable.Users(built.Project.ID)).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatal("the user is not in the region's database")
+	}
+	if _, err := manager.GetProjectUserByEmail(ctx, projectID, "a@example.com", false); err != nil {
+		t.Fatalf("GetProjectUserByEmail: %v", err)
+	}
+}
+
+func TestOAuthLoginWithOversizedFields(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	keys, err := tokenkeys.NewKeyRing("k1", map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))})
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	manager := projectusers.NewManager(db, nil, projects.NewManager(db, projects.Options{}), nil, nil, keys, nil)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID, roleID := built.Project.ID.String(), built.Roles["Member"].ID
+	ctx := context.Background()
+
+	// An ID too long to store is refused before the database is reached
+	info := &oauth.UserInfo{ID: strings.Repeat("1", oauth.MaxIDLength+1), Email: "a@example.com", Provider: "google", EmailVerified: true}
+	if _, err := manager.CreateOrUpdateOAuthProjectUser(ctx, projectID, info, roleID); !errors.Is(err, oauth.ErrFieldTooLong) {
+		t.Fatalf("err = %v, want %v", err, oauth.ErrFieldTooLong)
+	}
+	var count int64
+	if err := db.Table(testutil.ProjectUserTable(built.Project.ID)).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("%d users were stored for a refused login", count)
+	}
+
+	// Long names are cut, and tokens too long to store are dropped
+	info = &oauth.UserInfo{
+		ID: "google-1", Email: "a@example.com", Provider: "google", EmailVerified: true,
+		FirstName: strings.Repeat("a", 300),
+		Token:     &oauth2.Token{AccessToken: strings.Repeat("t", oauth.MaxTokenLength), RefreshToken: "refresh"},
+	}
+	user, err := manager.CreateOrUpdateOAuthProjectUser(ctx, projectID, info, roleID)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOAuthProjectUser: %v", err)
+	}
+	if len(user.FirstName) != oauth.MaxNameLength {
+		t.Errorf("first name is %d characters long, want %d", len(user.FirstName), oauth.MaxNameLength)
+	}
+	if _, err := manager.GetOAuthToken(ctx, projectID, uuid.MustParse(user.ID)); !errors.Is(err, projectusers.ErrNoOAuthToken) {
+		t.Errorf("GetOAuthToken = %v, want %v", err, projectusers.ErrNoOAuthToken)
+	}
+}
+
+func TestListDeletedProjectUsers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	impl := newManager(db, nil, nil).(*projectusers.ProjectUserManagerImpl)
+	clock := testutil.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	impl.Clock = clock
+	builder := testutil.AProject().WithRole("Member")
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, email := range emails {
+		builder.WithUser(email)
+	}
+	built := builder.Build(t, db)
+	projectID := built.Project.ID.String()
+	ctx := context.Background()
+
+	// a, c and e are deleted in that order; b and d stay
+	for _, email := range []string{"a@example.com", "c@example.com", "e@example.com"} {
+		clock.Advance(time.Minute)
+		if err := impl.DeleteProjectUser(ctx, projectID, built.Users[email].ID); err != nil {
+			t.Fatalf("DeleteProjectUser(%s): %v", email, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		page, pageSize int
+		want           []string
+	}{
+		{1, 0, []string{"e@example.com", "c@example.com", "a@example.com"}},
+		{1, 2, []string{"e@example.com", "c@example.com"}},
+		{2, 2, []string{"a@example.com"}},
+		{3, 2, nil},
+		{0, projectusers.MaxPageSize + 1, []string{"e@example.com", "c@example.com", "a@example.com"}},
+	} {
+		page, err := impl.ListDeletedProjectUsers(ctx, projectID, tc.page, tc.pageSize)
+		if err != nil {
+			t.Fatalf("ListDeletedProjectUsers(%d, %d): %v", tc.page, tc.pageSize, err)
+		}
+		var got []string
+		for _, user := range page.Users {
+			if user.DeletedAt == nil {
+				t.Errorf("page %d lists %s, who is not deleted", tc.page, user.Email)
+			}
+			got = append(got, user.Email)
+		}
+		if page.Total != 3 || strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("ListDeletedProjectUsers(%d, %d) = %v of %d, want %v of 3", tc.page, tc.pageSize, got, page.Total, tc.want)
+		}
+		if page.PageSize < 1 || page.PageSize > projectusers.MaxPageSize || page.Page < 1 {
+			t.Errorf("ListDeletedProjectUsers(%d, %d) reports page %d of size %d", tc.page, tc.pageSize, page.Page, page.PageSize)
+		}
+	}
+
+	if _, err := impl.ListDeletedProjectUsers(ctx, uuid.NewString(), 1, 10); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Errorf("unknown project: err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+}
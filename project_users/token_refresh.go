@@ -0,0 +1,123 @@
+package projectusers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+// IssuedToken is a fresh login token issued to a project user for an
+// administrator to hand out
+type IssuedToken struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenRefresh reports what RefreshProjectUserTokens changed
+type TokenRefresh struct {
+	RoleID    string        `json:"role_id"`
+	ExpiresAt time.Time     `json:"expires_at"` // The new token expiry of the role's users
+	Updated   int64         `json:"updated"`    // Users whose token expiry was recomputed
+	Tokens    []IssuedToken `json:"tokens"`     // Empty unless tokens were asked for
+}
+
+// RefreshProjectUserTokens recomputes the token expiry of the project's live
+// users holding the role from the role's current expiration, for after the
+// expiration changed. Tokens already handed out are JWTs that keep their
+// expiry; with issue set, a fresh token is returned for every user of the
+// role that may log in, for an administrator to distribute. Issuing is
+// refused for roles held by more than MaxBulkUsers users.
+func (m *ProjectUserManagerImpl) RefreshProjectUserTokens(ctx context.Context, projectID string, roleID uuid.UUID, issue bool) (*TokenRefresh, error) {
+	project, tableName, err := m.projectUsersTable(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	db := m.usersDB(project)
+
+	// The role must be one the project's users can hold
+	var role schemas.Role
+	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, roles.ErrRoleNotFound
+		}
+		m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if role.ProjectId != nil && *role.ProjectId != project.ID {
+		return nil, roles.ErrRoleNotFound
+	}
+
+	now := m.Clock.Now()
+	refresh := &TokenRefresh{
+		RoleID:    role.ID.String(),
+		ExpiresAt: now.Add(auth.SessionLength(role)),
+		Tokens:    []IssuedToken{},
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if issue {
+			var users []schemas.ProjectUser
+			if err := tx.Table(tableName).Where("role_id = ?", role.ID).Order("email").
+				Limit(MaxBulkUsers + 1).Find(&users).Error; err != nil {
+				m.Log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+				return errors.New("internal server error")
+			}
+			if len(users) > MaxBulkUsers {
+				return apierrors.BadRequest("TOO_MANY_USERS", fmt.Sprintf("tokens can be issued for at most %d users at once; refresh without issue_tokens", MaxBulkUsers))
+			}
+			for _, user := range users {
+				if !userstatus.CanLogin(user.Status) {
+					continue
+				}
+				token, err := auth.GenerateToken(user.ID, user.Email, role.ID, user.ProjectId, refresh.ExpiresAt)
+				if err != nil {
+					m.Log.For(ctx).Errorf("Error generating token: %v", err)
+					return errors.New("failed to generate authentication token")
+				}
+				refresh.Tokens = append(refresh.Tokens, IssuedToken{
+					UserID:    user.ID.String(),
+					Email:     user.Email,
+					Token:     token,
+					ExpiresAt: refresh.ExpiresAt,
+				})
+			}
+		}
+
+		result := tx.Table(tableName).Where("role_id = ? AND deleted_at IS NULL", role.ID).Update("token_expiry", refresh.ExpiresAt)
+		if result.Error != nil {
+			m.Log.For(ctx).Errorf("Failed to update token expiry: %v", redact.Error(result.Error))
+			return errors.New("failed to update users")
+		}
+		refresh.Updated = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details := fmt.Sprintf("token expiry of %d users set to %s", refresh.Updated, refresh.ExpiresAt.UTC().Format(time.RFC3339))
+	if issue {
+		details += fmt.Sprintf("; %d tokens issued", len(refresh.Tokens))
+	}
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceRole,
+		ResourceID:   role.ID.String(),
+		Details:      details,
+		At:           now,
+	})
+	return refresh, nil
+}
@@ -0,0 +1,97 @@
+package projectusers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+func TestRefreshProjectUserTokensUpdatesOnlyTheRolesLiveUsers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().
+		WithRole("Member").
+		WithUser("active@example.com").
+		WithUserInStatus("suspended@example.com", schemas.UserStatusSuspended).
+		WithUser("deleted@example.com").
+		WithRole("Other").
+		WithUser("other@example.com").
+		Build(t, db)
+	projectID := built.Project.ID.String()
+	member := built.Roles["Member"]
+	if err := db.Model(&schemas.Role{}).Where("id = ?", member.ID).Update("expiration", 2*time.Hour).Error; err != nil {
+		t.Fatalf("failed to set the role expiration: %v", err)
+	}
+	table := testutil.ProjectUserTable(built.Project.ID)
+	stale := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Table(table).Where("1 = 1").Update("token_expiry", stale).Error; err != nil {
+		t.Fatalf("failed to age the tokens: %v", err)
+	}
+	deletedID := built.Users["deleted@example.com"].ID
+	if err := db.Table(table).Where("id = ?", deletedID).Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatalf("failed to delete the user: %v", err)
+	}
+
+	manager := projectusers.NewManager(db, nil, projects.NewManager(db, projects.Options{}), nil, nil, nil, nil).(*projectusers.ProjectUserManagerImpl)
+	clock := testutil.NewFakeClock(time.Now().UTC().Truncate(time.Second))
+	manager.Clock = clock
+	ctx := context.Background()
+	wantExpiry := clock.Now().Add(2 * time.Hour)
+
+	refresh, err := manager.RefreshProjectUserTokens(ctx, projectID, member.ID, false)
+	if err != nil {
+		t.Fatalf("RefreshProjectUserTokens: %v", err)
+	}
+	if refresh.Updated != 2 || !refresh.ExpiresAt.Equal(wantExpiry) || len(refresh.Tokens) != 0 {
+		t.Errorf("refresh = %+v, want 2 users updated to %v and no tokens", refresh, wantExpiry)
+	}
+	for email, want := range map[string]time.Time{
+		"active@example.com":    wantExpiry,
+		"suspended@example.com": wantExpiry,
+		"deleted@example.com":   stale,
+		"other@example.com":     stale,
+	} {
+		var user schemas.ProjectUser
+		if err := db.Table(table).Unscoped().First(&user, "email = ?", email).Error; err != nil {
+			t.Fatalf("failed to load %s: %v", email, err)
+		}
+		if !user.TokenExpiry.Equal(want) {
+			t.Errorf("token expiry of %s = %v, want %v", email, user.TokenExpiry, want)
+		}
+	}
+
+	// Only users who may log in are handed a token
+	refresh, err = manager.RefreshProjectUserTokens(ctx, projectID, member.ID, true)
+	if err != nil {
+		t.Fatalf("RefreshProjectUserTokens issuing tokens: %v", err)
+	}
+	if len(refresh.Tokens) != 1 || refresh.Tokens[0].Email != "active@example.com" {
+		t.Fatalf("tokens = %+v, want one for active@example.com", refresh.Tokens)
+	}
+	claims, err := auth.ParseToken(refresh.Tokens[0].Token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.RoleId != member.ID || claims.ProjectId != built.Project.ID || !claims.ExpiresAt.Time.Equal(wantExpiry) {
+		t.Errorf("claims = %+v, want the role and project expiring at %v", claims, wantExpiry)
+	}
+
+	otherProject := testutil.AProject().Build(t, db).Project.ID
+	foreign := testutil.ARole("Foreign").Build(t, db)
+	if err := db.Model(&schemas.Role{}).Where("id = ?", foreign.ID).Update("project_id", otherProject).Error; err != nil {
+		t.Fatalf("failed to move the role: %v", err)
+	}
+	for name, roleID := range map[string]uuid.UUID{"unknown role": uuid.New(), "role of another project": foreign.ID} {
+		if _, err := manager.RefreshProjectUserTokens(ctx, projectID, roleID, false); !errors.Is(err, roles.ErrRoleNotFound) {
+			t.Errorf("%s: err = %v, want %v", name, err, roles.ErrRoleNotFound)
+		}
+	}
+}
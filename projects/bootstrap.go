@@ -0,0 +1,330 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/webhooks"
+	"gorm.io/gorm"
+)
+
+// Bootstrap describes a whole tenant to provision at once: the project and
+// its settings, the roles it starts with, its OAuth provider overrides and
+// webhook subscriptions, and the admin it is handed over to. The project
+// template does not apply; the bootstrap's roles replace it.
+type Bootstrap struct {
+	Name           string
+	Description    string
+	UniqueID       string
+	Settings       schemas.ProjectSettings
+	Roles          []RoleTemplate
+	OAuthProviders map[string]schemas.OAuthProviderSettings
+	Webhooks       []BootstrapWebhook
+	Admin          *BootstrapAdmin // Nil to invite no one
+}
+
+// BootstrapWebhook is a webhook subscription of a bootstrapped project
+type BootstrapWebhook struct {
+	URL    string
+	Events []string // Empty for every event
+	Format string   // Empty for raw
+}
+
+// BootstrapAdmin is the first user of a bootstrapped project. They are
+// created invited, without a password, and activated by following a magic
+// link.
+type BootstrapAdmin struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Role      string // Name of one of the bootstrap's roles; empty for the default one
+}
+
+// BootstrapResult is everything a bootstrap created
+type BootstrapResult struct {
+	Project   *schemas.Project
+	RoleIDs   map[string]uuid.UUID // By role name
+	PolicyIDs map[string]uuid.UUID // By policy name
+	// Webhooks are the subscriptions in the order they were given, with the
+	// secrets their deliveries are signed with
+	Webhooks []schemas.WebhookSubscription
+	AdminID  *uuid.UUID // Nil when no admin was invited
+}
+
+// maxAdminNameLength is the size of the name columns of project users
+const maxAdminNameLength = 100
+
+// BootstrapProject provisions a tenant in one go. Everything but the user
+// table is created in one transaction; the table, which cannot be created
+// transactionally everywhere, and the invited admin come after it commits,
+// and should they fail the committed rows are removed again. Validation
+// errors name the offending field by its JSON path.
+func (m *Manager) BootstrapProject(ctx context.Context, b Bootstrap) (*BootstrapResult, error) {
+	uniqueID, adminRole, err := m.validateBootstrap(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.checkProjectQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	// Deleted projects keep their unique ID, since the column is uniquely
+	// indexed
+	var existing schemas.Project
+	if err := m.DB.Unscoped().Where("unique_id = ?", uniqueID).First(&existing).Error; err == nil {
+		return nil, apierrors.Conflict("project with this unique ID already exists").At("unique_id")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	result := &BootstrapResult{}
+	err = m.DB.Transaction(func(tx *gorm.DB) error {
+		project := schemas.Project{
+			ID:          uuid.New(),
+			Name:        b.Name,
+			Description: b.Description,
+			UniqueID:    uniqueID,
+			Settings:    b.Settings,
+			CreatedAt:   m.Clock.Now(),
+			UpdatedAt:   m.Clock.Now(),
+			CreatedBy:   audit.ActorID(ctx),
+		}
+		if len(b.OAuthProviders) > 0 {
+			project.Settings.OAuthProviders = b.OAuthProviders
+		}
+		if err := tx.Create(&project).Error; err != nil {
+			log.For(ctx).Errorf("Failed to create project: %v", err)
+			return errors.New("failed to create project")
+		}
+
+		seeded, err := m.seedRoles(ctx, tx, project.ID, b.Roles)
+		if err != nil {
+			log.For(ctx).Errorf("Failed to seed the project roles: %v", err)
+			return errors.New("failed to create project resources")
+		}
+		if seeded.DefaultRole != nil {
+			project.Settings.DefaultRoleID = seeded.DefaultRole
+			if err := tx.Save(&project).Error; err != nil {
+				log.For(ctx).Errorf("Failed to set the default role: %v", err)
+				return errors.New("failed to create project resources")
+			}
+		}
+
+		subs := make([]schemas.WebhookSubscription, len(b.Webhooks))
+		for i, hook := range b.Webhooks {
+			subs[i] = schemas.WebhookSubscription{
+				ID:        uuid.New(),
+				ProjectId: project.ID,
+				URL:       hook.URL,
+				Secret:    webhooks.NewSecret(),
+				Events:    hook.Events,
+				Format:    hook.Format,
+				Enabled:   true,
+				CreatedAt: m.Clock.Now(),
+				UpdatedAt: m.Clock.Now(),
+			}
+			if err := tx.Create(&subs[i]).Error; err != nil {
+				log.For(ctx).Errorf("Failed to create webhook subscription: %v", err)
+				return errors.New("failed to create project resources")
+			}
+		}
+
+		result.Project = &project
+		result.RoleIDs = seeded.RoleIDs
+		result.PolicyIDs = seeded.PolicyIDs
+		result.Webhooks = subs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	adminID, err := m.createBootstrapUsers(ctx, result.Project.ID, b.Admin, result.RoleIDs[adminRole])
+	if err != nil {
+		m.undoProject(result.Project)
+		return nil, err
+	}
+	result.AdminID = adminID
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &result.Project.ID,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   result.Project.ID.String(),
+		Details:      "bootstrap",
+		At:           m.Clock.Now(),
+	})
+
+	return result, nil
+}
+
+// validateBootstrap checks the whole bootstrap before anything is created,
+// normalizing the webhooks in place. It returns the normalized unique ID and
+// the name of the role the admin is given.
+func (m *Manager) validateBootstrap(b *Bootstrap) (string, string, error) {
+	if strings.TrimSpace(b.Name) == "" {
+		return "", "", apierrors.BadRequest("VALIDATION_FAILED", "name is required").At("name")
+	}
+	uniqueID, err := NormalizeUniqueID(b.UniqueID)
+	if err != nil {
+		return "", "", invalidField("unique_id", err)
+	}
+
+	// Neither can be given in the settings: the roles do not exist yet and
+	// the overrides have a field of their own
+	if b.Settings.DefaultRoleID != nil {
+		return "", "", apierrors.BadRequest("VALIDATION_FAILED", "the default role is chosen by marking one of roles default").At("settings.default_role_id")
+	}
+	if len(b.Settings.OAuthProviders) > 0 {
+		return "", "", apierrors.BadRequest("VALIDATION_FAILED", "oauth provider overrides go in oauth_providers").At("settings.oauth_providers")
+	}
+	if err := validateSettings(b.Settings); err != nil {
+		return "", "", err.At("settings")
+	}
+
+	if err := (Template{Roles: b.Roles}).validate(m.Options.MaxRoleExpiration, b.Settings.CustomResources); err != nil {
+		return "", "", err
+	}
+
+	providers := make([]string, 0, len(b.OAuthProviders))
+	for provider := range b.OAuthProviders {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	for _, provider := range providers {
+		if err := m.validateOAuthProvider(provider, b.OAuthProviders[provider]); err != nil {
+			return "", "", err.At("oauth_providers." + provider)
+		}
+	}
+
+	for i, hook := range b.Webhooks {
+		events, format, err := webhooks.ValidateSubscription(hook.URL, hook.Events, hook.Format)
+		if err != nil {
+			return "", "", invalidField(fmt.Sprintf("webhooks[%d]", i), err)
+		}
+		b.Webhooks[i].Events = events
+		b.Webhooks[i].Format = format
+	}
+
+	if b.Admin == nil {
+		return uniqueID, "", nil
+	}
+	adminRole, err := validateBootstrapAdmin(*b.Admin, b.Roles)
+	if err != nil {
+		return "", "", err
+	}
+	return uniqueID, adminRole, nil
+}
+
+// validateBootstrapAdmin checks the admin to invite and returns the name of
+// the role they are given
+func validateBootstrapAdmin(admin BootstrapAdmin, roles []RoleTemplate) (string, error) {
+	address, err := mail.ParseAddress(admin.Email)
+	if err != nil || address.Address != admin.Email {
+		return "", apierrors.BadRequest("VALIDATION_FAILED", "admin email must be an email address").At("admin.email")
+	}
+	if utf8.RuneCountInString(admin.Email) > schemas.ProjectUserEmailSize {
+		return "", apierrors.BadRequest("VALIDATION_FAILED", fmt.Sprintf("admin email must be at most %d characters", schemas.ProjectUserEmailSize)).At("admin.email")
+	}
+	if utf8.RuneCountInString(admin.FirstName) > maxAdminNameLength {
+		return "", apierrors.BadRequest("VALIDATION_FAILED", fmt.Sprintf("admin first_name must be at most %d characters", maxAdminNameLength)).At("admin.first_name")
+	}
+	if utf8.RuneCountInString(admin.LastName) > maxAdminNameLength {
+		return "", apierrors.BadRequest("VALIDATION_FAILED", fmt.Sprintf("admin last_name must be at most %d characters", maxAdminNameLength)).At("admin.last_name")
+	}
+
+	for _, role := range roles {
+		if (admin.Role == "" && role.Default) || (admin.Role != "" && strings.EqualFold(role.Name, admin.Role)) {
+			return role.Name, nil
+		}
+	}
+	if admin.Role == "" {
+		return "", apierrors.BadRequest("VALIDATION_FAILED", "admin role is required when no role is the default").At("admin.role")
+	}
+	return "", apierrors.BadRequest("VALIDATION_FAILED", "admin role must name one of roles").At("admin.role")
+}
+
+// createBootstrapUsers creates the user table of a bootstrapped project and
+// the invited admin in it, returning the admin's ID
+func (m *Manager) createBootstrapUsers(ctx context.Context, projectID uuid.UUID, admin *BootstrapAdmin, roleID uuid.UUID) (*uuid.UUID, error) {
+	tableName := projecttable.Users(projectID)
+	if err := m.DB.Table(tableName).Migrator().CreateTable(&schemas.ProjectUser{}); err != nil {
+		log.For(ctx).Errorf("Failed to create project user table: %v", err)
+		return nil, errors.New("failed to create project resources")
+	}
+	if err := schemas.CreateProjectUserEmailIndex(m.DB, tableName); err != nil {
+		log.For(ctx).Errorf("Failed to index project user table: %v", err)
+		return nil, errors.New("failed to create project resources")
+	}
+	if admin == nil {
+		return nil, nil
+	}
+
+	user := schemas.ProjectUser{
+		ID:        uuid.New(),
+		Email:     admin.Email,
+		FirstName: admin.FirstName,
+		LastName:  admin.LastName,
+		RoleId:    roleID,
+		ProjectId: projectID,
+		CreatedAt: m.Clock.Now(),
+		UpdatedAt: m.Clock.Now(),
+	}
+	user.SetStatus(schemas.UserStatusInvited)
+	// GORM fills a false Active with the column default, so it is cleared
+	// after the insert
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(tableName).Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Table(tableName).Where("id = ?", user.ID).Update("active", false).Error
+	})
+	if err != nil {
+		log.For(ctx).Errorf("Failed to invite the project admin: %v", err)
+		return nil, errors.New("failed to create project resources")
+	}
+	user.Active = false
+	return &user.ID, nil
+}
+
+// undoProject removes what a bootstrap or a clone committed before the
+// project's user table failed, and the table if it was created. Failures are
+// only logged, leaving the rest for an operator.
+func (m *Manager) undoProject(project *schemas.Project) {
+	projectID := project.ID
+	tableName := projecttable.Users(projectID)
+	users := m.usersDB(m.DB, project)
+	if users.Migrator().HasTable(tableName) {
+		if err := users.Migrator().DropTable(tableName); err != nil {
+			log.Errorf("Failed to drop the user table of project %s: %v", projectID, err)
+		}
+	}
+
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(&schemas.WebhookSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(&schemas.Policy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("project_id = ?", projectID).Delete(&schemas.Role{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", projectID).Delete(&schemas.Project{}).Error
+	})
+	if err != nil {
+		log.Errorf("Failed to remove project %s: %v", projectID, err)
+	}
+}
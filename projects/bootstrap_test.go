@@ -0,0 +1,81 @@
+package projects_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+// acme is a bootstrap of a tenant with every part
+func acme() projects.Bootstrap {
+	return projects.Bootstrap{
+		Name:     "Acme",
+		UniqueID: "acme",
+		Roles: []projects.RoleTemplate{
+			{Name: "admin", Policies: []projects.PolicyTemplate{{Name: "everything", Resource: "*", Action: "*", Effect: "allow"}}},
+			{Name: "member", Default: true},
+		},
+		OAuthProviders: map[string]schemas.OAuthProviderSettings{"github": {ClientID: "id", ClientSecret: "secret"}},
+		Webhooks:       []projects.BootstrapWebhook{{URL: "https://hooks.acme.example.com/ums"}},
+		Admin:          &projects.BootstrapAdmin{Email: "it@acme.example.com", Role: "admin"},
+	}
+}
+
+func TestBootstrapProjectCleansUpAFailedUserTable(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		inject func(db *gorm.DB) error
+	}{
+		{"the table cannot be created", func(db *gorm.DB) error {
+			return db.Callback().Raw().Before("gorm:raw").Register("test:fail_table", func(tx *gorm.DB) {
+				if strings.HasPrefix(tx.Statement.SQL.String(), "CREATE TABLE") && projecttable.Valid(tx.Statement.Table) {
+					tx.AddError(errors.New("table refused"))
+				}
+			})
+		}},
+		{"the admin cannot be invited", func(db *gorm.DB) error {
+			return db.Callback().Create().Before("gorm:create").Register("test:fail_admin", func(tx *gorm.DB) {
+				if projecttable.Valid(tx.Statement.Table) {
+					tx.AddError(errors.New("insert refused"))
+				}
+			})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			manager := projects.NewManager(db, projects.Options{})
+			if err := tc.inject(db); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := manager.BootstrapProject(context.Background(), acme()); err == nil {
+				t.Fatal("BootstrapProject succeeded")
+			}
+			for _, model := range []interface{}{&schemas.Project{}, &schemas.Role{}, &schemas.Policy{}, &schemas.WebhookSubscription{}} {
+				var count int64
+				if err := db.Unscoped().Model(model).Count(&count).Error; err != nil {
+					t.Fatal(err)
+				}
+				if count != 0 {
+					t.Errorf("%d rows of %T are left", count, model)
+				}
+			}
+			tables, err := db.Migrator().GetTables()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, table := range tables {
+				if projecttable.Valid(table) {
+					t.Errorf("user table %s is left", table)
+				}
+			}
+		})
+	}
+}
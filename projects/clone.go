@@ -0,0 +1,179 @@
+package projects
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	roleManager "github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/webhooks"
+	"gorm.io/gorm"
+)
+
+// CloneResult describes a cloned project and how source IDs map to the copies
+type CloneResult struct {
+	Project   *schemas.Project
+	RoleIDs   map[uuid.UUID]uuid.UUID // source role ID -> cloned role ID
+	PolicyIDs map[uuid.UUID]uuid.UUID // source policy ID -> cloned policy ID
+}
+
+// CloneProject copies a project's settings, its project-scoped roles and
+// policies and its (disabled) webhook subscriptions into a new project with
+// an empty user table. Users are never copied. The project and its roles,
+// policies and webhooks are created in one transaction; the user table is
+// created once it commits, since DDL does not roll back everywhere, and the
+// project is removed again if the table cannot be created.
+func (m *Manager) CloneProject(ctx context.Context, id uuid.UUID, name, uniqueID string) (*CloneResult, error) {
+	if name == "" || uniqueID == "" {
+		return nil, apierrors.BadRequest("VALIDATION_FAILED", "name and unique_id are required")
+	}
+	uniqueID, err := NormalizeUniqueID(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkProjectQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	var existing schemas.Project
+	if err := m.DB.Unscoped().Where("unique_id = ? OR (name = ? AND deleted_at IS NULL)", uniqueID, name).First(&existing).Error; err == nil {
+		if existing.UniqueID == uniqueID {
+			return nil, apierrors.Conflict("project with this unique ID already exists")
+		}
+		return nil, apierrors.Conflict("project with this name already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	result := &CloneResult{
+		RoleIDs:   make(map[uuid.UUID]uuid.UUID),
+		PolicyIDs: make(map[uuid.UUID]uuid.UUID),
+	}
+
+	err = m.DB.Transaction(func(tx *gorm.DB) error {
+		project := schemas.Project{
+			ID:          uuid.New(),
+			Name:        name,
+			Description: source.Description,
+			UniqueID:    uniqueID,
+			Region:      source.Region,
+			Settings:    source.Settings,
+			CreatedAt:   m.Clock.Now(),
+			UpdatedAt:   m.Clock.Now(),
+			CreatedBy:   audit.ActorID(ctx),
+		}
+
+		var roles []schemas.Role
+		if err := tx.Scopes(schemas.InProject(&source.ID)).Find(&roles).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+
+		var policies []schemas.Policy
+		if err := tx.Scopes(schemas.InProject(&source.ID)).Find(&policies).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+
+		for _, role := range roles {
+			// Left over from before the name was reserved
+			if roleManager.IsReservedName(role.Name) {
+				return roleManager.ErrNameReserved
+			}
+			result.RoleIDs[role.ID] = uuid.New()
+		}
+
+		// The default role designation follows the role to its copy
+		if def := source.Settings.DefaultRoleID; def != nil {
+			if cloned, ok := result.RoleIDs[*def]; ok {
+				project.Settings.DefaultRoleID = &cloned
+			}
+		}
+
+		if err := tx.Create(&project).Error; err != nil {
+			log.For(ctx).Errorf("Failed to create project: %v", err)
+			return errors.New("failed to clone project")
+		}
+
+		for _, role := range roles {
+			role.ID = result.RoleIDs[role.ID]
+			role.ProjectId = &project.ID
+			role.CreatedBy = audit.ActorID(ctx)
+			role.CreatedAt = m.Clock.Now()
+			role.UpdatedAt = m.Clock.Now()
+			if err := tx.Create(&role).Error; err != nil {
+				log.For(ctx).Errorf("Failed to clone role: %v", err)
+				return errors.New("failed to clone project")
+			}
+		}
+
+		for _, policy := range policies {
+			newID := uuid.New()
+			result.PolicyIDs[policy.ID] = newID
+			policy.ID = newID
+			policy.ProjectId = &project.ID
+			policy.CreatedBy = audit.ActorID(ctx)
+			// Policies attached to a global role stay attached to it
+			if roleID, ok := result.RoleIDs[policy.RolesId]; ok {
+				policy.RolesId = roleID
+			}
+			policy.CreatedAt = m.Clock.Now()
+			policy.UpdatedAt = m.Clock.Now()
+			if err := tx.Create(&policy).Error; err != nil {
+				log.For(ctx).Errorf("Failed to clone policy: %v", err)
+				return errors.New("failed to clone project")
+			}
+		}
+
+		// Webhooks are copied disabled with fresh secrets, so the clone never
+		// delivers to the source's endpoints until someone opts in
+		var subs []schemas.WebhookSubscription
+		if err := tx.Where("project_id = ?", source.ID).Find(&subs).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+		for _, sub := range subs {
+			sub.ID = uuid.New()
+			sub.ProjectId = project.ID
+			sub.Secret = webhooks.NewSecret()
+			sub.Enabled = false
+			sub.CreatedAt = m.Clock.Now()
+			sub.UpdatedAt = m.Clock.Now()
+			if err := tx.Select("*").Create(&sub).Error; err != nil {
+				log.For(ctx).Errorf("Failed to clone webhook subscription: %v", err)
+				return errors.New("failed to clone project")
+			}
+		}
+
+		result.Project = &project
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.createUsersTable(ctx, m.DB, result.Project); err != nil {
+		m.undoProject(result.Project)
+		return nil, err
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &result.Project.ID,
+		Action:       audit.ActionClone,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   result.Project.ID.String(),
+		Details:      "cloned from " + source.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package projects_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestCloneProjectCreatesTheUserTableInTheRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	source := testutil.AProject().InRegion("eu", databases["eu"]).WithRole("Editor").Build(t, db)
+	role := source.Roles["Editor"]
+	if err := db.Model(&role).Update("project_id", source.Project.ID).Error; err != nil {
+		t.Fatalf("failed to scope the role: %v", err)
+	}
+
+	manager := projects.NewManager(db, projects.Options{Regions: resolver})
+	result, err := manager.CloneProject(context.Background(), source.Project.ID, "Copy", "copy")
+	if err != nil {
+		t.Fatalf("CloneProject: %v", err)
+	}
+	if !databases["eu"].Migrator().HasTable(projecttable.Users(result.Project.ID)) {
+		t.Fatal("the clone's user table is not in the region's database")
+	}
+	if db.Migrator().HasTable(projecttable.Users(result.Project.ID)) {
+		t.Fatal("the clone's user table is in the primary database")
+	}
+	if _, ok := result.RoleIDs[role.ID]; !ok {
+		t.Fatalf("role IDs = %v, want the project role cloned", result.RoleIDs)
+	}
+}
+
+func TestCloneProjectRemovesTheCloneWhenItsUserTableFails(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	source := testutil.AProject().InRegion("eu", databases["eu"]).WithRole("Editor").Build(t, db)
+	role := source.Roles["Editor"]
+	if err := db.Model(&role).Update("project_id", source.Project.ID).Error; err != nil {
+		t.Fatalf("failed to scope the role: %v", err)
+	}
+	testutil.CaptureKlog(t)
+
+	// The region's database going away fails the table after the rows commit
+	sqlDB, err := databases["eu"].DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	manager := projects.NewManager(db, projects.Options{Regions: resolver})
+	if _, err := manager.CloneProject(context.Background(), source.Project.ID, "Copy", "copy"); err == nil {
+		t.Fatal("CloneProject succeeded without a user table")
+	}
+
+	var clones int64
+	if err := db.Unscoped().Model(&schemas.Project{}).Where("unique_id = ?", "copy").Count(&clones).Error; err != nil {
+		t.Fatal(err)
+	}
+	var roles int64
+	if err := db.Unscoped().Model(&schemas.Role{}).Where("name = ?", "Editor").Count(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if clones != 0 || roles != 1 {
+		t.Fatalf("%d clones and %d Editor roles are left, want none and the source's", clones, roles)
+	}
+}
+
+func TestCloneProjectSharesNoIDsWithTheSource(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	source := testutil.AProject().WithRole("Editor").WithRole("Viewer").WithUser("a@example.com").Build(t, db)
+	global := testutil.ARole("Auditor").Build(t, db)
+
+	sourceRoles := map[uuid.UUID]bool{}
+	for _, name := range []string{"Editor", "Viewer"} {
+		role := source.Roles[name]
+		if err := db.Model(&role).Update("project_id", source.Project.ID).Error; err != nil {
+			t.Fatalf("failed to scope the role: %v", err)
+		}
+		sourceRoles[role.ID] = true
+	}
+	sourcePolicies := map[uuid.UUID]bool{}
+	for _, policy := range []schemas.Policy{
+		testutil.APolicy("edit", "documents", "write").ForRole(source.Roles["Editor"]).Build(t, db),
+		testutil.APolicy("view", "documents", "read").ForRole(source.Roles["Viewer"]).Build(t, db),
+		testutil.APolicy("audit", "logs", "read").ForRole(global).Build(t, db),
+	} {
+		if err := db.Model(&policy).Update("project_id", source.Project.ID).Error; err != nil {
+			t.Fatalf("failed to scope the policy: %v", err)
+		}
+		sourcePolicies[policy.ID] = true
+	}
+	defaultRole := source.Roles["Viewer"].ID
+	source.Project.Settings.DefaultRoleID = &defaultRole
+	if err := db.Save(&source.Project).Error; err != nil {
+		t.Fatalf("failed to set the default role: %v", err)
+	}
+	hook := schemas.WebhookSubscription{
+		ID:        uuid.New(),
+		ProjectId: source.Project.ID,
+		URL:       "https://hooks.example.com",
+		Secret:    "source-secret",
+		Events:    []string{"user.created"},
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatalf("failed to create the webhook: %v", err)
+	}
+
+	manager := projects.NewManager(db, projects.Options{})
+	result, err := manager.CloneProject(context.Background(), source.Project.ID, "Copy", "copy")
+	if err != nil {
+		t.Fatalf("CloneProject: %v", err)
+	}
+	clone := result.Project.ID
+	if clone == source.Project.ID {
+		t.Fatal("the clone reuses the source's project ID")
+	}
+
+	var roles []schemas.Role
+	if err := db.Where("project_id = ?", clone).Find(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 2 {
+		t.Fatalf("the clone has %d roles, want 2", len(roles))
+	}
+	cloneRoles := map[uuid.UUID]bool{}
+	for _, role := range roles {
+		if sourceRoles[role.ID] {
+			t.Errorf("cloned role %s reuses the source's ID", role.Name)
+		}
+		cloneRoles[role.ID] = true
+	}
+	for sourceID, cloneID := range result.RoleIDs {
+		if !sourceRoles[sourceID] || !cloneRoles[cloneID] {
+			t.Errorf("role ID mapping %s -> %s does not map a source role to a cloned one", sourceID, cloneID)
+		}
+	}
+
+	var policies []schemas.Policy
+	if err := db.Where("project_id = ?", clone).Find(&policies).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 3 {
+		t.Fatalf("the clone has %d policies, want 3", len(policies))
+	}
+	for _, policy := range policies {
+		if sourcePolicies[policy.ID] {
+			t.Errorf("cloned policy %s reuses the source's ID", policy.Name)
+		}
+		if sourceRoles[policy.RolesId] {
+			t.Errorf("cloned policy %s is attached to a source role", policy.Name)
+		}
+		if policy.Name == "audit" && policy.RolesId != global.ID {
+			t.Errorf("policy of the global role is attached to %s, want it left on %s", policy.RolesId, global.ID)
+		}
+	}
+
+	var project schemas.Project
+	if err := db.First(&project, "id = ?", clone).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := project.Settings.DefaultRoleID; got == nil || *got != result.RoleIDs[defaultRole] {
+		t.Errorf("default role = %v, want the copy of the source's default role %s", got, result.RoleIDs[defaultRole])
+	}
+
+	var hooks []schemas.WebhookSubscription
+	if err := db.Where("project_id = ?", clone).Find(&hooks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 {
+		t.Fatalf("the clone has %d webhooks, want 1", len(hooks))
+	}
+	if hooks[0].ID == hook.ID || hooks[0].Secret == hook.Secret || hooks[0].Enabled {
+		t.Errorf("cloned webhook = %+v, want a new ID and secret, disabled", hooks[0])
+	}
+
+	var users int64
+	if err := db.Table(projecttable.Users(clone)).Count(&users).Error; err != nil {
+		t.Fatalf("failed to count the clone's users: %v", err)
+	}
+	if users != 0 {
+		t.Errorf("the clone has %d users, want none", users)
+	}
+}
@@ -0,0 +1,20 @@
+package projects
+
+import (
+	"context"
+
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+type projectKey struct{}
+
+// WithProject returns a context carrying the project a request is scoped to
+func WithProject(ctx context.Context, project *schemas.Project) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// ProjectFromContext returns the project stored by WithProject
+func ProjectFromContext(ctx context.Context) (*schemas.Project, bool) {
+	project, ok := ctx.Value(projectKey{}).(*schemas.Project)
+	return project, ok && project != nil
+}
@@ -0,0 +1,180 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"gorm.io/gorm"
+)
+
+// ErrExportFailed is returned when the users export requested before a
+// delete could not be written; the project is left untouched
+var ErrExportFailed = apierrors.New(http.StatusInternalServerError, "EXPORT_FAILED", "users export failed; the project was not deleted")
+
+// DeletePreview counts what deleting a project removes. Users includes
+// soft-deleted users, whose rows go with the project's user table; Invites
+// counts the users still invited and is part of Users.
+type DeletePreview struct {
+	Users       int64 `json:"users"`
+	Invites     int64 `json:"invites"`
+	Roles       int64 `json:"roles"`
+	Policies    int64 `json:"policies"`
+	Webhooks    int64 `json:"webhooks"`
+	Passkeys    int64 `json:"passkeys"`
+	APITokens   int64 `json:"api_tokens"`
+	LoginEvents int64 `json:"login_events"`
+	ReportRuns  int64 `json:"report_runs"`
+	Consents    int64 `json:"consents"`
+}
+
+// projectResource is a kind of row deleted along with a project, found by
+// its project_id column
+type projectResource struct {
+	name  string
+	model interface{}
+	count func(*DeletePreview) *int64
+}
+
+// projectResources lists the rows deleted with a project besides its user
+// table. The preview counts exactly these and the delete removes exactly
+// these, so the two cannot disagree.
+var projectResources = []projectResource{
+	{"roles", &schemas.Role{}, func(p *DeletePreview) *int64 { return &p.Roles }},
+	{"policies", &schemas.Policy{}, func(p *DeletePreview) *int64 { return &p.Policies }},
+	{"webhook subscriptions", &schemas.WebhookSubscription{}, func(p *DeletePreview) *int64 { return &p.Webhooks }},
+	// Passkeys are scoped to the project's relying party and useless without it
+	{"webauthn credentials", &schemas.WebAuthnCredential{}, func(p *DeletePreview) *int64 { return &p.Passkeys }},
+	{"API tokens", &schemas.ProjectAPIToken{}, func(p *DeletePreview) *int64 { return &p.APITokens }},
+	{"login events", &schemas.LoginEvent{}, func(p *DeletePreview) *int64 { return &p.LoginEvents }},
+	{"report runs", &schemas.ReportRun{}, func(p *DeletePreview) *int64 { return &p.ReportRuns }},
+	{"consents", &schemas.Consent{}, func(p *DeletePreview) *int64 { return &p.Consents }},
+}
+
+// PreviewDelete returns what deleting the project would remove, without
+// changing anything
+func (m *Manager) PreviewDelete(ctx context.Context, id uuid.UUID) (*DeletePreview, error) {
+	project, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return collectDeletion(m.DB, m.usersDB(m.DB, project), project.ID)
+}
+
+// DeleteProject deletes a project with its users, roles, policies, webhook
+// subscriptions and the rest of its resources, and returns what was removed.
+// When export is set every user is written to it first, inside the same
+// transaction; if that fails nothing is deleted and ErrExportFailed is
+// returned.
+func (m *Manager) DeleteProject(ctx context.Context, id uuid.UUID, export io.Writer) (*DeletePreview, error) {
+	// Start a transaction
+	tx := m.DB.Begin()
+	if err := tx.Error; err != nil {
+		return nil, err
+	}
+
+	var project schemas.Project
+	if err := tx.First(&project, "id = ?", id).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	users := m.usersDB(tx, &project)
+	removed, err := collectDeletion(tx, users, project.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if export != nil {
+		if _, err := projectusers.ExportUsers(users, project.ID, export); err != nil {
+			tx.Rollback()
+			log.For(ctx).Errorf("Failed to export users of project %s: %v", project.ID, err)
+			return nil, ErrExportFailed
+		}
+	}
+
+	// Delete the project
+	if err := tx.Model(&project).Updates(schemas.SoftDeletion(m.Clock.Now(), audit.ActorID(ctx))).Error; err != nil {
+		tx.Rollback()
+		log.For(ctx).Errorf("Failed to delete project: %v", err)
+		return nil, errors.New("failed to delete project")
+	}
+
+	for _, resource := range projectResources {
+		if err := tx.Where("project_id = ?", project.ID).Delete(resource.model).Error; err != nil {
+			tx.Rollback()
+			log.For(ctx).Errorf("Failed to delete %s: %v", resource.name, err)
+			return nil, errors.New("failed to delete project resources")
+		}
+	}
+
+	// Drop the project-specific user table, which is named after the ID. A
+	// table in a regional database is only dropped once the project is gone.
+	if !m.regional(&project) {
+		if err := tx.Table(projecttable.Users(project.ID)).Migrator().DropTable(&schemas.ProjectUser{}); err != nil {
+			tx.Rollback()
+			log.For(ctx).Errorf("Failed to drop project user table: %v", err)
+			return nil, errors.New("failed to delete project resources")
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		log.For(ctx).Errorf("Failed to commit transaction: %v", err)
+		return nil, errors.New("failed to delete project")
+	}
+	m.dropRegionalUsersTable(ctx, &project)
+	m.evictProject(id)
+	m.evictOAuthProviders(id)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &id,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   id.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return removed, nil
+}
+
+// collectDeletion counts the rows deleting the project removes. db is the
+// delete's own transaction when called from DeleteProject, and users the
+// database holding the project's users.
+func collectDeletion(db, users *gorm.DB, projectID uuid.UUID) (*DeletePreview, error) {
+	var preview DeletePreview
+
+	table := projecttable.Users(projectID)
+	if users.Migrator().HasTable(table) {
+		if err := users.Table(table).Unscoped().Count(&preview.Users).Error; err != nil {
+			log.Errorf("Failed to count project users: %v", err)
+			return nil, errors.New("internal server error")
+		}
+		if err := users.Table(table).
+			Where("status = ? AND deleted_at IS NULL", schemas.UserStatusInvited).
+			Count(&preview.Invites).Error; err != nil {
+			log.Errorf("Failed to count project invites: %v", err)
+			return nil, errors.New("internal server error")
+		}
+	}
+
+	for _, resource := range projectResources {
+		if err := db.Model(resource.model).Where("project_id = ?", projectID).Count(resource.count(&preview)).Error; err != nil {
+			log.Errorf("Failed to count %s: %v", resource.name, err)
+			return nil, errors.New("internal server error")
+		}
+	}
+	return &preview, nil
+}
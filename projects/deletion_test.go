@@ -0,0 +1,170 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+)
+
+// seedProject builds a project with a user, an invite and a deleted user,
+// and one of each resource deleted along with a project
+func seedProject(t *testing.T, db *gorm.DB) schemas.Project {
+	t.Helper()
+
+	built := testutil.AProject().WithRole("Editor").
+		WithUser("a@example.com").
+		WithUserInStatus("invited@example.com", schemas.UserStatusInvited).
+		WithUser("gone@example.com").
+		Build(t, db)
+	project, user := built.Project, built.Users["a@example.com"]
+	if err := db.Table(testutil.ProjectUserTable(project.ID)).Where("email = ?", "gone@example.com").
+		Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	role := testutil.ARole("Auditor "+project.ID.String()).Build(t, db)
+	policy := testutil.APolicy("read users "+project.ID.String(), "users", "read").ForRole(role).Build(t, db)
+	for _, row := range []interface{}{
+		&schemas.Role{ID: role.ID},
+		&schemas.Policy{ID: policy.ID},
+	} {
+		if err := db.Model(row).Update("project_id", project.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, row := range []interface{}{
+		&schemas.WebhookSubscription{ID: uuid.New(), ProjectId: project.ID, URL: "https://hooks.example.com", Secret: "secret", Enabled: true},
+		&schemas.ProjectAPIToken{ID: uuid.New(), ProjectId: project.ID, Name: "ci", Prefix: "umt_ci", TokenHash: uuid.NewString()},
+		&schemas.LoginEvent{ID: uuid.New(), ProjectId: project.ID, UserId: user.ID, Method: "password"},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+	return project
+}
+
+// liveRows counts the rows of each kind the project owns, leaving out
+// soft-deleted ones. Users are rows of the project's table, deleted or not.
+func liveRows(t *testing.T, db *gorm.DB, projectID uuid.UUID) map[string]int64 {
+	t.Helper()
+
+	counts := map[string]int64{}
+	for name, model := range map[string]interface{}{
+		"roles":    &schemas.Role{},
+		"policies": &schemas.Policy{},
+		"webhooks": &schemas.WebhookSubscription{},
+		"tokens":   &schemas.ProjectAPIToken{},
+		"logins":   &schemas.LoginEvent{},
+	} {
+		var n int64
+		if err := db.Model(model).Where("project_id = ?", projectID).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		counts[name] = n
+	}
+	table := projecttable.Users(projectID)
+	if db.Migrator().HasTable(table) {
+		var n int64
+		if err := db.Table(table).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		counts["users"] = n
+	}
+	return counts
+}
+
+func TestDeletePreviewMatchesWhatDisappears(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	doomed := seedProject(t, db)
+	kept := seedProject(t, db)
+	ctx := context.Background()
+	keptBefore := liveRows(t, db, kept.ID)
+
+	preview, err := manager.PreviewDelete(ctx, doomed.ID)
+	if err != nil {
+		t.Fatalf("PreviewDelete: %v", err)
+	}
+	want := projects.DeletePreview{Users: 3, Invites: 1, Roles: 1, Policies: 1, Webhooks: 1, APITokens: 1, LoginEvents: 1}
+	if *preview != want {
+		t.Fatalf("preview = %+v, want %+v", *preview, want)
+	}
+	before := liveRows(t, db, doomed.ID)
+	previewed := map[string]int64{
+		"roles": preview.Roles, "policies": preview.Policies, "webhooks": preview.Webhooks,
+		"tokens": preview.APITokens, "logins": preview.LoginEvents, "users": preview.Users,
+	}
+	for name, n := range previewed {
+		if before[name] != n {
+			t.Errorf("previewed %d %s, the project has %d", n, name, before[name])
+		}
+	}
+
+	removed, err := manager.DeleteProject(ctx, doomed.ID, nil)
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if *removed != *preview {
+		t.Errorf("removed %+v, previewed %+v", *removed, *preview)
+	}
+	for name, n := range liveRows(t, db, doomed.ID) {
+		if n != 0 {
+			t.Errorf("%d %s of the deleted project are left", n, name)
+		}
+	}
+	if db.Migrator().HasTable(projecttable.Users(doomed.ID)) {
+		t.Error("the deleted project's user table is left")
+	}
+	for name, n := range liveRows(t, db, kept.ID) {
+		if n != keptBefore[name] {
+			t.Errorf("the other project has %d %s, had %d", n, name, keptBefore[name])
+		}
+	}
+}
+
+// failingWriter fails every write, like a full backup disk
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestDeleteProjectAbortsWhenTheExportFails(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	project := seedProject(t, db)
+	ctx := context.Background()
+	before := liveRows(t, db, project.ID)
+
+	if _, err := manager.DeleteProject(ctx, project.ID, failingWriter{}); !errors.Is(err, projects.ErrExportFailed) {
+		t.Fatalf("err = %v, want %v", err, projects.ErrExportFailed)
+	}
+	if _, err := manager.GetProject(ctx, project.ID); err != nil {
+		t.Fatalf("the project is gone after the failed export: %v", err)
+	}
+	after := liveRows(t, db, project.ID)
+	for name, n := range before {
+		if after[name] != n {
+			t.Errorf("%s went from %d to %d", name, n, after[name])
+		}
+	}
+
+	// The project can still be deleted once the export succeeds
+	var export bytes.Buffer
+	if _, err := manager.DeleteProject(ctx, project.ID, &export); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if !bytes.Contains(export.Bytes(), []byte("invited@example.com")) {
+		t.Errorf("the export %q lacks the users", export.String())
+	}
+}
@@ -3,43 +3,139 @@ package projects
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/cron"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/policies"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
+var log = logging.Named("projects")
+
 // ProjectManager defines the interface for project management operations
 type ProjectManager interface {
-	CreateProject(ctx context.Context, name, description, uniqueID string) (*schemas.Project, error)
+	CreateProject(ctx context.Context, name, description, uniqueID, region string) (*schemas.Project, error)
 	GetProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
-	ListProjects(ctx context.Context) ([]schemas.Project, error)
+	GetProjectIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
+	ResolveProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error)
+	ListProjects(ctx context.Context, order sorting.Order) ([]schemas.Project, error)
 	UpdateProject(ctx context.Context, id uuid.UUID, name, description string) (*schemas.Project, error)
-	DeleteProject(ctx context.Context, id uuid.UUID) error
+	DeleteProject(ctx context.Context, id uuid.UUID, export io.Writer) (*DeletePreview, error)
+	PreviewDelete(ctx context.Context, id uuid.UUID) (*DeletePreview, error)
+	GetProjectStats(ctx context.Context, id uuid.UUID) (*ProjectStats, error)
+	UpdateProjectSettings(ctx context.Context, id uuid.UUID, settings schemas.ProjectSettings) (*schemas.Project, error)
+	CloneProject(ctx context.Context, id uuid.UUID, name, uniqueID string) (*CloneResult, error)
+	BootstrapProject(ctx context.Context, b Bootstrap) (*BootstrapResult, error)
+	ValidateUniqueID(ctx context.Context, uniqueID string) (*UniqueIDCheck, error)
+	SetOAuthProvider(ctx context.Context, id uuid.UUID, provider string, config schemas.OAuthProviderSettings) (*schemas.Project, error)
+	DeleteOAuthProvider(ctx context.Context, id uuid.UUID, provider string) (*schemas.Project, error)
+	Template() Template
+}
+
+// ErrProjectNotFound is returned when a project does not exist or has been
+// deleted
+var ErrProjectNotFound = apierrors.NotFound("project not found")
+
+// statsCacheTTL is how long computed project statistics are served from memory
+const statsCacheTTL = 30 * time.Second
+
+// ProjectStats holds aggregate user statistics for a project
+type ProjectStats struct {
+	TotalUsers    int64      `json:"total_users"`
+	ActiveUsers   int64      `json:"active_users"`
+	OAuthUsers    int64      `json:"oauth_users"`
+	PasswordUsers int64      `json:"password_users"`
+	LastSignupAt  *time.Time `json:"last_signup_at"`
+}
+
+// Options configures the project manager
+type Options struct {
+	// CustomOAuthScopes names the OAuth providers whose project overrides
+	// may request scopes outside the provider's known ones
+	CustomOAuthScopes map[string]bool
+	// Template is applied to every new project; it must be valid
+	Template Template
+	// MaxRoleExpiration caps the expiration of roles a bootstrap seeds, as
+	// roles.max_expiration caps those of the roles API; 0 means no cap
+	MaxRoleExpiration time.Duration
+	// OAuthProviders holds the providers built from project overrides; they
+	// are dropped when a project's overrides change or it is deleted. Nil
+	// when nothing caches them.
+	OAuthProviders *oauth.ProviderFactory
+	// ResolveTTL is how long ResolveProject serves a project from memory; 0
+	// reads it from the database every time
+	ResolveTTL time.Duration
+	// MaxProjects caps the live projects of the deployment and
+	// MaxProjectsPerCreator those each user created; 0 means no cap.
+	// SuperAdmin callers are not capped.
+	MaxProjects           int
+	MaxProjectsPerCreator int
+	// Regions lists the regions projects may be created in and holds the
+	// databases of their users. Nil allows only the default region.
+	Regions *regions.Resolver
 }
 
 // Manager implements the ProjectManager interface
 type Manager struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Clock   clock.Clock
+	Options Options
+
+	statsCache   *cache.TTL[uuid.UUID, ProjectStats]
+	resolveCache *cache.TTL[uuid.UUID, schemas.Project] // Nil without a ResolveTTL
 }
 
 // NewManager creates a new project manager
-func NewManager(db *gorm.DB) ProjectManager {
-	return &Manager{
-		DB: db,
+func NewManager(db *gorm.DB, opts Options) ProjectManager {
+	m := &Manager{
+		DB:         db,
+		Clock:      clock.Real{},
+		Options:    opts,
+		statsCache: cache.NewTTL[uuid.UUID, ProjectStats](statsCacheTTL),
+	}
+	if opts.ResolveTTL > 0 {
+		m.resolveCache = cache.NewTTL[uuid.UUID, schemas.Project](opts.ResolveTTL)
 	}
+	return m
 }
 
-// CreateProject creates a new project
-func (m *Manager) CreateProject(ctx context.Context, name, description, uniqueID string) (*schemas.Project, error) {
-	// Check if project with the same unique ID already exists
+// CreateProject creates a new project whose users live in region
+func (m *Manager) CreateProject(ctx context.Context, name, description, uniqueID, region string) (*schemas.Project, error) {
+	uniqueID, err := NormalizeUniqueID(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Options.Regions.Validate(region); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkProjectQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	// Check if project with the same unique ID already exists. Deleted
+	// projects keep theirs, since the column is uniquely indexed.
 	var existingProject schemas.Project
-	if err := m.DB.Where("unique_id = ?", uniqueID).First(&existingProject).Error; err == nil {
-		return nil, errors.New("project with this unique ID already exists")
+	if err := m.DB.Unscoped().Where("unique_id = ?", uniqueID).First(&existingProject).Error; err == nil {
+		return nil, apierrors.Conflict("project with this unique ID already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
@@ -49,8 +145,10 @@ func (m *Manager) CreateProject(ctx context.Context, name, description, uniqueID
 		Name:        name,
 		Description: description,
 		UniqueID:    uniqueID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Region:      region,
+		CreatedAt:   m.Clock.Now(),
+		UpdatedAt:   m.Clock.Now(),
+		CreatedBy:   audit.ActorID(ctx),
 	}
 
 	// Start a transaction
@@ -62,44 +160,108 @@ func (m *Manager) CreateProject(ctx context.Context, name, description, uniqueID
 	// Create the project
 	if err := tx.Create(&project).Error; err != nil {
 		tx.Rollback()
-		klog.Errorf("Failed to create project: %v", err)
+		log.For(ctx).Errorf("Failed to create project: %v", err)
 		return nil, errors.New("failed to create project")
 	}
 
 	// Create project-specific user table
-	tableName := "project_" + project.ID.String() + "_users"
-	if err := tx.Table(tableName).Migrator().CreateTable(&schemas.ProjectUser{}); err != nil {
+	if err := m.createUsersTable(ctx, tx, &project); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Start the project with the configured roles and policies
+	defaultRole, err := m.applyTemplate(ctx, tx, project.ID)
+	if err != nil {
 		tx.Rollback()
-		klog.Errorf("Failed to create project user table: %v", err)
+		m.dropRegionalUsersTable(ctx, &project)
+		log.For(ctx).Errorf("Failed to apply the project template: %v", err)
 		return nil, errors.New("failed to create project resources")
 	}
+	if defaultRole != nil {
+		project.Settings.DefaultRoleID = defaultRole
+		if err := tx.Save(&project).Error; err != nil {
+			tx.Rollback()
+			m.dropRegionalUsersTable(ctx, &project)
+			log.For(ctx).Errorf("Failed to set the default role: %v", err)
+			return nil, errors.New("failed to create project resources")
+		}
+	}
 
 	if err := tx.Commit().Error; err != nil {
-		klog.Errorf("Failed to commit transaction: %v", err)
+		m.dropRegionalUsersTable(ctx, &project)
+		log.For(ctx).Errorf("Failed to commit transaction: %v", err)
 		return nil, errors.New("failed to create project")
 	}
 
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   project.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	return &project, nil
 }
 
 // GetProject gets a project by ID
 func (m *Manager) GetProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	return m.getProject(m.DB, id)
+}
+
+// GetProjectIncludingDeleted gets a project by ID even if it has been soft-deleted
+func (m *Manager) GetProjectIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	return m.getProject(m.DB.Unscoped(), id)
+}
+
+// ResolveProject gets a live project by ID for a request scoped to it,
+// read-through a cache when Options.ResolveTTL is set. The project may be up
+// to the TTL old, though changes made through this manager are seen at once,
+// and must not be modified.
+func (m *Manager) ResolveProject(ctx context.Context, id uuid.UUID) (*schemas.Project, error) {
+	if m.resolveCache != nil {
+		if project, ok := m.resolveCache.Get(id); ok {
+			return &project, nil
+		}
+	}
+	project, err := m.getProject(m.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.resolveCache != nil {
+		m.resolveCache.Set(id, *project)
+	}
+	return project, nil
+}
+
+// evictProject drops a changed or deleted project from the resolve cache
+func (m *Manager) evictProject(id uuid.UUID) {
+	if m.resolveCache != nil {
+		m.resolveCache.Delete(id)
+	}
+}
+
+func (m *Manager) getProject(db *gorm.DB, id uuid.UUID) (*schemas.Project, error) {
 	var project schemas.Project
-	if err := m.DB.First(&project, "id = ?", id).Error; err != nil {
+	if err := db.First(&project, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("project not found")
+			return nil, ErrProjectNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 	return &project, nil
 }
 
-// ListProjects lists all projects
-func (m *Manager) ListProjects(ctx context.Context) ([]schemas.Project, error) {
+// SortFields are the fields ListProjects can sort by
+var SortFields = []string{"name", "unique_id", "created_at", "updated_at"}
+
+// ListProjects lists all projects in order
+func (m *Manager) ListProjects(ctx context.Context, order sorting.Order) ([]schemas.Project, error) {
 	var projects []schemas.Project
-	if err := m.DB.Find(&projects).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+	if err := order.Apply(m.DB).Find(&projects).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 	return projects, nil
@@ -110,64 +272,385 @@ func (m *Manager) UpdateProject(ctx context.Context, id uuid.UUID, name, descrip
 	var project schemas.Project
 	if err := m.DB.First(&project, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("project not found")
+			return nil, ErrProjectNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
 	// Update project fields
 	project.Name = name
 	project.Description = description
-	project.UpdatedAt = time.Now()
+	project.UpdatedAt = m.Clock.Now()
 
 	if err := m.DB.Save(&project).Error; err != nil {
-		klog.Errorf("Failed to update project: %v", err)
+		log.For(ctx).Errorf("Failed to update project: %v", err)
 		return nil, errors.New("failed to update project")
 	}
+	m.evictProject(project.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   project.ID.String(),
+		At:           m.Clock.Now(),
+	})
 
 	return &project, nil
 }
 
-// DeleteProject deletes a project
-func (m *Manager) DeleteProject(ctx context.Context, id uuid.UUID) error {
-	// Start a transaction
-	tx := m.DB.Begin()
-	if err := tx.Error; err != nil {
-		return err
+// UpdateProjectSettings replaces the settings of a project
+func (m *Manager) UpdateProjectSettings(ctx context.Context, id uuid.UUID, settings schemas.ProjectSettings) (*schemas.Project, error) {
+	project, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get the project to get the uniqueID
-	var project schemas.Project
-	if err := tx.First(&project, "id = ?", id).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("project not found")
+	if settings.DefaultRoleID != nil {
+		var role schemas.Role
+		if err := m.DB.First(&role, "id = ?", *settings.DefaultRoleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("default role not found")
+			}
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, errors.New("internal server error")
+		}
+		if role.ProjectId != nil && *role.ProjectId != project.ID {
+			return nil, errors.New("default role belongs to another project")
 		}
-		klog.Errorf("Database error: %v", err)
-		return errors.New("internal server error")
 	}
 
-	// Delete the project
-	if err := tx.Delete(&project).Error; err != nil {
-		tx.Rollback()
-		klog.Errorf("Failed to delete project: %v", err)
-		return errors.New("failed to delete project")
+	if err := validateSettings(settings); err != nil {
+		return nil, err
 	}
 
-	// Drop the project-specific user table
-	tableName := "project_" + project.UniqueID + "_users"
-	if err := tx.Table(tableName).Migrator().DropTable(&schemas.ProjectUser{}); err != nil {
-		tx.Rollback()
-		klog.Errorf("Failed to drop project user table: %v", err)
-		return errors.New("failed to delete project resources")
+	// OAuth overrides carry secrets that are never returned to clients, so
+	// they are managed separately and kept across settings replacements
+	settings.OAuthProviders = project.Settings.OAuthProviders
+
+	project.Settings = settings
+	project.UpdatedAt = m.Clock.Now()
+
+	if err := m.DB.Save(project).Error; err != nil {
+		log.For(ctx).Errorf("Failed to update project settings: %v", err)
+		return nil, errors.New("failed to update project settings")
 	}
+	m.evictProject(project.ID)
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		klog.Errorf("Failed to commit transaction: %v", err)
-		return errors.New("failed to delete project")
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   project.ID.String(),
+		Details:      "settings",
+		At:           m.Clock.Now(),
+	})
+
+	return project, nil
+}
+
+// SetOAuthProvider stores a project's own client credentials for an OAuth
+// provider, replacing any previous override
+func (m *Manager) SetOAuthProvider(ctx context.Context, id uuid.UUID, provider string, config schemas.OAuthProviderSettings) (*schemas.Project, error) {
+	if err := m.validateOAuthProvider(provider, config); err != nil {
+		return nil, err
+	}
+
+	project, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]schemas.OAuthProviderSettings, len(project.Settings.OAuthProviders)+1)
+	for name, existing := range project.Settings.OAuthProviders {
+		providers[name] = existing
 	}
+	providers[provider] = config
+
+	return m.saveOAuthProviders(ctx, project, providers, "oauth provider "+provider)
+}
 
+// validateOAuthProvider checks a project's override of an OAuth provider.
+// Errors about the config name the offending field.
+func (m *Manager) validateOAuthProvider(provider string, config schemas.OAuthProviderSettings) *apierrors.Error {
+	if !oauth.IsSupported(provider) {
+		return apierrors.BadRequest("UNSUPPORTED_PROVIDER", "oauth provider "+provider+" is not supported")
+	}
+	if config.ClientID == "" || config.ClientSecret == "" {
+		field := "client_id"
+		if config.ClientID != "" {
+			field = "client_secret"
+		}
+		return apierrors.BadRequest("INVALID_OAUTH_PROVIDER", "client_id and client_secret are required").At(field)
+	}
+	if redirect := config.RedirectURL; redirect != "" {
+		u, err := url.Parse(redirect)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apierrors.BadRequest("INVALID_REDIRECT_URL", "oauth redirect_url must be an absolute http(s) URL").At("redirect_url")
+		}
+	}
+	if _, err := oauth.ValidateScopes(provider, config.Scopes, m.Options.CustomOAuthScopes[provider]); err != nil {
+		return apierrors.BadRequest("INVALID_SCOPES", fmt.Sprintf("%v; known scopes are %s", err, strings.Join(oauth.KnownScopes(provider), ", "))).At("scopes")
+	}
 	return nil
 }
+
+// DeleteOAuthProvider removes a project's override so the provider falls
+// back to the global configuration
+func (m *Manager) DeleteOAuthProvider(ctx context.Context, id uuid.UUID, provider string) (*schemas.Project, error) {
+	project, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := project.Settings.OAuthProviders[provider]; !ok {
+		return nil, apierrors.NotFound("oauth provider override not found")
+	}
+
+	providers := make(map[string]schemas.OAuthProviderSettings, len(project.Settings.OAuthProviders))
+	for name, existing := range project.Settings.OAuthProviders {
+		if name != provider {
+			providers[name] = existing
+		}
+	}
+
+	return m.saveOAuthProviders(ctx, project, providers, "oauth provider "+provider)
+}
+
+func (m *Manager) saveOAuthProviders(ctx context.Context, project *schemas.Project, providers map[string]schemas.OAuthProviderSettings, details string) (*schemas.Project, error) {
+	if len(providers) == 0 {
+		providers = nil
+	}
+	project.Settings.OAuthProviders = providers
+	project.UpdatedAt = m.Clock.Now()
+
+	if err := m.DB.Save(project).Error; err != nil {
+		log.For(ctx).Errorf("Failed to update project settings: %v", err)
+		return nil, errors.New("failed to update project settings")
+	}
+	m.evictProject(project.ID)
+	m.evictOAuthProviders(project.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceProject,
+		ResourceID:   project.ID.String(),
+		Details:      details,
+		At:           m.Clock.Now(),
+	})
+
+	return project, nil
+}
+
+// evictOAuthProviders drops the providers built from a project's overrides
+func (m *Manager) evictOAuthProviders(id uuid.UUID) {
+	if m.Options.OAuthProviders != nil {
+		m.Options.OAuthProviders.EvictProject(id)
+	}
+}
+
+// validateSettings checks the settings other than the default role, which
+// must be looked up. Errors name the offending field.
+func validateSettings(settings schemas.ProjectSettings) *apierrors.Error {
+	for resource, actions := range settings.CustomResources {
+		field := fmt.Sprintf("custom_resources[%q]", resource)
+		if err := policies.ValidateNamespacedResource(resource); err != nil {
+			return invalidField(field, err)
+		}
+		if len(actions) == 0 {
+			return apierrors.BadRequest("INVALID_RESOURCE", "custom resource "+resource+" must declare at least one action").At(field)
+		}
+	}
+
+	if redirect := settings.MagicLink.RedirectURL; redirect != "" {
+		u, err := url.Parse(redirect)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apierrors.BadRequest("INVALID_REDIRECT_URL", "magic link redirect_url must be an absolute http(s) URL").At("magic_link.redirect_url")
+		}
+	}
+
+	if err := validateWebAuthnSettings(settings.WebAuthn); err != nil {
+		return err.At("webauthn")
+	}
+
+	switch settings.OAuthLogin.UnverifiedEmail {
+	case "", schemas.UnverifiedEmailReject, schemas.UnverifiedEmailSeparate:
+	default:
+		return apierrors.BadRequest("INVALID_OAUTH_LOGIN_SETTINGS", "oauth_login unverified_email must be reject or separate").At("oauth_login.unverified_email")
+	}
+	for i, provider := range settings.OAuthLogin.Providers {
+		if !oauth.IsSupported(provider) {
+			return apierrors.BadRequest("INVALID_OAUTH_LOGIN_SETTINGS", "oauth_login providers may only name "+strings.Join(oauth.SupportedProviders, ", ")).At(fmt.Sprintf("oauth_login.providers[%d]", i))
+		}
+	}
+
+	if suspicious := settings.SuspiciousLogin; suspicious.MinHistory < 0 || suspicious.LookbackDays < 0 {
+		field := "suspicious_login.min_history"
+		if suspicious.MinHistory >= 0 {
+			field = "suspicious_login.lookback_days"
+		}
+		return apierrors.BadRequest("INVALID_SUSPICIOUS_LOGIN_SETTINGS", "suspicious_login min_history and lookback_days cannot be negative").At(field)
+	}
+	if revoke := settings.SuspiciousLogin.RevokeURL; revoke != "" {
+		u, err := url.Parse(revoke)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apierrors.BadRequest("INVALID_REDIRECT_URL", "suspicious_login revoke_url must be an absolute http(s) URL").At("suspicious_login.revoke_url")
+		}
+	}
+
+	if err := validateReportSettings(settings.Reports); err != nil {
+		return err.At("reports")
+	}
+
+	if maxAge := settings.Passwords.MaxAgeDays; maxAge != nil && *maxAge < 0 {
+		return apierrors.BadRequest("INVALID_PASSWORD_SETTINGS", "passwords max_age_days cannot be negative").At("passwords.max_age_days")
+	}
+
+	if err := validateCORSSettings(settings.CORS); err != nil {
+		return err.At("cors")
+	}
+
+	if err := validateConsentSettings(settings.Consents); err != nil {
+		return err.At("consents")
+	}
+	return nil
+}
+
+// maxConsentField is the longest document type or version a consent may
+// name, the size of their columns
+const maxConsentField = 50
+
+// validateConsentSettings checks that every required document has a type and
+// a version that fit the consents table
+func validateConsentSettings(settings schemas.ConsentSettings) *apierrors.Error {
+	for document, version := range settings.Required {
+		if document == "" || len(document) > maxConsentField || strings.TrimSpace(document) != document {
+			return apierrors.BadRequest("INVALID_CONSENT_SETTINGS",
+				fmt.Sprintf("consents document types must be 1 to %d characters without surrounding spaces", maxConsentField)).At("required")
+		}
+		if version == "" || len(version) > maxConsentField {
+			return apierrors.BadRequest("INVALID_CONSENT_SETTINGS",
+				fmt.Sprintf("consents version of %s must be 1 to %d characters", document, maxConsentField)).At(fmt.Sprintf("required[%q]", document))
+		}
+	}
+	return nil
+}
+
+// validateWebAuthnSettings checks that every origin may use the relying party
+// ID: it must be served over https (or http on localhost) from the RP ID's
+// host or one of its subdomains
+func validateWebAuthnSettings(settings schemas.WebAuthnSettings) *apierrors.Error {
+	if settings.RPID == "" && len(settings.Origins) == 0 {
+		return nil
+	}
+	if settings.RPID == "" || strings.ContainsAny(settings.RPID, ":/") {
+		return apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn rp_id must be a domain name").At("rp_id")
+	}
+	if len(settings.Origins) == 0 {
+		return apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn needs at least one origin").At("origins")
+	}
+	for i, origin := range settings.Origins {
+		field := fmt.Sprintf("origins[%d]", i)
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn origin "+origin+" must be a bare scheme://host[:port]").At(field)
+		}
+		host := u.Hostname()
+		if u.Scheme != "https" && !(u.Scheme == "http" && host == "localhost") {
+			return apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn origin "+origin+" must use https").At(field)
+		}
+		if host != settings.RPID && !strings.HasSuffix(host, "."+settings.RPID) {
+			return apierrors.BadRequest("INVALID_WEBAUTHN_SETTINGS", "webauthn origin "+origin+" is not within rp_id "+settings.RPID).At(field)
+		}
+	}
+	return nil
+}
+
+// MaxCORSOrigins is the most origins a project may allow
+const MaxCORSOrigins = 50
+
+// validateCORSSettings checks that every allowed origin is a bare http(s)
+// origin, which is what browsers send in the Origin header
+func validateCORSSettings(settings schemas.CORSSettings) *apierrors.Error {
+	if len(settings.AllowedOrigins) > MaxCORSOrigins {
+		return apierrors.BadRequest("INVALID_CORS_SETTINGS", fmt.Sprintf("cors may allow at most %d origins", MaxCORSOrigins)).At("allowed_origins")
+	}
+	for i, origin := range settings.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+			return apierrors.BadRequest("INVALID_CORS_SETTINGS", "cors origin "+origin+" must be a bare http(s)://host[:port]").At(fmt.Sprintf("allowed_origins[%d]", i))
+		}
+	}
+	return nil
+}
+
+// MaxReportRecipients is the most addresses a project's digest is sent to
+const MaxReportRecipients = 20
+
+// validateReportSettings checks the digest schedule and that every recipient
+// is a bare email address
+func validateReportSettings(settings schemas.ReportSettings) *apierrors.Error {
+	if settings.Schedule != "" {
+		if _, err := cron.Parse(settings.Schedule); err != nil {
+			return apierrors.BadRequest("INVALID_REPORT_SETTINGS", "reports "+err.Error()).At("schedule")
+		}
+	}
+	if len(settings.Recipients) > MaxReportRecipients {
+		return apierrors.BadRequest("INVALID_REPORT_SETTINGS", fmt.Sprintf("reports may have at most %d recipients", MaxReportRecipients)).At("recipients")
+	}
+	for i, recipient := range settings.Recipients {
+		if address, err := mail.ParseAddress(recipient); err != nil || address.Address != recipient {
+			return apierrors.BadRequest("INVALID_REPORT_SETTINGS", "reports recipient "+recipient+" is not an email address").At(fmt.Sprintf("recipients[%d]", i))
+		}
+	}
+	return nil
+}
+
+// GetProjectStats returns aggregate user statistics for a project
+func (m *Manager) GetProjectStats(ctx context.Context, id uuid.UUID) (*ProjectStats, error) {
+	if m.statsCache != nil {
+		if stats, ok := m.statsCache.Get(id); ok {
+			return &stats, nil
+		}
+	}
+
+	project, err := m.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats ProjectStats
+	users := m.usersDB(m.DB, project)
+	tableName := projecttable.Users(project.ID)
+	if err := users.Table(tableName).
+		Select(`COUNT(*) AS total_users,
+			COALESCE(SUM(CASE WHEN status = 'active' THEN 1 ELSE 0 END), 0) AS active_users,
+			COALESCE(SUM(CASE WHEN o_auth_type <> '' THEN 1 ELSE 0 END), 0) AS o_auth_users,
+			COALESCE(SUM(CASE WHEN password <> '' THEN 1 ELSE 0 END), 0) AS password_users`).
+		Where("deleted_at IS NULL").
+		Scan(&stats).Error; err != nil {
+		log.For(ctx).Errorf("Failed to compute project stats: %v", err)
+		return nil, errors.New("failed to compute project stats")
+	}
+
+	if stats.TotalUsers > 0 {
+		var latest schemas.ProjectUser
+		if err := users.Table(tableName).
+			Select("created_at").
+			Where("deleted_at IS NULL").
+			Order("created_at DESC").
+			Limit(1).
+			Scan(&latest).Error; err != nil {
+			log.For(ctx).Errorf("Failed to compute project stats: %v", err)
+			return nil, errors.New("failed to compute project stats")
+		}
+		stats.LastSignupAt = &latest.CreatedAt
+	}
+
+	if m.statsCache != nil {
+		m.statsCache.Set(id, stats)
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,361 @@
+package projects_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestCreateProject(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	ctx := context.Background()
+
+	project, err := manager.CreateProject(ctx, "Shop", "the shop", " Shop_Front ", "")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if project.UniqueID != "shop_front" {
+		t.Errorf("unique ID = %q, want it normalized to shop_front", project.UniqueID)
+	}
+	if !db.Migrator().HasTable(projecttable.Users(project.ID)) {
+		t.Error("the project has no user table")
+	}
+
+	if _, err := manager.CreateProject(ctx, "Other", "", "shop_front", ""); err == nil {
+		t.Error("CreateProject reused a unique ID")
+	}
+	if _, err := manager.CreateProject(ctx, "Other", "", "other", "eu"); err == nil {
+		t.Error("CreateProject accepted a region that is not configured")
+	}
+	if _, err := manager.CreateProject(ctx, "Other", "", "", ""); err == nil {
+		t.Error("CreateProject accepted an empty unique ID")
+	}
+
+	// Deleted projects keep their unique ID
+	if _, err := manager.DeleteProject(ctx, project.ID, nil); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if _, err := manager.CreateProject(ctx, "Shop", "", "shop_front", ""); err == nil {
+		t.Error("CreateProject reused the unique ID of a deleted project")
+	}
+}
+
+func TestCreateProjectValidatesTheUniqueID(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	ctx := context.Background()
+
+	for _, raw := range []string{"My Project!", "my-project", "my project", "x'; DROP TABLE projects; --", strings.Repeat("a", 51)} {
+		_, err := manager.CreateProject(ctx, "Rejected", "", raw, "")
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_UNIQUE_ID" {
+			t.Errorf("CreateProject(%q) = %v, want INVALID_UNIQUE_ID", raw, err)
+		}
+	}
+	var count int64
+	if err := db.Model(&schemas.Project{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("%d projects were created from invalid unique IDs", count)
+	}
+
+	project, err := manager.CreateProject(ctx, "Accepted", "", "my_project", "")
+	if err != nil {
+		t.Fatalf("CreateProject(\"my_project\"): %v", err)
+	}
+	if project.UniqueID != "my_project" {
+		t.Errorf("unique ID = %q, want my_project", project.UniqueID)
+	}
+}
+
+func TestCreateProjectQuota(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{MaxProjects: 1})
+	ctx := context.Background()
+
+	if _, err := manager.CreateProject(ctx, "First", "", "first", ""); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	_, err := manager.CreateProject(ctx, "Second", "", "second", "")
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "PROJECT_QUOTA_EXCEEDED" {
+		t.Fatalf("err = %v, want a PROJECT_QUOTA_EXCEEDED error", err)
+	}
+}
+
+func TestGetProjectStats(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	built := testutil.AProject().
+		WithUser("a@example.com").
+		WithInactiveUser("b@example.com").
+		WithOAuthUser("c@example.com", "google").
+		Build(t, db)
+
+	stats, err := manager.GetProjectStats(context.Background(), built.Project.ID)
+	if err != nil {
+		t.Fatalf("GetProjectStats: %v", err)
+	}
+	if stats.TotalUsers != 3 || stats.ActiveUsers != 2 || stats.OAuthUsers != 1 || stats.PasswordUsers != 2 {
+		t.Fatalf("stats = %+v, want 3 users, 2 active, 1 OAuth and 2 with passwords", stats)
+	}
+}
+
+func TestGetProjectStatsLeavesOutDeletedUsersAndIsCached(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	built := testutil.AProject().WithUser("a@example.com").WithUser("gone@example.com").Build(t, db)
+	table := projecttable.Users(built.Project.ID)
+	newest := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := db.Table(table).Where("email = ?", "a@example.com").Update("created_at", newest).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Table(table).Where("email = ?", "gone@example.com").Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	stats, err := manager.GetProjectStats(ctx, built.Project.ID)
+	if err != nil {
+		t.Fatalf("GetProjectStats: %v", err)
+	}
+	if stats.TotalUsers != 1 || stats.ActiveUsers != 1 || stats.PasswordUsers != 1 {
+		t.Fatalf("stats = %+v, want only the live user counted", stats)
+	}
+	if stats.LastSignupAt == nil || !stats.LastSignupAt.Equal(newest) {
+		t.Fatalf("last signup = %v, want %v", stats.LastSignupAt, newest)
+	}
+
+	// Stats are served from memory for a while, so a new user is not seen yet
+	if err := db.Table(table).Where("email = ?", "gone@example.com").Update("deleted_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	cached, err := manager.GetProjectStats(ctx, built.Project.ID)
+	if err != nil {
+		t.Fatalf("GetProjectStats: %v", err)
+	}
+	if cached.TotalUsers != 1 {
+		t.Fatalf("stats = %+v, want the cached ones", cached)
+	}
+
+	if _, err := manager.GetProjectStats(ctx, uuid.New()); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Fatalf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	built := testutil.AProject().WithRole("Editor").WithUser("a@example.com").WithUser("b@example.com").Build(t, db)
+	role := built.Roles["Editor"]
+	if err := db.Model(&role).Update("project_id", built.Project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	preview, err := manager.PreviewDelete(ctx, built.Project.ID)
+	if err != nil {
+		t.Fatalf("PreviewDelete: %v", err)
+	}
+	if preview.Users != 2 || preview.Roles != 1 {
+		t.Fatalf("preview = %+v, want 2 users and 1 role", preview)
+	}
+
+	var export bytes.Buffer
+	removed, err := manager.DeleteProject(ctx, built.Project.ID, &export)
+	if err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if *removed != *preview {
+		t.Errorf("removed %+v, previewed %+v", removed, preview)
+	}
+	if !bytes.Contains(export.Bytes(), []byte("a@example.com")) {
+		t.Errorf("the export %q lacks the users", export.String())
+	}
+	if db.Migrator().HasTable(projecttable.Users(built.Project.ID)) {
+		t.Error("the user table is left")
+	}
+	if _, err := manager.GetProject(ctx, built.Project.ID); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Errorf("err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+	var roles int64
+	if err := db.Model(&schemas.Role{}).Where("project_id = ?", built.Project.ID).Count(&roles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if roles != 0 {
+		t.Errorf("%d roles of the project are left", roles)
+	}
+}
+
+func TestValidateUniqueID(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	testutil.AProject().WithUniqueID("shop").Build(t, db)
+	ctx := context.Background()
+
+	taken, err := manager.ValidateUniqueID(ctx, "Shop")
+	if err != nil {
+		t.Fatalf("ValidateUniqueID: %v", err)
+	}
+	if !taken.Valid || taken.Available || len(taken.Suggestions) == 0 || taken.Suggestions[0] != "shop_2" {
+		t.Errorf("availability = %+v, want shop taken with shop_2 suggested", taken)
+	}
+
+	free, err := manager.ValidateUniqueID(ctx, "garden")
+	if err != nil {
+		t.Fatalf("ValidateUniqueID: %v", err)
+	}
+	if !free.Valid || !free.Available || free.UniqueID != "garden" {
+		t.Errorf("availability = %+v, want garden free", free)
+	}
+}
+
+func TestSetOAuthProviderValidatesScopes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{CustomOAuthScopes: map[string]bool{"google": true}})
+	project := testutil.AProject().Build(t, db).Project
+	ctx := context.Background()
+	override := func(scopes ...string) schemas.OAuthProviderSettings {
+		return schemas.OAuthProviderSettings{ClientID: "id", ClientSecret: "secret", Scopes: scopes}
+	}
+
+	_, err := manager.SetOAuthProvider(ctx, project.ID, "github", override("read:user", "repo"))
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_SCOPES" || !strings.Contains(apiErr.Message, "repo") {
+		t.Fatalf("err = %v, want INVALID_SCOPES naming repo", err)
+	}
+	if _, err := manager.SetOAuthProvider(ctx, project.ID, "github", override("read:user", "USER:EMAIL")); err != nil {
+		t.Errorf("known github scopes were rejected: %v", err)
+	}
+	if _, err := manager.SetOAuthProvider(ctx, project.ID, "google", override("https://www.googleapis.com/auth/drive")); err != nil {
+		t.Errorf("a custom scope of a provider allowing them was rejected: %v", err)
+	}
+}
+
+func TestUpdateProjectSettingsValidatesTheOAuthLoginProviders(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	project := testutil.AProject().Build(t, db).Project
+	ctx := context.Background()
+
+	settings := schemas.ProjectSettings{OAuthLogin: schemas.OAuthLoginSettings{Providers: []string{"github", "google"}}}
+	if _, err := manager.UpdateProjectSettings(ctx, project.ID, settings); err != nil {
+		t.Fatalf("UpdateProjectSettings: %v", err)
+	}
+	settings.OAuthLogin.Providers = []string{"github", "myspace"}
+	_, err := manager.UpdateProjectSettings(ctx, project.ID, settings)
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_OAUTH_LOGIN_SETTINGS" {
+		t.Fatalf("err = %v, want INVALID_OAUTH_LOGIN_SETTINGS", err)
+	}
+}
+
+func TestOAuthOverrideChangesEvictTheBuiltProviders(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	factory := oauth.NewProviderFactory(nil, oauth.ClientOptions{})
+	manager := projects.NewManager(db, projects.Options{OAuthProviders: factory})
+	project := testutil.AProject().Build(t, db).Project
+	other := testutil.AProject().Build(t, db).Project
+	ctx := context.Background()
+	config := &oauth.ProviderConfig{ClientID: "id", ClientSecret: "secret"}
+	get := func(id uuid.UUID) oauth.Provider {
+		t.Helper()
+		provider, err := factory.GetProjectProvider(id, "github", config)
+		if err != nil {
+			t.Fatalf("GetProjectProvider: %v", err)
+		}
+		return provider
+	}
+
+	built, untouched := get(project.ID), get(other.ID)
+	if _, err := manager.SetOAuthProvider(ctx, project.ID, "google", schemas.OAuthProviderSettings{ClientID: "id", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("SetOAuthProvider: %v", err)
+	}
+	if get(project.ID) == built {
+		t.Error("the project's provider was reused after its overrides were saved")
+	}
+	if get(other.ID) != untouched {
+		t.Error("another project's provider was evicted")
+	}
+
+	built = get(project.ID)
+	if _, err := manager.DeleteOAuthProvider(ctx, project.ID, "google"); err != nil {
+		t.Fatalf("DeleteOAuthProvider: %v", err)
+	}
+	if get(project.ID) == built {
+		t.Error("the project's provider was reused after an override was removed")
+	}
+}
+
+func TestResolveProjectServesRepeatedLookupsFromTheCache(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{ResolveTTL: time.Hour})
+	project := testutil.AProject().Named("Shop").Build(t, db).Project
+	ctx := context.Background()
+	resolve := func() (*schemas.Project, error) {
+		t.Helper()
+		return manager.ResolveProject(ctx, project.ID)
+	}
+
+	queries := testutil.CountQueries(t, db)
+	for i := 0; i < 3; i++ {
+		if resolved, err := resolve(); err != nil || resolved.Name != "Shop" {
+			t.Fatalf("ResolveProject = %+v, %v", resolved, err)
+		}
+	}
+	if queries.Count() != 1 {
+		t.Errorf("3 lookups ran %d queries, want 1", queries.Count())
+	}
+
+	if _, err := manager.UpdateProject(ctx, project.ID, "Store", ""); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	if resolved, err := resolve(); err != nil || resolved.Name != "Store" {
+		t.Errorf("ResolveProject after UpdateProject = %+v, %v, want the new name", resolved, err)
+	}
+	if _, err := manager.DeleteProject(ctx, project.ID, nil); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if _, err := resolve(); !errors.Is(err, projects.ErrProjectNotFound) {
+		t.Errorf("ResolveProject after DeleteProject: err = %v, want %v", err, projects.ErrProjectNotFound)
+	}
+}
+
+func BenchmarkResolveProject(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"cached", 5 * time.Second},
+		{"uncached", 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			db := testutil.NewTestDB(b)
+			manager := projects.NewManager(db, projects.Options{ResolveTTL: bm.ttl})
+			project := testutil.AProject().Build(b, db).Project
+			ctx := context.Background()
+			queries := testutil.CountQueries(b, db)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := manager.ResolveProject(ctx, project.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries.Count())/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// ErrProjectQuotaExceeded is an example of the error creating a project
+// beyond a configured cap returns
+var ErrProjectQuotaExceeded = quotaExceeded("the deployment", 10)
+
+func quotaExceeded(scope string, limit int) *apierrors.Error {
+	return apierrors.New(http.StatusForbidden, "PROJECT_QUOTA_EXCEEDED",
+		fmt.Sprintf("%s already has the maximum of %d projects", scope, limit))
+}
+
+// checkProjectQuota fails with a PROJECT_QUOTA_EXCEEDED error when a new
+// project would exceed Options.MaxProjects live projects in all, or
+// Options.MaxProjectsPerCreator created by the caller. SuperAdmin callers
+// are not limited. The counts are not locked, so requests racing for the
+// last slot may both succeed.
+func (m *Manager) checkProjectQuota(ctx context.Context) error {
+	if m.Options.MaxProjects <= 0 && m.Options.MaxProjectsPerCreator <= 0 {
+		return nil
+	}
+	actor := audit.ActorID(ctx)
+	if actor != nil {
+		superAdmin, err := m.isSuperAdmin(actor)
+		if err != nil {
+			return err
+		}
+		if superAdmin {
+			return nil
+		}
+	}
+
+	if limit := m.Options.MaxProjects; limit > 0 {
+		var count int64
+		if err := m.DB.Model(&schemas.Project{}).Count(&count).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+		if count >= int64(limit) {
+			return quotaExceeded("the deployment", limit)
+		}
+	}
+	if limit := m.Options.MaxProjectsPerCreator; limit > 0 && actor != nil {
+		var count int64
+		if err := m.DB.Model(&schemas.Project{}).Where("created_by = ?", *actor).Count(&count).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+		if count >= int64(limit) {
+			return quotaExceeded("your account", limit)
+		}
+	}
+	return nil
+}
+
+// isSuperAdmin reports whether a user holds the global SuperAdmin role as
+// its primary role
+func (m *Manager) isSuperAdmin(userID *uuid.UUID) (bool, error) {
+	var role schemas.Role
+	err := m.DB.Model(&schemas.Role{}).
+		Joins("JOIN users ON users.role_id = roles.id AND users.deleted_at IS NULL").
+		Where("users.id = ?", *userID).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		log.Errorf("Database error: %v", err)
+		return false, errors.New("internal server error")
+	}
+	return auth.IsSuperAdminRole(&role), nil
+}
@@ -0,0 +1,60 @@
+package projects_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestProjectQuota(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{MaxProjects: 4, MaxProjectsPerCreator: 1})
+	admins := testutil.AProject().WithRole("Admin").Build(t, db)
+	alice := testutil.AUser(t, db, "alice@example.com", admins.Roles["Admin"], admins.Project)
+	bob := testutil.AUser(t, db, "bob@example.com", admins.Roles["Admin"], admins.Project)
+	carol := testutil.AUser(t, db, "carol@example.com", admins.Roles["Admin"], admins.Project)
+	roots := testutil.AProject().WithRole(auth.SuperAdminRole).Build(t, db)
+	root := testutil.AUser(t, db, "root@example.com", roots.Roles[auth.SuperAdminRole], roots.Project)
+	as := func(user string) context.Context { return audit.WithActor(context.Background(), user) }
+	exceeded := func(err error, scope string) {
+		t.Helper()
+		var apiErr *apierrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != "PROJECT_QUOTA_EXCEEDED" || apiErr.StatusCode() != http.StatusForbidden ||
+			!strings.HasPrefix(apiErr.Message, scope) {
+			t.Errorf("err = %v, want 403 PROJECT_QUOTA_EXCEEDED for %s", err, scope)
+		}
+	}
+
+	// The builders' two projects leave room for two more
+	first, err := manager.CreateProject(as(alice.ID.String()), "Alice's", "", "alices", "")
+	if err != nil {
+		t.Fatalf("alice's first project: %v", err)
+	}
+	if first.CreatedBy == nil || *first.CreatedBy != alice.ID {
+		t.Errorf("created_by = %v, want alice", first.CreatedBy)
+	}
+
+	_, err = manager.CreateProject(as(alice.ID.String()), "Alice's second", "", "alices_second", "")
+	exceeded(err, "your account")
+	_, err = manager.CloneProject(as(alice.ID.String()), first.ID, "Alice's clone", "alices_clone")
+	exceeded(err, "your account")
+	if _, err := manager.CreateProject(as(bob.ID.String()), "Bob's", "", "bobs", ""); err != nil {
+		t.Fatalf("bob's first project: %v", err)
+	}
+	_, err = manager.CreateProject(as(carol.ID.String()), "Carol's", "", "carols", "")
+	exceeded(err, "the deployment")
+
+	for _, uniqueID := range []string{"roots", "roots_second"} {
+		if _, err := manager.CreateProject(as(root.ID.String()), uniqueID, "", uniqueID, ""); err != nil {
+			t.Errorf("a SuperAdmin creating %s past both caps: %v", uniqueID, err)
+		}
+	}
+}
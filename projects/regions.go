@@ -0,0 +1,61 @@
+package projects
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// regional reports whether the users of project live in a database of
+// their own region rather than the primary one
+func (m *Manager) regional(project *schemas.Project) bool {
+	db := m.Options.Regions.DB(project.Region)
+	return db != nil && db != m.DB
+}
+
+// usersDB returns the database holding the users table of project: that of
+// its region, or db, the primary database or a transaction on it, when the
+// region has none of its own
+func (m *Manager) usersDB(db *gorm.DB, project *schemas.Project) *gorm.DB {
+	if m.regional(project) {
+		return m.Options.Regions.DB(project.Region)
+	}
+	return db
+}
+
+// createUsersTable creates the users table of a new project with its email
+// index. tx is the transaction creating the project, or the primary
+// database once it has committed; a table in a regional database is created
+// outside it, and dropped with dropRegionalUsersTable when the transaction
+// fails.
+func (m *Manager) createUsersTable(ctx context.Context, tx *gorm.DB, project *schemas.Project) error {
+	db := m.usersDB(tx, project)
+	tableName := projecttable.Users(project.ID)
+	if err := db.Table(tableName).Migrator().CreateTable(&schemas.ProjectUser{}); err != nil {
+		log.For(ctx).Errorf("Failed to create project user table: %v", err)
+		return errors.New("failed to create project resources")
+	}
+	if err := schemas.CreateProjectUserEmailIndex(db, tableName); err != nil {
+		m.dropRegionalUsersTable(ctx, project)
+		log.For(ctx).Errorf("Failed to index project user table: %v", err)
+		return errors.New("failed to create project resources")
+	}
+	return nil
+}
+
+// dropRegionalUsersTable drops the users table of a project in a regional
+// database, which the transaction creating or deleting the project cannot
+// roll back or drop. A table in the primary database is left to the
+// transaction.
+func (m *Manager) dropRegionalUsersTable(ctx context.Context, project *schemas.Project) {
+	if !m.regional(project) {
+		return
+	}
+	db := m.Options.Regions.DB(project.Region)
+	if err := db.Table(projecttable.Users(project.ID)).Migrator().DropTable(&schemas.ProjectUser{}); err != nil {
+		log.For(ctx).Errorf("Failed to drop the user table of project %s in region %s: %v", project.ID, project.Region, err)
+	}
+}
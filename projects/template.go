@@ -0,0 +1,200 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/policies"
+	"github.com/yash3004/user_management_service/roles"
+	"gorm.io/gorm"
+)
+
+// Template is the RBAC setup every new project starts with: project-scoped
+// roles, each with its policies. It is configured at startup and applied by
+// CreateProject; clones copy their source instead.
+type Template struct {
+	Roles []RoleTemplate `json:"roles"`
+}
+
+// RoleTemplate describes a role created in every new project
+type RoleTemplate struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Expiration  time.Duration    `json:"expiration"`
+	Default     bool             `json:"default"` // Becomes the project's default_role_id
+	Policies    []PolicyTemplate `json:"policies"`
+}
+
+// PolicyTemplate describes a policy attached to a template role
+type PolicyTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Effect      string `json:"effect"`
+}
+
+// Validate checks the template the way the roles and policies API checks
+// what it creates, so that applying it cannot fail on bad input. Role
+// expirations are held to maxRoleExpiration, as the API holds them to the
+// roles.max_expiration configuration. Template policies may only use
+// built-in resources, since a new project has no custom resources yet.
+func (t Template) Validate(maxRoleExpiration time.Duration) error {
+	return t.validate(maxRoleExpiration, nil)
+}
+
+// validate checks the template, letting policies use the custom resources
+// besides the built-in ones. Errors name the offending field.
+func (t Template) validate(maxRoleExpiration time.Duration, custom map[string][]string) error {
+	roleNames := make(map[string]bool)
+	policyNames := make(map[string]bool)
+	defaults := 0
+	for i, role := range t.Roles {
+		path := fmt.Sprintf("roles[%d]", i)
+		if err := validateTemplateName("role", role.Name, roleNames); err != nil {
+			return invalidField(path+".name", err)
+		}
+		if roles.IsReservedName(role.Name) {
+			return invalidField(path+".name", roles.ErrNameReserved)
+		}
+		if err := roles.ValidateExpiration(role.Expiration, maxRoleExpiration); err != nil {
+			return invalidField(path+".expiration", fmt.Errorf("role %q: %w", role.Name, err))
+		}
+		if role.Default {
+			defaults++
+		}
+		for j, policy := range role.Policies {
+			path := fmt.Sprintf("%s.policies[%d]", path, j)
+			if err := validateTemplateName("policy", policy.Name, policyNames); err != nil {
+				return invalidField(path+".name", err)
+			}
+			if policy.Effect != "allow" && policy.Effect != "deny" {
+				return invalidField(path+".effect", fmt.Errorf("policy %q: effect must be allow or deny", policy.Name))
+			}
+			if err := policies.ValidateAction(policy.Resource, policy.Action, custom); err != nil {
+				field := path + ".action"
+				var apiErr *apierrors.Error
+				if errors.As(err, &apiErr) && apiErr.Code == "UNKNOWN_RESOURCE" {
+					field = path + ".resource"
+				}
+				return invalidField(field, fmt.Errorf("policy %q: %w", policy.Name, err))
+			}
+		}
+		if defaults > 1 {
+			return invalidField(path+".default", fmt.Errorf("at most one role may be the default, found %d", defaults))
+		}
+	}
+	return nil
+}
+
+// invalidField reports a validation failure of the field at path, keeping
+// the code of an API error and the field within path it names
+func invalidField(path string, err error) *apierrors.Error {
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) {
+		return apierrors.BadRequest("VALIDATION_FAILED", err.Error()).At(path)
+	}
+	invalid := apierrors.BadRequest(apiErr.Code, err.Error())
+	invalid.Field = apiErr.Field
+	return invalid.At(path)
+}
+
+// validateTemplateName checks a role or policy name and that it is unique
+// within the template. Names compare without case, as the database may.
+func validateTemplateName(kind, name string, seen map[string]bool) error {
+	if name == "" {
+		return fmt.Errorf("every %s needs a name", kind)
+	}
+	if len(name) > 100 {
+		return fmt.Errorf("%s name %q is longer than 100 characters", kind, name)
+	}
+	if _, err := uuid.Parse(name); err == nil {
+		return fmt.Errorf("%s name %q must not be a UUID", kind, name)
+	}
+	key := strings.ToLower(name)
+	if seen[key] {
+		return fmt.Errorf("%s %q appears twice", kind, name)
+	}
+	seen[key] = true
+	return nil
+}
+
+// Template returns the RBAC template new projects start with
+func (m *Manager) Template() Template {
+	return m.Options.Template
+}
+
+// seededRoles are the roles and policies seedRoles created, by name
+type seededRoles struct {
+	DefaultRole *uuid.UUID // Nil when no role is the default
+	RoleIDs     map[string]uuid.UUID
+	PolicyIDs   map[string]uuid.UUID
+}
+
+// applyTemplate creates the template's roles and policies in a new project
+// inside tx and returns the ID of the default role, if the template has one.
+// The project row must exist already.
+func (m *Manager) applyTemplate(ctx context.Context, tx *gorm.DB, projectID uuid.UUID) (*uuid.UUID, error) {
+	seeded, err := m.seedRoles(ctx, tx, projectID, m.Options.Template.Roles)
+	if err != nil {
+		return nil, err
+	}
+	return seeded.DefaultRole, nil
+}
+
+// seedRoles creates validated template roles and their policies in a new
+// project inside tx. The project row must exist already.
+func (m *Manager) seedRoles(ctx context.Context, tx *gorm.DB, projectID uuid.UUID, templates []RoleTemplate) (*seededRoles, error) {
+	seeded := &seededRoles{
+		RoleIDs:   make(map[string]uuid.UUID, len(templates)),
+		PolicyIDs: make(map[string]uuid.UUID),
+	}
+	now := m.Clock.Now()
+	for _, tmpl := range templates {
+		role := schemas.Role{
+			ID:          uuid.New(),
+			Name:        tmpl.Name,
+			Description: tmpl.Description,
+			Expiration:  tmpl.Expiration,
+			ProjectId:   &projectID,
+			CreatedBy:   audit.ActorID(ctx),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return nil, fmt.Errorf("creating role %q: %w", tmpl.Name, err)
+		}
+		seeded.RoleIDs[role.Name] = role.ID
+		if tmpl.Default {
+			seeded.DefaultRole = &role.ID
+		}
+
+		for _, p := range tmpl.Policies {
+			policy := schemas.Policy{
+				ID:          uuid.New(),
+				Name:        p.Name,
+				Description: p.Description,
+				Resource:    p.Resource,
+				Action:      p.Action,
+				Effect:      p.Effect,
+				ProjectId:   &projectID,
+				CreatedBy:   audit.ActorID(ctx),
+				RolesId:     role.ID,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if err := tx.Create(&policy).Error; err != nil {
+				return nil, fmt.Errorf("creating policy %q: %w", p.Name, err)
+			}
+			seeded.PolicyIDs[policy.Name] = policy.ID
+		}
+	}
+	return seeded, nil
+}
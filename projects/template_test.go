@@ -0,0 +1,135 @@
+package projects_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+// template is a setup with a default member role that reads users and an
+// admin role that manages them
+var template = projects.Template{Roles: []projects.RoleTemplate{
+	{Name: "Member", Default: true, Expiration: time.Hour, Policies: []projects.PolicyTemplate{
+		{Name: "read users", Resource: "users", Action: "read", Effect: "allow"},
+	}},
+	{Name: "Admin", Policies: []projects.PolicyTemplate{
+		{Name: "write users", Resource: "users", Action: "write", Effect: "allow"},
+		{Name: "never delete users", Resource: "users", Action: "delete", Effect: "deny"},
+	}},
+}}
+
+func TestCreateProjectAppliesTheTemplate(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{Template: template})
+	ctx := context.Background()
+
+	project, err := manager.CreateProject(ctx, "Shop", "", "shop", "")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	other, err := manager.CreateProject(ctx, "Blog", "", "blog", "")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	for _, p := range []*schemas.Project{project, other} {
+		var roles []schemas.Role
+		if err := db.Order("name").Find(&roles, "project_id = ?", p.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(roles) != 2 || roles[0].Name != "Admin" || roles[1].Name != "Member" || roles[1].Expiration != time.Hour {
+			t.Fatalf("roles of %s = %+v, want Admin and Member scoped to it", p.Name, roles)
+		}
+		if p.Settings.DefaultRoleID == nil || *p.Settings.DefaultRoleID != roles[1].ID {
+			t.Errorf("default role of %s = %v, want its Member role %s", p.Name, p.Settings.DefaultRoleID, roles[1].ID)
+		}
+
+		var policies []schemas.Policy
+		if err := db.Find(&policies, "project_id = ?", p.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, policy := range policies {
+			role := "Admin"
+			if policy.RolesId == roles[1].ID {
+				role = "Member"
+			} else if policy.RolesId != roles[0].ID {
+				role = policy.RolesId.String()
+			}
+			got = append(got, role+": "+policy.Name+" "+policy.Effect)
+		}
+		sort.Strings(got)
+		want := "Admin: never delete users deny, Admin: write users allow, Member: read users allow"
+		if strings.Join(got, ", ") != want {
+			t.Errorf("policies of %s = %v, want %s", p.Name, got, want)
+		}
+	}
+
+	// The stored settings carry the default role too
+	stored, err := manager.GetProject(ctx, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Settings.DefaultRoleID == nil || *stored.Settings.DefaultRoleID != *project.Settings.DefaultRoleID {
+		t.Errorf("stored default role = %v, want %v", stored.Settings.DefaultRoleID, project.Settings.DefaultRoleID)
+	}
+	if got := manager.Template(); len(got.Roles) != 2 {
+		t.Errorf("Template() = %+v, want the configured template", got)
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	role := func(name string, policies ...projects.PolicyTemplate) projects.RoleTemplate {
+		return projects.RoleTemplate{Name: name, Policies: policies}
+	}
+	policy := func(name, resource, action, effect string) projects.PolicyTemplate {
+		return projects.PolicyTemplate{Name: name, Resource: resource, Action: action, Effect: effect}
+	}
+	defaultRole := func(name string) projects.RoleTemplate {
+		r := role(name)
+		r.Default = true
+		return r
+	}
+	expiring := role("Temp")
+	expiring.Expiration = 48 * time.Hour
+
+	if err := template.Validate(24 * time.Hour); err != nil {
+		t.Fatalf("Validate of a valid template: %v", err)
+	}
+	for _, tc := range []struct {
+		name  string
+		roles []projects.RoleTemplate
+		field string
+	}{
+		{"a role without a name", []projects.RoleTemplate{role("")}, "roles[0].name"},
+		{"a role named by a UUID", []projects.RoleTemplate{role(uuid.NewString())}, "roles[0].name"},
+		{"a role named SuperAdmin", []projects.RoleTemplate{role("SuperAdmin")}, "roles[0].name"},
+		{"a role appearing twice", []projects.RoleTemplate{role("Member"), role("member")}, "roles[1].name"},
+		{"a role outliving the maximum expiration", []projects.RoleTemplate{expiring}, "roles[0].expiration"},
+		{"two default roles", []projects.RoleTemplate{defaultRole("A"), defaultRole("B")}, "roles[1].default"},
+		{"a policy appearing twice", []projects.RoleTemplate{
+			role("A", policy("read", "users", "read", "allow")),
+			role("B", policy("Read", "users", "read", "allow")),
+		}, "roles[1].policies[0].name"},
+		{"a policy with another effect", []projects.RoleTemplate{role("A", policy("read", "users", "read", "maybe"))}, "roles[0].policies[0].effect"},
+		{"a policy on an unknown resource", []projects.RoleTemplate{role("A", policy("read", "invoices", "read", "allow"))}, "roles[0].policies[0].resource"},
+		{"a policy with an unknown action", []projects.RoleTemplate{role("A", policy("read", "users", "fly", "allow"))}, "roles[0].policies[0].action"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := projects.Template{Roles: tc.roles}.Validate(24 * time.Hour)
+			var apiErr *apierrors.Error
+			if !errors.As(err, &apiErr) || apiErr.Field != tc.field {
+				t.Fatalf("Validate = %v, want a failure of %s", err, tc.field)
+			}
+		})
+	}
+}
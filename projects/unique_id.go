@@ -0,0 +1,109 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// maxUniqueIDLength matches the size of the unique_id column
+const maxUniqueIDLength = 50
+
+var uniqueIDPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// uniqueIDInvalidRun matches the characters a suggestion replaces with an
+// underscore
+var uniqueIDInvalidRun = regexp.MustCompile(`[^a-z0-9_]+`)
+
+const (
+	// uniqueIDSuffixes is how many numbered variants ValidateUniqueID looks at
+	uniqueIDSuffixes = 10
+	// maxUniqueIDSuggestions caps the suggestions ValidateUniqueID returns
+	maxUniqueIDSuggestions = 3
+)
+
+// NormalizeUniqueID trims and lowercases a project unique ID and checks that
+// only letters, digits and underscores remain
+func NormalizeUniqueID(raw string) (string, error) {
+	id := strings.ToLower(strings.TrimSpace(raw))
+	if id == "" {
+		return "", apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id is required")
+	}
+	if len(id) > maxUniqueIDLength {
+		return "", apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id must be at most 50 characters")
+	}
+	if !uniqueIDPattern.MatchString(id) {
+		return "", apierrors.BadRequest("INVALID_UNIQUE_ID", "unique_id may only contain letters, digits and underscores")
+	}
+	return id, nil
+}
+
+// UniqueIDCheck is the result of validating a unique ID before creation
+type UniqueIDCheck struct {
+	UniqueID    string // What a project would be created with; empty when invalid
+	Valid       bool
+	Available   bool     // No project uses it yet
+	Error       string   // Why it is invalid
+	Suggestions []string // Free unique IDs close to it, when it is invalid or taken
+}
+
+// ValidateUniqueID normalizes a unique ID exactly as creating a project does
+// and reports whether a project could be created with it. When it is invalid
+// or taken, it suggests free IDs: the ID with each run of invalid characters
+// made an underscore, then that with numeric suffixes. Every candidate is
+// checked in one query on the unique_id index.
+func (m *Manager) ValidateUniqueID(ctx context.Context, raw string) (*UniqueIDCheck, error) {
+	result := &UniqueIDCheck{Suggestions: []string{}}
+	base, err := NormalizeUniqueID(raw)
+	if err != nil {
+		result.Error = err.Error()
+		base = strings.Trim(uniqueIDInvalidRun.ReplaceAllString(strings.ToLower(strings.TrimSpace(raw)), "_"), "_")
+		if base == "" {
+			return result, nil
+		}
+	} else {
+		result.UniqueID, result.Valid = base, true
+	}
+
+	candidates := []string{truncateUniqueID(base, "")}
+	for n := 2; n <= uniqueIDSuffixes; n++ {
+		candidates = append(candidates, truncateUniqueID(base, fmt.Sprintf("_%d", n)))
+	}
+	var taken []string
+	if err := m.DB.Unscoped().Model(&schemas.Project{}).Where("unique_id IN ?", candidates).Pluck("unique_id", &taken).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	isTaken := make(map[string]bool, len(taken))
+	for _, id := range taken {
+		isTaken[id] = true
+	}
+
+	result.Available = result.Valid && !isTaken[base]
+	if result.Available {
+		return result, nil
+	}
+	for _, candidate := range candidates {
+		if len(result.Suggestions) == maxUniqueIDSuggestions {
+			break
+		}
+		if !isTaken[candidate] && candidate != result.UniqueID {
+			result.Suggestions = append(result.Suggestions, candidate)
+		}
+	}
+	return result, nil
+}
+
+// truncateUniqueID appends suffix to base, shortening base so that the
+// result fits the unique_id column
+func truncateUniqueID(base, suffix string) string {
+	if len(base)+len(suffix) > maxUniqueIDLength {
+		base = base[:maxUniqueIDLength-len(suffix)]
+	}
+	return base + suffix
+}
@@ -0,0 +1,99 @@
+package projects_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+)
+
+func TestValidateAndCreateNormalizeAlike(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	testutil.AProject().WithUniqueID("taken").Build(t, db)
+	ctx := context.Background()
+
+	for _, raw := range []string{
+		"acme_prod",
+		"  Acme_PROD2 ",
+		"ACME",
+		"Taken",
+		"acme-prod",
+		"acme prod",
+		"",
+		"   ",
+		strings.Repeat("a", 50),
+		strings.Repeat("a", 51),
+	} {
+		t.Run(raw, func(t *testing.T) {
+			check, err := manager.ValidateUniqueID(ctx, raw)
+			if err != nil {
+				t.Fatalf("ValidateUniqueID: %v", err)
+			}
+			project, err := manager.CreateProject(ctx, "Project", "", raw, "")
+
+			var apiErr *apierrors.Error
+			switch {
+			case check.Available:
+				if err != nil {
+					t.Fatalf("%q was reported available as %q, but CreateProject = %v", raw, check.UniqueID, err)
+				}
+				if project.UniqueID != check.UniqueID {
+					t.Errorf("created unique ID %q, but the check normalized it to %q", project.UniqueID, check.UniqueID)
+				}
+			case check.Valid:
+				if !errors.As(err, &apiErr) || apiErr.Code != "CONFLICT" {
+					t.Errorf("%q was reported taken, but CreateProject = %v, want CONFLICT", raw, err)
+				}
+			default:
+				if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_UNIQUE_ID" || apiErr.Message != check.Error {
+					t.Errorf("%q was reported invalid with %q, but CreateProject = %v", raw, check.Error, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUniqueIDSuggestions(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := projects.NewManager(db, projects.Options{})
+	for _, id := range []string{"acme", "acme_2", "acme_prod", "acme_prod_3"} {
+		testutil.AProject().WithUniqueID(id).Build(t, db)
+	}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		raw       string
+		available bool
+		valid     bool
+		want      []string
+	}{
+		{"acme_new", true, true, []string{}},
+		{"acme", false, true, []string{"acme_3", "acme_4", "acme_5"}},
+		{"acme-prod", false, false, []string{"acme_prod_2", "acme_prod_4", "acme_prod_5"}},
+		{"-!-", false, false, []string{}},
+		{strings.Repeat("b", 60), false, false, []string{strings.Repeat("b", 50), strings.Repeat("b", 48) + "_2", strings.Repeat("b", 48) + "_3"}},
+	} {
+		t.Run(tc.raw, func(t *testing.T) {
+			check, err := manager.ValidateUniqueID(ctx, tc.raw)
+			if err != nil {
+				t.Fatalf("ValidateUniqueID: %v", err)
+			}
+			if check.Available != tc.available || check.Valid != tc.valid || !reflect.DeepEqual(check.Suggestions, tc.want) {
+				t.Fatalf("check = %+v, want available %v, valid %v and suggestions %q", check, tc.available, tc.valid, tc.want)
+			}
+			// Every suggestion can be created as it is
+			for _, suggestion := range check.Suggestions {
+				project, err := manager.CreateProject(ctx, "Project", "", suggestion, "")
+				if err != nil || project.UniqueID != suggestion {
+					t.Errorf("creating the suggested %q: %+v, %v", suggestion, project, err)
+				}
+			}
+		})
+	}
+}
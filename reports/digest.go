@@ -0,0 +1,63 @@
+package reports
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Digest is what a report tells a project's admins about a period
+type Digest struct {
+	Project     string    `json:"project"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	NewUsers      int64 `json:"new_users"`     // Users created in the period
+	Deactivations int64 `json:"deactivations"` // Users deactivated in the period, by status change or merge
+
+	Logins           int64 `json:"logins"`            // Successful logins in the period
+	LoginUsers       int64 `json:"login_users"`       // Distinct users among them
+	SuspiciousLogins int64 `json:"suspicious_logins"` // Logins flagged from a new IP address or device
+
+	TotalUsers  int64 `json:"total_users"`  // Users now, not counting deleted ones
+	ActiveUsers int64 `json:"active_users"` // Users now able to log in
+}
+
+// digestFuncs format the times in the digest templates, always in UTC
+var digestFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format(time.DateOnly) },
+	"time": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") },
+}
+
+// digestSubject and digestBody are the templates of the digest email
+var (
+	digestSubject = template.Must(template.New("subject").Funcs(digestFuncs).Parse(
+		`{{.Project}} activity {{date .PeriodStart}} - {{date .PeriodEnd}}`))
+
+	digestBody = template.Must(template.New("body").Funcs(digestFuncs).Parse(
+		`Activity of {{.Project}} from {{time .PeriodStart}} to {{time .PeriodEnd}}:
+
+New users:          {{.NewUsers}}
+Deactivated users:  {{.Deactivations}}
+Logins:             {{.Logins}} by {{.LoginUsers}} users
+Suspicious logins:  {{.SuspiciousLogins}}
+
+Users now:          {{.TotalUsers}}, of whom {{.ActiveUsers}} active
+
+You receive this digest as a report recipient of {{.Project}}.
+`))
+)
+
+// render returns the subject and body of a digest email
+func render(d *Digest) (subject, body string, err error) {
+	var b strings.Builder
+	if err := digestSubject.Execute(&b, d); err != nil {
+		return "", "", err
+	}
+	subject = b.String()
+	b.Reset()
+	if err := digestBody.Execute(&b, d); err != nil {
+		return "", "", err
+	}
+	return subject, b.String(), nil
+}
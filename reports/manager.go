@@ -0,0 +1,357 @@
+// Package reports emails project admins a digest of their project's
+// activity: sign-ups, deactivations, logins and user counts. Each project
+// sets a cron schedule and recipients in its settings; a background loop
+// sends the digests that are due, and admins can send one at any time.
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/cron"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/mailer"
+	"github.com/yash3004/user_management_service/projects"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var log = logging.Named("reports")
+
+// Defaults for the options of the report manager
+const (
+	DefaultInterval = time.Minute
+	DefaultPeriod   = 7 * 24 * time.Hour
+	DefaultCatchUp  = 24 * time.Hour
+)
+
+// ErrNoRecipients is returned when a digest is asked for a project without
+// report recipients
+var ErrNoRecipients = apierrors.New(http.StatusConflict, "REPORTS_NOT_CONFIGURED", "the project has no report recipients")
+
+// ReportManager defines the interface for project digest reports
+type ReportManager interface {
+	// SendNow sends the project's digest at once, covering the time since
+	// the last scheduled digest
+	SendNow(ctx context.Context, projectID uuid.UUID) (*Run, error)
+	// Start checks for due digests every interval until ctx is done
+	Start(ctx context.Context)
+}
+
+// Options configures the report manager
+type Options struct {
+	Interval time.Duration // How often schedules are checked
+	Period   time.Duration // Covered by a project's first digest
+	CatchUp  time.Duration // How late a missed scheduled digest is still sent
+}
+
+// Run reports one digest
+type Run struct {
+	ID          string     `json:"id"`
+	Trigger     string     `json:"trigger"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Recipients  int        `json:"recipients"`
+	Sent        int        `json:"sent"`
+	Error       string     `json:"error,omitempty"`
+	Digest      Digest     `json:"digest"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// Manager implements the ReportManager interface
+type Manager struct {
+	DB       *gorm.DB
+	Regions  *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Projects projects.ProjectManager
+	Mailer   mailer.Mailer
+	Clock    clock.Clock
+	Options  Options
+}
+
+// NewManager creates a new report manager
+func NewManager(db *gorm.DB, regions *regions.Resolver, projectManager projects.ProjectManager, m mailer.Mailer, opts Options) ReportManager {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Period <= 0 {
+		opts.Period = DefaultPeriod
+	}
+	if opts.CatchUp <= 0 {
+		opts.CatchUp = DefaultCatchUp
+	}
+	return &Manager{
+		DB:       db,
+		Regions:  regions,
+		Projects: projectManager,
+		Mailer:   m,
+		Clock:    clock.Real{},
+		Options:  opts,
+	}
+}
+
+// Start launches the loop sending scheduled digests
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.Options.Interval)
+		defer ticker.Stop()
+		for {
+			m.RunDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunDue sends the digest of every project whose schedule fired since its
+// last scheduled digest. Only the latest missed firing is sent, and only if
+// it is no older than the catch-up window, so a service that was down for a
+// while does not flood the recipients. Each firing is claimed by inserting
+// its run first, so of several instances only one sends it.
+func (m *Manager) RunDue(ctx context.Context) {
+	var all []schemas.Project
+	if err := m.DB.Find(&all).Error; err != nil {
+		log.For(ctx).Errorf("Failed to load projects for reports: %v", err)
+		return
+	}
+
+	now := m.Clock.Now()
+	for _, project := range all {
+		settings := project.Settings.Reports
+		if !settings.Scheduled() {
+			continue
+		}
+		schedule, err := cron.Parse(settings.Schedule)
+		if err != nil {
+			log.For(ctx).Warningf("Skipping the reports of project %s: %v", project.ID, err)
+			continue
+		}
+
+		last, err := m.lastScheduled(project.ID)
+		if err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			continue
+		}
+		after := now.Add(-m.Options.CatchUp)
+		if last != nil && last.After(after) {
+			after = *last
+		}
+		due := schedule.Last(after, now)
+		if due.IsZero() {
+			continue
+		}
+
+		run, claimed, err := m.claim(project.ID, schemas.ReportTriggerSchedule, due, last)
+		if err != nil {
+			log.For(ctx).Errorf("Failed to claim the report of project %s: %v", project.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := m.send(ctx, &project, run); err != nil {
+			log.For(ctx).Errorf("Failed to send the report of project %s: %v", project.ID, err)
+		}
+	}
+}
+
+// SendNow sends the project's digest at once. It is recorded but leaves the
+// schedule alone: the next scheduled digest covers the same time again.
+func (m *Manager) SendNow(ctx context.Context, projectID uuid.UUID) (*Run, error) {
+	project, err := m.Projects.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(project.Settings.Reports.Recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	last, err := m.lastScheduled(project.ID)
+	if err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	run, claimed, err := m.claim(project.ID, schemas.ReportTriggerManual, m.Clock.Now(), last)
+	if err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if !claimed {
+		return nil, apierrors.Conflict("a report of this project is being sent")
+	}
+	result, err := m.send(ctx, project, run)
+	if err != nil {
+		log.For(ctx).Errorf("Failed to send the report of project %s: %v", project.ID, err)
+		return nil, errors.New("failed to send report")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &project.ID,
+		Action:       audit.ActionSend,
+		ResourceType: audit.ResourceReport,
+		ResourceID:   run.ID.String(),
+		Details:      fmt.Sprintf("sent to %d of %d recipients", result.Sent, result.Recipients),
+		At:           m.Clock.Now(),
+	})
+	return result, nil
+}
+
+// lastScheduled returns the fire time of the project's last scheduled run,
+// or nil if there was none
+func (m *Manager) lastScheduled(projectID uuid.UUID) (*time.Time, error) {
+	var runs []schemas.ReportRun
+	if err := m.DB.Where("project_id = ? AND triggered_by = ?", projectID, schemas.ReportTriggerSchedule).
+		Order("scheduled_for DESC").Limit(1).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return &runs[0].ScheduledFor, nil
+}
+
+// claim records a run for the time given. The period starts at the last
+// scheduled run, or Options.Period earlier for a project's first digest. It
+// reports false if another run for the same time exists already.
+func (m *Manager) claim(projectID uuid.UUID, trigger string, at time.Time, last *time.Time) (*schemas.ReportRun, bool, error) {
+	start := at.Add(-m.Options.Period)
+	if last != nil && last.Before(at) {
+		start = *last
+	}
+	run := &schemas.ReportRun{
+		ID:           uuid.New(),
+		ProjectId:    projectID,
+		ScheduledFor: at,
+		TriggeredBy:  trigger,
+		PeriodStart:  start,
+		PeriodEnd:    at,
+		CreatedAt:    m.Clock.Now(),
+	}
+	result := m.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(run)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return run, result.RowsAffected == 1, nil
+}
+
+// send builds the digest of a claimed run, mails it to every recipient and
+// records the outcome on the run
+func (m *Manager) send(ctx context.Context, project *schemas.Project, run *schemas.ReportRun) (*Run, error) {
+	digest, err := m.collect(ctx, project, run.PeriodStart, run.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, err := render(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := project.Settings.Reports.Recipients
+	run.Recipients = len(recipients)
+	for _, to := range recipients {
+		if err := m.Mailer.Send(ctx, mailer.Message{To: to, Subject: subject, Body: body}); err != nil {
+			log.For(ctx).Errorf("Failed to send the report of project %s to %s: %v", project.ID, redact.SafeEmail(to), redact.Error(err))
+			if run.Error == "" {
+				run.Error = truncate(redact.Error(err), 1000)
+			}
+			continue
+		}
+		run.Sent++
+	}
+	finished := m.Clock.Now()
+	run.FinishedAt = &finished
+	if err := m.DB.Model(run).Updates(map[string]interface{}{
+		"recipients":  run.Recipients,
+		"sent":        run.Sent,
+		"error":       run.Error,
+		"finished_at": run.FinishedAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &Run{
+		ID:          run.ID.String(),
+		Trigger:     run.TriggeredBy,
+		PeriodStart: run.PeriodStart,
+		PeriodEnd:   run.PeriodEnd,
+		Recipients:  run.Recipients,
+		Sent:        run.Sent,
+		Error:       run.Error,
+		Digest:      *digest,
+		FinishedAt:  run.FinishedAt,
+	}, nil
+}
+
+// collect gathers the digest of a project for the period (start, end]
+func (m *Manager) collect(ctx context.Context, project *schemas.Project, start, end time.Time) (*Digest, error) {
+	stats, err := m.Projects.GetProjectStats(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	digest := &Digest{
+		Project:     project.Name,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		TotalUsers:  stats.TotalUsers,
+		ActiveUsers: stats.ActiveUsers,
+	}
+	if name := project.Settings.Branding.DisplayName; name != "" {
+		digest.Project = name
+	}
+
+	// Soft-deleted users count as sign-ups of the period too
+	if err := m.Regions.For(m.DB, project.Region).Table(projecttable.Users(project.ID)).Unscoped().
+		Where("created_at > ? AND created_at <= ?", start, end).
+		Count(&digest.NewUsers).Error; err != nil {
+		return nil, err
+	}
+
+	// Deactivations are status changes and merges ending in deactivated
+	if err := m.DB.Model(&schemas.AuditEvent{}).
+		Where("project_id = ? AND resource_type = ? AND action IN ?", project.ID, audit.ResourceProjectUser,
+			[]string{audit.ActionStatusChange, audit.ActionMerge}).
+		Where("details LIKE ?", "%-> "+schemas.UserStatusDeactivated+"%").
+		Where("created_at > ? AND created_at <= ?", start, end).
+		Count(&digest.Deactivations).Error; err != nil {
+		return nil, err
+	}
+
+	var logins struct {
+		Logins           int64
+		LoginUsers       int64
+		SuspiciousLogins int64
+	}
+	if err := m.DB.Model(&schemas.LoginEvent{}).
+		Select("COUNT(*) AS logins, COUNT(DISTINCT user_id) AS login_users, "+
+			"COALESCE(SUM(CASE WHEN suspicious THEN 1 ELSE 0 END), 0) AS suspicious_logins").
+		Where("project_id = ? AND created_at > ? AND created_at <= ?", project.ID, start, end).
+		Scan(&logins).Error; err != nil {
+		return nil, err
+	}
+	digest.Logins = logins.Logins
+	digest.LoginUsers = logins.LoginUsers
+	digest.SuspiciousLogins = logins.SuspiciousLogins
+	return digest, nil
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
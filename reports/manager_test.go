@@ -0,0 +1,222 @@
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/reports"
+	"gorm.io/gorm"
+)
+
+// monday is when the fixture's weekly digest is first due
+var monday = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// fixture is a project whose digest goes to two admins on Mondays at 09:00
+// UTC, with a week of activity before monday: two sign-ups, a deactivation
+// and three logins by two users, one of them suspicious
+type fixture struct {
+	db      *gorm.DB
+	project schemas.Project
+	mailer  *testutil.FakeMailer
+	clock   *testutil.FakeClock
+	manager *reports.Manager
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").
+		WithUser("new@example.com").WithUser("newer@example.com").WithUser("old@example.com").WithInactiveUser("gone@example.com").
+		Build(t, db)
+	built.Project.Name = "Acme"
+	built.Project.Settings.Reports = schemas.ReportSettings{Schedule: "0 9 * * 1", Recipients: []string{"admin@example.com", "ops@example.com"}}
+	if err := db.Save(&built.Project).Error; err != nil {
+		t.Fatalf("failed to configure reports: %v", err)
+	}
+
+	table := testutil.ProjectUserTable(built.Project.ID)
+	created := map[string]time.Time{
+		"new@example.com":   monday.Add(-2 * 24 * time.Hour),
+		"newer@example.com": monday.Add(-time.Hour),
+		"old@example.com":   monday.Add(-30 * 24 * time.Hour),
+		"gone@example.com":  monday.Add(-30 * 24 * time.Hour),
+	}
+	for email, at := range created {
+		if err := db.Table(table).Where("email = ?", email).Update("created_at", at).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &fixture{db: db, project: built.Project, mailer: testutil.NewFakeMailer(), clock: testutil.NewFakeClock(monday.Add(-time.Minute))}
+	projectID := built.Project.ID
+	for _, row := range []interface{}{
+		&schemas.AuditEvent{ID: uuid.New(), ProjectId: &projectID, Action: audit.ActionStatusChange, ResourceType: audit.ResourceProjectUser,
+			ResourceID: built.Users["gone@example.com"].ID.String(), Details: "active -> deactivated", CreatedAt: monday.Add(-24 * time.Hour)},
+		&schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: projectID, UserId: built.Users["new@example.com"].ID, CreatedAt: monday.Add(-24 * time.Hour)},
+		&schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: projectID, UserId: built.Users["new@example.com"].ID, CreatedAt: monday.Add(-2 * time.Hour)},
+		&schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: projectID, UserId: built.Users["old@example.com"].ID, CreatedAt: monday.Add(-3 * time.Hour), Suspicious: true},
+		// Before the period
+		&schemas.LoginEvent{ID: uuid.New(), Method: "password", ProjectId: projectID, UserId: built.Users["old@example.com"].ID, CreatedAt: monday.Add(-8 * 24 * time.Hour)},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f.manager = f.newManager()
+	return f
+}
+
+// newManager returns a report manager over the fixture, as a restarted
+// service would have
+func (f *fixture) newManager() *reports.Manager {
+	manager := reports.NewManager(f.db, nil, projects.NewManager(f.db, projects.Options{}), f.mailer, reports.Options{}).(*reports.Manager)
+	manager.Clock = f.clock
+	return manager
+}
+
+func (f *fixture) runs(t *testing.T) []schemas.ReportRun {
+	t.Helper()
+	var runs []schemas.ReportRun
+	if err := f.db.Order("scheduled_for").Find(&runs, "project_id = ?", f.project.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func TestScheduledDigest(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	f.manager.RunDue(ctx)
+	if n := len(f.mailer.Messages()); n != 0 {
+		t.Fatalf("%d digests were sent before the schedule fired", n)
+	}
+
+	f.clock.Set(monday.Add(30 * time.Second))
+	f.manager.RunDue(ctx)
+	messages := f.mailer.Messages()
+	if len(messages) != 2 || messages[0].To != "admin@example.com" || messages[1].To != "ops@example.com" {
+		t.Fatalf("messages = %+v, want one to each recipient", messages)
+	}
+	if want := "Acme activity 2026-02-23 - 2026-03-02"; messages[0].Subject != want {
+		t.Errorf("subject = %q, want %q", messages[0].Subject, want)
+	}
+	body := messages[0].Body
+	for _, line := range []string{
+		"from 2026-02-23 09:00 UTC to 2026-03-02 09:00 UTC",
+		"New users:          2",
+		"Deactivated users:  1",
+		"Logins:             3 by 2 users",
+		"Suspicious logins:  1",
+		"Users now:          4, of whom 3 active",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("the digest lacks %q:\n%s", line, body)
+		}
+	}
+
+	runs := f.runs(t)
+	if len(runs) != 1 || !runs[0].ScheduledFor.Equal(monday) || runs[0].Sent != 2 || runs[0].FinishedAt == nil {
+		t.Fatalf("runs = %+v, want the Monday run sent to both recipients", runs)
+	}
+
+	// Neither checking again nor a restart sends the run twice
+	f.clock.Advance(time.Minute)
+	f.manager.RunDue(ctx)
+	f.newManager().RunDue(ctx)
+	if n := len(f.mailer.Messages()); n != 2 {
+		t.Fatalf("%d messages after checking again and restarting, want the first 2", n)
+	}
+
+	// The next digest covers the week since the last one
+	f.clock.Set(monday.Add(7*24*time.Hour + time.Minute))
+	f.manager.RunDue(ctx)
+	messages = f.mailer.Messages()
+	if len(messages) != 4 {
+		t.Fatalf("%d messages after the second Monday, want 4", len(messages))
+	}
+	if want := "Acme activity 2026-03-02 - 2026-03-09"; messages[3].Subject != want {
+		t.Errorf("subject = %q, want %q", messages[3].Subject, want)
+	}
+	if !strings.Contains(messages[3].Body, "New users:          0") {
+		t.Errorf("the second digest counts the first week's sign-ups:\n%s", messages[3].Body)
+	}
+}
+
+func TestMissedDigests(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	// The service was down over Monday and is back within the catch-up window
+	f.clock.Set(monday.Add(reports.DefaultCatchUp - time.Minute))
+	f.manager.RunDue(ctx)
+	if n := len(f.mailer.Messages()); n != 2 {
+		t.Fatalf("%d messages for a digest missed by less than the catch-up window, want 2", n)
+	}
+
+	// It was down over the next Monday for longer than that
+	f.clock.Set(monday.Add(7*24*time.Hour + reports.DefaultCatchUp + time.Minute))
+	f.manager.RunDue(ctx)
+	if n := len(f.mailer.Messages()); n != 2 {
+		t.Fatalf("%d messages after missing a digest by more than the catch-up window, want still 2", n)
+	}
+
+	// The Monday after covers both weeks
+	f.clock.Set(monday.Add(14 * 24 * time.Hour))
+	f.manager.RunDue(ctx)
+	messages := f.mailer.Messages()
+	if len(messages) != 4 {
+		t.Fatalf("%d messages after the third Monday, want 4", len(messages))
+	}
+	if want := "Acme activity 2026-03-02 - 2026-03-16"; messages[3].Subject != want {
+		t.Errorf("subject = %q, want %q", messages[3].Subject, want)
+	}
+}
+
+func TestSendNow(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	// Before the newer sign-up and the last login
+	f.clock.Set(monday.Add(-150 * time.Minute))
+
+	run, err := f.manager.SendNow(ctx, f.project.ID)
+	if err != nil {
+		t.Fatalf("SendNow: %v", err)
+	}
+	if run.Trigger != schemas.ReportTriggerManual || run.Sent != 2 || run.Digest.NewUsers != 1 || run.Digest.Logins != 2 {
+		t.Errorf("run = %+v, want a manual run of the week so far sent to both recipients", run)
+	}
+	if n := len(f.mailer.Messages()); n != 2 {
+		t.Errorf("%d messages, want one to each recipient", n)
+	}
+	var sends int64
+	if err := f.db.Model(&schemas.AuditEvent{}).Where("action = ? AND resource_id = ?", audit.ActionSend, run.ID).Count(&sends).Error; err != nil || sends != 1 {
+		t.Errorf("%d sends were audited (%v), want 1", sends, err)
+	}
+
+	// The schedule still sends the Monday digest, covering the same week
+	f.clock.Set(monday)
+	f.manager.RunDue(ctx)
+	messages := f.mailer.Messages()
+	if len(messages) != 4 || messages[3].Subject != "Acme activity 2026-02-23 - 2026-03-02" {
+		t.Fatalf("messages = %+v, want the scheduled digest after the manual one", messages)
+	}
+
+	f.project.Settings.Reports.Recipients = nil
+	if err := f.db.Save(&f.project).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.newManager().SendNow(ctx, f.project.ID); !errors.Is(err, reports.ErrNoRecipients) {
+		t.Fatalf("SendNow without recipients: err = %v, want %v", err, reports.ErrNoRecipients)
+	}
+}
@@ -0,0 +1,32 @@
+package roles
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// RoleEvent is the data of the role webhook events. Services caching
+// authorization data invalidate what they hold for RoleID on receiving one.
+type RoleEvent struct {
+	RoleID    uuid.UUID  `json:"role_id"`
+	ProjectID *uuid.UUID `json:"project_id"` // Nil for a global role
+	Name      string     `json:"name"`
+	PolicyID  *uuid.UUID `json:"policy_id,omitempty"` // Set for policy_attached and policy_detached
+}
+
+// publish publishes a role event to the role's project, or to every project
+// for a global role
+func (m *Manager) publish(ctx context.Context, event string, role *schemas.Role, policyID *uuid.UUID) {
+	projectID := uuid.Nil
+	if role.ProjectId != nil {
+		projectID = *role.ProjectId
+	}
+	m.Events.Publish(ctx, projectID, event, RoleEvent{
+		RoleID:    role.ID,
+		ProjectID: role.ProjectId,
+		Name:      role.Name,
+		PolicyID:  policyID,
+	})
+}
@@ -0,0 +1,127 @@
+package roles_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+// delivery is a role event received by a subscription's endpoint
+type delivery struct {
+	path    string
+	payload webhooks.RawPayload
+	data    roles.RoleEvent
+}
+
+// receiver starts an endpoint that hands each delivery, with the path it was
+// posted to, to the returned channel
+func receiver(t *testing.T) (*httptest.Server, <-chan delivery) {
+	t.Helper()
+
+	deliveries := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var d delivery
+		d.path = r.URL.Path
+		d.payload.Data = &d.data
+		if err := json.Unmarshal(body, &d.payload); err != nil {
+			t.Errorf("delivery to %s is not a raw payload: %v", r.URL.Path, err)
+		}
+		deliveries <- d
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+// receiveAll waits for n deliveries and then makes sure no more arrive
+func receiveAll(t *testing.T, deliveries <-chan delivery, n int) map[string]delivery {
+	t.Helper()
+
+	byPath := make(map[string]delivery)
+	for i := 0; i < n; i++ {
+		select {
+		case d := <-deliveries:
+			byPath[d.path] = d
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of %d webhooks", i, n)
+		}
+	}
+	select {
+	case d := <-deliveries:
+		t.Errorf("unexpected webhook to %s: %+v", d.path, d.payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+	return byPath
+}
+
+func TestAssigningAPolicyFiresTheWebhook(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	hooks := webhooks.NewManager(db, webhooks.Options{})
+	manager := roles.NewManager(db, nil, hooks, nil, roles.Options{})
+	project := testutil.AProject().Build(t, db).Project
+	other := testutil.AProject().Build(t, db).Project
+	ctx := context.Background()
+
+	server, deliveries := receiver(t)
+	subscribe := func(projectID uuid.UUID, path string, events ...string) {
+		t.Helper()
+		if _, err := hooks.CreateSubscription(ctx, projectID, server.URL+path, events, schemas.WebhookFormatRaw); err != nil {
+			t.Fatalf("CreateSubscription: %v", err)
+		}
+	}
+	subscribe(project.ID, "/attached", webhooks.EventRolePolicyAttached)
+	subscribe(project.ID, "/deleted", webhooks.EventRoleDeleted)
+	subscribe(other.ID, "/other", webhooks.EventRolePolicyAttached)
+
+	role, err := manager.CreateRole(ctx, "Editor", "", time.Hour, nil, &project.ID)
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	viewer := testutil.ARole("Viewer").Build(t, db)
+	policy := testutil.APolicy("read", "users", "read").ForRole(viewer).Build(t, db)
+
+	// A project role's event goes only to its project's subscription for it
+	if err := manager.AssignPolicyToRole(ctx, role.ID, policy.ID); err != nil {
+		t.Fatalf("AssignPolicyToRole: %v", err)
+	}
+	got := receiveAll(t, deliveries, 1)["/attached"]
+	if got.payload.Event != webhooks.EventRolePolicyAttached || got.payload.ProjectID != project.ID.String() {
+		t.Errorf("payload = %+v, want %s for project %s", got.payload, webhooks.EventRolePolicyAttached, project.ID)
+	}
+	if got.data.RoleID != role.ID || got.data.PolicyID == nil || *got.data.PolicyID != policy.ID {
+		t.Errorf("data = %+v, want role %s and policy %s", got.data, role.ID, policy.ID)
+	}
+	if got.data.ProjectID == nil || *got.data.ProjectID != project.ID {
+		t.Errorf("data project = %v, want %s", got.data.ProjectID, project.ID)
+	}
+
+	// A global role's event goes to every project subscribed to it
+	global := testutil.ARole("Support").Build(t, db)
+	if err := manager.AssignPolicyToRole(ctx, global.ID, policy.ID); err != nil {
+		t.Fatalf("AssignPolicyToRole: %v", err)
+	}
+	fanned := receiveAll(t, deliveries, 2)
+	for path, projectID := range map[string]uuid.UUID{"/attached": project.ID, "/other": other.ID} {
+		d, ok := fanned[path]
+		if !ok {
+			t.Errorf("%s got no webhook for the global role", path)
+			continue
+		}
+		if d.payload.ProjectID != projectID.String() || d.data.RoleID != global.ID || d.data.ProjectID != nil {
+			t.Errorf("%s got %+v with %+v, want the global role %s addressed to %s", path, d.payload, d.data, global.ID, projectID)
+		}
+		if d.data.PolicyID == nil || *d.data.PolicyID != policy.ID {
+			t.Errorf("%s got policy %v, want %s", path, d.data.PolicyID, policy.ID)
+		}
+	}
+}
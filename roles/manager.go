@@ -3,126 +3,353 @@ package roles
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/sorting"
+	"github.com/yash3004/user_management_service/webhooks"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
+var log = logging.Named("roles")
+
 type RoleManager interface {
-	CreateRole(ctx context.Context, name, description string,expTime time.Duration) (*schemas.Role, error)
+	CreateRole(ctx context.Context, name, description string, expTime time.Duration, allowedCIDRs []string, projectID *uuid.UUID) (*schemas.Role, error)
 	GetRole(ctx context.Context, id uuid.UUID) (*schemas.Role, error)
-	ListRoles(ctx context.Context) ([]schemas.Role, error)
-	UpdateRole(ctx context.Context, id uuid.UUID, name, description string ,expTime time.Duration) (*schemas.Role, error)
+	GetRoleIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Role, error)
+	GetRoleByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Role, error)
+	ListRoles(ctx context.Context, order sorting.Order) ([]schemas.Role, error)
+	UpdateRole(ctx context.Context, id uuid.UUID, name, description string, expTime time.Duration, allowedCIDRs []string) (*schemas.Role, error)
+	RenameRole(ctx context.Context, id uuid.UUID, name string) (*schemas.Role, error)
 	DeleteRole(ctx context.Context, id uuid.UUID) error
 	AssignPolicyToRole(ctx context.Context, roleID, policyID uuid.UUID) error
 	RemovePolicyFromRole(ctx context.Context, roleID, policyID uuid.UUID) error
 	GetExpirationTime(ctx context.Context, id uuid.UUID) (time.Duration, error)
+	Usage(ctx context.Context, projectID uuid.UUID) ([]RoleUsage, error)
+}
+
+// ErrRoleNotFound is returned when a role does not exist or has been deleted
+var ErrRoleNotFound = apierrors.NotFound("role not found")
+
+// ErrNameIsUUID is returned for a role name that parses as a UUID, which
+// would make fields accepting a role ID or name ambiguous
+var ErrNameIsUUID = apierrors.BadRequest("INVALID_NAME", "role name must not be a UUID")
+
+// ErrNameTaken is returned when renaming a role to the name of another role
+// in its scope: the same project, or the global roles
+var ErrNameTaken = apierrors.New(http.StatusConflict, "ROLE_NAME_TAKEN", "another role in the same scope already has this name")
+
+// SuperAdminRole is the name of the global role allowed everything. It is
+// reserved: no project role may take it.
+const SuperAdminRole = "SuperAdmin"
+
+// ErrNameReserved is returned for a project role named SuperAdminRole, which
+// only the global role may be called
+var ErrNameReserved = apierrors.BadRequest("RESERVED_NAME", "role name "+SuperAdminRole+" is reserved for the global role")
+
+// IsReservedName reports whether only the global role may be called name.
+// Names compare without case, as the database may.
+func IsReservedName(name string) bool {
+	return strings.EqualFold(name, SuperAdminRole)
+}
+
+// ErrNegativeExpiration is returned for a negative role expiration, which
+// would issue the role's users tokens that have already expired
+var ErrNegativeExpiration = apierrors.BadRequest("INVALID_EXPIRATION", "role expiration must not be negative")
+
+// ValidateExpiration checks the token lifetime of a role: it must not be
+// negative, nor longer than max when max is positive
+func ValidateExpiration(expiration, max time.Duration) error {
+	if expiration < 0 {
+		return ErrNegativeExpiration
+	}
+	if max > 0 && expiration > max {
+		return apierrors.BadRequest("INVALID_EXPIRATION", fmt.Sprintf("role expiration must not be longer than %s", max))
+	}
+	return nil
+}
+
+// Options configures a Manager
+type Options struct {
+	// MaxExpiration is the longest expiration a role may have; 0 for no
+	// maximum
+	MaxExpiration time.Duration
 }
 
 type Manager struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Regions  *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Clock    clock.Clock
+	Events   webhooks.Publisher
+	Networks *Networks // Told of roles whose networks change; may be nil
+	Options  Options
 }
 
-func NewManager(db *gorm.DB) RoleManager {
+// NewManager creates a role manager publishing role changes to events, or
+// discarding them when events is nil. networks may be nil when nothing
+// caches the networks of roles.
+func NewManager(db *gorm.DB, regions *regions.Resolver, events webhooks.Publisher, networks *Networks, opts Options) RoleManager {
+	if events == nil {
+		events = webhooks.NopPublisher{}
+	}
 	return &Manager{
-		DB: db,
+		DB:       db,
+		Regions:  regions,
+		Clock:    clock.Real{},
+		Events:   events,
+		Networks: networks,
+		Options:  opts,
 	}
 }
 
-func (m *Manager) CreateRole(ctx context.Context, name, description string, expTime time.Duration) (*schemas.Role, error) {
+// CreateRole creates a role, scoped to a project when projectID is set. Its
+// users may only log in and make requests from allowedCIDRs, or from anywhere
+// when the list is empty.
+func (m *Manager) CreateRole(ctx context.Context, name, description string, expTime time.Duration, allowedCIDRs []string, projectID *uuid.UUID) (*schemas.Role, error) {
+	if _, err := uuid.Parse(name); err == nil {
+		return nil, ErrNameIsUUID
+	}
+	if projectID != nil && IsReservedName(name) {
+		return nil, ErrNameReserved
+	}
+	if err := ValidateExpiration(expTime, m.Options.MaxExpiration); err != nil {
+		return nil, err
+	}
+	allowedCIDRs, err := ValidateCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	if projectID != nil {
+		var project schemas.Project
+		if err := m.DB.First(&project, "id = ?", *projectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("project not found")
+			}
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, errors.New("internal server error")
+		}
+	}
+
 	var existingRole schemas.Role
-	if err := m.DB.Where("name = ?", name).First(&existingRole).Error; err == nil {
-		return nil, errors.New("role with this name already exists")
+	if err := m.DB.Scopes(schemas.InProject(projectID)).Where("name = ?", name).First(&existingRole).Error; err == nil {
+		return nil, apierrors.Conflict("role with this name already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
 	role := schemas.Role{
-		ID:          uuid.New(),
-		Name:        name,
-		Description: description,
-		Expiration:  expTime,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:           uuid.New(),
+		Name:         name,
+		Description:  description,
+		Expiration:   expTime,
+		AllowedCIDRs: allowedCIDRs,
+		ProjectId:    projectID,
+		CreatedBy:    audit.ActorID(ctx),
+		CreatedAt:    m.Clock.Now(),
+		UpdatedAt:    m.Clock.Now(),
 	}
 
 	if err := m.DB.Create(&role).Error; err != nil {
-		klog.Errorf("Failed to create role: %v", err)
+		log.For(ctx).Errorf("Failed to create role: %v", err)
 		return nil, errors.New("failed to create role")
 	}
 
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    role.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceRole,
+		ResourceID:   role.ID.String(),
+		At:           m.Clock.Now(),
+	})
+	m.publish(ctx, webhooks.EventRoleCreated, &role, nil)
+
 	return &role, nil
 }
 
 func (m *Manager) GetRole(ctx context.Context, id uuid.UUID) (*schemas.Role, error) {
+	return m.getRole(m.DB, id)
+}
+
+// GetRoleIncludingDeleted gets a role by ID even if it has been soft-deleted
+func (m *Manager) GetRoleIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.Role, error) {
+	return m.getRole(m.DB.Unscoped(), id)
+}
+
+func (m *Manager) getRole(db *gorm.DB, id uuid.UUID) (*schemas.Role, error) {
 	var role schemas.Role
-	if err := m.DB.First(&role, "id = ?", id).Error; err != nil {
+	if err := db.First(&role, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("role not found")
+			return nil, ErrRoleNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 	return &role, nil
 }
 
-func (m *Manager) ListRoles(ctx context.Context) ([]schemas.Role, error) {
-	var roles []schemas.Role
-	if err := m.DB.Find(&roles).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+// GetRoleByName gets the role with exactly this name among the project's
+// roles, or among the global roles when projectID is nil. The lookup uses the
+// unique name index; since the column may compare without case, the name is
+// checked again so the match is case-sensitive.
+func (m *Manager) GetRoleByName(ctx context.Context, name string, projectID *uuid.UUID) (*schemas.Role, error) {
+	var role schemas.Role
+	if err := m.DB.Scopes(schemas.InProject(projectID)).Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
-	return roles, nil
+	if role.Name != name {
+		return nil, ErrRoleNotFound
+	}
+	return &role, nil
 }
 
-func (m *Manager) UpdateRole(ctx context.Context, id uuid.UUID, name, description string,expirationTime time.Duration) (*schemas.Role, error) {
-	var existingRole schemas.Role
-	if err := m.DB.Where("name = ? AND id != ?", name, id).First(&existingRole).Error; err == nil {
-		return nil, errors.New("another role with this name already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
+// SortFields are the fields ListRoles can sort by
+var SortFields = []string{"name", "created_at", "updated_at"}
+
+// ListRoles lists all roles in order
+func (m *Manager) ListRoles(ctx context.Context, order sorting.Order) ([]schemas.Role, error) {
+	var roles []schemas.Role
+	if err := order.Apply(m.DB).Find(&roles).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
+	return roles, nil
+}
 
+// UpdateRole replaces the name, description, expiration and allowed networks
+// of a role. New networks apply to the next request of every user of the
+// role, as the cached ones are dropped.
+func (m *Manager) UpdateRole(ctx context.Context, id uuid.UUID, name, description string, expirationTime time.Duration, allowedCIDRs []string) (*schemas.Role, error) {
+	if _, err := uuid.Parse(name); err == nil {
+		return nil, ErrNameIsUUID
+	}
+	if err := ValidateExpiration(expirationTime, m.Options.MaxExpiration); err != nil {
+		return nil, err
+	}
+	allowedCIDRs, err := ValidateCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
 	var role schemas.Role
 	if err := m.DB.First(&role, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("role not found")
+			return nil, ErrRoleNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if role.ProjectId != nil && IsReservedName(name) {
+		return nil, ErrNameReserved
+	}
+
+	var existingRole schemas.Role
+	if err := m.DB.Scopes(schemas.InProject(role.ProjectId)).Where("name = ? AND id != ?", name, id).First(&existingRole).Error; err == nil {
+		return nil, apierrors.Conflict("another role with this name already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", err)
 		return nil, errors.New("internal server error")
 	}
 
 	role.Name = name
 	role.Description = description
-	role.UpdatedAt = time.Now()
-	role.Expiration= expirationTime
+	role.UpdatedAt = m.Clock.Now()
+	role.Expiration = expirationTime
+	role.AllowedCIDRs = allowedCIDRs
 
 	if err := m.DB.Save(&role).Error; err != nil {
-		klog.Errorf("Failed to update role: %v", err)
+		log.For(ctx).Errorf("Failed to update role: %v", err)
 		return nil, errors.New("failed to update role")
 	}
+	m.Networks.Invalidate(role.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    role.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceRole,
+		ResourceID:   role.ID.String(),
+		At:           m.Clock.Now(),
+	})
+	m.publish(ctx, webhooks.EventRoleUpdated, &role, nil)
 
 	return &role, nil
 }
 
+// RenameRole changes the name of a role and nothing else. The name must be
+// free among the roles of the role's scope, deleted ones included since
+// their names stay reserved by the unique index.
+func (m *Manager) RenameRole(ctx context.Context, id uuid.UUID, name string) (*schemas.Role, error) {
+	if name == "" {
+		return nil, apierrors.BadRequest("INVALID_NAME", "role name is required")
+	}
+	if _, err := uuid.Parse(name); err == nil {
+		return nil, ErrNameIsUUID
+	}
+	role, err := m.GetRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == name {
+		return role, nil
+	}
+	if role.ProjectId != nil && IsReservedName(name) {
+		return nil, ErrNameReserved
+	}
+
+	var existing schemas.Role
+	if err := m.DB.Unscoped().Scopes(schemas.InProject(role.ProjectId)).Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
+		return nil, ErrNameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	previous := role.Name
+	role.Name = name
+	role.UpdatedAt = m.Clock.Now()
+	if err := m.DB.Model(role).Updates(map[string]interface{}{"name": role.Name, "updated_at": role.UpdatedAt}).Error; err != nil {
+		log.For(ctx).Errorf("Failed to rename role: %v", err)
+		return nil, errors.New("failed to rename role")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    role.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceRole,
+		ResourceID:   role.ID.String(),
+		Details:      "renamed " + previous + " -> " + name,
+		At:           m.Clock.Now(),
+	})
+	m.publish(ctx, webhooks.EventRoleUpdated, role, nil)
+
+	return role, nil
+}
+
 func (m *Manager) DeleteRole(ctx context.Context, id uuid.UUID) error {
 	var role schemas.Role
 	if err := m.DB.First(&role, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("role not found")
+			return ErrRoleNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
 
 	var count int64
 	if err := m.DB.Model(&schemas.User{}).Where("role_id = ?", id).Count(&count).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
 
@@ -130,10 +357,20 @@ func (m *Manager) DeleteRole(ctx context.Context, id uuid.UUID) error {
 		return errors.New("cannot delete role that is assigned to users")
 	}
 
-	if err := m.DB.Delete(&role).Error; err != nil {
-		klog.Errorf("Failed to delete role: %v", err)
+	if err := m.DB.Model(&role).Updates(schemas.SoftDeletion(m.Clock.Now(), audit.ActorID(ctx))).Error; err != nil {
+		log.For(ctx).Errorf("Failed to delete role: %v", err)
 		return errors.New("failed to delete role")
 	}
+	m.Networks.Invalidate(role.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    role.ProjectId,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceRole,
+		ResourceID:   role.ID.String(),
+		At:           m.Clock.Now(),
+	})
+	m.publish(ctx, webhooks.EventRoleDeleted, &role, nil)
 
 	return nil
 }
@@ -142,9 +379,9 @@ func (m *Manager) AssignPolicyToRole(ctx context.Context, roleID, policyID uuid.
 	var role schemas.Role
 	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("role not found")
+			return ErrRoleNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
 
@@ -153,15 +390,16 @@ func (m *Manager) AssignPolicyToRole(ctx context.Context, roleID, policyID uuid.
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("policy not found")
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
 
 	policy.RolesId = roleID
 	if err := m.DB.Save(&policy).Error; err != nil {
-		klog.Errorf("Failed to assign policy to role: %v", err)
+		log.For(ctx).Errorf("Failed to assign policy to role: %v", err)
 		return errors.New("failed to assign policy to role")
 	}
+	m.publish(ctx, webhooks.EventRolePolicyAttached, &role, &policyID)
 
 	return nil
 }
@@ -172,14 +410,24 @@ func (m *Manager) RemovePolicyFromRole(ctx context.Context, roleID, policyID uui
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("policy not found or not assigned to this role")
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
+		return errors.New("internal server error")
+	}
+
+	var role schemas.Role
+	if err := m.DB.Unscoped().First(&role, "id = ?", roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
 		return errors.New("internal server error")
 	}
 
 	if err := m.DB.Model(&policy).Update("roles_id", nil).Error; err != nil {
-		klog.Errorf("Failed to remove policy from role: %v", err)
+		log.For(ctx).Errorf("Failed to remove policy from role: %v", err)
 		return errors.New("failed to remove policy from role")
 	}
+	m.publish(ctx, webhooks.EventRolePolicyDetached, &role, &policyID)
 
 	return nil
 }
@@ -188,10 +436,10 @@ func (m *Manager) GetExpirationTime(ctx context.Context, id uuid.UUID) (time.Dur
 	var role schemas.Role
 	if err := m.DB.First(&role, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, errors.New("role not found")
+			return 0, ErrRoleNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", err)
 		return 0, errors.New("internal server error")
 	}
 	return role.Expiration, nil
-}
\ No newline at end of file
+}
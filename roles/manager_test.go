@@ -0,0 +1,299 @@
+package roles_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/roles"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+// recorder records the events published to it
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Publish(_ context.Context, _ uuid.UUID, event string, _ interface{}) {
+	r.events = append(r.events, event)
+}
+
+func TestCreateRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	events := &recorder{}
+	manager := roles.NewManager(db, nil, events, nil, roles.Options{MaxExpiration: 24 * time.Hour})
+	project := testutil.AProject().Build(t, db).Project
+	ctx := context.Background()
+
+	role, err := manager.CreateRole(ctx, "Editor", "edits", time.Hour, []string{"10.0.0.0/8"}, &project.ID)
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if role.ProjectId == nil || *role.ProjectId != project.ID || role.Expiration != time.Hour {
+		t.Errorf("role = %+v, want a project role expiring after an hour", role)
+	}
+	if len(events.events) != 1 || events.events[0] != webhooks.EventRoleCreated {
+		t.Errorf("events = %v, want %s", events.events, webhooks.EventRoleCreated)
+	}
+
+	// The same name is free among the global roles
+	if _, err := manager.CreateRole(ctx, "Editor", "", 0, nil, nil); err != nil {
+		t.Fatalf("CreateRole of a global role: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		role       string
+		expiration time.Duration
+		cidrs      []string
+		want       error
+	}{
+		{"duplicate name", "Editor", 0, nil, nil},
+		{"name is a UUID", uuid.NewString(), 0, nil, roles.ErrNameIsUUID},
+		{"reserved name", "superadmin", 0, nil, roles.ErrNameReserved},
+		{"negative expiration", "Viewer", -time.Second, nil, roles.ErrNegativeExpiration},
+		{"expiration over the maximum", "Viewer", 48 * time.Hour, nil, nil},
+		{"invalid network", "Viewer", 0, []string{"not a network"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.CreateRole(ctx, tc.role, "", tc.expiration, tc.cidrs, &project.ID)
+			if err == nil {
+				t.Fatal("CreateRole succeeded")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateExpiration(t *testing.T) {
+	const max = 720 * time.Hour
+	for _, tc := range []struct {
+		expiration time.Duration
+		max        time.Duration
+		valid      bool
+	}{
+		{0, max, true},
+		{24 * time.Hour, max, true},
+		{max, max, true},
+		{max + time.Hour, max, false},
+		{-time.Hour, max, false},
+		{10 * max, 0, true},
+		{-time.Nanosecond, 0, false},
+	} {
+		err := roles.ValidateExpiration(tc.expiration, tc.max)
+		if (err == nil) != tc.valid {
+			t.Errorf("ValidateExpiration(%v, %v) = %v, want valid %v", tc.expiration, tc.max, err, tc.valid)
+		}
+	}
+}
+
+func TestUpdateRoleValidatesTheExpiration(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{MaxExpiration: 24 * time.Hour})
+	role := testutil.ARole("Editor").Build(t, db)
+	ctx := context.Background()
+
+	if _, err := manager.UpdateRole(ctx, role.ID, role.Name, "", -time.Hour, nil); !errors.Is(err, roles.ErrNegativeExpiration) {
+		t.Errorf("err = %v, want %v", err, roles.ErrNegativeExpiration)
+	}
+	if _, err := manager.UpdateRole(ctx, role.ID, role.Name, "", 48*time.Hour, nil); err == nil {
+		t.Error("UpdateRole accepted an expiration over the maximum")
+	}
+	updated, err := manager.UpdateRole(ctx, role.ID, role.Name, "", 24*time.Hour, nil)
+	if err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if updated.Expiration != 24*time.Hour {
+		t.Errorf("expiration = %v, want 24h", updated.Expiration)
+	}
+}
+
+func TestGetRoleByNameIsCaseSensitive(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	role := testutil.ARole("Editor").Build(t, db)
+	ctx := context.Background()
+
+	found, err := manager.GetRoleByName(ctx, "Editor", nil)
+	if err != nil || found.ID != role.ID {
+		t.Fatalf("GetRoleByName = %v, %v, want the role", found, err)
+	}
+	if _, err := manager.GetRoleByName(ctx, "editor", nil); !errors.Is(err, roles.ErrRoleNotFound) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+}
+
+func TestRenameRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	editor := testutil.ARole("Editor").Build(t, db)
+	viewer := testutil.ARole("Viewer").Build(t, db)
+	ctx := context.Background()
+
+	if _, err := manager.RenameRole(ctx, editor.ID, "Viewer"); !errors.Is(err, roles.ErrNameTaken) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrNameTaken)
+	}
+	// Deleted roles keep their names reserved
+	if err := manager.DeleteRole(ctx, viewer.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if _, err := manager.RenameRole(ctx, editor.ID, "Viewer"); !errors.Is(err, roles.ErrNameTaken) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrNameTaken)
+	}
+
+	renamed, err := manager.RenameRole(ctx, editor.ID, "Writer")
+	if err != nil {
+		t.Fatalf("RenameRole: %v", err)
+	}
+	if renamed.Name != "Writer" {
+		t.Errorf("name = %q, want Writer", renamed.Name)
+	}
+
+	// Only the name changed
+	var stored schemas.Role
+	if err := db.First(&stored, "id = ?", editor.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Writer" || stored.Description != editor.Description || stored.Expiration != editor.Expiration || !stored.CreatedAt.Equal(editor.CreatedAt) {
+		t.Errorf("stored role = %+v, want only the name of %+v changed", stored, editor)
+	}
+	if again, err := manager.RenameRole(ctx, editor.ID, "Writer"); err != nil || again.Name != "Writer" {
+		t.Errorf("renaming to the current name = %+v, %v, want a no-op", again, err)
+	}
+}
+
+func TestProjectRolesCannotTakeTheSuperAdminName(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	project := testutil.AProject().Build(t, db).Project
+	editor := testutil.ARole("Editor").InProject(project.ID).Build(t, db)
+	ctx := context.Background()
+
+	if _, err := manager.RenameRole(ctx, editor.ID, roles.SuperAdminRole); !errors.Is(err, roles.ErrNameReserved) {
+		t.Errorf("RenameRole: err = %v, want %v", err, roles.ErrNameReserved)
+	}
+	if _, err := manager.UpdateRole(ctx, editor.ID, roles.SuperAdminRole, "", 0, nil); !errors.Is(err, roles.ErrNameReserved) {
+		t.Errorf("UpdateRole: err = %v, want %v", err, roles.ErrNameReserved)
+	}
+	if _, err := manager.CreateRole(ctx, roles.SuperAdminRole, "", 0, nil, nil); err != nil {
+		t.Errorf("CreateRole of the global role: %v", err)
+	}
+}
+
+func TestRenameRoleChecksItsOwnScope(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	first := testutil.AProject().Build(t, db).Project
+	second := testutil.AProject().Build(t, db).Project
+	testutil.ARole("Support").Build(t, db)
+	ctx := context.Background()
+
+	create := func(name string, project schemas.Project) *schemas.Role {
+		t.Helper()
+		role, err := manager.CreateRole(ctx, name, "", time.Hour, nil, &project.ID)
+		if err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		return role
+	}
+	editor, viewer, auditor := create("Editor", first), create("Viewer", first), create("Auditor", second)
+
+	for _, tc := range []struct {
+		name string
+		role *schemas.Role
+		to   string
+		want error
+	}{
+		{"a name taken in the same project", editor, "Viewer", roles.ErrNameTaken},
+		{"a name used in another project", auditor, "Viewer", nil},
+		{"a global role's name", viewer, "Support", nil},
+		{"a UUID", editor, uuid.NewString(), roles.ErrNameIsUUID},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			renamed, err := manager.RenameRole(ctx, tc.role.ID, tc.to)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if err == nil && (renamed.Name != tc.to || renamed.ProjectId == nil || *renamed.ProjectId != *tc.role.ProjectId) {
+				t.Errorf("renamed role = %+v, want %s in its project", renamed, tc.to)
+			}
+		})
+	}
+	if _, err := manager.RenameRole(ctx, uuid.New(), "Anything"); !errors.Is(err, roles.ErrRoleNotFound) {
+		t.Fatalf("renaming an unknown role: err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+}
+
+func TestDeleteRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	events := &recorder{}
+	manager := roles.NewManager(db, nil, events, nil, roles.Options{})
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	held := built.Roles["Member"]
+	testutil.AUser(t, db, "a@example.com", held, built.Project)
+	unused := testutil.ARole("Unused").Build(t, db)
+	ctx := context.Background()
+
+	if err := manager.DeleteRole(ctx, held.ID); err == nil {
+		t.Fatal("DeleteRole deleted a role held by a user")
+	}
+	if err := manager.DeleteRole(ctx, unused.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if _, err := manager.GetRole(ctx, unused.ID); !errors.Is(err, roles.ErrRoleNotFound) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrRoleNotFound)
+	}
+	deleted, err := manager.GetRoleIncludingDeleted(ctx, unused.ID)
+	if err != nil {
+		t.Fatalf("GetRoleIncludingDeleted: %v", err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Error("the role is not marked deleted")
+	}
+	if events.events[len(events.events)-1] != webhooks.EventRoleDeleted {
+		t.Errorf("events = %v, want %s last", events.events, webhooks.EventRoleDeleted)
+	}
+}
+
+func TestAssignPolicyToRole(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	events := &recorder{}
+	manager := roles.NewManager(db, nil, events, nil, roles.Options{})
+	editor := testutil.ARole("Editor").Build(t, db)
+	viewer := testutil.ARole("Viewer").Build(t, db)
+	policy := testutil.APolicy("read", "users", "read").ForRole(viewer).Build(t, db)
+	ctx := context.Background()
+
+	if err := manager.AssignPolicyToRole(ctx, editor.ID, policy.ID); err != nil {
+		t.Fatalf("AssignPolicyToRole: %v", err)
+	}
+	var stored schemas.Policy
+	if err := db.First(&stored, "id = ?", policy.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.RolesId != editor.ID {
+		t.Fatalf("policy role = %v, want %v", stored.RolesId, editor.ID)
+	}
+
+	if err := manager.RemovePolicyFromRole(ctx, viewer.ID, policy.ID); err == nil {
+		t.Fatal("RemovePolicyFromRole removed a policy from a role it is not assigned to")
+	}
+	if len(events.events) != 1 || events.events[0] != webhooks.EventRolePolicyAttached {
+		t.Errorf("events = %v, want %s", events.events, webhooks.EventRolePolicyAttached)
+	}
+}
+
+func TestUsageOfAnUnknownProject(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+
+	if _, err := manager.Usage(context.Background(), uuid.New()); !errors.Is(err, roles.ErrProjectNotFound) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrProjectNotFound)
+	}
+}
@@ -0,0 +1,128 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// ErrIPNotAllowed is returned for a login or request from an address outside
+// the allowed networks of the user's role
+var ErrIPNotAllowed = apierrors.New(http.StatusForbidden, "IP_NOT_ALLOWED", "your role does not allow access from this address")
+
+// ValidateCIDRs checks the allowed networks of a role and returns them in
+// canonical form: host bits cleared, IPv4-mapped addresses unmapped and
+// duplicates dropped. Entries must be CIDR blocks such as 10.0.0.0/8 or
+// 2001:db8::/32; a single address is written as a /32 or /128.
+func ValidateCIDRs(cidrs []string) ([]string, error) {
+	var valid []string
+	seen := make(map[string]bool, len(cidrs))
+	for _, raw := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, apierrors.BadRequest("INVALID_CIDR", "invalid CIDR "+raw+"; use a block such as 10.0.0.0/8 or 2001:db8::/32")
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		canonical := prefix.Masked().String()
+		if !seen[canonical] {
+			seen[canonical] = true
+			valid = append(valid, canonical)
+		}
+	}
+	return valid, nil
+}
+
+// AllowsIP reports whether a role with the given allowed networks may be used
+// from ip. An empty list allows every address; otherwise an address that is
+// missing or does not parse is refused.
+func AllowsIP(cidrs []string, ip string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIP returns ErrIPNotAllowed when the role may not be used from ip
+func CheckIP(role *schemas.Role, ip string) error {
+	if !AllowsIP(role.AllowedCIDRs, ip) {
+		return ErrIPNotAllowed
+	}
+	return nil
+}
+
+// Networks loads the allowed networks of roles for checking requests, read
+// through a cache when it has a TTL. Manager drops a role's entry as soon as
+// it changes or deletes the role, but only in this process, so other
+// instances see the change within a TTL.
+type Networks struct {
+	DB    *gorm.DB
+	cache *cache.TTL[uuid.UUID, []string] // Nil when not caching
+}
+
+// NewNetworks creates a loader caching networks for ttl; 0 disables the cache
+func NewNetworks(db *gorm.DB, ttl time.Duration) *Networks {
+	n := &Networks{DB: db}
+	if ttl > 0 {
+		n.cache = cache.NewTTL[uuid.UUID, []string](ttl)
+	}
+	return n
+}
+
+// Check returns ErrIPNotAllowed when the role may not be used from ip. A role
+// that no longer exists restricts nothing here; the checks of the request's
+// policies refuse it.
+func (n *Networks) Check(ctx context.Context, roleID uuid.UUID, ip string) error {
+	cidrs, ok := []string(nil), false
+	if n.cache != nil {
+		cidrs, ok = n.cache.Get(roleID)
+	}
+	if !ok {
+		var role schemas.Role
+		err := n.DB.WithContext(ctx).Select("id", "allowed_cidrs").First(&role, "id = ?", roleID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return errors.New("internal server error")
+		}
+		cidrs = role.AllowedCIDRs
+		if n.cache != nil {
+			n.cache.Set(roleID, cidrs)
+		}
+	}
+	if !AllowsIP(cidrs, ip) {
+		return ErrIPNotAllowed
+	}
+	return nil
+}
+
+// Invalidate drops the cached networks of a role that changed. It does
+// nothing on a nil loader.
+func (n *Networks) Invalidate(id uuid.UUID) {
+	if n != nil && n.cache != nil {
+		n.cache.Delete(id)
+	}
+}
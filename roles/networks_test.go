@@ -0,0 +1,92 @@
+package roles_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+func TestValidateCIDRs(t *testing.T) {
+	valid, err := roles.ValidateCIDRs([]string{" 10.1.2.3/8 ", "10.0.0.0/8", "2001:db8::1/32", "::ffff:192.168.1.0/120", "203.0.113.7/32"})
+	if err != nil {
+		t.Fatalf("ValidateCIDRs: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.0/24", "203.0.113.7/32"}
+	if !reflect.DeepEqual(valid, want) {
+		t.Errorf("ValidateCIDRs = %v, want %v", valid, want)
+	}
+
+	for _, invalid := range []string{"10.0.0.1", "10.0.0.0/33", "2001:db8::/129", "office"} {
+		if _, err := roles.ValidateCIDRs([]string{invalid}); err == nil {
+			t.Errorf("ValidateCIDRs accepted %q", invalid)
+		}
+	}
+}
+
+func TestAllowsIP(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8", "2001:db8::/32"}
+	for _, tc := range []struct {
+		cidrs   []string
+		ip      string
+		allowed bool
+	}{
+		{nil, "198.51.100.1", true},
+		{nil, "", true},
+		{cidrs, "10.20.30.40", true},
+		{cidrs, "11.0.0.1", false},
+		{cidrs, "2001:db8:1::5", true},
+		{cidrs, "2001:db9::5", false},
+		{cidrs, "::ffff:10.0.0.1", true},
+		{cidrs, "fe80::1%eth0", false},
+		{cidrs, "", false},
+		{cidrs, "not an address", false},
+	} {
+		if got := roles.AllowsIP(tc.cidrs, tc.ip); got != tc.allowed {
+			t.Errorf("AllowsIP(%v, %q) = %v, want %v", tc.cidrs, tc.ip, got, tc.allowed)
+		}
+	}
+}
+
+func TestNetworksSeeRoleChanges(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	const ttl = 200 * time.Millisecond
+	networks := roles.NewNetworks(db, ttl)
+	manager := roles.NewManager(db, nil, nil, networks, roles.Options{})
+	ctx := context.Background()
+	role, err := manager.CreateRole(ctx, "Admin", "", 0, []string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	check := func(ip string) error {
+		t.Helper()
+		return networks.Check(ctx, role.ID, ip)
+	}
+
+	if err := check("192.0.2.1"); !errors.Is(err, roles.ErrIPNotAllowed) {
+		t.Fatalf("err = %v, want %v", err, roles.ErrIPNotAllowed)
+	}
+	// Through the manager, which drops the cached networks at once
+	if _, err := manager.UpdateRole(ctx, role.ID, role.Name, "", 0, []string{"192.0.2.0/24"}); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	if err := check("192.0.2.1"); err != nil {
+		t.Fatalf("right after UpdateRole: %v", err)
+	}
+
+	// As another instance would, bypassing this process's cache
+	if err := db.Model(role).Update("allowed_cidrs", `["10.0.0.0/8"]`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := check("192.0.2.1"); err != nil {
+		t.Errorf("a change made elsewhere applied before the TTL: %v", err)
+	}
+	time.Sleep(ttl + 10*time.Millisecond)
+	if err := check("192.0.2.1"); !errors.Is(err, roles.ErrIPNotAllowed) {
+		t.Errorf("after the TTL: err = %v, want %v", err, roles.ErrIPNotAllowed)
+	}
+}
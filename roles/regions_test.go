@@ -0,0 +1,26 @@
+package roles_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+func TestUsageCountsUsersInTheProjectRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	built := testutil.AProject().InRegion("eu", databases["eu"]).
+		WithRole("Editor").WithUser("a@example.com").WithUser("b@example.com").
+		Build(t, db)
+
+	manager := roles.NewManager(db, resolver, nil, nil, roles.Options{})
+	usage, err := manager.Usage(context.Background(), built.Project.ID)
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if len(usage) != 1 || usage[0].RoleID != built.Roles["Editor"].ID || usage[0].Users != 2 {
+		t.Fatalf("usage = %+v, want the 2 editors of the regional table", usage)
+	}
+}
@@ -0,0 +1,73 @@
+package roles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// ErrProjectNotFound is returned for the usage of a project that does not
+// exist or has been deleted
+var ErrProjectNotFound = apierrors.NotFound("project not found")
+
+// RoleUsage is how many of a project's users hold a role
+type RoleUsage struct {
+	RoleID uuid.UUID
+	Role   *schemas.Role // Nil when the role no longer exists at all
+	Users  int64
+}
+
+// Usage counts the users of a project by role, most held first. Only roles
+// held by at least one user are listed, which may include global roles and
+// deleted ones. Soft-deleted users are not counted.
+func (m *Manager) Usage(ctx context.Context, projectID uuid.UUID) ([]RoleUsage, error) {
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	var counts []struct {
+		RoleID uuid.UUID
+		Users  int64
+	}
+	if err := m.Regions.For(m.DB, project.Region).Table(projecttable.Users(project.ID)).
+		Select("role_id, COUNT(*) AS users").
+		Where("deleted_at IS NULL").
+		Group("role_id").
+		Order("users DESC, role_id").
+		Scan(&counts).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	roleIDs := make([]uuid.UUID, len(counts))
+	for i, c := range counts {
+		roleIDs[i] = c.RoleID
+	}
+	var roles []schemas.Role
+	if len(roleIDs) > 0 {
+		if err := m.DB.Unscoped().Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+			log.For(ctx).Errorf("Database error: %v", err)
+			return nil, errors.New("internal server error")
+		}
+	}
+	byID := make(map[uuid.UUID]*schemas.Role, len(roles))
+	for i := range roles {
+		byID[roles[i].ID] = &roles[i]
+	}
+
+	usage := make([]RoleUsage, len(counts))
+	for i, c := range counts {
+		usage[i] = RoleUsage{RoleID: c.RoleID, Role: byID[c.RoleID], Users: c.Users}
+	}
+	return usage, nil
+}
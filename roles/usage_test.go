@@ -0,0 +1,80 @@
+package roles_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/roles"
+)
+
+func TestUsageMatchesTheSeededDistribution(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().
+		WithRole("Legacy").WithUser("legacy@example.com").
+		WithRole("Admin").WithUser("admin@example.com").
+		WithRole("Member").WithUser("m1@example.com").WithUser("m2@example.com").WithUser("m3@example.com").WithUser("gone@example.com").
+		Build(t, db)
+	// Another project's users are not counted
+	testutil.AProject().WithRole("Member").WithUser("other@example.com").Build(t, db)
+	table := testutil.ProjectUserTable(built.Project.ID)
+
+	// One member moves to a global role, one leaves and the Legacy role is
+	// deleted while still held
+	support := testutil.ARole("Support").Build(t, db)
+	if err := db.Table(table).Where("id = ?", built.Users["m3@example.com"].ID).Update("role_id", support.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Table(table).Where("id = ?", built.Users["gone@example.com"].ID).Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&schemas.Role{}, "id = ?", built.Roles["Legacy"].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	usage, err := manager.Usage(context.Background(), built.Project.ID)
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+
+	want := map[uuid.UUID]int64{
+		built.Roles["Member"].ID: 2,
+		built.Roles["Admin"].ID:  1,
+		built.Roles["Legacy"].ID: 1,
+		support.ID:               1,
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("usage = %+v, want %d roles", usage, len(want))
+	}
+	for _, u := range usage {
+		if u.Users != want[u.RoleID] {
+			t.Errorf("role %s has %d users, want %d", u.RoleID, u.Users, want[u.RoleID])
+		}
+		if u.Role == nil || u.Role.ID != u.RoleID {
+			t.Errorf("role %s was not loaded with its usage", u.RoleID)
+		}
+	}
+	if usage[0].RoleID != built.Roles["Member"].ID {
+		t.Errorf("the first role is %s, want the most held, Member", usage[0].RoleID)
+	}
+	for _, u := range usage {
+		if deleted := u.Role != nil && u.Role.DeletedAt.Valid; deleted != (u.RoleID == built.Roles["Legacy"].ID) {
+			t.Errorf("role %s is deleted %v, want only Legacy deleted", u.RoleID, deleted)
+		}
+	}
+}
+
+func TestUsageOfAProjectWithoutUsers(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager := roles.NewManager(db, nil, nil, nil, roles.Options{})
+	project := testutil.AProject().Build(t, db).Project
+
+	usage, err := manager.Usage(context.Background(), project.ID)
+	if err != nil || len(usage) != 0 {
+		t.Fatalf("Usage = %+v, %v, want nothing", usage, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package tokenkeys encrypts the OAuth tokens stored with users and rotates
+// the key they are encrypted under. Every ciphertext records the ID of the
+// key that made it, so tokens encrypted under a retired key keep decrypting
+// until a rotation re-encrypts them under the primary key.
+package tokenkeys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// prefix starts every stored ciphertext: enc:<key id>:<base64 nonce+sealed>
+const prefix = "enc:"
+
+// keyIDPattern keeps key IDs free of the separator
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrUnknownKey is returned when a value was encrypted under a key that is
+// no longer configured
+var ErrUnknownKey = errors.New("token was encrypted with an unknown key")
+
+// KeyRing holds the primary key, used for all new encryption, and the retired
+// keys still needed to decrypt older values
+type KeyRing struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyRing builds a key ring from base64 encoded 32-byte AES keys indexed by
+// key ID. primary must be one of them. With no keys at all it returns nil,
+// meaning token encryption is not configured.
+func NewKeyRing(primary string, keys map[string]string) (*KeyRing, error) {
+	if len(keys) == 0 && primary == "" {
+		return nil, nil
+	}
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary token key %q is not among the configured keys", primary)
+	}
+
+	ring := &KeyRing{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, encoded := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("token key ID %q may only contain letters, digits, '.', '_' and '-'", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("token key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("token key %q must be 32 bytes, got %d", id, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ring.aeads[id] = aead
+	}
+	return ring, nil
+}
+
+// PrimaryKeyID returns the ID of the key new values are encrypted under
+func (r *KeyRing) PrimaryKeyID() string {
+	return r.primary
+}
+
+// Encrypt encrypts a token under the primary key. The empty string, meaning
+// no token, stays empty.
+func (r *KeyRing) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	aead := r.aeads[r.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(r.primary))
+	return prefix + r.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the token a stored value holds. Values stored before
+// encryption was configured are returned as they are.
+func (r *KeyRing) Decrypt(value string) (string, error) {
+	id, payload, encrypted := split(value)
+	if !encrypted {
+		return value, nil
+	}
+	aead, ok := r.aeads[id]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted token")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("decrypting token with key %q: %w", id, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored value is a token not yet encrypted
+// under the primary key
+func (r *KeyRing) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	id, _, encrypted := split(value)
+	return !encrypted || id != r.primary
+}
+
+// split takes a stored value apart into the key ID and payload
+func split(value string) (id, payload string, encrypted bool) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return "", "", false
+	}
+	id, payload, ok = strings.Cut(rest, ":")
+	if !ok || !keyIDPattern.MatchString(id) {
+		return "", "", false
+	}
+	return id, payload, true
+}
@@ -0,0 +1,172 @@
+package tokenkeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("tokenkeys")
+
+// defaultBatchSize is how many users are re-encrypted per query
+const defaultBatchSize = 500
+
+// ErrNotConfigured is returned by Rotate when no token keys are configured
+var ErrNotConfigured = apierrors.New(http.StatusConflict, "TOKEN_KEYS_NOT_CONFIGURED", "no OAuth token encryption keys are configured")
+
+// TokenKeyManager defines the interface for OAuth token key management
+type TokenKeyManager interface {
+	// Rotate re-encrypts every stored OAuth token that is not yet encrypted
+	// under the primary key
+	Rotate(ctx context.Context) (*RotationResult, error)
+}
+
+// RotationResult reports a rotation
+type RotationResult struct {
+	KeyID   string `json:"key_id"`  // The primary key tokens are now encrypted under
+	Tables  int    `json:"tables"`  // User tables visited
+	Rotated int64  `json:"rotated"` // Users whose tokens were re-encrypted
+	Failed  int64  `json:"failed"`  // Users whose tokens could not be decrypted and were left alone
+}
+
+// Manager implements the TokenKeyManager interface
+type Manager struct {
+	DB        *gorm.DB
+	Regions   *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Keys      *KeyRing          // nil when token encryption is not configured
+	Clock     clock.Clock
+	BatchSize int
+}
+
+// NewManager creates a new token key manager
+func NewManager(db *gorm.DB, regions *regions.Resolver, keys *KeyRing) TokenKeyManager {
+	return &Manager{
+		DB:        db,
+		Regions:   regions,
+		Keys:      keys,
+		Clock:     clock.Real{},
+		BatchSize: defaultBatchSize,
+	}
+}
+
+// tokenRow is the part of a user row a rotation reads and writes. Global and
+// project users share these columns.
+type tokenRow struct {
+	ID           uuid.UUID
+	AccessToken  string
+	RefreshToken string
+}
+
+// userTable is a users table and the database holding it
+type userTable struct {
+	db   *gorm.DB
+	name string
+}
+
+// Rotate walks the global users table and every project's user table, in
+// the database of the project's region, in batches. Soft-deleted users are included, since they can be restored.
+// Each row is updated only if its tokens are unchanged since they were read,
+// so a login storing new tokens meanwhile is never overwritten; the next
+// rotation picks such rows up if needed.
+func (m *Manager) Rotate(ctx context.Context) (*RotationResult, error) {
+	if m.Keys == nil {
+		return nil, ErrNotConfigured
+	}
+
+	var projects []schemas.Project
+	if err := m.DB.Select("id", "region").Find(&projects).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	tables := []userTable{{db: m.DB, name: "users"}}
+	for _, project := range projects {
+		tables = append(tables, userTable{db: m.Regions.For(m.DB, project.Region), name: projecttable.Users(project.ID)})
+	}
+
+	result := &RotationResult{KeyID: m.Keys.PrimaryKeyID()}
+	for _, table := range tables {
+		if err := m.rotateTable(ctx, table, result); err != nil {
+			log.For(ctx).Errorf("Failed to rotate OAuth tokens in %s: %v", table.name, err)
+			return nil, errors.New("internal server error")
+		}
+		result.Tables++
+	}
+
+	log.For(ctx).Infof("Rotated OAuth tokens of %d users to key %s (%d failed)", result.Rotated, result.KeyID, result.Failed)
+	audit.Record(ctx, m.DB, audit.Entry{
+		Action:       audit.ActionRotate,
+		ResourceType: audit.ResourceTokenKey,
+		ResourceID:   result.KeyID,
+		Details:      "rotated stored OAuth tokens",
+		At:           m.Clock.Now(),
+	})
+	return result, nil
+}
+
+func (m *Manager) rotateTable(ctx context.Context, table userTable, result *RotationResult) error {
+	batchSize := m.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var batch []tokenRow
+	return table.db.Table(table.name).
+		Unscoped().
+		Select("id", "access_token", "refresh_token").
+		Where("access_token <> '' OR refresh_token <> ''").
+		Order("id").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for _, row := range batch {
+				if !m.Keys.NeedsRotation(row.AccessToken) && !m.Keys.NeedsRotation(row.RefreshToken) {
+					continue
+				}
+				access, refresh, err := m.reencryptRow(row)
+				if err != nil {
+					log.For(ctx).Warningf("Cannot re-encrypt OAuth tokens of user %s in %s: %v", row.ID, table.name, err)
+					result.Failed++
+					continue
+				}
+				update := table.db.Table(table.name).
+					Where("id = ? AND access_token = ? AND refresh_token = ?", row.ID, row.AccessToken, row.RefreshToken).
+					UpdateColumns(map[string]interface{}{"access_token": access, "refresh_token": refresh})
+				if update.Error != nil {
+					return update.Error
+				}
+				result.Rotated += update.RowsAffected
+			}
+			return nil
+		}).Error
+}
+
+// reencryptRow re-encrypts both tokens of a row under the primary key
+func (m *Manager) reencryptRow(row tokenRow) (access, refresh string, err error) {
+	if access, err = m.reencrypt(row.AccessToken); err != nil {
+		return "", "", err
+	}
+	if refresh, err = m.reencrypt(row.RefreshToken); err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// reencrypt decrypts a stored value and encrypts it under the primary key
+func (m *Manager) reencrypt(value string) (string, error) {
+	if !m.Keys.NeedsRotation(value) {
+		return value, nil
+	}
+	plaintext, err := m.Keys.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return m.Keys.Encrypt(plaintext)
+}
@@ -0,0 +1,178 @@
+package tokenkeys_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	projectusers "github.com/yash3004/user_management_service/project_users"
+	"github.com/yash3004/user_management_service/projects"
+	"github.com/yash3004/user_management_service/tokenkeys"
+	"golang.org/x/oauth2"
+)
+
+// key returns a 32-byte key filled with b, base64 encoded
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func keyRing(t *testing.T, primary string, keys map[string]string) *tokenkeys.KeyRing {
+	t.Helper()
+
+	ring, err := tokenkeys.NewKeyRing(primary, keys)
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	return ring
+}
+
+func TestKeyRing(t *testing.T) {
+	old := keyRing(t, "k1", map[string]string{"k1": key(1)})
+	sealed, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:k1:") || strings.Contains(sealed, "secret") {
+		t.Fatalf("Encrypt = %q, want a k1 ciphertext", sealed)
+	}
+	if again, _ := old.Encrypt("secret"); again == sealed {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+
+	rotated := keyRing(t, "k2", map[string]string{"k1": key(1), "k2": key(2)})
+	if plain, err := rotated.Decrypt(sealed); err != nil || plain != "secret" {
+		t.Fatalf("Decrypt under a retired key = %q, %v", plain, err)
+	}
+	if !rotated.NeedsRotation(sealed) || rotated.NeedsRotation("") {
+		t.Error("NeedsRotation does not single out the k1 ciphertext")
+	}
+	if plain, err := rotated.Decrypt("stored in plain text"); err != nil || plain != "stored in plain text" {
+		t.Errorf("Decrypt of a plain value = %q, %v", plain, err)
+	}
+
+	withoutOld := keyRing(t, "k2", map[string]string{"k2": key(2)})
+	if _, err := withoutOld.Decrypt(sealed); !errors.Is(err, tokenkeys.ErrUnknownKey) {
+		t.Errorf("Decrypt after k1 was removed = %v, want %v", err, tokenkeys.ErrUnknownKey)
+	}
+	// The key ID is authenticated, so a ciphertext relabelled to another key
+	// does not open
+	relabelled := keyRing(t, "k1", map[string]string{"k1": key(1), "k3": key(1)})
+	if _, err := relabelled.Decrypt(strings.Replace(sealed, "enc:k1:", "enc:k3:", 1)); err == nil {
+		t.Error("a ciphertext relabelled to another key decrypted")
+	}
+
+	for name, keys := range map[string]map[string]string{
+		"a short key":              {"k1": base64.StdEncoding.EncodeToString(make([]byte, 16))},
+		"a key that is not base64": {"k1": "not base64!"},
+		"a missing primary":        {"k2": key(2)},
+		"a key ID with a colon":    {"k1": key(1), "k:2": key(2)},
+	} {
+		if _, err := tokenkeys.NewKeyRing("k1", keys); err == nil {
+			t.Errorf("NewKeyRing accepted %s", name)
+		}
+	}
+	if ring, err := tokenkeys.NewKeyRing("", nil); ring != nil || err != nil {
+		t.Errorf("NewKeyRing without keys = %v, %v, want no ring", ring, err)
+	}
+}
+
+func TestRotatedTokensStillDecrypt(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID, roleID := built.Project.ID.String(), built.Roles["Member"].ID
+	projectManager := projects.NewManager(db, projects.Options{})
+	users := func(keys *tokenkeys.KeyRing) projectusers.ProjectUserManager {
+		return projectusers.NewManager(db, nil, projectManager, nil, nil, keys, nil)
+	}
+	ctx := context.Background()
+
+	// A login stores the provider's tokens encrypted under k1
+	k1 := keyRing(t, "k1", map[string]string{"k1": key(1)})
+	info := &oauth.UserInfo{
+		ID: "google-1", Email: "a@example.com", Provider: "google", EmailVerified: true,
+		Token: &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"},
+	}
+	user, err := users(k1).CreateOrUpdateOAuthProjectUser(ctx, projectID, info, roleID)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOAuthProjectUser: %v", err)
+	}
+	userID := uuid.MustParse(user.ID)
+	stored := func() schemas.ProjectUser {
+		t.Helper()
+		var row schemas.ProjectUser
+		if err := db.Table(testutil.ProjectUserTable(built.Project.ID)).First(&row, "id = ?", userID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return row
+	}
+	if row := stored(); !strings.HasPrefix(row.AccessToken, "enc:k1:") || !strings.HasPrefix(row.RefreshToken, "enc:k1:") {
+		t.Fatalf("stored tokens %q and %q, want both encrypted under k1", row.AccessToken, row.RefreshToken)
+	}
+
+	// k2 becomes the primary; the k1 tokens still decrypt
+	k2 := keyRing(t, "k2", map[string]string{"k1": key(1), "k2": key(2)})
+	token, err := users(k2).GetOAuthToken(ctx, projectID, userID)
+	if err != nil || token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Fatalf("GetOAuthToken before the rotation = %+v, %v", token, err)
+	}
+
+	result, err := tokenkeys.NewManager(db, nil, k2).Rotate(ctx)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if result.KeyID != "k2" || result.Rotated != 1 || result.Failed != 0 {
+		t.Fatalf("result = %+v, want the user rotated to k2", result)
+	}
+	if row := stored(); !strings.HasPrefix(row.AccessToken, "enc:k2:") || !strings.HasPrefix(row.RefreshToken, "enc:k2:") {
+		t.Fatalf("stored tokens %q and %q, want both encrypted under k2", row.AccessToken, row.RefreshToken)
+	}
+
+	// Once rotated, k1 can be removed
+	onlyK2 := keyRing(t, "k2", map[string]string{"k2": key(2)})
+	token, err = users(onlyK2).GetOAuthToken(ctx, projectID, userID)
+	if err != nil || token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+		t.Fatalf("GetOAuthToken after the rotation = %+v, %v", token, err)
+	}
+	if again, err := tokenkeys.NewManager(db, nil, onlyK2).Rotate(ctx); err != nil || again.Rotated != 0 {
+		t.Fatalf("a second rotation = %+v, %v, want nothing rotated", again, err)
+	}
+
+	// A later login without a refresh token keeps the stored one
+	info.Token = &oauth2.Token{AccessToken: "access-2"}
+	if _, err := users(onlyK2).CreateOrUpdateOAuthProjectUser(ctx, projectID, info, roleID); err != nil {
+		t.Fatalf("CreateOrUpdateOAuthProjectUser: %v", err)
+	}
+	token, err = users(onlyK2).GetOAuthToken(ctx, projectID, userID)
+	if err != nil || token.AccessToken != "access-2" || token.RefreshToken != "refresh-1" {
+		t.Fatalf("GetOAuthToken after another login = %+v, %v", token, err)
+	}
+}
+
+func TestTokensAreNotStoredWithoutKeys(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	projectID := built.Project.ID.String()
+	users := projectusers.NewManager(db, nil, projects.NewManager(db, projects.Options{}), nil, nil, nil, nil)
+	ctx := context.Background()
+
+	info := &oauth.UserInfo{
+		ID: "google-1", Email: "a@example.com", Provider: "google", EmailVerified: true,
+		Token: &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"},
+	}
+	user, err := users.CreateOrUpdateOAuthProjectUser(ctx, projectID, info, built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOAuthProjectUser: %v", err)
+	}
+	if _, err := users.GetOAuthToken(ctx, projectID, uuid.MustParse(user.ID)); !errors.Is(err, projectusers.ErrNoOAuthToken) {
+		t.Fatalf("GetOAuthToken = %v, want %v", err, projectusers.ErrNoOAuthToken)
+	}
+	if _, err := tokenkeys.NewManager(db, nil, nil).Rotate(ctx); !errors.Is(err, tokenkeys.ErrNotConfigured) {
+		t.Fatalf("Rotate = %v, want %v", err, tokenkeys.ErrNotConfigured)
+	}
+}
@@ -0,0 +1,46 @@
+package tokenkeys_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/tokenkeys"
+)
+
+func TestRotateCoversRegionalTables(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	built := testutil.AProject().InRegion("eu", databases["eu"]).WithOAuthUser("a@example.com", "google").Build(t, db)
+	user := built.Users["a@example.com"]
+	table := databases["eu"].Table(testutil.ProjectUserTable(built.Project.ID))
+	if err := table.Where("id = ?", user.ID).Update("access_token", "plain access token").Error; err != nil {
+		t.Fatalf("failed to store the token: %v", err)
+	}
+
+	keys, err := tokenkeys.NewKeyRing("k1", map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))})
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	result, err := tokenkeys.NewManager(db, resolver, keys).Rotate(context.Background())
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if result.Tables != 2 || result.Rotated != 1 {
+		t.Fatalf("result = %+v, want both tables visited and the regional user rotated", result)
+	}
+
+	var stored schemas.ProjectUser
+	if err := databases["eu"].Table(testutil.ProjectUserTable(built.Project.ID)).First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatalf("failed to read the user back: %v", err)
+	}
+	if strings.Contains(stored.AccessToken, "plain") {
+		t.Fatalf("access token %q was not re-encrypted", stored.AccessToken)
+	}
+	if plain, err := keys.Decrypt(stored.AccessToken); err != nil || plain != "plain access token" {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,140 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"gorm.io/gorm"
+)
+
+// ErrAuthUserNotFound is returned by AuthUsers.Get for a user that does not
+// exist or has been deleted
+var ErrAuthUserNotFound = errors.New("user not found")
+
+// AuthUser is what authenticating a request needs of a global user. The
+// cache holds these rather than whole rows, so that it keeps nothing else.
+type AuthUser struct {
+	ID                uuid.UUID
+	Status            string
+	Type              string
+	RoleId            uuid.UUID
+	ProjectId         uuid.UUID
+	PasswordChangedAt *time.Time
+}
+
+// User returns a user with only the fields of the AuthUser set
+func (u AuthUser) User() schemas.User {
+	user := schemas.User{
+		ID:                u.ID,
+		Type:              u.Type,
+		RoleId:            u.RoleId,
+		ProjectId:         u.ProjectId,
+		PasswordChangedAt: u.PasswordChangedAt,
+	}
+	user.SetStatus(u.Status)
+	return user
+}
+
+// AuthUsers loads the users requests authenticate as, read-through a cache
+// when it has a TTL. Entries live for the TTL at most; Manager drops a
+// user's entry as soon as it changes the user's status, role or password,
+// but only in this process, so other instances see the change within a TTL.
+type AuthUsers struct {
+	DB *gorm.DB
+	// DeactivateOrphaned deactivates the users RoleDeleted is called for,
+	// rather than only leaving them to be denied
+	DeactivateOrphaned bool
+	cache              *cache.TTL[uuid.UUID, AuthUser] // Nil when not caching
+}
+
+// NewAuthUsers creates a loader caching users for ttl; 0 disables the cache
+func NewAuthUsers(db *gorm.DB, ttl time.Duration) *AuthUsers {
+	a := &AuthUsers{DB: db}
+	if ttl > 0 {
+		a.cache = cache.NewTTL[uuid.UUID, AuthUser](ttl)
+	}
+	return a
+}
+
+// Get returns the user with the given ID, failing with ErrAuthUserNotFound
+// when there is none. Users that are not found are not cached.
+func (a *AuthUsers) Get(ctx context.Context, id uuid.UUID) (*AuthUser, error) {
+	if a.cache != nil {
+		if user, ok := a.cache.Get(id); ok {
+			return &user, nil
+		}
+	}
+
+	var row schemas.User
+	if err := a.DB.WithContext(ctx).Select("id", "status", "type", "role_id", "project_id", "password_changed_at").
+		First(&row, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuthUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	user := AuthUser{
+		ID:                row.ID,
+		Status:            row.Status,
+		Type:              row.Type,
+		RoleId:            row.RoleId,
+		ProjectId:         row.ProjectId,
+		PasswordChangedAt: row.PasswordChangedAt,
+	}
+	if a.cache != nil {
+		a.cache.Set(id, user)
+	}
+	return &user, nil
+}
+
+// Invalidate drops the cached entry of a user whose status, role or password
+// changed. It does nothing on a nil loader.
+func (a *AuthUsers) Invalidate(id uuid.UUID) {
+	if a != nil && a.cache != nil {
+		a.cache.Delete(id)
+	}
+}
+
+// RoleDeleted handles a user found holding a global role that no longer
+// exists, which roles deleted with force or reassignment can leave behind.
+// Such users are denied everything; with DeactivateOrphaned they are also
+// deactivated, so that they stop authenticating at all. Failing to
+// deactivate is only logged.
+func (a *AuthUsers) RoleDeleted(ctx context.Context, user schemas.User) {
+	log.For(ctx).Warningf("User %s holds role %s, which no longer exists", user.ID, user.RoleId)
+	if a == nil || !a.DeactivateOrphaned || !userstatus.CanTransition(user.Status, schemas.UserStatusDeactivated) {
+		return
+	}
+
+	previous := user.Status
+	user.SetStatus(schemas.UserStatusDeactivated)
+	now := time.Now()
+	result := a.DB.WithContext(ctx).Model(&schemas.User{}).Where("id = ? AND status = ?", user.ID, previous).
+		Updates(map[string]interface{}{"status": user.Status, "active": user.Active, "updated_at": now})
+	if result.Error != nil {
+		log.For(ctx).Errorf("Failed to deactivate user %s: %v", user.ID, redact.Error(result.Error))
+		return
+	}
+	a.Invalidate(user.ID)
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	log.For(ctx).Infof("Deactivated user %s, whose role %s was deleted", user.ID, user.RoleId)
+	audit.Record(ctx, a.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionStatusChange,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		Details:      previous + " -> " + user.Status + " (role deleted)",
+		At:           now,
+	})
+}
@@ -0,0 +1,151 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/users"
+)
+
+func TestAuthUsersServeRepeatedLookupsFromTheCache(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["member"], built.Project)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name    string
+		ttl     time.Duration
+		queries int64
+	}{
+		{"cached", time.Hour, 1},
+		{"disabled", 0, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			authUsers := users.NewAuthUsers(db, tt.ttl)
+			queries := testutil.CountQueries(t, db)
+			for i := 0; i < 3; i++ {
+				got, err := authUsers.Get(ctx, user.ID)
+				if err != nil {
+					t.Fatalf("Get: %v", err)
+				}
+				want := users.AuthUser{ID: user.ID, Status: user.Status, Type: user.Type, RoleId: user.RoleId, ProjectId: user.ProjectId}
+				if *got != want {
+					t.Fatalf("Get = %+v, want %+v", *got, want)
+				}
+			}
+			if queries.Count() != tt.queries {
+				t.Errorf("3 lookups ran %d queries, want %d", queries.Count(), tt.queries)
+			}
+		})
+	}
+
+	if _, err := users.NewAuthUsers(db, time.Hour).Get(ctx, uuid.New()); !errors.Is(err, users.ErrAuthUserNotFound) {
+		t.Errorf("err = %v, want %v", err, users.ErrAuthUserNotFound)
+	}
+}
+
+func TestChangesThroughTheManagerDropTheCachedUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	authUsers := users.NewAuthUsers(db, time.Hour)
+	manager := users.NewManager(db, nil, nil, "", authUsers)
+	built := testutil.AProject().WithRole("member").WithRole("admin").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["member"], built.Project)
+	ctx := context.Background()
+	cached := func() *users.AuthUser {
+		t.Helper()
+		got, err := authUsers.Get(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		return got
+	}
+	cached()
+
+	if err := manager.AssignRole(ctx, user.ID, built.Roles["admin"].ID); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	if got := cached(); got.RoleId != built.Roles["admin"].ID {
+		t.Errorf("role after AssignRole = %s, want %s", got.RoleId, built.Roles["admin"].ID)
+	}
+
+	if _, err := manager.SetUserStatus(ctx, user.ID, schemas.UserStatusDeactivated); err != nil {
+		t.Fatalf("SetUserStatus: %v", err)
+	}
+	if got := cached(); got.Status != schemas.UserStatusDeactivated {
+		t.Errorf("status after deactivating = %s, want %s", got.Status, schemas.UserStatusDeactivated)
+	}
+
+	if err := manager.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := authUsers.Get(ctx, user.ID); !errors.Is(err, users.ErrAuthUserNotFound) {
+		t.Errorf("Get after deleting: err = %v, want %v", err, users.ErrAuthUserNotFound)
+	}
+}
+
+func TestDeactivationElsewhereTakesEffectWithinOneTTL(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	const ttl = 50 * time.Millisecond
+	authUsers := users.NewAuthUsers(db, ttl)
+	built := testutil.AProject().WithRole("member").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["member"], built.Project)
+	ctx := context.Background()
+
+	if _, err := authUsers.Get(ctx, user.ID); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	// As another instance would, bypassing this process's cache
+	if err := db.Model(&schemas.User{}).Where("id = ?", user.ID).Update("status", schemas.UserStatusDeactivated).Error; err != nil {
+		t.Fatal(err)
+	}
+	deactivatedAt := time.Now()
+
+	for {
+		// Measured before the lookup, so a slow Get is not taken for a stale entry
+		elapsed := time.Since(deactivatedAt)
+		got, err := authUsers.Get(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Status == schemas.UserStatusDeactivated {
+			break
+		}
+		if elapsed > ttl {
+			t.Fatalf("the user was still %s %v after being deactivated, past the TTL of %v", got.Status, elapsed, ttl)
+		}
+		time.Sleep(ttl / 10)
+	}
+}
+
+func BenchmarkAuthUsersGet(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"cached", 5 * time.Second},
+		{"uncached", 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			db := testutil.NewTestDB(b)
+			built := testutil.AProject().WithRole("member").Build(b, db)
+			user := testutil.AUser(b, db, "a@example.com", built.Roles["member"], built.Project)
+			authUsers := users.NewAuthUsers(db, bm.ttl)
+			ctx := context.Background()
+			queries := testutil.CountQueries(b, db)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := authUsers.Get(ctx, user.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries.Count())/float64(b.N), "queries/op")
+		})
+	}
+}
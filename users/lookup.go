@@ -0,0 +1,241 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// Kinds of EmailMatch
+const (
+	MatchUser        = "user"         // A global user, in its primary project
+	MatchMembership  = "membership"   // A global user's access to an additional project
+	MatchProjectUser = "project_user" // A user in a project's own user table
+)
+
+// ErrLookupEmailRequired is returned by LookupEmail without an email
+var ErrLookupEmailRequired = apierrors.BadRequest("EMAIL_REQUIRED", "email is required")
+
+// lookupTablesPerQuery bounds how many user tables one lookup query unions,
+// keeping statements a reasonable size when there are many projects
+const lookupTablesPerQuery = 100
+
+// EmailMatch is a place a user with the looked up email exists
+type EmailMatch struct {
+	Kind        string
+	UserID      uuid.UUID
+	Email       string
+	ProjectID   uuid.UUID
+	ProjectName string // Empty when the project was deleted
+	RoleID      uuid.UUID
+	RoleName    string // Empty when the role was deleted
+	Status      string
+	LastLoginAt *time.Time // Project users only; global user logins are not recorded
+}
+
+// lookupRow is a user found by the lookup query, global or project
+type lookupRow struct {
+	Kind      string
+	ID        uuid.UUID
+	Email     string
+	ProjectId uuid.UUID
+	RoleId    uuid.UUID
+	Status    string
+}
+
+// LookupEmail finds every live user with the email: the global user, its
+// additional project memberships and the users of every project's table.
+// The tables are searched with UNION ALL queries of up to
+// lookupTablesPerQuery tables each, rather than one query per project, in
+// the database of each project's region. The
+// lookup crosses tenants, so it is audited: once with the email, and once
+// for every match, in the match's project.
+func (m *Manager) LookupEmail(ctx context.Context, email string) ([]EmailMatch, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ErrLookupEmailRequired
+	}
+
+	var projects []schemas.Project
+	if err := m.DB.Order("created_at").Find(&projects).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	rows, err := m.lookupRows(email, projects)
+	if err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	matches, err := m.describeMatches(rows, projects)
+	if err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	now := m.Clock.Now()
+	audit.Record(ctx, m.DB, audit.Entry{
+		Action:       audit.ActionLookup,
+		ResourceType: audit.ResourceUser,
+		Details:      fmt.Sprintf("email %s: %d matches", email, len(matches)),
+		At:           now,
+	})
+	for _, match := range matches {
+		projectID := match.ProjectID
+		audit.Record(ctx, m.DB, audit.Entry{
+			ProjectID:    &projectID,
+			Action:       audit.ActionLookup,
+			ResourceType: audit.ResourceUser,
+			ResourceID:   match.UserID.String(),
+			Details:      "found as " + match.Kind + " by a cross-project email lookup",
+			At:           now,
+		})
+	}
+
+	return matches, nil
+}
+
+// lookupRows runs the lookup over the global users table and the user
+// tables of the projects, global users first
+func (m *Manager) lookupRows(email string, projects []schemas.Project) ([]lookupRow, error) {
+	var rows []lookupRow
+	if err := m.DB.Model(&schemas.User{}).
+		Select("? AS kind, id, email, project_id, role_id, status", MatchUser).
+		Where("email = ?", email).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Each query unions tables of one database: the main one, or that of a
+	// region the projects keep their users in
+	var databases []*gorm.DB
+	byDatabase := make(map[*gorm.DB][]schemas.Project)
+	for _, project := range projects {
+		db := m.DB
+		if regional := m.Regions.DB(project.Region); regional != nil {
+			db = regional
+		}
+		if _, ok := byDatabase[db]; !ok {
+			databases = append(databases, db)
+		}
+		byDatabase[db] = append(byDatabase[db], project)
+	}
+
+	for _, db := range databases {
+		projects := byDatabase[db]
+		for start := 0; start < len(projects); start += lookupTablesPerQuery {
+			end := min(start+lookupTablesPerQuery, len(projects))
+			selects := make([]string, 0, end-start)
+			args := make([]interface{}, 0, 2*(end-start))
+			for _, project := range projects[start:end] {
+				table := projecttable.Quote(db, projecttable.Users(project.ID))
+				selects = append(selects, "SELECT ? AS kind, id, email, project_id, role_id, status FROM "+table+" WHERE email = ? AND deleted_at IS NULL")
+				args = append(args, MatchProjectUser, email)
+			}
+			var batch []lookupRow
+			if err := db.Raw(strings.Join(selects, " UNION ALL "), args...).Scan(&batch).Error; err != nil {
+				return nil, err
+			}
+			rows = append(rows, batch...)
+		}
+	}
+	return rows, nil
+}
+
+// describeMatches turns the rows into matches, adding the memberships of the
+// global users, the project and role names, and the last logins
+func (m *Manager) describeMatches(rows []lookupRow, projects []schemas.Project) ([]EmailMatch, error) {
+	var matches []EmailMatch
+	var globalIDs, projectUserIDs []uuid.UUID
+	for _, row := range rows {
+		matches = append(matches, EmailMatch{
+			Kind:      row.Kind,
+			UserID:    row.ID,
+			Email:     row.Email,
+			ProjectID: row.ProjectId,
+			RoleID:    row.RoleId,
+			Status:    row.Status,
+		})
+		if row.Kind == MatchUser {
+			globalIDs = append(globalIDs, row.ID)
+		} else {
+			projectUserIDs = append(projectUserIDs, row.ID)
+		}
+	}
+
+	if len(globalIDs) > 0 {
+		var memberships []schemas.UserProjectMembership
+		if err := m.DB.Where("user_id IN ?", globalIDs).Order("created_at").Find(&memberships).Error; err != nil {
+			return nil, err
+		}
+		for _, membership := range memberships {
+			for _, match := range matches {
+				if match.Kind == MatchUser && match.UserID == membership.UserId {
+					matches = append(matches, EmailMatch{
+						Kind:      MatchMembership,
+						UserID:    match.UserID,
+						Email:     match.Email,
+						ProjectID: membership.ProjectId,
+						RoleID:    membership.RoleId,
+						Status:    match.Status,
+					})
+					break
+				}
+			}
+		}
+	}
+
+	projectNames := make(map[uuid.UUID]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+	roleIDs := make([]uuid.UUID, 0, len(matches))
+	for _, match := range matches {
+		roleIDs = append(roleIDs, match.RoleID)
+	}
+	roleNames := make(map[uuid.UUID]string)
+	if len(roleIDs) > 0 {
+		var roles []schemas.Role
+		if err := m.DB.Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			roleNames[role.ID] = role.Name
+		}
+	}
+
+	lastLogins := make(map[uuid.UUID]time.Time)
+	if len(projectUserIDs) > 0 {
+		// The newest event of each user, selected as a column rather than
+		// as MAX(created_at) so that it scans as a time on every dialect
+		var events []schemas.LoginEvent
+		if err := m.DB.Select("user_id", "created_at").Where("user_id IN ?", projectUserIDs).
+			Where("created_at = (SELECT MAX(created_at) FROM login_events AS latest WHERE latest.user_id = login_events.user_id)").
+			Find(&events).Error; err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			lastLogins[event.UserId] = event.CreatedAt
+		}
+	}
+
+	for i := range matches {
+		matches[i].ProjectName = projectNames[matches[i].ProjectID]
+		matches[i].RoleName = roleNames[matches[i].RoleID]
+		if matches[i].Kind == MatchProjectUser {
+			if at, ok := lastLogins[matches[i].UserID]; ok {
+				matches[i].LastLoginAt = &at
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,89 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/users"
+)
+
+func TestLookupEmailFindsTheGlobalUserAndEveryProjectUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, clock := newManager(t, db)
+	const email = "alice@example.com"
+	home := testutil.AProject().Named("Home").WithRole("Admin").Build(t, db)
+	shop := testutil.AProject().Named("Shop").WithRole("Buyer").WithUser(email).WithUser("bob@example.com").Build(t, db)
+	blog := testutil.AProject().Named("Blog").WithRole("Writer").WithUser(email).Build(t, db)
+	testutil.AProject().Named("Empty").WithRole("Reader").WithUser("carol@example.com").Build(t, db)
+	global := testutil.AUser(t, db, email, home.Roles["Admin"], home.Project)
+	login := schemas.LoginEvent{ID: uuid.New(), Method: "password", CreatedAt: clock.Now(),
+		ProjectId: shop.Project.ID, UserId: shop.Users[email].ID}
+	if err := db.Create(&login).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := manager.LookupEmail(context.Background(), " "+email+" ")
+	if err != nil {
+		t.Fatalf("LookupEmail: %v", err)
+	}
+	want := map[uuid.UUID]users.EmailMatch{
+		global.ID:            {Kind: users.MatchUser, ProjectName: "Home", RoleName: "Admin"},
+		shop.Users[email].ID: {Kind: users.MatchProjectUser, ProjectName: "Shop", RoleName: "Buyer"},
+		blog.Users[email].ID: {Kind: users.MatchProjectUser, ProjectName: "Blog", RoleName: "Writer"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("matches = %+v, want %d", matches, len(want))
+	}
+	for _, match := range matches {
+		expected, ok := want[match.UserID]
+		if !ok || match.Kind != expected.Kind || match.ProjectName != expected.ProjectName || match.RoleName != expected.RoleName || match.Email != email {
+			t.Errorf("match %+v, want %+v", match, expected)
+		}
+		if match.Status != schemas.UserStatusActive {
+			t.Errorf("match %s has status %q, want %q", match.UserID, match.Status, schemas.UserStatusActive)
+		}
+		loggedIn := match.UserID == shop.Users[email].ID
+		if (match.LastLoginAt != nil) != loggedIn || (loggedIn && !match.LastLoginAt.Equal(login.CreatedAt)) {
+			t.Errorf("match %s last logged in at %v", match.UserID, match.LastLoginAt)
+		}
+	}
+
+	var audited int64
+	if err := db.Model(&schemas.AuditEvent{}).Where("action = ?", audit.ActionLookup).Count(&audited).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audited != 1+int64(len(want)) {
+		t.Errorf("the lookup wrote %d audit events, want one for the lookup and one per match", audited)
+	}
+
+	if _, err := manager.LookupEmail(context.Background(), " "); !errors.Is(err, users.ErrLookupEmailRequired) {
+		t.Errorf("err = %v, want %v", err, users.ErrLookupEmailRequired)
+	}
+}
+
+func TestLookupEmailFindsProjectUsersInTheirRegion(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	resolver, databases := testutil.NewTestRegions(t, db, "eu")
+	manager.Regions = resolver
+	const email = "alice@example.com"
+	home := testutil.AProject().Named("Home").WithRole("Buyer").WithUser(email).Build(t, db)
+	eu := testutil.AProject().Named("EU Shop").WithRole("Buyer").WithUser(email).InRegion("eu", databases["eu"]).Build(t, db)
+
+	matches, err := manager.LookupEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("LookupEmail: %v", err)
+	}
+	found := map[uuid.UUID]string{}
+	for _, match := range matches {
+		found[match.UserID] = match.ProjectName
+	}
+	if len(found) != 2 || found[home.Users[email].ID] != "Home" || found[eu.Users[email].ID] != "EU Shop" {
+		t.Errorf("matches = %+v, want the user of Home and the user of EU Shop", matches)
+	}
+}
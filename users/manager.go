@@ -3,132 +3,221 @@ package users
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
 	"github.com/yash3004/user_management_service/auth/oauth"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
 	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/regions"
 	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
 	roleManager "github.com/yash3004/user_management_service/roles"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
-	"k8s.io/klog/v2"
 )
 
+var log = logging.Named("users")
+
+// ErrUserNotFound is returned for a global user that does not exist or was
+// deleted
+var ErrUserNotFound = apierrors.NotFound("user not found")
+
+// ErrRoleOfAnotherProject is returned when giving a user, in its own project
+// or one it is added to, a role scoped to a different project
+var ErrRoleOfAnotherProject = apierrors.BadRequest("ROLE_OF_ANOTHER_PROJECT", "role belongs to another project")
+
 type UserManager interface {
 	CreateUser(ctx context.Context, email, password, firstName, lastName string, roleID, projectID uuid.UUID) (*schemas.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (*schemas.User, error)
+	GetUserIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*schemas.User, error)
-	ListUsers(ctx context.Context) ([]schemas.User, error)
+	ListUsers(ctx context.Context, status, userType string) ([]schemas.User, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, firstName, lastName string, active bool) (*schemas.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	ChangePassword(ctx context.Context, id uuid.UUID, currentPassword, newPassword string) error
+	SetUserStatus(ctx context.Context, id uuid.UUID, status string) (*schemas.User, error)
 	AssignRole(ctx context.Context, userID, roleID uuid.UUID) error
 	CreateOrUpdateOAuthUser(ctx context.Context, userInfo *oauth.UserInfo, projectID uuid.UUID, roleID uuid.UUID) (*models.DisplayUser, error)
+	AddUserToProject(ctx context.Context, userID, projectID, roleID uuid.UUID) (*schemas.UserProjectMembership, error)
+	RemoveUserFromProject(ctx context.Context, userID, projectID uuid.UUID) error
+	ListUserProjects(ctx context.Context, userID uuid.UUID) ([]schemas.UserProjectMembership, error)
+	GetProjectRole(ctx context.Context, userID, projectID uuid.UUID) (uuid.UUID, error)
+	ValidateNewUser(ctx context.Context, email, password string, roleID, projectID uuid.UUID) ([]FieldError, error)
+	LookupEmail(ctx context.Context, email string) ([]EmailMatch, error)
+	CreateServiceAccount(ctx context.Context, projectID uuid.UUID, name string, roleID uuid.UUID) (*schemas.User, error)
+	GetServiceAccount(ctx context.Context, projectID, id uuid.UUID) (*schemas.User, error)
+	ListServiceAccounts(ctx context.Context, projectID uuid.UUID) ([]schemas.User, error)
 }
 
 type Manager struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Clock     clock.Clock
+	Passwords *password.Hasher
+	// EmailScope is schemas.EmailScopeGlobal or schemas.EmailScopeProject
+	EmailScope string
+	// AuthUsers is the loader requests authenticate through; users it may
+	// have cached are dropped from it when they change. Nil when none caches.
+	AuthUsers *AuthUsers
+	// Regions routes the project user tables LookupEmail searches; nil when
+	// they are all in DB
+	Regions *regions.Resolver
 }
 
-func NewManager(db *gorm.DB) UserManager {
+// NewManager creates a new user manager. Passwords may be nil to hash with
+// bcrypt; an empty emailScope means schemas.EmailScopeGlobal. authUsers may
+// be nil when requests do not authenticate through a cache, and regions nil
+// when every project user table is in db.
+func NewManager(db *gorm.DB, regions *regions.Resolver, passwords *password.Hasher, emailScope string, authUsers *AuthUsers) UserManager {
+	if passwords == nil {
+		passwords = password.Default()
+	}
+	if emailScope == "" {
+		emailScope = schemas.EmailScopeGlobal
+	}
 	return &Manager{
-		DB: db,
+		DB:         db,
+		Clock:      clock.Real{},
+		Passwords:  passwords,
+		EmailScope: emailScope,
+		AuthUsers:  authUsers,
+		Regions:    regions,
 	}
 }
 
-func (m *Manager) CreateUser(ctx context.Context, email, password, firstName, lastName string, roleID, projectID uuid.UUID) (*schemas.User, error) {
-	var existingUser schemas.User
-	if err := m.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user with this email already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		klog.Errorf("Database error: %v", err)
-		return nil, errors.New("internal server error")
+// withEmail narrows a users query to the ones whose email clashes with
+// email for a user of projectID under the email scope
+func (m *Manager) withEmail(email string, projectID uuid.UUID) *gorm.DB {
+	query := m.DB.Where("email = ?", email)
+	if m.EmailScope == schemas.EmailScopeProject {
+		query = query.Where("project_id = ?", projectID)
 	}
+	return query
+}
 
-	var role schemas.Role
-	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("role not found")
-		}
-		klog.Errorf("Database error: %v", err)
-		return nil, errors.New("internal server error")
+func (m *Manager) CreateUser(ctx context.Context, email, password, firstName, lastName string, roleID, projectID uuid.UUID) (*schemas.User, error) {
+	if err := m.checkEmailFree(ctx, email, projectID); err != nil {
+		return nil, err
 	}
-
-	var project schemas.Project
-	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("project not found")
-		}
-		klog.Errorf("Database error: %v", err)
-		return nil, errors.New("internal server error")
+	if err := m.checkRoleOf(ctx, roleID, projectID); err != nil {
+		return nil, err
+	}
+	if err := m.checkProjectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if err := m.Passwords.Check(password); err != nil {
+		return nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := m.Passwords.Hash(password)
 	if err != nil {
-		klog.Errorf("Failed to hash password: %v", err)
+		log.For(ctx).Errorf("Failed to hash password: %v", redact.Error(err))
 		return nil, errors.New("failed to process password")
 	}
-	roleManager := roleManager.NewManager(m.DB)
+	roleManager := roleManager.NewManager(m.DB, m.Regions, nil, nil, roleManager.Options{})
 	expirationTimeDuration, err := roleManager.GetExpirationTime(ctx, roleID)
 	if err != nil {
-		klog.Errorf("Failed to get expiration time: %v", err)
+		log.For(ctx).Errorf("Failed to get expiration time: %v", redact.Error(err))
 		return nil, errors.New("failed to get expiration time")
 	}
-	expirationTime := time.Now().Add(expirationTimeDuration)
+	now := m.Clock.Now()
+	expirationTime := now.Add(expirationTimeDuration)
 
 	user := schemas.User{
-		ID:             uuid.New(),
-		Email:          email,
-		Password:       string(hashedPassword),
-		FirstName:      firstName,
-		LastName:       lastName,
-		Active:         true,
-		RoleId:         roleID,
-		ProjectId:      projectID,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-		ExpirationTime: expirationTime,
+		ID:                uuid.New(),
+		Email:             email,
+		Password:          hashedPassword,
+		FirstName:         firstName,
+		LastName:          lastName,
+		Status:            schemas.UserStatusActive,
+		Active:            true,
+		PasswordChangedAt: &now,
+		RoleId:            roleID,
+		ProjectId:         projectID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		ExpirationTime:    expirationTime,
 	}
 
 	if err := m.DB.Create(&user).Error; err != nil {
-		klog.Errorf("Failed to create user: %v", err)
+		// Another user took the email since it was checked
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		log.For(ctx).Errorf("Failed to create user: %v", redact.Error(err))
 		return nil, errors.New("failed to create user")
 	}
 
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
 	return &user, nil
 }
 
 func (m *Manager) GetUser(ctx context.Context, id uuid.UUID) (*schemas.User, error) {
+	return m.getUser(m.DB, id)
+}
+
+// GetUserIncludingDeleted gets a user by ID even if it has been soft-deleted
+func (m *Manager) GetUserIncludingDeleted(ctx context.Context, id uuid.UUID) (*schemas.User, error) {
+	return m.getUser(m.DB.Unscoped(), id)
+}
+
+func (m *Manager) getUser(db *gorm.DB, id uuid.UUID) (*schemas.User, error) {
 	var user schemas.User
-	if err := m.DB.First(&user, "id = ?", id).Error; err != nil {
+	if err := db.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 	return &user, nil
 }
 
-// GetUserByEmail gets a user by email
+// GetUserByEmail gets a user by email. In the project email scope the email
+// may belong to a user in each project; the earliest created is returned.
 func (m *Manager) GetUserByEmail(ctx context.Context, email string) (*schemas.User, error) {
 	var user schemas.User
-	if err := m.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := m.DB.Where("email = ?", email).Order("created_at").First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 	return &user, nil
 }
 
-// ListUsers lists all users
-func (m *Manager) ListUsers(ctx context.Context) ([]schemas.User, error) {
+// ListUsers lists all users, only those in status if it is set. Only human
+// users are listed unless userType asks for schemas.UserTypeService.
+func (m *Manager) ListUsers(ctx context.Context, status, userType string) ([]schemas.User, error) {
+	switch userType {
+	case "":
+		userType = schemas.UserTypeHuman
+	case schemas.UserTypeHuman, schemas.UserTypeService:
+	default:
+		return nil, ErrUnknownUserType
+	}
+	query := m.DB.Where("type = ?", userType)
+	if status != "" {
+		if !userstatus.Valid(status) {
+			return nil, userstatus.ErrUnknownStatus
+		}
+		query = query.Where("status = ?", status)
+	}
+
 	var users []schemas.User
-	if err := m.DB.Find(&users).Error; err != nil {
-		klog.Errorf("Database error: %v", err)
+	if err := query.Find(&users).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 	return users, nil
@@ -138,21 +227,37 @@ func (m *Manager) UpdateUser(ctx context.Context, id uuid.UUID, firstName, lastN
 	var user schemas.User
 	if err := m.DB.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return nil, errors.New("internal server error")
 	}
 
+	// The legacy active flag moves the user between active and deactivated
+	if status := userstatus.ApplyActive(user.Status, active); status != user.Status {
+		if err := userstatus.Check(user.Status, status); err != nil {
+			return nil, err
+		}
+		user.SetStatus(status)
+	}
+
 	user.FirstName = firstName
 	user.LastName = lastName
-	user.Active = active
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = m.Clock.Now()
 
 	if err := m.DB.Save(&user).Error; err != nil {
-		klog.Errorf("Failed to update user: %v", err)
+		log.For(ctx).Errorf("Failed to update user: %v", redact.Error(err))
 		return nil, errors.New("failed to update user")
 	}
+	m.AuthUsers.Invalidate(user.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
 
 	return &user, nil
 }
@@ -162,16 +267,39 @@ func (m *Manager) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	var user schemas.User
 	if err := m.DB.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return errors.New("internal server error")
 	}
 
-	if err := m.DB.Delete(&user).Error; err != nil {
-		klog.Errorf("Failed to delete user: %v", err)
+	// A service account's API tokens go with it
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(schemas.SoftDeletion(m.Clock.Now(), audit.ActorID(ctx))).Error; err != nil {
+			return err
+		}
+		if user.IsServiceAccount() {
+			return revokeServiceAccountTokens(tx, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.For(ctx).Errorf("Failed to delete user: %v", redact.Error(err))
 		return errors.New("failed to delete user")
 	}
+	m.AuthUsers.Invalidate(user.ID)
+
+	if err := m.DB.Where("user_id = ?", id).Delete(&schemas.UserProjectMembership{}).Error; err != nil {
+		log.For(ctx).Errorf("Failed to delete project memberships: %v", redact.Error(err))
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		At:           m.Clock.Now(),
+	})
 
 	return nil
 }
@@ -180,40 +308,102 @@ func (m *Manager) ChangePassword(ctx context.Context, id uuid.UUID, currentPassw
 	var user schemas.User
 	if err := m.DB.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return errors.New("internal server error")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+	if user.IsServiceAccount() {
+		return ErrServiceAccountPassword
+	}
+	if ok, _ := m.Passwords.Verify(user.Password, currentPassword); !ok {
 		return errors.New("current password is incorrect")
 	}
+	if err := m.Passwords.Check(newPassword); err != nil {
+		return err
+	}
+	if err := m.checkNotReused(&user, newPassword); err != nil {
+		return err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := m.Passwords.Hash(newPassword)
 	if err != nil {
-		klog.Errorf("Failed to hash password: %v", err)
+		log.For(ctx).Errorf("Failed to hash password: %v", redact.Error(err))
 		return errors.New("failed to process password")
 	}
 
-	user.Password = string(hashedPassword)
-	user.UpdatedAt = time.Now()
+	previous := user.Password
+	now := m.Clock.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
 
-	if err := m.DB.Save(&user).Error; err != nil {
-		klog.Errorf("Failed to update password: %v", err)
+	err = m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return m.rememberPassword(tx, user.ID, previous)
+	})
+	if err != nil {
+		log.For(ctx).Errorf("Failed to update password: %v", redact.Error(err))
 		return errors.New("failed to update password")
 	}
+	m.AuthUsers.Invalidate(user.ID)
 
 	return nil
 }
 
+// SetUserStatus moves a user to another status. Changes the lifecycle does
+// not allow fail with a conflict.
+func (m *Manager) SetUserStatus(ctx context.Context, id uuid.UUID, status string) (*schemas.User, error) {
+	var user schemas.User
+	if err := m.DB.First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	previous := user.Status
+	if err := userstatus.Check(previous, status); err != nil {
+		return nil, err
+	}
+	user.SetStatus(status)
+	user.UpdatedAt = m.Clock.Now()
+
+	// The status is only changed if no one else changed it meanwhile
+	result := m.DB.Model(&schemas.User{}).Where("id = ? AND status = ?", user.ID, previous).
+		Updates(map[string]interface{}{"status": user.Status, "active": user.Active, "updated_at": user.UpdatedAt})
+	if result.Error != nil {
+		log.For(ctx).Errorf("Failed to update user status: %v", redact.Error(result.Error))
+		return nil, errors.New("failed to update user status")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apierrors.Conflict("user status was changed concurrently")
+	}
+	m.AuthUsers.Invalidate(user.ID)
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &user.ProjectId,
+		Action:       audit.ActionStatusChange,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.ID.String(),
+		Details:      previous + " -> " + status,
+		At:           m.Clock.Now(),
+	})
+
+	return &user, nil
+}
+
 func (m *Manager) AssignRole(ctx context.Context, userID, roleID uuid.UUID) error {
 	var user schemas.User
 	if err := m.DB.First(&user, "id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return errors.New("internal server error")
 	}
 
@@ -222,17 +412,199 @@ func (m *Manager) AssignRole(ctx context.Context, userID, roleID uuid.UUID) erro
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("role not found")
 		}
-		klog.Errorf("Database error: %v", err)
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
 		return errors.New("internal server error")
 	}
+	if role.ProjectId != nil && *role.ProjectId != user.ProjectId {
+		return ErrRoleOfAnotherProject
+	}
 
 	user.RoleId = roleID
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = m.Clock.Now()
 
 	if err := m.DB.Save(&user).Error; err != nil {
-		klog.Errorf("Failed to assign role to user: %v", err)
+		log.For(ctx).Errorf("Failed to assign role to user: %v", redact.Error(err))
 		return errors.New("failed to assign role to user")
 	}
+	m.AuthUsers.Invalidate(user.ID)
 
 	return nil
 }
+
+// AddUserToProject grants a user access to an additional project with the
+// given role. Adding a user to a project it already belongs to updates the role.
+func (m *Manager) AddUserToProject(ctx context.Context, userID, projectID, roleID uuid.UUID) (*schemas.UserProjectMembership, error) {
+	var user schemas.User
+	if err := m.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	if user.ProjectId == projectID {
+		return nil, errors.New("project is already the user's primary project")
+	}
+
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("project not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	var role schemas.Role
+	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+	if role.ProjectId != nil && *role.ProjectId != projectID {
+		return nil, ErrRoleOfAnotherProject
+	}
+
+	var membership schemas.UserProjectMembership
+	err := m.DB.First(&membership, "user_id = ? AND project_id = ?", userID, projectID).Error
+	switch {
+	case err == nil:
+		membership.RoleId = roleID
+		membership.UpdatedAt = m.Clock.Now()
+		if err := m.DB.Save(&membership).Error; err != nil {
+			log.For(ctx).Errorf("Failed to update project membership: %v", redact.Error(err))
+			return nil, errors.New("failed to add user to project")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		membership = schemas.UserProjectMembership{
+			UserId:    userID,
+			ProjectId: projectID,
+			RoleId:    roleID,
+			CreatedAt: m.Clock.Now(),
+			UpdatedAt: m.Clock.Now(),
+		}
+		if err := m.DB.Create(&membership).Error; err != nil {
+			log.For(ctx).Errorf("Failed to create project membership: %v", redact.Error(err))
+			return nil, errors.New("failed to add user to project")
+		}
+	default:
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   userID.String(),
+		Details:      "added to project with role " + roleID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return &membership, nil
+}
+
+// RemoveUserFromProject revokes a user's access to an additional project.
+// The primary project cannot be removed this way.
+func (m *Manager) RemoveUserFromProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	var user schemas.User
+	if err := m.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+
+	if user.ProjectId == projectID {
+		return errors.New("cannot remove user from its primary project")
+	}
+
+	result := m.DB.Where("user_id = ? AND project_id = ?", userID, projectID).Delete(&schemas.UserProjectMembership{})
+	if result.Error != nil {
+		log.For(ctx).Errorf("Failed to delete project membership: %v", redact.Error(result.Error))
+		return errors.New("failed to remove user from project")
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotProjectMember
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   userID.String(),
+		Details:      "removed from project",
+		At:           m.Clock.Now(),
+	})
+
+	return nil
+}
+
+// ListUserProjects lists every project a user can access, starting with the
+// primary project
+func (m *Manager) ListUserProjects(ctx context.Context, userID uuid.UUID) ([]schemas.UserProjectMembership, error) {
+	var user schemas.User
+	if err := m.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	var memberships []schemas.UserProjectMembership
+	if err := m.DB.Where("user_id = ?", userID).Order("created_at").Find(&memberships).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return nil, errors.New("internal server error")
+	}
+
+	primary := schemas.UserProjectMembership{
+		UserId:    user.ID,
+		ProjectId: user.ProjectId,
+		RoleId:    user.RoleId,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+
+	return append([]schemas.UserProjectMembership{primary}, memberships...), nil
+}
+
+// GetProjectRole returns the role a user holds in a project, either through
+// its primary project or a membership row
+func (m *Manager) GetProjectRole(ctx context.Context, userID, projectID uuid.UUID) (uuid.UUID, error) {
+	var user schemas.User
+	if err := m.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrUserNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return uuid.Nil, errors.New("internal server error")
+	}
+
+	return ProjectRole(ctx, m.DB, &user, projectID)
+}
+
+// ErrNotProjectMember is returned when a user has no access to a project
+var ErrNotProjectMember = errors.New("user is not a member of this project")
+
+// ProjectRole resolves the role held by an already loaded user in a project
+func ProjectRole(ctx context.Context, db *gorm.DB, user *schemas.User, projectID uuid.UUID) (uuid.UUID, error) {
+	if user.ProjectId == projectID {
+		return user.RoleId, nil
+	}
+
+	var membership schemas.UserProjectMembership
+	if err := db.First(&membership, "user_id = ? AND project_id = ?", user.ID, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrNotProjectMember
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return uuid.Nil, errors.New("internal server error")
+	}
+
+	return membership.RoleId, nil
+}
@@ -0,0 +1,276 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/users"
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newManager returns a user manager on db with a fake clock, hashing with
+// the cheapest bcrypt cost and requiring passwords of 8 characters
+func newManager(t *testing.T, db *gorm.DB) (*users.Manager, *testutil.FakeClock) {
+	t.Helper()
+
+	passwords, err := password.New(password.Config{BcryptCost: 4, MinLength: 8})
+	if err != nil {
+		t.Fatalf("failed to create the hasher: %v", err)
+	}
+	manager := users.NewManager(db, nil, passwords, "", nil).(*users.Manager)
+	clock := testutil.NewFakeClock(now)
+	manager.Clock = clock
+	return manager, clock
+}
+
+func TestCreateUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	role := built.Roles["Member"]
+	other := testutil.AProject().Build(t, db).Project
+	otherRole := testutil.ARole("Admin").InProject(other.ID).Build(t, db)
+	ctx := context.Background()
+
+	user, err := manager.CreateUser(ctx, "a@example.com", "long enough", "Ann", "Lee", role.ID, built.Project.ID)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.Status != schemas.UserStatusActive || !user.Active {
+		t.Errorf("status = %q, active = %v, want an active user", user.Status, user.Active)
+	}
+	if user.PasswordChangedAt == nil || !user.PasswordChangedAt.Equal(now) {
+		t.Errorf("password changed at %v, want %v", user.PasswordChangedAt, now)
+	}
+	if user.Password == "long enough" {
+		t.Error("the password was stored in plain text")
+	}
+
+	for _, tc := range []struct {
+		name     string
+		email    string
+		password string
+		roleID   uuid.UUID
+		want     error
+	}{
+		{"duplicate email", "a@example.com", "long enough", role.ID, users.ErrEmailTaken},
+		{"weak password", "b@example.com", "short", role.ID, password.ErrTooShort},
+		{"unknown role", "c@example.com", "long enough", uuid.New(), nil},
+		{"role of another project", "d@example.com", "long enough", otherRole.ID, users.ErrRoleOfAnotherProject},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.CreateUser(ctx, tc.email, tc.password, "", "", tc.roleID, built.Project.ID)
+			if err == nil {
+				t.Fatal("CreateUser succeeded")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, clock := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["Member"], built.Project)
+	ctx := context.Background()
+
+	if err := manager.ChangePassword(ctx, user.ID, "wrong password", "new password"); err == nil {
+		t.Fatal("ChangePassword accepted a wrong current password")
+	}
+	if err := manager.ChangePassword(ctx, user.ID, testutil.DefaultPassword, "short"); !errors.Is(err, password.ErrTooShort) {
+		t.Fatalf("err = %v, want %v", err, password.ErrTooShort)
+	}
+
+	clock.Advance(time.Hour)
+	if err := manager.ChangePassword(ctx, user.ID, testutil.DefaultPassword, "new password"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	changed, err := manager.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if changed.PasswordChangedAt == nil || !changed.PasswordChangedAt.Equal(clock.Now()) {
+		t.Errorf("password changed at %v, want %v", changed.PasswordChangedAt, clock.Now())
+	}
+
+	if err := manager.ChangePassword(ctx, user.ID, "new password", testutil.DefaultPassword); !errors.Is(err, users.ErrPasswordReused) {
+		t.Fatalf("err = %v, want %v", err, users.ErrPasswordReused)
+	}
+}
+
+func TestSetUserStatus(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["Member"], built.Project)
+	ctx := context.Background()
+
+	suspended, err := manager.SetUserStatus(ctx, user.ID, schemas.UserStatusSuspended)
+	if err != nil {
+		t.Fatalf("SetUserStatus: %v", err)
+	}
+	if suspended.Status != schemas.UserStatusSuspended || suspended.Active {
+		t.Errorf("status = %q, active = %v, want an inactive suspended user", suspended.Status, suspended.Active)
+	}
+	if _, err := manager.SetUserStatus(ctx, user.ID, schemas.UserStatusInvited); err == nil {
+		t.Fatal("SetUserStatus moved a suspended user back to invited")
+	}
+	if _, err := manager.SetUserStatus(ctx, user.ID, "unknown"); err == nil {
+		t.Fatal("SetUserStatus accepted an unknown status")
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	other := testutil.AProject().WithRole("Viewer").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["Member"], built.Project)
+	ctx := context.Background()
+
+	if _, err := manager.AddUserToProject(ctx, user.ID, other.Project.ID, other.Roles["Viewer"].ID); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+	if err := manager.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := manager.GetUser(ctx, user.ID); err == nil {
+		t.Error("GetUser found a deleted user")
+	}
+	if _, err := manager.GetUserIncludingDeleted(ctx, user.ID); err != nil {
+		t.Errorf("GetUserIncludingDeleted: %v", err)
+	}
+	var memberships int64
+	if err := db.Model(&schemas.UserProjectMembership{}).Where("user_id = ?", user.ID).Count(&memberships).Error; err != nil {
+		t.Fatal(err)
+	}
+	if memberships != 0 {
+		t.Errorf("%d memberships are left", memberships)
+	}
+}
+
+func TestProjectMemberships(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	other := testutil.AProject().WithRole("Viewer").WithRole("Editor").Build(t, db)
+	user := testutil.AUser(t, db, "a@example.com", built.Roles["Member"], built.Project)
+	ctx := context.Background()
+
+	if _, err := manager.GetProjectRole(ctx, user.ID, other.Project.ID); !errors.Is(err, users.ErrNotProjectMember) {
+		t.Fatalf("err = %v, want %v", err, users.ErrNotProjectMember)
+	}
+	if _, err := manager.AddUserToProject(ctx, user.ID, built.Project.ID, built.Roles["Member"].ID); err == nil {
+		t.Fatal("AddUserToProject added the user to its primary project")
+	}
+	scoped := built.Roles["Member"]
+	scoped.ProjectId = &built.Project.ID
+	if err := db.Save(&scoped).Error; err != nil {
+		t.Fatalf("failed to scope the role to the project: %v", err)
+	}
+	if _, err := manager.AddUserToProject(ctx, user.ID, other.Project.ID, scoped.ID); !errors.Is(err, users.ErrRoleOfAnotherProject) {
+		t.Fatalf("err = %v, want %v", err, users.ErrRoleOfAnotherProject)
+	}
+
+	if _, err := manager.AddUserToProject(ctx, user.ID, other.Project.ID, other.Roles["Viewer"].ID); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+	// Adding again updates the role
+	if _, err := manager.AddUserToProject(ctx, user.ID, other.Project.ID, other.Roles["Editor"].ID); err != nil {
+		t.Fatalf("AddUserToProject: %v", err)
+	}
+	role, err := manager.GetProjectRole(ctx, user.ID, other.Project.ID)
+	if err != nil || role != other.Roles["Editor"].ID {
+		t.Fatalf("GetProjectRole = %v, %v, want the Editor role", role, err)
+	}
+	memberships, err := manager.ListUserProjects(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListUserProjects: %v", err)
+	}
+	if len(memberships) != 2 || memberships[0].ProjectId != built.Project.ID {
+		t.Fatalf("memberships = %+v, want the primary project then the other", memberships)
+	}
+
+	if err := manager.RemoveUserFromProject(ctx, user.ID, other.Project.ID); err != nil {
+		t.Fatalf("RemoveUserFromProject: %v", err)
+	}
+	if err := manager.RemoveUserFromProject(ctx, user.ID, other.Project.ID); !errors.Is(err, users.ErrNotProjectMember) {
+		t.Fatalf("err = %v, want %v", err, users.ErrNotProjectMember)
+	}
+	if err := manager.RemoveUserFromProject(ctx, user.ID, built.Project.ID); err == nil {
+		t.Fatal("RemoveUserFromProject removed the primary project")
+	}
+}
+
+func TestEmailScope(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T, scope string) (*gorm.DB, *users.Manager, *testutil.BuiltProject, *testutil.BuiltProject) {
+		t.Helper()
+		db := testutil.NewTestDB(t)
+		if err := internal.MigrateEmailScope(db, scope); err != nil {
+			t.Fatalf("MigrateEmailScope(%s): %v", scope, err)
+		}
+		manager, _ := newManager(t, db)
+		manager.EmailScope = scope
+		return db, manager, testutil.AProject().WithRole("Member").Build(t, db), testutil.AProject().WithRole("Member").Build(t, db)
+	}
+	create := func(manager *users.Manager, email string, in *testutil.BuiltProject) error {
+		_, err := manager.CreateUser(ctx, email, "long enough", "", "", in.Roles["Member"].ID, in.Project.ID)
+		return err
+	}
+
+	t.Run("global", func(t *testing.T) {
+		db, manager, first, second := setup(t, schemas.EmailScopeGlobal)
+		if err := create(manager, "a@example.com", first); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		if err := create(manager, "a@example.com", second); !errors.Is(err, users.ErrEmailTaken) {
+			t.Fatalf("the email in another project: err = %v, want %v", err, users.ErrEmailTaken)
+		}
+		// The index holds even for writes that skip the manager's check
+		duplicate := testutil.AUser(t, db, "b@example.com", first.Roles["Member"], first.Project)
+		duplicate.ID, duplicate.ProjectId, duplicate.RoleId = uuid.New(), second.Project.ID, second.Roles["Member"].ID
+		if err := db.Create(&duplicate).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("inserting the email in another project: err = %v, want a duplicate key", err)
+		}
+	})
+
+	t.Run("project", func(t *testing.T) {
+		db, manager, first, second := setup(t, schemas.EmailScopeProject)
+		for _, in := range []*testutil.BuiltProject{first, second} {
+			if err := create(manager, "a@example.com", in); err != nil {
+				t.Fatalf("CreateUser in project %s: %v", in.Project.ID, err)
+			}
+		}
+		if err := create(manager, "a@example.com", first); !errors.Is(err, users.ErrEmailTaken) {
+			t.Fatalf("the email again in the same project: err = %v, want %v", err, users.ErrEmailTaken)
+		}
+		var count int64
+		if err := db.Model(&schemas.User{}).Where("email = ?", "a@example.com").Count(&count).Error; err != nil || count != 2 {
+			t.Fatalf("%d users have the email (%v), want 2", count, err)
+		}
+
+		// Back to global fails while the email is shared, keeping the
+		// per-project index
+		if err := internal.MigrateEmailScope(db, schemas.EmailScopeGlobal); err == nil {
+			t.Fatal("switching to the global scope with a shared email succeeded")
+		}
+		migrator := db.Migrator()
+		if !migrator.HasIndex(&schemas.User{}, schemas.UserProjectEmailIndex) || migrator.HasIndex(&schemas.User{}, schemas.UserEmailIndex) {
+			t.Error("the failed switch changed the indexes")
+		}
+	})
+}
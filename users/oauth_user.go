@@ -3,55 +3,70 @@ package users
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/yash3004/user_management_service/auth/oauth"
 	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/redact"
 	"github.com/yash3004/user_management_service/internal/schemas"
-	"k8s.io/klog/v2"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"gorm.io/gorm"
 )
 
-// CreateOrUpdateOAuthUser creates or updates a user from OAuth provider information
+// CreateOrUpdateOAuthUser creates or updates a user from OAuth provider information.
+// Names too long to store are cut; an ID or email too long fails with
+// oauth.ErrFieldTooLong.
 func (m *Manager) CreateOrUpdateOAuthUser(ctx context.Context, userInfo *oauth.UserInfo, projectID uuid.UUID, roleID uuid.UUID) (*models.DisplayUser, error) {
+	if err := userInfo.Fit(schemas.UserEmailSize); err != nil {
+		log.For(ctx).Errorf("OAuth user from %s does not fit: %v", userInfo.Provider, err)
+		return nil, oauth.ErrFieldTooLong
+	}
+
 	// Check if user with the same email already exists
 	var existingUser schemas.User
-	if err := m.DB.Where("email = ?", userInfo.Email).First(&existingUser).Error; err == nil {
+	if err := m.withEmail(userInfo.Email, projectID).First(&existingUser).Error; err == nil {
+		// Emails are unique, so an unverified one cannot get a separate user
+		if !userInfo.EmailVerified {
+			return nil, oauth.ErrEmailNotVerified
+		}
+
 		// User exists, update OAuth information
 		existingUser.FirstName = userInfo.FirstName
 		existingUser.LastName = userInfo.LastName
-		existingUser.UpdatedAt = time.Now()
+		existingUser.UpdatedAt = m.Clock.Now()
 
 		if err := m.DB.Save(&existingUser).Error; err != nil {
-			klog.Errorf("Failed to update user: %v", err)
+			log.For(ctx).Errorf("Failed to update user: %v", redact.Error(err))
 			return nil, errors.New("failed to update user")
 		}
 
 		// Return the updated user
 		return &models.DisplayUser{
-			ID:        existingUser.ID.String(),
-			Email:     existingUser.Email,
-			FirstName: existingUser.FirstName,
-			LastName:  existingUser.LastName,
-			Active:    existingUser.Active,
-			RoleID:    existingUser.RoleId.String(),
-			ProjectID: existingUser.ProjectId.String(),
-			CreatedAt: existingUser.CreatedAt,
-			UpdatedAt: existingUser.UpdatedAt,
+			ID:          existingUser.ID.String(),
+			Email:       existingUser.Email,
+			FirstName:   existingUser.FirstName,
+			LastName:    existingUser.LastName,
+			Active:      existingUser.Active,
+			Status:      existingUser.Status,
+			Transitions: userstatus.Next(existingUser.Status),
+			RoleID:      existingUser.RoleId.String(),
+			ProjectID:   existingUser.ProjectId.String(),
+			CreatedAt:   existingUser.CreatedAt,
+			UpdatedAt:   existingUser.UpdatedAt,
 		}, nil
 	}
 
 	// Check if project exists
 	var project schemas.Project
 	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
-		klog.Errorf("Project not found: %v", err)
+		log.For(ctx).Errorf("Project not found: %v", redact.Error(err))
 		return nil, errors.New("project not found")
 	}
 
 	// Check if role exists
 	var role schemas.Role
 	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
-		klog.Errorf("Role not found: %v", err)
+		log.For(ctx).Errorf("Role not found: %v", redact.Error(err))
 		return nil, errors.New("role not found")
 	}
 
@@ -61,29 +76,34 @@ func (m *Manager) CreateOrUpdateOAuthUser(ctx context.Context, userInfo *oauth.U
 		Email:     userInfo.Email,
 		FirstName: userInfo.FirstName,
 		LastName:  userInfo.LastName,
+		Status:    schemas.UserStatusActive,
 		Active:    true,
 		RoleId:    roleID,
 		ProjectId: projectID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: m.Clock.Now(),
+		UpdatedAt: m.Clock.Now(),
 	}
 
 	if err := m.DB.Create(&newUser).Error; err != nil {
-		klog.Errorf("Failed to create user: %v", err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		log.For(ctx).Errorf("Failed to create user: %v", redact.Error(err))
 		return nil, errors.New("failed to create user")
 	}
 
 	// Return the created user
 	return &models.DisplayUser{
-		ID:        newUser.ID.String(),
-		Email:     newUser.Email,
-		FirstName: newUser.FirstName,
-		LastName:  newUser.LastName,
-		Active:    newUser.Active,
-		RoleID:    newUser.RoleId.String(),
-		ProjectID: newUser.ProjectId.String(),
-		CreatedAt: newUser.CreatedAt,
-		UpdatedAt: newUser.UpdatedAt,
+		ID:          newUser.ID.String(),
+		Email:       newUser.Email,
+		FirstName:   newUser.FirstName,
+		LastName:    newUser.LastName,
+		Active:      newUser.Active,
+		Status:      newUser.Status,
+		Transitions: userstatus.Next(newUser.Status),
+		RoleID:      newUser.RoleId.String(),
+		ProjectID:   newUser.ProjectId.String(),
+		CreatedAt:   newUser.CreatedAt,
+		UpdatedAt:   newUser.UpdatedAt,
 	}, nil
 }
-
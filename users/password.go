@@ -0,0 +1,85 @@
+package users
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// ErrPasswordReused is returned by ChangePassword for a new password that is
+// one of the user's latest ones
+var ErrPasswordReused = password.ErrReused
+
+// PasswordExpiry returns when the user's password expires: its maximum age,
+// from the settings of the user's primary project or else from passwords,
+// after it was last changed. The zero time means it never expires, as for
+// service accounts, which have no password.
+func PasswordExpiry(db *gorm.DB, passwords *password.Hasher, user *schemas.User) (time.Time, error) {
+	if user.IsServiceAccount() {
+		return time.Time{}, nil
+	}
+	var project schemas.Project
+	if err := db.First(&project, "id = ?", user.ProjectId).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Errorf("Database error: %v", redact.Error(err))
+		return time.Time{}, errors.New("internal server error")
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return passwords.Expiry(changedAt, project.Settings.Passwords.MaxAgeDays), nil
+}
+
+// checkNotReused fails with ErrPasswordReused when newPassword matches the
+// user's current password or one of the hashes kept in their history, up to
+// the configured history size in all
+func (m *Manager) checkNotReused(user *schemas.User, newPassword string) error {
+	size := m.Passwords.HistorySize()
+	if size == 0 {
+		return nil
+	}
+
+	var history []string
+	if err := m.DB.Model(&schemas.PasswordHistory{}).Where("user_id = ?", user.ID).
+		Order("created_at DESC").Limit(size-1).Pluck("hash", &history).Error; err != nil {
+		log.Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	if m.Passwords.Reused(newPassword, append([]string{user.Password}, history...)...) {
+		return ErrPasswordReused
+	}
+	return nil
+}
+
+// rememberPassword adds a replaced password hash to the user's history and
+// drops the entries beyond what the history size needs
+func (m *Manager) rememberPassword(tx *gorm.DB, userID uuid.UUID, hash string) error {
+	keep := m.Passwords.HistorySize() - 1
+	if keep <= 0 || hash == "" {
+		return nil
+	}
+	if err := tx.Create(&schemas.PasswordHistory{
+		ID:        uuid.New(),
+		UserId:    userID,
+		Hash:      hash,
+		CreatedAt: m.Clock.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	var ids []uuid.UUID
+	if err := tx.Model(&schemas.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("created_at DESC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) <= keep {
+		return nil
+	}
+	return tx.Where("id IN ?", ids[keep:]).Delete(&schemas.PasswordHistory{}).Error
+}
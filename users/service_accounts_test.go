@@ -0,0 +1,124 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/password"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/users"
+)
+
+func TestListUsersLeavesServiceAccountsOutUnlessAsked(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	human := testutil.AUser(t, db, "a@example.com", built.Roles["Member"], built.Project)
+	ctx := context.Background()
+
+	account, err := manager.CreateServiceAccount(ctx, built.Project.ID, " ci ", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	if !account.IsServiceAccount() || account.FirstName != "ci" || account.Email != "" || account.Password != "" {
+		t.Errorf("service account = %+v, want one named ci without an email or password", account)
+	}
+	// No email is stored, so a second account never collides on it
+	if _, err := manager.CreateServiceAccount(ctx, built.Project.ID, "deploy", built.Roles["Member"].ID); err != nil {
+		t.Fatalf("CreateServiceAccount of a second account: %v", err)
+	}
+
+	for _, tc := range []struct {
+		userType string
+		want     int
+	}{
+		{"", 1},
+		{schemas.UserTypeHuman, 1},
+		{schemas.UserTypeService, 2},
+	} {
+		listed, err := manager.ListUsers(ctx, "", tc.userType)
+		if err != nil {
+			t.Fatalf("ListUsers(%q): %v", tc.userType, err)
+		}
+		if len(listed) != tc.want {
+			t.Errorf("ListUsers(%q) listed %d users, want %d", tc.userType, len(listed), tc.want)
+		}
+		for _, user := range listed {
+			if user.IsServiceAccount() != (tc.userType == schemas.UserTypeService) {
+				t.Errorf("ListUsers(%q) listed %s, a user of type %q", tc.userType, user.ID, user.Type)
+			}
+		}
+	}
+	if _, err := manager.ListUsers(ctx, "", "robot"); !errors.Is(err, users.ErrUnknownUserType) {
+		t.Errorf("ListUsers of an unknown type: err = %v, want %v", err, users.ErrUnknownUserType)
+	}
+
+	if _, err := manager.GetServiceAccount(ctx, built.Project.ID, human.ID); !errors.Is(err, users.ErrServiceAccountNotFound) {
+		t.Errorf("GetServiceAccount of a human user: err = %v, want %v", err, users.ErrServiceAccountNotFound)
+	}
+	if _, err := manager.GetServiceAccount(ctx, uuid.New(), account.ID); !errors.Is(err, users.ErrServiceAccountNotFound) {
+		t.Errorf("GetServiceAccount in another project: err = %v, want %v", err, users.ErrServiceAccountNotFound)
+	}
+	if _, err := manager.CreateServiceAccount(ctx, built.Project.ID, "  ", built.Roles["Member"].ID); !errors.Is(err, users.ErrServiceAccountNameRequired) {
+		t.Errorf("CreateServiceAccount without a name: err = %v, want %v", err, users.ErrServiceAccountNameRequired)
+	}
+}
+
+func TestServiceAccountsAreExemptFromPasswords(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	ctx := context.Background()
+	account, err := manager.CreateServiceAccount(ctx, built.Project.ID, "ci", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+
+	if err := manager.ChangePassword(ctx, account.ID, "", "long enough"); !errors.Is(err, users.ErrServiceAccountPassword) {
+		t.Errorf("ChangePassword: err = %v, want %v", err, users.ErrServiceAccountPassword)
+	}
+
+	passwords, err := password.New(password.Config{BcryptCost: 4, MaxAgeDays: 1})
+	if err != nil {
+		t.Fatalf("failed to create the hasher: %v", err)
+	}
+	expiry, err := users.PasswordExpiry(db, passwords, account)
+	if err != nil {
+		t.Fatalf("PasswordExpiry: %v", err)
+	}
+	if !expiry.IsZero() {
+		t.Errorf("the password of a service account expires at %v, want never", expiry)
+	}
+}
+
+func TestDeleteServiceAccountRevokesItsTokens(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	ctx := context.Background()
+	account, err := manager.CreateServiceAccount(ctx, built.Project.ID, "ci", built.Roles["Member"].ID)
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	issued := schemas.ProjectAPIToken{ID: uuid.New(), Name: "ci", Prefix: "ums_ci", TokenHash: "account", ProjectId: built.Project.ID, ServiceAccountId: &account.ID}
+	projectOnly := schemas.ProjectAPIToken{ID: uuid.New(), Name: "backend", Prefix: "ums_be", TokenHash: "project", ProjectId: built.Project.ID}
+	for _, token := range []*schemas.ProjectAPIToken{&issued, &projectOnly} {
+		if err := db.Create(token).Error; err != nil {
+			t.Fatalf("failed to create token %s: %v", token.Name, err)
+		}
+	}
+
+	if err := manager.DeleteUser(ctx, account.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	var left []schemas.ProjectAPIToken
+	if err := db.Find(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != projectOnly.ID {
+		t.Errorf("tokens left = %+v, want only the project's own", left)
+	}
+}
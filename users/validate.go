@@ -0,0 +1,110 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/redact"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"gorm.io/gorm"
+)
+
+// ErrEmailTaken is returned when creating a user with the email of another
+// live user within the email scope
+var ErrEmailTaken = apierrors.Conflict("user with this email already exists")
+
+// Errors of the checks CreateUser and ValidateNewUser share. CreateUser
+// returns them as they are; ValidateNewUser reports them per field.
+var (
+	errRoleNotFound    = errors.New("role not found")
+	errProjectNotFound = errors.New("project not found")
+)
+
+// FieldError is a field of a new user that CreateUser would reject
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidateNewUser runs the checks CreateUser makes, without creating
+// anything, and lists every field that would be rejected. An empty list means
+// CreateUser would accept the user as it stands; the email could still be
+// taken before it is called.
+func (m *Manager) ValidateNewUser(ctx context.Context, email, password string, roleID, projectID uuid.UUID) ([]FieldError, error) {
+	var fields []FieldError
+
+	switch err := m.checkEmailFree(ctx, email, projectID); {
+	case errors.Is(err, ErrEmailTaken):
+		fields = append(fields, FieldError{Field: "email", Code: "EMAIL_TAKEN", Message: err.Error()})
+	case err != nil:
+		return nil, err
+	}
+
+	var apiErr *apierrors.Error
+	if err := m.Passwords.Check(password); errors.As(err, &apiErr) {
+		fields = append(fields, FieldError{Field: "password", Code: apiErr.Code, Message: apiErr.Message})
+	}
+
+	switch err := m.checkRoleOf(ctx, roleID, projectID); {
+	case errors.Is(err, errRoleNotFound):
+		fields = append(fields, FieldError{Field: "role_id", Code: "ROLE_NOT_FOUND", Message: err.Error()})
+	case errors.As(err, &apiErr):
+		fields = append(fields, FieldError{Field: "role_id", Code: apiErr.Code, Message: apiErr.Message})
+	case err != nil:
+		return nil, err
+	}
+
+	switch err := m.checkProjectExists(ctx, projectID); {
+	case errors.Is(err, errProjectNotFound):
+		fields = append(fields, FieldError{Field: "project_id", Code: "PROJECT_NOT_FOUND", Message: err.Error()})
+	case err != nil:
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// checkEmailFree fails with ErrEmailTaken when a user's email would clash
+// with email for a new user of projectID
+func (m *Manager) checkEmailFree(ctx context.Context, email string, projectID uuid.UUID) error {
+	var existingUser schemas.User
+	if err := m.withEmail(email, projectID).First(&existingUser).Error; err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	return nil
+}
+
+// checkRoleOf fails unless roleID is a global role or one of projectID's
+// roles, which a user of projectID may hold
+func (m *Manager) checkRoleOf(ctx context.Context, roleID, projectID uuid.UUID) error {
+	var role schemas.Role
+	if err := m.DB.First(&role, "id = ?", roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errRoleNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	if role.ProjectId != nil && *role.ProjectId != projectID {
+		return ErrRoleOfAnotherProject
+	}
+	return nil
+}
+
+func (m *Manager) checkProjectExists(ctx context.Context, projectID uuid.UUID) error {
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errProjectNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", redact.Error(err))
+		return errors.New("internal server error")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package users_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+)
+
+func TestValidateNewUser(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	manager, _ := newManager(t, db)
+	built := testutil.AProject().WithRole("Member").Build(t, db)
+	testutil.AUser(t, db, "taken@example.com", built.Roles["Member"], built.Project)
+	role, project := built.Roles["Member"].ID, built.Project.ID
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name      string
+		email     string
+		password  string
+		roleID    uuid.UUID
+		projectID uuid.UUID
+		want      []string
+	}{
+		{"valid", "new@example.com", "long enough", role, project, nil},
+		{"duplicate email", "taken@example.com", "long enough", role, project, []string{"email:EMAIL_TAKEN"}},
+		{"weak password", "new@example.com", "short", role, project, []string{"password:PASSWORD_TOO_SHORT"}},
+		{"everything wrong", "taken@example.com", "short", uuid.New(), uuid.New(), []string{
+			"email:EMAIL_TAKEN", "password:PASSWORD_TOO_SHORT", "role_id:ROLE_NOT_FOUND", "project_id:PROJECT_NOT_FOUND",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := manager.ValidateNewUser(ctx, tc.email, tc.password, tc.roleID, tc.projectID)
+			if err != nil {
+				t.Fatalf("ValidateNewUser: %v", err)
+			}
+			var got []string
+			for _, f := range fields {
+				got = append(got, f.Field+":"+f.Code)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fields = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&schemas.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d users exist after validating, want only the one built", count)
+	}
+}
@@ -0,0 +1,83 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/descope/virtualwebauthn"
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/webauthn"
+)
+
+// passkey is a virtual authenticator holding one ES256 credential. It
+// answers the service's ceremony options the way a browser and a platform
+// authenticator would, for the relying party the options name.
+type passkey struct {
+	rp            virtualwebauthn.RelyingParty
+	authenticator virtualwebauthn.Authenticator
+	credential    virtualwebauthn.Credential
+}
+
+func newPasskey(origin string) *passkey {
+	return &passkey{
+		rp:            virtualwebauthn.RelyingParty{Origin: origin},
+		authenticator: virtualwebauthn.NewAuthenticator(),
+		credential:    virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2),
+	}
+}
+
+// aaguid is the authenticator's model
+func (p *passkey) aaguid() uuid.UUID {
+	return uuid.UUID(p.authenticator.Aaguid)
+}
+
+// register answers registration options with an attestation
+func (p *passkey) register(t *testing.T, options *webauthn.CreationOptions) webauthn.AttestationResponse {
+	t.Helper()
+
+	parsed, err := virtualwebauthn.ParseAttestationOptions(encode(t, options))
+	if err != nil {
+		t.Fatalf("invalid creation options: %v", err)
+	}
+	p.rp.ID, p.rp.Name = parsed.RelyingPartyID, parsed.RelyingPartyName
+	p.authenticator.Options.UserHandle = []byte(parsed.UserID)
+
+	var response webauthn.AttestationResponse
+	decode(t, virtualwebauthn.CreateAttestationResponse(p.rp, p.authenticator, p.credential, *parsed), &response)
+	return response
+}
+
+// login answers login options with a signed assertion, advancing the
+// signature counter by step
+func (p *passkey) login(t *testing.T, options *webauthn.RequestOptions, step uint32) webauthn.AssertionResponse {
+	t.Helper()
+
+	parsed, err := virtualwebauthn.ParseAssertionOptions(encode(t, options))
+	if err != nil {
+		t.Fatalf("invalid request options: %v", err)
+	}
+	p.rp.ID = parsed.RelyingPartyID
+	p.credential.Counter += step
+
+	var response webauthn.AssertionResponse
+	decode(t, virtualwebauthn.CreateAssertionResponse(p.rp, p.authenticator, p.credential, *parsed), &response)
+	return response
+}
+
+func encode(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func decode(t *testing.T, data string, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// user presents a project user to the library, with the passkeys a ceremony
+// may use
+type user struct {
+	id          uuid.UUID
+	name        string
+	displayName string
+	credentials []gowebauthn.Credential
+}
+
+// newUser presents a project user for registration
+func newUser(projectUser *schemas.ProjectUser) *user {
+	displayName := strings.TrimSpace(projectUser.FirstName + " " + projectUser.LastName)
+	if displayName == "" {
+		displayName = projectUser.Email
+	}
+	return &user{id: projectUser.ID, name: projectUser.Email, displayName: displayName}
+}
+
+// WebAuthnID is the user handle, the user's ID
+func (u *user) WebAuthnID() []byte {
+	return u.id[:]
+}
+
+// WebAuthnName is the user's email
+func (u *user) WebAuthnName() string {
+	return u.name
+}
+
+// WebAuthnDisplayName is the user's full name, or email when it has none
+func (u *user) WebAuthnDisplayName() string {
+	return u.displayName
+}
+
+// WebAuthnCredentials lists the passkeys the ceremony may use
+func (u *user) WebAuthnCredentials() []gowebauthn.Credential {
+	return u.credentials
+}
+
+// encodeID is the stored form of a credential ID
+func encodeID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// libraryCredential converts a stored passkey to the library's form. The
+// flags of passkeys stored before they were kept are taken from the response
+// being verified, current.
+func libraryCredential(cred *schemas.WebAuthnCredential, current protocol.AuthenticatorFlags) (gowebauthn.Credential, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cred.CredentialID)
+	if err != nil {
+		return gowebauthn.Credential{}, err
+	}
+	flags := current
+	if cred.Flags != nil {
+		flags = protocol.AuthenticatorFlags(*cred.Flags)
+	}
+	transports := make([]protocol.AuthenticatorTransport, 0, len(cred.Transports))
+	for _, transport := range cred.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+	return gowebauthn.Credential{
+		ID:        id,
+		PublicKey: cred.PublicKey,
+		Transport: transports,
+		Flags:     gowebauthn.NewCredentialFlags(flags),
+		Authenticator: gowebauthn.Authenticator{
+			AAGUID:    cred.AAGUID[:],
+			SignCount: cred.SignCount,
+		},
+	}, nil
+}
@@ -0,0 +1,519 @@
+// Package webauthn implements passkey (WebAuthn Level 2) registration and
+// login for project users. Each project is its own relying party, configured
+// through its settings. The ceremonies are verified by the go-webauthn
+// library; this package keeps the pending ceremonies and the passkeys.
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/cache"
+	"github.com/yash3004/user_management_service/internal/clock"
+	"github.com/yash3004/user_management_service/internal/logging"
+	"github.com/yash3004/user_management_service/internal/models"
+	"github.com/yash3004/user_management_service/internal/projecttable"
+	"github.com/yash3004/user_management_service/internal/regions"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/userstatus"
+	"github.com/yash3004/user_management_service/logins"
+	"gorm.io/gorm"
+)
+
+var log = logging.Named("webauthn")
+
+// CeremonyTimeout is how long a begun registration or login stays valid
+const CeremonyTimeout = 5 * time.Minute
+
+// Client data types for the two ceremonies
+const (
+	ceremonyCreate = protocol.CreateCeremony
+	ceremonyGet    = protocol.AssertCeremony
+)
+
+var (
+	// ErrDisabled is returned when the project has not configured passkeys
+	ErrDisabled = apierrors.New(http.StatusForbidden, "WEBAUTHN_DISABLED", "passkeys are not configured for this project")
+
+	// ErrUnknownChallenge is returned when a response answers no pending ceremony
+	ErrUnknownChallenge = apierrors.BadRequest("UNKNOWN_CHALLENGE", "webauthn challenge is unknown or has expired")
+
+	// ErrCredentialNotFound is returned for credentials that do not exist in the project
+	ErrCredentialNotFound = apierrors.NotFound("credential not found")
+)
+
+// Session is the result of a passkey login
+type Session struct {
+	Token     string
+	ExpiresAt time.Time
+	User      models.DisplayUser
+}
+
+// WebAuthnManager defines the interface for passkey enrollment and login
+type WebAuthnManager interface {
+	BeginRegistration(ctx context.Context, projectID, userID uuid.UUID) (*CreationOptions, error)
+	FinishRegistration(ctx context.Context, projectID, userID uuid.UUID, name string, response AttestationResponse) (*schemas.WebAuthnCredential, error)
+	BeginLogin(ctx context.Context, projectID uuid.UUID) (*RequestOptions, error)
+	FinishLogin(ctx context.Context, projectID uuid.UUID, response AssertionResponse) (*Session, error)
+	ListCredentials(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.WebAuthnCredential, error)
+	RenameCredential(ctx context.Context, projectID, userID, id uuid.UUID, name string) (*schemas.WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, projectID, userID, id uuid.UUID) error
+}
+
+// ceremony is a begun registration or login awaiting its response
+type ceremony struct {
+	kind      protocol.CeremonyType
+	projectID uuid.UUID
+	userID    uuid.UUID // Registration only
+	session   gowebauthn.SessionData
+}
+
+// Manager implements the WebAuthnManager interface
+type Manager struct {
+	DB      *gorm.DB
+	Regions *regions.Resolver // Routes the project users tables; nil keeps them in DB
+	Clock   clock.Clock
+	Logins  logins.LoginRecorder
+
+	mu         sync.Mutex
+	ceremonies *cache.TTL[string, ceremony] // Keyed by base64url challenge
+}
+
+// NewManager creates a new WebAuthn manager
+func NewManager(db *gorm.DB, regions *regions.Resolver, recorder logins.LoginRecorder) WebAuthnManager {
+	return &Manager{
+		DB:         db,
+		Regions:    regions,
+		Clock:      clock.Real{},
+		Logins:     recorder,
+		ceremonies: cache.NewTTL[string, ceremony](CeremonyTimeout),
+	}
+}
+
+// relyingParty configures the library as the project's relying party,
+// failing when its passkey settings are incomplete
+func (m *Manager) relyingParty(ctx context.Context, projectID uuid.UUID) (*gowebauthn.WebAuthn, error) {
+	var project schemas.Project
+	if err := m.DB.First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.NotFound("project not found")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	settings := project.Settings.WebAuthn
+	if !settings.Enabled() {
+		return nil, ErrDisabled
+	}
+	if settings.RPName == "" {
+		settings.RPName = project.Settings.Branding.DisplayName
+	}
+	if settings.RPName == "" {
+		settings.RPName = project.Name
+	}
+
+	timeout := gowebauthn.TimeoutConfig{Timeout: CeremonyTimeout, TimeoutUVD: CeremonyTimeout}
+	rp, err := gowebauthn.New(&gowebauthn.Config{
+		RPID:          settings.RPID,
+		RPDisplayName: settings.RPName,
+		RPOrigins:     settings.Origins,
+		// Attestation only vouches for the authenticator's make and model,
+		// and the service does not restrict which may be enrolled
+		AttestationPreference: protocol.PreferNoAttestation,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+		// Expiry is enforced by the ceremony cache
+		Timeouts: gowebauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		log.For(ctx).Errorf("Invalid webauthn settings of project %s: %v", projectID, err)
+		return nil, errors.New("internal server error")
+	}
+	return rp, nil
+}
+
+// projectUser loads an active project user
+func (m *Manager) projectUser(ctx context.Context, projectID, userID uuid.UUID) (*schemas.ProjectUser, error) {
+	users, err := m.Regions.UsersDB(m.DB, projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apierrors.NotFound("project not found")
+	} else if err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	var user schemas.ProjectUser
+	if err := users.Table(projecttable.Users(projectID)).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found in this project")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if !userstatus.CanLogin(user.Status) {
+		return nil, userstatus.LoginError(user.Status)
+	}
+	return &user, nil
+}
+
+// begin keeps a begun ceremony until it is answered or expires
+func (m *Manager) begin(c ceremony) {
+	m.ceremonies.Set(c.session.Challenge, c)
+}
+
+// take consumes the pending ceremony answered by client data, so that each
+// challenge can be used once
+func (m *Manager) take(cd protocol.CollectedClientData, kind protocol.CeremonyType, projectID uuid.UUID) (ceremony, error) {
+	if cd.Type != kind {
+		return ceremony{}, invalid("unexpected client data type")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.TrimRight(cd.Challenge, "=")
+	c, ok := m.ceremonies.Get(key)
+	if !ok || c.kind != kind || c.projectID != projectID {
+		return ceremony{}, ErrUnknownChallenge
+	}
+	m.ceremonies.Delete(key)
+	return c, nil
+}
+
+// BeginRegistration starts enrolling a passkey for a user
+func (m *Manager) BeginRegistration(ctx context.Context, projectID, userID uuid.UUID) (*CreationOptions, error) {
+	rp, err := m.relyingParty(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	projectUser, err := m.projectUser(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := m.ListCredentials(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([]protocol.CredentialDescriptor, 0, len(existing))
+	for i := range existing {
+		cred, err := libraryCredential(&existing[i], 0)
+		if err != nil {
+			continue
+		}
+		exclude = append(exclude, cred.Descriptor())
+	}
+
+	creation, session, err := rp.BeginRegistration(newUser(projectUser),
+		gowebauthn.WithCredentialParameters(gowebauthn.CredentialParametersRecommendedL3()),
+		gowebauthn.WithExclusions(exclude))
+	if err != nil {
+		log.For(ctx).Errorf("Failed to begin webauthn registration: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	m.begin(ceremony{kind: ceremonyCreate, projectID: projectID, userID: userID, session: *session})
+	return &creation.Response, nil
+}
+
+// FinishRegistration verifies an attestation and stores the new credential
+func (m *Manager) FinishRegistration(ctx context.Context, projectID, userID uuid.UUID, name string, response AttestationResponse) (*schemas.WebAuthnCredential, error) {
+	rp, err := m.relyingParty(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := response.Parse()
+	if err != nil {
+		return nil, rejected(err)
+	}
+	c, err := m.take(parsed.Response.CollectedClientData, ceremonyCreate, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if c.userID != userID {
+		return nil, ErrUnknownChallenge
+	}
+
+	projectUser, err := m.projectUser(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := rp.CreateCredential(newUser(projectUser), c.session, parsed)
+	if err != nil {
+		return nil, rejected(err)
+	}
+	if len(parsed.RawID) > 0 && !bytes.Equal(parsed.RawID, verified.ID) {
+		return nil, invalid("credential ID mismatch")
+	}
+	var key webauthncose.PublicKeyData
+	if err := webauthncbor.Unmarshal(verified.PublicKey, &key); err != nil {
+		return nil, invalid("malformed credential public key")
+	}
+	aaguid, err := uuid.FromBytes(verified.Authenticator.AAGUID)
+	if err != nil {
+		return nil, invalid("malformed AAGUID")
+	}
+
+	credentialID := encodeID(verified.ID)
+	var count int64
+	if err := m.DB.Model(&schemas.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&count).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	if count > 0 {
+		return nil, apierrors.Conflict("credential is already registered")
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = "Passkey"
+	}
+	flags := uint8(verified.Flags.ProtocolValue())
+	transports := make([]string, 0, len(verified.Transport))
+	for _, transport := range verified.Transport {
+		transports = append(transports, string(transport))
+	}
+	now := m.Clock.Now()
+	cred := schemas.WebAuthnCredential{
+		ID:           uuid.New(),
+		CredentialID: credentialID,
+		Name:         name,
+		AAGUID:       aaguid,
+		PublicKey:    verified.PublicKey,
+		Algorithm:    int(key.Algorithm),
+		SignCount:    verified.Authenticator.SignCount,
+		Flags:        &flags,
+		Transports:   transports,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ProjectId:    projectID,
+		UserId:       userID,
+	}
+	if err := m.DB.Create(&cred).Error; err != nil {
+		log.For(ctx).Errorf("Failed to store webauthn credential: %v", err)
+		return nil, errors.New("failed to register credential")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionCreate,
+		ResourceType: audit.ResourceCredential,
+		ResourceID:   cred.ID.String(),
+		Details:      "for user " + userID.String(),
+		At:           now,
+	})
+
+	return &cred, nil
+}
+
+// BeginLogin starts a passwordless login. Credentials are discoverable, so
+// no user is named up front; the authenticator picks the credential.
+func (m *Manager) BeginLogin(ctx context.Context, projectID uuid.UUID) (*RequestOptions, error) {
+	rp, err := m.relyingParty(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, session, err := rp.BeginDiscoverableLogin(gowebauthn.WithUserVerification(protocol.VerificationPreferred))
+	if err != nil {
+		log.For(ctx).Errorf("Failed to begin webauthn login: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	m.begin(ceremony{kind: ceremonyGet, projectID: projectID, session: *session})
+	return &assertion.Response, nil
+}
+
+// FinishLogin verifies an assertion, resolving the user by credential ID,
+// and issues the same JWT as a password login
+func (m *Manager) FinishLogin(ctx context.Context, projectID uuid.UUID, response AssertionResponse) (*Session, error) {
+	rp, err := m.relyingParty(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := response.Parse()
+	if err != nil {
+		return nil, rejected(err)
+	}
+	c, err := m.take(parsed.Response.CollectedClientData, ceremonyGet, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.RawID) == 0 {
+		return nil, invalid("missing credential ID")
+	}
+
+	var cred schemas.WebAuthnCredential
+	if err := m.DB.First(&cred, "credential_id = ? AND project_id = ?", encodeID(parsed.RawID), projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalid("unknown credential")
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	stored, err := libraryCredential(&cred, parsed.Response.AuthenticatorData.Flags)
+	if err != nil {
+		log.For(ctx).Errorf("Stored webauthn credential %s is unusable: %v", cred.ID, err)
+		return nil, errors.New("internal server error")
+	}
+	// The library checks that the user handle is the credential's user
+	owner := &user{id: cred.UserId, credentials: []gowebauthn.Credential{stored}}
+	verified, err := rp.ValidateDiscoverableLogin(func(_, _ []byte) (gowebauthn.User, error) {
+		return owner, nil
+	}, c.session, parsed)
+	if err != nil {
+		return nil, rejected(err)
+	}
+
+	now := m.Clock.Now()
+
+	// A counter that fails to advance means two authenticators may hold the
+	// same key. Authenticators that do not count report zero every time.
+	if verified.Authenticator.CloneWarning {
+		received := parsed.Response.AuthenticatorData.Counter
+		log.For(ctx).Warningf("Sign count regression on webauthn credential %s: stored %d, got %d", cred.ID, cred.SignCount, received)
+		audit.Record(ctx, m.DB, audit.Entry{
+			ProjectID:    &projectID,
+			Action:       audit.ActionSignCountRegression,
+			ResourceType: audit.ResourceCredential,
+			ResourceID:   cred.ID.String(),
+			Details:      fmt.Sprintf("possible cloned authenticator: stored sign count %d, received %d", cred.SignCount, received),
+			At:           now,
+		})
+	}
+
+	// The library keeps the counter on a regression, so a clone stays detectable
+	flags := uint8(verified.Flags.ProtocolValue())
+	if err := m.DB.Model(&cred).Updates(map[string]interface{}{
+		"sign_count":   verified.Authenticator.SignCount,
+		"flags":        flags,
+		"last_used_at": now,
+		"updated_at":   now,
+	}).Error; err != nil {
+		log.For(ctx).Errorf("Failed to update webauthn credential: %v", err)
+		return nil, errors.New("internal server error")
+	}
+
+	projectUser, err := m.projectUser(ctx, projectID, cred.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := auth.IssueProjectUserToken(ctx, m.DB, *projectUser, now)
+	if err != nil {
+		return nil, err
+	}
+	m.Logins.RecordLogin(ctx, projectID, projectUser.ID, projectUser.Email, logins.MethodWebAuthn)
+
+	return &Session{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User: models.DisplayUser{
+			ID:            projectUser.ID.String(),
+			Email:         projectUser.Email,
+			EmailVerified: projectUser.EmailVerified,
+			FirstName:     projectUser.FirstName,
+			LastName:      projectUser.LastName,
+			Active:        projectUser.Active,
+			Status:        projectUser.Status,
+			Transitions:   userstatus.Next(projectUser.Status),
+			RoleID:        projectUser.RoleId.String(),
+			ProjectID:     projectUser.ProjectId.String(),
+			CreatedAt:     projectUser.CreatedAt,
+			UpdatedAt:     projectUser.UpdatedAt,
+		},
+	}, nil
+}
+
+// ListCredentials lists a user's passkeys, oldest first
+func (m *Manager) ListCredentials(ctx context.Context, projectID, userID uuid.UUID) ([]schemas.WebAuthnCredential, error) {
+	var creds []schemas.WebAuthnCredential
+	if err := m.DB.Where("project_id = ? AND user_id = ?", projectID, userID).Order("created_at").Find(&creds).Error; err != nil {
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return creds, nil
+}
+
+// credential loads one of a user's passkeys
+func (m *Manager) credential(ctx context.Context, projectID, userID, id uuid.UUID) (*schemas.WebAuthnCredential, error) {
+	var cred schemas.WebAuthnCredential
+	if err := m.DB.First(&cred, "id = ? AND project_id = ? AND user_id = ?", id, projectID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		log.For(ctx).Errorf("Database error: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	return &cred, nil
+}
+
+// RenameCredential changes a passkey's display name
+func (m *Manager) RenameCredential(ctx context.Context, projectID, userID, id uuid.UUID, name string) (*schemas.WebAuthnCredential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apierrors.BadRequest("NAME_REQUIRED", "name is required")
+	}
+	if len(name) > 100 {
+		return nil, apierrors.BadRequest("NAME_TOO_LONG", "name must be at most 100 characters")
+	}
+
+	cred, err := m.credential(ctx, projectID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cred.Name = name
+	cred.UpdatedAt = m.Clock.Now()
+	if err := m.DB.Save(cred).Error; err != nil {
+		log.For(ctx).Errorf("Failed to rename webauthn credential: %v", err)
+		return nil, errors.New("failed to rename credential")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionUpdate,
+		ResourceType: audit.ResourceCredential,
+		ResourceID:   cred.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return cred, nil
+}
+
+// DeleteCredential removes a passkey
+func (m *Manager) DeleteCredential(ctx context.Context, projectID, userID, id uuid.UUID) error {
+	cred, err := m.credential(ctx, projectID, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.DB.Delete(cred).Error; err != nil {
+		log.For(ctx).Errorf("Failed to delete webauthn credential: %v", err)
+		return errors.New("failed to delete credential")
+	}
+
+	audit.Record(ctx, m.DB, audit.Entry{
+		ProjectID:    &projectID,
+		Action:       audit.ActionDelete,
+		ResourceType: audit.ResourceCredential,
+		ResourceID:   cred.ID.String(),
+		At:           m.Clock.Now(),
+	})
+
+	return nil
+}
@@ -0,0 +1,314 @@
+package webauthn_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/audit"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+	"github.com/yash3004/user_management_service/internal/auth"
+	"github.com/yash3004/user_management_service/internal/mocks"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/logins"
+	"github.com/yash3004/user_management_service/webauthn"
+	"gorm.io/gorm"
+)
+
+const origin = "https://app.example.com"
+
+// fixture is a WebAuthn manager over a project whose relying party is
+// example.com, with one user, a@example.com
+type fixture struct {
+	db      *gorm.DB
+	manager webauthn.WebAuthnManager
+	project schemas.Project
+	user    schemas.ProjectUser
+	logins  []string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	db := testutil.NewTestDB(t)
+	built := testutil.AProject().WithRole("member").WithUser("a@example.com").Build(t, db)
+	built.Project.Settings.WebAuthn = schemas.WebAuthnSettings{RPID: "example.com", Origins: []string{origin}}
+	if err := db.Save(&built.Project).Error; err != nil {
+		t.Fatalf("failed to configure passkeys: %v", err)
+	}
+
+	f := &fixture{db: db, project: built.Project, user: built.Users["a@example.com"]}
+	recorder := &mocks.LoginManager{
+		RecordLoginFunc: func(_ context.Context, _, _ uuid.UUID, email, method string) {
+			f.logins = append(f.logins, email+" "+method)
+		},
+	}
+	f.manager = webauthn.NewManager(db, nil, recorder)
+	return f
+}
+
+// enroll registers the authenticator's credential for the fixture's user
+func (f *fixture) enroll(t *testing.T, authenticator *passkey) *schemas.WebAuthnCredential {
+	t.Helper()
+
+	ctx := context.Background()
+	options, err := f.manager.BeginRegistration(ctx, f.project.ID, f.user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	cred, err := f.manager.FinishRegistration(ctx, f.project.ID, f.user.ID, "Laptop", authenticator.register(t, options))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return cred
+}
+
+// login runs a login ceremony, advancing the authenticator's counter by step
+func (f *fixture) login(t *testing.T, authenticator *passkey, step uint32) (*webauthn.Session, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	options, err := f.manager.BeginLogin(ctx, f.project.ID)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	return f.manager.FinishLogin(ctx, f.project.ID, authenticator.login(t, options, step))
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var apiErr *apierrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	f := newFixture(t)
+	authenticator := newPasskey(origin)
+
+	cred := f.enroll(t, authenticator)
+	if cred.AAGUID != authenticator.aaguid() || cred.UserId != f.user.ID || cred.Name != "Laptop" || cred.Algorithm != int(webauthncose.AlgES256) {
+		t.Errorf("credential = %+v, want the authenticator's ES256 credential for the user", cred)
+	}
+
+	session, err := f.login(t, authenticator, 1)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	claims, err := auth.ParseToken(session.Token)
+	if err != nil {
+		t.Fatalf("the session token does not parse: %v", err)
+	}
+	if claims.UserID != f.user.ID || claims.ProjectId != f.project.ID {
+		t.Errorf("claims = %+v, want user %s of project %s", claims, f.user.ID, f.project.ID)
+	}
+	if len(f.logins) != 1 || f.logins[0] != "a@example.com "+logins.MethodWebAuthn {
+		t.Errorf("recorded logins %v, want one passkey login", f.logins)
+	}
+
+	var stored schemas.WebAuthnCredential
+	if err := f.db.First(&stored, "id = ?", cred.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.SignCount != 1 || stored.LastUsedAt == nil {
+		t.Errorf("credential has sign count %d and last use %v, want 1 and the login", stored.SignCount, stored.LastUsedAt)
+	}
+}
+
+func TestLoginWithPasskeyStoredWithoutFlags(t *testing.T) {
+	f := newFixture(t)
+	authenticator := newPasskey(origin)
+	cred := f.enroll(t, authenticator)
+	if err := f.db.Model(cred).Update("flags", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.login(t, authenticator, 1); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	var stored schemas.WebAuthnCredential
+	if err := f.db.First(&stored, "id = ?", cred.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Flags == nil {
+		t.Error("the login did not store the flags")
+	}
+}
+
+func TestRegistrationRejects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a second registration of the credential", func(t *testing.T) {
+		f := newFixture(t)
+		authenticator := newPasskey(origin)
+		f.enroll(t, authenticator)
+
+		options, err := f.manager.BeginRegistration(ctx, f.project.ID, f.user.ID)
+		if err != nil {
+			t.Fatalf("BeginRegistration: %v", err)
+		}
+		if len(options.CredentialExcludeList) != 1 {
+			t.Errorf("options exclude %d credentials, want the enrolled one", len(options.CredentialExcludeList))
+		}
+		_, err = f.manager.FinishRegistration(ctx, f.project.ID, f.user.ID, "", authenticator.register(t, options))
+		wantCode(t, err, "CONFLICT")
+	})
+
+	t.Run("another origin", func(t *testing.T) {
+		f := newFixture(t)
+		options, err := f.manager.BeginRegistration(ctx, f.project.ID, f.user.ID)
+		if err != nil {
+			t.Fatalf("BeginRegistration: %v", err)
+		}
+		response := newPasskey("https://evil.example.net").register(t, options)
+		_, err = f.manager.FinishRegistration(ctx, f.project.ID, f.user.ID, "", response)
+		wantCode(t, err, webauthn.ErrInvalidCredential.Code)
+	})
+
+	t.Run("another user's challenge", func(t *testing.T) {
+		f := newFixture(t)
+		options, err := f.manager.BeginRegistration(ctx, f.project.ID, f.user.ID)
+		if err != nil {
+			t.Fatalf("BeginRegistration: %v", err)
+		}
+		response := newPasskey(origin).register(t, options)
+		if _, err := f.manager.FinishRegistration(ctx, f.project.ID, uuid.New(), "", response); !errors.Is(err, webauthn.ErrUnknownChallenge) {
+			t.Fatalf("err = %v, want %v", err, webauthn.ErrUnknownChallenge)
+		}
+	})
+
+	t.Run("a project without passkeys", func(t *testing.T) {
+		f := newFixture(t)
+		other := testutil.AProject().WithUser("b@example.com").Build(t, f.db)
+		if _, err := f.manager.BeginRegistration(ctx, other.Project.ID, other.Users["b@example.com"].ID); !errors.Is(err, webauthn.ErrDisabled) {
+			t.Fatalf("err = %v, want %v", err, webauthn.ErrDisabled)
+		}
+	})
+}
+
+func TestLoginRejects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a replayed assertion", func(t *testing.T) {
+		f := newFixture(t)
+		authenticator := newPasskey(origin)
+		f.enroll(t, authenticator)
+
+		options, err := f.manager.BeginLogin(ctx, f.project.ID)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err)
+		}
+		response := authenticator.login(t, options, 1)
+		if _, err := f.manager.FinishLogin(ctx, f.project.ID, response); err != nil {
+			t.Fatalf("FinishLogin: %v", err)
+		}
+		if _, err := f.manager.FinishLogin(ctx, f.project.ID, response); !errors.Is(err, webauthn.ErrUnknownChallenge) {
+			t.Fatalf("err = %v, want %v", err, webauthn.ErrUnknownChallenge)
+		}
+	})
+
+	t.Run("a bad signature", func(t *testing.T) {
+		f := newFixture(t)
+		authenticator := newPasskey(origin)
+		f.enroll(t, authenticator)
+
+		options, err := f.manager.BeginLogin(ctx, f.project.ID)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err)
+		}
+		response := authenticator.login(t, options, 1)
+		response.AssertionResponse.Signature[len(response.AssertionResponse.Signature)-1] ^= 0xff
+		_, err = f.manager.FinishLogin(ctx, f.project.ID, response)
+		wantCode(t, err, webauthn.ErrInvalidCredential.Code)
+	})
+
+	t.Run("another relying party", func(t *testing.T) {
+		f := newFixture(t)
+		authenticator := newPasskey(origin)
+		f.enroll(t, authenticator)
+
+		options, err := f.manager.BeginLogin(ctx, f.project.ID)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err)
+		}
+		options.RelyingPartyID = "evil.example.net"
+		_, err = f.manager.FinishLogin(ctx, f.project.ID, authenticator.login(t, options, 1))
+		wantCode(t, err, webauthn.ErrInvalidCredential.Code)
+	})
+
+	t.Run("an unknown credential", func(t *testing.T) {
+		f := newFixture(t)
+		_, err := f.login(t, newPasskey(origin), 1)
+		wantCode(t, err, webauthn.ErrInvalidCredential.Code)
+	})
+}
+
+func TestSignCountRegressionIsAudited(t *testing.T) {
+	f := newFixture(t)
+	authenticator := newPasskey(origin)
+	cred := f.enroll(t, authenticator)
+
+	if _, err := f.login(t, authenticator, 5); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	// A clone of the key replays a counter the service has already seen
+	authenticator.credential.Counter = 2
+	if _, err := f.login(t, authenticator, 0); err != nil {
+		t.Fatalf("FinishLogin with a regressed counter: %v", err)
+	}
+
+	var events []schemas.AuditEvent
+	if err := f.db.Where("action = ? AND resource_id = ?", audit.ActionSignCountRegression, cred.ID.String()).Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d sign count regressions were audited, want 1", len(events))
+	}
+
+	var stored schemas.WebAuthnCredential
+	if err := f.db.First(&stored, "id = ?", cred.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.SignCount != 5 {
+		t.Errorf("sign count = %d, want it kept at 5", stored.SignCount)
+	}
+}
+
+func TestCredentialManagement(t *testing.T) {
+	f := newFixture(t)
+	cred := f.enroll(t, newPasskey(origin))
+	ctx := context.Background()
+
+	creds, err := f.manager.ListCredentials(ctx, f.project.ID, f.user.ID)
+	if err != nil {
+		t.Fatalf("ListCredentials: %v", err)
+	}
+	if len(creds) != 1 || creds[0].ID != cred.ID {
+		t.Fatalf("credentials = %+v, want the enrolled one", creds)
+	}
+
+	renamed, err := f.manager.RenameCredential(ctx, f.project.ID, f.user.ID, cred.ID, " Phone ")
+	if err != nil {
+		t.Fatalf("RenameCredential: %v", err)
+	}
+	if renamed.Name != "Phone" {
+		t.Errorf("name = %q, want Phone", renamed.Name)
+	}
+	_, err = f.manager.RenameCredential(ctx, f.project.ID, f.user.ID, cred.ID, " ")
+	wantCode(t, err, "NAME_REQUIRED")
+	if _, err := f.manager.RenameCredential(ctx, f.project.ID, uuid.New(), cred.ID, "Phone"); !errors.Is(err, webauthn.ErrCredentialNotFound) {
+		t.Fatalf("renaming another user's credential: err = %v, want %v", err, webauthn.ErrCredentialNotFound)
+	}
+
+	if err := f.manager.DeleteCredential(ctx, f.project.ID, f.user.ID, cred.ID); err != nil {
+		t.Fatalf("DeleteCredential: %v", err)
+	}
+	if err := f.manager.DeleteCredential(ctx, f.project.ID, f.user.ID, cred.ID); !errors.Is(err, webauthn.ErrCredentialNotFound) {
+		t.Fatalf("err = %v, want %v", err, webauthn.ErrCredentialNotFound)
+	}
+}
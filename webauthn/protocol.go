@@ -0,0 +1,40 @@
+package webauthn
+
+import (
+	"errors"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/yash3004/user_management_service/internal/apierrors"
+)
+
+// ErrInvalidCredential is returned for any response that fails verification
+var ErrInvalidCredential = apierrors.BadRequest("INVALID_CREDENTIAL", "webauthn response could not be verified")
+
+// invalid returns ErrInvalidCredential with a more specific message
+func invalid(reason string) error {
+	return apierrors.BadRequest(ErrInvalidCredential.Code, "webauthn response could not be verified: "+reason)
+}
+
+// rejected returns ErrInvalidCredential with the reason the library gave for
+// refusing a response
+func rejected(err error) error {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		return invalid(protocolErr.Details)
+	}
+	return invalid(err.Error())
+}
+
+// The ceremony options and responses are those of the go-webauthn library,
+// whose JSON matches what browsers pass to and return from
+// navigator.credentials. Binary fields are unpadded base64url.
+type (
+	// CreationOptions are passed to navigator.credentials.create
+	CreationOptions = protocol.PublicKeyCredentialCreationOptions
+	// RequestOptions are passed to navigator.credentials.get
+	RequestOptions = protocol.PublicKeyCredentialRequestOptions
+	// AttestationResponse is the JSON form of a registration PublicKeyCredential
+	AttestationResponse = protocol.CredentialCreationResponse
+	// AssertionResponse is the JSON form of a login PublicKeyCredential
+	AssertionResponse = protocol.CredentialAssertionResponse
+)
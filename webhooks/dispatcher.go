@@ -0,0 +1,93 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with
+// the subscription secret and prefixed with "sha256="
+const SignatureHeader = "X-Webhook-Signature"
+
+// Publish delivers an event to every enabled subscription of the project
+// that wants it, or of every project when projectID is uuid.Nil. Delivery
+// happens in the background; failures are logged.
+func (m *Manager) Publish(ctx context.Context, projectID uuid.UUID, name string, data interface{}) {
+	query := m.DB.Where("enabled = ?", true)
+	if projectID != uuid.Nil {
+		query = query.Where("project_id = ?", projectID)
+	}
+	var subs []schemas.WebhookSubscription
+	if err := query.Find(&subs).Error; err != nil {
+		log.For(ctx).Errorf("Failed to load webhook subscriptions: %v", err)
+		return
+	}
+
+	ev := event{
+		ID:        uuid.New(),
+		Name:      name,
+		ProjectID: projectID,
+		Time:      m.Clock.Now(),
+		Data:      data,
+	}
+
+	for _, sub := range subs {
+		if !wants(sub, name) {
+			continue
+		}
+		// A global event is addressed to each subscription's own project
+		ev.ProjectID = sub.ProjectId
+		body, contentType, err := m.encode(sub, ev)
+		if err != nil {
+			log.For(ctx).Errorf("Failed to encode webhook %s for subscription %s: %v", name, sub.ID, err)
+			continue
+		}
+		go m.deliver(ctx, sub, body, contentType)
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts an event to a subscription. ctx is the publishing request's,
+// and only names it in the log: delivery outlives the request.
+func (m *Manager) deliver(ctx context.Context, sub schemas.WebhookSubscription, body []byte, contentType string) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		log.For(ctx).Errorf("Failed to build webhook request for subscription %s: %v", sub.ID, err)
+		return
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, body))
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		log.For(ctx).Warningf("Webhook delivery to subscription %s failed: %v", sub.ID, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.For(ctx).Warningf("Webhook delivery to subscription %s returned %d", sub.ID, resp.StatusCode)
+	}
+}
+
+func wants(sub schemas.WebhookSubscription, name string) bool {
+	for _, ev := range sub.Events {
+		if ev == name || ev == AllEvents {
+			return true
+		}
+	}
+	return false
+}
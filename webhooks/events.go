@@ -0,0 +1,55 @@
+package webhooks
+
+// Events published by the service. Subscriptions list the events they want,
+// or AllEvents for everything.
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventUserRestored = "user.restored"
+
+	EventUserStatusChanged = "user.status_changed"
+
+	EventUserSuspiciousLogin = "user.suspicious_login"
+
+	EventRoleCreated        = "role.created"
+	EventRoleUpdated        = "role.updated"
+	EventRoleDeleted        = "role.deleted"
+	EventRolePolicyAttached = "role.policy_attached"
+	EventRolePolicyDetached = "role.policy_detached"
+
+	AllEvents = "*"
+)
+
+// cloudEventTypePrefix namespaces event names as CloudEvents types
+const cloudEventTypePrefix = "com.ums."
+
+// EventInfo describes an event in the catalog
+type EventInfo struct {
+	Name           string `json:"name"`
+	CloudEventType string `json:"cloudevent_type"`
+	Description    string `json:"description"`
+}
+
+// Catalog lists every event that can be subscribed to
+var Catalog = map[string]EventInfo{
+	EventUserCreated:         {Name: EventUserCreated, CloudEventType: cloudEventTypePrefix + EventUserCreated, Description: "A project user was created"},
+	EventUserUpdated:         {Name: EventUserUpdated, CloudEventType: cloudEventTypePrefix + EventUserUpdated, Description: "A project user was updated"},
+	EventUserDeleted:         {Name: EventUserDeleted, CloudEventType: cloudEventTypePrefix + EventUserDeleted, Description: "A project user was deleted"},
+	EventUserRestored:        {Name: EventUserRestored, CloudEventType: cloudEventTypePrefix + EventUserRestored, Description: "A deleted project user was restored"},
+	EventUserStatusChanged:   {Name: EventUserStatusChanged, CloudEventType: cloudEventTypePrefix + EventUserStatusChanged, Description: "A project user's status changed"},
+	EventUserSuspiciousLogin: {Name: EventUserSuspiciousLogin, CloudEventType: cloudEventTypePrefix + EventUserSuspiciousLogin, Description: "A project user logged in from a new IP address or device"},
+	EventRoleCreated:         {Name: EventRoleCreated, CloudEventType: cloudEventTypePrefix + EventRoleCreated, Description: "A role was created"},
+	EventRoleUpdated:         {Name: EventRoleUpdated, CloudEventType: cloudEventTypePrefix + EventRoleUpdated, Description: "A role was updated or renamed"},
+	EventRoleDeleted:         {Name: EventRoleDeleted, CloudEventType: cloudEventTypePrefix + EventRoleDeleted, Description: "A role was deleted"},
+	EventRolePolicyAttached:  {Name: EventRolePolicyAttached, CloudEventType: cloudEventTypePrefix + EventRolePolicyAttached, Description: "A policy was assigned to a role"},
+	EventRolePolicyDetached:  {Name: EventRolePolicyDetached, CloudEventType: cloudEventTypePrefix + EventRolePolicyDetached, Description: "A policy was removed from a role"},
+}
+
+// CloudEventType returns the CloudEvents type for an event name
+func CloudEventType(event string) string {
+	if info, ok := Catalog[event]; ok {
+		return info.CloudEventType
+	}
+	return cloudEventTypePrefix + event
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/version"
+)
+
+// cloudEventsSpecVersion is the CloudEvents version of the structured envelope
+const cloudEventsSpecVersion = "1.0"
+
+// RawPayload is the body delivered to subscriptions in the raw format
+type RawPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	ProjectID string      `json:"project_id"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data"`
+
+	ServiceVersion string `json:"service_version"` // Version of the service that emitted the event
+}
+
+// CloudEvent is the CloudEvents 1.0 structured-mode JSON envelope
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+
+	// ServiceVersion is an extension attribute carrying the version of the
+	// service that emitted the event
+	ServiceVersion string `json:"serviceversion"`
+}
+
+// event is a published event before it is encoded for a subscription
+type event struct {
+	ID        uuid.UUID
+	Name      string
+	ProjectID uuid.UUID
+	Time      time.Time
+	Data      interface{}
+}
+
+// encode renders the event in the subscription's format, returning the body
+// and its content type. The signature is computed over exactly this body, so
+// in cloudevents mode it covers the whole envelope.
+func (m *Manager) encode(sub schemas.WebhookSubscription, ev event) ([]byte, string, error) {
+	if sub.Format == schemas.WebhookFormatCloudEvents {
+		body, err := json.Marshal(CloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              ev.ID.String(),
+			Source:          strings.TrimRight(m.Options.ServiceURL, "/") + "/projects/" + ev.ProjectID.String(),
+			Type:            CloudEventType(ev.Name),
+			Time:            ev.Time.UTC(),
+			DataContentType: "application/json",
+			Data:            ev.Data,
+			ServiceVersion:  version.Get().Version,
+		})
+		return body, "application/cloudevents+json", err
+	}
+
+	body, err := json.Marshal(RawPayload{
+		ID:        ev.ID.String(),
+		Event:     ev.Name,
+		ProjectID: ev.ProjectID.String(),
+		Time:      ev.Time.UTC(),
+		Data:      ev.Data,
+
+		ServiceVersion: version.Get().Version,
+	})
+	return body, "application/json", err
+}
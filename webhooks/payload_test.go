@@ -0,0 +1,163 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yash3004/user_management_service/internal/schemas"
+	"github.com/yash3004/user_management_service/internal/testutil"
+	"github.com/yash3004/user_management_service/internal/version"
+	"github.com/yash3004/user_management_service/webhooks"
+)
+
+// delivery is a webhook request received by the test endpoint
+type delivery struct {
+	contentType string
+	signature   string
+	body        []byte
+}
+
+// receiver starts an endpoint that hands each delivery to the returned channel
+func receiver(t *testing.T) (*httptest.Server, <-chan delivery) {
+	t.Helper()
+
+	deliveries := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{
+			contentType: r.Header.Get("Content-Type"),
+			signature:   r.Header.Get(webhooks.SignatureHeader),
+			body:        body,
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+func receive(t *testing.T, deliveries <-chan delivery) delivery {
+	t.Helper()
+
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook was delivered")
+		return delivery{}
+	}
+}
+
+// extensionName is the CloudEvents rule for attribute names
+var extensionName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// TestCloudEventsEnvelope checks a delivered envelope against the
+// CloudEvents 1.0 JSON schema: the required context attributes, their
+// types and formats, and the naming rule for every attribute
+func TestCloudEventsEnvelope(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	project := testutil.AProject().Build(t, db).Project
+	manager := webhooks.NewManager(db, webhooks.Options{ServiceURL: "https://ums.example.com/"})
+	ctx := context.Background()
+
+	server, deliveries := receiver(t)
+	sub, err := manager.CreateSubscription(ctx, project.ID, server.URL, []string{webhooks.EventUserCreated}, schemas.WebhookFormatCloudEvents)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	manager.Publish(ctx, project.ID, webhooks.EventUserCreated, map[string]string{"email": "a@example.com"})
+	d := receive(t, deliveries)
+
+	if d.contentType != "application/cloudevents+json" {
+		t.Errorf("Content-Type = %q, want application/cloudevents+json", d.contentType)
+	}
+	if want := webhooks.Sign(sub.Secret, d.body); d.signature != want {
+		t.Errorf("signature = %q, want %q over the whole envelope", d.signature, want)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(d.body, &envelope); err != nil {
+		t.Fatalf("the envelope is not a JSON object: %v", err)
+	}
+	for name := range envelope {
+		if !extensionName.MatchString(name) {
+			t.Errorf("attribute %q is not a valid CloudEvents attribute name", name)
+		}
+	}
+
+	stringAttr := func(name string) string {
+		t.Helper()
+		value, ok := envelope[name].(string)
+		if !ok || value == "" {
+			t.Errorf("%s = %v, want a non-empty string", name, envelope[name])
+		}
+		return value
+	}
+	if got := stringAttr("specversion"); got != "1.0" {
+		t.Errorf("specversion = %q, want 1.0", got)
+	}
+	if _, err := uuid.Parse(stringAttr("id")); err != nil {
+		t.Errorf("id is not a UUID: %v", err)
+	}
+	if got, want := stringAttr("source"), "https://ums.example.com/projects/"+project.ID.String(); got != want {
+		t.Errorf("source = %q, want %q", got, want)
+	} else if _, err := url.Parse(got); err != nil {
+		t.Errorf("source is not a URI reference: %v", err)
+	}
+	if got := stringAttr("type"); got != webhooks.Catalog[webhooks.EventUserCreated].CloudEventType || got != "com.ums.user.created" {
+		t.Errorf("type = %q, want the catalog's com.ums.user.created", got)
+	}
+	if _, err := time.Parse(time.RFC3339, stringAttr("time")); err != nil {
+		t.Errorf("time is not RFC 3339: %v", err)
+	}
+	if got := stringAttr("datacontenttype"); got != "application/json" {
+		t.Errorf("datacontenttype = %q, want application/json", got)
+	}
+	if got := stringAttr("serviceversion"); got != version.Get().Version {
+		t.Errorf("serviceversion = %q, want %q", got, version.Get().Version)
+	}
+	data, ok := envelope["data"].(map[string]interface{})
+	if !ok || data["email"] != "a@example.com" {
+		t.Errorf("data = %v, want the published event data", envelope["data"])
+	}
+}
+
+func TestRawPayload(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	project := testutil.AProject().Build(t, db).Project
+	manager := webhooks.NewManager(db, webhooks.Options{ServiceURL: "https://ums.example.com"})
+	ctx := context.Background()
+
+	server, deliveries := receiver(t)
+	sub, err := manager.CreateSubscription(ctx, project.ID, server.URL, nil, "")
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if sub.Format != schemas.WebhookFormatRaw {
+		t.Fatalf("format = %q, want raw by default", sub.Format)
+	}
+
+	manager.Publish(ctx, project.ID, webhooks.EventRoleCreated, map[string]string{"name": "Editor"})
+	d := receive(t, deliveries)
+
+	if d.contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", d.contentType)
+	}
+	if want := webhooks.Sign(sub.Secret, d.body); d.signature != want {
+		t.Errorf("signature = %q, want %q", d.signature, want)
+	}
+	var payload webhooks.RawPayload
+	if err := json.Unmarshal(d.body, &payload); err != nil {
+		t.Fatalf("failed to decode the payload: %v", err)
+	}
+	if payload.Event != webhooks.EventRoleCreated || payload.ProjectID != project.ID.String() || payload.ServiceVersion != version.Get().Version {
+		t.Errorf("payload = %+v, want %s of project %s from version %s", payload, webhooks.EventRoleCreated, project.ID, version.Get().Version)
+	}
+}